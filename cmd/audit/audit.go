@@ -0,0 +1,111 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package audit
+
+import (
+	"bufio"
+	"fmt"
+
+	"github.com/rancher/k3d/v5/cmd/util"
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+type auditTailFlags struct {
+	name   string
+	node   string
+	lines  int
+	follow bool
+}
+
+// NewCmdAudit returns a new cobra command
+func NewCmdAudit() *cobra.Command {
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Access the API server audit log of a cluster",
+		Long: `Access the API server audit log of a cluster.
+Audit logging has to be enabled at creation time, using 'k3d cluster create --audit-policy ./policy.yaml'.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := cmd.Help(); err != nil {
+				l.Log().Errorln("Couldn't get help text")
+				l.Log().Fatalln(err)
+			}
+		},
+	}
+
+	// add subcommands
+	cmd.AddCommand(NewCmdAuditTail())
+
+	// done
+	return cmd
+}
+
+// NewCmdAuditTail returns a new cobra command
+func NewCmdAuditTail() *cobra.Command {
+
+	flags := auditTailFlags{}
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:   "tail [--name CLUSTERNAME]",
+		Short: "Stream the audit log of a cluster",
+		Long:  `Stream the audit events (one JSON object per line) written by the API server of a cluster`,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if flags.lines < 0 {
+				l.Log().Fatalln("--lines must not be negative")
+			}
+
+			stream, err := client.ClusterAuditLogTail(cmd.Context(), runtimes.SelectedRuntime, &k3d.Cluster{Name: flags.name}, flags.node, flags.lines, flags.follow)
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+			defer stream.Close()
+
+			scanner := bufio.NewScanner(stream)
+			scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024) // single audit events may be huge (e.g. including request bodies)
+			for scanner.Scan() {
+				fmt.Println(scanner.Text())
+			}
+			if err := scanner.Err(); err != nil && cmd.Context().Err() == nil {
+				l.Log().Fatalf("Failed to read audit log: %v", err)
+			}
+		},
+	}
+
+	// add flags
+	cmd.Flags().StringVarP(&flags.name, "name", "n", k3d.DefaultClusterName, "Name of the cluster")
+	if err := cmd.RegisterFlagCompletionFunc("name", util.ValidArgsAvailableClusters); err != nil {
+		l.Log().Fatalln("Failed to register flag completion for '--name'", err)
+	}
+	cmd.Flags().StringVar(&flags.node, "node", "", "Server node to read the audit log from (default: first running server)")
+	cmd.Flags().IntVar(&flags.lines, "lines", 10, "Number of past audit events to show")
+	cmd.Flags().BoolVarP(&flags.follow, "follow", "f", true, "Keep streaming new audit events")
+
+	// done
+	return cmd
+}
@@ -0,0 +1,310 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/liggitt/tabwriter"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+
+	cliutil "github.com/rancher/k3d/v5/cmd/util"
+	cliconfig "github.com/rancher/k3d/v5/cmd/util/config"
+	"github.com/rancher/k3d/v5/pkg/client"
+	"github.com/rancher/k3d/v5/pkg/config"
+	conf "github.com/rancher/k3d/v5/pkg/config/v1alpha3"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/rancher/k3d/v5/version"
+)
+
+const benchCreateDefaultClusterName = "bench"
+
+type benchCreateFlags struct {
+	configFile string
+	iterations int
+	output     string
+}
+
+// benchStageResult holds the aggregated durations of a single stage over all iterations
+type benchStageResult struct {
+	Stage   string  `yaml:"stage" json:"stage"`
+	Samples int     `yaml:"samples" json:"samples"`
+	P50     float64 `yaml:"p50Seconds" json:"p50Seconds"`
+	P95     float64 `yaml:"p95Seconds" json:"p95Seconds"`
+	Min     float64 `yaml:"minSeconds" json:"minSeconds"`
+	Max     float64 `yaml:"maxSeconds" json:"maxSeconds"`
+}
+
+// NewCmdBenchCreate returns a new cobra command
+func NewCmdBenchCreate() *cobra.Command {
+
+	flags := benchCreateFlags{}
+	cfgViper := viper.New()
+
+	// create new command
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Measure cluster boot time by repeatedly creating and deleting a throwaway cluster",
+		Long: `Measure cluster boot time by repeatedly creating and deleting a throwaway cluster.
+The durations of the single creation and deletion stages are reported as percentiles (p50/p95) over all iterations.
+Use a config file (--config) to benchmark the same setup you're using with 'k3d cluster create'.`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return cliconfig.InitViperWithConfigFile(cfgViper, flags.configFile)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			if flags.iterations < 1 {
+				l.Log().Fatalf("Number of iterations must be at least 1, got %d", flags.iterations)
+			}
+
+			simpleCfg, err := benchCreateSimpleConfig(cfgViper)
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+
+			// we're going to delete the cluster after each iteration, so we better not touch an existing one
+			if _, err := client.ClusterGet(cmd.Context(), runtimes.SelectedRuntime, &k3d.Cluster{Name: simpleCfg.Name}); err == nil {
+				l.Log().Fatalf("Cannot run benchmark: a cluster named '%s' already exists", simpleCfg.Name)
+			}
+
+			samples := map[string][]time.Duration{}
+			stages := []string{}
+			record := func(timings *k3d.OperationTimings) {
+				for _, stage := range timings.Stages {
+					if _, ok := samples[stage.Stage]; !ok {
+						stages = append(stages, stage.Stage)
+					}
+					samples[stage.Stage] = append(samples[stage.Stage], stage.Duration)
+				}
+			}
+
+			for i := 1; i <= flags.iterations; i++ {
+				l.Log().Infof("===== Benchmark Iteration %d/%d =====", i, flags.iterations)
+				createTimings, deleteTimings, err := benchCreateIteration(cmd.Context(), simpleCfg)
+				if err != nil {
+					l.Log().Fatalf("Benchmark iteration %d failed: %v", i, err)
+				}
+				record(createTimings)
+				record(deleteTimings)
+			}
+
+			results := []benchStageResult{}
+			for _, stage := range stages {
+				results = append(results, newBenchStageResult(stage, samples[stage]))
+			}
+
+			printBenchResults(results, flags.output)
+		},
+	}
+
+	/*********
+	 * Flags *
+	 *********/
+
+	cmd.Flags().IntVarP(&flags.iterations, "iterations", "n", 5, "Number of times the cluster should be created and deleted")
+	cmd.Flags().StringVarP(&flags.output, "output", "o", "", "Output format. One of: json|yaml")
+
+	cmd.Flags().StringVarP(&flags.configFile, "config", "c", "", "Path of a config file to use for the benchmarked cluster")
+	if err := cmd.MarkFlagFilename("config", "yaml", "yml"); err != nil {
+		l.Log().Fatalln("Failed to mark flag 'config' as filename flag")
+	}
+
+	cmd.Flags().IntP("servers", "s", 1, "Specify how many servers you want to create")
+	_ = cfgViper.BindPFlag("servers", cmd.Flags().Lookup("servers"))
+	cfgViper.SetDefault("servers", 1)
+
+	cmd.Flags().IntP("agents", "a", 0, "Specify how many agents you want to create")
+	_ = cfgViper.BindPFlag("agents", cmd.Flags().Lookup("agents"))
+	cfgViper.SetDefault("agents", 0)
+
+	cmd.Flags().StringP("image", "i", "", "Specify k3s image that you want to use for the nodes")
+	_ = cfgViper.BindPFlag("image", cmd.Flags().Lookup("image"))
+	cfgViper.SetDefault("image", fmt.Sprintf("%s:%s", k3d.DefaultK3sImageRepo, version.GetK3sVersion(false)))
+
+	cmd.Flags().Duration("timeout", 0*time.Second, "Fail the iteration if the cluster couldn't be created in specified duration.")
+	_ = cfgViper.BindPFlag("options.k3d.timeout", cmd.Flags().Lookup("timeout"))
+
+	cmd.Flags().Bool("no-lb", false, "Disable the creation of a LoadBalancer in front of the server nodes")
+	_ = cfgViper.BindPFlag("options.k3d.disableloadbalancer", cmd.Flags().Lookup("no-lb"))
+
+	cmd.Flags().Bool("no-image-volume", false, "Disable the creation of a volume for importing images")
+	_ = cfgViper.BindPFlag("options.k3d.disableimagevolume", cmd.Flags().Lookup("no-image-volume"))
+
+	cmd.Flags().StringArray("registry-use", nil, "Connect to one or more k3d-managed registries running locally")
+	_ = cfgViper.BindPFlag("registries.use", cmd.Flags().Lookup("registry-use"))
+
+	cmd.Flags().String("registry-config", "", "Specify path to an extra registries.yaml file")
+	_ = cfgViper.BindPFlag("registries.config", cmd.Flags().Lookup("registry-config"))
+
+	// done
+	return cmd
+}
+
+// benchCreateSimpleConfig reads the config used for all benchmark iterations
+func benchCreateSimpleConfig(cfgViper *viper.Viper) (conf.SimpleConfig, error) {
+	if cfgViper.GetString("apiversion") == "" {
+		cfgViper.Set("apiversion", config.DefaultConfigApiVersion)
+	}
+	if cfgViper.GetString("kind") == "" {
+		cfgViper.Set("kind", "Simple")
+	}
+	cfg, err := config.FromViper(cfgViper)
+	if err != nil {
+		return conf.SimpleConfig{}, err
+	}
+
+	if cfg.GetAPIVersion() != config.DefaultConfigApiVersion {
+		cfg, err = config.Migrate(cfg, config.DefaultConfigApiVersion)
+		if err != nil {
+			return conf.SimpleConfig{}, err
+		}
+	}
+
+	simpleCfg := cfg.(conf.SimpleConfig)
+	if simpleCfg.Name == "" {
+		simpleCfg.Name = benchCreateDefaultClusterName
+	}
+
+	// the benchmark measures cluster boot time, so we always wait for the server(s) and leave the kubeconfig alone
+	simpleCfg.Options.K3dOptions.Wait = true
+	simpleCfg.Options.KubeconfigOptions.UpdateDefaultKubeconfig = false
+	simpleCfg.Options.KubeconfigOptions.SwitchCurrentContext = false
+
+	return simpleCfg, nil
+}
+
+// benchCreateIteration creates and deletes a single cluster, recording the durations of all stages
+func benchCreateIteration(ctx context.Context, simpleCfg conf.SimpleConfig) (*k3d.OperationTimings, *k3d.OperationTimings, error) {
+
+	// every iteration gets a fresh API port (unless a fixed one was configured)
+	if simpleCfg.ExposeAPI.HostPort == "" {
+		port, err := cliutil.GetFreePort()
+		if err != nil || port == 0 {
+			l.Log().Warnf("Failed to get random free port, falling back to internal port %s (may be blocked though): %v", k3d.DefaultAPIPort, err)
+			simpleCfg.ExposeAPI.HostPort = k3d.DefaultAPIPort
+		} else {
+			simpleCfg.ExposeAPI.HostPort = strconv.Itoa(port)
+		}
+	}
+
+	clusterConfig, err := config.TransformSimpleToClusterConfig(ctx, runtimes.SelectedRuntime, simpleCfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	clusterConfig, err = config.ProcessClusterConfig(*clusterConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := config.ValidateClusterConfig(ctx, runtimes.SelectedRuntime, *clusterConfig); err != nil {
+		return nil, nil, fmt.Errorf("failed cluster configuration validation: %w", err)
+	}
+
+	createTimings := k3d.NewOperationTimings()
+	clusterConfig.ClusterCreateOpts.Timings = createTimings
+	runErr := client.ClusterRun(ctx, runtimes.SelectedRuntime, clusterConfig)
+	createTimings.Record("create (total)", createTimings.Total())
+
+	// always clean up, even if the creation failed: like the rollback of 'cluster create', a failed creation deletes its registries
+	// unconditionally (they may not be connected to all of their networks yet), a successful one keeps those shared with other clusters
+	deleteTimings := k3d.NewOperationTimings()
+	if err := client.ClusterDelete(ctx, runtimes.SelectedRuntime, &clusterConfig.Cluster, k3d.ClusterDeleteOpts{SkipRegistryCheck: runErr != nil, Timings: deleteTimings}); err != nil {
+		if runErr != nil {
+			return nil, nil, fmt.Errorf("failed to create cluster (%v) and failed to clean up: %w", runErr, err)
+		}
+		return nil, nil, fmt.Errorf("failed to delete cluster: %w", err)
+	}
+	deleteTimings.Record("delete (total)", deleteTimings.Total())
+
+	if runErr != nil {
+		return nil, nil, fmt.Errorf("failed to create cluster: %w", runErr)
+	}
+
+	return createTimings, deleteTimings, nil
+}
+
+// newBenchStageResult aggregates the durations of a stage
+func newBenchStageResult(stage string, durations []time.Duration) benchStageResult {
+	sorted := make([]time.Duration, len(durations))
+	copy(sorted, durations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return benchStageResult{
+		Stage:   stage,
+		Samples: len(sorted),
+		P50:     percentile(sorted, 50).Seconds(),
+		P95:     percentile(sorted, 95).Seconds(),
+		Min:     sorted[0].Seconds(),
+		Max:     sorted[len(sorted)-1].Seconds(),
+	}
+}
+
+// percentile returns the p-th percentile (nearest-rank method) of the given sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func printBenchResults(results []benchStageResult, outputFormat string) {
+	switch strings.ToLower(outputFormat) {
+	case "json":
+		b, err := json.Marshal(results)
+		if err != nil {
+			l.Log().Fatalln(err)
+		}
+		fmt.Println(string(b))
+	case "yaml":
+		b, err := yaml.Marshal(results)
+		if err != nil {
+			l.Log().Fatalln(err)
+		}
+		fmt.Println(string(b))
+	default:
+		tabwriter := tabwriter.NewWriter(os.Stdout, 6, 4, 3, ' ', tabwriter.RememberWidths)
+		defer tabwriter.Flush()
+		fmt.Fprintf(tabwriter, "%s\n", strings.Join([]string{"STAGE", "SAMPLES", "P50", "P95", "MIN", "MAX"}, "\t"))
+		for _, r := range results {
+			fmt.Fprintf(tabwriter, "%s\t%d\t%s\t%s\t%s\t%s\n", r.Stage, r.Samples, seconds(r.P50), seconds(r.P95), seconds(r.Min), seconds(r.Max))
+		}
+	}
+}
+
+func seconds(s float64) string {
+	return fmt.Sprintf("%.2fs", s)
+}
@@ -0,0 +1,53 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package bench
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	testCases := []struct {
+		name     string
+		sorted   []time.Duration
+		p        float64
+		expected time.Duration
+	}{
+		{name: "no samples", sorted: nil, p: 50, expected: 0},
+		{name: "one sample p50", sorted: []time.Duration{3 * time.Second}, p: 50, expected: 3 * time.Second},
+		{name: "one sample p95", sorted: []time.Duration{3 * time.Second}, p: 95, expected: 3 * time.Second},
+		{name: "two samples p50", sorted: []time.Duration{1 * time.Second, 2 * time.Second}, p: 50, expected: 1 * time.Second},
+		{name: "two samples p95", sorted: []time.Duration{1 * time.Second, 2 * time.Second}, p: 95, expected: 2 * time.Second},
+		{name: "ten samples p50", sorted: []time.Duration{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, p: 50, expected: 5},
+		{name: "ten samples p95", sorted: []time.Duration{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, p: 95, expected: 10},
+		{name: "p0", sorted: []time.Duration{1, 2, 3}, p: 0, expected: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := percentile(tc.sorted, tc.p); got != tc.expected {
+				t.Errorf("expected p%v of %v to be %v, got %v", tc.p, tc.sorted, tc.expected, got)
+			}
+		})
+	}
+}
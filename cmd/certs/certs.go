@@ -0,0 +1,183 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package certs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/liggitt/tabwriter"
+	"github.com/rancher/k3d/v5/cmd/util"
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+type certsInfoFlags struct {
+	name     string
+	noHeader bool
+	output   string
+}
+
+// NewCmdCerts returns a new cobra command
+func NewCmdCerts() *cobra.Command {
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:   "certs",
+		Short: "Inspect and rotate the certificates of a cluster",
+		Long:  `Inspect and rotate the certificates generated by k3s`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := cmd.Help(); err != nil {
+				l.Log().Errorln("Couldn't get help text")
+				l.Log().Fatalln(err)
+			}
+		},
+	}
+
+	// add subcommands
+	cmd.AddCommand(NewCmdCertsInfo())
+	cmd.AddCommand(NewCmdCertsRotate())
+
+	// done
+	return cmd
+}
+
+// NewCmdCertsInfo returns a new cobra command
+func NewCmdCertsInfo() *cobra.Command {
+
+	flags := certsInfoFlags{}
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:   "info [--name CLUSTERNAME]",
+		Short: "Show the expiry of the certificates generated by k3s",
+		Long:  `Show the expiry of the certificates generated by k3s in all server and agent nodes of a cluster`,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			certs, err := client.ClusterCertificatesGet(cmd.Context(), runtimes.SelectedRuntime, &k3d.Cluster{Name: flags.name})
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+			printCertificates(certs, flags)
+		},
+	}
+
+	// add flags
+	cmd.Flags().StringVarP(&flags.name, "name", "n", k3d.DefaultClusterName, "Name of the cluster")
+	if err := cmd.RegisterFlagCompletionFunc("name", util.ValidArgsAvailableClusters); err != nil {
+		l.Log().Fatalln("Failed to register flag completion for '--name'", err)
+	}
+	cmd.Flags().BoolVar(&flags.noHeader, "no-headers", false, "Disable headers")
+	cmd.Flags().StringVarP(&flags.output, "output", "o", "", "Output format. One of: json|yaml")
+
+	// done
+	return cmd
+}
+
+// NewCmdCertsRotate returns a new cobra command
+func NewCmdCertsRotate() *cobra.Command {
+
+	var name string
+	writeKubeConfigOptions := client.WriteKubeConfigOptions{
+		UpdateExisting:       true,
+		UpdateCurrentContext: false,
+		OverwriteExisting:    false,
+	}
+	var updateKubeconfig bool
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:   "rotate [--name CLUSTERNAME]",
+		Short: "Regenerate the certificates of a cluster",
+		Long: `Regenerate all leaf certificates of a cluster from the k3s CAs (similar to 'k3s certificate rotate').
+The cluster is restarted in the process and the default kubeconfig is refreshed afterwards, as the admin client certificate changes as well.
+The CAs themselves are not rotated, so kubeconfigs and tools only trusting the CA keep working.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			cluster := &k3d.Cluster{Name: name}
+			if err := client.ClusterCertificatesRotate(cmd.Context(), runtimes.SelectedRuntime, cluster); err != nil {
+				l.Log().Fatalln(err)
+			}
+			l.Log().Infof("Rotated certificates of cluster '%s'", cluster.Name)
+
+			if updateKubeconfig {
+				path, err := client.KubeconfigGetWrite(cmd.Context(), runtimes.SelectedRuntime, cluster, "", &writeKubeConfigOptions)
+				if err != nil {
+					l.Log().Fatalf("Failed to refresh kubeconfig: %v", err)
+				}
+				l.Log().Infof("Refreshed kubeconfig '%s'", path)
+			}
+		},
+	}
+
+	// add flags
+	cmd.Flags().StringVarP(&name, "name", "n", k3d.DefaultClusterName, "Name of the cluster")
+	if err := cmd.RegisterFlagCompletionFunc("name", util.ValidArgsAvailableClusters); err != nil {
+		l.Log().Fatalln("Failed to register flag completion for '--name'", err)
+	}
+	cmd.Flags().BoolVar(&updateKubeconfig, "kubeconfig-update-default", true, "Refresh the cluster's entry in the default kubeconfig")
+
+	// done
+	return cmd
+}
+
+func printCertificates(certs []*k3d.CertificateInfo, flags certsInfoFlags) {
+	switch strings.ToLower(flags.output) {
+	case "json":
+		b, err := json.Marshal(certs)
+		if err != nil {
+			l.Log().Fatalln(err)
+		}
+		fmt.Println(string(b))
+	case "yaml":
+		b, err := yaml.Marshal(certs)
+		if err != nil {
+			l.Log().Fatalln(err)
+		}
+		fmt.Println(string(b))
+	default:
+		tabwriter := tabwriter.NewWriter(os.Stdout, 6, 4, 3, ' ', tabwriter.RememberWidths)
+		defer tabwriter.Flush()
+		if !flags.noHeader {
+			fmt.Fprintf(tabwriter, "%s\n", strings.Join([]string{"NODE", "FILE", "SUBJECT", "CA", "EXPIRES", "REMAINING"}, "\t"))
+		}
+		for _, c := range certs {
+			fmt.Fprintf(tabwriter, "%s\t%s\t%s\t%t\t%s\t%s\n", c.Node, c.File, c.Subject, c.IsCA, c.NotAfter.Format(time.RFC3339), remaining(c.NotAfter))
+		}
+	}
+}
+
+// remaining formats the time left until the given expiry in days (or "EXPIRED")
+func remaining(notAfter time.Time) string {
+	left := time.Until(notAfter)
+	if left <= 0 {
+		return "EXPIRED"
+	}
+	return fmt.Sprintf("%dd", int(left.Hours()/24))
+}
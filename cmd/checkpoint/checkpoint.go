@@ -0,0 +1,106 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package checkpoint
+
+import (
+	"github.com/rancher/k3d/v5/cmd/util"
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdCheckpoint returns a new cobra command
+func NewCmdCheckpoint() *cobra.Command {
+
+	var name string
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:   "checkpoint [CLUSTERNAME] --name NAME",
+		Short: "Save the state of a cluster's nodes",
+		Long: `Save the state of a cluster's nodes to be able to return to it later via 'k3d rollback'.
+The node containers are committed to new images ('<node>:checkpoint-<name>') and the k3s data directories
+are archived to the k3d config directory. The cluster is stopped during the checkpoint.`,
+		Args:              cobra.MaximumNArgs(1), // 0 or 1 cluster name
+		ValidArgsFunction: util.ValidArgsAvailableClusters,
+		Run: func(cmd *cobra.Command, args []string) {
+			cluster := &k3d.Cluster{Name: k3d.DefaultClusterName}
+			if len(args) > 0 {
+				cluster.Name = args[0]
+			}
+
+			checkpoint, err := client.ClusterCheckpointCreate(cmd.Context(), runtimes.SelectedRuntime, cluster, name)
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+
+			l.Log().Infof("Created checkpoint '%s' of cluster '%s' (%d nodes)", checkpoint.Name, checkpoint.Cluster, len(checkpoint.Nodes))
+		},
+	}
+
+	// add flags
+	cmd.Flags().StringVarP(&name, "name", "n", "", "Name of the checkpoint")
+	if err := cmd.MarkFlagRequired("name"); err != nil {
+		l.Log().Fatalln("Failed to mark flag 'name' as required")
+	}
+
+	// done
+	return cmd
+}
+
+// NewCmdRollback returns a new cobra command
+func NewCmdRollback() *cobra.Command {
+
+	var name string
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:               "rollback [CLUSTERNAME] --name NAME",
+		Short:             "Return a cluster to a checkpoint",
+		Long:              `Return a cluster to a checkpoint created via 'k3d checkpoint': the k3s nodes are re-created from the saved state.`,
+		Args:              cobra.MaximumNArgs(1), // 0 or 1 cluster name
+		ValidArgsFunction: util.ValidArgsAvailableClusters,
+		Run: func(cmd *cobra.Command, args []string) {
+			cluster := &k3d.Cluster{Name: k3d.DefaultClusterName}
+			if len(args) > 0 {
+				cluster.Name = args[0]
+			}
+
+			if err := client.ClusterCheckpointRollback(cmd.Context(), runtimes.SelectedRuntime, cluster, name); err != nil {
+				l.Log().Fatalln(err)
+			}
+
+			l.Log().Infof("Rolled back cluster '%s' to checkpoint '%s'", cluster.Name, name)
+		},
+	}
+
+	// add flags
+	cmd.Flags().StringVarP(&name, "name", "n", "", "Name of the checkpoint")
+	if err := cmd.MarkFlagRequired("name"); err != nil {
+		l.Log().Fatalln("Failed to mark flag 'name' as required")
+	}
+
+	// done
+	return cmd
+}
@@ -0,0 +1,95 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package checkpoint
+
+import (
+	"github.com/rancher/k3d/v5/cmd/util"
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+const freezeRequirements = `
+Requires a container runtime with experimental checkpoint support, i.e. for docker:
+	- CRIU installed on the docker host
+	- "experimental": true in the docker daemon.json`
+
+// NewCmdFreeze returns a new cobra command
+func NewCmdFreeze() *cobra.Command {
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:               "freeze [CLUSTERNAME [CLUSTERNAME ...]]",
+		Short:             "[Experimental] Suspend cluster(s) including the memory state of running pods (CRIU)",
+		Long:              `[Experimental] Suspend cluster(s) including the memory state of running pods (CRIU), so they can be resumed instantly via 'k3d thaw'.` + freezeRequirements,
+		ValidArgsFunction: util.ValidArgsAvailableClusters,
+		Run: func(cmd *cobra.Command, args []string) {
+			for _, cluster := range clustersFromArgs(args) {
+				if err := client.ClusterFreeze(cmd.Context(), runtimes.SelectedRuntime, cluster); err != nil {
+					l.Log().Fatalln(err)
+				}
+				l.Log().Infof("Froze cluster '%s'", cluster.Name)
+			}
+		},
+	}
+
+	// done
+	return cmd
+}
+
+// NewCmdThaw returns a new cobra command
+func NewCmdThaw() *cobra.Command {
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:               "thaw [CLUSTERNAME [CLUSTERNAME ...]]",
+		Short:             "[Experimental] Resume cluster(s) suspended via 'k3d freeze'",
+		Long:              `[Experimental] Resume cluster(s) suspended via 'k3d freeze'.` + freezeRequirements,
+		ValidArgsFunction: util.ValidArgsAvailableClusters,
+		Run: func(cmd *cobra.Command, args []string) {
+			for _, cluster := range clustersFromArgs(args) {
+				if err := client.ClusterThaw(cmd.Context(), runtimes.SelectedRuntime, cluster); err != nil {
+					l.Log().Fatalln(err)
+				}
+				l.Log().Infof("Thawed cluster '%s'", cluster.Name)
+			}
+		},
+	}
+
+	// done
+	return cmd
+}
+
+// clustersFromArgs returns the clusters referenced by name (default cluster if none given)
+func clustersFromArgs(args []string) []*k3d.Cluster {
+	names := []string{k3d.DefaultClusterName}
+	if len(args) > 0 {
+		names = args
+	}
+	clusters := make([]*k3d.Cluster, 0, len(names))
+	for _, name := range names {
+		clusters = append(clusters, &k3d.Cluster{Name: name})
+	}
+	return clusters
+}
@@ -32,9 +32,10 @@ func NewCmdCluster() *cobra.Command {
 
 	// create new cobra command
 	cmd := &cobra.Command{
-		Use:   "cluster",
-		Short: "Manage cluster(s)",
-		Long:  `Manage cluster(s)`,
+		Use:     "cluster",
+		Aliases: []string{"clusters"},
+		Short:   "Manage cluster(s)",
+		Long:    `Manage cluster(s)`,
 		Run: func(cmd *cobra.Command, args []string) {
 			if err := cmd.Help(); err != nil {
 				l.Log().Errorln("Couldn't get help text")
@@ -50,6 +51,9 @@ func NewCmdCluster() *cobra.Command {
 	cmd.AddCommand(NewCmdClusterDelete())
 	cmd.AddCommand(NewCmdClusterList())
 	cmd.AddCommand(NewCmdClusterEdit())
+	cmd.AddCommand(NewCmdClusterEndpoints())
+	cmd.AddCommand(NewCmdClusterInspect())
+	cmd.AddCommand(NewCmdClusterAnnotate())
 
 	// add flags
 
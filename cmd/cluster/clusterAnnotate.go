@@ -0,0 +1,65 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cluster
+
+import (
+	"github.com/rancher/k3d/v5/cmd/util"
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdClusterAnnotate returns a new cobra command
+func NewCmdClusterAnnotate() *cobra.Command {
+	var description string
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:   "annotate CLUSTER --description TEXT",
+		Short: "Change the description of a cluster",
+		Long: `Change the description of a cluster, e.g. to document what it's used for on a shared host.
+The description is shown by 'k3d cluster list'. Use --description "" to clear it.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: util.ValidArgsAvailableClusters,
+		Run: func(cmd *cobra.Command, args []string) {
+			cluster, err := client.ClusterGet(cmd.Context(), runtimes.SelectedRuntime, &k3d.Cluster{Name: args[0]})
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+			if err := client.ClusterAnnotate(cmd.Context(), runtimes.SelectedRuntime, cluster, description); err != nil {
+				l.Log().Fatalln(err)
+			}
+			l.Log().Infof("Updated the description of cluster '%s'", cluster.Name)
+		},
+	}
+
+	// add flags
+	cmd.Flags().StringVar(&description, "description", "", "New description of the cluster")
+	if err := cmd.MarkFlagRequired("description"); err != nil {
+		l.Log().Fatalln("Failed to mark required flag '--description'")
+	}
+
+	// done
+	return cmd
+}
@@ -0,0 +1,154 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cluster
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	cliutil "github.com/rancher/k3d/v5/cmd/util"
+	k3dCluster "github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/rancher/k3d/v5/version"
+)
+
+// NewCmdBackup returns a new cobra command
+func NewCmdBackup() *cobra.Command {
+
+	var output string
+	var force bool
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:   "backup [CLUSTERNAME]",
+		Short: "Write a backup of a cluster to an archive, which 'k3d restore' turns into a cluster again",
+		Long: `Write a backup of a cluster to a gzipped tar archive, which 'k3d restore' turns into a cluster again (e.g. on another machine).
+The archive contains the config the cluster was created with (see 'k3d record'), the cluster token and a copy of the server's SQLite datastore.
+The cluster is stopped while the datastore is copied and restarted afterwards.
+Only clusters with a single server and the default SQLite datastore (no embedded etcd or external datastore) are supported.
+Images are not part of the backup: the restored cluster pulls them again (or use 'k3d image import').
+The archive contains the cluster token, so treat it like a secret.`,
+		Example: `  k3d backup mycluster -o mycluster.tar.gz
+  k3d restore mycluster.tar.gz`,
+		Args:              cobra.MaximumNArgs(1), // 0 or 1 cluster name
+		ValidArgsFunction: cliutil.ValidArgsAvailableClusters,
+		Run: func(cmd *cobra.Command, args []string) {
+			cluster := &k3d.Cluster{Name: k3d.DefaultClusterName}
+			if len(args) > 0 {
+				cluster.Name = args[0]
+			}
+			if output == "" {
+				output = fmt.Sprintf("%s-%s-backup.tar.gz", k3d.DefaultObjectNamePrefix, cluster.Name)
+			}
+			if _, err := os.Stat(output); err == nil && !force {
+				l.Log().Fatalf("Output file '%s' exists and --force was not set", output)
+			} else if err != nil && !os.IsNotExist(err) {
+				l.Log().Fatalf("Failed to stat output file: %+v", err)
+			}
+
+			archive, err := os.OpenFile(output, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+			if err != nil {
+				l.Log().Fatalf("Failed to create output file: %v", err)
+			}
+			backup, err := k3dCluster.ClusterBackup(cmd.Context(), runtimes.SelectedRuntime, cluster, archive)
+			if closeErr := archive.Close(); err == nil && closeErr != nil {
+				err = fmt.Errorf("failed to write output file: %w", closeErr)
+			}
+			if err != nil {
+				if backup == nil {
+					if removeErr := os.Remove(output); removeErr != nil {
+						l.Log().Warnf("Failed to remove incomplete output file '%s': %v", output, removeErr)
+					}
+				}
+				l.Log().Fatalln(err)
+			}
+			l.Log().Infof("Wrote backup of cluster '%s' to '%s': restore it with 'k3d restore %s'", cluster.Name, output, output)
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Write the backup to this path (Format: `FILE`, default: k3d-CLUSTERNAME-backup.tar.gz)")
+	if err := cmd.MarkFlagFilename("output", "tar.gz", "tgz"); err != nil {
+		l.Log().Fatalf("Failed to mark flag 'output' as filename flag: %v", err)
+	}
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Force overwrite of the output file")
+
+	// done
+	return cmd
+}
+
+// NewCmdRestore returns a new cobra command
+func NewCmdRestore() *cobra.Command {
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:   "restore FILE [CLUSTERNAME]",
+		Short: "Create a cluster from a backup written by 'k3d backup'",
+		Long: `Create a cluster from a backup written by 'k3d backup': it's created with the recorded config (name, ports, ...) and token of the original cluster
+and starts from the backed up datastore (like 'k3d cluster create --config CONFIG --token TOKEN --from-backup DATASTORE [CLUSTERNAME]').
+Warns if the backup was written by a different version of k3d, as defaults (e.g. the k3s image) may have changed in between.`,
+		Args: cobra.RangeArgs(1, 2),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) == 0 {
+				return []string{"tar.gz", "tgz"}, cobra.ShellCompDirectiveFilterFileExt
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			archive, err := os.Open(args[0])
+			if err != nil {
+				l.Log().Fatalf("Failed to open backup: %v", err)
+			}
+			defer archive.Close()
+
+			dir, err := ioutil.TempDir("", "k3d-restore-")
+			if err != nil {
+				l.Log().Fatalf("Failed to create temporary directory: %v", err)
+			}
+			defer os.RemoveAll(dir)
+
+			backup, err := k3dCluster.ClusterBackupExtract(archive, dir)
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+			if backup.K3dVersion != version.GetVersion() {
+				l.Log().Warnf("'%s' was written by k3d %s, but this is k3d %s: the resulting cluster may differ", args[0], backup.K3dVersion, version.GetVersion())
+			}
+			l.Log().Infof("Restoring cluster '%s' backed up at %s", backup.Cluster, backup.Created.Format("2006-01-02 15:04:05"))
+
+			configFile = backup.ConfigPath
+			if err := initConfig(); err != nil {
+				l.Log().Fatalln(err)
+			}
+			cfgViper.Set("token", backup.Token)
+			cfgViper.Set("options.k3s.fromBackup", backup.DatastorePath)
+			runClusterCreate(cmd, args[1:])
+		},
+	}
+
+	// done
+	return cmd
+}
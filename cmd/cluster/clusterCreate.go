@@ -23,9 +23,9 @@ THE SOFTWARE.
 package cluster
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"runtime"
 	"strconv"
 	"strings"
 	"time"
@@ -34,6 +34,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"gopkg.in/yaml.v2"
 
@@ -49,6 +50,8 @@ import (
 )
 
 var configFile string
+var clusterCreateTimings string
+var clusterCreateCIOutputFiles []string
 
 const clusterCreateDescription = `
 Create a new k3s cluster with containerized nodes (k3s in docker).
@@ -94,132 +97,12 @@ func NewCmdClusterCreate() *cobra.Command {
 		Long:  clusterCreateDescription,
 		Args:  cobra.RangeArgs(0, 1), // exactly one cluster name can be set (default: k3d.DefaultClusterName)
 		PreRunE: func(cmd *cobra.Command, args []string) error {
-			return initConfig()
-		},
-		Run: func(cmd *cobra.Command, args []string) {
-
-			/*************************
-			 * Compute Configuration *
-			 *************************/
-			if cfgViper.GetString("apiversion") == "" {
-				cfgViper.Set("apiversion", config.DefaultConfigApiVersion)
-			}
-			if cfgViper.GetString("kind") == "" {
-				cfgViper.Set("kind", "Simple")
-			}
-			cfg, err := config.FromViper(cfgViper)
-			if err != nil {
-				l.Log().Fatalln(err)
-			}
-
-			if cfg.GetAPIVersion() != config.DefaultConfigApiVersion {
-				l.Log().Warnf("Default config apiVersion is '%s', but you're using '%s': consider migrating.", config.DefaultConfigApiVersion, cfg.GetAPIVersion())
-				cfg, err = config.Migrate(cfg, config.DefaultConfigApiVersion)
-				if err != nil {
-					l.Log().Fatalln(err)
-				}
-			}
-
-			simpleCfg := cfg.(conf.SimpleConfig)
-
-			l.Log().Debugf("========== Simple Config ==========\n%+v\n==========================\n", simpleCfg)
-
-			simpleCfg, err = applyCLIOverrides(simpleCfg)
-			if err != nil {
-				l.Log().Fatalf("Failed to apply CLI overrides: %+v", err)
-			}
-
-			l.Log().Debugf("========== Merged Simple Config ==========\n%+v\n==========================\n", simpleCfg)
-
-			/**************************************
-			 * Transform, Process & Validate Configuration *
-			 **************************************/
-
-			// Set the name
-			if len(args) != 0 {
-				simpleCfg.Name = args[0]
-			}
-
-			clusterConfig, err := config.TransformSimpleToClusterConfig(cmd.Context(), runtimes.SelectedRuntime, simpleCfg)
-			if err != nil {
-				l.Log().Fatalln(err)
-			}
-			l.Log().Debugf("===== Merged Cluster Config =====\n%+v\n===== ===== =====\n", clusterConfig)
-
-			clusterConfig, err = config.ProcessClusterConfig(*clusterConfig)
-			if err != nil {
-				l.Log().Fatalln(err)
+			if cmd.Flags().Changed("no-schedule-on-server") && cmd.Flags().Changed("schedule-on-server") {
+				return fmt.Errorf("--no-schedule-on-server and --schedule-on-server are mutually exclusive")
 			}
-			l.Log().Debugf("===== Processed Cluster Config =====\n%+v\n===== ===== =====\n", clusterConfig)
-
-			if err := config.ValidateClusterConfig(cmd.Context(), runtimes.SelectedRuntime, *clusterConfig); err != nil {
-				l.Log().Fatalln("Failed Cluster Configuration Validation: ", err)
-			}
-
-			/**************************************
-			 * Create cluster if it doesn't exist *
-			 **************************************/
-
-			// check if a cluster with that name exists already
-			if _, err := k3dCluster.ClusterGet(cmd.Context(), runtimes.SelectedRuntime, &clusterConfig.Cluster); err == nil {
-				l.Log().Fatalf("Failed to create cluster '%s' because a cluster with that name already exists", clusterConfig.Cluster.Name)
-			}
-
-			// create cluster
-			if clusterConfig.KubeconfigOpts.UpdateDefaultKubeconfig {
-				l.Log().Debugln("'--kubeconfig-update-default set: enabling wait-for-server")
-				clusterConfig.ClusterCreateOpts.WaitForServer = true
-			}
-			//if err := k3dCluster.ClusterCreate(cmd.Context(), runtimes.SelectedRuntime, &clusterConfig.Cluster, &clusterConfig.ClusterCreateOpts); err != nil {
-			if err := k3dCluster.ClusterRun(cmd.Context(), runtimes.SelectedRuntime, clusterConfig); err != nil {
-				// rollback if creation failed
-				l.Log().Errorln(err)
-				if simpleCfg.Options.K3dOptions.NoRollback { // TODO: move rollback mechanics to pkg/
-					l.Log().Fatalln("Cluster creation FAILED, rollback deactivated.")
-				}
-				// rollback if creation failed
-				l.Log().Errorln("Failed to create cluster >>> Rolling Back")
-				if err := k3dCluster.ClusterDelete(cmd.Context(), runtimes.SelectedRuntime, &clusterConfig.Cluster, k3d.ClusterDeleteOpts{SkipRegistryCheck: true}); err != nil {
-					l.Log().Errorln(err)
-					l.Log().Fatalln("Cluster creation FAILED, also FAILED to rollback changes!")
-				}
-				l.Log().Fatalln("Cluster creation FAILED, all changes have been rolled back!")
-			}
-			l.Log().Infof("Cluster '%s' created successfully!", clusterConfig.Cluster.Name)
-
-			/**************
-			 * Kubeconfig *
-			 **************/
-
-			if clusterConfig.KubeconfigOpts.UpdateDefaultKubeconfig && clusterConfig.KubeconfigOpts.SwitchCurrentContext {
-				l.Log().Infoln("--kubeconfig-update-default=false --> sets --kubeconfig-switch-context=false")
-				clusterConfig.KubeconfigOpts.SwitchCurrentContext = false
-			}
-
-			if clusterConfig.KubeconfigOpts.UpdateDefaultKubeconfig {
-				l.Log().Debugf("Updating default kubeconfig with a new context for cluster %s", clusterConfig.Cluster.Name)
-				if _, err := k3dCluster.KubeconfigGetWrite(cmd.Context(), runtimes.SelectedRuntime, &clusterConfig.Cluster, "", &k3dCluster.WriteKubeConfigOptions{UpdateExisting: true, OverwriteExisting: false, UpdateCurrentContext: simpleCfg.Options.KubeconfigOptions.SwitchCurrentContext}); err != nil {
-					l.Log().Warningln(err)
-				}
-			}
-
-			/*****************
-			 * User Feedback *
-			 *****************/
-
-			// print information on how to use the cluster with kubectl
-			l.Log().Infoln("You can now use it like this:")
-			if clusterConfig.KubeconfigOpts.UpdateDefaultKubeconfig && !clusterConfig.KubeconfigOpts.SwitchCurrentContext {
-				fmt.Printf("kubectl config use-context %s\n", fmt.Sprintf("%s-%s", k3d.DefaultObjectNamePrefix, clusterConfig.Cluster.Name))
-			} else if !clusterConfig.KubeconfigOpts.SwitchCurrentContext {
-				if runtime.GOOS == "windows" {
-					fmt.Printf("$env:KUBECONFIG=(%s kubeconfig write %s)\n", os.Args[0], clusterConfig.Cluster.Name)
-				} else {
-					fmt.Printf("export KUBECONFIG=$(%s kubeconfig write %s)\n", os.Args[0], clusterConfig.Cluster.Name)
-				}
-			}
-			fmt.Println("kubectl cluster-info")
+			return initConfig()
 		},
+		Run: runClusterCreate,
 	}
 
 	/***************
@@ -231,6 +114,10 @@ func NewCmdClusterCreate() *cobra.Command {
 		l.Log().Fatalln("Failed to mark flag 'config' as filename flag")
 	}
 
+	cmd.Flags().StringVar(&clusterCreateTimings, "timings", "", "Write a JSON report of the creation stage durations, nodes, ports and kubeconfig path to stdout or, if a path is given (Format: `--timings=FILE`), to a file (e.g. for tracking cluster boot times in CI)")
+	cmd.Flags().Lookup("timings").NoOptDefVal = "-"
+	cmd.Flags().StringArrayVar(&clusterCreateCIOutputFiles, "ci-output-file", nil, "Append the results as `KEY=VALUE` lines (KUBECONFIG, K3D_CLUSTER) to a file, e.g. for passing them to later CI steps (use flag multiple times)\n - Example: `k3d cluster create --ci --ci-output-file \"$GITHUB_ENV\" --ci-output-file \"$GITHUB_OUTPUT\"`")
+
 	/***********************
 	 * Pre-Processed Flags *
 	 ***********************
@@ -250,13 +137,16 @@ func NewCmdClusterCreate() *cobra.Command {
 	cmd.Flags().String("api-port", "", "Specify the Kubernetes API server port exposed on the LoadBalancer (Format: `[HOST:]HOSTPORT`)\n - Example: `k3d cluster create --servers 3 --api-port 0.0.0.0:6550`")
 	_ = ppViper.BindPFlag("cli.api-port", cmd.Flags().Lookup("api-port"))
 
+	cmd.Flags().Bool("api-tunnel", false, "Reach the Kubernetes API through an SSH port-forward to the remote docker host (DOCKER_HOST=ssh://...), instead of publishing it on the remote host's network (the tunnel runs in the background and is re-opened by 'k3d cluster start')")
+	_ = cfgViper.BindPFlag("kubeapi.tunnel", cmd.Flags().Lookup("api-tunnel"))
+
 	cmd.Flags().StringArrayP("env", "e", nil, "Add environment variables to nodes (Format: `KEY[=VALUE][@NODEFILTER[;NODEFILTER...]]`\n - Example: `k3d cluster create --agents 2 -e \"HTTP_PROXY=my.proxy.com@server:0\" -e \"SOME_KEY=SOME_VAL@server:0\"`")
 	_ = ppViper.BindPFlag("cli.env", cmd.Flags().Lookup("env"))
 
-	cmd.Flags().StringArrayP("volume", "v", nil, "Mount volumes into the nodes (Format: `[SOURCE:]DEST[@NODEFILTER[;NODEFILTER...]]`\n - Example: `k3d cluster create --agents 2 -v /my/path@agent:0,1 -v /tmp/test:/tmp/other@server:0`")
+	cmd.Flags().StringArrayP("volume", "v", nil, "Mount volumes into the nodes (Format: `[SOURCE:]DEST[@NODEFILTER[;NODEFILTER...]]`\n - Example: `k3d cluster create --agents 2 -v /my/path@agent:0,1 -v /tmp/test:/tmp/other@server:0`\n - Windows paths (e.g. `C:\\Users\\me:/data`) are translated to their WSL mount point (e.g. `/mnt/c/Users/me`) when running in WSL")
 	_ = ppViper.BindPFlag("cli.volumes", cmd.Flags().Lookup("volume"))
 
-	cmd.Flags().StringArrayP("port", "p", nil, "Map ports from the node containers (via the serverlb) to the host (Format: `[HOST:][HOSTPORT:]CONTAINERPORT[/PROTOCOL][@NODEFILTER]`)\n - Example: `k3d cluster create --agents 2 -p 8080:80@agent:0 -p 8081@agent:1`")
+	cmd.Flags().StringArrayP("port", "p", nil, "Map ports from the node containers (via the serverlb) to the host (Format: `[HOST:][HOSTPORT:]CONTAINERPORT[/PROTOCOL][@NODEFILTER]`, PROTOCOL is tcp (default), udp or sctp, which the serverlb can't proxy, so it has to be mapped with the 'direct' suffix; a direct mapping on multiple nodes gives each node its own free host port, counting upwards from HOSTPORT)\n - Example: `k3d cluster create --agents 2 -p 8080:80@agent:0 -p 8081@agent:1 -p 8000-8010:8000-8010@server:0 -p 5353:53/udp@agent:0 -p 9999:9999/sctp@agent:1:direct -p 9100:9100@agent:*:direct`")
 	_ = ppViper.BindPFlag("cli.ports", cmd.Flags().Lookup("port"))
 
 	cmd.Flags().StringArrayP("k3s-node-label", "", nil, "Add label to k3s node (Format: `KEY[=VALUE][@NODEFILTER[;NODEFILTER...]]`\n - Example: `k3d cluster create --agents 2 --k3s-node-label \"my.label@agent:0,1\" --k3s-node-label \"other.label=somevalue@server:0\"`")
@@ -265,10 +155,99 @@ func NewCmdClusterCreate() *cobra.Command {
 	cmd.Flags().StringArrayP("runtime-label", "", nil, "Add label to container runtime (Format: `KEY[=VALUE][@NODEFILTER[;NODEFILTER...]]`\n - Example: `k3d cluster create --agents 2 --runtime-label \"my.label@agent:0,1\" --runtime-label \"other.label=somevalue@server:0\"`")
 	_ = ppViper.BindPFlag("cli.runtime-labels", cmd.Flags().Lookup("runtime-label"))
 
+	cmd.Flags().StringArray("secret", nil, "Create a Secret in the cluster right after it started (Format: `NAME=SOURCE[:NAMESPACE]`, SOURCE is a .env file with one KEY=VALUE per line or any other file)\n - Example: `k3d cluster create --secret db-credentials=./db.env:myapp`")
+	_ = ppViper.BindPFlag("cli.secrets", cmd.Flags().Lookup("secret"))
+
+	cmd.Flags().String("audit-policy", "", "Enable audit logging in the API server with the given audit policy file (Format: `FILE`, follow the log using 'k3d audit tail')\n - Example: `k3d cluster create --audit-policy ./policy.yaml`")
+	_ = cfgViper.BindPFlag("options.k3s.auditpolicy", cmd.Flags().Lookup("audit-policy"))
+
+	cmd.Flags().StringArray("manifest", nil, "Let k3s deploy a Kubernetes manifest (or all *.yaml, *.yml and *.json files in a directory) on startup, e.g. a CNI or an ingress controller (Format: `PATH`, use flag multiple times)\n - Example: `k3d cluster create --manifest ./deploy/ingress.yaml --manifest ./deploy/apps/`")
+	_ = cfgViper.BindPFlag("options.k3s.manifests", cmd.Flags().Lookup("manifest"))
+
+	cmd.Flags().StringArray("hook", nil, "Run a command on the host at a phase of the cluster's lifecycle, with $K3D_CLUSTER, $K3D_HOOK_PHASE and (after preCreate) $KUBECONFIG set (Format: `PHASE:COMMAND`, PHASE one of preCreate, postServerReady, postClusterReady, preDelete; use flag multiple times)\n - Example: `k3d cluster create --hook 'postClusterReady:kubectl apply -k ./overlays/dev'`")
+
+	cmd.Flags().String("from-backup", "", "Start the server with a backup of the SQLite datastore of another (single-server) cluster instead of an empty one, e.g. to rehearse disaster recovery (Format: `FILE`, a copy of the server's /var/lib/rancher/k3s/server/db/state.db; use the original cluster's --token)\n - Example: `k3d cluster create restored --from-backup ./state.db --token $TOKEN`")
+	_ = cfgViper.BindPFlag("options.k3s.frombackup", cmd.Flags().Lookup("from-backup"))
+
+	cmd.Flags().StringArray("configmap", nil, "Create a ConfigMap in the cluster right after it started (Format: `NAME=SOURCE[:NAMESPACE]`, SOURCE is a .env file with one KEY=VALUE per line or any other file)\n - Example: `k3d cluster create --configmap app-config=./config.yaml`")
+	_ = ppViper.BindPFlag("cli.configmaps", cmd.Flags().Lookup("configmap"))
+
+	cmd.Flags().StringArray("custom-ca", nil, "Let k3s sign its serving certificates with your own CA instead of generating one (Format: `FILE`, use flag twice: certificate first, then key)\n - Example: `k3d cluster create --custom-ca ./ca.crt --custom-ca ./ca.key`")
+	_ = ppViper.BindPFlag("cli.custom-ca", cmd.Flags().Lookup("custom-ca"))
+
+	cmd.Flags().StringArray("trust-ca", nil, "Add a PEM-encoded CA certificate to the system trust store of the nodes, e.g. for pulling images through TLS-intercepting proxies (Format: `FILE`, use flag multiple times)\n - Example: `k3d cluster create --trust-ca ./corp-root.pem`")
+	_ = cfgViper.BindPFlag("options.k3d.trustcas", cmd.Flags().Lookup("trust-ca"))
+
+	cmd.Flags().String("node-init", "", "Execute a shell script inside each server and agent node before k3s starts (on every start), e.g. to install CA certificates, tune sysctls or add debugging tools without building a custom image (Format: `FILE`)\n - Example: `k3d cluster create --node-init ./init.sh`")
+	_ = cfgViper.BindPFlag("options.k3d.nodeinit", cmd.Flags().Lookup("node-init"))
+
+	cmd.Flags().Bool("host-dns", false, "Use the nameservers and search domains of the host's resolv.conf (without loopback resolvers like systemd-resolved's stub) in the nodes and as CoreDNS upstream, e.g. to resolve internal chart/image hosts behind a corporate VPN\n - Example: `k3d cluster create --host-dns`")
+	_ = cfgViper.BindPFlag("options.k3d.hostdns", cmd.Flags().Lookup("host-dns"))
+
+	cmd.Flags().Bool("api-dns", false, "Access the Kubernetes API via the stable name <cluster>.k3d.internal (added to the hosts file) on a loopback address derived from the cluster name and the default port 6443, so the kubeconfig stays valid when the cluster is recreated (Linux only, elsewhere only the name is stable)\n - Example: `k3d cluster create dev --api-dns` -> `https://dev.k3d.internal:6443`")
+	_ = cfgViper.BindPFlag("options.k3d.apidns", cmd.Flags().Lookup("api-dns"))
+
+	cmd.Flags().Bool("fix-sysctls", false, "Raise the kernel parameters of the container host, which k3d warns about if they are below the recommended values (kernel.pid_max, fs.inotify.max_user_watches, fs.inotify.max_user_instances, fs.file-max), via the privileged nodes (not permitted e.g. with rootless runtimes)\n - Example: `k3d cluster create --fix-sysctls`")
+	_ = cfgViper.BindPFlag("options.k3d.fixsysctls", cmd.Flags().Lookup("fix-sysctls"))
+
+	cmd.Flags().Bool("image-cache", false, "Import the system images (pause, coredns, ...) from a host-wide cache volume per k3s image instead of pulling them in every node: the first cluster using a k3s image fills its cache, all later ones boot faster (remove the cache with 'docker volume rm')\n - Example: `k3d cluster create --agents 3 --image-cache`")
+	_ = cfgViper.BindPFlag("options.k3d.imagecache", cmd.Flags().Lookup("image-cache"))
+
+	cmd.Flags().StringArray("cache-volume", nil, "Mount a named cache volume (e.g. a Go module or npm cache) into all server and agent nodes, which is kept when the cluster is deleted, so a re-created cluster of the same name gets it back (Format: `NAME:PATH`, use flag multiple times; remove it with 'docker volume rm k3d-CLUSTER-cache-NAME')\n - Example: `k3d cluster create --cache-volume gomod:/root/go/pkg/mod --cache-volume npm:/root/.npm`")
+	_ = cfgViper.BindPFlag("options.k3d.cachevolumes", cmd.Flags().Lookup("cache-volume"))
+
+	cmd.Flags().Bool("defer-workers", false, "Start the agent nodes only after the servers passed their readiness checks, so that they don't retry (and back off) their registration against a server that's still booting, which slows down the overall startup of larger clusters\n - Example: `k3d cluster create --agents 5 --defer-workers`")
+	_ = cfgViper.BindPFlag("options.k3d.deferworkers", cmd.Flags().Lookup("defer-workers"))
+
+	cmd.Flags().Bool("no-hostip", false, "Disable the automatic injection of the Host IP as 'host.k3d.internal' into the containers and CoreDNS, which lets workloads reach services running on the docker host (also applies to later starts of the cluster)")
+	_ = cfgViper.BindPFlag("options.k3d.disablehostipinjection", cmd.Flags().Lookup("no-hostip"))
+
+	cmd.Flags().Bool("resolve-digest", false, "Resolve the k3s image tag(s) to the digest they currently point to and use that for all nodes, incl. those added later and clusters replayed from 'k3d record', so that every node runs the very same k3s build\n - Example: `k3d cluster create --image rancher/k3s:v1.21.4-k3s1 --resolve-digest`")
+	_ = cfgViper.BindPFlag("options.k3d.resolvedigest", cmd.Flags().Lookup("resolve-digest"))
+
+	cmd.Flags().Bool("no-start", false, "Create all containers, networks and volumes, but leave the nodes stopped (warm standby), so that 'k3d cluster start' brings the cluster up in seconds when it's needed\n - Example: `k3d cluster create --no-start mycluster && k3d cluster start mycluster`")
+	_ = cfgViper.BindPFlag("options.k3d.nostart", cmd.Flags().Lookup("no-start"))
+
+	cmd.Flags().String("node-name-template", "", "Go template for the names (and hostnames) of server and agent nodes. Available fields: {{.Prefix}}, {{.Cluster}}, {{.Role}}, {{.Index}}\n - Default: `"+k3d.DefaultNodeNameTemplate+"`\n - Example: `k3d cluster create --agents 2 --node-name-template '{{.Cluster}}-{{.Role}}{{.Index}}'`")
+	_ = cfgViper.BindPFlag("options.k3d.nodenametemplate", cmd.Flags().Lookup("node-name-template"))
+
+	cmd.Flags().String("oidc-issuer-url", "", "Configure the Kubernetes API server to accept OIDC tokens from this issuer (also adds a '<context>-oidc' context using the kubectl oidc-login plugin to the kubeconfig)")
+	_ = cfgViper.BindPFlag("options.k3s.oidc.issuerurl", cmd.Flags().Lookup("oidc-issuer-url"))
+
+	cmd.Flags().String("oidc-client-id", "", "OIDC client ID, which all tokens must be issued for")
+	_ = cfgViper.BindPFlag("options.k3s.oidc.clientid", cmd.Flags().Lookup("oidc-client-id"))
+
+	cmd.Flags().String("oidc-username-claim", "", "OIDC claim to use as the user name (default: 'sub')")
+	_ = cfgViper.BindPFlag("options.k3s.oidc.usernameclaim", cmd.Flags().Lookup("oidc-username-claim"))
+
+	cmd.Flags().String("oidc-groups-claim", "", "OIDC claim to use as the user's groups")
+	_ = cfgViper.BindPFlag("options.k3s.oidc.groupsclaim", cmd.Flags().Lookup("oidc-groups-claim"))
+
+	cmd.Flags().StringSlice("feature-gates", nil, "Toggle Kubernetes feature gates in all components (API server, controller manager, scheduler, kubelet, kube-proxy) of all nodes (Format: `GATE=true|false[,GATE=true|false...]`)\n - Example: `k3d cluster create --feature-gates EphemeralContainers=true,GracefulNodeShutdown=false`")
+	_ = cfgViper.BindPFlag("options.k3s.featuregates", cmd.Flags().Lookup("feature-gates"))
+
+	cmd.Flags().StringArray("etcd-arg", nil, "Additional argument passed to the embedded etcd of multi-server clusters, overriding k3d's low-memory defaults (use flag multiple times)\n - Example: `k3d cluster create --servers 3 --etcd-arg snapshot-count=5000`")
+	_ = cfgViper.BindPFlag("options.k3s.etcdargs", cmd.Flags().Lookup("etcd-arg"))
+
+	cmd.Flags().String("cri", "", "Container runtime k3s runs the pods with inside the nodes: its embedded containerd or, for dockershim-compatible behavior of legacy workloads, a docker daemon started in the nodes via cri-dockerd (k3s' --docker), which requires a node image shipping dockerd (Format: `containerd|cri-dockerd`, default: containerd)\n - Example: `k3d cluster create --image my/k3s-with-docker:v1.24.4-k3s1 --cri cri-dockerd`")
+	_ = cfgViper.BindPFlag("options.k3s.cri", cmd.Flags().Lookup("cri"))
+
+	cmd.Flags().Bool("no-schedule-on-server", false, fmt.Sprintf("Taint the server nodes with '%s', so that regular workloads only run on agent nodes (like control-plane nodes in production clusters)", k3d.DefaultServerTaint))
+	_ = cfgViper.BindPFlag("options.k3s.noscheduleonserver", cmd.Flags().Lookup("no-schedule-on-server"))
+
+	cmd.Flags().Bool("schedule-on-server", false, "Let regular workloads run on the server nodes (the default), overriding 'noScheduleOnServer' from the config file")
+	_ = ppViper.BindPFlag("cli.schedule-on-server", cmd.Flags().Lookup("schedule-on-server"))
+
+	cmd.Flags().StringArray("fake-node-memory", nil, "Make the kubelet see the given memory capacity on the selected nodes without limiting the container (Format: `MEMORY[@NODEFILTER[;NODEFILTER...]]`)\n - Example: `k3d cluster create --agents 2 --fake-node-memory \"64Gi@agent:0\"`")
+	_ = ppViper.BindPFlag("cli.fake-node-memory", cmd.Flags().Lookup("fake-node-memory"))
+
 	cmd.Flags().String("registry-create", "", "Create a k3d-managed registry and connect it to the cluster (Format: `NAME[:HOST][:HOSTPORT]`\n - Example: `k3d cluster create --registry-create mycluster-registry:0.0.0.0:5432`")
 	_ = ppViper.BindPFlag("cli.registries.create", cmd.Flags().Lookup("registry-create"))
 
 	/* k3s */
+	cmd.Flags().StringArrayP("image", "i", nil, "Specify k3s image that you want to use for the nodes, optionally only for the nodes matching a node filter, e.g. to test version skew between servers and agents (Format: `IMAGE[@NODEFILTER[;NODEFILTER...]]`)\n - Example: `k3d cluster create --agents 2 --image rancher/k3s:v1.22.4-k3s1 --image rancher/k3s:v1.21.7-k3s1@agent:1`")
+	_ = ppViper.BindPFlag("cli.images", cmd.Flags().Lookup("image"))
+
 	cmd.Flags().StringArray("k3s-arg", nil, "Additional args passed to k3s command (Format: `ARG@NODEFILTER[;@NODEFILTER]`)\n - Example: `k3d cluster create --k3s-arg \"--disable=traefik@server:0\"")
 	_ = ppViper.BindPFlag("cli.k3sargs", cmd.Flags().Lookup("k3s-arg"))
 
@@ -281,6 +260,18 @@ func NewCmdClusterCreate() *cobra.Command {
 	 * Default Values set via Viper.
 	 */
 
+	cmd.Flags().String("k3s-version", "", "Use the k3s image of the given k3s release instead of a full image reference (Format: `VERSION`)\n - Example: `k3d cluster create --k3s-version v1.21.4+k3s1`")
+	_ = cfgViper.BindPFlag("k3sversion", cmd.Flags().Lookup("k3s-version"))
+
+	cmd.Flags().String("channel", "", "Use the k3s image of the release a k3s channel currently points to, looked up via the k3s update channel server (last fetched channels or, for stable/latest, the default k3s version when offline; list them with 'k3d version list') (Format: `CHANNEL`, e.g. stable, latest, v1.21)\n - Example: `k3d cluster create --channel stable`")
+	_ = cfgViper.BindPFlag("channel", cmd.Flags().Lookup("channel"))
+
+	cmd.Flags().String("description", "", "Describe what the cluster is for, e.g. on a shared host (shown by 'cluster list', change it with 'cluster annotate')")
+	_ = cfgViper.BindPFlag("description", cmd.Flags().Lookup("description"))
+
+	cmd.Flags().StringArray("depends-on", nil, "Registry or cluster which 'cluster start' has to start (and wait for) before this cluster, e.g. a database cluster or a shared registry (registries used via --registry-use/--registry-create are added automatically) (Format: `KIND:NAME`, KIND: cluster|registry)\n - Example: `k3d cluster create --depends-on cluster:database --depends-on registry:k3d-shared-registry`")
+	_ = cfgViper.BindPFlag("dependson", cmd.Flags().Lookup("depends-on"))
+
 	cmd.Flags().IntP("servers", "s", 0, "Specify how many servers you want to create")
 	_ = cfgViper.BindPFlag("servers", cmd.Flags().Lookup("servers"))
 	cfgViper.SetDefault("servers", 1)
@@ -288,9 +279,8 @@ func NewCmdClusterCreate() *cobra.Command {
 	cmd.Flags().IntP("agents", "a", 0, "Specify how many agents you want to create")
 	_ = cfgViper.BindPFlag("agents", cmd.Flags().Lookup("agents"))
 	cfgViper.SetDefault("agents", 0)
+	cliutil.DeprecateFlag(cmd, cliutil.FlagDeprecation{Name: "workers", Replacement: "agents", RemovalVersion: "v6.0.0"})
 
-	cmd.Flags().StringP("image", "i", "", "Specify k3s image that you want to use for the nodes")
-	_ = cfgViper.BindPFlag("image", cmd.Flags().Lookup("image"))
 	cfgViper.SetDefault("image", fmt.Sprintf("%s:%s", k3d.DefaultK3sImageRepo, version.GetK3sVersion(false)))
 
 	cmd.Flags().String("network", "", "Join an existing network")
@@ -299,8 +289,16 @@ func NewCmdClusterCreate() *cobra.Command {
 	cmd.Flags().String("subnet", "", "[Experimental: IPAM] Define a subnet for the newly created container network (Example: `172.28.0.0/16`)")
 	_ = cfgViper.BindPFlag("subnet", cmd.Flags().Lookup("subnet"))
 
+	cmd.Flags().String("gateway", "", "[Experimental: IPAM] Define the gateway of the newly created container network, requires --subnet (Default: second IP of the subnet) (Example: `172.28.0.254`)")
+	_ = cfgViper.BindPFlag("gateway", cmd.Flags().Lookup("gateway"))
+
+	cmd.Flags().String("ipv6", "", "Enable IPv6 (dual-stack) in the newly created container network, using the given IPv6 subnet or one assigned by docker with 'auto' (requires an IPv6 address pool in the docker daemon config) (Example: `fd00:cafe::/64`)")
+	cmd.Flags().Lookup("ipv6").NoOptDefVal = "auto"
+	_ = cfgViper.BindPFlag("ipv6", cmd.Flags().Lookup("ipv6"))
+
 	cmd.Flags().String("token", "", "Specify a cluster token. By default, we generate one.")
 	_ = cfgViper.BindPFlag("token", cmd.Flags().Lookup("token"))
+	cliutil.SkipFlagEnv(cmd.Flags(), "token") // secret: must not be picked up from e.g. the API token of 'k3d serve'
 
 	cmd.Flags().Bool("wait", true, "Wait for the server(s) to be ready before returning. Use '--timeout DURATION' to not wait forever.")
 	_ = cfgViper.BindPFlag("options.k3d.wait", cmd.Flags().Lookup("wait"))
@@ -308,12 +306,21 @@ func NewCmdClusterCreate() *cobra.Command {
 	cmd.Flags().Duration("timeout", 0*time.Second, "Rollback changes if cluster couldn't be created in specified duration.")
 	_ = cfgViper.BindPFlag("options.k3d.timeout", cmd.Flags().Lookup("timeout"))
 
+	cmd.Flags().Duration("readiness-timeout", 0*time.Second, "Maximum time for each phase of the readiness check with '--wait': the Kubernetes API answering on /readyz via the published API port and all nodes being Ready (default: no timeout apart from '--timeout')")
+	_ = cfgViper.BindPFlag("options.k3d.readinesstimeout", cmd.Flags().Lookup("readiness-timeout"))
+
 	cmd.Flags().Bool("kubeconfig-update-default", true, "Directly update the default kubeconfig with the new cluster's context")
 	_ = cfgViper.BindPFlag("options.kubeconfig.updatedefaultkubeconfig", cmd.Flags().Lookup("kubeconfig-update-default"))
 
 	cmd.Flags().Bool("kubeconfig-switch-context", true, "Directly switch the default kubeconfig's current-context to the new cluster's context (requires --kubeconfig-update-default)")
 	_ = cfgViper.BindPFlag("options.kubeconfig.switchcurrentcontext", cmd.Flags().Lookup("kubeconfig-switch-context"))
 
+	cmd.Flags().String("default-namespace", "", "Namespace of the cluster's kubeconfig context(s), so that kubectl commands land in it without '--namespace' (it's created in the cluster if it doesn't exist)\n - Example: `k3d cluster create --default-namespace dev`")
+	_ = cfgViper.BindPFlag("options.kubeconfig.defaultnamespace", cmd.Flags().Lookup("default-namespace"))
+
+	cmd.Flags().String("kubeconfig-context-template", "", "Go `TEMPLATE` for the name of the cluster's kubeconfig context (also used by 'k3d kubeconfig get/merge' later on). Available fields: {{.Prefix}}, {{.Cluster}}, {{.User}} (the local user) (default: '"+k3d.DefaultKubeconfigContextTemplate+"')\n - Example: `k3d cluster create --kubeconfig-context-template '{{.Cluster}}-{{.User}}'`")
+	_ = cfgViper.BindPFlag("options.kubeconfig.contexttemplate", cmd.Flags().Lookup("kubeconfig-context-template"))
+
 	cmd.Flags().Bool("no-lb", false, "Disable the creation of a LoadBalancer in front of the server nodes")
 	_ = cfgViper.BindPFlag("options.k3d.disableloadbalancer", cmd.Flags().Lookup("no-lb"))
 
@@ -329,6 +336,46 @@ func NewCmdClusterCreate() *cobra.Command {
 	cmd.Flags().String("agents-memory", "", "Memory limit imposed on the agents nodes [From docker]")
 	_ = cfgViper.BindPFlag("options.runtime.agentsmemory", cmd.Flags().Lookup("agents-memory"))
 
+	cmd.Flags().String("servers-cpus", "", "CPU limit imposed on the server nodes as a (fractional) number of CPUs [From docker]\n - Example: `k3d cluster create --servers-cpus 2`")
+	_ = cfgViper.BindPFlag("options.runtime.serverscpus", cmd.Flags().Lookup("servers-cpus"))
+
+	cmd.Flags().String("agents-cpus", "", "CPU limit imposed on the agent nodes as a (fractional) number of CPUs [From docker]\n - Example: `k3d cluster create --agents 2 --agents-cpus 0.5`")
+	_ = cfgViper.BindPFlag("options.runtime.agentscpus", cmd.Flags().Lookup("agents-cpus"))
+
+	cmd.Flags().String("memory-budget", "", "Total memory limit for the cluster, split evenly across all server and agent nodes without an explicit limit (Format: `MEMORY`)\n - Example: `k3d cluster create --agents 2 --memory-budget 8g`")
+	_ = cfgViper.BindPFlag("options.runtime.memorybudget", cmd.Flags().Lookup("memory-budget"))
+
+	cmd.Flags().String("shm-size", "", "Size of /dev/shm in the server and agent containers, as the default of 64m breaks some workloads like databases or browsers in CI (Format: `SIZE`) [From docker]\n - Example: `k3d cluster create --shm-size 1g`")
+	_ = cfgViper.BindPFlag("options.runtime.shmsize", cmd.Flags().Lookup("shm-size"))
+
+	cmd.Flags().String("log-driver", "", "Logging driver of the server and agent containers, e.g. to keep the logs of chatty nodes from filling the disk ('none' is not supported, as k3d reads the node logs) (Format: `DRIVER`, default: the docker daemon's default, usually json-file) [From docker]\n - Example: `k3d cluster create --log-driver local`")
+	_ = cfgViper.BindPFlag("options.runtime.logdriver", cmd.Flags().Lookup("log-driver"))
+
+	cmd.Flags().StringArray("log-opt", nil, "Option of the logging driver of the server and agent containers, e.g. to rotate their logs (Format: `KEY=VALUE`, use flag multiple times) [From docker]\n - Example: `k3d cluster create --log-opt max-size=10m --log-opt max-file=3`")
+	_ = cfgViper.BindPFlag("options.runtime.logopts", cmd.Flags().Lookup("log-opt"))
+
+	cmd.Flags().String("pull", "", "Whether the k3s image of the nodes is pulled before they're created: 'always' keeps tags like 'latest' fresh, 'never' fails if any image isn't present locally (Format: `missing|always|never`, default: missing) [From docker]\n - Example: `k3d cluster create --image rancher/k3s:latest --pull always`")
+	_ = cfgViper.BindPFlag("options.runtime.pullpolicy", cmd.Flags().Lookup("pull"))
+
+	cmd.Flags().Bool("ephemeral-state", false, "Keep the kubelet and containerd state of the server and agent nodes on tmpfs, for faster pod churn and less disk wear in short-lived (CI) clusters; images and pods are lost when the nodes stop and it counts against the memory of the host [From docker]\n - Example: `k3d cluster create --ephemeral-state`")
+	_ = cfgViper.BindPFlag("options.runtime.ephemeralstate", cmd.Flags().Lookup("ephemeral-state"))
+
+	cmd.Flags().Bool("selinux", false, "Make the nodes work on SELinux-enforcing hosts: disable SELinux labeling for the server and agent containers and relabel bind-mounted host paths (volume option 'z') [From docker]\n - Example: `k3d cluster create --selinux --volume $HOME/data:/data`")
+	_ = cfgViper.BindPFlag("options.runtime.selinux", cmd.Flags().Lookup("selinux"))
+
+	cmd.Flags().String("apparmor-profile", "", "AppArmor profile to run the server and agent containers with (Format: `PROFILE`) [From docker]\n - Example: `k3d cluster create --apparmor-profile unconfined`")
+	_ = cfgViper.BindPFlag("options.runtime.apparmorprofile", cmd.Flags().Lookup("apparmor-profile"))
+
+	cmd.Flags().StringArray("wait-for", nil, "Block until the given Kubernetes resource is ready (Format: `[NAMESPACE/]KIND/NAME`, supported kinds: deployment, statefulset, daemonset, pod, job, node, crd; namespace defaults to 'default')\n - Example: `k3d cluster create --wait-for kube-system/deployment/traefik --wait-for crd/helmcharts.helm.cattle.io`")
+	_ = cfgViper.BindPFlag("options.k3d.waitfor", cmd.Flags().Lookup("wait-for"))
+
+	cmd.Flags().String("runner-container", "", "Connect the container k3d is running in (e.g. a CI job) to the cluster network and use the loadbalancer's (or first server's) container name as the Kubernetes API endpoint in the kubeconfig (Format: `CONTAINER` as name or ID; without a value, k3d detects its own container)\n - Example: `k3d cluster create --runner-container` in a job container using the host's docker socket\n - With a docker:dind sidecar (DOCKER_HOST=tcp://docker:2375), the own container is unknown to the daemon and the API is reached via the DOCKER_HOST hostname instead")
+	cmd.Flags().Lookup("runner-container").NoOptDefVal = k3dCluster.RunnerContainerAuto
+	_ = cfgViper.BindPFlag("options.k3d.runnercontainer", cmd.Flags().Lookup("runner-container"))
+
+	cmd.Flags().Int("virtual-workers", 0, "Register the given number of fake nodes (kwok-style, no containers) to test scheduling at scale (Example: `--virtual-workers 50`)")
+	_ = cfgViper.BindPFlag("options.k3d.virtualworkers", cmd.Flags().Lookup("virtual-workers"))
+
 	/* Image Importing */
 	cmd.Flags().Bool("no-image-volume", false, "Disable the creation of a volume for importing images")
 	_ = cfgViper.BindPFlag("options.k3d.disableimagevolume", cmd.Flags().Lookup("no-image-volume"))
@@ -337,11 +384,20 @@ func NewCmdClusterCreate() *cobra.Command {
 	cmd.Flags().StringArray("registry-use", nil, "Connect to one or more k3d-managed registries running locally")
 	_ = cfgViper.BindPFlag("registries.use", cmd.Flags().Lookup("registry-use"))
 
-	cmd.Flags().String("registry-config", "", "Specify path to an extra registries.yaml file")
+	cmd.Flags().String("registry-config", "", "Specify path to an extra registries.yaml file, written to /etc/rancher/k3s/registries.yaml in every node (alias: --registries-file)")
 	_ = cfgViper.BindPFlag("registries.config", cmd.Flags().Lookup("registry-config"))
 	if err := cmd.MarkFlagFilename("registry-config", "yaml", "yml"); err != nil {
 		l.Log().Fatalln("Failed to mark flag 'config' as filename flag")
 	}
+	cmd.Flags().SetNormalizeFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "registries-file" {
+			name = "registry-config"
+		}
+		return pflag.NormalizedName(name)
+	})
+
+	cmd.Flags().StringArray("registry-mirror", nil, "Add a pull-through mirror for a registry to the registries.yaml (takes precedence over the registries file for the same registry, use flag multiple times) (Format: `REGISTRY=ENDPOINT[,ENDPOINT...]`)\n - Example: `k3d cluster create --registry-mirror docker.io=https://mirror.corp`")
+	_ = cfgViper.BindPFlag("registries.mirrors", cmd.Flags().Lookup("registry-mirror"))
 
 	/* Loadbalancer / Proxy */
 	cmd.Flags().StringSlice("lb-config-override", nil, "Use dotted YAML path syntax to override nginx loadbalancer settings")
@@ -353,6 +409,204 @@ func NewCmdClusterCreate() *cobra.Command {
 	return cmd
 }
 
+// runClusterCreate creates a cluster from the config file and the flags of the given create command
+func runClusterCreate(cmd *cobra.Command, args []string) {
+	clusterConfig, simpleCfg := clusterCreateConfig(cmd, args)
+
+	// check if a cluster with that name exists already
+	if _, err := k3dCluster.ClusterGet(cmd.Context(), runtimes.SelectedRuntime, &clusterConfig.Cluster); err == nil {
+		l.Log().Fatalf("Failed to create cluster '%s' because a cluster with that name already exists", clusterConfig.Cluster.Name)
+	}
+
+	clusterCreateRun(cmd, clusterConfig, simpleCfg)
+
+	if clusterConfig.ClusterCreateOpts.NoStart {
+		// there's no kubeconfig before the first start
+		l.Log().Infof("Bring it up with 'k3d cluster start %s' and get its kubeconfig with 'k3d kubeconfig merge %s --kubeconfig-merge-default'", clusterConfig.Cluster.Name, clusterConfig.Cluster.Name)
+		return
+	}
+
+	/**************
+	 * Kubeconfig *
+	 **************/
+
+	if clusterConfig.KubeconfigOpts.UpdateDefaultKubeconfig && clusterConfig.KubeconfigOpts.SwitchCurrentContext {
+		l.Log().Infoln("--kubeconfig-update-default=false --> sets --kubeconfig-switch-context=false")
+		clusterConfig.KubeconfigOpts.SwitchCurrentContext = false
+	}
+
+	// a dedicated kubeconfig file left over from a former cluster with the same name holds stale credentials
+	refreshClusterKubeconfigFile(cmd, &clusterConfig.Cluster)
+
+	var err error
+	kubeconfigPath := ""
+	if clusterConfig.KubeconfigOpts.UpdateDefaultKubeconfig {
+		l.Log().Debugf("Updating default kubeconfig with a new context for cluster %s", clusterConfig.Cluster.Name)
+		stopTiming := clusterConfig.ClusterCreateOpts.Timings.Track("update kubeconfig")
+		if kubeconfigPath, err = k3dCluster.KubeconfigGetWrite(cmd.Context(), runtimes.SelectedRuntime, &clusterConfig.Cluster, "", &k3dCluster.WriteKubeConfigOptions{UpdateExisting: true, OverwriteExisting: false, UpdateCurrentContext: simpleCfg.Options.KubeconfigOptions.SwitchCurrentContext}); err != nil {
+			l.Log().Warningln(err)
+		}
+		stopTiming()
+	} else if cliutil.CIMode || len(clusterCreateCIOutputFiles) > 0 {
+		// CI jobs need a kubeconfig path: use a dedicated file like `k3d kubeconfig merge` (removed again by `k3d cluster delete`)
+		kubeconfigPath, err = writeClusterKubeconfigFile(cmd, &clusterConfig.Cluster)
+		if err != nil {
+			l.Log().Warningln(err)
+		}
+	}
+
+	/*****************
+	 * User Feedback *
+	 *****************/
+
+	newClusterOperationSummary("create", &clusterConfig.Cluster, clusterConfig.ClusterCreateOpts.Timings, kubeconfigPath).print(clusterCreateTimings)
+	if cliutil.CIMode || len(clusterCreateCIOutputFiles) > 0 {
+		outputs := map[string]string{cliutil.ClusterEnvVar: clusterConfig.Cluster.Name, "KUBECONFIG": kubeconfigPath}
+		if err := cliutil.WriteCIOutputs(os.Stdout, outputs, clusterCreateCIOutputFiles); err != nil {
+			l.Log().Fatalln(err)
+		}
+	}
+	if clusterCreateTimings == "-" || cliutil.CIMode {
+		return
+	}
+
+	// print information on how to use the cluster with kubectl
+	l.Log().Infoln("You can now use it like this:")
+	printKubectlUsage(&clusterConfig.Cluster, clusterConfig.KubeconfigOpts.UpdateDefaultKubeconfig, clusterConfig.KubeconfigOpts.SwitchCurrentContext)
+}
+
+// clusterCreateConfig computes, transforms and validates the cluster config from the config file and the flags of the create command
+func clusterCreateConfig(cmd *cobra.Command, args []string) (*conf.ClusterConfig, conf.SimpleConfig) {
+	/*************************
+	 * Compute Configuration *
+	 *************************/
+	if cfgViper.GetString("apiversion") == "" {
+		cfgViper.Set("apiversion", config.DefaultConfigApiVersion)
+	}
+	if cfgViper.GetString("kind") == "" {
+		cfgViper.Set("kind", "Simple")
+	}
+	cfg, err := config.FromViper(cfgViper)
+	if err != nil {
+		l.Log().Fatalln(err)
+	}
+
+	if cfg.GetAPIVersion() != config.DefaultConfigApiVersion {
+		l.Log().Warnf("Default config apiVersion is '%s', but you're using '%s': consider migrating.", config.DefaultConfigApiVersion, cfg.GetAPIVersion())
+		cfg, err = config.Migrate(cfg, config.DefaultConfigApiVersion)
+		if err != nil {
+			l.Log().Fatalln(err)
+		}
+	}
+
+	simpleCfg := cfg.(conf.SimpleConfig)
+	cliutil.MaskSecret(simpleCfg.ClusterToken)
+
+	l.Log().Debugf("========== Simple Config ==========\n%+v\n==========================\n", simpleCfg)
+
+	simpleCfg, err = applyCLIOverrides(simpleCfg)
+	if err != nil {
+		l.Log().Fatalf("Failed to apply CLI overrides: %+v", err)
+	}
+
+	// read directly from the flag, as viper would split the commands at commas
+	hookFlags, err := cmd.Flags().GetStringArray("hook")
+	if err != nil {
+		l.Log().Fatalln(err)
+	}
+	for _, hookFlag := range hookFlags {
+		split := strings.SplitN(hookFlag, ":", 2)
+		if len(split) != 2 || split[1] == "" {
+			l.Log().Fatalf("Invalid --hook '%s': expected PHASE:COMMAND", hookFlag)
+		}
+		simpleCfg.Hooks = append(simpleCfg.Hooks, conf.SimpleConfigHook{Phase: split[0], Command: split[1]})
+	}
+
+	l.Log().Debugf("========== Merged Simple Config ==========\n%+v\n==========================\n", simpleCfg)
+
+	/**************************************
+	 * Transform, Process & Validate Configuration *
+	 **************************************/
+
+	// Set the name
+	if len(args) != 0 {
+		simpleCfg.Name = args[0]
+	}
+	simpleCfg.Name = cliutil.QualifyClusterName(simpleCfg.Name)
+
+	// resolve the digests up front, so that they end up in the recorded config
+	if simpleCfg.Options.K3dOptions.ResolveDigest {
+		if err := config.ResolveImageDigests(cmd.Context(), runtimes.SelectedRuntime, &simpleCfg); err != nil {
+			l.Log().Fatalln(err)
+		}
+	}
+
+	clusterConfig, err := config.TransformSimpleToClusterConfig(cmd.Context(), runtimes.SelectedRuntime, simpleCfg)
+	if err != nil {
+		l.Log().Fatalln(err)
+	}
+	if cliutil.CIMode && clusterConfig.Cluster.Token == "" {
+		clusterConfig.Cluster.Token = k3dCluster.GenerateClusterToken() // generate it here already, so that it's masked in all log output
+	}
+	cliutil.MaskSecret(clusterConfig.Cluster.Token)
+	l.Log().Debugf("===== Merged Cluster Config =====\n%+v\n===== ===== =====\n", clusterConfig)
+
+	clusterConfig, err = config.ProcessClusterConfig(*clusterConfig)
+	if err != nil {
+		l.Log().Fatalln(err)
+	}
+	l.Log().Debugf("===== Processed Cluster Config =====\n%+v\n===== ===== =====\n", clusterConfig)
+
+	if err := config.ValidateClusterConfig(cmd.Context(), runtimes.SelectedRuntime, *clusterConfig); err != nil {
+		l.Log().Fatalln("Failed Cluster Configuration Validation: ", err)
+	}
+	clusterConfig.ClusterCreateOpts.GlobalLabels[k3d.LabelClusterOwner] = cliutil.ClusterOwner()
+	recordClusterCreate(clusterConfig, simpleCfg)
+
+	return clusterConfig, simpleCfg
+}
+
+// clusterCreateRun creates the cluster described by the config, rolling back on failure (unless disabled)
+func clusterCreateRun(cmd *cobra.Command, clusterConfig *conf.ClusterConfig, simpleCfg conf.SimpleConfig) {
+	if simpleCfg.Options.K3dOptions.APIDNS {
+		if err := k3dCluster.ClusterRegisterAPIDNS(clusterConfig.Cluster.Name, clusterConfig.Cluster.KubeAPI.Binding.HostIP); err != nil {
+			l.Log().Fatalln(err)
+		}
+	}
+
+	// create cluster
+	if clusterConfig.KubeconfigOpts.UpdateDefaultKubeconfig {
+		l.Log().Debugln("'--kubeconfig-update-default set: enabling wait-for-server")
+		clusterConfig.ClusterCreateOpts.WaitForServer = true
+	}
+	clusterConfig.ClusterCreateOpts.Timings = k3d.NewOperationTimings()
+	//if err := k3dCluster.ClusterCreate(cmd.Context(), runtimes.SelectedRuntime, &clusterConfig.Cluster, &clusterConfig.ClusterCreateOpts); err != nil {
+	if err := k3dCluster.ClusterRun(cmd.Context(), runtimes.SelectedRuntime, clusterConfig); err != nil {
+		// rollback if creation failed
+		l.Log().Errorln(err)
+		if errors.Is(err, k3dCluster.ErrClusterAlreadyExists) { // not ours to delete
+			l.Log().Fatalln("Cluster creation FAILED, the existing cluster has been left untouched.")
+		}
+		if simpleCfg.Options.K3dOptions.NoRollback { // TODO: move rollback mechanics to pkg/
+			l.Log().Fatalln("Cluster creation FAILED, rollback deactivated.")
+		}
+		// rollback if creation failed
+		l.Log().Errorln("Failed to create cluster >>> Rolling Back")
+		if err := k3dCluster.ClusterDelete(cmd.Context(), runtimes.SelectedRuntime, &clusterConfig.Cluster, k3d.ClusterDeleteOpts{SkipRegistryCheck: true}); err != nil {
+			l.Log().Errorln(err)
+			l.Log().Fatalln("Cluster creation FAILED, also FAILED to rollback changes!")
+		}
+		l.Log().Fatalln("Cluster creation FAILED, all changes have been rolled back!")
+	}
+	l.Log().Infof("Cluster '%s' created successfully!", clusterConfig.Cluster.Name)
+	if mappings := k3dCluster.ClusterPortMappings(&clusterConfig.Cluster); len(mappings) > 0 {
+		l.Log().Infoln("Published ports:")
+		for _, mapping := range mappings {
+			l.Log().Infof("  %s", mapping)
+		}
+	}
+}
+
 func applyCLIOverrides(cfg conf.SimpleConfig) (conf.SimpleConfig, error) {
 
 	/****************************
@@ -388,8 +642,8 @@ func applyCLIOverrides(cfg conf.SimpleConfig) (conf.SimpleConfig, error) {
 		}
 	}
 
-	// Set to random port if port is empty string
-	if len(exposeAPI.Binding.HostPort) == 0 {
+	// Set to random port if port is empty string (with a stable DNS name for the API, the default port is used on a loopback address of its own)
+	if len(exposeAPI.Binding.HostPort) == 0 && !(cfg.Options.K3dOptions.APIDNS && exposeAPI.Binding.HostIP == "" && k3dCluster.ClusterAPILoopbackIP(cfg.Name) != "") {
 		var freePort string
 		port, err := cliutil.GetFreePort()
 		freePort = strconv.Itoa(port)
@@ -405,6 +659,7 @@ func applyCLIOverrides(cfg conf.SimpleConfig) (conf.SimpleConfig, error) {
 		Host:     exposeAPI.Host,
 		HostIP:   exposeAPI.Binding.HostIP,
 		HostPort: exposeAPI.Binding.HostPort,
+		Tunnel:   cfg.ExposeAPI.Tunnel,
 	}
 
 	// -> VOLUMES
@@ -523,6 +778,82 @@ func applyCLIOverrides(cfg conf.SimpleConfig) (conf.SimpleConfig, error) {
 
 	l.Log().Tracef("RuntimeLabelFilterMap: %+v", runtimeLabelFilterMap)
 
+	// --schedule-on-server
+	if ppViper.GetBool("cli.schedule-on-server") {
+		cfg.Options.K3sOptions.NoScheduleOnServer = false
+	}
+
+	// --image
+	defaultImageSet := false
+	for _, imageFlag := range ppViper.GetStringSlice("cli.images") {
+		image, nodeFilters, err := cliutil.SplitImageFiltersFromFlag(imageFlag)
+		if err != nil {
+			l.Log().Fatalln(err)
+		}
+
+		if len(nodeFilters) == 0 {
+			if defaultImageSet {
+				l.Log().Fatalf("--image '%s': only one image without a node filter allowed", imageFlag)
+			}
+			cfg.Image = image
+			defaultImageSet = true
+			continue
+		}
+
+		cfg.Options.Runtime.NodeImages = append(cfg.Options.Runtime.NodeImages, conf.ImageWithNodeFilters{
+			Image:       image,
+			NodeFilters: nodeFilters,
+		})
+	}
+	if defaultImageSet && (cfg.K3sVersion != "" || cfg.Channel != "") {
+		return cfg, fmt.Errorf("--image without a node filter can't be combined with --k3s-version or --channel (k3sVersion or channel in the config file)")
+	}
+
+	// --custom-ca
+	if customCA := ppViper.GetStringSlice("cli.custom-ca"); len(customCA) > 0 {
+		if len(customCA) != 2 {
+			l.Log().Fatalf("--custom-ca expects exactly two files (certificate and key, use flag twice), got %d", len(customCA))
+		}
+		cfg.Options.K3sOptions.CustomCA = conf.SimpleConfigCustomCA{
+			Cert: customCA[0],
+			Key:  customCA[1],
+		}
+	}
+
+	// --secret, --configmap
+	for _, seed := range []struct {
+		key     string
+		objects *[]conf.SeedObjectFromSource
+	}{
+		{"cli.secrets", &cfg.Options.K3sOptions.Secrets},
+		{"cli.configmaps", &cfg.Options.K3sOptions.ConfigMaps},
+	} {
+		for _, seedFlag := range ppViper.GetStringSlice(seed.key) {
+			name, source, namespace, err := cliutil.ParseSeedObjectFlag(seedFlag)
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+			*seed.objects = append(*seed.objects, conf.SeedObjectFromSource{
+				Name:      name,
+				Namespace: namespace,
+				Source:    source,
+			})
+		}
+	}
+
+	// --fake-node-memory
+	for _, fakeMemoryFlag := range ppViper.GetStringSlice("cli.fake-node-memory") {
+		memory, nodeFilters, err := cliutil.SplitFiltersFromFlag(fakeMemoryFlag)
+		if err != nil {
+			l.Log().Fatalln(err)
+		}
+
+		cfg.Options.Runtime.FakeNodeMemory = append(cfg.Options.Runtime.FakeNodeMemory, conf.MemoryWithNodeFilters{
+			Memory:      memory,
+			NodeFilters: nodeFilters,
+		})
+	}
+
 	// --env
 	// envFilterMap will add container env vars to applied node filters
 	envFilterMap := make(map[string][]string, 1)
@@ -597,3 +928,12 @@ func applyCLIOverrides(cfg conf.SimpleConfig) (conf.SimpleConfig, error) {
 
 	return cfg, nil
 }
+
+// writeClusterKubeconfigFile writes the kubeconfig of the cluster to a dedicated file in the k3d config directory
+func writeClusterKubeconfigFile(cmd *cobra.Command, cluster *k3d.Cluster) (string, error) {
+	output, err := k3dCluster.KubeconfigClusterFilePath(cluster.Name)
+	if err != nil {
+		return "", err
+	}
+	return k3dCluster.KubeconfigGetWrite(cmd.Context(), runtimes.SelectedRuntime, cluster, output, &k3dCluster.WriteKubeConfigOptions{UpdateExisting: true, OverwriteExisting: true, UpdateCurrentContext: true})
+}
@@ -22,9 +22,8 @@ THE SOFTWARE.
 package cluster
 
 import (
-	"fmt"
 	"os"
-	"path"
+	"time"
 
 	"github.com/rancher/k3d/v5/cmd/util"
 	cliconfig "github.com/rancher/k3d/v5/cmd/util/config"
@@ -32,7 +31,6 @@ import (
 	l "github.com/rancher/k3d/v5/pkg/logger"
 	"github.com/rancher/k3d/v5/pkg/runtimes"
 	k3d "github.com/rancher/k3d/v5/pkg/types"
-	k3dutil "github.com/rancher/k3d/v5/pkg/util"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -40,6 +38,8 @@ import (
 
 var clusterDeleteConfigFile string
 var clusterDeleteCfgViper = viper.New()
+var clusterDeleteTimings string
+var clusterDeleteTimeout time.Duration
 
 // NewCmdClusterDelete returns a new cobra command
 func NewCmdClusterDelete() *cobra.Command {
@@ -61,29 +61,46 @@ func NewCmdClusterDelete() *cobra.Command {
 			if len(clusters) == 0 {
 				l.Log().Infoln("No clusters found")
 			} else {
+				// with multiple clusters, all reports are written at once as a single JSON array
+				summaries := []*clusterOperationSummary{}
 				for _, c := range clusters {
-					if err := client.ClusterDelete(cmd.Context(), runtimes.SelectedRuntime, c, k3d.ClusterDeleteOpts{SkipRegistryCheck: false}); err != nil {
+					timings := k3d.NewOperationTimings()
+					if err := client.ClusterDelete(cmd.Context(), runtimes.SelectedRuntime, c, k3d.ClusterDeleteOpts{SkipRegistryCheck: false, Timings: timings, Timeout: clusterDeleteTimeout}); err != nil {
 						l.Log().Fatalln(err)
 					}
 					l.Log().Infoln("Removing cluster details from default kubeconfig...")
+					stopTiming := timings.Track("cleanup kubeconfig")
+					kubeconfigPath, err := client.KubeconfigGetDefaultPath()
+					if err != nil {
+						l.Log().Debugf("Failed to get default kubeconfig path: %v", err)
+					}
 					if err := client.KubeconfigRemoveClusterFromDefaultConfig(cmd.Context(), c); err != nil {
 						l.Log().Warnln("Failed to remove cluster details from default kubeconfig")
 						l.Log().Warnln(err)
 					}
 					l.Log().Infoln("Removing standalone kubeconfig file (if there is one)...")
-					configDir, err := k3dutil.GetConfigDirOrCreate()
+					kubeconfigfile, err := client.KubeconfigClusterFilePath(c.Name)
 					if err != nil {
 						l.Log().Warnf("Failed to delete kubeconfig file: %+v", err)
-					} else {
-						kubeconfigfile := path.Join(configDir, fmt.Sprintf("kubeconfig-%s.yaml", c.Name))
-						if err := os.Remove(kubeconfigfile); err != nil {
-							if !os.IsNotExist(err) {
-								l.Log().Warnf("Failed to delete kubeconfig file '%s'", kubeconfigfile)
-							}
-						}
+					} else if err := os.Remove(kubeconfigfile); err != nil && !os.IsNotExist(err) {
+						l.Log().Warnf("Failed to delete kubeconfig file '%s'", kubeconfigfile)
+					}
+					if err := client.ClusterUnregisterAPIDNS(c.Name); err != nil {
+						l.Log().Warnln(err)
 					}
+					stopTiming()
 
 					l.Log().Infof("Successfully deleted cluster %s!", c.Name)
+					summary := newClusterOperationSummary("delete", c, timings, kubeconfigPath)
+					if clusterDeleteTimings != "-" {
+						summary.log()
+					}
+					summaries = append(summaries, summary)
+				}
+				if clusterDeleteTimings != "" {
+					if err := writeTimingsReport(clusterDeleteTimings, summaries); err != nil {
+						l.Log().Errorf("Failed to write timings report: %v", err)
+					}
 				}
 			}
 
@@ -94,6 +111,9 @@ func NewCmdClusterDelete() *cobra.Command {
 
 	// add flags
 	cmd.Flags().BoolP("all", "a", false, "Delete all existing clusters")
+	cmd.Flags().StringVar(&clusterDeleteTimings, "timings", "", "Write a JSON report (an array with one entry per cluster) of the deletion stage durations and removed nodes to stdout or, if a path is given (Format: `--timings=FILE`), to a file")
+	cmd.Flags().Lookup("timings").NoOptDefVal = "-"
+	cmd.Flags().DurationVar(&clusterDeleteTimeout, "timeout", 0, "Maximum time for deleting the nodes of a cluster (which happens concurrently), e.g. '2m' (default: no timeout)")
 
 	/***************
 	 * Config File *
@@ -37,9 +37,11 @@ func NewCmdClusterEdit() *cobra.Command {
 
 	// create new cobra command
 	cmd := &cobra.Command{
-		Use:               "edit CLUSTER",
-		Short:             "[EXPERIMENTAL] Edit cluster(s).",
-		Long:              `[EXPERIMENTAL] Edit cluster(s).`,
+		Use:   "edit CLUSTER",
+		Short: "[EXPERIMENTAL] Edit cluster(s).",
+		Long: `[EXPERIMENTAL] Edit cluster(s).
+Adding env vars or k3s args recreates the affected nodes one after another (servers first) with the updated spec, keeping their data.
+The config recorded at cluster creation (see 'k3d record') is updated accordingly.`,
 		Args:              cobra.ExactArgs(1),
 		Aliases:           []string{"update"},
 		ValidArgsFunction: util.ValidArgsAvailableClusters,
@@ -49,6 +51,25 @@ func NewCmdClusterEdit() *cobra.Command {
 
 			l.Log().Debugf("===== Current =====\n%+v\n===== Changeset =====\n%+v\n", existingCluster, changeset)
 
+			portsDelete, err := cmd.Flags().GetStringArray("port-delete")
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+			if len(portsDelete) > 0 {
+				if err := client.ClusterRemovePorts(cmd.Context(), runtimes.SelectedRuntime, existingCluster, portsDelete); err != nil {
+					l.Log().Fatalf("Failed to remove ports from the cluster: %v", err)
+				}
+				if len(changeset.Ports) == 0 && len(changeset.Env) == 0 && len(changeset.Options.K3sOptions.ExtraArgs) == 0 {
+					l.Log().Infof("Successfully updated %s", existingCluster.Name)
+					return
+				}
+				// the loadbalancer was replaced: continue with the current one
+				existingCluster, err = client.ClusterGet(cmd.Context(), runtimes.SelectedRuntime, &k3d.Cluster{Name: existingCluster.Name})
+				if err != nil {
+					l.Log().Fatalln(err)
+				}
+			}
+
 			if err := client.ClusterEditChangesetSimple(cmd.Context(), runtimes.SelectedRuntime, existingCluster, changeset); err != nil {
 				l.Log().Fatalf("Failed to update the cluster: %v", err)
 			}
@@ -62,6 +83,9 @@ func NewCmdClusterEdit() *cobra.Command {
 
 	// add flags
 	cmd.Flags().StringArray("port-add", nil, "[EXPERIMENTAL] Map ports from the node containers (via the serverlb) to the host (Format: `[HOST:][HOSTPORT:]CONTAINERPORT[/PROTOCOL][@NODEFILTER]`)\n - Example: `k3d node edit k3d-mycluster-serverlb --port-add 8080:80`")
+	cmd.Flags().StringArray("env-add", nil, "[EXPERIMENTAL] Add environment variables to the k3s nodes, replacing existing values (Format: `KEY[=VALUE][@NODEFILTER[;NODEFILTER...]]`, default node filter: all servers and agents)\n - Example: `k3d cluster edit mycluster --env-add HTTP_PROXY=my.proxy.com@server:0`")
+	cmd.Flags().StringArray("k3s-arg-add", nil, "[EXPERIMENTAL] Add args passed to the k3s command (Format: `ARG[@NODEFILTER[;NODEFILTER...]]`, default node filter: all servers and agents)\n - Example: `k3d cluster edit mycluster --k3s-arg-add \"--disable=traefik@server:*\"`")
+	cmd.Flags().StringArray("port-delete", nil, "[EXPERIMENTAL] Remove ports mapped to the host via the serverlb (Format: `[HOST:][HOSTPORT:]CONTAINERPORT[/PROTOCOL]`, host IP and port only needed to pick one of multiple mappings)\n - Example: `k3d cluster edit mycluster --port-delete 8080:80`")
 
 	// done
 	return cmd
@@ -120,5 +144,41 @@ func parseEditClusterCmd(cmd *cobra.Command, args []string) (*k3d.Cluster, *conf
 
 	l.Log().Tracef("PortFilterMap: %+v", portFilterMap)
 
+	/*
+	 * --env-add
+	 */
+	envFlags, err := cmd.Flags().GetStringArray("env-add")
+	if err != nil {
+		l.Log().Fatalln(err)
+	}
+	for _, envFlag := range envFlags {
+		envVar, filters, err := cliutil.SplitFiltersFromFlag(envFlag)
+		if err != nil {
+			l.Log().Fatalln(err)
+		}
+		changeset.Env = append(changeset.Env, conf.EnvVarWithNodeFilters{
+			EnvVar:      envVar,
+			NodeFilters: filters,
+		})
+	}
+
+	/*
+	 * --k3s-arg-add
+	 */
+	argFlags, err := cmd.Flags().GetStringArray("k3s-arg-add")
+	if err != nil {
+		l.Log().Fatalln(err)
+	}
+	for _, argFlag := range argFlags {
+		arg, filters, err := cliutil.SplitFiltersFromFlag(argFlag)
+		if err != nil {
+			l.Log().Fatalln(err)
+		}
+		changeset.Options.K3sOptions.ExtraArgs = append(changeset.Options.K3sOptions.ExtraArgs, conf.K3sArgWithNodeFilters{
+			Arg:         arg,
+			NodeFilters: filters,
+		})
+	}
+
 	return existingCluster, &changeset
 }
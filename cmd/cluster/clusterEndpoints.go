@@ -0,0 +1,106 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/liggitt/tabwriter"
+	"github.com/rancher/k3d/v5/cmd/util"
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+type clusterEndpointsFlags struct {
+	noHeader bool
+	output   string
+}
+
+// NewCmdClusterEndpoints returns a new cobra command
+func NewCmdClusterEndpoints() *cobra.Command {
+	flags := clusterEndpointsFlags{}
+
+	// create new command
+	cmd := &cobra.Command{
+		Use:     "endpoints NAME",
+		Aliases: []string{"get-endpoints"},
+		Short:   "Show where the API, the ingress and the published ports of a cluster can be reached",
+		Long: `Show where the API, the ingress and the published ports of a cluster can be reached.
+
+Prints the host address of the Kubernetes API, of the ingress (ports 80/443 of the loadbalancer) and of every other port mapping,
+including the host ports picked at random by the runtime, so there's no need to look them up with 'docker ps'.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: util.ValidArgsAvailableClusters,
+		Run: func(cmd *cobra.Command, args []string) {
+			cluster, err := client.ClusterGet(cmd.Context(), runtimes.SelectedRuntime, &k3d.Cluster{Name: args[0]})
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+			if err := printEndpoints(client.ClusterEndpoints(cluster), flags); err != nil {
+				l.Log().Fatalln(err)
+			}
+		},
+	}
+
+	// add flags
+	cmd.Flags().BoolVar(&flags.noHeader, "no-headers", false, "Disable headers")
+	cmd.Flags().StringVarP(&flags.output, "output", "o", "", "Output format. One of: json|yaml")
+
+	// done
+	return cmd
+}
+
+func printEndpoints(endpoints []client.Endpoint, flags clusterEndpointsFlags) error {
+	switch strings.ToLower(flags.output) {
+	case "json":
+		b, err := json.Marshal(endpoints)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	case "yaml":
+		b, err := yaml.Marshal(endpoints)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	case "":
+		tabwriter := tabwriter.NewWriter(os.Stdout, 6, 4, 3, ' ', tabwriter.RememberWidths)
+		defer tabwriter.Flush()
+		if !flags.noHeader {
+			fmt.Fprintln(tabwriter, "KIND\tADDRESS\tTARGET")
+		}
+		for _, endpoint := range endpoints {
+			fmt.Fprintf(tabwriter, "%s\t%s\t%s\n", endpoint.Kind, endpoint.Address, endpoint.Target)
+		}
+	default:
+		return fmt.Errorf("unknown output format '%s'", flags.output)
+	}
+	return nil
+}
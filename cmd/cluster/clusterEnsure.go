@@ -0,0 +1,156 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cluster
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	cliutil "github.com/rancher/k3d/v5/cmd/util"
+	k3dCluster "github.com/rancher/k3d/v5/pkg/client"
+	"github.com/rancher/k3d/v5/pkg/config"
+	conf "github.com/rancher/k3d/v5/pkg/config/v1alpha3"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// NewCmdEnsure returns a new cobra command
+func NewCmdEnsure() *cobra.Command {
+
+	var reconcile bool
+
+	// create new command
+	cmd := &cobra.Command{
+		Use:   "ensure --config FILE [CLUSTERNAME]",
+		Short: "Make sure a cluster described by a config file exists and is running, then print the path of its kubeconfig",
+		Long: `Make sure a cluster described by a config file exists and is running (idempotent, e.g. for Makefiles and dev scripts):
+	- the cluster is created if it doesn't exist
+	- the cluster is started if (some of) its nodes are stopped
+	- nothing happens if it's up and running already
+If the config differs from the one the cluster was created with (see 'k3d record'), k3d warns about the drift or,
+with --reconcile, recreates the cluster from the config (deleting all of its data!).
+In all of these cases, k3d exits with 0 and prints only the path of the cluster's kubeconfig file to stdout (logs go to stderr).`,
+		Example: `  export KUBECONFIG=$(k3d ensure --config cluster.yaml)`,
+		Args:    cobra.RangeArgs(0, 1), // the cluster name may override the one in the config file
+		Run: func(cmd *cobra.Command, args []string) {
+			cliutil.LogToStderr()
+
+			if err := initConfig(); err != nil {
+				l.Log().Fatalln(err)
+			}
+			clusterConfig, simpleCfg := clusterCreateConfig(cmd, args)
+			clusterConfig.ClusterCreateOpts.NoStart = false // the cluster is supposed to be running afterwards
+
+			cluster, err := k3dCluster.ClusterGet(cmd.Context(), runtimes.SelectedRuntime, &clusterConfig.Cluster)
+			if err != nil && err != k3dCluster.ClusterGetNoNodesFoundError {
+				l.Log().Fatalf("Failed to get cluster '%s': %v", clusterConfig.Cluster.Name, err)
+			}
+
+			if cluster != nil && clusterConfigDrifted(cluster, simpleCfg) {
+				if !reconcile {
+					l.Log().Warnf("Cluster '%s' was created with a config that differs from '%s': rerun with --reconcile to recreate it", cluster.Name, configFile)
+				} else {
+					l.Log().Infof("Cluster '%s' was created with a config that differs from '%s': recreating it...", cluster.Name, configFile)
+					if err := k3dCluster.ClusterDelete(cmd.Context(), runtimes.SelectedRuntime, cluster, k3d.ClusterDeleteOpts{}); err != nil {
+						l.Log().Fatalf("Failed to delete cluster '%s': %v", cluster.Name, err)
+					}
+					cluster = nil
+				}
+			}
+
+			switch {
+			case cluster == nil:
+				clusterCreateRun(cmd, clusterConfig, simpleCfg)
+				cluster = &clusterConfig.Cluster
+			case !clusterRunning(cluster):
+				l.Log().Infof("Starting cluster '%s'...", cluster.Name)
+				envInfo, err := k3dCluster.GatherEnvironmentInfo(cmd.Context(), runtimes.SelectedRuntime, cluster)
+				if err != nil {
+					l.Log().Fatalf("failed to gather info about cluster environment: %v", err)
+				}
+				if err := k3dCluster.ClusterStart(cmd.Context(), runtimes.SelectedRuntime, cluster, k3d.ClusterStartOpts{
+					WaitForServer:    true,
+					Timeout:          clusterConfig.ClusterCreateOpts.Timeout,
+					ReadinessTimeout: clusterConfig.ClusterCreateOpts.ReadinessTimeout,
+					EnvironmentInfo:  envInfo,
+				}); err != nil {
+					l.Log().Fatalln(err)
+				}
+				if refreshed, err := k3dCluster.KubeconfigRefreshDefault(cmd.Context(), runtimes.SelectedRuntime, cluster); err != nil {
+					l.Log().Warnf("Failed to refresh the default kubeconfig for cluster '%s': %v", cluster.Name, err)
+				} else if refreshed {
+					l.Log().Infof("Refreshed the default kubeconfig for cluster '%s'", cluster.Name)
+				}
+			default:
+				l.Log().Infof("Cluster '%s' is up and running already", cluster.Name)
+			}
+
+			kubeconfigPath, err := writeClusterKubeconfigFile(cmd, cluster)
+			if err != nil {
+				l.Log().Fatalf("Failed to write kubeconfig of cluster '%s': %v", cluster.Name, err)
+			}
+			fmt.Fprintln(os.Stdout, kubeconfigPath)
+		},
+	}
+
+	// add flags
+	cmd.Flags().StringVarP(&configFile, "config", "c", "", "Path of the config file describing the cluster (required)")
+	if err := cmd.MarkFlagRequired("config"); err != nil {
+		l.Log().Fatalln("Failed to mark flag 'config' as required")
+	}
+	if err := cmd.MarkFlagFilename("config", "yaml", "yml"); err != nil {
+		l.Log().Fatalln("Failed to mark flag 'config' as filename flag")
+	}
+	cmd.Flags().BoolVar(&reconcile, "reconcile", false, "Recreate the cluster if its config differs from the config file (deletes all data of the cluster!)")
+
+	// done
+	return cmd
+}
+
+// clusterRunning returns true if all k3s nodes of the cluster are running
+func clusterRunning(cluster *k3d.Cluster) bool {
+	for _, node := range cluster.Nodes {
+		if (node.Role == k3d.ServerRole || node.Role == k3d.AgentRole) && !node.State.Running {
+			return false
+		}
+	}
+	return true
+}
+
+// clusterConfigDrifted returns true if the config a cluster was created with (recorded in its node labels) differs from the given one.
+// Clusters without a recorded config (created by older versions of k3d) are never considered drifted.
+func clusterConfigDrifted(cluster *k3d.Cluster, simpleCfg conf.SimpleConfig) bool {
+	_, recorded, ok := k3dCluster.ClusterRecordedConfig(cluster)
+	if !ok {
+		l.Log().Debugf("Cluster '%s' has no recorded config: skipping drift detection", cluster.Name)
+		return false
+	}
+	wanted, err := config.MarshalSimpleConfig(simpleCfg)
+	if err != nil {
+		l.Log().Warnf("Failed to compare the config of cluster '%s': %v", cluster.Name, err)
+		return false
+	}
+	return string(wanted) != recorded
+}
@@ -0,0 +1,163 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rancher/k3d/v5/cmd/util"
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// clusterInspection is the structured description of a cluster printed by 'k3d cluster inspect'
+type clusterInspection struct {
+	Name           string            `yaml:"name" json:"name"`
+	Description    string            `yaml:"description,omitempty" json:"description,omitempty"`
+	Created        string            `yaml:"created,omitempty" json:"created,omitempty"` // creation time of the oldest node
+	Network        string            `yaml:"network" json:"network"`
+	ImageVolume    string            `yaml:"imageVolume,omitempty" json:"imageVolume,omitempty"`
+	ServersRunning int               `yaml:"serversRunning" json:"serversRunning"`
+	ServersCount   int               `yaml:"serversCount" json:"serversCount"`
+	AgentsRunning  int               `yaml:"agentsRunning" json:"agentsRunning"`
+	AgentsCount    int               `yaml:"agentsCount" json:"agentsCount"`
+	Endpoints      []client.Endpoint `yaml:"endpoints,omitempty" json:"endpoints,omitempty"`
+	Nodes          []nodeInspection  `yaml:"nodes" json:"nodes"`
+}
+
+type nodeInspection struct {
+	Name      string   `yaml:"name" json:"name"`
+	Role      string   `yaml:"role" json:"role"`
+	Image     string   `yaml:"image" json:"image"`
+	Status    string   `yaml:"status" json:"status"`
+	Running   bool     `yaml:"running" json:"running"`
+	OOMKilled bool     `yaml:"oomKilled,omitempty" json:"oomKilled,omitempty"` // processes in the node were killed for running out of memory
+	Created   string   `yaml:"created,omitempty" json:"created,omitempty"`
+	IP        string   `yaml:"ip,omitempty" json:"ip,omitempty"`
+	Ports     []string `yaml:"ports,omitempty" json:"ports,omitempty"`
+	Volumes   []string `yaml:"volumes,omitempty" json:"volumes,omitempty"`
+	Networks  []string `yaml:"networks,omitempty" json:"networks,omitempty"`
+}
+
+// NewCmdClusterInspect returns a new cobra command
+func NewCmdClusterInspect() *cobra.Command {
+	var output string
+
+	// create new command
+	cmd := &cobra.Command{
+		Use:   "inspect NAME",
+		Short: "Print the details of a cluster as structured data",
+		Long: `Print the details of a cluster (nodes with their status, image, ports, IP, networks and volumes, endpoints and creation time) as YAML or JSON,
+so that scripts and other tools can consume them without parsing tables or logs.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: util.ValidArgsAvailableClusters,
+		Run: func(cmd *cobra.Command, args []string) {
+			cluster, err := client.ClusterGet(cmd.Context(), runtimes.SelectedRuntime, &k3d.Cluster{Name: args[0]})
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+			inspection := inspectCluster(cluster)
+			if description, err := client.ClusterDescription(cmd.Context(), runtimes.SelectedRuntime, cluster); err != nil {
+				l.Log().Warnf("Failed to read the description of cluster '%s': %v", cluster.Name, err)
+			} else {
+				inspection.Description = description
+			}
+
+			var b []byte
+			switch strings.ToLower(output) {
+			case "json":
+				b, err = json.MarshalIndent(inspection, "", "  ")
+			case "yaml":
+				b, err = yaml.Marshal(inspection)
+			default:
+				l.Log().Fatalf("unknown output format '%s'", output)
+			}
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+			fmt.Println(strings.TrimSuffix(string(b), "\n"))
+		},
+	}
+
+	// add flags
+	cmd.Flags().StringVarP(&output, "output", "o", "yaml", "Output format. One of: json|yaml")
+
+	// done
+	return cmd
+}
+
+// inspectCluster collects the details of a cluster (as returned by ClusterGet) for printing
+func inspectCluster(cluster *k3d.Cluster) clusterInspection {
+	serverCount, serversRunning := cluster.ServerCountRunning()
+	agentCount, agentsRunning := cluster.AgentCountRunning()
+	inspection := clusterInspection{
+		Name:           cluster.Name,
+		Description:    cluster.Description,
+		Network:        cluster.Network.Name,
+		ImageVolume:    cluster.ImageVolume,
+		ServersRunning: serversRunning,
+		ServersCount:   serverCount,
+		AgentsRunning:  agentsRunning,
+		AgentsCount:    agentCount,
+		Endpoints:      client.ClusterEndpoints(cluster),
+		Nodes:          []nodeInspection{},
+	}
+
+	for _, node := range cluster.Nodes {
+		n := nodeInspection{
+			Name:      node.Name,
+			Role:      string(node.Role),
+			Image:     node.Image,
+			Status:    node.State.Status,
+			Running:   node.State.Running,
+			OOMKilled: node.State.OOMKilled,
+			Created:   node.Created,
+			Volumes:   node.Volumes,
+			Networks:  node.Networks,
+		}
+		if pinned, ok := node.RuntimeLabels[k3d.LabelImagePinned]; ok { // more telling than the image ID reported by the runtime
+			n.Image = pinned
+		}
+		if !node.IP.IP.IsZero() {
+			n.IP = node.IP.IP.String()
+		}
+		for _, mapping := range client.NodePortMappings(node) {
+			n.Ports = append(n.Ports, mapping.Short())
+		}
+		if inspection.Created == "" || (node.Created != "" && node.Created < inspection.Created) {
+			inspection.Created = node.Created
+		}
+		inspection.Nodes = append(inspection.Nodes, n)
+	}
+	sort.Slice(inspection.Nodes, func(i, j int) bool {
+		return inspection.Nodes[i].Name < inspection.Nodes[j].Name
+	})
+
+	return inspection
+}
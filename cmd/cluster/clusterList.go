@@ -41,9 +41,11 @@ import (
 
 // TODO : deal with --all flag to manage differentiate started cluster and stopped cluster like `docker ps` and `docker ps -a`
 type clusterFlags struct {
-	noHeader bool
-	token    bool
-	output   string
+	noHeader        bool
+	token           bool
+	output          string
+	owner           string
+	checkKubeconfig bool
 }
 
 // NewCmdClusterList returns a new cobra command
@@ -57,8 +59,25 @@ func NewCmdClusterList() *cobra.Command {
 		Short:   "List cluster(s)",
 		Long:    `List cluster(s).`,
 		Run: func(cmd *cobra.Command, args []string) {
-			clusters := buildClusterList(cmd.Context(), args)
-			PrintClusters(clusters, clusterFlags)
+			if clusterFlags.owner == "" {
+				clusterFlags.owner = util.Tenant
+			}
+			clusters := buildClusterList(cmd.Context(), args, clusterFlags.owner)
+			for _, cluster := range clusters {
+				description, err := k3cluster.ClusterDescription(cmd.Context(), runtimes.SelectedRuntime, cluster)
+				if err != nil {
+					l.Log().Warnln(err)
+					continue
+				}
+				cluster.Description = description
+			}
+			kubeconfigChecks := map[string]*k3d.KubeconfigCheck{}
+			if clusterFlags.checkKubeconfig {
+				for _, cluster := range clusters {
+					kubeconfigChecks[cluster.Name] = k3cluster.KubeconfigCheck(cmd.Context(), cluster)
+				}
+			}
+			PrintClusters(clusters, clusterFlags, kubeconfigChecks)
 		},
 		ValidArgsFunction: util.ValidArgsAvailableClusters,
 	}
@@ -67,6 +86,8 @@ func NewCmdClusterList() *cobra.Command {
 	cmd.Flags().BoolVar(&clusterFlags.noHeader, "no-headers", false, "Disable headers")
 	cmd.Flags().BoolVar(&clusterFlags.token, "token", false, "Print k3s cluster token")
 	cmd.Flags().StringVarP(&clusterFlags.output, "output", "o", "", "Output format. One of: json|yaml")
+	cmd.Flags().BoolVar(&clusterFlags.checkKubeconfig, "check-kubeconfig", false, "Check whether the kubeconfig recorded for each running cluster (default kubeconfig or the cluster's kubeconfig file) still authenticates and mark stale ones")
+	cmd.Flags().StringVar(&clusterFlags.owner, "owner", "", "Only list the clusters of this owner (the tenant or user who created them; default: the tenant, if set)")
 
 	// add subcommands
 
@@ -74,7 +95,7 @@ func NewCmdClusterList() *cobra.Command {
 	return cmd
 }
 
-func buildClusterList(ctx context.Context, args []string) []*k3d.Cluster {
+func buildClusterList(ctx context.Context, args []string, owner string) []*k3d.Cluster {
 	var clusters []*k3d.Cluster
 	var err error
 
@@ -84,6 +105,15 @@ func buildClusterList(ctx context.Context, args []string) []*k3d.Cluster {
 		if err != nil {
 			l.Log().Fatalln(err)
 		}
+		if owner != "" {
+			owned := []*k3d.Cluster{}
+			for _, cluster := range clusters {
+				if len(cluster.Nodes) > 0 && cluster.Nodes[0].RuntimeLabels[k3d.LabelClusterOwner] == owner {
+					owned = append(owned, cluster)
+				}
+			}
+			clusters = owned
+		}
 	} else {
 		for _, clusterName := range args {
 			// cluster name specified : get specific cluster
@@ -99,15 +129,18 @@ func buildClusterList(ctx context.Context, args []string) []*k3d.Cluster {
 }
 
 // PrintPrintClusters : display list of cluster
-func PrintClusters(clusters []*k3d.Cluster, flags clusterFlags) {
+func PrintClusters(clusters []*k3d.Cluster, flags clusterFlags, kubeconfigChecks map[string]*k3d.KubeconfigCheck) {
 	// the output details printed when we dump JSON/YAML
 	type jsonOutput struct {
 		k3d.Cluster
-		ServersRunning int  `yaml:"servers_running" json:"serversRunning"`
-		ServersCount   int  `yaml:"servers_count" json:"serversCount"`
-		AgentsRunning  int  `yaml:"agents_running" json:"agentsRunning"`
-		AgentsCount    int  `yaml:"agents_count" json:"agentsCount"`
-		LoadBalancer   bool `yaml:"has_lb,omitempty" json:"hasLoadbalancer,omitempty"`
+		ServersRunning int                  `yaml:"servers_running" json:"serversRunning"`
+		ServersCount   int                  `yaml:"servers_count" json:"serversCount"`
+		AgentsRunning  int                  `yaml:"agents_running" json:"agentsRunning"`
+		AgentsCount    int                  `yaml:"agents_count" json:"agentsCount"`
+		LoadBalancer   bool                 `yaml:"has_lb,omitempty" json:"hasLoadbalancer,omitempty"`
+		Ports          []string             `yaml:"ports,omitempty" json:"ports,omitempty"`
+		OOMKilled      []string             `yaml:"oom_killed,omitempty" json:"oomKilled,omitempty"` // nodes with OOM-killed processes
+		Kubeconfig     *k3d.KubeconfigCheck `yaml:"kubeconfig,omitempty" json:"kubeconfig,omitempty"`
 	}
 
 	jsonOutputEntries := []jsonOutput{}
@@ -119,7 +152,10 @@ func PrintClusters(clusters []*k3d.Cluster, flags clusterFlags) {
 
 	if outputFormat != "json" && outputFormat != "yaml" {
 		if !flags.noHeader {
-			headers := []string{"NAME", "SERVERS", "AGENTS", "LOADBALANCER"} // TODO: getCluster: add status column
+			headers := []string{"NAME", "SERVERS", "AGENTS", "LOADBALANCER", "PORTS", "DESCRIPTION", "WARN"} // TODO: getCluster: add status column
+			if flags.checkKubeconfig {
+				headers = append(headers, "KUBECONFIG")
+			}
 			if flags.token {
 				headers = append(headers, "TOKEN")
 			}
@@ -132,10 +168,19 @@ func PrintClusters(clusters []*k3d.Cluster, flags clusterFlags) {
 
 	k3cluster.SortClusters(clusters)
 
+	oomKilled := []*k3d.Node{}
 	for _, cluster := range clusters {
+		clusterOOMKilled := cluster.OOMKilledNodes()
+		oomKilled = append(oomKilled, clusterOOMKilled...)
+		oomKilledNames := []string{}
+		for _, node := range clusterOOMKilled {
+			oomKilledNames = append(oomKilledNames, node.Name)
+		}
+
 		serverCount, serversRunning := cluster.ServerCountRunning()
 		agentCount, agentsRunning := cluster.AgentCountRunning()
 		hasLB := cluster.HasLoadBalancer()
+		mappings := k3cluster.ClusterPortMappings(cluster)
 
 		if outputFormat == "json" || outputFormat == "yaml" {
 			entry := jsonOutput{
@@ -145,6 +190,11 @@ func PrintClusters(clusters []*k3d.Cluster, flags clusterFlags) {
 				AgentsRunning:  agentsRunning,
 				AgentsCount:    agentCount,
 				LoadBalancer:   hasLB,
+				OOMKilled:      oomKilledNames,
+				Kubeconfig:     kubeconfigChecks[cluster.Name],
+			}
+			for _, mapping := range mappings {
+				entry.Ports = append(entry.Ports, mapping.String())
 			}
 
 			if !flags.token {
@@ -156,11 +206,19 @@ func PrintClusters(clusters []*k3d.Cluster, flags clusterFlags) {
 
 			jsonOutputEntries = append(jsonOutputEntries, entry)
 		} else {
+			ports := clusterPortsColumn(cluster, mappings)
+			warn := ""
+			if len(oomKilledNames) > 0 {
+				warn = "oom-killed:" + strings.Join(oomKilledNames, ",")
+			}
+			columns := []string{cluster.Name, fmt.Sprintf("%d/%d", serversRunning, serverCount), fmt.Sprintf("%d/%d", agentsRunning, agentCount), fmt.Sprintf("%t", hasLB), ports, cluster.Description, warn}
+			if flags.checkKubeconfig {
+				columns = append(columns, string(kubeconfigChecks[cluster.Name].Validity))
+			}
 			if flags.token {
-				fmt.Fprintf(tabwriter, "%s\t%d/%d\t%d/%d\t%t\t%s\n", cluster.Name, serversRunning, serverCount, agentsRunning, agentCount, hasLB, cluster.Token)
-			} else {
-				fmt.Fprintf(tabwriter, "%s\t%d/%d\t%d/%d\t%t\n", cluster.Name, serversRunning, serverCount, agentsRunning, agentCount, hasLB)
+				columns = append(columns, cluster.Token)
 			}
+			fmt.Fprintf(tabwriter, "%s\n", strings.Join(columns, "\t"))
 		}
 	}
 
@@ -179,4 +237,42 @@ func PrintClusters(clusters []*k3d.Cluster, flags clusterFlags) {
 		}
 		fmt.Println(string(b))
 	}
+
+	// below the table (the hint goes to stderr, so it doesn't break parsing the JSON/YAML output)
+	if hint := k3cluster.OOMKilledHint(oomKilled); hint != "" {
+		tabwriter.Flush()
+		l.Log().Warnln(hint)
+	}
+	for _, cluster := range clusters {
+		if check := kubeconfigChecks[cluster.Name]; check != nil && check.Validity == k3d.KubeconfigStale {
+			tabwriter.Flush()
+			l.Log().Warnf("Kubeconfig '%s' is stale for cluster '%s' (%s): %s", check.Path, cluster.Name, check.Reason, staleKubeconfigFix(cluster.Name, check.Path))
+		}
+	}
+}
+
+// staleKubeconfigFix returns the hint on how to refresh the cluster's entries in the given kubeconfig
+func staleKubeconfigFix(clusterName string, path string) string {
+	if clusterFile, err := k3cluster.KubeconfigClusterFilePath(clusterName); err == nil && clusterFile == path {
+		return fmt.Sprintf("refresh it with 'k3d kubeconfig merge %s'", clusterName)
+	}
+	return fmt.Sprintf("refresh it with 'k3d kubeconfig merge %s --kubeconfig-merge-default'", clusterName)
+}
+
+// clusterPortsColumn renders the published ports of a cluster in the compact docker ps style.
+// Ports not published via the loadbalancer are suffixed with the node they belong to.
+func clusterPortsColumn(cluster *k3d.Cluster, mappings []k3cluster.PortMapping) string {
+	roles := map[string]k3d.Role{}
+	for _, node := range cluster.Nodes {
+		roles[node.Name] = node.Role
+	}
+	ports := []string{}
+	for _, mapping := range mappings {
+		if roles[mapping.Node] == k3d.LoadBalancerRole {
+			ports = append(ports, mapping.Short())
+		} else {
+			ports = append(ports, fmt.Sprintf("%s@%s", mapping.Short(), mapping.Node))
+		}
+	}
+	return strings.Join(ports, ",")
 }
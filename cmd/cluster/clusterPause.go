@@ -0,0 +1,135 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cluster
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/rancher/k3d/v5/cmd/util"
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// NewCmdPause returns a new cobra command
+func NewCmdPause() *cobra.Command {
+
+	// create new command
+	cmd := &cobra.Command{
+		Use:   "pause [--name NAME [--name NAME...] | --all]",
+		Short: "Pause cluster(s), freezing all of their processes until 'k3d unpause'",
+		Long: `Pause cluster(s) via the container runtime (e.g. 'docker pause'): all processes of the nodes are suspended in place,
+so the cluster doesn't use any CPU anymore and is resumed with its full state within seconds via 'k3d unpause'.
+Unlike 'k3d cluster stop', memory isn't freed. Unlike 'k3d freeze', no experimental runtime features are required,
+but the state doesn't survive a restart of the runtime.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			for _, cluster := range parseClusterSelection(cmd) {
+				if err := client.ClusterPause(cmd.Context(), runtimes.SelectedRuntime, cluster); err != nil {
+					l.Log().Fatalln(err)
+				}
+				l.Log().Infof("Paused cluster '%s'", cluster.Name)
+			}
+		},
+	}
+
+	// add flags
+	addClusterSelectionFlags(cmd, "Pause")
+
+	// done
+	return cmd
+}
+
+// NewCmdUnpause returns a new cobra command
+func NewCmdUnpause() *cobra.Command {
+
+	// create new command
+	cmd := &cobra.Command{
+		Use:   "unpause [--name NAME [--name NAME...] | --all]",
+		Short: "Resume cluster(s) paused via 'k3d pause'",
+		Long:  `Resume cluster(s) paused via 'k3d pause'.`,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			for _, cluster := range parseClusterSelection(cmd) {
+				if err := client.ClusterUnpause(cmd.Context(), runtimes.SelectedRuntime, cluster); err != nil {
+					l.Log().Fatalln(err)
+				}
+				l.Log().Infof("Unpaused cluster '%s'", cluster.Name)
+			}
+		},
+	}
+
+	// add flags
+	addClusterSelectionFlags(cmd, "Unpause")
+
+	// done
+	return cmd
+}
+
+// addClusterSelectionFlags adds the --name and --all flags parsed by parseClusterSelection
+func addClusterSelectionFlags(cmd *cobra.Command, verb string) {
+	cmd.Flags().StringSliceP("name", "n", nil, verb+" the cluster with this name (repeatable, default: '"+k3d.DefaultClusterName+"')")
+	if err := cmd.RegisterFlagCompletionFunc("name", util.ValidArgsAvailableClusters); err != nil {
+		l.Log().Fatalln("Failed to register flag completion for '--name'", err)
+	}
+	cmd.Flags().BoolP("all", "a", false, verb+" all existing clusters")
+}
+
+// parseClusterSelection returns the clusters selected via --name or --all
+func parseClusterSelection(cmd *cobra.Command) []*k3d.Cluster {
+	names, err := cmd.Flags().GetStringSlice("name")
+	if err != nil {
+		l.Log().Fatalln(err)
+	}
+	all, err := cmd.Flags().GetBool("all")
+	if err != nil {
+		l.Log().Fatalln(err)
+	}
+	if all && len(names) > 0 {
+		l.Log().Fatalln("Cannot use both '--name' and '--all' at the same time")
+	}
+
+	if all {
+		clusters, err := client.ClusterList(cmd.Context(), runtimes.SelectedRuntime)
+		if err != nil {
+			l.Log().Fatalln(err)
+		}
+		if len(clusters) == 0 {
+			l.Log().Infoln("No clusters found")
+		}
+		return clusters
+	}
+
+	if len(names) == 0 {
+		names = []string{k3d.DefaultClusterName}
+	}
+	clusters := make([]*k3d.Cluster, 0, len(names))
+	for _, name := range names {
+		cluster, err := client.ClusterGet(cmd.Context(), runtimes.SelectedRuntime, &k3d.Cluster{Name: name})
+		if err != nil {
+			l.Log().Fatalf("Failed to get cluster '%s': %v", name, err)
+		}
+		clusters = append(clusters, cluster)
+	}
+	return clusters
+}
@@ -0,0 +1,78 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cluster
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// NewCmdReconcile returns a new cobra command
+func NewCmdReconcile() *cobra.Command {
+
+	opts := k3d.ClusterReconcileOpts{}
+
+	// create new command
+	cmd := &cobra.Command{
+		Use:   "reconcile [--name NAME [--name NAME...] | --all]",
+		Short: "Restart agents that came up before the servers of their cluster, e.g. after a restart of the docker daemon",
+		Long: `Restart agents that came up before the servers of their cluster.
+When the docker daemon restarts, it brings back the node containers (restart policy) in arbitrary order,
+so agents may start before the servers and fail to rejoin the cluster. This detects running agents that were started
+before the last (re-)start of a running server and agents that are stuck restarting, waits for the servers to be ready
+and restarts those agents. Stopped agents and clusters without a running server are left alone.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			for _, cluster := range parseClusterSelection(cmd) {
+				agents, err := client.ClusterReconcile(cmd.Context(), runtimes.SelectedRuntime, cluster, opts)
+				if err != nil {
+					l.Log().Fatalf("Failed to reconcile cluster '%s': %v", cluster.Name, err)
+				}
+				if len(agents) == 0 {
+					l.Log().Infof("Cluster '%s' is fine, no agents to restart", cluster.Name)
+					continue
+				}
+				for _, agent := range agents {
+					if opts.DryRun {
+						l.Log().Infof("Would restart agent '%s' of cluster '%s'", agent.Name, cluster.Name)
+					} else {
+						l.Log().Infof("Restarted agent '%s' of cluster '%s'", agent.Name, cluster.Name)
+					}
+				}
+			}
+		},
+	}
+
+	// add flags
+	addClusterSelectionFlags(cmd, "Reconcile")
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "Only list the agents that would be restarted")
+	cmd.Flags().DurationVar(&opts.Timeout, "timeout", 5*time.Minute, "Maximum time to wait for the servers to be ready and the agents to be restarted (per cluster)")
+
+	// done
+	return cmd
+}
@@ -0,0 +1,205 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cluster
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	cliutil "github.com/rancher/k3d/v5/cmd/util"
+	k3dCluster "github.com/rancher/k3d/v5/pkg/client"
+	"github.com/rancher/k3d/v5/pkg/config"
+	conf "github.com/rancher/k3d/v5/pkg/config/v1alpha3"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/rancher/k3d/v5/version"
+)
+
+// recordVersionPrefix starts the header line of recorded config files that names the k3d version used to create the cluster
+const recordVersionPrefix = "# k3d version: "
+
+// recordClusterCreate records the effective config and the command line of a cluster creation in labels of the nodes,
+// so that 'k3d record' can turn them into a config file later on
+func recordClusterCreate(clusterConfig *conf.ClusterConfig, simpleCfg conf.SimpleConfig) {
+	recorded, err := config.MarshalSimpleConfig(simpleCfg)
+	if err != nil {
+		l.Log().Warnf("Failed to record the config of cluster '%s': %v", clusterConfig.Cluster.Name, err)
+		return
+	}
+	clusterConfig.ClusterCreateOpts.GlobalLabels[k3d.LabelClusterCreateConfig] = string(recorded)
+	clusterConfig.ClusterCreateOpts.GlobalLabels[k3d.LabelClusterCreateCommand] = recordedCommandLine(os.Args)
+}
+
+// recordedCommandLine returns the (shell-quoted) command line with the value of the --token flag masked
+func recordedCommandLine(args []string) string {
+	quoted := make([]string, 0, len(args))
+	maskNext := false
+	for i, arg := range args {
+		switch {
+		case maskNext:
+			arg = "***"
+			maskNext = false
+		case arg == "--token":
+			maskNext = true
+		case strings.HasPrefix(arg, "--token="):
+			arg = "--token=***"
+		}
+		if i == 0 {
+			arg = "k3d"
+		}
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"\\$`*?&|;<>(){}[]!#~") {
+			arg = strconv.Quote(arg)
+		}
+		quoted = append(quoted, arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// NewCmdRecord returns a new cobra command
+func NewCmdRecord() *cobra.Command {
+
+	var output string
+	var force bool
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:   "record [CLUSTERNAME]",
+		Short: "Write the config a cluster was created with to a config file",
+		Long: `Write the config a cluster was created with (config file and flags of 'k3d cluster create' combined) to a config file, which reproduces the cluster via 'k3d replay'.
+The config file starts with comments naming the k3d version and the original command line. The cluster token is not recorded.`,
+		Example: `  k3d record mycluster -o mycluster.yaml
+  k3d replay mycluster.yaml mycluster-2`,
+		Args:              cobra.MaximumNArgs(1), // 0 or 1 cluster name
+		ValidArgsFunction: cliutil.ValidArgsAvailableClusters,
+		Run: func(cmd *cobra.Command, args []string) {
+			cluster := &k3d.Cluster{Name: k3d.DefaultClusterName}
+			if len(args) > 0 {
+				cluster.Name = args[0]
+			}
+			cluster, err := k3dCluster.ClusterGet(cmd.Context(), runtimes.SelectedRuntime, cluster)
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+
+			recordedNode, recordedConfig, ok := k3dCluster.ClusterRecordedConfig(cluster)
+			if !ok {
+				l.Log().Fatalf("Cluster '%s' has no recorded config: it was created with an older version of k3d or not via 'k3d cluster create'", cluster.Name)
+			}
+
+			var header bytes.Buffer
+			fmt.Fprintf(&header, "# Recorded from cluster '%s' created at %s\n", cluster.Name, recordedNode.Created)
+			fmt.Fprintf(&header, "%s%s\n", recordVersionPrefix, recordedNode.RuntimeLabels["k3d.version"])
+			fmt.Fprintf(&header, "# Command: %s\n", recordedNode.RuntimeLabels[k3d.LabelClusterCreateCommand])
+			fmt.Fprintf(&header, "# Replay: k3d replay FILE [CLUSTERNAME]\n")
+			content := append(header.Bytes(), []byte(recordedConfig)...)
+
+			if output == "-" {
+				fmt.Print(string(content))
+				return
+			}
+			if output == "" {
+				output = fmt.Sprintf("%s-%s.yaml", k3d.DefaultObjectNamePrefix, cluster.Name)
+			}
+			if _, err := os.Stat(output); err == nil && !force {
+				l.Log().Fatalf("Output file '%s' exists and --force was not set", output)
+			} else if err != nil && !os.IsNotExist(err) {
+				l.Log().Fatalf("Failed to stat output file: %+v", err)
+			}
+			if err := os.WriteFile(output, content, 0644); err != nil {
+				l.Log().Fatalf("Failed to write output file: %v", err)
+			}
+			l.Log().Infof("Recorded the config of cluster '%s' in '%s': reproduce it with 'k3d replay %s'", cluster.Name, output, output)
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Write the config file to this path or to stdout with '-' (Format: `FILE`, default: k3d-CLUSTERNAME.yaml)")
+	if err := cmd.MarkFlagFilename("output", "yaml", "yml"); err != nil {
+		l.Log().Fatalf("Failed to mark flag 'output' as filename flag: %v", err)
+	}
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Force overwrite of the output file")
+
+	// done
+	return cmd
+}
+
+// NewCmdReplay returns a new cobra command
+func NewCmdReplay() *cobra.Command {
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:   "replay FILE [CLUSTERNAME]",
+		Short: "Create a cluster from a config file written by 'k3d record'",
+		Long: `Create a cluster from a config file written by 'k3d record' (like 'k3d cluster create --config FILE [CLUSTERNAME]').
+Warns if the file was recorded with a different version of k3d, as defaults (e.g. the k3s image) may have changed in between.`,
+		Args: cobra.RangeArgs(1, 2),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) == 0 {
+				return []string{"yaml", "yml"}, cobra.ShellCompDirectiveFilterFileExt
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			if recordedVersion, err := readRecordedVersion(args[0]); err != nil {
+				l.Log().Fatalln(err)
+			} else if recordedVersion != "" && recordedVersion != version.GetVersion() {
+				l.Log().Warnf("'%s' was recorded with k3d %s, but this is k3d %s: the resulting cluster may differ", args[0], recordedVersion, version.GetVersion())
+			}
+
+			configFile = args[0]
+			if err := initConfig(); err != nil {
+				l.Log().Fatalln(err)
+			}
+			runClusterCreate(cmd, args[1:])
+		},
+	}
+
+	// done
+	return cmd
+}
+
+// readRecordedVersion returns the k3d version noted in the header of a recorded config file (empty if there's none)
+func readRecordedVersion(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "#") {
+			break // end of the header
+		}
+		if strings.HasPrefix(line, recordVersionPrefix) {
+			return strings.TrimSpace(strings.TrimPrefix(line, recordVersionPrefix)), nil
+		}
+	}
+	return "", scanner.Err()
+}
@@ -22,6 +22,8 @@ THE SOFTWARE.
 package cluster
 
 import (
+	"context"
+	"fmt"
 	"time"
 
 	"github.com/rancher/k3d/v5/cmd/util"
@@ -38,11 +40,12 @@ import (
 func NewCmdClusterStart() *cobra.Command {
 
 	startClusterOpts := types.ClusterStartOpts{}
+	var updateKubeconfig bool
 
 	// create new command
 	cmd := &cobra.Command{
 		Use:               "start [NAME [NAME...] | --all]",
-		Long:              `Start existing k3d cluster(s)`,
+		Long:              "Start existing k3d cluster(s).\nRegistries and clusters they depend on (see 'cluster create --depends-on') are started first, each cluster only after the clusters it depends on are ready.",
 		Short:             "Start existing k3d cluster(s)",
 		ValidArgsFunction: util.ValidArgsAvailableClusters,
 		Run: func(cmd *cobra.Command, args []string) {
@@ -50,15 +53,50 @@ func NewCmdClusterStart() *cobra.Command {
 			if len(clusters) == 0 {
 				l.Log().Infoln("No clusters found")
 			} else {
-				for _, c := range clusters {
+				plan, err := client.ClusterStartOrder(cmd.Context(), runtimes.SelectedRuntime, clusters)
+				if err != nil {
+					l.Log().Fatalln(err)
+				}
+				// registries first, as the nodes pull from them right away
+				if len(plan.Registries) > 0 {
+					regCtx, cancel := cmd.Context(), context.CancelFunc(func() {})
+					if startClusterOpts.Timeout > 0 {
+						regCtx, cancel = context.WithTimeout(regCtx, startClusterOpts.Timeout)
+					}
+					for _, reg := range plan.Registries {
+						if err := client.NodeStart(regCtx, runtimes.SelectedRuntime, reg, &k3d.NodeStartOpts{Wait: true}); err != nil {
+							l.Log().Fatalf("failed to start registry '%s': %v", reg.Name, err)
+						}
+					}
+					cancel()
+				}
+				for _, c := range plan.Clusters {
 					envInfo, err := client.GatherEnvironmentInfo(cmd.Context(), runtimes.SelectedRuntime, c)
 					if err != nil {
 						l.Log().Fatalf("failed to gather info about cluster environment: %v", err)
 					}
-					startClusterOpts.EnvironmentInfo = envInfo
-					if err := client.ClusterStart(cmd.Context(), runtimes.SelectedRuntime, c, startClusterOpts); err != nil {
+					clusterStartOpts := startClusterOpts
+					clusterStartOpts.EnvironmentInfo = envInfo
+					if plan.Required[c.Name] && !clusterStartOpts.WaitForServer {
+						l.Log().Infof("Waiting for cluster '%s' to be ready, as other clusters depend on it", c.Name)
+						clusterStartOpts.WaitForServer = true
+					}
+					if err := client.ClusterStart(cmd.Context(), runtimes.SelectedRuntime, c, clusterStartOpts); err != nil {
 						l.Log().Fatalln(err)
 					}
+					refreshed := false
+					if updateKubeconfig {
+						refreshed, err = client.KubeconfigRefreshDefault(cmd.Context(), runtimes.SelectedRuntime, c)
+						if err != nil {
+							l.Log().Warnf("Failed to refresh the default kubeconfig for cluster '%s': %v", c.Name, err)
+						} else if refreshed {
+							l.Log().Infof("Refreshed the default kubeconfig for cluster '%s'", c.Name)
+						}
+					}
+					refreshClusterKubeconfigFile(cmd, c)
+					if clusterStartOpts.WaitForServer && !util.CIMode {
+						printClusterStarted(cmd, c, refreshed)
+					}
 				}
 			}
 		},
@@ -68,6 +106,10 @@ func NewCmdClusterStart() *cobra.Command {
 	cmd.Flags().BoolP("all", "a", false, "Start all existing clusters")
 	cmd.Flags().BoolVar(&startClusterOpts.WaitForServer, "wait", true, "Wait for the server(s) (and loadbalancer) to be ready before returning.")
 	cmd.Flags().DurationVar(&startClusterOpts.Timeout, "timeout", 0*time.Second, "Maximum waiting time for '--wait' before canceling/returning.")
+	cmd.Flags().DurationVar(&startClusterOpts.ReadinessTimeout, "readiness-timeout", 0*time.Second, "Maximum time for each phase of the readiness check with '--wait': the Kubernetes API answering on /readyz via the published API port and all nodes being Ready")
+	cmd.Flags().BoolVar(&updateKubeconfig, "kubeconfig-update-default", true, "Refresh the cluster's entry in the default kubeconfig (if it has one), as the API port may change on restart")
+	cmd.Flags().BoolVar(&startClusterOpts.DeferWorkers, "defer-workers", false, "Start the agent nodes only after the servers passed their readiness checks")
+	cmd.Flags().BoolVar(&startClusterOpts.RecreateFailed, "recreate-failed", false, "Recreate agent nodes from their spec if they repeatedly fail to start (e.g. exited or corrupted containers)")
 
 	// add subcommands
 
@@ -75,6 +117,20 @@ func NewCmdClusterStart() *cobra.Command {
 	return cmd
 }
 
+// printClusterStarted prints the API endpoint the kubeconfig of a started cluster points to (the port may have changed) and how to use it
+func printClusterStarted(cmd *cobra.Command, cluster *k3d.Cluster, inDefaultKubeconfig bool) {
+	kubeconfig, err := client.KubeconfigGet(cmd.Context(), runtimes.SelectedRuntime, cluster)
+	if err != nil {
+		l.Log().Warnf("Failed to get kubeconfig of cluster '%s': %v", cluster.Name, err)
+		return
+	}
+	if entry, ok := kubeconfig.Clusters[fmt.Sprintf("%s-%s", k3d.DefaultObjectNamePrefix, cluster.Name)]; ok {
+		l.Log().Infof("Cluster '%s' started, its Kubernetes API is available at %s", cluster.Name, entry.Server)
+	}
+	l.Log().Infoln("You can now use it like this:")
+	printKubectlUsage(cluster, inDefaultKubeconfig, false)
+}
+
 // parseStartClusterCmd parses the command input into variables required to start clusters
 func parseStartClusterCmd(cmd *cobra.Command, args []string) []*k3d.Cluster {
 	// --all
@@ -105,3 +161,13 @@ func parseStartClusterCmd(cmd *cobra.Command, args []string) []*k3d.Cluster {
 
 	return clusters
 }
+
+// refreshClusterKubeconfigFile refreshes the cluster's dedicated kubeconfig file (if there is one), when it doesn't match the cluster anymore
+func refreshClusterKubeconfigFile(cmd *cobra.Command, cluster *k3d.Cluster) {
+	refreshed, err := client.KubeconfigRefreshClusterFile(cmd.Context(), runtimes.SelectedRuntime, cluster)
+	if err != nil {
+		l.Log().Warnf("Failed to refresh the kubeconfig file of cluster '%s': %v", cluster.Name, err)
+	} else if refreshed {
+		l.Log().Infof("Refreshed the stale kubeconfig file of cluster '%s'", cluster.Name)
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/liggitt/tabwriter"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// NewCmdStatus returns a new cobra command
+func NewCmdStatus() *cobra.Command {
+
+	var noHeader bool
+	var output string
+
+	// create new command
+	cmd := &cobra.Command{
+		Use:   "status [--name NAME [--name NAME...] | --all]",
+		Short: "Show the state of the nodes of cluster(s) and whether their Kubernetes API is reachable",
+		Long: `Show the container state (e.g. running, paused or exited) and uptime of each node of cluster(s)
+and whether the cluster's Kubernetes API is reachable via its published API port.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			statuses := []*k3d.ClusterStatus{}
+			for _, cluster := range parseClusterSelection(cmd) {
+				statuses = append(statuses, client.ClusterStatus(cmd.Context(), runtimes.SelectedRuntime, cluster))
+			}
+			printClusterStatus(statuses, noHeader, output)
+		},
+	}
+
+	// add flags
+	addClusterSelectionFlags(cmd, "Show")
+	cmd.Flags().BoolVar(&noHeader, "no-headers", false, "Disable headers")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Output format. One of: json|yaml")
+
+	// done
+	return cmd
+}
+
+func printClusterStatus(statuses []*k3d.ClusterStatus, noHeader bool, output string) {
+	switch strings.ToLower(output) {
+	case "json":
+		b, err := json.Marshal(statuses)
+		if err != nil {
+			l.Log().Fatalln(err)
+		}
+		fmt.Println(string(b))
+	case "yaml":
+		b, err := yaml.Marshal(statuses)
+		if err != nil {
+			l.Log().Fatalln(err)
+		}
+		fmt.Println(string(b))
+	default:
+		tabwriter := tabwriter.NewWriter(os.Stdout, 6, 4, 3, ' ', tabwriter.RememberWidths)
+		defer tabwriter.Flush()
+		if !noHeader {
+			fmt.Fprintf(tabwriter, "%s\n", strings.Join([]string{"CLUSTER", "API", "NODE", "ROLE", "STATE", "UPTIME"}, "\t"))
+		}
+		for _, status := range statuses {
+			api := "reachable"
+			if !status.APIReachable {
+				api = "unreachable"
+				l.Log().Debugf("Kubernetes API of cluster '%s' not reachable: %s", status.Cluster, status.APIError)
+			}
+			for _, node := range status.Nodes {
+				uptime := "-"
+				if !node.Started.IsZero() {
+					uptime = units.HumanDuration(time.Since(node.Started))
+				}
+				fmt.Fprintf(tabwriter, "%s\t%s\t%s\t%s\t%s\t%s\n", status.Cluster, api, node.Name, node.Role, node.State, uptime)
+			}
+		}
+	}
+}
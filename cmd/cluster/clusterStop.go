@@ -34,11 +34,17 @@ import (
 // NewCmdClusterStop returns a new cobra command
 func NewCmdClusterStop() *cobra.Command {
 
+	stopClusterOpts := k3d.ClusterStopOpts{}
+
 	// create new command
 	cmd := &cobra.Command{
-		Use:               "stop [NAME [NAME...] | --all]",
-		Short:             "Stop existing k3d cluster(s)",
-		Long:              `Stop existing k3d cluster(s).`,
+		Use:   "stop [NAME [NAME...] | --all]",
+		Short: "Stop existing k3d cluster(s)",
+		Long: `Stop existing k3d cluster(s).
+
+With --graceful, all nodes are drained before stopping them, so that workloads (e.g. databases) get terminated
+with their termination grace period instead of being killed along with the node containers.
+The drained nodes are uncordoned when the cluster is started again.`,
 		ValidArgsFunction: util.ValidArgsAvailableClusters,
 		Run: func(cmd *cobra.Command, args []string) {
 			clusters := parseStopClusterCmd(cmd, args)
@@ -46,7 +52,7 @@ func NewCmdClusterStop() *cobra.Command {
 				l.Log().Infoln("No clusters found")
 			} else {
 				for _, c := range clusters {
-					if err := client.ClusterStop(cmd.Context(), runtimes.SelectedRuntime, c); err != nil {
+					if err := client.ClusterStop(cmd.Context(), runtimes.SelectedRuntime, c, stopClusterOpts); err != nil {
 						l.Log().Fatalln(err)
 					}
 				}
@@ -56,6 +62,8 @@ func NewCmdClusterStop() *cobra.Command {
 
 	// add flags
 	cmd.Flags().BoolP("all", "a", false, "Stop all existing clusters")
+	cmd.Flags().BoolVar(&stopClusterOpts.Graceful, "graceful", false, "Drain all nodes (evicting their pods with their termination grace period) before stopping them")
+	cmd.Flags().DurationVar(&stopClusterOpts.GracefulTimeout, "graceful-timeout", k3d.DefaultGracefulStopTimeout, "Maximum time to wait for the drain with '--graceful' before stopping the nodes anyway")
 
 	// add subcommands
 
@@ -0,0 +1,159 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// printKubectlUsage prints how to use a cluster with kubectl, depending on whether it's in the default kubeconfig and its current context
+func printKubectlUsage(cluster *k3d.Cluster, inDefaultKubeconfig bool, isCurrentContext bool) {
+	if inDefaultKubeconfig && !isCurrentContext {
+		contextName, err := client.KubeconfigContextName(cluster)
+		if err != nil {
+			contextName = fmt.Sprintf("%s-%s", k3d.DefaultObjectNamePrefix, cluster.Name)
+		}
+		fmt.Printf("kubectl config use-context %s\n", contextName)
+	} else if !isCurrentContext {
+		if runtime.GOOS == "windows" {
+			fmt.Printf("$env:KUBECONFIG=(%s kubeconfig write %s)\n", os.Args[0], cluster.Name)
+		} else {
+			fmt.Printf("export KUBECONFIG=$(%s kubeconfig write %s)\n", os.Args[0], cluster.Name)
+		}
+	}
+	fmt.Println("kubectl cluster-info")
+}
+
+// clusterOperationSummary is the report printed at the end of cluster create/delete
+type clusterOperationSummary struct {
+	Operation  string               `json:"operation"`
+	Cluster    string               `json:"cluster"`
+	Total      float64              `json:"totalSeconds"`
+	Stages     []stageTimingSummary `json:"stages"`
+	Nodes      []string             `json:"nodes,omitempty"`
+	Ports      []string             `json:"ports,omitempty"`
+	Kubeconfig string               `json:"kubeconfig,omitempty"`
+}
+
+type stageTimingSummary struct {
+	Stage   string  `json:"stage"`
+	Seconds float64 `json:"seconds"`
+}
+
+// newClusterOperationSummary gathers the relevant information about a cluster and the recorded timings
+func newClusterOperationSummary(operation string, cluster *k3d.Cluster, timings *k3d.OperationTimings, kubeconfigPath string) *clusterOperationSummary {
+	summary := &clusterOperationSummary{
+		Operation:  operation,
+		Cluster:    cluster.Name,
+		Total:      timings.Total().Seconds(),
+		Stages:     []stageTimingSummary{},
+		Kubeconfig: kubeconfigPath,
+	}
+
+	if timings != nil {
+		for _, stage := range timings.Stages {
+			summary.Stages = append(summary.Stages, stageTimingSummary{Stage: stage.Stage, Seconds: stage.Duration.Seconds()})
+		}
+	}
+
+	for _, node := range cluster.Nodes {
+		summary.Nodes = append(summary.Nodes, node.Name)
+		for containerPort, bindings := range node.Ports {
+			for _, binding := range bindings {
+				hostIP := binding.HostIP
+				if hostIP == "" {
+					hostIP = "0.0.0.0"
+				}
+				hostPort := binding.HostPort
+				if hostPort == "" {
+					hostPort = "random"
+				}
+				summary.Ports = append(summary.Ports, fmt.Sprintf("%s:%s->%s (%s)", hostIP, hostPort, containerPort, node.Name))
+			}
+		}
+	}
+	sort.Strings(summary.Ports)
+
+	return summary
+}
+
+// print writes the summary as human readable log output and, if requested, as a JSON report to stdout ("-") or a file
+func (s *clusterOperationSummary) print(timingsTarget string) {
+	if timingsTarget != "" {
+		if err := writeTimingsReport(timingsTarget, s); err != nil {
+			l.Log().Errorf("Failed to write timings report: %v", err)
+		}
+	}
+	if timingsTarget != "-" {
+		s.log()
+	}
+}
+
+// log writes the summary as human readable log output
+func (s *clusterOperationSummary) log() {
+	l.Log().Infof("Summary of %s cluster '%s' (took %s):", s.Operation, s.Cluster, roundDuration(s.Total))
+	for _, stage := range s.Stages {
+		l.Log().Infof("  - %-25s %s", stage.Stage, roundDuration(stage.Seconds))
+	}
+	if len(s.Nodes) > 0 {
+		l.Log().Infof("  Nodes: %s", strings.Join(s.Nodes, ", "))
+	}
+	if len(s.Ports) > 0 {
+		l.Log().Infof("  Ports: %s", strings.Join(s.Ports, ", "))
+	}
+	if s.Kubeconfig != "" {
+		l.Log().Infof("  Kubeconfig: %s", s.Kubeconfig)
+	}
+}
+
+func roundDuration(seconds float64) time.Duration {
+	return (time.Duration(seconds * float64(time.Second))).Round(time.Millisecond)
+}
+
+// writeTimingsReport writes a single summary or a list of summaries as JSON to stdout ("-") or to the given file
+func writeTimingsReport(target string, report interface{}) error {
+	out := os.Stdout
+	if target != "-" {
+		f, err := os.Create(target)
+		if err != nil {
+			return fmt.Errorf("failed to create file '%s': %w", target, err)
+		}
+		defer f.Close()
+		out = f
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package compose
+
+import (
+	"github.com/rancher/k3d/v5/cmd/util"
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdComposeAttach returns a new cobra command
+func NewCmdComposeAttach() *cobra.Command {
+
+	var composeFile, projectName, clusterName string
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:   "attach [--file FILE] [--name CLUSTERNAME]",
+		Short: "Connect the services of a compose project to a cluster",
+		Long: `Connect all running containers of a docker compose project to the cluster network and inject host entries both ways:
+the service names (and container names) are added to /etc/hosts of the nodes and to the CoreDNS ConfigMap, so that pods can reach e.g. a database defined in the compose file as 'db',
+and the node names are added to /etc/hosts of the service containers (if they have a shell; they also resolve via the network's DNS).`,
+		Example: `  docker compose up -d
+  k3d compose attach --file docker-compose.yml --name mycluster`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			cluster, err := client.ClusterGet(cmd.Context(), runtimes.SelectedRuntime, &k3d.Cluster{Name: clusterName})
+			if err != nil {
+				l.Log().Fatalf("Failed to get cluster '%s': %v", clusterName, err)
+			}
+
+			project, err := client.ComposeProjectFromFile(composeFile, projectName)
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+
+			if err := client.ComposeAttach(cmd.Context(), runtimes.SelectedRuntime, cluster, project); err != nil {
+				l.Log().Fatalln(err)
+			}
+			l.Log().Infof("Successfully attached compose project '%s' to cluster '%s'", project.Name, cluster.Name)
+		},
+	}
+
+	cmd.Flags().StringVarP(&composeFile, "file", "f", "docker-compose.yml", "Compose file defining the services")
+	if err := cmd.MarkFlagFilename("file", "yaml", "yml"); err != nil {
+		l.Log().Fatalln("Failed to mark flag 'file' as filename flag")
+	}
+	cmd.Flags().StringVarP(&projectName, "project-name", "p", "", "Compose project name (default: $COMPOSE_PROJECT_NAME, 'name' in the compose file or the name of its directory, like docker compose)")
+	cmd.Flags().StringVarP(&clusterName, "name", "n", k3d.DefaultClusterName, "Name of the cluster to attach the services to")
+	if err := cmd.RegisterFlagCompletionFunc("name", util.ValidArgsAvailableClusters); err != nil {
+		l.Log().Fatalln("Failed to register flag completion for '--name'", err)
+	}
+
+	return cmd
+}
@@ -0,0 +1,106 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package dns
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/rancher/k3d/v5/cmd/util"
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdDNSExpose returns a new cobra command
+func NewCmdDNSExpose() *cobra.Command {
+
+	var clusterName string
+	opts := k3d.DNSExposeOpts{}
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:   "expose [--name CLUSTERNAME]",
+		Short: "Publish the cluster DNS on a host port",
+		Long: `Publish the cluster DNS (CoreDNS) on a host port and print the resolver configuration,
+so that names like 'myservice.mynamespace.svc.cluster.local' resolve from the host, e.g. for debugging with local clients.
+This creates the NodePort Service '` + k3d.DefaultDNSExposeServiceName + `' in the kube-system namespace and adds a port mapping to the loadbalancer (which recreates the loadbalancer container).
+Note: the resolved cluster IPs are only reachable from the host if it has a route to them.`,
+		Example: `  k3d dns expose --name mycluster
+  dig @127.0.0.1 -p 10053 kubernetes.default.svc.cluster.local`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			cluster, err := client.ClusterGet(cmd.Context(), runtimes.SelectedRuntime, &k3d.Cluster{Name: clusterName})
+			if err != nil {
+				l.Log().Fatalf("Failed to get cluster '%s': %v", clusterName, err)
+			}
+
+			addr, err := client.DNSExpose(cmd.Context(), runtimes.SelectedRuntime, cluster, opts)
+			if err != nil {
+				l.Log().Fatalf("Failed to expose the DNS of cluster '%s': %v", cluster.Name, err)
+			}
+
+			host, port, err := net.SplitHostPort(addr)
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+			if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+				host = "127.0.0.1"
+			}
+			printResolverConfig(cluster.Name, host, port)
+		},
+	}
+
+	cmd.Flags().StringVarP(&clusterName, "name", "n", k3d.DefaultClusterName, "Name of the cluster to expose the DNS of")
+	if err := cmd.RegisterFlagCompletionFunc("name", util.ValidArgsAvailableClusters); err != nil {
+		l.Log().Fatalln("Failed to register flag completion for '--name'", err)
+	}
+	cmd.Flags().StringVar(&opts.HostIP, "host-ip", k3d.DefaultDNSExposeHostIP, "Host IP to publish the cluster DNS on (use '0.0.0.0' to make it reachable from other machines)")
+	cmd.Flags().IntVarP(&opts.HostPort, "port", "p", k3d.DefaultDNSExposeHostPort, "Host port to publish the cluster DNS on (UDP and TCP)")
+
+	return cmd
+}
+
+// printResolverConfig prints snippets to make the host resolve the cluster domain via the published cluster DNS
+func printResolverConfig(clusterName string, host string, port string) {
+	domain := k3d.DefaultClusterDomain
+	fmt.Printf(`Cluster DNS of '%[1]s' is published on %[2]s
+
+Query it directly:
+  dig @%[3]s -p %[4]s kubernetes.default.svc.%[5]s
+
+Resolve '*.%[5]s' via the cluster DNS:
+  # Linux with systemd-resolved (>= v246), e.g. /etc/systemd/resolved.conf.d/k3d-%[1]s.conf
+  [Resolve]
+  DNS=%[2]s
+  Domains=~%[5]s
+
+  # macOS, /etc/resolver/%[5]s
+  nameserver %[3]s
+  port %[4]s
+
+  # dnsmasq
+  server=/%[5]s/%[3]s#%[4]s
+`, clusterName, net.JoinHostPort(host, port), host, port, domain)
+}
@@ -0,0 +1,128 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package du
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/docker/go-units"
+	"github.com/liggitt/tabwriter"
+	"github.com/rancher/k3d/v5/cmd/util"
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+type duFlags struct {
+	noHeader bool
+	output   string
+}
+
+// NewCmdDu returns a new cobra command
+func NewCmdDu() *cobra.Command {
+
+	flags := duFlags{}
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:   "du [CLUSTERNAME [CLUSTERNAME ...]]",
+		Short: "Show disk usage of cluster(s)",
+		Long: `Show disk usage of cluster(s), i.e. what deleting a cluster will actually free:
+	- NODES: writable layers of the node containers
+	- IMAGE VOLUME: the volume used for importing images ('k3d image import')
+	- VOLUMES: other (named) volumes mounted into the nodes
+	- REGISTRIES: cluster-specific registry containers and their storage
+Note: volumes shared between multiple clusters are counted for each of them.`,
+		ValidArgsFunction: util.ValidArgsAvailableClusters,
+		Run: func(cmd *cobra.Command, args []string) {
+			var clusters []*k3d.Cluster
+			var err error
+
+			if len(args) == 0 {
+				clusters, err = client.ClusterList(cmd.Context(), runtimes.SelectedRuntime)
+				if err != nil {
+					l.Log().Fatalln(err)
+				}
+			} else {
+				for _, name := range args {
+					cluster, err := client.ClusterGet(cmd.Context(), runtimes.SelectedRuntime, &k3d.Cluster{Name: name})
+					if err != nil {
+						l.Log().Fatalf("Failed to get cluster '%s': %v", name, err)
+					}
+					clusters = append(clusters, cluster)
+				}
+			}
+
+			client.SortClusters(clusters)
+
+			usages, err := client.ClusterGetDiskUsage(cmd.Context(), runtimes.SelectedRuntime, clusters)
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+
+			printDiskUsage(usages, flags)
+		},
+	}
+
+	// add flags
+	cmd.Flags().BoolVar(&flags.noHeader, "no-headers", false, "Disable headers")
+	cmd.Flags().StringVarP(&flags.output, "output", "o", "", "Output format. One of: json|yaml")
+
+	// done
+	return cmd
+}
+
+func printDiskUsage(usages []*k3d.ClusterDiskUsage, flags duFlags) {
+	switch strings.ToLower(flags.output) {
+	case "json":
+		b, err := json.Marshal(usages)
+		if err != nil {
+			l.Log().Fatalln(err)
+		}
+		fmt.Println(string(b))
+	case "yaml":
+		b, err := yaml.Marshal(usages)
+		if err != nil {
+			l.Log().Fatalln(err)
+		}
+		fmt.Println(string(b))
+	default:
+		tabwriter := tabwriter.NewWriter(os.Stdout, 6, 4, 3, ' ', tabwriter.RememberWidths)
+		defer tabwriter.Flush()
+		if !flags.noHeader {
+			fmt.Fprintf(tabwriter, "%s\n", strings.Join([]string{"NAME", "NODES", "IMAGE VOLUME", "VOLUMES", "REGISTRIES", "TOTAL"}, "\t"))
+		}
+		for _, u := range usages {
+			fmt.Fprintf(tabwriter, "%s\t%s\t%s\t%s\t%s\t%s\n", u.Cluster, humanSize(u.Nodes), humanSize(u.ImageVolume), humanSize(u.Volumes), humanSize(u.Registries), humanSize(u.Total))
+		}
+	}
+}
+
+func humanSize(bytes int64) string {
+	return units.HumanSize(float64(bytes))
+}
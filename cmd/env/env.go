@@ -0,0 +1,153 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package env
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rancher/k3d/v5/cmd/util"
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdEnv returns a new cobra command
+func NewCmdEnv() *cobra.Command {
+
+	var name, shell string
+	var withNetwork, withRegistries, emitEnvrc bool
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:   "env",
+		Short: "Print the shell commands to set up the environment for a cluster",
+		Long: `Print the shell commands to set up the environment for a cluster:
+	- KUBECONFIG: a kubeconfig file dedicated to the cluster (the one 'k3d kubeconfig write' writes), so kubectl, helm, etc. use it
+	- K3D_CLUSTER: the name of the cluster (not used for the --cluster flag of other commands)
+	- DOCKER_NETWORK: the network of the cluster, e.g. for 'docker run --network "$DOCKER_NETWORK"' (with --network)
+	- K3D_ENV_REGISTRY_HOSTS: the host addresses of the registries connected to the cluster, comma-separated, e.g. for 'docker push' (with --registries)
+Evaluate them in your shell, e.g. 'eval "$(k3d env --name dev)"' (sh/bash/zsh), 'k3d env --name dev --shell fish | source' (fish)
+or 'k3d env --name dev | Invoke-Expression' (PowerShell).
+With --emit-envrc, they're written to a .envrc in the current directory instead, so that direnv sets them whenever you enter it.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			cluster, err := client.ClusterGet(cmd.Context(), runtimes.SelectedRuntime, &k3d.Cluster{Name: name})
+			if err != nil {
+				l.Log().Fatalf("Failed to get cluster '%s': %v", name, err)
+			}
+
+			kubeconfigPath, err := client.KubeconfigClusterFilePath(cluster.Name)
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+			kubeconfigPath, err = client.KubeconfigGetWrite(cmd.Context(), runtimes.SelectedRuntime, cluster, kubeconfigPath, &client.WriteKubeConfigOptions{UpdateExisting: true, OverwriteExisting: true, UpdateCurrentContext: true})
+			if err != nil {
+				l.Log().Fatalf("Failed to write kubeconfig of cluster '%s': %v", cluster.Name, err)
+			}
+
+			var registryHosts []string
+			if withRegistries {
+				registries, err := client.RegistryListForCluster(cmd.Context(), runtimes.SelectedRuntime, cluster)
+				if err != nil {
+					l.Log().Fatalf("Failed to list registries of cluster '%s': %v", cluster.Name, err)
+				}
+				registryHosts = make([]string, 0, len(registries))
+				for _, reg := range registries {
+					registryHosts = append(registryHosts, client.RegistryHostAddress(reg))
+				}
+			}
+			vars := envVars(cluster, kubeconfigPath, withNetwork, registryHosts)
+
+			if emitEnvrc {
+				writeEnvrc(vars)
+				return
+			}
+
+			if err := util.ShellExports(os.Stdout, shell, vars); err != nil {
+				l.Log().Fatalln(err)
+			}
+		},
+	}
+
+	// add flags
+	cmd.Flags().StringVar(&name, "name", k3d.DefaultClusterName, "Name of the cluster to set up the environment for")
+	if err := cmd.RegisterFlagCompletionFunc("name", util.ValidArgsAvailableClusters); err != nil {
+		l.Log().Fatalln("Failed to register flag completion for '--name'", err)
+	}
+	cmd.Flags().BoolVar(&withNetwork, "network", false, "Also print DOCKER_NETWORK with the name of the cluster network")
+	cmd.Flags().BoolVar(&withRegistries, "registries", false, "Also print K3D_ENV_REGISTRY_HOSTS with the host addresses of the registries connected to the cluster")
+	cmd.Flags().BoolVar(&emitEnvrc, "emit-envrc", false, "Write the commands to a .envrc in the current directory for direnv instead of printing them (updates the k3d block of an existing .envrc, ignores --shell)")
+	cmd.Flags().StringVar(&shell, "shell", util.DefaultShell(), fmt.Sprintf("Shell to print the commands for (one of: %s)", strings.Join(util.Shells, ", ")))
+	if err := cmd.RegisterFlagCompletionFunc("shell", func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		return util.Shells, cobra.ShellCompDirectiveNoFileComp
+	}); err != nil {
+		l.Log().Fatalln("Failed to register flag completion for '--shell'", err)
+	}
+
+	// done
+	return cmd
+}
+
+// envVars returns the environment variables set up for the cluster (registryHosts: nil without --registries).
+// None of them may be read as a flag by later k3d commands (see util.ApplyFlagEnv).
+func envVars(cluster *k3d.Cluster, kubeconfigPath string, withNetwork bool, registryHosts []string) map[string]string {
+	vars := map[string]string{
+		"KUBECONFIG":       kubeconfigPath,
+		util.ClusterEnvVar: cluster.Name,
+	}
+	if withNetwork {
+		vars["DOCKER_NETWORK"] = cluster.Network.Name
+	}
+	if registryHosts != nil {
+		vars["K3D_ENV_REGISTRY_HOSTS"] = strings.Join(registryHosts, ",")
+	}
+	return vars
+}
+
+// writeEnvrc writes the environment variables to the .envrc in the current directory, keeping any other content of it
+func writeEnvrc(vars map[string]string) {
+	path, err := filepath.Abs(".envrc")
+	if err != nil {
+		l.Log().Fatalf("Failed to get path of .envrc: %v", err)
+	}
+
+	existing, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		l.Log().Fatalf("Failed to read existing %s: %v", path, err)
+	}
+
+	content, err := util.EnvrcWithExports(string(existing), vars)
+	if err != nil {
+		l.Log().Fatalln(err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		l.Log().Fatalf("Failed to write %s: %v", path, err)
+	}
+	l.Log().Infof("Wrote the environment of cluster '%s' to %s, run 'direnv allow' to load it", vars[util.ClusterEnvVar], path)
+}
@@ -0,0 +1,52 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package env
+
+import (
+	"testing"
+
+	"github.com/rancher/k3d/v5/cmd/node"
+	"github.com/rancher/k3d/v5/cmd/util"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// the variables of 'k3d env' end up in the environment of all later k3d commands, so they mustn't change their flags
+func TestEnvVarsAreNoFlags(t *testing.T) {
+	cluster := &k3d.Cluster{Name: "dev", Network: k3d.ClusterNetwork{Name: "k3d-dev"}}
+	for k, v := range envVars(cluster, "/home/me/.k3d/kubeconfig-dev.yaml", true, []string{"localhost:5000", "registry.localhost:5001"}) {
+		t.Setenv(k, v)
+	}
+
+	// --registries (bool) of env and node delete, --cluster of node create
+	for _, cmd := range []*cobra.Command{NewCmdEnv(), node.NewCmdNodeDelete(), node.NewCmdNodeCreate()} {
+		if err := util.ApplyFlagEnv(cmd.Flags()); err != nil {
+			t.Errorf("'%s': unexpected error: %v", cmd.Name(), err)
+		}
+		cmd.Flags().VisitAll(func(f *pflag.Flag) {
+			if f.Changed {
+				t.Errorf("'%s': expected --%s not to be set by the environment of 'k3d env'", cmd.Name(), f.Name)
+			}
+		})
+	}
+}
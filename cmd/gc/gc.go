@@ -0,0 +1,119 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package gc
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+type gcFlags struct {
+	minFree              string
+	path                 string
+	checkpointsOlderThan time.Duration
+	dryRun               bool
+}
+
+// NewCmdGC returns a new cobra command
+func NewCmdGC() *cobra.Command {
+
+	flags := gcFlags{}
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:   "gc [--min-free SIZE]",
+		Short: "Free disk space by removing what k3d doesn't need anymore",
+		Long: `Free disk space by removing what k3d doesn't need anymore, one kind of resource at a time until the free space reaches '--min-free':
+	- image tarballs kept in the image volumes of the clusters by 'k3d image import --keep-tarball'
+	- exited tools nodes, which 'k3d image import' creates again when needed
+	- k3s images (rancher/k3s) which are not used by any existing cluster
+	- checkpoints older than '--checkpoints-older-than' (only if set)
+Nothing is removed if the free space is above '--min-free' already, e.g. to run 'k3d gc --min-free 10G' regularly on CI hosts.
+Stopped clusters are never touched; resources left behind by deleted clusters are removed by 'k3d prune --orphans'.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			opts := k3d.GCOpts{
+				Path:                 flags.path,
+				CheckpointsOlderThan: flags.checkpointsOlderThan,
+				DryRun:               flags.dryRun,
+			}
+			if flags.minFree != "" {
+				minFree, err := units.FromHumanSize(flags.minFree)
+				if err != nil || minFree < 0 {
+					l.Log().Fatalf("Invalid value '%s' for --min-free, expected a size like '10G'", flags.minFree)
+				}
+				opts.MinFree = uint64(minFree)
+			}
+
+			report, err := client.GC(cmd.Context(), runtimes.SelectedRuntime, opts)
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+
+			printGCReport(report, opts)
+		},
+	}
+
+	// add flags
+	cmd.Flags().StringVar(&flags.minFree, "min-free", "", "Only remove resources while the free disk space is below this size (Format: `SIZE`, e.g. 10G; default: remove everything)")
+	cmd.Flags().StringVar(&flags.path, "path", "", "Check the free space of the filesystem holding this path (Format: `PATH`, default: the docker data root, if it's on this host, or the home directory)")
+	cmd.Flags().DurationVar(&flags.checkpointsOlderThan, "checkpoints-older-than", 0, "Also remove checkpoints created longer ago than this, e.g. 168h (default: keep all checkpoints)")
+	cmd.Flags().BoolVar(&flags.dryRun, "dry-run", false, "Only list what would be removed")
+
+	// done
+	return cmd
+}
+
+func printGCReport(report *k3d.GCReport, opts k3d.GCOpts) {
+	if len(report.Items) == 0 {
+		if opts.MinFree > 0 && report.FreeBefore >= opts.MinFree {
+			l.Log().Infof("Free space of '%s' (%s) is above %s, nothing to remove", report.Path, units.HumanSize(float64(report.FreeBefore)), units.HumanSize(float64(opts.MinFree)))
+		} else {
+			l.Log().Infoln("Nothing to remove")
+		}
+		return
+	}
+
+	for _, item := range report.Items {
+		cluster := item.Cluster
+		if cluster == "" {
+			cluster = "-"
+		}
+		fmt.Printf("%s\t%s\t%s\t%s\n", item.Kind, item.Name, cluster, units.HumanSize(float64(item.Size)))
+	}
+
+	if opts.DryRun {
+		l.Log().Infof("Would remove %d resource(s), reclaiming about %s (free space of '%s': %s)", len(report.Items), units.HumanSize(float64(report.Freed)), report.Path, units.HumanSize(float64(report.FreeBefore)))
+	} else {
+		l.Log().Infof("Removed %d resource(s), reclaiming about %s (free space of '%s': %s -> %s)", len(report.Items), units.HumanSize(float64(report.Freed)), report.Path, units.HumanSize(float64(report.FreeBefore)), units.HumanSize(float64(report.FreeAfter)))
+	}
+	if opts.MinFree > 0 && report.FreeAfter < opts.MinFree {
+		l.Log().Warnf("Free space of '%s' is still below %s: remove clusters or other docker resources to free more", report.Path, units.HumanSize(float64(opts.MinFree)))
+	}
+}
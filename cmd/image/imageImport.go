@@ -37,6 +37,7 @@ import (
 func NewCmdImageImport() *cobra.Command {
 
 	loadImageOpts := k3d.ImageImportOpts{}
+	var importMode string
 
 	// create new command
 	cmd := &cobra.Command{
@@ -54,11 +55,21 @@ If an IMAGE does not have a version tag, then ':latest' is assumed.
 That is, 'rancher/k3d-tools' is treated as 'rancher/k3d-tools:latest'.
 
 A file ARCHIVE always takes precedence.
-So if a file './rancher/k3d-tools' exists, k3d will try to import it instead of the IMAGE of the same name.`,
+So if a file './rancher/k3d-tools' exists, k3d will try to import it instead of the IMAGE of the same name.
+Use --tar to import an ARCHIVE (e.g. created via 'docker save') explicitly.
+
+By default, images are streamed into all nodes at once (--mode direct), without storing them in the shared image volume.
+With --mode tools-node (implied by --keep-tarball and --keep-tools), a tools container saves them to the image volume first,
+from where the nodes import them.`,
 		Aliases: []string{"load"},
-		Args:    cobra.MinimumNArgs(1),
+		Args:    cobra.ArbitraryArgs,
 		Run: func(cmd *cobra.Command, args []string) {
 			images, clusters := parseLoadImageCmd(cmd, args)
+			mode, ok := k3d.ImportModes[importMode]
+			if !ok {
+				l.Log().Fatalf("Unknown import mode '%s' (one of: direct, tools-node)", importMode)
+			}
+			loadImageOpts.Mode = mode
 			l.Log().Debugf("Importing image(s) [%+v] from runtime [%s] into cluster(s) [%+v]...", images, runtimes.SelectedRuntime, clusters)
 			errOccured := false
 			for _, cluster := range clusters {
@@ -86,6 +97,16 @@ So if a file './rancher/k3d-tools' exists, k3d will try to import it instead of
 
 	cmd.Flags().BoolVarP(&loadImageOpts.KeepTar, "keep-tarball", "k", false, "Do not delete the tarball containing the saved images from the shared volume")
 	cmd.Flags().BoolVarP(&loadImageOpts.KeepToolsNode, "keep-tools", "t", false, "Do not delete the tools node after import")
+	cmd.Flags().StringVarP(&importMode, "mode", "m", string(k3d.ImportModeDirect), "How to get the images into the nodes: 'direct' streams them into all nodes at once, 'tools-node' saves them to the shared image volume first")
+	if err := cmd.RegisterFlagCompletionFunc("mode", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{string(k3d.ImportModeDirect), string(k3d.ImportModeToolsNode)}, cobra.ShellCompDirectiveNoFileComp
+	}); err != nil {
+		l.Log().Fatalln("Failed to register flag completion for '--mode'", err)
+	}
+	cmd.Flags().StringArray("tar", nil, "Import the images from a tarball (e.g. created via 'docker save'), use flag multiple times (Format: `FILE`)")
+	if err := cmd.MarkFlagFilename("tar", "tar"); err != nil {
+		l.Log().Fatalln("Failed to mark flag 'tar' as filename flag", err)
+	}
 
 	/* Subcommands */
 
@@ -108,6 +129,16 @@ func parseLoadImageCmd(cmd *cobra.Command, args []string) ([]string, []k3d.Clust
 
 	// images
 	images := args
+	tarballs, err := cmd.Flags().GetStringArray("tar")
+	if err != nil {
+		l.Log().Fatalln(err)
+	}
+	for _, tarball := range tarballs {
+		if _, err := os.Stat(tarball); err != nil {
+			l.Log().Fatalf("Failed to read tarball: %v", err)
+		}
+		images = append(images, tarball)
+	}
 	if len(images) == 0 {
 		l.Log().Fatalln("No images specified!")
 	}
@@ -0,0 +1,93 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/rancher/k3d/v5/pkg/client"
+	"github.com/rancher/k3d/v5/pkg/config"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+type inventoryFlags struct {
+	output string
+	schema bool
+}
+
+// NewCmdInventory returns a new cobra command
+func NewCmdInventory() *cobra.Command {
+
+	flags := inventoryFlags{}
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:   "inventory",
+		Short: "Export all k3d-managed resources with their labels and relationships",
+		Long: `Export all k3d-managed docker resources (clusters, nodes, registries, networks and volumes) with their labels and relationships,
+e.g. for external drift-detection or cleanup tooling on shared CI hosts.
+The output is validated against a versioned JSON schema before it's printed (print the schema with '--schema').`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if flags.schema {
+				fmt.Println(string(k3d.InventorySchema))
+				return
+			}
+
+			inventory, err := client.Inventory(cmd.Context(), runtimes.SelectedRuntime)
+			if err != nil {
+				l.Log().Fatalf("Failed to build inventory: %v", err)
+			}
+
+			if err := config.ValidateInventory(inventory); err != nil {
+				l.Log().Fatalln(err)
+			}
+
+			var b []byte
+			switch strings.ToLower(flags.output) {
+			case "json":
+				b, err = json.MarshalIndent(inventory, "", "  ")
+			case "yaml":
+				b, err = yaml.Marshal(inventory)
+			default:
+				l.Log().Fatalf("Unknown output format '%s', must be one of: json|yaml", flags.output)
+			}
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+			fmt.Println(string(b))
+		},
+	}
+
+	// add flags
+	cmd.Flags().StringVarP(&flags.output, "output", "o", "json", "Output format. One of: json|yaml")
+	cmd.Flags().BoolVar(&flags.schema, "schema", false, "Print the JSON schema of the inventory instead of the inventory")
+
+	// done
+	return cmd
+}
@@ -0,0 +1,168 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package k8sevents
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/liggitt/tabwriter"
+	"github.com/moby/term"
+	"github.com/rancher/k3d/v5/cmd/util"
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// ANSI escape sequences used to highlight warnings. Every colored line starts with a sequence of the same length,
+// so that the columns stay aligned (tabwriter counts them as part of the first cell).
+const (
+	colorDefault = "\x1b[39m"
+	colorWarning = "\x1b[33m"
+	colorReset   = "\x1b[0m"
+)
+
+type k8sEventsFlags struct {
+	name      string
+	namespace string
+	watch     bool
+	noHeader  bool
+	output    string
+}
+
+// NewCmdK8sEvents returns a new cobra command
+func NewCmdK8sEvents() *cobra.Command {
+
+	flags := k8sEventsFlags{}
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:   "k8s-events [--name CLUSTERNAME] [-w]",
+		Short: "Print (and follow) the Kubernetes events of a cluster",
+		Long: `Print the Kubernetes events of a cluster (oldest first), using the cluster's kubeconfig.
+Warnings are highlighted when printing to a terminal. With --watch, new events are streamed until k3d is interrupted.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			cluster, err := client.ClusterGet(cmd.Context(), runtimes.SelectedRuntime, &k3d.Cluster{Name: flags.name})
+			if err != nil {
+				l.Log().Fatalf("Failed to get cluster '%s': %v", flags.name, err)
+			}
+
+			printer, err := newEventPrinter(flags)
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+			defer printer.flush()
+
+			if err := client.ClusterK8sEvents(cmd.Context(), runtimes.SelectedRuntime, cluster, k3d.K8sEventsOpts{
+				Namespace: flags.namespace,
+				Watch:     flags.watch,
+			}, printer.print); err != nil {
+				l.Log().Fatalln(err)
+			}
+		},
+	}
+
+	// add flags
+	cmd.Flags().StringVarP(&flags.name, "name", "n", k3d.DefaultClusterName, "Name of the cluster")
+	if err := cmd.RegisterFlagCompletionFunc("name", util.ValidArgsAvailableClusters); err != nil {
+		l.Log().Fatalln("Failed to register flag completion for '--name'", err)
+	}
+	cmd.Flags().StringVar(&flags.namespace, "namespace", "", "Only show events in this namespace (default: all namespaces)")
+	cmd.Flags().BoolVarP(&flags.watch, "watch", "w", false, "Keep streaming new events")
+	cmd.Flags().BoolVar(&flags.noHeader, "no-headers", false, "Disable headers")
+	cmd.Flags().StringVarP(&flags.output, "output", "o", "", "Output format. One of: json|yaml (json: one event per line)")
+
+	// done
+	return cmd
+}
+
+// eventPrinter prints events as they come in: the table is flushed after every row, so that watched events show up right away
+type eventPrinter struct {
+	output    string
+	color     bool
+	tabwriter *tabwriter.Writer
+}
+
+func newEventPrinter(flags k8sEventsFlags) (*eventPrinter, error) {
+	output := strings.ToLower(flags.output)
+	switch output {
+	case "", "json", "yaml":
+	default:
+		return nil, fmt.Errorf("unknown output format '%s' (must be one of 'json' or 'yaml')", flags.output)
+	}
+
+	_, isTerminal := term.GetFdInfo(os.Stdout)
+	p := &eventPrinter{
+		output:    output,
+		color:     isTerminal && !util.CIMode,
+		tabwriter: tabwriter.NewWriter(os.Stdout, 6, 4, 3, ' ', tabwriter.RememberWidths),
+	}
+
+	if output == "" && !flags.noHeader {
+		p.row(colorDefault, "NAMESPACE", "LAST SEEN", "TYPE", "REASON", "OBJECT", "COUNT", "MESSAGE")
+	}
+
+	return p, nil
+}
+
+func (p *eventPrinter) print(event *k3d.K8sEvent) {
+	switch p.output {
+	case "json":
+		b, err := json.Marshal(event)
+		if err != nil {
+			l.Log().Fatalln(err)
+		}
+		fmt.Println(string(b))
+	case "yaml":
+		b, err := yaml.Marshal([]*k3d.K8sEvent{event})
+		if err != nil {
+			l.Log().Fatalln(err)
+		}
+		fmt.Print(string(b))
+	default:
+		color := colorDefault
+		if event.Type == k3d.K8sEventTypeWarning {
+			color = colorWarning
+		}
+		p.row(color, event.Namespace, event.LastSeen.Local().Format("2006-01-02 15:04:05"), event.Type, event.Reason, event.Object,
+			fmt.Sprintf("%d", event.Count), strings.ReplaceAll(event.Message, "\n", " "))
+	}
+}
+
+func (p *eventPrinter) row(color string, cells ...string) {
+	line := strings.Join(cells, "\t")
+	if p.color {
+		line = color + line + colorReset
+	}
+	fmt.Fprintln(p.tabwriter, line)
+	p.flush()
+}
+
+func (p *eventPrinter) flush() {
+	p.tabwriter.Flush()
+}
@@ -31,9 +31,10 @@ func NewCmdKubeconfig() *cobra.Command {
 
 	// create new cobra command
 	cmd := &cobra.Command{
-		Use:   "kubeconfig",
-		Short: "Manage kubeconfig(s)",
-		Long:  `Manage kubeconfig(s)`,
+		Use:     "kubeconfig",
+		Aliases: []string{"kubeconfigs"},
+		Short:   "Manage kubeconfig(s)",
+		Long:    `Manage kubeconfig(s)`,
 		Run: func(cmd *cobra.Command, args []string) {
 			if err := cmd.Help(); err != nil {
 				l.Log().Errorln("Couldn't get help text")
@@ -45,6 +46,8 @@ func NewCmdKubeconfig() *cobra.Command {
 	// add subcommands
 	cmd.AddCommand(NewCmdKubeconfigGet())
 	cmd.AddCommand(NewCmdKubeconfigMerge())
+	cmd.AddCommand(NewCmdKubeconfigCreateSA())
+	cmd.AddCommand(NewCmdKubeconfigPath())
 
 	// add flags
 
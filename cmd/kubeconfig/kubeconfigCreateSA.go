@@ -0,0 +1,125 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package kubeconfig
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rancher/k3d/v5/cmd/util"
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// NewCmdKubeconfigCreateSA returns a new cobra command
+func NewCmdKubeconfigCreateSA() *cobra.Command {
+
+	opts := k3d.ServiceAccountKubeconfigOpts{}
+	var clusterName, output string
+
+	// create new command
+	cmd := &cobra.Command{
+		Use:   "create-sa --sa SERVICEACCOUNT [--name CLUSTER] [--namespace NAMESPACE]",
+		Short: "Create a service account and print a kubeconfig scoped to it.",
+		Long: `Create a service account (and its namespace, if required), bind a ClusterRole to it within the namespace (or cluster-wide)
+and print a kubeconfig authenticating as that service account with a token instead of client certificates,
+e.g. to hand limited-privilege credentials to CI jobs or to tools that can't handle certificate rotation.
+Long-lived tokens are stored in a secret and revoked by deleting it (or the service account).`,
+		Example: `  k3d kubeconfig create-sa --name mycluster --namespace ci --sa deployer -o deployer.yaml
+  k3d kubeconfig create-sa --name mycluster --namespace kube-system --sa admin --role cluster-admin --cluster-wide --token-duration 8h`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			cluster, err := client.ClusterGet(cmd.Context(), runtimes.SelectedRuntime, &k3d.Cluster{Name: clusterName})
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+
+			kubeconfig, err := client.KubeconfigCreateServiceAccount(cmd.Context(), runtimes.SelectedRuntime, cluster, opts)
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+
+			if output == "-" {
+				if err := client.KubeconfigWriteToPath(cmd.Context(), kubeconfig, output); err != nil {
+					l.Log().Fatalln(err)
+				}
+				return
+			}
+
+			if err := writeTokenKubeconfig(kubeconfig, output); err != nil {
+				l.Log().Fatalln(err)
+			}
+			l.Log().Infof("Wrote kubeconfig for service account '%s/%s' to '%s'", opts.Namespace, opts.ServiceAccount, output)
+		},
+	}
+
+	// add flags
+	cmd.Flags().StringVar(&clusterName, "name", k3d.DefaultClusterName, "Name of the cluster")
+	if err := cmd.RegisterFlagCompletionFunc("name", util.ValidArgsAvailableClusters); err != nil {
+		l.Log().Fatalln("Failed to register flag completion for '--name'", err)
+	}
+	cmd.Flags().StringVar(&opts.ServiceAccount, "sa", "", "Name of the service account")
+	if err := cmd.MarkFlagRequired("sa"); err != nil {
+		l.Log().Fatalln("Failed to mark flag 'sa' as required")
+	}
+	cmd.Flags().StringVarP(&opts.Namespace, "namespace", "n", "default", "Namespace of the service account (created, if it doesn't exist)")
+	cmd.Flags().StringVar(&opts.ClusterRole, "role", "edit", "ClusterRole bound to the service account within the namespace")
+	cmd.Flags().BoolVar(&opts.ClusterWide, "cluster-wide", false, "Bind the ClusterRole cluster-wide instead of within the namespace, e.g. '--role cluster-admin --cluster-wide' for a token-based admin kubeconfig")
+	cmd.Flags().DurationVar(&opts.TokenDuration, "token-duration", 0, "Use a short-lived token expiring after this duration (at least 10m) instead of a long-lived one stored in a secret (revoke it by deleting the service account)")
+	cmd.Flags().DurationVar(&opts.Timeout, "timeout", 1*time.Minute, "Maximum time to wait for the service account token")
+	cmd.Flags().StringVarP(&output, "output", "o", "-", "Where to write the kubeconfig to ('-' for stdout)")
+
+	// done
+	return cmd
+}
+
+// writeTokenKubeconfig writes a kubeconfig containing a token to the given path, which must never be readable by others
+// (not even for a moment, so the file is created with restricted permissions instead of being chmod'ed afterwards)
+func writeTokenKubeconfig(kubeconfig *clientcmdapi.Config, path string) error {
+	kubeconfigBytes, err := clientcmd.Write(*kubeconfig)
+	if err != nil {
+		return fmt.Errorf("failed to serialize kubeconfig: %w", err)
+	}
+
+	output, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create file '%s': %w", path, err)
+	}
+	defer output.Close()
+
+	// the mode only applies to newly created files, so restrict an existing file as well
+	if err := output.Chmod(0600); err != nil {
+		return fmt.Errorf("failed to restrict permissions of '%s': %w", path, err)
+	}
+
+	if _, err := output.Write(kubeconfigBytes); err != nil {
+		return fmt.Errorf("failed to write file '%s': %w", path, err)
+	}
+
+	return output.Close()
+}
@@ -0,0 +1,128 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package kubeconfig
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/rancher/k3d/v5/cmd/util"
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+type pathKubeconfigFlags struct {
+	all   bool
+	json  bool
+	watch bool
+}
+
+// NewCmdKubeconfigPath returns a new cobra command
+func NewCmdKubeconfigPath() *cobra.Command {
+
+	flags := pathKubeconfigFlags{}
+
+	// create new command
+	cmd := &cobra.Command{
+		Use:   "path [CLUSTER [CLUSTER [...]] | --all]",
+		Short: "Print the paths of the kubeconfig files of cluster(s)",
+		Long: `Print the paths of the dedicated kubeconfig files of cluster(s) (written if they don't exist yet), one per line.
+With --json, print a map of cluster name to kubeconfig path and context names instead (also naming the default kubeconfig, if it has the cluster),
+e.g. for IDE Kubernetes plugins to discover k3d clusters. With --watch, it's printed again (one line each) whenever the kubeconfig files change.`,
+		Example: `  k3d kubeconfig path --all --json --watch
+  {"mycluster":{"path":"/home/me/.k3d/kubeconfig-mycluster.yaml","contexts":["k3d-mycluster"]}}`,
+		ValidArgsFunction: util.ValidArgsAvailableClusters,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if (len(args) < 1 && !flags.all) || (len(args) > 0 && flags.all) {
+				return fmt.Errorf("Need to specify one or more cluster names *or* set `--all` flag")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			util.LogToStderr() // keep stdout parseable
+
+			printKubeconfigPaths(cmd, args, flags)
+			if !flags.watch {
+				return
+			}
+
+			if err := client.KubeconfigWatch(cmd.Context(), func() { printKubeconfigPaths(cmd, args, flags) }); err != nil {
+				l.Log().Fatalln(err)
+			}
+		},
+	}
+
+	// add flags
+	cmd.Flags().BoolVarP(&flags.all, "all", "a", false, "Print the kubeconfig paths of all existing clusters")
+	cmd.Flags().BoolVar(&flags.json, "json", false, "Print a JSON map of cluster name to kubeconfig path and context names")
+	cmd.Flags().BoolVarP(&flags.watch, "watch", "w", false, "Print the paths again whenever the kubeconfig files change (e.g. a cluster was created or deleted)")
+
+	// done
+	return cmd
+}
+
+// printKubeconfigPaths prints the kubeconfig locations of the selected clusters. Clusters that are gone (or broken) are left out,
+// so that watchers get an up-to-date picture instead of an error.
+func printKubeconfigPaths(cmd *cobra.Command, args []string, flags pathKubeconfigFlags) {
+	var clusters []*k3d.Cluster
+	if flags.all {
+		var err error
+		clusters, err = client.ClusterList(cmd.Context(), runtimes.SelectedRuntime)
+		if err != nil {
+			l.Log().Fatalln(err)
+		}
+	} else {
+		for _, clusterName := range args {
+			cluster, err := client.ClusterGet(cmd.Context(), runtimes.SelectedRuntime, &k3d.Cluster{Name: clusterName})
+			if err != nil {
+				l.Log().Warnf("Failed to get cluster '%s': %v", clusterName, err)
+				continue
+			}
+			clusters = append(clusters, cluster)
+		}
+	}
+	client.SortClusters(clusters)
+
+	locations := map[string]*k3d.KubeconfigLocation{}
+	for _, cluster := range clusters {
+		location, err := client.KubeconfigLocate(cmd.Context(), runtimes.SelectedRuntime, cluster)
+		if err != nil {
+			l.Log().Warnln(err)
+			continue
+		}
+		locations[cluster.Name] = location
+		if !flags.json {
+			fmt.Println(location.Path)
+		}
+	}
+
+	if flags.json {
+		b, err := json.Marshal(locations)
+		if err != nil {
+			l.Log().Fatalln(err)
+		}
+		fmt.Println(string(b))
+	}
+}
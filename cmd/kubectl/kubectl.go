@@ -0,0 +1,151 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package kubectl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	cliutil "github.com/rancher/k3d/v5/cmd/util"
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/spf13/cobra"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// NewCmdKubectl returns a new cobra command
+func NewCmdKubectl() *cobra.Command {
+
+	var clusterName string
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:   "kubectl [--name CLUSTERNAME] -- [KUBECTL ARGS...]",
+		Short: "Run kubectl against a cluster without touching your kubeconfig",
+		Long: `Run kubectl against a cluster without touching your kubeconfig.
+The cluster's kubeconfig is written to a temporary file, which is passed to the kubectl binary found in your PATH.
+If there's no kubectl in your PATH, the command is executed using the kubectl shipped in the cluster's first server node (no stdin).`,
+		Example: `  k3d kubectl --name mycluster -- get pods -A`,
+		Args:    cobra.ArbitraryArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			cluster, err := client.ClusterGet(cmd.Context(), runtimes.SelectedRuntime, &k3d.Cluster{Name: clusterName})
+			if err != nil {
+				l.Log().Fatalf("Failed to get cluster '%s': %v", clusterName, err)
+			}
+
+			kubectlPath, err := exec.LookPath("kubectl")
+			if err != nil {
+				l.Log().Debugf("No kubectl found in PATH (%v), falling back to the one in the server node", err)
+				if err := kubectlInNode(cmd, cluster, args); err != nil {
+					l.Log().Fatalln(err)
+				}
+				return
+			}
+
+			kubeconfig, err := client.KubeconfigGet(cmd.Context(), runtimes.SelectedRuntime, cluster)
+			if err != nil {
+				l.Log().Fatalf("Failed to get kubeconfig for cluster '%s': %v", cluster.Name, err)
+			}
+
+			kubeconfigPath, err := writeTempKubeconfig(cmd.Context(), cluster.Name, kubeconfig)
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+			defer os.Remove(kubeconfigPath)
+
+			if err := cliutil.ExecPlugin(cmd.Context(), kubectlPath, args, kubectlEnv(os.Environ(), kubeconfigPath)); err != nil {
+				var exitErr *exec.ExitError
+				if errors.As(err, &exitErr) {
+					os.Remove(kubeconfigPath) // deferred calls don't run on os.Exit
+					os.Exit(exitErr.ExitCode())
+				}
+				l.Log().Fatalf("Failed to run kubectl: %v", err)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&clusterName, "name", k3d.DefaultClusterName, "Name of the cluster to run kubectl against")
+	if err := cmd.RegisterFlagCompletionFunc("name", cliutil.ValidArgsAvailableClusters); err != nil {
+		l.Log().Fatalln("Failed to register flag completion for '--name'", err)
+	}
+
+	return cmd
+}
+
+// writeTempKubeconfig writes the kubeconfig of a cluster to a new temporary file and returns its path
+func writeTempKubeconfig(ctx context.Context, clusterName string, kubeconfig *clientcmdapi.Config) (string, error) {
+	tmpfile, err := ioutil.TempFile("", fmt.Sprintf("%s-%s-kubeconfig-*.yaml", k3d.DefaultObjectNamePrefix, clusterName))
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary kubeconfig file: %w", err)
+	}
+	tmpfile.Close()
+
+	if err := client.KubeconfigWrite(ctx, kubeconfig, tmpfile.Name()); err != nil {
+		os.Remove(tmpfile.Name())
+		return "", err
+	}
+	return tmpfile.Name(), nil
+}
+
+// kubectlEnv returns the given environment with KUBECONFIG pointing to the given file only
+func kubectlEnv(environ []string, kubeconfigPath string) []string {
+	env := make([]string, 0, len(environ)+1)
+	for _, e := range environ {
+		if !strings.HasPrefix(e, "KUBECONFIG=") {
+			env = append(env, e)
+		}
+	}
+	return append(env, fmt.Sprintf("KUBECONFIG=%s", kubeconfigPath))
+}
+
+// kubectlInNode runs kubectl inside the first server node of the cluster and copies its output to stdout
+func kubectlInNode(cmd *cobra.Command, cluster *k3d.Cluster, args []string) error {
+	var server *k3d.Node
+	for _, node := range cluster.Nodes {
+		if node.Role == k3d.ServerRole {
+			server = node
+			break
+		}
+	}
+	if server == nil {
+		return fmt.Errorf("cluster '%s' has no server node to run kubectl in", cluster.Name)
+	}
+
+	logreader, err := runtimes.SelectedRuntime.ExecInNodeGetLogs(cmd.Context(), server, append([]string{"kubectl"}, args...))
+	if logreader != nil {
+		if _, copyErr := io.Copy(os.Stdout, logreader); copyErr != nil {
+			l.Log().Warnf("Failed to read kubectl output: %v", copyErr)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to run kubectl in node '%s': %w", server.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package kubectl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKubectlEnv(t *testing.T) {
+	environ := []string{"PATH=/usr/bin", "KUBECONFIG=/home/me/.kube/config", "HOME=/home/me"}
+
+	env := kubectlEnv(environ, "/tmp/k3d-test-kubeconfig.yaml")
+
+	expected := []string{"PATH=/usr/bin", "HOME=/home/me", "KUBECONFIG=/tmp/k3d-test-kubeconfig.yaml"}
+	if !reflect.DeepEqual(env, expected) {
+		t.Errorf("expected %v, got %v", expected, env)
+	}
+	if environ[1] != "KUBECONFIG=/home/me/.kube/config" {
+		t.Errorf("the original environment must not be modified")
+	}
+}
+
+func TestKubectlArgsPassthrough(t *testing.T) {
+	cmd := NewCmdKubectl()
+
+	if err := cmd.ParseFlags([]string{"--name", "mycluster", "--", "get", "pods", "-A", "--name", "not-for-k3d"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	name, err := cmd.Flags().GetString("name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "mycluster" {
+		t.Errorf("expected cluster name 'mycluster', got '%s'", name)
+	}
+
+	expected := []string{"get", "pods", "-A", "--name", "not-for-k3d"}
+	if args := cmd.Flags().Args(); !reflect.DeepEqual(args, expected) {
+		t.Errorf("expected kubectl args %v, got %v", expected, args)
+	}
+}
@@ -22,7 +22,10 @@ THE SOFTWARE.
 package node
 
 import (
+	"github.com/rancher/k3d/v5/pkg/client"
 	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
 	"github.com/spf13/cobra"
 )
 
@@ -31,9 +34,10 @@ func NewCmdNode() *cobra.Command {
 
 	// create new cobra command
 	cmd := &cobra.Command{
-		Use:   "node",
-		Short: "Manage node(s)",
-		Long:  `Manage node(s)`,
+		Use:     "node",
+		Aliases: []string{"nodes"},
+		Short:   "Manage node(s)",
+		Long:    `Manage node(s)`,
 		Run: func(cmd *cobra.Command, args []string) {
 			if err := cmd.Help(); err != nil {
 				l.Log().Errorln("Couldn't get help text")
@@ -46,12 +50,40 @@ func NewCmdNode() *cobra.Command {
 	cmd.AddCommand(NewCmdNodeCreate())
 	cmd.AddCommand(NewCmdNodeStart())
 	cmd.AddCommand(NewCmdNodeStop())
+	cmd.AddCommand(NewCmdNodeRestart())
 	cmd.AddCommand(NewCmdNodeDelete())
 	cmd.AddCommand(NewCmdNodeList())
 	cmd.AddCommand(NewCmdNodeEdit())
+	cmd.AddCommand(NewCmdNodeBake())
+	cmd.AddCommand(NewCmdNodeExec())
+	cmd.AddCommand(NewCmdNodeShell())
+	cmd.AddCommand(NewCmdNodeLogs())
+	cmd.AddCommand(NewCmdNodeStress())
 
 	// add flags
 
 	// done
 	return cmd
 }
+
+// parseNodeRefs returns the nodes referenced by the command arguments: node names or, if a cluster is set,
+// names without the cluster prefix (e.g. 'agent-1') or ROLE:INDEX (e.g. 'agent:1') as well
+func parseNodeRefs(cmd *cobra.Command, clusterName string, args []string) []*k3d.Node {
+	if len(args) == 0 || len(args[0]) == 0 {
+		l.Log().Fatalln("No node name given")
+	}
+
+	nodes := make([]*k3d.Node, 0, len(args))
+	for _, ref := range args {
+		if clusterName == "" {
+			nodes = append(nodes, &k3d.Node{Name: ref})
+			continue
+		}
+		node, err := client.NodeGetInCluster(cmd.Context(), runtimes.SelectedRuntime, clusterName, ref)
+		if err != nil {
+			l.Log().Fatalln(err)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
@@ -0,0 +1,85 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package node
+
+import (
+	"github.com/rancher/k3d/v5/cmd/util"
+	"github.com/rancher/k3d/v5/pkg/client"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	"github.com/spf13/cobra"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+)
+
+type nodeBakeFlags struct {
+	Name    string
+	Tag     string
+	Cluster string
+}
+
+// NewCmdNodeBake returns a new cobra command
+func NewCmdNodeBake() *cobra.Command {
+
+	flags := nodeBakeFlags{}
+
+	// create new command
+	cmd := &cobra.Command{
+		Use:   "bake [NODE] --tag IMAGE",
+		Short: "Create a k3s node image from an existing node",
+		Long: `Create a k3s node image from a prepared (running) k3d node, including the container images it pulled or imported and
+the packages installed into it, e.g. 'k3d node bake --name k3d-foo-agent-0 --tag myorg/k3s-custom:dev'.
+Pass the image to '--image' when creating clusters or nodes to skip preparing them again.`,
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: util.ValidArgsAvailableNodes,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) > 0 {
+				if flags.Name != "" && flags.Name != args[0] {
+					l.Log().Fatalf("Node given twice: '%s' (--name) and '%s'", flags.Name, args[0])
+				}
+				flags.Name = args[0]
+			}
+			if flags.Tag == "" {
+				l.Log().Fatalln("No image given (--tag)")
+			}
+
+			node := parseNodeRefs(cmd, flags.Cluster, []string{flags.Name})[0]
+			if err := client.NodeBake(cmd.Context(), runtimes.SelectedRuntime, node, flags.Tag); err != nil {
+				l.Log().Fatalln(err)
+			}
+			l.Log().Infof("Successfully baked node '%s' into image '%s'", node.Name, flags.Tag)
+		},
+	}
+
+	// add flags
+	cmd.Flags().StringVarP(&flags.Name, "name", "n", "", "Node to create the image from (alternatively passed as argument)")
+	cmd.Flags().StringVarP(&flags.Tag, "tag", "t", "", "Image reference to create, e.g. 'myorg/k3s-custom:dev'")
+	cmd.Flags().StringVarP(&flags.Cluster, "cluster", "c", "", "Cluster of the node, allows referencing it by short name (e.g. 'agent-1') or ROLE:INDEX (e.g. 'agent:1')")
+	if err := cmd.RegisterFlagCompletionFunc("cluster", util.ValidArgsAvailableClusters); err != nil {
+		l.Log().Fatalln("Failed to register flag completion for '--cluster'", err)
+	}
+	if err := cmd.RegisterFlagCompletionFunc("name", util.ValidArgsAvailableNodes); err != nil {
+		l.Log().Fatalln("Failed to register flag completion for '--name'", err)
+	}
+
+	// done
+	return cmd
+}
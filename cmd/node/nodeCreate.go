@@ -34,6 +34,7 @@ import (
 	k3dc "github.com/rancher/k3d/v5/pkg/client"
 	l "github.com/rancher/k3d/v5/pkg/logger"
 	"github.com/rancher/k3d/v5/pkg/runtimes"
+	runtimeutil "github.com/rancher/k3d/v5/pkg/runtimes/util"
 	k3d "github.com/rancher/k3d/v5/pkg/types"
 	"github.com/rancher/k3d/v5/version"
 )
@@ -78,7 +79,11 @@ func NewCmdNodeCreate() *cobra.Command {
 	}
 
 	cmd.Flags().StringP("image", "i", fmt.Sprintf("%s:%s", k3d.DefaultK3sImageRepo, version.GetK3sVersion(false)), "Specify k3s image used for the node(s)")
+	cmd.Flags().String("k3s-version", "", "Use the k3s image of the given k3s release instead of --image (Format: `VERSION`, e.g. v1.21.4+k3s1)")
+	cmd.Flags().String("channel", "", "Use the k3s image of the release a k3s channel currently points to instead of --image (Format: `CHANNEL`, e.g. stable, latest, v1.21)")
 	cmd.Flags().String("memory", "", "Memory limit imposed on the node [From docker]")
+	cmd.Flags().String("cpus", "", "CPU limit imposed on the node as a (fractional) number of CPUs, e.g. '1.5' [From docker]")
+	cmd.Flags().String("gpus", "", "GPU devices to add to the node containers ('all' to pass all GPUs) [From docker]")
 
 	cmd.Flags().BoolVar(&createNodeOpts.Wait, "wait", true, "Wait for the node(s) to be ready before returning.")
 	cmd.Flags().DurationVar(&createNodeOpts.Timeout, "timeout", 0*time.Second, "Maximum waiting time for '--wait' before canceling/returning.")
@@ -89,6 +94,7 @@ func NewCmdNodeCreate() *cobra.Command {
 	cmd.Flags().StringSliceP("network", "n", []string{}, "Add node to (another) runtime network")
 
 	cmd.Flags().StringVarP(&createNodeOpts.ClusterToken, "token", "t", "", "Override cluster token (required when connecting to an external cluster)")
+	cliutil.SkipFlagEnv(cmd.Flags(), "token") // secret: must not be picked up from e.g. the API token of 'k3d serve'
 
 	// done
 	return cmd
@@ -122,6 +128,29 @@ func parseCreateNodeCmd(cmd *cobra.Command, args []string) ([]*k3d.Node, string)
 		l.Log().Fatalln(err)
 	}
 
+	// --k3s-version, --channel
+	k3sVersion, err := cmd.Flags().GetString("k3s-version")
+	if err != nil {
+		l.Log().Fatalln(err)
+	}
+	channel, err := cmd.Flags().GetString("channel")
+	if err != nil {
+		l.Log().Fatalln(err)
+	}
+	if k3sVersion != "" || channel != "" {
+		if cmd.Flags().Changed("image") || (k3sVersion != "" && channel != "") {
+			l.Log().Fatalln("Only one of --image, --k3s-version and --channel can be set")
+		}
+		if k3sVersion != "" {
+			image, err = k3dc.K3sImageForVersion(k3sVersion)
+		} else {
+			image, err = k3dc.K3sImageForChannel(cmd.Context(), channel)
+		}
+		if err != nil {
+			l.Log().Fatalln(err)
+		}
+	}
+
 	// --cluster
 	clusterName, err := cmd.Flags().GetString("cluster")
 	if err != nil {
@@ -138,6 +167,21 @@ func parseCreateNodeCmd(cmd *cobra.Command, args []string) ([]*k3d.Node, string)
 		l.Log().Errorf("Provided memory limit value is invalid")
 	}
 
+	// --cpus
+	cpus, err := cmd.Flags().GetString("cpus")
+	if err != nil {
+		l.Log().Fatalln(err)
+	}
+	if _, err := runtimeutil.ParseCPUs(cpus); cpus != "" && err != nil {
+		l.Log().Fatalf("Provided CPU limit value is invalid: %v", err)
+	}
+
+	// --gpus
+	gpus, err := cmd.Flags().GetString("gpus")
+	if err != nil {
+		l.Log().Fatalln(err)
+	}
+
 	// --runtime-label
 	runtimeLabelsFlag, err := cmd.Flags().GetStringSlice("runtime-label")
 	if err != nil {
@@ -191,6 +235,8 @@ func parseCreateNodeCmd(cmd *cobra.Command, args []string) ([]*k3d.Node, string)
 			RuntimeLabels: runtimeLabels,
 			Restart:       true,
 			Memory:        memory,
+			CPUs:          cpus,
+			GPURequest:    gpus,
 			Networks:      networks,
 		}
 		nodes = append(nodes, node)
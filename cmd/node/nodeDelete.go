@@ -33,6 +33,7 @@ import (
 type nodeDeleteFlags struct {
 	All               bool
 	IncludeRegistries bool
+	Cluster           string
 }
 
 // NewCmdNodeDelete returns a new cobra command
@@ -42,7 +43,7 @@ func NewCmdNodeDelete() *cobra.Command {
 
 	// create new cobra command
 	cmd := &cobra.Command{
-		Use:               "delete (NAME | --all)",
+		Use:               "delete (NAME... | --all)",
 		Short:             "Delete node(s).",
 		Long:              `Delete node(s).`,
 		ValidArgsFunction: util.ValidArgsAvailableNodes,
@@ -69,6 +70,10 @@ func NewCmdNodeDelete() *cobra.Command {
 	// add flags
 	cmd.Flags().BoolVarP(&flags.All, "all", "a", false, "Delete all existing nodes")
 	cmd.Flags().BoolVarP(&flags.IncludeRegistries, "registries", "r", false, "Also delete registries")
+	cmd.Flags().StringVarP(&flags.Cluster, "cluster", "c", "", "Cluster of the node(s), allows referencing nodes by short name (e.g. 'agent-1') or ROLE:INDEX (e.g. 'agent:1')")
+	if err := cmd.RegisterFlagCompletionFunc("cluster", util.ValidArgsAvailableClusters); err != nil {
+		l.Log().Fatalln("Failed to register flag completion for '--cluster'", err)
+	}
 
 	// done
 	return cmd
@@ -102,6 +107,10 @@ func parseDeleteNodeCmd(cmd *cobra.Command, args []string, flags *nodeDeleteFlag
 		l.Log().Fatalln("Expecting at least one node name if `--all` is not set")
 	}
 
+	if flags.Cluster != "" {
+		return parseNodeRefs(cmd, flags.Cluster, args)
+	}
+
 	for _, name := range args {
 		node, err := client.NodeGet(cmd.Context(), runtimes.SelectedRuntime, &k3d.Node{Name: name})
 		if err != nil {
@@ -0,0 +1,119 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package node
+
+import (
+	"io"
+	"os"
+
+	"github.com/moby/term"
+	"github.com/rancher/k3d/v5/cmd/util"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/spf13/cobra"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+)
+
+type nodeExecFlags struct {
+	Cluster     string
+	Interactive bool
+	TTY         bool
+}
+
+// NewCmdNodeExec returns a new cobra command
+func NewCmdNodeExec() *cobra.Command {
+
+	flags := nodeExecFlags{}
+
+	// create new command
+	cmd := &cobra.Command{
+		Use:   "exec NODE -- COMMAND [ARG...]",
+		Short: "Run a command in a node",
+		Long: `Run a command in a node (like 'docker exec'), e.g. 'k3d node exec --cluster mycluster agent:0 -- crictl ps'.
+The exit code of the command is passed on.`,
+		Args:              cobra.MinimumNArgs(2),
+		ValidArgsFunction: util.ValidArgsAvailableNodes,
+		Run: func(cmd *cobra.Command, args []string) {
+			node := parseNodeRefs(cmd, flags.Cluster, args[:1])[0]
+			os.Exit(execInNode(cmd, node, args[1:], flags.Interactive, flags.TTY))
+		},
+	}
+
+	// add flags
+	cmd.Flags().StringVarP(&flags.Cluster, "cluster", "c", "", "Cluster of the node, allows referencing it by short name (e.g. 'agent-1') or ROLE:INDEX (e.g. 'agent:1')")
+	if err := cmd.RegisterFlagCompletionFunc("cluster", util.ValidArgsAvailableClusters); err != nil {
+		l.Log().Fatalln("Failed to register flag completion for '--cluster'", err)
+	}
+	cmd.Flags().BoolVarP(&flags.Interactive, "interactive", "i", false, "Pass stdin on to the command")
+	cmd.Flags().BoolVarP(&flags.TTY, "tty", "t", false, "Allocate a TTY for the command")
+	// everything after the node belongs to the command, also without '--' (e.g. 'k3d node exec NODE ls -l')
+	cmd.Flags().SetInterspersed(false)
+
+	// done
+	return cmd
+}
+
+// NewCmdNodeShell returns a new cobra command
+func NewCmdNodeShell() *cobra.Command {
+
+	flags := nodeExecFlags{}
+	var shell string
+
+	// create new command
+	cmd := &cobra.Command{
+		Use:               "shell NODE",
+		Short:             "Open an interactive shell in a node",
+		Long:              `Open an interactive shell in a node, e.g. 'k3d node shell --cluster mycluster server:0' (the same as 'k3d node exec -it NODE -- sh').`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: util.ValidArgsAvailableNodes,
+		Run: func(cmd *cobra.Command, args []string) {
+			node := parseNodeRefs(cmd, flags.Cluster, args)[0]
+			// only ask for a TTY if there's a terminal to connect it to, so that the shell can also read a script from a pipe
+			_, isTerminal := term.GetFdInfo(os.Stdin)
+			os.Exit(execInNode(cmd, node, []string{shell}, true, isTerminal))
+		},
+	}
+
+	// add flags
+	cmd.Flags().StringVarP(&flags.Cluster, "cluster", "c", "", "Cluster of the node, allows referencing it by short name (e.g. 'agent-1') or ROLE:INDEX (e.g. 'agent:1')")
+	if err := cmd.RegisterFlagCompletionFunc("cluster", util.ValidArgsAvailableClusters); err != nil {
+		l.Log().Fatalln("Failed to register flag completion for '--cluster'", err)
+	}
+	cmd.Flags().StringVar(&shell, "shell", "sh", "Shell to run (the k3s image only ships the busybox 'sh')")
+
+	// done
+	return cmd
+}
+
+// execInNode runs the command in the node with the standard streams attached and returns its exit code
+func execInNode(cmd *cobra.Command, node *k3d.Node, command []string, interactive bool, tty bool) int {
+	var stdin io.Reader
+	if interactive {
+		stdin = os.Stdin
+	}
+	exitCode, err := runtimes.SelectedRuntime.ExecInNodeInteractive(cmd.Context(), node, command, tty, stdin, os.Stdout, os.Stderr)
+	if err != nil {
+		l.Log().Fatalln(err)
+	}
+	return exitCode
+}
@@ -37,6 +37,8 @@ import (
 type nodeListFlags struct {
 	noHeader bool
 	output   string
+	roles    []string
+	clusters []string
 }
 
 // NewCmdNodeList returns a new cobra command
@@ -76,28 +78,107 @@ func NewCmdNodeList() *cobra.Command {
 				}
 			}
 
+			// registries used by a cluster (--registry-use) don't belong to it, but are connected to its network
+			clusterNetworks := []string{}
+			for _, clusterName := range nodeListFlags.clusters {
+				cluster, err := client.ClusterGet(cmd.Context(), runtimes.SelectedRuntime, &k3d.Cluster{Name: clusterName})
+				if err != nil {
+					l.Log().Fatalln(err)
+				}
+				clusterNetworks = append(clusterNetworks, cluster.Network.Name)
+			}
+
+			existingNodes, err := filterNodeList(existingNodes, &nodeListFlags, clusterNetworks)
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+
 			// print existing nodes
 			headers := &[]string{}
 			if !nodeListFlags.noHeader {
-				headers = &[]string{"NAME", "ROLE", "CLUSTER", "STATUS"}
+				headers = &[]string{"NAME", "ROLE", "CLUSTER", "STATUS", "PORTS"}
 			}
 
 			util.PrintNodes(existingNodes, nodeListFlags.output,
 				headers, util.NodePrinterFunc(func(tabwriter *tabwriter.Writer, node *k3d.Node) {
-					fmt.Fprintf(tabwriter, "%s\t%s\t%s\t%s\n",
+					ports := []string{}
+					for _, mapping := range client.NodePortMappings(node) {
+						ports = append(ports, mapping.Short())
+					}
+					status := node.State.Status
+					if node.State.OOMKilled {
+						status += " (oom-killed)"
+					}
+					fmt.Fprintf(tabwriter, "%s\t%s\t%s\t%s\t%s\n",
 						strings.TrimPrefix(node.Name, "/"),
 						string(node.Role),
 						node.RuntimeLabels[k3d.LabelClusterName],
-						node.State.Status)
+						status,
+						strings.Join(ports, ","))
 				}))
 		},
 	}
 	// add flags
 	cmd.Flags().BoolVar(&nodeListFlags.noHeader, "no-headers", false, "Disable headers")
 	cmd.Flags().StringVarP(&nodeListFlags.output, "output", "o", "", "Output format. One of: json|yaml")
+	cmd.Flags().StringSliceVar(&nodeListFlags.roles, "role", []string{}, "Only list nodes with the given role(s). One of: server|agent|loadbalancer|registry")
+	if err := cmd.RegisterFlagCompletionFunc("role", util.ValidArgsNodeRoles); err != nil {
+		l.Log().Fatalln("Failed to register flag completion for '--role'", err)
+	}
+	cmd.Flags().StringSliceVarP(&nodeListFlags.clusters, "cluster", "c", []string{}, "Only list nodes belonging to the given cluster(s), incl. registries connected to their network (e.g. via --registry-use)")
+	if err := cmd.RegisterFlagCompletionFunc("cluster", util.ValidArgsAvailableClusters); err != nil {
+		l.Log().Fatalln("Failed to register flag completion for '--cluster'", err)
+	}
 
 	// add subcommands
 
 	// done
 	return cmd
 }
+
+// filterNodeList reduces the list of nodes to those matching the --role and --cluster flags
+// Registries match a cluster, if they belong to it or if they're connected to one of the given cluster networks.
+func filterNodeList(nodes []*k3d.Node, flags *nodeListFlags, clusterNetworks []string) ([]*k3d.Node, error) {
+	if len(flags.roles) > 0 {
+		roles := []k3d.Role{}
+		for _, r := range flags.roles {
+			role, ok := k3d.NodeRoles[r]
+			if !ok {
+				return nil, fmt.Errorf("unknown node role '%s'", r)
+			}
+			roles = append(roles, role)
+		}
+		nodes = client.NodeFilterByRoles(nodes, roles, []k3d.Role{})
+	}
+
+	if len(flags.clusters) > 0 {
+		filtered := []*k3d.Node{}
+		for _, node := range nodes {
+			if nodeInClusters(node, flags.clusters, clusterNetworks) {
+				filtered = append(filtered, node)
+			}
+		}
+		nodes = filtered
+	}
+
+	return nodes, nil
+}
+
+// nodeInClusters checks if a node belongs to one of the given clusters or, for registries, is connected to one of their networks
+func nodeInClusters(node *k3d.Node, clusters []string, clusterNetworks []string) bool {
+	for _, c := range clusters {
+		if node.RuntimeLabels[k3d.LabelClusterName] == c {
+			return true
+		}
+	}
+	if node.Role == k3d.RegistryRole {
+		for _, net := range node.Networks {
+			for _, clusterNet := range clusterNetworks {
+				if net == clusterNet {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package node
+
+import (
+	"testing"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestFilterNodeList(t *testing.T) {
+	newNode := func(name string, role k3d.Role, cluster string, networks ...string) *k3d.Node {
+		labels := map[string]string{}
+		if cluster != "" {
+			labels[k3d.LabelClusterName] = cluster
+		}
+		return &k3d.Node{Name: name, Role: role, RuntimeLabels: labels, Networks: networks}
+	}
+	nodes := []*k3d.Node{
+		newNode("k3d-a-server-0", k3d.ServerRole, "a", "k3d-a"),
+		newNode("k3d-a-agent-0", k3d.AgentRole, "a", "k3d-a"),
+		newNode("k3d-a-serverlb", k3d.LoadBalancerRole, "a", "k3d-a"),
+		newNode("k3d-a-registry", k3d.RegistryRole, "a", "k3d-a"),
+		newNode("k3d-b-server-0", k3d.ServerRole, "b", "k3d-b"),
+		newNode("k3d-shared-registry", k3d.RegistryRole, "", "bridge", "k3d-b"), // used by cluster b via --registry-use
+		newNode("k3d-unused-registry", k3d.RegistryRole, "", "bridge"),
+	}
+
+	tests := map[string]struct {
+		flags           nodeListFlags
+		clusterNetworks []string
+		expected        []string
+		expectErr       bool
+	}{
+		"no filter": {
+			expected: []string{"k3d-a-server-0", "k3d-a-agent-0", "k3d-a-serverlb", "k3d-a-registry", "k3d-b-server-0", "k3d-shared-registry", "k3d-unused-registry"},
+		},
+		"roles": {
+			flags:    nodeListFlags{roles: []string{"loadbalancer", "registry"}},
+			expected: []string{"k3d-a-serverlb", "k3d-a-registry", "k3d-shared-registry", "k3d-unused-registry"},
+		},
+		"cluster": {
+			flags:           nodeListFlags{clusters: []string{"a"}},
+			clusterNetworks: []string{"k3d-a"},
+			expected:        []string{"k3d-a-server-0", "k3d-a-agent-0", "k3d-a-serverlb", "k3d-a-registry"},
+		},
+		"cluster with used registry": {
+			flags:           nodeListFlags{clusters: []string{"b"}},
+			clusterNetworks: []string{"k3d-b"},
+			expected:        []string{"k3d-b-server-0", "k3d-shared-registry"},
+		},
+		"role and cluster": {
+			flags:           nodeListFlags{roles: []string{"registry"}, clusters: []string{"a", "b"}},
+			clusterNetworks: []string{"k3d-a", "k3d-b"},
+			expected:        []string{"k3d-a-registry", "k3d-shared-registry"},
+		},
+		"unknown role": {
+			flags:     nodeListFlags{roles: []string{"worker"}},
+			expectErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		filtered, err := filterNodeList(nodes, &tc.flags, tc.clusterNetworks)
+		if tc.expectErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+			continue
+		}
+		names := []string{}
+		for _, node := range filtered {
+			names = append(names, node.Name)
+		}
+		if len(names) != len(tc.expected) {
+			t.Errorf("%s: expected %v, got %v", name, tc.expected, names)
+			continue
+		}
+		for i := range names {
+			if names[i] != tc.expected[i] {
+				t.Errorf("%s: expected %v, got %v", name, tc.expected, names)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package node
+
+import (
+	"os"
+	"sort"
+	"time"
+
+	"github.com/rancher/k3d/v5/cmd/util"
+	"github.com/rancher/k3d/v5/pkg/client"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/spf13/cobra"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+)
+
+type nodeLogsFlags struct {
+	Cluster string
+	Follow  bool
+	Since   time.Duration
+}
+
+// NewCmdNodeLogs returns a new cobra command
+func NewCmdNodeLogs() *cobra.Command {
+
+	flags := nodeLogsFlags{}
+
+	// create new command
+	cmd := &cobra.Command{
+		Use:   "logs [NODE...]",
+		Short: "Print the logs of node(s)",
+		Long: `Print the logs of the given node(s) or, without nodes, of all nodes of the cluster (--cluster, default: 'k3s-default').
+The logs of multiple nodes are printed concurrently, with each line prefixed by the name of its node,
+e.g. 'k3d node logs --cluster mycluster -f' follows the logs of the whole cluster.`,
+		ValidArgsFunction: util.ValidArgsAvailableNodes,
+		Run: func(cmd *cobra.Command, args []string) {
+			var nodes []*k3d.Node
+			if len(args) == 0 {
+				if flags.Cluster == "" {
+					flags.Cluster = k3d.DefaultClusterName
+				}
+				cluster, err := client.ClusterGet(cmd.Context(), runtimes.SelectedRuntime, &k3d.Cluster{Name: flags.Cluster})
+				if err != nil {
+					l.Log().Fatalln(err)
+				}
+				nodes = cluster.Nodes
+				sort.Slice(nodes, func(i, j int) bool {
+					return nodes[i].Name < nodes[j].Name
+				})
+			} else {
+				nodes = parseNodeRefs(cmd, flags.Cluster, args)
+			}
+
+			opts := k3d.NodeLogsOpts{Follow: flags.Follow}
+			if flags.Since > 0 {
+				opts.Since = time.Now().Add(-flags.Since)
+			}
+			if err := client.NodeLogs(cmd.Context(), runtimes.SelectedRuntime, nodes, opts, os.Stdout); err != nil {
+				l.Log().Fatalln(err)
+			}
+		},
+	}
+
+	// add flags
+	cmd.Flags().StringVarP(&flags.Cluster, "cluster", "c", "", "Cluster of the node(s), allows referencing nodes by short name (e.g. 'agent-1') or ROLE:INDEX (e.g. 'agent:1')")
+	if err := cmd.RegisterFlagCompletionFunc("cluster", util.ValidArgsAvailableClusters); err != nil {
+		l.Log().Fatalln("Failed to register flag completion for '--cluster'", err)
+	}
+	cmd.Flags().BoolVarP(&flags.Follow, "follow", "f", false, "Keep printing new log lines until the node(s) stop")
+	cmd.Flags().DurationVar(&flags.Since, "since", 0, "Only print log lines of the given period, e.g. '10m'")
+
+	// done
+	return cmd
+}
@@ -0,0 +1,68 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package node
+
+import (
+	"github.com/rancher/k3d/v5/cmd/util"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	"github.com/spf13/cobra"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+)
+
+type nodeRestartFlags struct {
+	Cluster string
+}
+
+// NewCmdNodeRestart returns a new cobra command
+func NewCmdNodeRestart() *cobra.Command {
+
+	flags := nodeRestartFlags{}
+
+	// create new command
+	cmd := &cobra.Command{
+		Use:               "restart NODE...",
+		Short:             "Restart existing k3d node(s)",
+		Long:              `Restart existing k3d node(s) by stopping and starting them again, e.g. with 'k3d node restart --cluster mycluster server:0'.`,
+		ValidArgsFunction: util.ValidArgsAvailableNodes,
+		Run: func(cmd *cobra.Command, args []string) {
+			for _, node := range parseNodeRefs(cmd, flags.Cluster, args) {
+				l.Log().Infof("Restarting node '%s'", node.Name)
+				if err := runtimes.SelectedRuntime.StopNode(cmd.Context(), node); err != nil {
+					l.Log().Fatalln(err)
+				}
+				if err := runtimes.SelectedRuntime.StartNode(cmd.Context(), node); err != nil {
+					l.Log().Fatalln(err)
+				}
+			}
+		},
+	}
+
+	// add flags
+	cmd.Flags().StringVarP(&flags.Cluster, "cluster", "c", "", "Cluster of the node(s), allows referencing nodes by short name (e.g. 'agent-1') or ROLE:INDEX (e.g. 'agent:1')")
+	if err := cmd.RegisterFlagCompletionFunc("cluster", util.ValidArgsAvailableClusters); err != nil {
+		l.Log().Fatalln("Failed to register flag completion for '--cluster'", err)
+	}
+
+	// done
+	return cmd
+}
@@ -25,37 +25,40 @@ import (
 	"github.com/rancher/k3d/v5/cmd/util"
 	l "github.com/rancher/k3d/v5/pkg/logger"
 	"github.com/rancher/k3d/v5/pkg/runtimes"
-	k3d "github.com/rancher/k3d/v5/pkg/types"
 	"github.com/spf13/cobra"
 )
 
+type nodeStartFlags struct {
+	Cluster string
+}
+
 // NewCmdNodeStart returns a new cobra command
 func NewCmdNodeStart() *cobra.Command {
 
+	flags := nodeStartFlags{}
+
 	// create new command
 	cmd := &cobra.Command{
-		Use:               "start NODE", // TODO: startNode: allow one or more names or --all
-		Short:             "Start an existing k3d node",
-		Long:              `Start an existing k3d node.`,
+		Use:               "start NODE...",
+		Short:             "Start existing k3d node(s)",
+		Long:              `Start existing k3d node(s), e.g. to let a stopped node rejoin its cluster with 'k3d node start --cluster mycluster agent:0'.`,
 		ValidArgsFunction: util.ValidArgsAvailableNodes,
 		Run: func(cmd *cobra.Command, args []string) {
-			node := parseStartNodeCmd(cmd, args)
-			if err := runtimes.SelectedRuntime.StartNode(cmd.Context(), node); err != nil {
-				l.Log().Fatalln(err)
+			for _, node := range parseNodeRefs(cmd, flags.Cluster, args) {
+				l.Log().Infof("Starting node '%s'", node.Name)
+				if err := runtimes.SelectedRuntime.StartNode(cmd.Context(), node); err != nil {
+					l.Log().Fatalln(err)
+				}
 			}
 		},
 	}
 
-	// done
-	return cmd
-}
-
-// parseStartNodeCmd parses the command input into variables required to start a node
-func parseStartNodeCmd(cmd *cobra.Command, args []string) *k3d.Node {
-	// node name // TODO: startNode: allow node filters, e.g. `k3d node start mycluster@agent` to start all agent nodes of cluster 'mycluster'
-	if len(args) == 0 || len(args[0]) == 0 {
-		l.Log().Fatalln("No node name given")
+	// add flags
+	cmd.Flags().StringVarP(&flags.Cluster, "cluster", "c", "", "Cluster of the node(s), allows referencing nodes by short name (e.g. 'agent-1') or ROLE:INDEX (e.g. 'agent:1')")
+	if err := cmd.RegisterFlagCompletionFunc("cluster", util.ValidArgsAvailableClusters); err != nil {
+		l.Log().Fatalln("Failed to register flag completion for '--cluster'", err)
 	}
 
-	return &k3d.Node{Name: args[0]}
+	// done
+	return cmd
 }
@@ -27,36 +27,39 @@ import (
 	"github.com/spf13/cobra"
 
 	l "github.com/rancher/k3d/v5/pkg/logger"
-	k3d "github.com/rancher/k3d/v5/pkg/types"
 )
 
+type nodeStopFlags struct {
+	Cluster string
+}
+
 // NewCmdNodeStop returns a new cobra command
 func NewCmdNodeStop() *cobra.Command {
 
+	flags := nodeStopFlags{}
+
 	// create new command
 	cmd := &cobra.Command{
-		Use:               "stop NAME", // TODO: stopNode: allow one or more names or --all",
-		Short:             "Stop an existing k3d node",
-		Long:              `Stop an existing k3d node.`,
+		Use:               "stop NODE...",
+		Short:             "Stop existing k3d node(s)",
+		Long:              `Stop existing k3d node(s), e.g. to simulate the failure of a single node with 'k3d node stop --cluster mycluster agent:0'.`,
 		ValidArgsFunction: util.ValidArgsAvailableNodes,
 		Run: func(cmd *cobra.Command, args []string) {
-			node := parseStopNodeCmd(cmd, args)
-			if err := runtimes.SelectedRuntime.StopNode(cmd.Context(), node); err != nil {
-				l.Log().Fatalln(err)
+			for _, node := range parseNodeRefs(cmd, flags.Cluster, args) {
+				l.Log().Infof("Stopping node '%s'", node.Name)
+				if err := runtimes.SelectedRuntime.StopNode(cmd.Context(), node); err != nil {
+					l.Log().Fatalln(err)
+				}
 			}
 		},
 	}
 
-	// done
-	return cmd
-}
-
-// parseStopNodeCmd parses the command input into variables required to stop a node
-func parseStopNodeCmd(cmd *cobra.Command, args []string) *k3d.Node {
-	// node name // TODO: allow node filters, e.g. `k3d node stop mycluster@agent` to stop all agent nodes of cluster 'mycluster'
-	if len(args) == 0 || len(args[0]) == 0 {
-		l.Log().Fatalln("No node name given")
+	// add flags
+	cmd.Flags().StringVarP(&flags.Cluster, "cluster", "c", "", "Cluster of the node(s), allows referencing nodes by short name (e.g. 'agent-1') or ROLE:INDEX (e.g. 'agent:1')")
+	if err := cmd.RegisterFlagCompletionFunc("cluster", util.ValidArgsAvailableClusters); err != nil {
+		l.Log().Fatalln("Failed to register flag completion for '--cluster'", err)
 	}
 
-	return &k3d.Node{Name: args[0]}
+	// done
+	return cmd
 }
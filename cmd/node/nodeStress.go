@@ -0,0 +1,96 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package node
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/rancher/k3d/v5/cmd/util"
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+type nodeStressFlags struct {
+	Cluster  string
+	CPU      int
+	IO       bool
+	FillDisk string
+	Stop     bool
+}
+
+// NewCmdNodeStress returns a new cobra command
+func NewCmdNodeStress() *cobra.Command {
+
+	flags := nodeStressFlags{}
+
+	// create new command
+	cmd := &cobra.Command{
+		Use:   "stress NODE [NODE...]",
+		Short: "Inject CPU, IO and disk pressure into node(s)",
+		Long: `Inject load into node(s) to trigger pressure conditions and kubelet evictions, e.g. to test the resilience of operators:
+	--cpu N: N CPU-burning workers (stress-ng, if the node image ships it, else busy loops)
+	--io: keep writing and syncing a file
+	--fill-disk PERCENT: fill the filesystem of the kubelet directory up to PERCENT (disk pressure at the default eviction threshold of 10% available)
+The load keeps running in the background until it's stopped via '--stop' (or the node is restarted).`,
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: util.ValidArgsAvailableNodes,
+		Run: func(cmd *cobra.Command, args []string) {
+			for _, node := range parseNodeRefs(cmd, flags.Cluster, args) {
+				if flags.Stop {
+					if err := client.NodeStressStop(cmd.Context(), runtimes.SelectedRuntime, node); err != nil {
+						l.Log().Fatalln(err)
+					}
+					l.Log().Infof("Stopped load in node '%s'", node.Name)
+					continue
+				}
+				opts := k3d.NodeStressOpts{CPU: flags.CPU, IO: flags.IO}
+				if flags.FillDisk != "" {
+					percent, err := strconv.Atoi(strings.TrimSuffix(flags.FillDisk, "%"))
+					if err != nil {
+						l.Log().Fatalf("Invalid value '%s' for --fill-disk (format 'PERCENT', e.g. '90%%')", flags.FillDisk)
+					}
+					opts.FillDisk = percent
+				}
+				if err := client.NodeStress(cmd.Context(), runtimes.SelectedRuntime, node, opts); err != nil {
+					l.Log().Fatalln(err)
+				}
+			}
+		},
+	}
+
+	// add flags
+	cmd.Flags().StringVarP(&flags.Cluster, "cluster", "c", "", "Cluster of the node(s), allows referencing them by short name (e.g. 'agent-1') or ROLE:INDEX (e.g. 'agent:1')")
+	if err := cmd.RegisterFlagCompletionFunc("cluster", util.ValidArgsAvailableClusters); err != nil {
+		l.Log().Fatalln("Failed to register flag completion for '--cluster'", err)
+	}
+	cmd.Flags().IntVar(&flags.CPU, "cpu", 0, "Number of CPU-burning workers")
+	cmd.Flags().BoolVar(&flags.IO, "io", false, "Keep writing and syncing a file to load the disk")
+	cmd.Flags().StringVar(&flags.FillDisk, "fill-disk", "", "Fill the filesystem of the kubelet directory up to this usage (Format: `PERCENT`, e.g. '90%')")
+	cmd.Flags().BoolVar(&flags.Stop, "stop", false, "Stop the injected load and free the filled disk space")
+
+	// done
+	return cmd
+}
@@ -0,0 +1,127 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"gopkg.in/yaml.v2"
+
+	cliutil "github.com/rancher/k3d/v5/cmd/util"
+	"github.com/rancher/k3d/v5/pkg/client"
+	"github.com/rancher/k3d/v5/pkg/config"
+	conf "github.com/rancher/k3d/v5/pkg/config/v1alpha3"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdPool returns a new cobra command
+func NewCmdPool() *cobra.Command {
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:   "pool",
+		Short: "Manage warm pools of clusters",
+		Long: `Manage warm pools of pre-created clusters.
+Parallel CI jobs acquire an isolated cluster from the pool instantly instead of each paying the boot cost and release it when they're done.
+Released clusters are re-created in the background of the releasing job, so that the next job gets a fresh one.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := cmd.Help(); err != nil {
+				l.Log().Errorln("Couldn't get help text")
+				l.Log().Fatalln(err)
+			}
+		},
+	}
+
+	// add subcommands
+	cmd.AddCommand(NewCmdPoolCreate())
+	cmd.AddCommand(NewCmdPoolAcquire())
+	cmd.AddCommand(NewCmdPoolRelease())
+	cmd.AddCommand(NewCmdPoolList())
+	cmd.AddCommand(NewCmdPoolDelete())
+
+	// done
+	return cmd
+}
+
+// poolCreateCluster creates a single cluster of a pool from the pool's config
+func poolCreateCluster(ctx context.Context, pool *k3d.Pool, clusterName string) error {
+	simpleCfg := conf.SimpleConfig{}
+	if err := yaml.Unmarshal([]byte(pool.Config), &simpleCfg); err != nil {
+		return fmt.Errorf("failed to parse config of pool '%s': %w", pool.Name, err)
+	}
+	simpleCfg.Name = clusterName
+
+	// every cluster of the pool needs its own API port
+	if simpleCfg.ExposeAPI.HostPort == "" {
+		port, err := cliutil.GetFreePort()
+		if err != nil || port == 0 {
+			return fmt.Errorf("failed to get a free port for the Kubernetes API: %w", err)
+		}
+		simpleCfg.ExposeAPI.HostPort = strconv.Itoa(port)
+	}
+
+	clusterConfig, err := config.TransformSimpleToClusterConfig(ctx, runtimes.SelectedRuntime, simpleCfg)
+	if err != nil {
+		return err
+	}
+	clusterConfig, err = config.ProcessClusterConfig(*clusterConfig)
+	if err != nil {
+		return err
+	}
+	if err := config.ValidateClusterConfig(ctx, runtimes.SelectedRuntime, *clusterConfig); err != nil {
+		return fmt.Errorf("failed cluster configuration validation: %w", err)
+	}
+	clusterConfig.ClusterCreateOpts.GlobalLabels[k3d.LabelPool] = pool.Name
+	clusterConfig.ClusterCreateOpts.GlobalLabels[k3d.LabelClusterOwner] = cliutil.ClusterOwner()
+
+	l.Log().Infof("Creating cluster '%s' of pool '%s'...", clusterName, pool.Name)
+	if err := client.ClusterRun(ctx, runtimes.SelectedRuntime, clusterConfig); err != nil {
+		if errors.Is(err, client.ErrClusterAlreadyExists) { // not ours to delete
+			return fmt.Errorf("failed to create cluster '%s': %w", clusterName, err)
+		}
+		if deleteErr := client.ClusterDelete(ctx, runtimes.SelectedRuntime, &clusterConfig.Cluster, k3d.ClusterDeleteOpts{SkipRegistryCheck: true}); deleteErr != nil {
+			l.Log().Warnf("Failed to clean up cluster '%s': %v", clusterName, deleteErr)
+		}
+		return fmt.Errorf("failed to create cluster '%s': %w", clusterName, err)
+	}
+	return nil
+}
+
+// poolRecreateCluster replaces a cluster of a pool with a fresh one and hands it back to the pool
+func poolRecreateCluster(ctx context.Context, pool *k3d.Pool, clusterName string) error {
+	if err := client.ClusterDelete(ctx, runtimes.SelectedRuntime, &k3d.Cluster{Name: clusterName}, k3d.ClusterDeleteOpts{SkipRegistryCheck: true}); err != nil {
+		l.Log().Warnf("Failed to delete cluster '%s' (trying to re-create it anyway): %v", clusterName, err)
+	}
+	if err := poolCreateCluster(ctx, pool, clusterName); err != nil {
+		if removeErr := client.PoolRemoveCluster(ctx, pool.Name, clusterName); removeErr != nil {
+			l.Log().Warnf("Failed to remove cluster '%s' from pool '%s': %v", clusterName, pool.Name, removeErr)
+		}
+		return err
+	}
+	return client.PoolSetClusterState(ctx, pool.Name, clusterName, k3d.PoolClusterStateFree)
+}
@@ -0,0 +1,108 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+type poolAcquireFlags struct {
+	holder     string
+	wait       time.Duration
+	kubeconfig string
+}
+
+// NewCmdPoolAcquire returns a new cobra command
+func NewCmdPoolAcquire() *cobra.Command {
+
+	flags := poolAcquireFlags{}
+
+	// create new command
+	cmd := &cobra.Command{
+		Use:   "acquire NAME",
+		Short: "Lease a free cluster of a pool",
+		Long: `Lease a free cluster of a pool and print its name.
+The cluster stays leased until it's handed back via 'k3d pool release NAME CLUSTER'.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			name := args[0]
+
+			holder := flags.holder
+			if holder == "" {
+				hostname, _ := os.Hostname()
+				holder = fmt.Sprintf("%s/%d", hostname, os.Getpid())
+			}
+
+			deadline := time.Now().Add(flags.wait)
+			var cluster *k3d.PoolCluster
+			for {
+				var err error
+				cluster, err = client.PoolAcquire(cmd.Context(), name, holder)
+				if err == nil {
+					break
+				}
+				if !errors.Is(err, client.ErrPoolExhausted) || time.Now().After(deadline) {
+					l.Log().Fatalln(err)
+				}
+				l.Log().Debugf("%v, retrying...", err)
+				select {
+				case <-cmd.Context().Done():
+					l.Log().Fatalln(cmd.Context().Err())
+				case <-time.After(2 * time.Second):
+				}
+			}
+			l.Log().Infof("Acquired cluster '%s' of pool '%s' for '%s'", cluster.Name, name, holder)
+
+			if flags.kubeconfig != "" {
+				kubeconfig, err := client.KubeconfigGet(cmd.Context(), runtimes.SelectedRuntime, &k3d.Cluster{Name: cluster.Name})
+				if err == nil {
+					err = client.KubeconfigWriteToPath(cmd.Context(), kubeconfig, flags.kubeconfig)
+				}
+				if err != nil {
+					if releaseErr := client.PoolRelease(cmd.Context(), name, cluster.Name, true); releaseErr != nil {
+						l.Log().Warnf("Failed to release cluster '%s': %v", cluster.Name, releaseErr)
+					}
+					l.Log().Fatalf("Failed to write kubeconfig of cluster '%s': %v", cluster.Name, err)
+				}
+				l.Log().Infof("Wrote kubeconfig to '%s'", flags.kubeconfig)
+			}
+
+			fmt.Println(cluster.Name)
+		},
+	}
+
+	cmd.Flags().StringVar(&flags.holder, "holder", "", "Identifier of the lease holder shown in 'k3d pool list', e.g. a CI job ID (default: HOSTNAME/PID)")
+	cmd.Flags().DurationVar(&flags.wait, "wait", 0, "Wait up to this long for a cluster to become free, if all are leased")
+	cmd.Flags().StringVar(&flags.kubeconfig, "kubeconfig-file", "", "Write the kubeconfig of the acquired cluster to this file")
+
+	// done
+	return cmd
+}
@@ -0,0 +1,185 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package pool
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+
+	cliconfig "github.com/rancher/k3d/v5/cmd/util/config"
+	"github.com/rancher/k3d/v5/pkg/client"
+	"github.com/rancher/k3d/v5/pkg/config"
+	conf "github.com/rancher/k3d/v5/pkg/config/v1alpha3"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/rancher/k3d/v5/version"
+)
+
+type poolCreateFlags struct {
+	configFile string
+	size       int
+}
+
+// NewCmdPoolCreate returns a new cobra command
+func NewCmdPoolCreate() *cobra.Command {
+
+	flags := poolCreateFlags{}
+	cfgViper := viper.New()
+
+	// create new command
+	cmd := &cobra.Command{
+		Use:   "create NAME",
+		Short: "Create a pool of clusters",
+		Long: `Create a pool of identical clusters named NAME-0 ... NAME-<size-1>.
+Use a config file (--config) to set up the clusters just like with 'k3d cluster create'.`,
+		Args: cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return cliconfig.InitViperWithConfigFile(cfgViper, flags.configFile)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			name := args[0]
+			if flags.size < 1 {
+				l.Log().Fatalf("Pool size must be at least 1, got %d", flags.size)
+			}
+			if err := client.CheckName(client.PoolClusterName(name, flags.size-1)); err != nil {
+				l.Log().Fatalf("Invalid pool name '%s': %v", name, err)
+			}
+
+			poolCfg, err := poolCreateConfig(cfgViper)
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+
+			// don't touch any existing clusters, which happen to have the same name
+			for i := 0; i < flags.size; i++ {
+				clusterName := client.PoolClusterName(name, i)
+				if _, err := client.ClusterGet(cmd.Context(), runtimes.SelectedRuntime, &k3d.Cluster{Name: clusterName}); err == nil {
+					l.Log().Fatalf("Cannot create pool '%s': a cluster named '%s' already exists", name, clusterName)
+				}
+			}
+
+			pool, err := client.PoolUpdate(cmd.Context(), name, true, func(pool *k3d.Pool) error {
+				if pool.Size > 0 {
+					return fmt.Errorf("pool '%s' already exists", name)
+				}
+				pool.Size = flags.size
+				pool.Config = poolCfg
+				for i := 0; i < flags.size; i++ {
+					pool.Clusters = append(pool.Clusters, &k3d.PoolCluster{Name: client.PoolClusterName(name, i), State: k3d.PoolClusterStateCreating})
+				}
+				return nil
+			})
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+
+			var errs []error
+			for _, cluster := range pool.Clusters {
+				if err := poolCreateCluster(cmd.Context(), pool, cluster.Name); err != nil {
+					l.Log().Errorln(err)
+					errs = append(errs, err)
+					if err := client.PoolRemoveCluster(cmd.Context(), name, cluster.Name); err != nil {
+						l.Log().Warnf("Failed to remove cluster '%s' from pool '%s': %v", cluster.Name, name, err)
+					}
+					continue
+				}
+				if err := client.PoolSetClusterState(cmd.Context(), name, cluster.Name, k3d.PoolClusterStateFree); err != nil {
+					l.Log().Fatalln(err)
+				}
+			}
+
+			if len(errs) > 0 {
+				l.Log().Fatalf("Failed to create %d of %d clusters of pool '%s'", len(errs), flags.size, name)
+			}
+			l.Log().Infof("Pool '%s' with %d clusters is ready. Acquire a cluster via 'k3d pool acquire %s'", name, flags.size, name)
+		},
+	}
+
+	/*********
+	 * Flags *
+	 *********/
+
+	cmd.Flags().IntVar(&flags.size, "size", 2, "Number of clusters in the pool")
+
+	cmd.Flags().StringVarP(&flags.configFile, "config", "c", "", "Path of a config file to use for the clusters of the pool")
+	if err := cmd.MarkFlagFilename("config", "yaml", "yml"); err != nil {
+		l.Log().Fatalln("Failed to mark flag 'config' as filename flag")
+	}
+
+	cmd.Flags().IntP("servers", "s", 1, "Specify how many servers you want to create")
+	_ = cfgViper.BindPFlag("servers", cmd.Flags().Lookup("servers"))
+	cfgViper.SetDefault("servers", 1)
+
+	cmd.Flags().IntP("agents", "a", 0, "Specify how many agents you want to create")
+	_ = cfgViper.BindPFlag("agents", cmd.Flags().Lookup("agents"))
+	cfgViper.SetDefault("agents", 0)
+
+	cmd.Flags().StringP("image", "i", "", "Specify k3s image that you want to use for the nodes")
+	_ = cfgViper.BindPFlag("image", cmd.Flags().Lookup("image"))
+	cfgViper.SetDefault("image", fmt.Sprintf("%s:%s", k3d.DefaultK3sImageRepo, version.GetK3sVersion(false)))
+
+	// done
+	return cmd
+}
+
+// poolCreateConfig reads the config used for all clusters of the pool and returns it as YAML
+func poolCreateConfig(cfgViper *viper.Viper) (string, error) {
+	if cfgViper.GetString("apiversion") == "" {
+		cfgViper.Set("apiversion", config.DefaultConfigApiVersion)
+	}
+	if cfgViper.GetString("kind") == "" {
+		cfgViper.Set("kind", "Simple")
+	}
+	cfg, err := config.FromViper(cfgViper)
+	if err != nil {
+		return "", err
+	}
+
+	if cfg.GetAPIVersion() != config.DefaultConfigApiVersion {
+		cfg, err = config.Migrate(cfg, config.DefaultConfigApiVersion)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	simpleCfg := cfg.(conf.SimpleConfig)
+	if simpleCfg.ExposeAPI.HostPort != "" {
+		return "", errors.New("the clusters of a pool can't share a fixed API port: remove kubeAPI.hostPort from the config")
+	}
+
+	// pool clusters are accessed via 'k3d pool acquire', so we wait for them to be up and leave the default kubeconfig alone
+	simpleCfg.Name = ""
+	simpleCfg.Options.K3dOptions.Wait = true
+	simpleCfg.Options.KubeconfigOptions.UpdateDefaultKubeconfig = false
+	simpleCfg.Options.KubeconfigOptions.SwitchCurrentContext = false
+
+	content, err := yaml.Marshal(simpleCfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal pool config: %w", err)
+	}
+	return string(content), nil
+}
@@ -0,0 +1,83 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package pool
+
+import (
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdPoolDelete returns a new cobra command
+func NewCmdPoolDelete() *cobra.Command {
+
+	var force bool
+
+	// create new command
+	cmd := &cobra.Command{
+		Use:     "delete NAME",
+		Aliases: []string{"del", "rm"},
+		Short:   "Delete a pool and all of its clusters",
+		Long:    `Delete a pool and all of its clusters`,
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			name := args[0]
+
+			pool, err := client.PoolGet(name)
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+			for _, cluster := range pool.Clusters {
+				if cluster.State == k3d.PoolClusterStateLeased && !force {
+					l.Log().Fatalf("Cluster '%s' of pool '%s' is still leased by '%s' (use --force to delete it anyway)", cluster.Name, name, cluster.Holder)
+				}
+			}
+
+			// also catch clusters, which are not (or no longer) tracked in the pool state
+			clusters, err := client.ClusterList(cmd.Context(), runtimes.SelectedRuntime)
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+			for _, cluster := range clusters {
+				if len(cluster.Nodes) == 0 || cluster.Nodes[0].RuntimeLabels[k3d.LabelPool] != name {
+					continue
+				}
+				l.Log().Infof("Deleting cluster '%s'...", cluster.Name)
+				if err := client.ClusterDelete(cmd.Context(), runtimes.SelectedRuntime, cluster, k3d.ClusterDeleteOpts{SkipRegistryCheck: true}); err != nil {
+					l.Log().Fatalf("Failed to delete cluster '%s': %v", cluster.Name, err)
+				}
+			}
+
+			if err := client.PoolRemove(cmd.Context(), name); err != nil {
+				l.Log().Fatalln(err)
+			}
+			l.Log().Infof("Deleted pool '%s'", name)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Delete the pool even if some of its clusters are still leased")
+
+	// done
+	return cmd
+}
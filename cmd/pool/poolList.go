@@ -0,0 +1,116 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package pool
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/liggitt/tabwriter"
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+type poolListFlags struct {
+	noHeader bool
+	output   string
+}
+
+// NewCmdPoolList returns a new cobra command
+func NewCmdPoolList() *cobra.Command {
+
+	flags := poolListFlags{}
+
+	// create new command
+	cmd := &cobra.Command{
+		Use:     "list [NAME [NAME...]]",
+		Aliases: []string{"ls", "get"},
+		Short:   "List pools and the leases of their clusters",
+		Long:    `List pools and the leases of their clusters`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var pools []*k3d.Pool
+			if len(args) == 0 {
+				var err error
+				pools, err = client.PoolList()
+				if err != nil {
+					l.Log().Fatalln(err)
+				}
+			} else {
+				for _, name := range args {
+					pool, err := client.PoolGet(name)
+					if err != nil {
+						l.Log().Fatalf("Failed to get pool '%s': %v", name, err)
+					}
+					pools = append(pools, pool)
+				}
+			}
+			printPools(pools, flags)
+		},
+	}
+
+	cmd.Flags().BoolVar(&flags.noHeader, "no-headers", false, "Disable headers")
+	cmd.Flags().StringVarP(&flags.output, "output", "o", "", "Output format. One of: json|yaml")
+
+	// done
+	return cmd
+}
+
+func printPools(pools []*k3d.Pool, flags poolListFlags) {
+	switch strings.ToLower(flags.output) {
+	case "json":
+		b, err := json.Marshal(pools)
+		if err != nil {
+			l.Log().Fatalln(err)
+		}
+		fmt.Println(string(b))
+	case "yaml":
+		for _, pool := range pools {
+			pool.Config = "" // not interesting here, as it's the cluster config
+		}
+		b, err := yaml.Marshal(pools)
+		if err != nil {
+			l.Log().Fatalln(err)
+		}
+		fmt.Println(string(b))
+	default:
+		tabwriter := tabwriter.NewWriter(os.Stdout, 6, 4, 3, ' ', tabwriter.RememberWidths)
+		defer tabwriter.Flush()
+		if !flags.noHeader {
+			fmt.Fprintf(tabwriter, "%s\n", strings.Join([]string{"POOL", "CLUSTER", "STATE", "HOLDER", "LEASED"}, "\t"))
+		}
+		for _, pool := range pools {
+			for _, cluster := range pool.Clusters {
+				leased := ""
+				if cluster.AcquiredAt != nil {
+					leased = time.Since(*cluster.AcquiredAt).Round(time.Second).String()
+				}
+				fmt.Fprintf(tabwriter, "%s\t%s\t%s\t%s\t%s\n", pool.Name, cluster.Name, cluster.State, cluster.Holder, leased)
+			}
+		}
+	}
+}
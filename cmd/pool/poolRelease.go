@@ -0,0 +1,69 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package pool
+
+import (
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdPoolRelease returns a new cobra command
+func NewCmdPoolRelease() *cobra.Command {
+
+	var reuse bool
+
+	// create new command
+	cmd := &cobra.Command{
+		Use:   "release NAME CLUSTER",
+		Short: "Hand a leased cluster back to its pool",
+		Long: `Hand a leased cluster back to its pool.
+By default, the cluster is deleted and re-created, so that the next job gets a fresh one (this blocks until the new cluster is up).
+Use --reuse to skip this, if the cluster is still in a good state.`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			name, clusterName := args[0], args[1]
+
+			if err := client.PoolRelease(cmd.Context(), name, clusterName, reuse); err != nil {
+				l.Log().Fatalln(err)
+			}
+			if reuse {
+				l.Log().Infof("Released cluster '%s' back to pool '%s'", clusterName, name)
+				return
+			}
+
+			pool, err := client.PoolGet(name)
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+			if err := poolRecreateCluster(cmd.Context(), pool, clusterName); err != nil {
+				l.Log().Fatalf("Failed to re-create cluster '%s' (removed it from pool '%s'): %v", clusterName, name, err)
+			}
+			l.Log().Infof("Re-created cluster '%s' and released it back to pool '%s'", clusterName, name)
+		},
+	}
+
+	cmd.Flags().BoolVar(&reuse, "reuse", false, "Hand the cluster back as it is instead of re-creating it")
+
+	// done
+	return cmd
+}
@@ -0,0 +1,129 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package prune
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/go-units"
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+type pruneFlags struct {
+	images  bool
+	orphans bool
+	dryRun  bool
+}
+
+// NewCmdPrune returns a new cobra command
+func NewCmdPrune() *cobra.Command {
+
+	flags := pruneFlags{}
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove unused k3d resources.",
+		Long: `Remove unused k3d resources.
+Currently supported:
+	- k3s images (rancher/k3s) which are not used by any existing cluster (--images)
+	- containers, volumes, networks and files in the k3d config directory left behind by clusters
+	  that don't exist (completely) anymore, e.g. after a failed creation (--orphans)`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if !flags.images && !flags.orphans {
+				l.Log().Infoln("Nothing to prune: choose what should be removed (e.g. --images or --orphans)")
+				if err := cmd.Help(); err != nil {
+					l.Log().Fatalln(err)
+				}
+				return
+			}
+
+			// orphans first: their removal may leave further images unused
+			if flags.orphans {
+				pruneOrphans(cmd, flags.dryRun)
+			}
+			if flags.images {
+				pruneImages(cmd, flags.dryRun)
+			}
+		},
+	}
+
+	// add flags
+	cmd.Flags().BoolVar(&flags.images, "images", false, "Remove rancher/k3s images that are not used by any existing cluster")
+	cmd.Flags().BoolVar(&flags.orphans, "orphans", false, "Remove resources left behind by clusters that don't exist (completely) anymore")
+	cmd.Flags().BoolVar(&flags.dryRun, "dry-run", false, "Only list what would be removed")
+
+	// done
+	return cmd
+}
+
+func pruneImages(cmd *cobra.Command, dryRun bool) {
+	pruned, err := client.ImagePruneUnused(cmd.Context(), runtimes.SelectedRuntime, k3d.ImagePruneOpts{DryRun: dryRun})
+	if err != nil {
+		l.Log().Fatalln(err)
+	}
+
+	if len(pruned) == 0 {
+		l.Log().Infoln("No unused k3s images found")
+		return
+	}
+
+	var reclaimed int64
+	for _, image := range pruned {
+		fmt.Printf("%s\t%s\n", strings.Join(image.Tags, ", "), units.HumanSize(float64(image.Size)))
+		reclaimed += image.Size
+	}
+
+	if dryRun {
+		l.Log().Infof("Would remove %d unused k3s image(s), reclaiming up to %s", len(pruned), units.HumanSize(float64(reclaimed)))
+	} else {
+		l.Log().Infof("Removed %d unused k3s image(s), reclaiming up to %s", len(pruned), units.HumanSize(float64(reclaimed)))
+	}
+}
+
+func pruneOrphans(cmd *cobra.Command, dryRun bool) {
+	pruned, err := client.OrphansPrune(cmd.Context(), runtimes.SelectedRuntime, k3d.OrphanPruneOpts{DryRun: dryRun})
+	if err != nil {
+		l.Log().Fatalln(err)
+	}
+
+	if len(pruned) == 0 {
+		l.Log().Infoln("No orphaned k3d resources found")
+		return
+	}
+
+	for _, orphan := range pruned {
+		fmt.Printf("%s\t%s\t%s\n", orphan.Kind, orphan.Name, orphan.Reason)
+	}
+
+	if dryRun {
+		l.Log().Infof("Would remove %d orphaned k3d resource(s)", len(pruned))
+	} else {
+		l.Log().Infof("Removed %d orphaned k3d resource(s)", len(pruned))
+	}
+}
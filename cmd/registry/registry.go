@@ -49,6 +49,8 @@ func NewCmdRegistry() *cobra.Command {
 	cmd.AddCommand(NewCmdRegistryStop())
 	cmd.AddCommand(NewCmdRegistryDelete())
 	cmd.AddCommand(NewCmdRegistryList())
+	cmd.AddCommand(NewCmdRegistryPush())
+	cmd.AddCommand(NewCmdRegistryRewrite())
 
 	// add flags
 
@@ -0,0 +1,73 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package registry
+
+import (
+	"fmt"
+
+	"github.com/rancher/k3d/v5/cmd/util"
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdRegistryPush returns a new cobra command
+func NewCmdRegistryPush() *cobra.Command {
+	var registryName string
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:   "push IMAGE [IMAGE...]",
+		Short: "Push local image(s) to a k3d-managed registry",
+		Long: `Push local image(s) to a k3d-managed registry.
+
+The images are tagged for the registry's host port and pushed to it, so there's no need to figure out the right address
+or to configure the registry as insecure registry in docker. Afterwards, the reference to use in the cluster is printed.`,
+		Example: `  k3d registry push myapp:dev
+  k3d registry push --registry registry.localhost myapp:dev ghcr.io/org/worker:v1`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			reg, err := client.RegistryLookup(cmd.Context(), runtimes.SelectedRuntime, registryName)
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+			for _, image := range args {
+				ref, err := client.RegistryPushImage(cmd.Context(), runtimes.SelectedRuntime, reg, image)
+				if err != nil {
+					l.Log().Fatalln(err)
+				}
+				l.Log().Infof("Pushed image '%s' to registry '%s', use it in connected clusters as:", image, reg.Host)
+				fmt.Println(ref)
+			}
+		},
+	}
+
+	// add flags
+	cmd.Flags().StringVarP(&registryName, "registry", "r", "", "Name of the registry to push to (default: the only existing registry)")
+	if err := cmd.RegisterFlagCompletionFunc("registry", util.ValidArgsAvailableRegistries); err != nil {
+		l.Log().Fatalln("Failed to register flag completion for '--registry'", err)
+	}
+
+	// done
+	return cmd
+}
@@ -0,0 +1,131 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package registry
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/rancher/k3d/v5/cmd/util"
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+type registryRewriteFlags struct {
+	registry string
+	inPlace  bool
+}
+
+// NewCmdRegistryRewrite returns a new cobra command
+func NewCmdRegistryRewrite() *cobra.Command {
+	flags := registryRewriteFlags{}
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:   "rewrite FILE [FILE...]",
+		Short: "Rewrite image references to k3d-managed registries in manifests, so they work inside the cluster",
+		Long: `Rewrite image references to k3d-managed registries in manifests, so they work inside the cluster.
+
+Images pushed to a registry via 'localhost:PORT' can't be pulled using that address from inside the cluster, where 'localhost' is the node itself.
+This replaces such addresses (localhost, 127.0.0.1, 0.0.0.0 or the registry's host IP with its host port) in all 'image:' fields
+by the address the cluster nodes pull from (e.g. 'k3d-registry.localhost:5000'). Everything else in the files is kept as is.
+Use '-' as FILE to read from stdin.`,
+		Example: `  k3d registry rewrite deployment.yaml | kubectl apply -f -
+  k3d registry rewrite --in-place manifests/*.yaml`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var registries []*k3d.Registry
+			if flags.registry != "" {
+				reg, err := client.RegistryLookup(cmd.Context(), runtimes.SelectedRuntime, flags.registry)
+				if err != nil {
+					l.Log().Fatalln(err)
+				}
+				registries = append(registries, reg)
+			} else {
+				var err error
+				registries, err = client.RegistryList(cmd.Context(), runtimes.SelectedRuntime)
+				if err != nil {
+					l.Log().Fatalln(err)
+				}
+				if len(registries) == 0 {
+					l.Log().Fatalln("no registries found: create one using 'k3d registry create'")
+				}
+			}
+
+			for _, file := range args {
+				if err := rewriteManifestFile(file, registries, flags.inPlace); err != nil {
+					l.Log().Fatalln(err)
+				}
+			}
+		},
+	}
+
+	// add flags
+	cmd.Flags().StringVarP(&flags.registry, "registry", "r", "", "Only rewrite references to this registry (default: all k3d-managed registries)")
+	if err := cmd.RegisterFlagCompletionFunc("registry", util.ValidArgsAvailableRegistries); err != nil {
+		l.Log().Fatalln("Failed to register flag completion for '--registry'", err)
+	}
+	cmd.Flags().BoolVarP(&flags.inPlace, "in-place", "i", false, "Write the rewritten manifests back to the files instead of printing them")
+
+	// done
+	return cmd
+}
+
+// rewriteManifestFile rewrites the image references in a manifest file (or stdin for '-') and prints the result or writes it back to the file
+func rewriteManifestFile(file string, registries []*k3d.Registry, inPlace bool) error {
+	var content []byte
+	var err error
+	if file == "-" {
+		if inPlace {
+			return fmt.Errorf("cannot rewrite stdin in place")
+		}
+		content, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		content, err = ioutil.ReadFile(file)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read manifests from '%s': %w", file, err)
+	}
+
+	rewritten, count := client.RegistryRewriteImages(content, registries)
+	l.Log().Infof("Rewrote %d image reference(s) in '%s'", count, file)
+
+	if !inPlace {
+		_, err = os.Stdout.Write(rewritten)
+		return err
+	}
+	if count == 0 {
+		return nil
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(file, rewritten, info.Mode()); err != nil {
+		return fmt.Errorf("failed to write rewritten manifests to '%s': %w", file, err)
+	}
+	return nil
+}
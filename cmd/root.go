@@ -27,21 +27,48 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v2"
 
+	"github.com/rancher/k3d/v5/cmd/audit"
+	"github.com/rancher/k3d/v5/cmd/bench"
+	"github.com/rancher/k3d/v5/cmd/certs"
+	"github.com/rancher/k3d/v5/cmd/checkpoint"
 	"github.com/rancher/k3d/v5/cmd/cluster"
+	"github.com/rancher/k3d/v5/cmd/compose"
 	cfg "github.com/rancher/k3d/v5/cmd/config"
 	"github.com/rancher/k3d/v5/cmd/debug"
+	"github.com/rancher/k3d/v5/cmd/dns"
+	"github.com/rancher/k3d/v5/cmd/doctor"
+	"github.com/rancher/k3d/v5/cmd/du"
+	"github.com/rancher/k3d/v5/cmd/env"
+	"github.com/rancher/k3d/v5/cmd/gc"
 	"github.com/rancher/k3d/v5/cmd/image"
+	"github.com/rancher/k3d/v5/cmd/inventory"
+	"github.com/rancher/k3d/v5/cmd/k8sevents"
 	"github.com/rancher/k3d/v5/cmd/kubeconfig"
+	"github.com/rancher/k3d/v5/cmd/kubectl"
 	"github.com/rancher/k3d/v5/cmd/node"
+	"github.com/rancher/k3d/v5/cmd/pool"
+	"github.com/rancher/k3d/v5/cmd/prune"
 	"github.com/rancher/k3d/v5/cmd/registry"
+	"github.com/rancher/k3d/v5/cmd/run"
+	"github.com/rancher/k3d/v5/cmd/serve"
+	k3dsync "github.com/rancher/k3d/v5/cmd/sync"
+	"github.com/rancher/k3d/v5/cmd/template"
+	"github.com/rancher/k3d/v5/cmd/top"
 	cliutil "github.com/rancher/k3d/v5/cmd/util"
+	"github.com/rancher/k3d/v5/cmd/verify"
+	k3dversion "github.com/rancher/k3d/v5/cmd/version"
+	"github.com/rancher/k3d/v5/pkg/client"
 	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/metrics"
 	"github.com/rancher/k3d/v5/pkg/runtimes"
+	"github.com/rancher/k3d/v5/pkg/runtimes/docker"
 	"github.com/rancher/k3d/v5/version"
 	"github.com/sirupsen/logrus"
 	"github.com/sirupsen/logrus/hooks/writer"
@@ -51,8 +78,14 @@ import (
 type RootFlags struct {
 	debugLogging       bool
 	traceLogging       bool
+	quietLogging       bool
 	timestampedLogging bool
+	logFormat          string
+	strict             bool
 	version            bool
+	metricsListenAddr  string
+	metricsTextfile    string
+	dockerClient       docker.ClientOptions
 }
 
 var flags = RootFlags{}
@@ -67,6 +100,17 @@ func NewCmdK3d() *cobra.Command {
 k3d is a wrapper CLI that helps you to easily create k3s clusters inside docker.
 Nodes of a k3d cluster are docker containers running a k3s image.
 All Nodes of a k3d cluster are part of the same docker network.`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// the global flags got their env vars in initFlagEnv already, this is for the flags of the command
+			if err := cliutil.ApplyFlagEnv(cmd.Flags()); err != nil {
+				return err
+			}
+			cliutil.ApplyFlagDeprecations(cmd.Flags())
+			if client.WaitPollInterval <= 0 {
+				return fmt.Errorf("--wait-poll-interval must be positive, got '%s'", client.WaitPollInterval)
+			}
+			return cliutil.CheckReadOnly(cmd)
+		},
 		Run: func(cmd *cobra.Command, args []string) {
 			if flags.version {
 				printVersion()
@@ -80,7 +124,27 @@ All Nodes of a k3d cluster are part of the same docker network.`,
 
 	rootCmd.PersistentFlags().BoolVar(&flags.debugLogging, "verbose", false, "Enable verbose output (debug logging)")
 	rootCmd.PersistentFlags().BoolVar(&flags.traceLogging, "trace", false, "Enable super verbose output (trace logging)")
+	rootCmd.PersistentFlags().BoolVarP(&flags.quietLogging, "quiet", "q", false, "Only output warnings and errors (overridden by --verbose and --trace)")
 	rootCmd.PersistentFlags().BoolVar(&flags.timestampedLogging, "timestamps", false, "Enable Log timestamps")
+	rootCmd.PersistentFlags().StringVar(&flags.logFormat, "log-format", "", "Format of the log output, one of 'text' or 'json' (one object per line, e.g. for log collectors; always with timestamps) (default: $LOG_FORMAT or 'text')")
+	rootCmd.PersistentFlags().BoolVar(&cliutil.CIMode, "ci", false, "Optimize the output for CI jobs: plain log lines with timestamps and without colors, masked cluster token (also via '::add-mask::' in GitHub Actions) and machine-parsable results like 'KUBECONFIG=PATH' on stdout")
+	rootCmd.PersistentFlags().StringVar(&cliutil.Tenant, "tenant", os.Getenv("K3D_TENANT"), "Work in the tenant's namespace on a shared docker host: new clusters are prefixed with 'TENANT-' and 'cluster list' only shows the tenant's clusters (Format: `TENANT`, default: $K3D_TENANT; without a value, the invoking user is the tenant)")
+	rootCmd.PersistentFlags().Lookup("tenant").NoOptDefVal = cliutil.TenantCurrentUser
+	rootCmd.PersistentFlags().BoolVar(&cliutil.ReadOnly, "read-only", false, "Reject all commands that could modify clusters, nodes, registries or images, e.g. for shared dashboards built on k3d (default: $K3D_READONLY)")
+	cliutil.SetFlagEnvVar(rootCmd.PersistentFlags(), "read-only", "K3D_READONLY") // established before all flags got their env var
+	strictDefault, _ := strconv.ParseBool(os.Getenv("K3D_STRICT"))
+	rootCmd.PersistentFlags().BoolVar(&flags.strict, "strict", strictDefault, "Exit with a non-zero exit code if any warnings were logged, e.g. so CI refuses clusters that came up with degraded configuration (all warnings are listed again at the end; default: $K3D_STRICT)")
+	offlineDefault, _ := strconv.ParseBool(os.Getenv("K3D_OFFLINE"))
+	rootCmd.PersistentFlags().BoolVar(&client.Offline, "offline", offlineDefault, "Forbid any network access, e.g. on air-gapped machines or in hermetic CI: images are never pulled (missing ones make k3d fail right away), the latest k3s version isn't looked up and the docker-machine IP isn't queried (default: $K3D_OFFLINE)")
+	rootCmd.PersistentFlags().DurationVar(&client.WaitPollInterval, "wait-poll-interval", client.WaitPollInterval, "Interval in which k3d checks again while waiting for something to become ready (resources from '--wait-for', servers with '--defer-workers', restarting nodes), e.g. raise it to reduce the load on slow or constrained container runtimes")
+	rootCmd.PersistentFlags().StringVar(&flags.dockerClient.APIVersion, "docker-api-version", "", "Pin the docker API version instead of negotiating it with the daemon, e.g. for old daemons: 'cluster create' then only warns about daemons not supporting docker API "+docker.MinimumAPIVersion+" instead of failing (Format: `VERSION`, default: $DOCKER_API_VERSION)")
+	rootCmd.PersistentFlags().StringVar(&flags.dockerClient.TLSCACert, "docker-tls-ca", "", "Verify the docker daemon with this CA certificate instead of the one of the docker context or $DOCKER_CERT_PATH (Format: `PATH`)")
+	rootCmd.PersistentFlags().StringVar(&flags.dockerClient.TLSCert, "docker-tls-cert", "", "Authenticate to the docker daemon with this client certificate, requires --docker-tls-key (Format: `PATH`)")
+	rootCmd.PersistentFlags().StringVar(&flags.dockerClient.TLSKey, "docker-tls-key", "", "Key of the client certificate given via --docker-tls-cert (Format: `PATH`)")
+	rootCmd.PersistentFlags().DurationVar(&flags.dockerClient.Timeout, "docker-timeout", 0, "Maximum time to wait for the docker daemon to answer a request, e.g. to fail fast with an unresponsive remote daemon (streamed responses like logs or image pulls aren't limited; should be longer than the 10s it takes to stop a node; default: no timeout)")
+	rootCmd.PersistentFlags().IntVar(&flags.dockerClient.MaxConcurrency, "max-concurrency", 0, "Maximum number of container operations (create, start, stop, delete) sent to the docker daemon at the same time, e.g. lower it if parallel operations overwhelm the VM of Docker Desktop (default: number of CPUs)")
+	rootCmd.PersistentFlags().StringVar(&flags.metricsListenAddr, "metrics-listen", "", "Expose prometheus metrics on /metrics at the given address while k3d is running, i.e. only useful for long-running commands like 'k3d serve' (Format: `[HOST]:PORT`)")
+	rootCmd.PersistentFlags().StringVar(&flags.metricsTextfile, "metrics-textfile", "", "Write prometheus metrics of the operations of this k3d invocation to a file when it exits, e.g. for the node_exporter textfile collector (Format: `FILE`)")
 
 	// add local flags
 	rootCmd.Flags().BoolVar(&flags.version, "version", false, "Show k3d and default k3s version")
@@ -94,15 +158,50 @@ All Nodes of a k3d cluster are part of the same docker network.`,
 	rootCmd.AddCommand(cfg.NewCmdConfig())
 	rootCmd.AddCommand(registry.NewCmdRegistry())
 	rootCmd.AddCommand(debug.NewCmdDebug())
-
-	rootCmd.AddCommand(&cobra.Command{
+	rootCmd.AddCommand(bench.NewCmdBench())
+	rootCmd.AddCommand(prune.NewCmdPrune())
+	rootCmd.AddCommand(du.NewCmdDu())
+	rootCmd.AddCommand(checkpoint.NewCmdCheckpoint())
+	rootCmd.AddCommand(checkpoint.NewCmdRollback())
+	rootCmd.AddCommand(checkpoint.NewCmdFreeze())
+	rootCmd.AddCommand(checkpoint.NewCmdThaw())
+	rootCmd.AddCommand(cluster.NewCmdPause())
+	rootCmd.AddCommand(cluster.NewCmdUnpause())
+	rootCmd.AddCommand(cluster.NewCmdStatus())
+	rootCmd.AddCommand(cluster.NewCmdReconcile())
+	rootCmd.AddCommand(cluster.NewCmdEnsure())
+	rootCmd.AddCommand(cluster.NewCmdRecord())
+	rootCmd.AddCommand(cluster.NewCmdReplay())
+	rootCmd.AddCommand(cluster.NewCmdBackup())
+	rootCmd.AddCommand(cluster.NewCmdRestore())
+	rootCmd.AddCommand(kubectl.NewCmdKubectl())
+	rootCmd.AddCommand(run.NewCmdRun())
+	rootCmd.AddCommand(verify.NewCmdVerify())
+	rootCmd.AddCommand(doctor.NewCmdDoctor())
+	rootCmd.AddCommand(certs.NewCmdCerts())
+	rootCmd.AddCommand(audit.NewCmdAudit())
+	rootCmd.AddCommand(pool.NewCmdPool())
+	rootCmd.AddCommand(serve.NewCmdServe())
+	rootCmd.AddCommand(compose.NewCmdCompose())
+	rootCmd.AddCommand(k3dsync.NewCmdSync())
+	rootCmd.AddCommand(dns.NewCmdDNS())
+	rootCmd.AddCommand(env.NewCmdEnv())
+	rootCmd.AddCommand(template.NewCmdTemplate())
+	rootCmd.AddCommand(top.NewCmdTop())
+	rootCmd.AddCommand(k8sevents.NewCmdK8sEvents())
+	rootCmd.AddCommand(inventory.NewCmdInventory())
+	rootCmd.AddCommand(gc.NewCmdGC())
+
+	versionCmd := &cobra.Command{
 		Use:   "version",
 		Short: "Show k3d and default k3s version",
 		Long:  "Show k3d and default k3s version",
 		Run: func(cmd *cobra.Command, args []string) {
 			printVersion()
 		},
-	})
+	}
+	versionCmd.AddCommand(k3dversion.NewCmdVersionList())
+	rootCmd.AddCommand(versionCmd)
 
 	rootCmd.AddCommand(&cobra.Command{
 		Use:   "runtime-info",
@@ -122,7 +221,8 @@ All Nodes of a k3d cluster are part of the same docker network.`,
 	})
 
 	// Init
-	cobra.OnInitialize(initLogging, initRuntime)
+	// the env vars of the global flags have to be applied before the initializers use them
+	cobra.OnInitialize(func() { initFlagEnv(rootCmd) }, initLogging, initRuntime, initMetrics, initTenant)
 
 	return rootCmd
 }
@@ -133,6 +233,12 @@ func Execute() {
 	cmd := NewCmdK3d()
 	if len(os.Args) > 1 {
 		parts := os.Args[1:]
+		// Translate the verb-noun layout of older k3d versions (e.g. 'k3d create cluster') to the current noun-verb layout
+		if translated, legacy := cliutil.TranslateLegacyArgs(cmd, parts); legacy != "" {
+			l.Log().Warnf("'k3d %s' is deprecated, use 'k3d %s' instead", legacy, strings.Join(translated[:2], " "))
+			parts = translated
+			cmd.SetArgs(parts)
+		}
 		// Check if it's a built-in command, else try to execute it as a plugin
 		if _, _, err := cmd.Find(parts); err != nil {
 			pluginFound, err := cliutil.HandlePlugin(context.Background(), parts)
@@ -147,28 +253,28 @@ func Execute() {
 	if err := cmd.Execute(); err != nil {
 		l.Log().Fatalln(err)
 	}
+	writeMetricsTextfile()
+	checkStrict()
+}
+
+// checkStrict fails in strict mode, if any warnings were logged
+func checkStrict() {
+	if !flags.strict {
+		return
+	}
+	warnings := l.Warnings.List()
+	if len(warnings) == 0 {
+		return
+	}
+	for _, warning := range warnings {
+		l.Log().Errorf("Warning: %s", warning.Message)
+	}
+	l.Log().Fatalf("Strict mode: %d warning(s) were logged", len(warnings))
 }
 
 // initLogging initializes the logger
 func initLogging() {
-	if flags.traceLogging {
-		l.Log().SetLevel(logrus.TraceLevel)
-	} else if flags.debugLogging {
-		l.Log().SetLevel(logrus.DebugLevel)
-	} else {
-		switch logLevel := strings.ToUpper(os.Getenv("LOG_LEVEL")); logLevel {
-		case "TRACE":
-			l.Log().SetLevel(logrus.TraceLevel)
-		case "DEBUG":
-			l.Log().SetLevel(logrus.DebugLevel)
-		case "WARN":
-			l.Log().SetLevel(logrus.WarnLevel)
-		case "ERROR":
-			l.Log().SetLevel(logrus.ErrorLevel)
-		default:
-			l.Log().SetLevel(logrus.InfoLevel)
-		}
-	}
+	l.Log().SetLevel(flags.logLevel())
 	l.Log().SetOutput(ioutil.Discard)
 	l.Log().AddHook(&writer.Hook{
 		Writer: os.Stderr,
@@ -188,29 +294,138 @@ func initLogging() {
 		},
 	})
 
-	formatter := &logrus.TextFormatter{
-		ForceColors: true,
+	formatter, err := flags.logFormatter()
+	if err != nil {
+		l.Log().SetFormatter(&logrus.TextFormatter{})
+		l.Log().Fatalln(err)
 	}
 
-	if flags.timestampedLogging || os.Getenv("LOG_TIMESTAMPS") != "" {
-		formatter.FullTimestamp = true
+	l.Log().SetFormatter(&l.MaskingFormatter{Formatter: formatter})
+
+}
+
+// logLevel returns the log level selected by --trace, --verbose or --quiet (in this order), falling back to $LOG_LEVEL
+func (f RootFlags) logLevel() logrus.Level {
+	level := logrus.InfoLevel
+	if f.traceLogging {
+		level = logrus.TraceLevel
+	} else if f.debugLogging {
+		level = logrus.DebugLevel
+	} else if f.quietLogging {
+		level = logrus.WarnLevel
+	} else {
+		switch strings.ToUpper(os.Getenv("LOG_LEVEL")) {
+		case "TRACE":
+			level = logrus.TraceLevel
+		case "DEBUG":
+			level = logrus.DebugLevel
+		case "WARN":
+			level = logrus.WarnLevel
+		case "ERROR":
+			level = logrus.ErrorLevel
+		}
 	}
+	// warnings are only recorded if they're logged
+	if f.strict && level < logrus.WarnLevel {
+		level = logrus.WarnLevel
+	}
+	return level
+}
+
+// logFormatter returns the formatter selected by --log-format (or $LOG_FORMAT), failing for unknown formats
+func (f RootFlags) logFormatter() (logrus.Formatter, error) {
+	logFormat := f.logFormat
+	if logFormat == "" {
+		logFormat = os.Getenv("LOG_FORMAT")
+	}
+
+	switch strings.ToLower(logFormat) {
+	case "", "text":
+		textFormatter := &logrus.TextFormatter{
+			ForceColors: true,
+		}
+
+		if f.timestampedLogging || os.Getenv("LOG_TIMESTAMPS") != "" {
+			textFormatter.FullTimestamp = true
+		}
 
-	l.Log().SetFormatter(formatter)
+		if cliutil.CIMode {
+			textFormatter.ForceColors = false
+			textFormatter.DisableColors = true
+			textFormatter.FullTimestamp = true
+		}
+		return textFormatter, nil
+	case "json":
+		return &logrus.JSONFormatter{}, nil
+	default:
+		return nil, fmt.Errorf("Unknown log format '%s' (must be one of 'text' or 'json')", logFormat)
+	}
+}
 
+// initFlagEnv sets the global flags that were not given on the command line from their environment variables (see cliutil.ApplyFlagEnv)
+func initFlagEnv(rootCmd *cobra.Command) {
+	if err := cliutil.ApplyFlagEnv(rootCmd.PersistentFlags()); err != nil {
+		l.Log().Fatalln(err)
+	}
 }
 
 func initRuntime() {
+	flags.dockerClient.Offline = client.Offline
+	if err := docker.SetClientOptions(flags.dockerClient); err != nil {
+		l.Log().Fatalf("Invalid docker connection settings: %v", err)
+	}
 	runtime, err := runtimes.GetRuntime("docker")
 	if err != nil {
 		l.Log().Fatalln(err)
 	}
+	if cliutil.ReadOnly {
+		runtime = runtimes.ReadOnly(runtime)
+	}
 	runtimes.SelectedRuntime = runtime
 	if rtinfo, err := runtime.Info(); err == nil {
 		l.Log().Debugf("Runtime Info:\n%+v", rtinfo)
 	}
 }
 
+func initTenant() {
+	if cliutil.Tenant == cliutil.TenantCurrentUser {
+		cliutil.Tenant = cliutil.CurrentUser()
+	}
+	if cliutil.Tenant != "" {
+		if err := client.ValidateHostname(cliutil.Tenant); err != nil {
+			l.Log().Fatalf("Invalid tenant: %v", err)
+		}
+		l.Log().Debugf("Working in the namespace of tenant '%s'", cliutil.Tenant)
+	}
+}
+
+func initMetrics() {
+	if flags.metricsTextfile != "" {
+		// failed commands exit via log.Fatal, but their metrics are just as interesting
+		logrus.RegisterExitHandler(writeMetricsTextfile)
+	}
+	if flags.metricsListenAddr == "" {
+		return
+	}
+	if err := metrics.Serve(context.Background(), flags.metricsListenAddr); err != nil {
+		l.Log().Fatalf("Failed to start metrics listener: %v", err)
+	}
+}
+
+var writeMetricsTextfileOnce sync.Once
+
+// writeMetricsTextfile writes the metrics to the file given via --metrics-textfile (only once, no matter how k3d exits)
+func writeMetricsTextfile() {
+	if flags.metricsTextfile == "" {
+		return
+	}
+	writeMetricsTextfileOnce.Do(func() {
+		if err := metrics.WriteTextfile(flags.metricsTextfile); err != nil {
+			l.Log().Errorf("Failed to write metrics textfile: %v", err)
+		}
+	})
+}
+
 func printVersion() {
 	fmt.Printf("k3d version %s\n", version.GetVersion())
 	fmt.Printf("k3s version %s (default)\n", version.K3sVersion)
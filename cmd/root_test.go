@@ -0,0 +1,96 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestRootFlagsLogLevel(t *testing.T) {
+	testCases := []struct {
+		name     string
+		flags    RootFlags
+		envLevel string
+		expected logrus.Level
+	}{
+		{name: "default", expected: logrus.InfoLevel},
+		{name: "quiet", flags: RootFlags{quietLogging: true}, expected: logrus.WarnLevel},
+		{name: "verbose overrides quiet", flags: RootFlags{debugLogging: true, quietLogging: true}, expected: logrus.DebugLevel},
+		{name: "trace overrides verbose", flags: RootFlags{traceLogging: true, debugLogging: true}, expected: logrus.TraceLevel},
+		{name: "quiet overrides env", flags: RootFlags{quietLogging: true}, envLevel: "debug", expected: logrus.WarnLevel},
+		{name: "env", envLevel: "error", expected: logrus.ErrorLevel},
+		{name: "strict logs warnings", flags: RootFlags{strict: true}, envLevel: "error", expected: logrus.WarnLevel},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("LOG_LEVEL", tc.envLevel)
+			if level := tc.flags.logLevel(); level != tc.expected {
+				t.Errorf("expected log level '%s', got '%s'", tc.expected, level)
+			}
+		})
+	}
+}
+
+func TestRootFlagsLogFormatter(t *testing.T) {
+	t.Setenv("LOG_FORMAT", "")
+	t.Setenv("LOG_TIMESTAMPS", "")
+
+	formatter, err := RootFlags{}.logFormatter()
+	if err != nil {
+		t.Fatalf("unexpected error for the default format: %v", err)
+	}
+	if _, ok := formatter.(*logrus.TextFormatter); !ok {
+		t.Errorf("expected a text formatter by default, got %T", formatter)
+	}
+
+	formatter, err = RootFlags{logFormat: "text", timestampedLogging: true}.logFormatter()
+	if err != nil {
+		t.Fatalf("unexpected error for format 'text': %v", err)
+	}
+	if textFormatter, ok := formatter.(*logrus.TextFormatter); !ok || !textFormatter.FullTimestamp {
+		t.Errorf("expected a text formatter with timestamps, got %+v", formatter)
+	}
+
+	formatter, err = RootFlags{logFormat: "JSON"}.logFormatter()
+	if err != nil {
+		t.Fatalf("unexpected error for format 'JSON': %v", err)
+	}
+	if _, ok := formatter.(*logrus.JSONFormatter); !ok {
+		t.Errorf("expected a JSON formatter, got %T", formatter)
+	}
+
+	t.Setenv("LOG_FORMAT", "json")
+	formatter, err = RootFlags{}.logFormatter()
+	if err != nil {
+		t.Fatalf("unexpected error for $LOG_FORMAT=json: %v", err)
+	}
+	if _, ok := formatter.(*logrus.JSONFormatter); !ok {
+		t.Errorf("expected $LOG_FORMAT=json to select a JSON formatter, got %T", formatter)
+	}
+
+	if _, err := (RootFlags{logFormat: "xml"}).logFormatter(); err == nil {
+		t.Errorf("expected an error for an unknown log format")
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package run
+
+import (
+	"os"
+	"time"
+
+	"github.com/rancher/k3d/v5/cmd/util"
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdRun returns a new cobra command
+func NewCmdRun() *cobra.Command {
+
+	opts := k3d.PodRunOpts{}
+	var clusterName string
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:   "run [--name CLUSTERNAME] [--image IMAGE] [-- COMMAND [ARGS...]]",
+		Short: "Run a one-off pod in a cluster and stream its logs",
+		Long: `Run a one-off pod in a cluster and stream its logs, e.g. for smoke-testing a cluster right after creating it in CI.
+k3d exits with the exit code of the pod's container. The pod is deleted afterwards, unless '--keep' is set.`,
+		Example: `  k3d run --name mycluster --image busybox -- sh -c "nslookup kubernetes.default"`,
+		Args:    cobra.ArbitraryArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			cluster, err := client.ClusterGet(cmd.Context(), runtimes.SelectedRuntime, &k3d.Cluster{Name: clusterName})
+			if err != nil {
+				l.Log().Fatalf("Failed to get cluster '%s': %v", clusterName, err)
+			}
+
+			opts.Command = args
+
+			exitCode, err := client.PodRun(cmd.Context(), runtimes.SelectedRuntime, cluster, opts, os.Stdout)
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+			if exitCode != 0 {
+				os.Exit(exitCode)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&clusterName, "name", "n", k3d.DefaultClusterName, "Name of the cluster to run the pod in")
+	if err := cmd.RegisterFlagCompletionFunc("name", util.ValidArgsAvailableClusters); err != nil {
+		l.Log().Fatalln("Failed to register flag completion for '--name'", err)
+	}
+	cmd.Flags().StringVar(&opts.Name, "pod-name", "", "Name of the pod (default: random name)")
+	cmd.Flags().StringVarP(&opts.Image, "image", "i", "busybox", "Image to run")
+	cmd.Flags().StringVar(&opts.Namespace, "namespace", "default", "Namespace to create the pod in")
+	cmd.Flags().DurationVar(&opts.Timeout, "timeout", 5*time.Minute, "Maximum time to wait for the pod to finish")
+	cmd.Flags().BoolVar(&opts.Keep, "keep", false, "Don't delete the pod after it finished")
+
+	return cmd
+}
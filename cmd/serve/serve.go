@@ -0,0 +1,109 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package serve
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"os/signal"
+	"syscall"
+
+	cliutil "github.com/rancher/k3d/v5/cmd/util"
+	"github.com/rancher/k3d/v5/pkg/api"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	"github.com/spf13/cobra"
+)
+
+// TokenEnvVar is the environment variable that can be used instead of --token
+const TokenEnvVar = "K3D_SERVE_TOKEN"
+
+type serveFlags struct {
+	listen string
+	token  string
+}
+
+// NewCmdServe returns a new cobra command
+func NewCmdServe() *cobra.Command {
+
+	flags := serveFlags{}
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:   "serve [--listen [HOST]:PORT]",
+		Short: "Run the k3d management API",
+		Long: `Run a long-running daemon exposing the k3d management API via HTTP, so that other programs (IDE plugins, GUIs, remote tooling) can manage clusters without shelling out to the CLI.
+Routes:
+	GET    /healthz
+	GET    /openapi.yaml                   (OpenAPI specification of the API)
+	GET    /version
+	GET    /v1/clusters
+	POST   /v1/clusters                    (body: k3d config file; hooks, host path volumes and other fields accessing host files are rejected)
+	GET    /v1/clusters/{name}
+	DELETE /v1/clusters/{name}
+	POST   /v1/clusters/{name}/start
+	POST   /v1/clusters/{name}/stop
+	GET    /v1/clusters/{name}/kubeconfig
+	GET    /metrics
+All routes except for /healthz and /openapi.yaml require the bearer token given via --token or $` + TokenEnvVar + ` (a random one is generated and printed, if none is set).
+Requests from web pages of other origins (Origin header) are rejected and config files have to be sent as application/json or application/yaml.
+Go programs can use the typed client of the package github.com/rancher/k3d/v5/pkg/api.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if flags.token == "" {
+				token, err := generateToken()
+				if err != nil {
+					l.Log().Fatalf("Failed to generate a token: %v", err)
+				}
+				flags.token = token
+				l.Log().Infof("No token set, generated one for this session (use --token or $%s for a stable one): %s", TokenEnvVar, flags.token)
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			server := api.NewServer(runtimes.SelectedRuntime, flags.token)
+			if err := server.Serve(ctx, flags.listen); err != nil {
+				l.Log().Fatalln(err)
+			}
+			l.Log().Infoln("API server stopped")
+		},
+	}
+
+	// add flags
+	cmd.Flags().StringVar(&flags.listen, "listen", "127.0.0.1:7080", "Address to serve the API on (Format: `[HOST]:PORT`)")
+	cmd.Flags().StringVar(&flags.token, "token", "", "Bearer token required to access the API (default: $"+TokenEnvVar+" or a random token printed on startup)")
+	cliutil.SetFlagEnvVar(cmd.Flags(), "token", TokenEnvVar) // $K3D_TOKEN would be ambiguous with the cluster token
+
+	// done
+	return cmd
+}
+
+// generateToken returns a random token for the API
+func generateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
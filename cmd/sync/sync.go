@@ -0,0 +1,82 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package sync
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/rancher/k3d/v5/cmd/util"
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdSync returns a new cobra command
+func NewCmdSync() *cobra.Command {
+
+	opts := k3d.SyncOpts{}
+	var clusterName string
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:   "sync SOURCE [NAMESPACE/]KIND/NAME:PATH",
+		Short: "Sync a local directory into the containers of pods",
+		Long: `Sync a local directory into the containers of all running pods of a deployment, statefulset, daemonset or a single pod and keep syncing changes (incl. deletions) while watching the directory.
+The files are streamed as a tar archive into 'kubectl exec' inside of a server node, so the containers need 'sh' and 'tar', but there's no need for any tool on your machine.
+Pods are looked up on every sync, so that restarted pods get the following changes as well (use '--once' to sync everything into new pods).`,
+		Example: `  k3d sync ./src deployment/myapp:/app
+  k3d sync ./static --name mycluster --container web --once staging/pod/web-0:/usr/share/nginx/html`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			target, err := client.ParseSyncTarget(args[1])
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+
+			cluster, err := client.ClusterGet(cmd.Context(), runtimes.SelectedRuntime, &k3d.Cluster{Name: clusterName})
+			if err != nil {
+				l.Log().Fatalf("Failed to get cluster '%s': %v", clusterName, err)
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			if err := client.Sync(ctx, runtimes.SelectedRuntime, cluster, args[0], target, opts); err != nil {
+				l.Log().Fatalln(err)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&clusterName, "name", "n", k3d.DefaultClusterName, "Name of the cluster the pods are running in")
+	if err := cmd.RegisterFlagCompletionFunc("name", util.ValidArgsAvailableClusters); err != nil {
+		l.Log().Fatalln("Failed to register flag completion for '--name'", err)
+	}
+	cmd.Flags().StringVarP(&opts.Container, "container", "c", "", "Container of the pods to sync into (default: the pod's default container)")
+	cmd.Flags().StringSliceVar(&opts.Exclude, "exclude", []string{".git"}, "Don't sync files or directories whose name matches one of these patterns (Format: `PATTERN[,PATTERN...]`)\n - Example: `k3d sync ./src deployment/myapp:/app --exclude .git,node_modules,'*.swp'`")
+	cmd.Flags().BoolVar(&opts.Once, "once", false, "Sync once and exit instead of watching for changes")
+
+	return cmd
+}
@@ -0,0 +1,69 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package template
+
+import (
+	"github.com/rancher/k3d/v5/pkg/config/templates"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+// templateDirs holds the directories given via --template-dir
+var templateDirs []string
+
+// NewCmdTemplate returns a new cobra command
+func NewCmdTemplate() *cobra.Command {
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:   "template",
+		Short: "Use templates for common cluster setups",
+		Long: `Use templates for common cluster setups, which expand to full k3d config files.
+Besides the builtin templates, k3d looks for '*.yaml' templates in the directories given via --template-dir, $K3D_TEMPLATE_PATH and $HOME/.k3d/templates (in that order, the first template with a given name wins).
+Templates are Go templates, which can use the values {{ .Name }} (cluster name), {{ .Image }} (k3s image) and {{ .WorkDir }} (current working directory).`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := cmd.Help(); err != nil {
+				l.Log().Errorln("Couldn't get help text")
+				l.Log().Fatalln(err)
+			}
+		},
+	}
+
+	cmd.PersistentFlags().StringArrayVar(&templateDirs, "template-dir", nil, "Look for templates in this directory (Format: `DIR`)")
+
+	// add subcommands
+	cmd.AddCommand(NewCmdTemplateList())
+	cmd.AddCommand(NewCmdTemplateUse())
+
+	// done
+	return cmd
+}
+
+// lookupDirs returns all user template directories
+func lookupDirs() []string {
+	configDir, err := util.GetConfigDirOrCreate()
+	if err != nil {
+		l.Log().Warnf("Not looking for templates in the k3d config directory: %v", err)
+	}
+	return templates.Dirs(templateDirs, configDir)
+}
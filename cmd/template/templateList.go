@@ -0,0 +1,68 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package template
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/liggitt/tabwriter"
+	"github.com/rancher/k3d/v5/pkg/config/templates"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdTemplateList returns a new cobra command
+func NewCmdTemplateList() *cobra.Command {
+
+	var noHeader bool
+
+	// create new command
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls", "get"},
+		Short:   "List the available templates",
+		Long:    `List the builtin templates and the ones found in the template directories`,
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			tpls, err := templates.List(lookupDirs())
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+
+			tabwriter := tabwriter.NewWriter(os.Stdout, 6, 4, 3, ' ', tabwriter.RememberWidths)
+			defer tabwriter.Flush()
+			if !noHeader {
+				fmt.Fprintf(tabwriter, "%s\n", strings.Join([]string{"NAME", "SOURCE", "DESCRIPTION"}, "\t"))
+			}
+			for _, tpl := range tpls {
+				fmt.Fprintf(tabwriter, "%s\t%s\t%s\n", tpl.Name, tpl.Source, tpl.Description)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&noHeader, "no-headers", false, "Disable headers")
+
+	// done
+	return cmd
+}
@@ -0,0 +1,114 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package template
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rancher/k3d/v5/pkg/config/templates"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/rancher/k3d/v5/version"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdTemplateUse returns a new cobra command
+func NewCmdTemplateUse() *cobra.Command {
+
+	var output, image string
+	var force bool
+
+	// create new command
+	cmd := &cobra.Command{
+		Use:   "use TEMPLATE [CLUSTERNAME]",
+		Short: "Expand a template to a k3d config file",
+		Long: `Expand a template to a k3d config file, which can be adjusted and passed to 'k3d cluster create --config'.
+The cluster name defaults to '` + k3d.DefaultClusterName + `'.`,
+		Example: `  k3d template use ha-3server mycluster
+  k3d cluster create --config k3d-ha-3server.yaml`,
+		Args: cobra.RangeArgs(1, 2),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) > 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			tpls, err := templates.List(lookupDirs())
+			if err != nil {
+				return nil, cobra.ShellCompDirectiveError
+			}
+			names := []string{}
+			for _, tpl := range tpls {
+				names = append(names, fmt.Sprintf("%s\t%s", tpl.Name, tpl.Description))
+			}
+			return names, cobra.ShellCompDirectiveNoFileComp
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			tpl, err := templates.Get(args[0], lookupDirs())
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+
+			values := templates.Values{
+				Name:  k3d.DefaultClusterName,
+				Image: image,
+			}
+			if len(args) > 1 {
+				values.Name = args[1]
+			}
+			if values.WorkDir, err = os.Getwd(); err != nil {
+				l.Log().Fatalf("Failed to get the working directory: %v", err)
+			}
+
+			content, err := tpl.Render(values)
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+
+			if output == "-" {
+				fmt.Print(string(content))
+				return
+			}
+			if output == "" {
+				output = fmt.Sprintf("%s-%s.yaml", k3d.DefaultObjectNamePrefix, tpl.Name)
+			}
+			if _, err := os.Stat(output); err == nil && !force {
+				l.Log().Fatalf("Output file '%s' exists and --force was not set", output)
+			} else if err != nil && !os.IsNotExist(err) {
+				l.Log().Fatalf("Failed to stat output file: %+v", err)
+			}
+			if err := os.WriteFile(output, content, 0644); err != nil {
+				l.Log().Fatalf("Failed to write output file: %v", err)
+			}
+			l.Log().Infof("Wrote config file '%s' from template '%s': create the cluster with 'k3d cluster create --config %s'", output, tpl.Name, output)
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Write the config file to this path or to stdout with '-' (Format: `FILE`, default: k3d-TEMPLATE.yaml)")
+	if err := cmd.MarkFlagFilename("output", "yaml", "yml"); err != nil {
+		l.Log().Fatalf("Failed to mark flag 'output' as filename flag: %v", err)
+	}
+	cmd.Flags().StringVarP(&image, "image", "i", fmt.Sprintf("%s:%s", k3d.DefaultK3sImageRepo, version.GetK3sVersion(false)), "K3s image used in the config file")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Force overwrite of the output file")
+
+	// done
+	return cmd
+}
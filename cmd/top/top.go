@@ -0,0 +1,142 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package top
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/liggitt/tabwriter"
+	"github.com/rancher/k3d/v5/cmd/util"
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+type topFlags struct {
+	name      string
+	namespace string
+	noInstall bool
+	timeout   time.Duration
+	noHeader  bool
+	output    string
+}
+
+// NewCmdTop returns a new cobra command
+func NewCmdTop() *cobra.Command {
+
+	flags := topFlags{}
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:   "top [--name CLUSTERNAME]",
+		Short: "Show CPU and memory usage of a cluster's nodes and pods",
+		Long: `Show the CPU and memory usage of a cluster's nodes and pods in one view, as reported by metrics-server.
+If the metrics API is not available (e.g. because the cluster was created with '--k3s-arg --disable=metrics-server@server:*'),
+k3d installs metrics-server into the cluster and waits for it to report the first metrics (disable with '--no-install').`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			cluster, err := client.ClusterGet(cmd.Context(), runtimes.SelectedRuntime, &k3d.Cluster{Name: flags.name})
+			if err != nil {
+				l.Log().Fatalf("Failed to get cluster '%s': %v", flags.name, err)
+			}
+
+			top, err := client.ClusterTop(cmd.Context(), runtimes.SelectedRuntime, cluster, k3d.ClusterTopOpts{
+				Namespace:            flags.namespace,
+				InstallMetricsServer: !flags.noInstall,
+				Timeout:              flags.timeout,
+			})
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+
+			printTop(top, flags)
+		},
+	}
+
+	// add flags
+	cmd.Flags().StringVarP(&flags.name, "name", "n", k3d.DefaultClusterName, "Name of the cluster")
+	if err := cmd.RegisterFlagCompletionFunc("name", util.ValidArgsAvailableClusters); err != nil {
+		l.Log().Fatalln("Failed to register flag completion for '--name'", err)
+	}
+	cmd.Flags().StringVar(&flags.namespace, "namespace", "", "Only show pods in this namespace (default: all namespaces)")
+	cmd.Flags().BoolVar(&flags.noInstall, "no-install", false, "Fail instead of installing metrics-server if the metrics API is not available")
+	cmd.Flags().DurationVar(&flags.timeout, "timeout", 2*time.Minute, "Maximum time to wait for the metrics API to become available")
+	cmd.Flags().BoolVar(&flags.noHeader, "no-headers", false, "Disable headers")
+	cmd.Flags().StringVarP(&flags.output, "output", "o", "", "Output format. One of: json|yaml")
+
+	// done
+	return cmd
+}
+
+func printTop(top *k3d.ClusterTop, flags topFlags) {
+	switch strings.ToLower(flags.output) {
+	case "json":
+		b, err := json.Marshal(top)
+		if err != nil {
+			l.Log().Fatalln(err)
+		}
+		fmt.Println(string(b))
+	case "yaml":
+		b, err := yaml.Marshal(top)
+		if err != nil {
+			l.Log().Fatalln(err)
+		}
+		fmt.Println(string(b))
+	default:
+		nodeWriter := tabwriter.NewWriter(os.Stdout, 6, 4, 3, ' ', tabwriter.RememberWidths)
+		if !flags.noHeader {
+			fmt.Fprintf(nodeWriter, "%s\n", strings.Join([]string{"NODE", "CPU(cores)", "CPU%", "MEMORY(bytes)", "MEMORY%"}, "\t"))
+		}
+		for _, node := range top.Nodes {
+			fmt.Fprintf(nodeWriter, "%s\t%s\t%d%%\t%s\t%d%%\n", node.Name, formatCPU(node.CPUMillis), node.CPUPercent, formatMemory(node.MemoryBytes), node.MemoryPercent)
+		}
+		nodeWriter.Flush()
+
+		fmt.Println()
+
+		podWriter := tabwriter.NewWriter(os.Stdout, 6, 4, 3, ' ', tabwriter.RememberWidths)
+		defer podWriter.Flush()
+		if !flags.noHeader {
+			fmt.Fprintf(podWriter, "%s\n", strings.Join([]string{"NAMESPACE", "POD", "CPU(cores)", "MEMORY(bytes)"}, "\t"))
+		}
+		for _, pod := range top.Pods {
+			fmt.Fprintf(podWriter, "%s\t%s\t%s\t%s\n", pod.Namespace, pod.Name, formatCPU(pod.CPUMillis), formatMemory(pod.MemoryBytes))
+		}
+	}
+}
+
+// formatCPU formats CPU usage like 'kubectl top' does, e.g. 250m
+func formatCPU(millis int64) string {
+	return fmt.Sprintf("%dm", millis)
+}
+
+// formatMemory formats memory usage like 'kubectl top' does, e.g. 128Mi
+func formatMemory(bytes int64) string {
+	return fmt.Sprintf("%dMi", bytes/(1024*1024))
+}
@@ -0,0 +1,79 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+)
+
+// CIMode is set by the global --ci flag: plain log output, masked secrets and machine-parsable results
+var CIMode bool
+
+// MaskSecret hides the secret in the log output of k3d and, when running in GitHub Actions, in the whole job log (only in CI mode)
+func MaskSecret(secret string) {
+	if !CIMode || secret == "" {
+		return
+	}
+	l.MaskSecret(secret)
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		fmt.Printf("::add-mask::%s\n", secret) // workflow command, not shown in the job log
+	}
+}
+
+// WriteCIOutputs prints the outputs as 'KEY=VALUE' lines to the writer and appends them to the given files ($GITHUB_ENV/$GITHUB_OUTPUT style)
+func WriteCIOutputs(w io.Writer, outputs map[string]string, files []string) error {
+	keys := make([]string, 0, len(outputs))
+	for k := range outputs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	lines := ""
+	for _, k := range keys {
+		lines += fmt.Sprintf("%s=%s\n", k, outputs[k])
+	}
+
+	if _, err := io.WriteString(w, lines); err != nil {
+		return fmt.Errorf("failed to print outputs: %w", err)
+	}
+
+	for _, file := range files {
+		f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open output file '%s': %w", file, err)
+		}
+		if _, err := f.WriteString(lines); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write output file '%s': %w", file, err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to close output file '%s': %w", file, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteCIOutputs(t *testing.T) {
+	githubEnv := filepath.Join(t.TempDir(), "github_env")
+	if err := ioutil.WriteFile(githubEnv, []byte("EXISTING=1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	githubOutput := filepath.Join(t.TempDir(), "github_output") // created on demand
+
+	var stdout bytes.Buffer
+	outputs := map[string]string{"KUBECONFIG": "/home/ci/.kube/config", "K3D_CLUSTER": "ci"}
+	if err := WriteCIOutputs(&stdout, outputs, []string{githubEnv, githubOutput}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "K3D_CLUSTER=ci\nKUBECONFIG=/home/ci/.kube/config\n"
+	if stdout.String() != expected {
+		t.Errorf("expected stdout %q, got %q", expected, stdout.String())
+	}
+	if content, _ := ioutil.ReadFile(githubEnv); string(content) != "EXISTING=1\n"+expected {
+		t.Errorf("expected outputs to be appended to the existing file, got %q", content)
+	}
+	if content, _ := ioutil.ReadFile(githubOutput); string(content) != expected {
+		t.Errorf("expected outputs in the new file, got %q", content)
+	}
+}
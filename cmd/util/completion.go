@@ -45,6 +45,9 @@ func ValidArgsAvailableClusters(cmd *cobra.Command, args []string, toComplete st
 
 clusterLoop:
 	for _, cluster := range clusters {
+		if Tenant != "" && (len(cluster.Nodes) == 0 || cluster.Nodes[0].RuntimeLabels[k3d.LabelClusterOwner] != Tenant) {
+			continue
+		}
 		for _, arg := range args {
 			if arg == cluster.Name { // only clusters, that are not in the args yet
 				continue clusterLoop
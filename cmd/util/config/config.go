@@ -22,19 +22,87 @@ THE SOFTWARE.
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/mitchellh/go-homedir"
 	"github.com/rancher/k3d/v5/pkg/config"
 	l "github.com/rancher/k3d/v5/pkg/logger"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"gopkg.in/yaml.v2"
+	k8syaml "sigs.k8s.io/yaml"
 )
 
+// UserDefaultsFileEnv overrides the location of the user defaults file (e.g. to share one within a team or to disable it with an empty value)
+const UserDefaultsFileEnv = "K3D_USER_CONFIG"
+
+// UserDefaultsFile returns the path of the file with the user's defaults for every cluster creation: $K3D_USER_CONFIG or $XDG_CONFIG_HOME/k3d/config.yaml (default: ~/.config/k3d/config.yaml)
+func UserDefaultsFile() (string, error) {
+	if path, ok := os.LookupEnv(UserDefaultsFileEnv); ok {
+		return path, nil
+	}
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		homeDir, err := homedir.Dir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get user's home directory: %w", err)
+		}
+		configHome = filepath.Join(homeDir, ".config")
+	}
+	return filepath.Join(configHome, "k3d", "config.yaml"), nil
+}
+
+// mergeUserDefaults reads the user defaults file (if it exists) into the viper config, so that config files and flags override its values.
+// It has the format of a config file, but apiVersion and kind may be omitted.
+func mergeUserDefaults(cfgViper *viper.Viper) error {
+	path, err := UserDefaultsFile()
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		return nil
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read user defaults file %s: %w", path, err)
+	}
+
+	expanded := []byte(os.ExpandEnv(string(content)))
+	defaults := map[string]interface{}{}
+	if err := k8syaml.Unmarshal(expanded, &defaults); err != nil {
+		return fmt.Errorf("failed to parse user defaults file %s: %w", path, err)
+	}
+	if _, ok := defaults["apiVersion"]; !ok {
+		defaults["apiVersion"] = config.DefaultConfigApiVersion
+	}
+	if _, ok := defaults["kind"]; !ok {
+		defaults["kind"] = "Simple"
+	}
+
+	schema, err := config.GetSchemaByVersion(fmt.Sprint(defaults["apiVersion"]))
+	if err != nil {
+		return fmt.Errorf("cannot validate user defaults file %s: %w", path, err)
+	}
+	if err := config.ValidateSchema(defaults, schema); err != nil {
+		return fmt.Errorf("schema validation failed for user defaults file %s: %w", path, err)
+	}
+
+	// merge the YAML (not the map used for validation, which has JSON number types), just like a config file
+	if err := cfgViper.MergeConfig(bytes.NewReader(expanded)); err != nil {
+		return fmt.Errorf("failed to apply user defaults file %s: %w", path, err)
+	}
+	l.Log().Infof("Using user defaults from %s", path)
+	return nil
+}
+
 func InitViperWithConfigFile(cfgViper *viper.Viper, configFile string) error {
 
 	// viper for the general config (file, env and non pre-processed flags)
@@ -43,6 +111,11 @@ func InitViperWithConfigFile(cfgViper *viper.Viper, configFile string) error {
 
 	cfgViper.SetConfigType("yaml")
 
+	// user defaults have the lowest priority, so they're read first
+	if err := mergeUserDefaults(cfgViper); err != nil {
+		l.Log().Fatalln(err)
+	}
+
 	// Set config file, if specified
 	if configFile != "" {
 
@@ -70,8 +143,8 @@ func InitViperWithConfigFile(cfgViper *viper.Viper, configFile string) error {
 		// use temp file with expanded variables
 		cfgViper.SetConfigFile(tmpfile.Name())
 
-		// try to read config into memory (viper map structure)
-		if err := cfgViper.ReadInConfig(); err != nil {
+		// try to read config into memory (viper map structure), overriding the user defaults
+		if err := cfgViper.MergeInConfig(); err != nil {
 			if _, ok := err.(viper.ConfigFileNotFoundError); ok {
 				l.Log().Fatalf("Config file %s not found: %+v", configFile, err)
 			}
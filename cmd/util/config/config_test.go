@@ -0,0 +1,82 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestInitViperWithConfigFileUserDefaults(t *testing.T) {
+	dir := t.TempDir()
+	defaultsFile := filepath.Join(dir, "defaults.yaml")
+	if err := os.WriteFile(defaultsFile, []byte(`image: rancher/k3s:v1.21.4-k3s1
+agents: 2
+volumes:
+  - volume: /tmp/src:/src
+    nodeFilters:
+      - all
+options:
+  k3d:
+    wait: true
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	configFile := filepath.Join(dir, "cluster.yaml")
+	if err := os.WriteFile(configFile, []byte(`apiVersion: k3d.io/v1alpha3
+kind: Simple
+agents: 3
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(UserDefaultsFileEnv, defaultsFile)
+
+	cfgViper := viper.New()
+	if err := InitViperWithConfigFile(cfgViper, configFile); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if agents := cfgViper.GetInt("agents"); agents != 3 {
+		t.Errorf("expected the config file to override the default agents, got %d", agents)
+	}
+	if image := cfgViper.GetString("image"); image != "rancher/k3s:v1.21.4-k3s1" {
+		t.Errorf("expected the default image, got %q", image)
+	}
+	if !cfgViper.GetBool("options.k3d.wait") {
+		t.Errorf("expected the default wait option")
+	}
+	if volumes, ok := cfgViper.Get("volumes").([]interface{}); !ok || len(volumes) != 1 {
+		t.Errorf("expected the default volume mount, got %v", cfgViper.Get("volumes"))
+	}
+
+	// no defaults file
+	t.Setenv(UserDefaultsFileEnv, filepath.Join(dir, "missing.yaml"))
+	cfgViper = viper.New()
+	if err := InitViperWithConfigFile(cfgViper, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfgViper.IsSet("image") {
+		t.Errorf("expected no image to be set without defaults file")
+	}
+}
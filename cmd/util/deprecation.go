@@ -0,0 +1,93 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"fmt"
+	"strings"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Annotations of deprecated flags, evaluated by ApplyFlagDeprecations
+const (
+	flagAnnotationReplacement    = "k3d.io/deprecated-replacement"
+	flagAnnotationRemovalVersion = "k3d.io/deprecated-removal"
+)
+
+// FlagDeprecation describes a flag that is about to be renamed or removed
+type FlagDeprecation struct {
+	Name           string // the deprecated flag
+	Replacement    string // the flag to use instead, if any
+	RemovalVersion string // the k3d version which will remove the deprecated flag, e.g. 'v6.0.0'
+}
+
+// DeprecateFlag marks a flag of the command as deprecated and hides it from the help text.
+// If it has a replacement, the deprecated flag is (if not registered yet) added as an alias of the replacement, sharing its value,
+// so that scripts using the old name keep working until the removal version.
+func DeprecateFlag(cmd *cobra.Command, deprecation FlagDeprecation) {
+	flags := cmd.Flags()
+	flag := flags.Lookup(deprecation.Name)
+	if flag == nil {
+		replacement := flags.Lookup(deprecation.Replacement)
+		if replacement == nil {
+			l.Log().Fatalf("cannot deprecate unknown flag '--%s' of '%s' without known replacement", deprecation.Name, cmd.Name())
+		}
+		flag = &pflag.Flag{
+			Name:        deprecation.Name,
+			Usage:       replacement.Usage,
+			Value:       replacement.Value,
+			DefValue:    replacement.DefValue,
+			NoOptDefVal: replacement.NoOptDefVal,
+		}
+		flags.AddFlag(flag)
+	}
+	flag.Hidden = true
+	if deprecation.Replacement != "" {
+		_ = flags.SetAnnotation(deprecation.Name, flagAnnotationReplacement, []string{deprecation.Replacement})
+	}
+	_ = flags.SetAnnotation(deprecation.Name, flagAnnotationRemovalVersion, []string{deprecation.RemovalVersion})
+}
+
+// ApplyFlagDeprecations warns about every deprecated flag that was used and marks its replacement as given,
+// so that it takes precedence over the config file like the replacement itself would.
+func ApplyFlagDeprecations(flags *pflag.FlagSet) {
+	flags.Visit(func(f *pflag.Flag) {
+		removal, ok := f.Annotations[flagAnnotationRemovalVersion]
+		if !ok {
+			return
+		}
+		msg := fmt.Sprintf("Flag '--%s' is deprecated", f.Name)
+		if replacement, ok := f.Annotations[flagAnnotationReplacement]; ok {
+			msg += fmt.Sprintf(", use '--%s' instead", replacement[0])
+			if r := flags.Lookup(replacement[0]); r != nil {
+				r.Changed = true
+			}
+		}
+		if v := strings.Join(removal, ""); v != "" {
+			msg += fmt.Sprintf(" (it will be removed in %s)", v)
+		}
+		l.Log().Warnln(msg)
+	})
+}
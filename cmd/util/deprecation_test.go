@@ -0,0 +1,55 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestDeprecateFlag(t *testing.T) {
+	cmd := &cobra.Command{Use: "create"}
+	agents := cmd.Flags().IntP("agents", "a", 0, "")
+	cmd.Flags().Bool("old", false, "")
+	DeprecateFlag(cmd, FlagDeprecation{Name: "workers", Replacement: "agents", RemovalVersion: "v6.0.0"})
+	DeprecateFlag(cmd, FlagDeprecation{Name: "old"})
+
+	if f := cmd.Flags().Lookup("workers"); f == nil || !f.Hidden {
+		t.Fatalf("expected a hidden alias flag '--workers', got %+v", f)
+	}
+
+	if err := cmd.Flags().Parse([]string{"--workers", "3", "--old"}); err != nil {
+		t.Fatal(err)
+	}
+	ApplyFlagDeprecations(cmd.Flags())
+
+	if *agents != 3 {
+		t.Errorf("expected the deprecated flag to set the replacement, got %d", *agents)
+	}
+	if !cmd.Flags().Changed("agents") {
+		t.Errorf("expected the replacement to be marked as changed")
+	}
+	if !cmd.Flags().Lookup("old").Hidden {
+		t.Errorf("expected the deprecated flag without replacement to be hidden")
+	}
+}
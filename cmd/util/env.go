@@ -0,0 +1,100 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/spf13/pflag"
+)
+
+// FlagEnvPrefix is the prefix of the environment variables that can be used instead of flags, e.g. $K3D_API_PORT for --api-port
+const FlagEnvPrefix = "K3D_"
+
+// ClusterEnvVar holds the name of a cluster in environments set up by k3d ('k3d env', hooks, --ci-output-file).
+// It's never read as a flag, as it would silently set the --cluster flag of all commands run in such an environment.
+const ClusterEnvVar = "K3D_CLUSTER"
+
+// FlagEnvAnnotation is the flag annotation overriding the environment variable derived from the flag name (see SetFlagEnvVar and SkipFlagEnv)
+const FlagEnvAnnotation = "k3d_env"
+
+// FlagEnvVar returns the name of the environment variable equivalent to the flag with the given name
+func FlagEnvVar(flagName string) string {
+	return FlagEnvPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// SetFlagEnvVar lets a flag take its value from the given environment variable instead of the one derived from its name,
+// e.g. because another name is established already or the derived one would be ambiguous
+func SetFlagEnvVar(flags *pflag.FlagSet, flagName string, envVar string) {
+	if err := flags.SetAnnotation(flagName, FlagEnvAnnotation, []string{envVar}); err != nil {
+		l.Log().Fatalf("cannot set the env var of unknown flag '--%s': %v", flagName, err)
+	}
+}
+
+// SkipFlagEnv keeps flags from being set via environment variables, e.g. secrets which mustn't be picked up from a variable meant for something else
+func SkipFlagEnv(flags *pflag.FlagSet, flagNames ...string) {
+	for _, name := range flagNames {
+		SetFlagEnvVar(flags, name, "")
+	}
+}
+
+// flagEnvVar returns the environment variable of the flag, if it can be set via one
+func flagEnvVar(f *pflag.Flag) (string, bool) {
+	if envVars, ok := f.Annotations[FlagEnvAnnotation]; ok && len(envVars) == 1 {
+		return envVars[0], envVars[0] != ""
+	}
+	return FlagEnvVar(f.Name), true
+}
+
+// ApplyFlagEnv sets all flags that were not given on the command line from their environment variables (see FlagEnvVar),
+// so the precedence is flag > environment variable > config file > default.
+// Flags that can be given multiple times take one value per line.
+func ApplyFlagEnv(flags *pflag.FlagSet) error {
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed || f.Name == "help" {
+			return
+		}
+		envVar, ok := flagEnvVar(f)
+		if !ok || envVar == ClusterEnvVar {
+			return
+		}
+		value, ok := os.LookupEnv(envVar)
+		if !ok {
+			return
+		}
+		values := []string{value}
+		if _, isSlice := f.Value.(pflag.SliceValue); isSlice {
+			values = strings.Split(strings.TrimSpace(value), "\n")
+		}
+		for _, v := range values {
+			if setErr := flags.Set(f.Name, v); setErr != nil {
+				err = fmt.Errorf("invalid value '%s' in $%s for --%s: %w", v, envVar, f.Name, setErr)
+				return
+			}
+		}
+	})
+	return err
+}
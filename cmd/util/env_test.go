@@ -0,0 +1,102 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestApplyFlagEnv(t *testing.T) {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	image := flags.String("image", "default", "")
+	apiPort := flags.String("api-port", "", "")
+	agents := flags.Int("agents", 0, "")
+	ports := flags.StringArray("port", nil, "")
+
+	t.Setenv("K3D_IMAGE", "rancher/k3s:from-env")
+	t.Setenv("K3D_API_PORT", "6550")
+	t.Setenv("K3D_AGENTS", "2")
+	t.Setenv("K3D_PORT", "8080:80@loadbalancer\n8443:443@loadbalancer")
+
+	if err := flags.Parse([]string{"--image", "rancher/k3s:from-flag"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyFlagEnv(flags); err != nil {
+		t.Fatal(err)
+	}
+
+	if *image != "rancher/k3s:from-flag" {
+		t.Errorf("expected the flag to take precedence over the env var, got '%s'", *image)
+	}
+	if *apiPort != "6550" || *agents != 2 {
+		t.Errorf("expected api-port and agents from env vars, got '%s' and '%d'", *apiPort, *agents)
+	}
+	if len(*ports) != 2 || (*ports)[1] != "8443:443@loadbalancer" {
+		t.Errorf("expected one port per line from the env var, got %v", *ports)
+	}
+	if !flags.Changed("agents") {
+		t.Errorf("expected flags set from env vars to be marked as changed, so they override config files")
+	}
+
+	t.Setenv("K3D_AGENTS", "two")
+	flags.Lookup("agents").Changed = false
+	if err := ApplyFlagEnv(flags); err == nil {
+		t.Errorf("expected an error for an invalid value")
+	}
+}
+
+func TestApplyFlagEnvAnnotations(t *testing.T) {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	readOnly := flags.Bool("read-only", false, "")
+	token := flags.String("token", "", "")
+	SetFlagEnvVar(flags, "read-only", "K3D_READONLY")
+	SkipFlagEnv(flags, "token")
+
+	t.Setenv("K3D_READ_ONLY", "not-a-bool") // the derived name isn't used anymore
+	t.Setenv("K3D_READONLY", "true")
+	t.Setenv("K3D_TOKEN", "api-token")
+
+	if err := ApplyFlagEnv(flags); err != nil {
+		t.Fatal(err)
+	}
+	if !*readOnly {
+		t.Errorf("expected --read-only from $K3D_READONLY")
+	}
+	if *token != "" {
+		t.Errorf("expected --token to ignore $K3D_TOKEN, got '%s'", *token)
+	}
+}
+
+func TestApplyFlagEnvClusterEnvVar(t *testing.T) {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	cluster := flags.String("cluster", "k3s-default", "")
+	t.Setenv(ClusterEnvVar, "dev")
+
+	if err := ApplyFlagEnv(flags); err != nil {
+		t.Fatal(err)
+	}
+	if *cluster != "k3s-default" {
+		t.Errorf("expected $%s not to set --cluster, got '%s'", ClusterEnvVar, *cluster)
+	}
+}
@@ -23,6 +23,7 @@ package util
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 
 	l "github.com/rancher/k3d/v5/pkg/logger"
@@ -77,3 +78,19 @@ func SplitFiltersFromFlag(flag string) (string, []string, error) {
 	return newsplit[0], strings.Split(newsplit[1], ";"), nil
 
 }
+
+// imageDigestRegexp matches the digest part of an image reference (e.g. 'sha256:<hex>'), which follows an '@' just like a node filter
+var imageDigestRegexp = regexp.MustCompile(`^[a-z0-9]+([+._-][a-z0-9]+)*:[a-fA-F0-9]{32,}$`)
+
+// SplitImageFiltersFromFlag separates an image reference from the node filter, if there is one
+// Other than in SplitFiltersFromFlag, an '@' followed by a digest is part of the image reference (e.g. 'rancher/k3s@sha256:...@agent:0')
+func SplitImageFiltersFromFlag(flag string) (string, []string, error) {
+	i := strings.LastIndex(flag, "@")
+	if i < 0 || imageDigestRegexp.MatchString(flag[i+1:]) {
+		return flag, nil, nil
+	}
+	if i == 0 || i == len(flag)-1 {
+		return "", nil, fmt.Errorf("Invalid flag '%s' includes '@' but is missing an image or a node filter", flag)
+	}
+	return flag[:i], strings.Split(flag[i+1:], ";"), nil
+}
@@ -0,0 +1,64 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitImageFiltersFromFlag(t *testing.T) {
+	digest := "sha256:8d5e1f2b7c0b3f5c9e4b9f3f36a1c9c3c3c1c7a1e2f9a0b4d5e6f7a8b9c0d1e2"
+
+	tests := map[string]struct {
+		flag        string
+		image       string
+		nodeFilters []string
+		expectErr   bool
+	}{
+		"plain image":             {flag: "rancher/k3s:v1.22.4-k3s1", image: "rancher/k3s:v1.22.4-k3s1"},
+		"digest":                  {flag: "rancher/k3s@" + digest, image: "rancher/k3s@" + digest},
+		"digest and node filter":  {flag: "rancher/k3s@" + digest + "@agent:0", image: "rancher/k3s@" + digest, nodeFilters: []string{"agent:0"}},
+		"node filter":             {flag: "rancher/k3s:v1.21.7-k3s1@agent:0", image: "rancher/k3s:v1.21.7-k3s1", nodeFilters: []string{"agent:0"}},
+		"multiple node filters":   {flag: "img@agent:0;server:1", image: "img", nodeFilters: []string{"agent:0", "server:1"}},
+		"latest with node filter": {flag: "latest@agent:*", image: "latest", nodeFilters: []string{"agent:*"}},
+		"missing image":           {flag: "@x", expectErr: true},
+		"missing node filter":     {flag: "img@", expectErr: true},
+	}
+
+	for name, tc := range tests {
+		image, nodeFilters, err := SplitImageFiltersFromFlag(tc.flag)
+		if tc.expectErr {
+			if err == nil {
+				t.Errorf("%s: expected an error for '%s'", name, tc.flag)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+			continue
+		}
+		if image != tc.image || !reflect.DeepEqual(nodeFilters, tc.nodeFilters) {
+			t.Errorf("%s: expected image '%s' with node filters %v, got '%s' with %v", name, tc.image, tc.nodeFilters, image, nodeFilters)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// legacyCommands maps commands of k3d v1 that don't follow the verb-noun pattern to their current equivalent
+var legacyCommands = map[string][]string{
+	"get-kubeconfig": {"kubeconfig", "get"},
+	"import-images":  {"image", "import"},
+	"import-image":   {"image", "import"},
+	"add-node":       {"node", "create"},
+	"delete-node":    {"node", "delete"},
+	"start-node":     {"node", "start"},
+	"stop-node":      {"node", "stop"},
+	"restart-node":   {"node", "restart"},
+}
+
+// legacyPositionalFlags lists the flags of legacy commands whose value is a positional argument of the current command
+var legacyPositionalFlags = map[string][]string{
+	"get-kubeconfig": {"--name", "-n"},
+}
+
+// TranslateLegacyArgs rewrites the command line of the old verb-noun layout (e.g. 'k3d create cluster NAME' or 'k3d get-kubeconfig')
+// to the noun-verb layout ('k3d cluster create NAME'), so existing scripts keep working. Only command lines that don't match
+// any current command are translated and only if the result does. Global flags in front of the legacy command are moved behind
+// the current one. It also returns the translated legacy command (e.g. 'create cluster'), which is empty if nothing was translated.
+func TranslateLegacyArgs(root *cobra.Command, args []string) ([]string, string) {
+	globalFlags, ok := leadingGlobalFlags(root, args)
+	if !ok || len(globalFlags) == len(args) {
+		return args, ""
+	}
+	if cmd, _, err := root.Find(args); err == nil && cmd != root {
+		return args, ""
+	}
+	rest := args[len(globalFlags):]
+
+	var translated []string
+	var legacy string
+	if replacement, ok := legacyCommands[rest[0]]; ok {
+		translated = append(append([]string{}, replacement...), positionalLegacyFlags(rest[0], rest[1:])...)
+		legacy = rest[0]
+	} else if len(rest) > 1 {
+		translated = append([]string{rest[1], rest[0]}, rest[2:]...)
+		legacy = rest[0] + " " + rest[1]
+	} else {
+		return args, ""
+	}
+
+	// only accept translations resolving to a subcommand of a noun, i.e. not to the noun itself with the verb as an argument
+	cmd, _, err := root.Find(translated[:2])
+	if err != nil || cmd == root || cmd.Parent() == root {
+		return args, ""
+	}
+	translated[0], translated[1] = cmd.Parent().Name(), cmd.Name() // resolve aliases, e.g. for the deprecation warning
+	translated = append(append(translated[:2:2], globalFlags...), translated[2:]...)
+	return translated, legacy
+}
+
+// leadingGlobalFlags returns the global flags (with their values) in front of the first argument that isn't one.
+// It fails for unknown flags, as it can't tell whether they take a value.
+func leadingGlobalFlags(root *cobra.Command, args []string) ([]string, bool) {
+	i := 0
+	for i < len(args) && strings.HasPrefix(args[i], "-") {
+		if args[i] == "--" {
+			return nil, false
+		}
+		name := strings.TrimLeft(args[i], "-")
+		hasValue := strings.Contains(name, "=")
+		if hasValue {
+			name = name[:strings.Index(name, "=")]
+		}
+		var flag *pflag.Flag
+		if strings.HasPrefix(args[i], "--") {
+			flag = root.PersistentFlags().Lookup(name)
+		} else if len(name) == 1 {
+			flag = root.PersistentFlags().ShorthandLookup(name)
+		}
+		if flag == nil {
+			return nil, false
+		}
+		i++
+		if !hasValue && flag.NoOptDefVal == "" {
+			i++ // the value is the next argument
+		}
+	}
+	if i > len(args) {
+		return nil, false
+	}
+	return args[:i], true
+}
+
+// positionalLegacyFlags turns the flags of a legacy command that are positional arguments of the current command (see legacyPositionalFlags) into such
+func positionalLegacyFlags(legacy string, args []string) []string {
+	names := legacyPositionalFlags[legacy]
+	if len(names) == 0 {
+		return args
+	}
+	positional := []string{}
+	others := []string{}
+	for i := 0; i < len(args); i++ {
+		matched := false
+		for _, name := range names {
+			if args[i] == name && i+1 < len(args) {
+				positional = append(positional, args[i+1])
+				i++
+				matched = true
+			} else if strings.HasPrefix(args[i], name+"=") {
+				positional = append(positional, strings.TrimPrefix(args[i], name+"="))
+				matched = true
+			}
+			if matched {
+				break
+			}
+		}
+		if !matched {
+			others = append(others, args[i])
+		}
+	}
+	return append(positional, others...)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestTranslateLegacyArgs(t *testing.T) {
+	root := &cobra.Command{Use: "k3d", Run: func(*cobra.Command, []string) {}}
+	root.PersistentFlags().Bool("verbose", false, "")
+	root.PersistentFlags().BoolP("quiet", "q", false, "")
+	root.PersistentFlags().String("log-format", "", "")
+	cluster := &cobra.Command{Use: "cluster", Aliases: []string{"clusters"}, Run: func(*cobra.Command, []string) {}}
+	cluster.AddCommand(&cobra.Command{Use: "create", Run: func(*cobra.Command, []string) {}})
+	cluster.AddCommand(&cobra.Command{Use: "list", Aliases: []string{"ls"}, Run: func(*cobra.Command, []string) {}})
+	kubeconfig := &cobra.Command{Use: "kubeconfig", Run: func(*cobra.Command, []string) {}}
+	kubeconfig.AddCommand(&cobra.Command{Use: "get", Run: func(*cobra.Command, []string) {}})
+	node := &cobra.Command{Use: "node", Run: func(*cobra.Command, []string) {}}
+	node.AddCommand(&cobra.Command{Use: "stop", Run: func(*cobra.Command, []string) {}})
+	image := &cobra.Command{Use: "image", Run: func(*cobra.Command, []string) {}}
+	image.AddCommand(&cobra.Command{Use: "import", Run: func(*cobra.Command, []string) {}})
+	root.AddCommand(cluster, kubeconfig, node, image)
+
+	tests := []struct {
+		args     []string
+		expected []string
+		legacy   string
+	}{
+		{[]string{"create", "cluster", "dev", "--agents", "2"}, []string{"cluster", "create", "dev", "--agents", "2"}, "create cluster"},
+		{[]string{"ls", "clusters"}, []string{"cluster", "list"}, "ls clusters"},
+		{[]string{"get-kubeconfig", "--name", "dev"}, []string{"kubeconfig", "get", "dev"}, "get-kubeconfig"},
+		{[]string{"get-kubeconfig", "--overwrite", "-n=dev"}, []string{"kubeconfig", "get", "dev", "--overwrite"}, "get-kubeconfig"},
+		{[]string{"--verbose", "get-kubeconfig", "--name=dev"}, []string{"kubeconfig", "get", "--verbose", "dev"}, "get-kubeconfig"},
+		{[]string{"-q", "--log-format", "json", "create", "cluster", "dev"}, []string{"cluster", "create", "-q", "--log-format", "json", "dev"}, "create cluster"},
+		{[]string{"--log-format=json", "ls", "clusters"}, []string{"cluster", "list", "--log-format=json"}, "ls clusters"},
+		{[]string{"stop-node", "--cluster", "dev", "agent:0"}, []string{"node", "stop", "--cluster", "dev", "agent:0"}, "stop-node"},
+		{[]string{"import-image", "--tar", "images.tar"}, []string{"image", "import", "--tar", "images.tar"}, "import-image"},
+		{[]string{"cluster", "create", "dev"}, []string{"cluster", "create", "dev"}, ""}, // current layout
+		{[]string{"foo", "cluster"}, []string{"foo", "cluster"}, ""},                     // no such subcommand of cluster
+		{[]string{"create"}, []string{"create"}, ""},
+		{[]string{"--verbose"}, []string{"--verbose"}, ""},
+		{[]string{"--unknown", "create", "cluster"}, []string{"--unknown", "create", "cluster"}, ""}, // can't tell whether the flag takes a value
+		{[]string{"--log-format"}, []string{"--log-format"}, ""},
+	}
+	for _, tt := range tests {
+		translated, legacy := TranslateLegacyArgs(root, tt.args)
+		if !reflect.DeepEqual(translated, tt.expected) || legacy != tt.legacy {
+			t.Errorf("%v: expected %v (legacy '%s'), got %v (legacy '%s')", tt.args, tt.expected, tt.legacy, translated, legacy)
+		}
+	}
+}
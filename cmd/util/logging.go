@@ -0,0 +1,41 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"os"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/sirupsen/logrus/hooks/writer"
+)
+
+// LogToStderr moves the info/debug log output (written to stdout by default) to stderr,
+// so that stdout only carries the result of commands meant to be used in scripts, e.g. 'KUBECONFIG=$(k3d ensure ...)'
+func LogToStderr() {
+	for _, hooks := range l.Log().Hooks {
+		for _, hook := range hooks {
+			if w, ok := hook.(*writer.Hook); ok && w.Writer == os.Stdout {
+				w.Writer = os.Stderr
+			}
+		}
+	}
+}
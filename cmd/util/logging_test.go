@@ -0,0 +1,46 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"os"
+	"testing"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/writer"
+)
+
+func TestLogToStderr(t *testing.T) {
+	stdoutHook := &writer.Hook{Writer: os.Stdout, LogLevels: []logrus.Level{logrus.InfoLevel}}
+	stderrHook := &writer.Hook{Writer: os.Stderr, LogLevels: []logrus.Level{logrus.ErrorLevel}}
+	previous := l.Log().ReplaceHooks(logrus.LevelHooks{})
+	defer l.Log().ReplaceHooks(previous)
+	l.Log().AddHook(stdoutHook)
+	l.Log().AddHook(stderrHook)
+
+	LogToStderr()
+
+	if stdoutHook.Writer != os.Stderr || stderrHook.Writer != os.Stderr {
+		t.Errorf("expected all log output to go to stderr")
+	}
+}
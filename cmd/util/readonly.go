@@ -0,0 +1,91 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// ReadOnly is set by the global --read-only flag (or $K3D_READONLY): only commands that merely inspect clusters may run,
+// so that e.g. a shared dashboard built on top of k3d can never modify anything by accident
+var ReadOnly bool
+
+// readOnlySafeCommands lists the commands (by command path) that don't modify clusters, nodes, registries or images.
+// Commands not listed here are rejected in read-only mode, so new commands are safe by default.
+var readOnlySafeCommands = map[string]bool{
+	"k3d":                               true,
+	"k3d completion":                    true,
+	"k3d version":                       true,
+	"k3d version list":                  true,
+	"k3d runtime-info":                  true,
+	"k3d record":                        true,
+	"k3d du":                            true,
+	"k3d doctor":                        true,
+	"k3d status":                        true,
+	"k3d k8s-events":                    true,
+	"k3d inventory":                     true,
+	"k3d env":                           true, // only writes the cluster's kubeconfig file
+	"k3d serve":                         true, // served with the read-only runtime, so mutating endpoints fail
+	"k3d cluster":                       true,
+	"k3d cluster list":                  true,
+	"k3d cluster endpoints":             true,
+	"k3d cluster inspect":               true,
+	"k3d node":                          true,
+	"k3d node list":                     true,
+	"k3d node logs":                     true,
+	"k3d registry":                      true,
+	"k3d registry list":                 true,
+	"k3d registry rewrite":              true, // only writes local files
+	"k3d pool":                          true,
+	"k3d pool list":                     true,
+	"k3d kubeconfig":                    true,
+	"k3d kubeconfig get":                true,
+	"k3d kubeconfig merge":              true, // only writes the local kubeconfig
+	"k3d kubeconfig path":               true, // only writes the clusters' kubeconfig files
+	"k3d config":                        true,
+	"k3d config init":                   true,
+	"k3d config migrate":                true,
+	"k3d config view":                   true,
+	"k3d template":                      true,
+	"k3d template list":                 true,
+	"k3d template use":                  true,
+	"k3d debug":                         true,
+	"k3d debug loadbalancer":            true,
+	"k3d debug loadbalancer get-config": true,
+}
+
+// CheckReadOnly returns an error if read-only mode is enabled and the given command may modify something
+func CheckReadOnly(cmd *cobra.Command) error {
+	if !ReadOnly {
+		return nil
+	}
+	switch cmd.Name() {
+	case "help", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return nil
+	}
+	if readOnlySafeCommands[cmd.CommandPath()] {
+		return nil
+	}
+	return fmt.Errorf("'%s' is not allowed in read-only mode (--read-only / $K3D_READONLY)", cmd.CommandPath())
+}
@@ -0,0 +1,56 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseSeedObjectFlag parses the value of the --secret and --configmap flags (format: NAME=SOURCE[:NAMESPACE])
+func ParseSeedObjectFlag(flag string) (name string, source string, namespace string, err error) {
+	split := strings.SplitN(flag, "=", 2)
+	if len(split) != 2 || split[0] == "" || split[1] == "" {
+		return "", "", "", fmt.Errorf("invalid format '%s': expected NAME=SOURCE[:NAMESPACE]", flag)
+	}
+	name, source = split[0], split[1]
+
+	// the namespace is everything after the last colon, unless that colon belongs to a Windows drive letter (e.g. C:\config.yaml)
+	// or the remainder looks like a path
+	if idx := strings.LastIndex(source, ":"); idx > 0 && !isWindowsDriveColon(source, idx) && !strings.ContainsAny(source[idx+1:], `/\`) {
+		source, namespace = source[:idx], source[idx+1:]
+		if namespace == "" {
+			return "", "", "", fmt.Errorf("invalid format '%s': empty namespace", flag)
+		}
+	}
+
+	return name, source, namespace, nil
+}
+
+// isWindowsDriveColon checks if the colon at the given index is the one of a Windows drive letter followed by a path (e.g. C:\ or C:/)
+func isWindowsDriveColon(source string, idx int) bool {
+	if idx != 1 || len(source) < 3 {
+		return false
+	}
+	letter := source[0]
+	return ((letter >= 'a' && letter <= 'z') || (letter >= 'A' && letter <= 'Z')) && (source[2] == '\\' || source[2] == '/')
+}
@@ -0,0 +1,63 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import "testing"
+
+func TestParseSeedObjectFlag(t *testing.T) {
+	tests := map[string]struct {
+		flag      string
+		name      string
+		source    string
+		namespace string
+		expectErr bool
+	}{
+		"plain":                 {flag: "db=./db.env", name: "db", source: "./db.env"},
+		"namespace":             {flag: "db=./db.env:myapp", name: "db", source: "./db.env", namespace: "myapp"},
+		"single char source":    {flag: "db=x:myapp", name: "db", source: "x", namespace: "myapp"},
+		"windows drive":         {flag: `db=C:\config\db.env`, name: "db", source: `C:\config\db.env`},
+		"windows drive forward": {flag: "db=C:/config/db.env", name: "db", source: "C:/config/db.env"},
+		"windows drive and ns":  {flag: `db=C:\config\db.env:myapp`, name: "db", source: `C:\config\db.env`, namespace: "myapp"},
+		"colon in directory":    {flag: "db=./a:b/db.env", name: "db", source: "./a:b/db.env"},
+		"empty namespace":       {flag: "db=./db.env:", expectErr: true},
+		"no source":             {flag: "db=", expectErr: true},
+		"no name":               {flag: "=./db.env", expectErr: true},
+		"no separator":          {flag: "./db.env", expectErr: true},
+	}
+
+	for name, tc := range tests {
+		resName, source, namespace, err := ParseSeedObjectFlag(tc.flag)
+		if tc.expectErr {
+			if err == nil {
+				t.Errorf("%s: expected an error for '%s'", name, tc.flag)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+			continue
+		}
+		if resName != tc.name || source != tc.source || namespace != tc.namespace {
+			t.Errorf("%s: expected (%s, %s, %s), got (%s, %s, %s)", name, tc.name, tc.source, tc.namespace, resName, source, namespace)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Shells lists the shells ShellExports can generate commands for
+var Shells = []string{"sh", "bash", "zsh", "fish", "powershell"}
+
+// DefaultShell returns the shell ShellExports generates commands for by default on this OS
+func DefaultShell() string {
+	if runtime.GOOS == "windows" {
+		return "powershell"
+	}
+	return "sh"
+}
+
+// ShellExports prints commands setting the given environment variables (sorted by name) in the given shell, meant to be evaluated by it
+func ShellExports(w io.Writer, shell string, vars map[string]string) error {
+	var format func(name, value string) string
+	switch shell {
+	case "sh", "bash", "zsh":
+		format = func(name, value string) string {
+			return fmt.Sprintf("export %s='%s'", name, strings.ReplaceAll(value, "'", `'\''`))
+		}
+	case "fish":
+		format = func(name, value string) string {
+			return fmt.Sprintf("set -gx %s '%s'", name, strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(value))
+		}
+	case "powershell":
+		format = func(name, value string) string {
+			return fmt.Sprintf("$env:%s = '%s'", name, strings.ReplaceAll(value, "'", "''"))
+		}
+	default:
+		return fmt.Errorf("unsupported shell '%s' (supported: %s)", shell, strings.Join(Shells, ", "))
+	}
+
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, err := fmt.Fprintln(w, format(name, vars[name])); err != nil {
+			return fmt.Errorf("failed to print environment: %w", err)
+		}
+	}
+	return nil
+}
+
+// envrc block markers, so that the k3d part of an existing .envrc can be replaced without touching the rest
+const (
+	envrcBlockStart = "# >>> k3d env >>>"
+	envrcBlockEnd   = "# <<< k3d env <<<"
+)
+
+// EnvrcWithExports returns the content of a direnv .envrc with the commands setting the given environment variables,
+// replacing the k3d block of the existing content (if any) and keeping everything else
+func EnvrcWithExports(existing string, vars map[string]string) (string, error) {
+	block := &strings.Builder{}
+	fmt.Fprintf(block, "%s\n# generated by 'k3d env --emit-envrc', changes in this block are overwritten\n", envrcBlockStart)
+	if err := ShellExports(block, "bash", vars); err != nil {
+		return "", err
+	}
+	block.WriteString(envrcBlockEnd + "\n")
+
+	start := strings.Index(existing, envrcBlockStart)
+	end := strings.Index(existing, envrcBlockEnd)
+	if start >= 0 && end > start {
+		return existing[:start] + block.String() + strings.TrimPrefix(existing[end+len(envrcBlockEnd):], "\n"), nil
+	}
+	if existing != "" && !strings.HasSuffix(existing, "\n") {
+		existing += "\n"
+	}
+	return existing + block.String(), nil
+}
@@ -0,0 +1,73 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestShellExports(t *testing.T) {
+	vars := map[string]string{
+		"KUBECONFIG":  "/home/o'neil/.config/k3d/kubeconfig-dev.yaml",
+		"K3D_CLUSTER": "dev",
+	}
+
+	for shell, expected := range map[string]string{
+		"sh":         "export K3D_CLUSTER='dev'\nexport KUBECONFIG='/home/o'\\''neil/.config/k3d/kubeconfig-dev.yaml'\n",
+		"bash":       "export K3D_CLUSTER='dev'\nexport KUBECONFIG='/home/o'\\''neil/.config/k3d/kubeconfig-dev.yaml'\n",
+		"fish":       "set -gx K3D_CLUSTER 'dev'\nset -gx KUBECONFIG '/home/o\\'neil/.config/k3d/kubeconfig-dev.yaml'\n",
+		"powershell": "$env:K3D_CLUSTER = 'dev'\n$env:KUBECONFIG = '/home/o''neil/.config/k3d/kubeconfig-dev.yaml'\n",
+	} {
+		out := &bytes.Buffer{}
+		if err := ShellExports(out, shell, vars); err != nil {
+			t.Fatalf("unexpected error for %s: %v", shell, err)
+		}
+		if out.String() != expected {
+			t.Errorf("expected for %s:\n%s\ngot:\n%s", shell, expected, out.String())
+		}
+	}
+
+	if err := ShellExports(&bytes.Buffer{}, "cmd.exe", vars); err == nil {
+		t.Error("expected an error for an unsupported shell")
+	}
+}
+
+func TestEnvrcWithExports(t *testing.T) {
+	vars := map[string]string{"K3D_CLUSTER": "dev"}
+	block := "# >>> k3d env >>>\n# generated by 'k3d env --emit-envrc', changes in this block are overwritten\nexport K3D_CLUSTER='dev'\n# <<< k3d env <<<\n"
+
+	for existing, expected := range map[string]string{
+		"":                                 block,
+		"layout go":                        "layout go\n" + block,
+		"layout go\n" + block + "dotenv\n": "layout go\n" + block + "dotenv\n",
+		"layout go\n# >>> k3d env >>>\nexport K3D_CLUSTER='old'\n# <<< k3d env <<<\ndotenv\n": "layout go\n" + block + "dotenv\n",
+	} {
+		content, err := EnvrcWithExports(existing, vars)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if content != expected {
+			t.Errorf("expected for %q:\n%s\ngot:\n%s", existing, expected, content)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"os"
+	"os/user"
+	"regexp"
+	"strings"
+)
+
+// Tenant is set by the global --tenant flag (or $K3D_TENANT): new clusters are prefixed with it and cluster lists only show its clusters,
+// so that multiple users sharing a docker host don't trample each other's clusters
+var Tenant string
+
+// TenantCurrentUser is the value of the --tenant flag given without a value, which makes the invoking user the tenant
+const TenantCurrentUser = "$USER"
+
+// tenantInvalidCharsRegexp matches everything that may not be part of a cluster name
+var tenantInvalidCharsRegexp = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// CurrentUser returns the name of the invoking user, sanitized to be usable as part of a cluster name
+func CurrentUser() string {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		name = u.Username
+	}
+	if i := strings.LastIndexAny(name, `\/`); i >= 0 { // e.g. 'DOMAIN\user' on Windows
+		name = name[i+1:]
+	}
+	return strings.Trim(tenantInvalidCharsRegexp.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// ClusterOwner returns the owner recorded on new clusters: the tenant, if set, or the invoking user
+func ClusterOwner() string {
+	if Tenant != "" {
+		return Tenant
+	}
+	return CurrentUser()
+}
+
+// QualifyClusterName prefixes a cluster name with the tenant, unless there's no tenant or it's prefixed already
+func QualifyClusterName(name string) string {
+	if Tenant == "" || strings.HasPrefix(name, Tenant+"-") {
+		return name
+	}
+	return Tenant + "-" + name
+}
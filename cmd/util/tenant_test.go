@@ -0,0 +1,43 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import "testing"
+
+func TestQualifyClusterName(t *testing.T) {
+	defer func() { Tenant = "" }()
+
+	if name := QualifyClusterName("dev"); name != "dev" {
+		t.Errorf("expected the name to be unchanged without tenant, got '%s'", name)
+	}
+
+	Tenant = "alice"
+	if name := QualifyClusterName("dev"); name != "alice-dev" {
+		t.Errorf("expected 'alice-dev', got '%s'", name)
+	}
+	if name := QualifyClusterName("alice-dev"); name != "alice-dev" {
+		t.Errorf("expected an already prefixed name to be unchanged, got '%s'", name)
+	}
+	if owner := ClusterOwner(); owner != "alice" {
+		t.Errorf("expected the tenant to be the owner, got '%s'", owner)
+	}
+}
@@ -0,0 +1,117 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package verify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/liggitt/tabwriter"
+	"github.com/rancher/k3d/v5/cmd/util"
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+type verifyFlags struct {
+	name     string
+	noHeader bool
+	output   string
+}
+
+// NewCmdVerify returns a new cobra command
+func NewCmdVerify() *cobra.Command {
+
+	flags := verifyFlags{}
+
+	// create new cobra command
+	cmd := &cobra.Command{
+		Use:   "verify [--name CLUSTERNAME]",
+		Short: "Run smoke tests against a cluster",
+		Long: `Run smoke tests against a running cluster and print a pass/fail report:
+	- nodes: all k3s nodes are registered and Ready
+	- dns: a test pod can resolve the kubernetes service
+	- service: a test pod can connect to the kubernetes service
+	- ingress: the ports published for the ingress (80/443 on the loadbalancer) answer HTTP requests
+k3d exits with a non-zero exit code if any of the checks failed.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			cluster, err := client.ClusterGet(cmd.Context(), runtimes.SelectedRuntime, &k3d.Cluster{Name: flags.name})
+			if err != nil {
+				l.Log().Fatalf("Failed to get cluster '%s': %v", flags.name, err)
+			}
+
+			report, err := client.ClusterVerify(cmd.Context(), runtimes.SelectedRuntime, cluster)
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+
+			printReport(report, flags)
+
+			if !report.Passed {
+				os.Exit(1)
+			}
+		},
+	}
+
+	// add flags
+	cmd.Flags().StringVarP(&flags.name, "name", "n", k3d.DefaultClusterName, "Name of the cluster to verify")
+	if err := cmd.RegisterFlagCompletionFunc("name", util.ValidArgsAvailableClusters); err != nil {
+		l.Log().Fatalln("Failed to register flag completion for '--name'", err)
+	}
+	cmd.Flags().BoolVar(&flags.noHeader, "no-headers", false, "Disable headers")
+	cmd.Flags().StringVarP(&flags.output, "output", "o", "", "Output format. One of: json|yaml")
+
+	// done
+	return cmd
+}
+
+func printReport(report *k3d.ClusterVerifyReport, flags verifyFlags) {
+	switch strings.ToLower(flags.output) {
+	case "json":
+		b, err := json.Marshal(report)
+		if err != nil {
+			l.Log().Fatalln(err)
+		}
+		fmt.Println(string(b))
+	case "yaml":
+		b, err := yaml.Marshal(report)
+		if err != nil {
+			l.Log().Fatalln(err)
+		}
+		fmt.Println(string(b))
+	default:
+		tabwriter := tabwriter.NewWriter(os.Stdout, 6, 4, 3, ' ', tabwriter.RememberWidths)
+		defer tabwriter.Flush()
+		if !flags.noHeader {
+			fmt.Fprintf(tabwriter, "%s\n", strings.Join([]string{"CHECK", "RESULT", "DETAILS"}, "\t"))
+		}
+		for _, check := range report.Checks {
+			fmt.Fprintf(tabwriter, "%s\t%s\t%s\n", check.Name, strings.ToUpper(string(check.Result)), check.Details)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package version
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/liggitt/tabwriter"
+	"github.com/rancher/k3d/v5/pkg/client"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+type versionListFlags struct {
+	noHeader bool
+	output   string
+}
+
+// NewCmdVersionList returns a new cobra command
+func NewCmdVersionList() *cobra.Command {
+	flags := versionListFlags{}
+
+	// create new command
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls", "get"},
+		Short:   "List the k3s channels and the versions they point to",
+		Long:    `List the k3s release channels (usable with 'k3d cluster create --channel') and the k3s versions they currently point to, as published by the k3s update channel server (the last fetched ones when offline).`,
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			channels, err := client.K3sChannelsGet(cmd.Context())
+			if err != nil {
+				l.Log().Fatalln(err)
+			}
+
+			switch flags.output {
+			case "json":
+				if err := json.NewEncoder(os.Stdout).Encode(channels); err != nil {
+					l.Log().Fatalln(err)
+				}
+				return
+			case "yaml":
+				if err := yaml.NewEncoder(os.Stdout).Encode(channels); err != nil {
+					l.Log().Fatalln(err)
+				}
+				return
+			case "":
+			default:
+				l.Log().Fatalf("Unknown output format '%s' (one of json|yaml)", flags.output)
+			}
+
+			tabwriter := tabwriter.NewWriter(os.Stdout, 6, 4, 3, ' ', tabwriter.RememberWidths)
+			defer tabwriter.Flush()
+			if !flags.noHeader {
+				fmt.Fprintf(tabwriter, "%s\n", strings.Join([]string{"CHANNEL", "VERSION", "IMAGE"}, "\t"))
+			}
+			for _, channel := range channels {
+				image, err := client.K3sImageForVersion(channel.Latest)
+				if err != nil {
+					image = "-"
+				}
+				fmt.Fprintf(tabwriter, "%s\t%s\t%s\n", channel.Name, channel.Latest, image)
+			}
+		},
+	}
+
+	// add flags
+	cmd.Flags().BoolVar(&flags.noHeader, "no-headers", false, "Disable headers")
+	cmd.Flags().StringVarP(&flags.output, "output", "o", "", "Output format. One of: json|yaml")
+
+	// done
+	return cmd
+}
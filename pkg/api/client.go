@@ -0,0 +1,194 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// StatusError is returned by the Client for every non-2xx response of the API
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("k3d API returned %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// IsNotFound checks if the error is a StatusError caused by a missing cluster (or route)
+func IsNotFound(err error) bool {
+	statusErr, ok := err.(*StatusError)
+	return ok && statusErr.StatusCode == http.StatusNotFound
+}
+
+// Client is a typed client for the k3d management API served by 'k3d serve'
+type Client struct {
+	BaseURL    string // e.g. http://127.0.0.1:7080
+	Token      string // bearer token, if the server requires one
+	HTTPClient *http.Client
+}
+
+// NewClient returns a new client for the API served at the given base URL
+func NewClient(baseURL string, token string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		Token:      token,
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// Version returns the version of the k3d build serving the API
+func (c *Client) Version(ctx context.Context) (*Version, error) {
+	v := &Version{}
+	if err := c.doJSON(ctx, http.MethodGet, "/version", nil, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ListClusters returns all clusters
+func (c *Client) ListClusters(ctx context.Context) ([]Cluster, error) {
+	clusters := []Cluster{}
+	if err := c.doJSON(ctx, http.MethodGet, "/v1/clusters", nil, &clusters); err != nil {
+		return nil, err
+	}
+	return clusters, nil
+}
+
+// GetCluster returns a single cluster
+func (c *Client) GetCluster(ctx context.Context, name string) (*Cluster, error) {
+	return c.clusterRequest(ctx, http.MethodGet, clusterPath(name, ""))
+}
+
+// CreateCluster creates a cluster from a k3d config file (YAML or JSON, kind Simple) and returns it once it's running
+func (c *Client) CreateCluster(ctx context.Context, config []byte) (*Cluster, error) {
+	cluster := &Cluster{}
+	if err := c.doJSON(ctx, http.MethodPost, "/v1/clusters", bytes.NewReader(config), cluster); err != nil {
+		return nil, err
+	}
+	return cluster, nil
+}
+
+// DeleteCluster deletes a cluster
+func (c *Client) DeleteCluster(ctx context.Context, name string) error {
+	return c.doJSON(ctx, http.MethodDelete, clusterPath(name, ""), nil, nil)
+}
+
+// StartCluster starts a cluster and returns it once its servers are ready
+func (c *Client) StartCluster(ctx context.Context, name string) (*Cluster, error) {
+	return c.clusterRequest(ctx, http.MethodPost, clusterPath(name, "start"))
+}
+
+// StopCluster stops a cluster
+func (c *Client) StopCluster(ctx context.Context, name string) (*Cluster, error) {
+	return c.clusterRequest(ctx, http.MethodPost, clusterPath(name, "stop"))
+}
+
+// Kubeconfig returns the admin kubeconfig (YAML) of a cluster
+func (c *Client) Kubeconfig(ctx context.Context, name string) ([]byte, error) {
+	resp, err := c.do(ctx, http.MethodGet, clusterPath(name, "kubeconfig"), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return ioutil.ReadAll(resp.Body)
+}
+
+func clusterPath(name string, action string) string {
+	path := "/v1/clusters/" + url.PathEscape(name)
+	if action != "" {
+		path += "/" + action
+	}
+	return path
+}
+
+func (c *Client) clusterRequest(ctx context.Context, method string, path string) (*Cluster, error) {
+	cluster := &Cluster{}
+	if err := c.doJSON(ctx, method, path, nil, cluster); err != nil {
+		return nil, err
+	}
+	return cluster, nil
+}
+
+// doJSON sends a request and decodes the JSON response into result (if not nil)
+func (c *Client) doJSON(ctx context.Context, method string, path string, body io.Reader, result interface{}) error {
+	resp, err := c.do(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode response of %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+// do sends a request and returns the response, if its status is 2xx (the caller has to close the body)
+func (c *Client) do(ctx context.Context, method string, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request %s %s: %w", method, path, err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/yaml")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request %s %s failed: %w", method, path, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		statusErr := &StatusError{StatusCode: resp.StatusCode}
+		apiErr := Error{}
+		if content, err := ioutil.ReadAll(resp.Body); err == nil {
+			if json.Unmarshal(content, &apiErr) == nil && apiErr.Error != "" {
+				statusErr.Message = apiErr.Error
+			} else {
+				statusErr.Message = strings.TrimSpace(string(content))
+			}
+		}
+		return nil, statusErr
+	}
+
+	return resp, nil
+}
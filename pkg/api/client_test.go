@@ -0,0 +1,177 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package api
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	errUnauthorizedForTest = errors.New("missing or invalid bearer token")
+	errNotFoundForTest     = errors.New("cluster 'missing' not found")
+)
+
+func TestClient(t *testing.T) {
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			writeError(w, http.StatusUnauthorized, errUnauthorizedForTest)
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /v1/clusters":
+			writeJSON(w, http.StatusOK, []Cluster{{Name: "a"}, {Name: "b"}})
+		case "POST /v1/clusters":
+			body, _ := ioutil.ReadAll(r.Body)
+			if string(body) != "name: sandbox\n" {
+				writeError(w, http.StatusBadRequest, errors.New("unexpected config"))
+				return
+			}
+			writeJSON(w, http.StatusOK, Cluster{Name: "sandbox", Servers: 1, ServersRunning: 1})
+		case "POST /v1/clusters/sandbox/stop":
+			writeJSON(w, http.StatusOK, Cluster{Name: "sandbox", Servers: 1})
+		case "DELETE /v1/clusters/sandbox":
+			w.WriteHeader(http.StatusNoContent)
+		case "GET /v1/clusters/sandbox/kubeconfig":
+			w.Header().Set("Content-Type", "application/yaml")
+			_, _ = w.Write([]byte("apiVersion: v1\nkind: Config\n"))
+		default:
+			writeError(w, http.StatusNotFound, errNotFoundForTest)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	c := NewClient(server.URL+"/", "secret")
+
+	clusters, err := c.ListClusters(ctx)
+	if err != nil || len(clusters) != 2 || clusters[1].Name != "b" {
+		t.Errorf("unexpected result listing clusters: %+v (%v)", clusters, err)
+	}
+
+	cluster, err := c.CreateCluster(ctx, []byte("name: sandbox\n"))
+	if err != nil || cluster.Name != "sandbox" || cluster.ServersRunning != 1 {
+		t.Errorf("unexpected result creating cluster: %+v (%v)", cluster, err)
+	}
+
+	cluster, err = c.StopCluster(ctx, "sandbox")
+	if err != nil || cluster.ServersRunning != 0 {
+		t.Errorf("unexpected result stopping cluster: %+v (%v)", cluster, err)
+	}
+
+	kubeconfig, err := c.Kubeconfig(ctx, "sandbox")
+	if err != nil || string(kubeconfig) != "apiVersion: v1\nkind: Config\n" {
+		t.Errorf("unexpected kubeconfig: '%s' (%v)", kubeconfig, err)
+	}
+
+	if err := c.DeleteCluster(ctx, "sandbox"); err != nil {
+		t.Errorf("unexpected error deleting cluster: %v", err)
+	}
+
+	if _, err := c.GetCluster(ctx, "missing"); !IsNotFound(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	} else if err.(*StatusError).Message != errNotFoundForTest.Error() {
+		t.Errorf("expected the error message of the API, got '%s'", err.(*StatusError).Message)
+	}
+
+	if _, err := NewClient(server.URL, "wrong").ListClusters(ctx); err == nil || err.(*StatusError).StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected an unauthorized error, got %v", err)
+	}
+
+	expected := []string{
+		"GET /v1/clusters",
+		"POST /v1/clusters",
+		"POST /v1/clusters/sandbox/stop",
+		"GET /v1/clusters/sandbox/kubeconfig",
+		"DELETE /v1/clusters/sandbox",
+		"GET /v1/clusters/missing",
+		"GET /v1/clusters",
+	}
+	if len(requests) != len(expected) {
+		t.Fatalf("expected requests %v, got %v", expected, requests)
+	}
+	for i := range expected {
+		if requests[i] != expected[i] {
+			t.Errorf("expected request %d to be '%s', got '%s'", i, expected[i], requests[i])
+		}
+	}
+}
+
+func TestClientVersion(t *testing.T) {
+	server := httptest.NewServer(NewServer(nil, "secret").Handler())
+	defer server.Close()
+
+	v, err := NewClient(server.URL, "secret").Version(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v.K3s == "" {
+		t.Errorf("expected a k3s version, got %+v", v)
+	}
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	spec := struct {
+		OpenAPI string                            `yaml:"openapi"`
+		Paths   map[string]map[string]interface{} `yaml:"paths"`
+	}{}
+	if err := yaml.Unmarshal(OpenAPISpec, &spec); err != nil {
+		t.Fatalf("invalid OpenAPI spec: %v", err)
+	}
+
+	// every route of the server has to be documented
+	routes := map[string][]string{
+		"/healthz":                       {"get"},
+		"/openapi.yaml":                  {"get"},
+		"/version":                       {"get"},
+		"/v1/clusters":                   {"get", "post"},
+		"/v1/clusters/{name}":            {"get", "delete"},
+		"/v1/clusters/{name}/start":      {"post"},
+		"/v1/clusters/{name}/stop":       {"post"},
+		"/v1/clusters/{name}/kubeconfig": {"get"},
+		"/metrics":                       {"get"},
+	}
+	if len(spec.Paths) != len(routes) {
+		t.Errorf("expected %d documented paths, got %d", len(routes), len(spec.Paths))
+	}
+	for path, methods := range routes {
+		for _, method := range methods {
+			if _, ok := spec.Paths[path][method]; !ok {
+				t.Errorf("route '%s %s' is not documented", method, path)
+			}
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	NewServer(nil, "secret").Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.yaml", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != string(OpenAPISpec) {
+		t.Errorf("expected the spec to be served without authentication, got status %d", rec.Code)
+	}
+}
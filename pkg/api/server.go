@@ -0,0 +1,393 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	_ "embed" // embeds the OpenAPI specification
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/rancher/k3d/v5/pkg/client"
+	"github.com/rancher/k3d/v5/pkg/config"
+	conf "github.com/rancher/k3d/v5/pkg/config/v1alpha3"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/metrics"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/rancher/k3d/v5/version"
+)
+
+// OpenAPISpec is the OpenAPI specification of the API
+//
+//go:embed openapi.yaml
+var OpenAPISpec []byte
+
+// MaxConfigSize is the maximum size of a config file accepted when creating a cluster
+const MaxConfigSize = 1 << 20
+
+// Server serves the k3d management API
+type Server struct {
+	Runtime runtimes.Runtime
+	Token   string // all requests (except for /healthz and /openapi.yaml) need to carry the header 'Authorization: Bearer <Token>'
+}
+
+// configContentTypes are the content types accepted for config files when creating a cluster
+// (browsers can send other ones cross-site without asking the server first)
+var configContentTypes = map[string]bool{
+	"application/json":   true,
+	"application/yaml":   true,
+	"application/x-yaml": true,
+	"text/yaml":          true,
+	"text/x-yaml":        true,
+}
+
+// NewServer returns a new API server managing clusters in the given runtime
+func NewServer(runtime runtimes.Runtime, token string) *Server {
+	return &Server{
+		Runtime: runtime,
+		Token:   token,
+	}
+}
+
+// Handler returns the http.Handler serving all routes of the API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/openapi.yaml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		_, _ = w.Write(OpenAPISpec)
+	})
+	mux.Handle("/version", s.authenticated(http.HandlerFunc(s.handleVersion)))
+	mux.Handle("/v1/clusters", s.authenticated(http.HandlerFunc(s.handleClusters)))
+	mux.Handle("/v1/clusters/", s.authenticated(http.HandlerFunc(s.handleCluster)))
+	mux.Handle("/metrics", s.authenticated(metrics.Handler()))
+	return mux
+}
+
+// Serve serves the API at the given address until the context is cancelled
+func (s *Server) Serve(ctx context.Context, addr string) error {
+	if s.Token == "" {
+		return errors.New("refusing to serve the API without a token")
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on '%s': %w", addr, err)
+	}
+
+	server := &http.Server{
+		Handler: s.Handler(),
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			l.Log().Warnf("Failed to shut down API server: %v", err)
+		}
+	}()
+
+	l.Log().Infof("Serving the k3d API on http://%s", listener.Addr())
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("API server failed: %w", err)
+	}
+	return nil
+}
+
+// authenticated rejects requests without the configured bearer token and requests sent by web pages of other origins
+func (s *Server) authenticated(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" && !sameOrigin(origin, r.Host) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("requests from origin '%s' are not allowed", origin))
+			return
+		}
+		if s.Token == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+s.Token)) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sameOrigin checks whether the Origin header of a request refers to the API server itself
+func sameOrigin(origin string, host string) bool {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host == host
+}
+
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+	writeJSON(w, http.StatusOK, Version{
+		K3d: version.GetVersion(),
+		K3s: version.GetK3sVersion(false),
+	})
+}
+
+// handleClusters serves /v1/clusters
+func (s *Server) handleClusters(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		clusters, err := client.ClusterList(r.Context(), s.Runtime)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		result := make([]Cluster, 0, len(clusters))
+		for _, cluster := range clusters {
+			result = append(result, clusterFromK3d(cluster))
+		}
+		writeJSON(w, http.StatusOK, result)
+	case http.MethodPost:
+		s.createCluster(w, r)
+	default:
+		writeMethodNotAllowed(w, http.MethodGet, http.MethodPost)
+	}
+}
+
+// handleCluster serves /v1/clusters/{name}[/{action}]
+func (s *Server) handleCluster(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/v1/clusters/"), "/")
+	name := parts[0]
+	if name == "" || len(parts) > 2 {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no such route '%s'", r.URL.Path))
+		return
+	}
+	action := ""
+	if len(parts) == 2 {
+		action = parts[1]
+	}
+
+	cluster, err := client.ClusterGet(r.Context(), s.Runtime, &k3d.Cluster{Name: name})
+	if err != nil {
+		if errors.Is(err, client.ClusterGetNoNodesFoundError) {
+			writeError(w, http.StatusNotFound, fmt.Errorf("cluster '%s' not found", name))
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	switch action {
+	case "":
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, clusterFromK3d(cluster))
+		case http.MethodDelete:
+			l.Log().Infof("API: deleting cluster '%s'", name)
+			if err := client.ClusterDelete(r.Context(), s.Runtime, cluster, k3d.ClusterDeleteOpts{SkipRegistryCheck: false}); err != nil {
+				writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			writeMethodNotAllowed(w, http.MethodGet, http.MethodDelete)
+		}
+	case "start":
+		if r.Method != http.MethodPost {
+			writeMethodNotAllowed(w, http.MethodPost)
+			return
+		}
+		l.Log().Infof("API: starting cluster '%s'", name)
+		envInfo, err := client.GatherEnvironmentInfo(r.Context(), s.Runtime, cluster)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to gather info about cluster environment: %w", err))
+			return
+		}
+		if err := client.ClusterStart(r.Context(), s.Runtime, cluster, k3d.ClusterStartOpts{WaitForServer: true, EnvironmentInfo: envInfo}); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		s.writeCluster(w, r, name)
+	case "stop":
+		if r.Method != http.MethodPost {
+			writeMethodNotAllowed(w, http.MethodPost)
+			return
+		}
+		l.Log().Infof("API: stopping cluster '%s'", name)
+		if err := client.ClusterStop(r.Context(), s.Runtime, cluster, k3d.ClusterStopOpts{}); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		s.writeCluster(w, r, name)
+	case "kubeconfig":
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, http.MethodGet)
+			return
+		}
+		kubeconfig, err := client.KubeconfigGet(r.Context(), s.Runtime, cluster)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		content, err := clientcmd.Write(*kubeconfig)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to serialize kubeconfig: %w", err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/yaml")
+		_, _ = w.Write(content)
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("no such route '%s'", r.URL.Path))
+	}
+}
+
+// createCluster creates a cluster from the k3d config file in the request body
+func (s *Server) createCluster(w http.ResponseWriter, r *http.Request) {
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || !configContentTypes[mediaType] {
+		writeError(w, http.StatusUnsupportedMediaType, fmt.Errorf("unsupported content type '%s': send the config file as application/json or application/yaml", r.Header.Get("Content-Type")))
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, MaxConfigSize))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("failed to read request body: %w", err))
+		return
+	}
+
+	simpleCfg, err := parseSimpleConfig(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// anyone with the token may send configs, but must not get to run commands on the host or read its files that way
+	clusterConfig, err := config.TransformUntrustedSimpleToClusterConfig(r.Context(), s.Runtime, *simpleCfg)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("failed to transform config: %w", err))
+		return
+	}
+	clusterConfig, err = config.ProcessClusterConfig(*clusterConfig)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("failed to process config: %w", err))
+		return
+	}
+	if err := config.ValidateClusterConfig(r.Context(), s.Runtime, *clusterConfig); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("failed cluster configuration validation: %w", err))
+		return
+	}
+
+	if _, err := client.ClusterGet(r.Context(), s.Runtime, &clusterConfig.Cluster); err == nil {
+		writeError(w, http.StatusConflict, fmt.Errorf("cluster '%s' already exists", clusterConfig.Cluster.Name))
+		return
+	}
+
+	l.Log().Infof("API: creating cluster '%s'", clusterConfig.Cluster.Name)
+	if err := client.ClusterRun(r.Context(), s.Runtime, clusterConfig); err != nil {
+		if errors.Is(err, client.ErrClusterAlreadyExists) { // created by someone else in the meantime, so it's not ours to delete
+			writeError(w, http.StatusConflict, err)
+			return
+		}
+		// the request context may be cancelled already, but we don't want to leave anything behind
+		if deleteErr := client.ClusterDelete(context.Background(), s.Runtime, &clusterConfig.Cluster, k3d.ClusterDeleteOpts{SkipRegistryCheck: true}); deleteErr != nil {
+			l.Log().Warnf("Failed to clean up cluster '%s': %v", clusterConfig.Cluster.Name, deleteErr)
+		}
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to create cluster '%s': %w", clusterConfig.Cluster.Name, err))
+		return
+	}
+
+	s.writeCluster(w, r, clusterConfig.Cluster.Name)
+}
+
+// parseSimpleConfig reads a k3d config file of kind Simple (YAML or JSON) and migrates it to the current config version
+func parseSimpleConfig(content []byte) (*conf.SimpleConfig, error) {
+	cfgViper := viper.New()
+	cfgViper.SetConfigType("yaml")
+	if err := cfgViper.ReadConfig(bytes.NewReader(content)); err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	if cfgViper.GetString("apiversion") == "" {
+		cfgViper.Set("apiversion", config.DefaultConfigApiVersion)
+	}
+	if cfgViper.GetString("kind") == "" {
+		cfgViper.Set("kind", "Simple")
+	}
+
+	cfg, err := config.FromViper(cfgViper)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.GetAPIVersion() != config.DefaultConfigApiVersion {
+		cfg, err = config.Migrate(cfg, config.DefaultConfigApiVersion)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	simpleCfg, ok := cfg.(conf.SimpleConfig)
+	if !ok {
+		return nil, fmt.Errorf("unsupported config kind '%s': only 'Simple' is supported", cfg.GetKind())
+	}
+	return &simpleCfg, nil
+}
+
+// writeCluster responds with the current state of the given cluster
+func (s *Server) writeCluster(w http.ResponseWriter, r *http.Request, name string) {
+	cluster, err := client.ClusterGet(r.Context(), s.Runtime, &k3d.Cluster{Name: name})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, clusterFromK3d(cluster))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		l.Log().Warnf("Failed to write API response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, Error{Error: err.Error()})
+}
+
+func writeMethodNotAllowed(w http.ResponseWriter, methods ...string) {
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+}
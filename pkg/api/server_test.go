@@ -0,0 +1,170 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServerAuthentication(t *testing.T) {
+	handler := NewServer(nil, "secret").Handler()
+
+	tests := []struct {
+		path   string
+		header string
+		origin string
+		status int
+	}{
+		{path: "/healthz", status: http.StatusOK},
+		{path: "/version", status: http.StatusUnauthorized},
+		{path: "/version", header: "Bearer wrong", status: http.StatusUnauthorized},
+		{path: "/version", header: "secret", status: http.StatusUnauthorized},
+		{path: "/version", header: "bearer secret", status: http.StatusUnauthorized},
+		{path: "/version", header: "Bearer secret", status: http.StatusOK},
+		{path: "/version", header: "Bearer secret", origin: "http://attacker.test", status: http.StatusForbidden},
+		{path: "/version", header: "Bearer secret", origin: "http://example.com:8080", status: http.StatusForbidden},
+		{path: "/version", header: "Bearer secret", origin: "null", status: http.StatusForbidden},
+		{path: "/version", header: "Bearer secret", origin: "http://example.com", status: http.StatusOK}, // httptest requests go to example.com
+		{path: "/v1/clusters", status: http.StatusUnauthorized},
+	}
+
+	for _, tc := range tests {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		if tc.origin != "" {
+			req.Header.Set("Origin", tc.origin)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Errorf("GET %s with '%s' from '%s': expected status %d, got %d", tc.path, tc.header, tc.origin, tc.status, rec.Code)
+		}
+	}
+
+	// requests of the server's own origin (e.g. a UI served next to the API) are fine
+	req := httptest.NewRequest(http.MethodGet, "http://127.0.0.1:7080/version", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Origin", "http://127.0.0.1:7080")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected a same-origin request to be allowed, got status %d", rec.Code)
+	}
+
+	// without a token, nothing gets through
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/version", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	NewServer(nil, "").Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected a server without token to reject requests, got status %d", rec.Code)
+	}
+	if err := NewServer(nil, "").Serve(context.Background(), "127.0.0.1:0"); err == nil {
+		t.Errorf("expected a server without token to refuse to serve")
+	}
+}
+
+func TestServerCreateClusterContentType(t *testing.T) {
+	handler := NewServer(nil, "secret").Handler()
+
+	for _, contentType := range []string{"", "text/plain", "application/x-www-form-urlencoded", "multipart/form-data; boundary=x"} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/clusters", strings.NewReader("name: foo\n"))
+		req.Header.Set("Authorization", "Bearer secret")
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("POST with content type '%s': expected status %d, got %d", contentType, http.StatusUnsupportedMediaType, rec.Code)
+		}
+	}
+}
+
+func TestServerCreateClusterUntrustedConfig(t *testing.T) {
+	handler := NewServer(nil, "secret").Handler()
+
+	for _, body := range []string{
+		"name: foo\nhooks:\n  - phase: preCreate\n    command: touch /tmp/pwned\n",
+		"name: foo\nvolumes:\n  - volume: /etc:/host-etc\n",
+		"name: foo\noptions:\n  k3s:\n    manifests:\n      - /home/me/.ssh/id_rsa\n",
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/clusters", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("Content-Type", "application/yaml")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req) // the runtime is nil, so anything getting past the check would panic
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "not allowed") {
+			t.Errorf("expected config %q to be rejected, got status %d: %s", body, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestServerMethodNotAllowed(t *testing.T) {
+	handler := NewServer(nil, "secret").Handler()
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/v1/clusters", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status %d, got %d", http.StatusMethodNotAllowed, rec.Code)
+	}
+	if allow := rec.Header().Get("Allow"); allow != "GET, POST" {
+		t.Errorf("expected 'Allow: GET, POST', got '%s'", allow)
+	}
+}
+
+func TestParseSimpleConfig(t *testing.T) {
+	cfg, err := parseSimpleConfig([]byte(`
+apiVersion: k3d.io/v1alpha3
+kind: Simple
+name: api-test
+servers: 1
+agents: 2
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Name != "api-test" || cfg.Servers != 1 || cfg.Agents != 2 {
+		t.Errorf("unexpected config: name=%s servers=%d agents=%d", cfg.Name, cfg.Servers, cfg.Agents)
+	}
+
+	// JSON without apiVersion and kind defaults to the current Simple config
+	cfg, err = parseSimpleConfig([]byte(`{"name": "api-json", "agents": 1}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Name != "api-json" || cfg.Agents != 1 {
+		t.Errorf("unexpected config: name=%s agents=%d", cfg.Name, cfg.Agents)
+	}
+
+	if _, err := parseSimpleConfig([]byte("apiVersion: k3d.io/v1alpha3\nkind: Cluster\nname: foo\n")); err == nil {
+		t.Errorf("expected an error for a config of kind Cluster")
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+// Package api implements the k3d management API served by 'k3d serve',
+// which lets other programs (IDE plugins, GUIs, remote tooling) manage clusters via HTTP instead of shelling out to the CLI.
+//
+// Routes (all request and response bodies are JSON, unless noted otherwise):
+//
+//	GET    /healthz                          -> "ok" (text/plain, no authentication)
+//	GET    /openapi.yaml                     -> OpenAPI specification of the API (application/yaml, no authentication)
+//	GET    /version                          -> Version
+//	GET    /v1/clusters                      -> []Cluster
+//	POST   /v1/clusters                      <- k3d config file (YAML or JSON) -> Cluster
+//	GET    /v1/clusters/{name}               -> Cluster
+//	DELETE /v1/clusters/{name}               -> 204 No Content
+//	POST   /v1/clusters/{name}/start         -> Cluster
+//	POST   /v1/clusters/{name}/stop          -> Cluster
+//	GET    /v1/clusters/{name}/kubeconfig    -> admin kubeconfig (application/yaml)
+//	GET    /metrics                          -> prometheus metrics
+//
+// Errors are returned as Error with a matching HTTP status code.
+// Client is a typed Go client for the API.
+package api
+
+import (
+	"strings"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// Version describes the k3d build serving the API
+type Version struct {
+	K3d string `json:"k3d"`
+	K3s string `json:"k3s"`
+}
+
+// Cluster is the API representation of a k3d cluster
+type Cluster struct {
+	Name           string `json:"name"`
+	Network        string `json:"network,omitempty"`
+	Servers        int    `json:"servers"`
+	ServersRunning int    `json:"serversRunning"`
+	Agents         int    `json:"agents"`
+	AgentsRunning  int    `json:"agentsRunning"`
+	Nodes          []Node `json:"nodes"`
+}
+
+// Node is the API representation of a node of a k3d cluster
+type Node struct {
+	Name    string `json:"name"`
+	Role    string `json:"role"`
+	Image   string `json:"image,omitempty"`
+	Status  string `json:"status"`
+	Running bool   `json:"running"`
+}
+
+// Error is returned with every non-2xx response
+type Error struct {
+	Error string `json:"error"`
+}
+
+// clusterFromK3d converts a cluster as returned by the k3d client into its API representation
+func clusterFromK3d(cluster *k3d.Cluster) Cluster {
+	c := Cluster{
+		Name:    cluster.Name,
+		Network: cluster.Network.Name,
+		Nodes:   make([]Node, 0, len(cluster.Nodes)),
+	}
+	c.ServersRunning, c.Servers = cluster.ServerCountRunning()
+	c.AgentsRunning, c.Agents = cluster.AgentCountRunning()
+	for _, node := range cluster.Nodes {
+		c.Nodes = append(c.Nodes, Node{
+			Name:    strings.TrimPrefix(node.Name, "/"),
+			Role:    string(node.Role),
+			Image:   node.Image,
+			Status:  node.State.Status,
+			Running: node.State.Running,
+		})
+	}
+	return c
+}
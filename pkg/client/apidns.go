@@ -0,0 +1,89 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net"
+	goruntime "runtime"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/rancher/k3d/v5/pkg/util"
+)
+
+// ClusterAPIDNSName returns the stable name of the cluster's Kubernetes API on the host, e.g. 'dev.k3d.internal'
+func ClusterAPIDNSName(clusterName string) string {
+	return fmt.Sprintf("%s.%s", clusterName, k3d.DefaultAPIDNSDomain)
+}
+
+// ClusterAPILoopbackIP returns the loopback address the cluster's Kubernetes API is published on with a stable DNS name.
+// It's derived from the cluster name, so a cluster recreated with the same name gets the same one and the API can use its
+// default port without conflicts between clusters. Only Linux routes the whole 127.0.0.0/8 to the loopback interface,
+// so it's empty elsewhere.
+func ClusterAPILoopbackIP(clusterName string) string {
+	if goruntime.GOOS != "linux" {
+		return ""
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(clusterName))
+	sum := h.Sum32()
+	// never 127.0.x.x (127.0.0.1 & co. are commonly used) and never a network or broadcast-like address
+	return fmt.Sprintf("127.%d.%d.%d", 1+sum%254, (sum>>8)&0xff, 1+(sum>>16)%254)
+}
+
+// ClusterRegisterAPIDNS makes the stable DNS name of the cluster's API resolve to the given address via the hosts file.
+// It fails with a hint how to add the entry manually, if the hosts file isn't writable and doesn't resolve the name yet.
+func ClusterRegisterAPIDNS(clusterName string, ip string) error {
+	if parsed := net.ParseIP(ip); parsed == nil || parsed.IsUnspecified() {
+		ip = "127.0.0.1" // published on all interfaces
+	}
+	name := ClusterAPIDNSName(clusterName)
+	path := util.HostsFilePath()
+	changed, err := util.HostsFileSetEntry(path, ip, name)
+	if err != nil {
+		if addrs, lookupErr := net.LookupHost(name); lookupErr == nil && len(addrs) > 0 && addrs[0] == ip {
+			l.Log().Debugf("Failed to update the hosts file, but '%s' already resolves to %s: %v", name, ip, err)
+			return nil
+		}
+		return fmt.Errorf("failed to register '%s' (add the line '%s %s' to %s manually, it stays valid for clusters with this name): %w", name, ip, name, path, err)
+	}
+	if changed {
+		l.Log().Infof("Added '%s' pointing to %s to %s", name, ip, path)
+	}
+	return nil
+}
+
+// ClusterUnregisterAPIDNS removes the hosts file entry k3d added for the stable DNS name of the cluster's API (if there is one)
+func ClusterUnregisterAPIDNS(clusterName string) error {
+	name := ClusterAPIDNSName(clusterName)
+	path := util.HostsFilePath()
+	changed, err := util.HostsFileRemoveEntry(path, name)
+	if err != nil {
+		return fmt.Errorf("failed to remove '%s' from %s: %w", name, path, err)
+	}
+	if changed {
+		l.Log().Infof("Removed '%s' from %s", name, path)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"net"
+	goruntime "runtime"
+	"testing"
+)
+
+func TestClusterAPILoopbackIP(t *testing.T) {
+	if goruntime.GOOS != "linux" {
+		if ip := ClusterAPILoopbackIP("dev"); ip != "" {
+			t.Errorf("expected no loopback address outside of Linux, got %s", ip)
+		}
+		return
+	}
+
+	seen := map[string]string{}
+	for _, name := range []string{"dev", "test", "k3s-default", "a-very-long-cluster-name"} {
+		ip := ClusterAPILoopbackIP(name)
+		if ip != ClusterAPILoopbackIP(name) {
+			t.Errorf("%s: expected the same address for the same cluster name", name)
+		}
+		parsed := net.ParseIP(ip).To4()
+		if parsed == nil || !parsed.IsLoopback() || parsed[1] == 0 || parsed[3] == 0 || parsed[3] == 255 {
+			t.Errorf("%s: expected a loopback address outside of 127.0.0.0/16, got %s", name, ip)
+		}
+		if other, ok := seen[ip]; ok {
+			t.Errorf("%s: got the same address %s as %s", name, ip, other)
+		}
+		seen[ip] = name
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// AuditServerArgs returns the k3s server arguments enabling audit logging in the kube-apiserver with the policy written to k3d.DefaultAuditPolicyPath
+func AuditServerArgs() []string {
+	return []string{
+		fmt.Sprintf("%s=audit-policy-file=%s", kubeAPIServerArgFlag, k3d.DefaultAuditPolicyPath),
+		fmt.Sprintf("%s=audit-log-path=%s", kubeAPIServerArgFlag, k3d.DefaultAuditLogPath),
+		fmt.Sprintf("%s=audit-log-maxsize=100", kubeAPIServerArgFlag),
+		fmt.Sprintf("%s=audit-log-maxbackup=3", kubeAPIServerArgFlag),
+	}
+}
+
+// ClusterAuditLogTail returns the last lines of the API server audit log of a server node and, if follow is set, keeps streaming new events
+// If nodeName is empty, the first running server node is used. Otherwise, the node is looked up by its name as given first and then with the default k3d prefix.
+func ClusterAuditLogTail(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster, nodeName string, lines int, follow bool) (io.ReadCloser, error) {
+	cluster, err := ClusterGet(ctx, runtime, cluster)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster: %w", err)
+	}
+
+	server := auditServerNode(cluster, nodeName)
+	if server == nil {
+		if nodeName != "" {
+			return nil, fmt.Errorf("no running server node '%s' in cluster '%s'", nodeName, cluster.Name)
+		}
+		return nil, fmt.Errorf("no running server node in cluster '%s'", cluster.Name)
+	}
+
+	if err := runtime.ExecInNode(ctx, server, []string{"test", "-f", k3d.DefaultAuditLogPath}); err != nil {
+		return nil, fmt.Errorf("no audit log found in node '%s' (was the cluster created with an audit policy?): %w", server.Name, err)
+	}
+
+	cmd := []string{"tail", "-n", strconv.Itoa(lines)}
+	if follow {
+		cmd = append(cmd, "-F")
+	}
+	cmd = append(cmd, k3d.DefaultAuditLogPath)
+
+	return runtime.ExecInNodeStream(ctx, server, cmd)
+}
+
+// auditServerNode returns the running server node to read the audit log from
+// Node names may follow a custom template (see k3d.DefaultNodeNameTemplate), so the prefix is only added if there's no node with the exact name.
+func auditServerNode(cluster *k3d.Cluster, nodeName string) *k3d.Node {
+	candidates := []string{nodeName}
+	if nodeName != "" {
+		candidates = append(candidates, fmt.Sprintf("%s-%s", k3d.DefaultObjectNamePrefix, nodeName))
+	}
+	for _, candidate := range candidates {
+		for _, node := range cluster.Nodes {
+			if node.Role != k3d.ServerRole || !node.State.Running {
+				continue
+			}
+			if candidate == "" || node.Name == candidate {
+				return node
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"testing"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestAuditServerNode(t *testing.T) {
+	cluster := &k3d.Cluster{
+		Name: "test",
+		Nodes: []*k3d.Node{
+			newFakeNode("test", "k3d-test-server-0", k3d.ServerRole, false),
+			newFakeNode("test", "k3d-test-server-1", k3d.ServerRole, true),
+			newFakeNode("test", "test-server1", k3d.ServerRole, true), // custom --node-name-template
+			newFakeNode("test", "k3d-test-agent-0", k3d.AgentRole, true),
+		},
+	}
+
+	tests := []struct {
+		nodeName string
+		expected string
+	}{
+		{nodeName: "", expected: "k3d-test-server-1"},
+		{nodeName: "k3d-test-server-1", expected: "k3d-test-server-1"},
+		{nodeName: "test-server-1", expected: "k3d-test-server-1"},
+		{nodeName: "test-server1", expected: "test-server1"},
+		{nodeName: "k3d-test-server-0", expected: ""}, // not running
+		{nodeName: "k3d-test-agent-0", expected: ""},  // not a server
+	}
+
+	for _, tc := range tests {
+		node := auditServerNode(cluster, tc.nodeName)
+		name := ""
+		if node != nil {
+			name = node.Name
+		}
+		if name != tc.expected {
+			t.Errorf("node '%s': expected '%s', got '%s'", tc.nodeName, tc.expected, name)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/rancher/k3d/v5/version"
+)
+
+// files in a cluster backup archive
+const (
+	backupMetadataFile  = "backup.yaml"
+	backupConfigFile    = "config.yaml"
+	backupDatastoreFile = "state.db"
+)
+
+// ClusterBackup writes a gzipped tar archive to w, which contains the config the cluster was created with,
+// its token and a copy of the server's SQLite datastore, so that the cluster can be re-created from it on another machine.
+// Only clusters with a single server and the default SQLite datastore are supported.
+// The cluster is stopped while the datastore is copied and restarted afterwards, if it was running before.
+func ClusterBackup(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster, w io.Writer) (_ *k3d.ClusterBackup, err error) {
+	unlock, err := ClusterLock(ctx, cluster.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	cluster, err = ClusterGet(ctx, runtime, cluster)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster: %w", err)
+	}
+
+	servers := []*k3d.Node{}
+	wasRunning := false
+	for _, node := range cluster.Nodes {
+		if node.Role == k3d.ServerRole {
+			servers = append(servers, node)
+		}
+		wasRunning = wasRunning || node.State.Running
+	}
+	if len(servers) != 1 {
+		return nil, fmt.Errorf("cluster '%s' has %d servers, but backups are only supported for a single server with the default SQLite datastore", cluster.Name, len(servers))
+	}
+	server := servers[0]
+
+	_, recordedConfig, ok := ClusterRecordedConfig(cluster)
+	if !ok {
+		return nil, fmt.Errorf("cluster '%s' has no recorded config: it was created with an older version of k3d or not via 'k3d cluster create'", cluster.Name)
+	}
+
+	backup := &k3d.ClusterBackup{
+		Cluster:    cluster.Name,
+		Created:    time.Now(),
+		K3dVersion: version.GetVersion(),
+		K3sImage:   server.Image,
+		Token:      cluster.Token,
+	}
+
+	// SQLite is only consistent on disk, when k3s is not running
+	if wasRunning {
+		if err := clusterStop(ctx, runtime, cluster); err != nil {
+			return nil, fmt.Errorf("failed to stop cluster for backup: %w", err)
+		}
+		defer func() {
+			if restartErr := clusterRestart(context.Background(), runtime, cluster); restartErr != nil {
+				if err == nil {
+					err = fmt.Errorf("backup created, but failed to restart cluster: %w", restartErr)
+				} else {
+					l.Log().Warnf("Failed to restart cluster '%s' after failed backup: %v", cluster.Name, restartErr)
+				}
+			}
+		}()
+	}
+
+	datastore, err := readFileFromNode(ctx, runtime, server, k3d.DefaultServerDatastorePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the SQLite datastore of server '%s' (etcd and external datastores are not supported): %w", server.Name, err)
+	}
+
+	metadata, err := yaml.Marshal(backup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal backup metadata: %w", err)
+	}
+
+	if err := writeBackupArchive(w, map[string][]byte{
+		backupMetadataFile:  metadata,
+		backupConfigFile:    []byte(recordedConfig),
+		backupDatastoreFile: datastore,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to write backup archive: %w", err)
+	}
+
+	return backup, nil
+}
+
+// ClusterBackupExtract extracts a backup archive written by ClusterBackup into dir
+// and returns its metadata along with the paths of the extracted config file and datastore
+func ClusterBackupExtract(r io.Reader, dir string) (*k3d.ClusterBackup, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup archive: %w", err)
+	}
+	defer gz.Close()
+
+	found := map[string]bool{}
+	backup := &k3d.ClusterBackup{}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read backup archive: %w", err)
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read '%s' from backup archive: %w", header.Name, err)
+		}
+
+		switch header.Name {
+		case backupMetadataFile:
+			if err := yaml.Unmarshal(content, backup); err != nil {
+				return nil, fmt.Errorf("failed to parse backup metadata: %w", err)
+			}
+		case backupConfigFile, backupDatastoreFile:
+			if err := ioutil.WriteFile(path.Join(dir, header.Name), content, 0600); err != nil {
+				return nil, fmt.Errorf("failed to extract '%s': %w", header.Name, err)
+			}
+		default:
+			l.Log().Debugf("Ignoring unknown file '%s' in backup archive", header.Name)
+			continue
+		}
+		found[header.Name] = true
+	}
+
+	for _, name := range []string{backupMetadataFile, backupConfigFile, backupDatastoreFile} {
+		if !found[name] {
+			return nil, fmt.Errorf("backup archive is missing '%s': not a backup written by 'k3d backup'", name)
+		}
+	}
+
+	backup.ConfigPath = path.Join(dir, backupConfigFile)
+	backup.DatastorePath = path.Join(dir, backupDatastoreFile)
+	return backup, nil
+}
+
+// writeBackupArchive writes the given files to w as a gzipped tar archive (in a fixed order, metadata first)
+func writeBackupArchive(w io.Writer, files map[string][]byte) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, name := range []string{backupMetadataFile, backupConfigFile, backupDatastoreFile} {
+		content := files[name]
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0600,
+			Size:    int64(len(content)),
+			ModTime: time.Now(),
+		}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(content); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// readFileFromNode returns the content of a single (regular) file in a node
+func readFileFromNode(ctx context.Context, runtime runtimes.Runtime, node *k3d.Node, filePath string) ([]byte, error) {
+	reader, err := runtime.ReadFromNode(ctx, filePath, node)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	// the runtime returns a tar stream
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("'%s' is not a regular file", filePath)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag == tar.TypeReg {
+			return ioutil.ReadAll(tr)
+		}
+	}
+}
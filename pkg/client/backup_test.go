@@ -0,0 +1,123 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestClusterBackupExtract(t *testing.T) {
+	written := &k3d.ClusterBackup{
+		Cluster:    "test",
+		Created:    time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC),
+		K3dVersion: "v5.0.0",
+		K3sImage:   "rancher/k3s:v1.21.5-k3s2",
+		Token:      "secret",
+	}
+	metadata, err := yaml.Marshal(written)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var archive bytes.Buffer
+	if err := writeBackupArchive(&archive, map[string][]byte{
+		backupMetadataFile:  metadata,
+		backupConfigFile:    []byte("apiVersion: k3d.io/v1alpha3\nkind: Simple\n"),
+		backupDatastoreFile: []byte("SQLite format 3\x00"),
+	}); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+
+	dir := t.TempDir()
+	read, err := ClusterBackupExtract(&archive, dir)
+	if err != nil {
+		t.Fatalf("failed to extract archive: %v", err)
+	}
+	if read.Cluster != written.Cluster || read.Token != written.Token || read.K3sImage != written.K3sImage || !read.Created.Equal(written.Created) {
+		t.Errorf("metadata changed in round trip:\nwritten: %+v\nread:    %+v", written, read)
+	}
+	if datastore, err := ioutil.ReadFile(read.DatastorePath); err != nil || string(datastore) != "SQLite format 3\x00" {
+		t.Errorf("expected the datastore to be extracted to '%s', got '%s' (%v)", read.DatastorePath, datastore, err)
+	}
+	if config, err := ioutil.ReadFile(read.ConfigPath); err != nil || !strings.Contains(string(config), "kind: Simple") {
+		t.Errorf("expected the config to be extracted to '%s', got '%s' (%v)", read.ConfigPath, config, err)
+	}
+}
+
+func TestClusterBackupExtractIncomplete(t *testing.T) {
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	metadata := []byte("cluster: test\n")
+	if err := tw.WriteHeader(&tar.Header{Name: backupMetadataFile, Mode: 0600, Size: int64(len(metadata))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(metadata); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ClusterBackupExtract(&archive, t.TempDir()); err == nil || !strings.Contains(err.Error(), backupConfigFile) {
+		t.Errorf("expected an error for an archive without config, got %v", err)
+	}
+	if _, err := ClusterBackupExtract(strings.NewReader("not an archive"), t.TempDir()); err == nil {
+		t.Errorf("expected an error for an invalid archive")
+	}
+}
+
+func TestClusterBackupRequiresSingleServer(t *testing.T) {
+	useTempConfigDir(t)
+
+	runtime := &fakeRuntime{
+		nodes: []*k3d.Node{
+			newFakeNode("test", "k3d-test-server-0", k3d.ServerRole, true),
+			newFakeNode("test", "k3d-test-server-1", k3d.ServerRole, true),
+		},
+	}
+
+	var archive bytes.Buffer
+	if _, err := ClusterBackup(context.Background(), runtime, &k3d.Cluster{Name: "test"}, &archive); err == nil || !strings.Contains(err.Error(), "2 servers") {
+		t.Fatalf("expected backup of a cluster with two servers to fail, got %v", err)
+	}
+	if calls := runtime.callsOf("StopNode"); len(calls) != 0 {
+		t.Errorf("expected no nodes to be stopped, got %v", calls)
+	}
+	if archive.Len() != 0 {
+		t.Errorf("expected nothing to be written")
+	}
+}
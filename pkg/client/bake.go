@@ -0,0 +1,160 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+const (
+	// bakedImagesArchive holds the container images of a baked node inside the image (outside of the k3s volumes)
+	bakedImagesArchive = "/var/lib/k3d/baked-images.tar"
+	// bakedEntrypoint makes k3s import the baked container images on startup
+	bakedEntrypoint = "/bin/k3d-entrypoint-baked.sh"
+)
+
+// bakedEntrypointScript links the baked images archive into the k3s images directory (a volume, so it's empty in new nodes), where k3s imports it from.
+// It's the entrypoint of baked images, but also works as one of the k3d entrypoint scripts, which run without arguments and must not start k3s.
+var bakedEntrypointScript = fmt.Sprintf(`#!/bin/sh
+
+set -o errexit
+
+mkdir -p /var/lib/rancher/k3s/agent/images
+if [ -f %[1]s ]; then
+  ln -sf %[1]s /var/lib/rancher/k3s/agent/images/k3d-baked-images.tar
+fi
+
+if [ $# -gt 0 ]; then
+  exec /bin/k3s "$@"
+fi
+`, bakedImagesArchive)
+
+// bakeExcludedPaths are files of the node which are specific to it or its cluster and are thus not baked into the image
+var bakeExcludedPaths = []string{
+	".dockerenv",
+	"etc/rancher/k3s/k3s.yaml",
+	"etc/rancher/k3s/registries.yaml",
+	"etc/rancher/node/password",
+	strings.TrimPrefix(bakedEntrypoint, "/"),
+}
+
+// NodeBake creates an image from a running k3s node, which can be passed to '--image' to create nodes with the same
+// payload (preloaded container images, extra packages, ...) without preparing them again.
+// The node's filesystem is exported instead of committing the container, so that the image doesn't inherit the node's
+// configuration (cluster labels, token in the environment, ...), but only the one of the image the node was created from.
+// The container images known to k3s are stored in a volume, so they're exported to an archive inside the image,
+// which k3s imports when a node of the new image starts.
+func NodeBake(ctx context.Context, runtime runtimes.Runtime, node *k3d.Node, image string) error {
+	node, err := NodeGet(ctx, runtime, node)
+	if err != nil {
+		return fmt.Errorf("failed to get node: %w", err)
+	}
+	if node.Role != k3d.ServerRole && node.Role != k3d.AgentRole {
+		return fmt.Errorf("node '%s' is no k3s node (role '%s'), only servers and agents can be baked", node.Name, node.Role)
+	}
+	if !node.State.Running {
+		return fmt.Errorf("node '%s' is not running, but the container images have to be exported from a running node", node.Name)
+	}
+
+	l.Log().Infof("Exporting container images of node '%s'...", node.Name)
+	exportCmd := fmt.Sprintf(`mkdir -p $(dirname %[1]s) && ctr -n k8s.io images ls -q | grep -v -e '^sha256:' -e '@sha256:' | xargs -r ctr -n k8s.io images export %[1]s`, bakedImagesArchive)
+	if err := runtime.ExecInNode(ctx, node, []string{"sh", "-c", exportCmd}); err != nil {
+		return fmt.Errorf("failed to export container images of node '%s': %w", node.Name, err)
+	}
+	defer func() {
+		if err := runtime.ExecInNode(context.Background(), node, []string{"rm", "-f", bakedImagesArchive}); err != nil {
+			l.Log().Warnf("Failed to remove '%s' from node '%s': %v", bakedImagesArchive, node.Name, err)
+		}
+	}()
+
+	l.Log().Infof("Baking node '%s' into image '%s'...", node.Name, image)
+	archive, err := runtime.ExportNode(ctx, node)
+	if err != nil {
+		return fmt.Errorf("failed to export node '%s': %w", node.Name, err)
+	}
+	defer archive.Close()
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(bakeNodeArchive(archive, writer))
+	}()
+	changes := []string{
+		fmt.Sprintf(`ENTRYPOINT ["%s"]`, bakedEntrypoint),
+		fmt.Sprintf("LABEL %s=%q", k3d.LabelNodeBakedFrom, node.Name),
+	}
+	if err := runtime.ImportImageFromArchive(ctx, reader, image, node.Image, changes); err != nil {
+		reader.CloseWithError(err) // unblock the archive filter
+		return fmt.Errorf("failed to create image '%s': %w", image, err)
+	}
+	return nil
+}
+
+// bakeNodeArchive copies the exported filesystem of a node without the node specific files and adds the baked entrypoint
+func bakeNodeArchive(src io.Reader, dst io.Writer) error {
+	excluded := map[string]bool{}
+	for _, p := range bakeExcludedPaths {
+		excluded[p] = true
+	}
+
+	tr := tar.NewReader(src)
+	tw := tar.NewWriter(dst)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read node archive: %w", err)
+		}
+		if excluded[strings.TrimPrefix(header.Name, "/")] {
+			continue
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write image archive: %w", err)
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return fmt.Errorf("failed to write image archive: %w", err)
+		}
+	}
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name:     strings.TrimPrefix(bakedEntrypoint, "/"),
+		Mode:     0755,
+		Size:     int64(len(bakedEntrypointScript)),
+		Typeflag: tar.TypeReg,
+	}); err != nil {
+		return fmt.Errorf("failed to write image archive: %w", err)
+	}
+	if _, err := io.WriteString(tw, bakedEntrypointScript); err != nil {
+		return fmt.Errorf("failed to write image archive: %w", err)
+	}
+	return tw.Close()
+}
@@ -0,0 +1,133 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// testArchive returns a tar archive with the given files (name -> content)
+func testArchive(t *testing.T, files ...string) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for _, name := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(name))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(tw, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// archiveFiles returns the names of the files in a tar archive and the content of the baked entrypoint
+func archiveFiles(t *testing.T, archive []byte) ([]string, string) {
+	t.Helper()
+	names := []string{}
+	entrypoint := ""
+	tr := tar.NewReader(bytes.NewReader(archive))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, header.Name)
+		if "/"+header.Name == bakedEntrypoint {
+			content, err := io.ReadAll(tr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			entrypoint = string(content)
+		}
+	}
+	return names, entrypoint
+}
+
+func TestBakeNodeArchive(t *testing.T) {
+	src := testArchive(t, "bin/k3s", ".dockerenv", "etc/rancher/k3s/registries.yaml", "etc/rancher/k3s/k3s.yaml", "etc/rancher/node/password", "usr/bin/jq", "bin/k3d-entrypoint-baked.sh")
+
+	dst := &bytes.Buffer{}
+	if err := bakeNodeArchive(bytes.NewReader(src), dst); err != nil {
+		t.Fatal(err)
+	}
+
+	names, entrypoint := archiveFiles(t, dst.Bytes())
+	expected := []string{"bin/k3s", "usr/bin/jq", "bin/k3d-entrypoint-baked.sh"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected files %v, got %v", expected, names)
+	}
+	if entrypoint != bakedEntrypointScript {
+		t.Errorf("expected the baked entrypoint script, got '%s'", entrypoint)
+	}
+}
+
+func TestNodeBake(t *testing.T) {
+	server := newFakeNode("test", "k3d-test-server-0", k3d.ServerRole, true)
+	server.Image = "rancher/k3s:latest"
+	stopped := newFakeNode("test", "k3d-test-agent-0", k3d.AgentRole, false)
+	lb := newFakeNode("test", "k3d-test-serverlb", k3d.LoadBalancerRole, true)
+	runtime := &fakeRuntime{
+		nodes:   []*k3d.Node{server, stopped, lb},
+		exports: map[string][]byte{server.Name: testArchive(t, "bin/k3s")},
+	}
+
+	if err := NodeBake(context.Background(), runtime, &k3d.Node{Name: server.Name}, "myorg/k3s-custom:dev"); err != nil {
+		t.Fatal(err)
+	}
+
+	if imports := runtime.callsOf("ImportImageFromArchive"); !reflect.DeepEqual(imports, []string{"myorg/k3s-custom:dev<-rancher/k3s:latest"}) {
+		t.Errorf("expected the image to be based on the node's image, got %v", imports)
+	}
+	names, _ := archiveFiles(t, runtime.written["image:myorg/k3s-custom:dev"])
+	if !reflect.DeepEqual(names, []string{"bin/k3s", "bin/k3d-entrypoint-baked.sh"}) {
+		t.Errorf("unexpected image content %v", names)
+	}
+	if changes := string(runtime.written["changes:myorg/k3s-custom:dev"]); !strings.Contains(changes, `ENTRYPOINT ["/bin/k3d-entrypoint-baked.sh"]`) || !strings.Contains(changes, k3d.LabelNodeBakedFrom+`="k3d-test-server-0"`) {
+		t.Errorf("unexpected image changes '%s'", changes)
+	}
+	execs := runtime.execs[server.Name]
+	if len(execs) != 2 || !strings.Contains(execs[0], "images export "+bakedImagesArchive) || execs[1] != "rm -f "+bakedImagesArchive {
+		t.Errorf("expected the container images to be exported and removed afterwards, got %v", execs)
+	}
+
+	for _, node := range []*k3d.Node{stopped, lb} {
+		if err := NodeBake(context.Background(), runtime, &k3d.Node{Name: node.Name}, "myorg/k3s-custom:dev"); err == nil {
+			t.Errorf("expected baking node '%s' to fail", node.Name)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	k3drt "github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// CacheVolumeName returns the name of the runtime volume backing a cache volume of the given cluster
+func CacheVolumeName(clusterName string, name string) string {
+	return fmt.Sprintf("%s-%s-cache-%s", k3d.DefaultObjectNamePrefix, clusterName, name)
+}
+
+// ClusterPrepCacheVolumes creates the cluster's cache volumes (unless they exist from an earlier cluster of the same name)
+// and mounts them into all server and agent nodes.
+// The volumes don't carry the cluster label, so neither deleting the cluster nor 'k3d prune --orphans' removes them.
+func ClusterPrepCacheVolumes(ctx context.Context, runtime k3drt.Runtime, cluster *k3d.Cluster, cacheVolumes []k3d.CacheVolume) error {
+	for _, cacheVolume := range cacheVolumes {
+		volume := CacheVolumeName(cluster.Name, cacheVolume.Name)
+		if _, err := runtime.GetVolume(volume); err != nil {
+			if err := runtime.CreateVolume(ctx, volume, map[string]string{k3d.LabelCacheVolume: cacheVolume.Name, k3d.LabelCacheVolumeCluster: cluster.Name}); err != nil {
+				return fmt.Errorf("failed to create cache volume '%s': %w", volume, err)
+			}
+			l.Log().Infof("Created cache volume '%s'", volume)
+		} else {
+			l.Log().Infof("Reusing cache volume '%s'", volume)
+		}
+
+		for _, node := range cluster.Nodes {
+			if node.Role != k3d.ServerRole && node.Role != k3d.AgentRole {
+				continue
+			}
+			if hasVolumeMountedAt(node.Volumes, cacheVolume.Path) {
+				l.Log().Warnf("Not mounting cache volume '%s' into node '%s', as there's a volume mounted at '%s' already", cacheVolume.Name, node.Name, cacheVolume.Path)
+				continue
+			}
+			node.Volumes = append(node.Volumes, fmt.Sprintf("%s:%s", volume, cacheVolume.Path))
+		}
+	}
+	return nil
+}
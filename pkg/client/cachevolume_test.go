@@ -0,0 +1,62 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestClusterPrepCacheVolumes(t *testing.T) {
+	server := newFakeNode("test", "k3d-test-server-0", k3d.ServerRole, false)
+	agent := newFakeNode("test", "k3d-test-agent-0", k3d.AgentRole, false)
+	agent.Volumes = []string{"/host/npm:/root/.npm"}
+	lb := newFakeNode("test", "k3d-test-serverlb", k3d.LoadBalancerRole, false)
+
+	// the Go module cache exists from an earlier cluster of the same name
+	runtime := &fakeRuntime{volumes: map[string]map[string]string{"k3d-test-cache-gomod": {}}}
+	cluster := &k3d.Cluster{Name: "test", Nodes: []*k3d.Node{server, agent, lb}}
+	cacheVolumes := []k3d.CacheVolume{{Name: "gomod", Path: "/root/go/pkg/mod"}, {Name: "npm", Path: "/root/.npm"}}
+
+	if err := ClusterPrepCacheVolumes(context.Background(), runtime, cluster, cacheVolumes); err != nil {
+		t.Fatal(err)
+	}
+
+	if created := runtime.callsOf("CreateVolume"); !reflect.DeepEqual(created, []string{"k3d-test-cache-npm"}) {
+		t.Errorf("expected only the missing cache volume to be created, got %v", created)
+	}
+	if labels := runtime.volumes["k3d-test-cache-npm"]; labels[k3d.LabelCacheVolume] != "npm" || labels[k3d.LabelClusterName] != "" {
+		t.Errorf("expected the cache volume to be labeled with its name but not bound to the cluster, got %v", labels)
+	}
+	if expected := []string{"k3d-test-cache-gomod:/root/go/pkg/mod", "k3d-test-cache-npm:/root/.npm"}; !reflect.DeepEqual(server.Volumes, expected) {
+		t.Errorf("expected the server to mount %v, got %v", expected, server.Volumes)
+	}
+	if expected := []string{"/host/npm:/root/.npm", "k3d-test-cache-gomod:/root/go/pkg/mod"}; !reflect.DeepEqual(agent.Volumes, expected) {
+		t.Errorf("expected the agent to keep its own volume at /root/.npm, got %v", agent.Volumes)
+	}
+	if len(lb.Volumes) != 0 {
+		t.Errorf("expected no cache volumes in the loadbalancer, got %v", lb.Volumes)
+	}
+}
@@ -0,0 +1,186 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/rancher/k3d/v5/pkg/util"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// certificateListingMarker separates the single files in the output of the certificate listing command
+const certificateListingMarker = "==> "
+
+// certificateRotateScript moves all leaf certificates (i.e. everything but the CAs and the service account key) of a k3s server
+// to a backup directory, so that k3s regenerates them from its CAs on the next start (like `k3s certificate rotate`)
+const certificateRotateScript = `set -e
+cd /var/lib/rancher/k3s/server/tls
+backup="../tls-backup-$(date +%s)"
+for f in client-* serving-* etcd/client.* etcd/server-client.* etcd/peer-server-client.* dynamic-cert.json; do
+  [ -e "$f" ] || continue
+  case "$f" in *-ca.*) continue;; esac
+  mkdir -p "$backup/$(dirname "$f")"
+  mv "$f" "$backup/$f"
+done
+echo "$backup"`
+
+// ClusterCertificatesGet lists the certificates generated by k3s in all server and agent nodes of the cluster
+func ClusterCertificatesGet(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster) ([]*k3d.CertificateInfo, error) {
+	cluster, err := ClusterGet(ctx, runtime, cluster)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster: %w", err)
+	}
+
+	var globs []string
+	for _, dir := range k3d.CertificateDirectories {
+		globs = append(globs, dir+"/*.crt")
+	}
+	listCmd := fmt.Sprintf(`for f in %s; do [ -f "$f" ] && echo "%s$f" && cat "$f"; done; true`, strings.Join(globs, " "), certificateListingMarker)
+
+	certs := []*k3d.CertificateInfo{}
+	for _, node := range cluster.Nodes {
+		if node.Role != k3d.ServerRole && node.Role != k3d.AgentRole {
+			continue
+		}
+		if !node.State.Running {
+			l.Log().Warnf("Node '%s' is not running, skipping", node.Name)
+			continue
+		}
+
+		logreader, err := runtime.ExecInNodeGetLogs(ctx, node, []string{"sh", "-c", listCmd})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list certificates in node '%s': %w", node.Name, err)
+		}
+		out, err := ioutil.ReadAll(logreader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read certificate listing of node '%s': %w", node.Name, err)
+		}
+
+		nodeCerts, err := parseCertificateListing(node.Name, out)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, nodeCerts...)
+	}
+
+	return certs, nil
+}
+
+// parseCertificateListing parses the output of the certificate listing command (marker line with the file path, followed by the PEM content)
+func parseCertificateListing(nodeName string, out []byte) ([]*k3d.CertificateInfo, error) {
+	certs := []*k3d.CertificateInfo{}
+
+	// TTY output uses CRLF line endings
+	files := strings.Split(strings.ReplaceAll(string(out), "\r\n", "\n"), certificateListingMarker)
+	for _, file := range files {
+		file = strings.TrimSpace(file)
+		if file == "" {
+			continue
+		}
+		split := strings.SplitN(file, "\n", 2)
+		if len(split) != 2 {
+			continue
+		}
+		path, rest := split[0], []byte(split[1])
+
+		// only the first certificate of a bundle is the one the file is about, the rest are the issuing CAs
+		block, _ := pem.Decode(rest)
+		if block == nil || block.Type != "CERTIFICATE" {
+			l.Log().Debugf("No PEM certificate found in '%s' of node '%s'", path, nodeName)
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate '%s' of node '%s': %w", path, nodeName, err)
+		}
+
+		certs = append(certs, &k3d.CertificateInfo{
+			Node:      nodeName,
+			File:      path,
+			Subject:   cert.Subject.String(),
+			Issuer:    cert.Issuer.String(),
+			IsCA:      cert.IsCA,
+			NotBefore: cert.NotBefore,
+			NotAfter:  cert.NotAfter,
+		})
+	}
+
+	return certs, nil
+}
+
+// ClusterCertificatesRotate makes k3s regenerate all leaf certificates of the cluster from its CAs:
+// the leaf certificates of all servers are moved to a backup directory and the cluster is restarted.
+// Note: the admin client certificate in existing kubeconfigs is rotated as well, so they have to be refreshed afterwards.
+func ClusterCertificatesRotate(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster) error {
+	unlock, err := ClusterLock(ctx, cluster.Name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cluster, err = ClusterGet(ctx, runtime, cluster)
+	if err != nil {
+		return fmt.Errorf("failed to get cluster: %w", err)
+	}
+
+	// the serving certificate is cached in a secret, which k3s would otherwise use to restore the old certificate.
+	// If the certificates already expired, we can't reach the API anymore, but then k3s won't re-use the cached one either.
+	if core, err := KubeRESTClient(ctx, runtime, cluster, "v1"); err != nil {
+		l.Log().Warnf("Failed to connect to the Kubernetes API, not removing the cached serving certificate: %v", err)
+	} else if err := core.Delete().Namespace("kube-system").Resource("secrets").Name("k3s-serving").Timeout(10 * time.Second).Do(ctx).Error(); err != nil && !apierrors.IsNotFound(err) {
+		l.Log().Warnf("Failed to remove the cached serving certificate: %v", err)
+	}
+
+	for _, node := range util.FilterNodesByRole(cluster.Nodes, k3d.ServerRole) {
+		if !node.State.Running {
+			if err := runtime.StartNode(ctx, node); err != nil {
+				return fmt.Errorf("failed to start node '%s' to rotate its certificates: %w", node.Name, err)
+			}
+		}
+		logreader, err := runtime.ExecInNodeGetLogs(ctx, node, []string{"sh", "-c", certificateRotateScript})
+		if err != nil {
+			return fmt.Errorf("failed to move certificates in node '%s': %w", node.Name, err)
+		}
+		backup, _ := ioutil.ReadAll(logreader)
+		l.Log().Infof("Moved certificates of node '%s' to '%s'", node.Name, strings.TrimSpace(string(backup)))
+	}
+
+	l.Log().Infoln("Restarting cluster to regenerate the certificates...")
+	if err := clusterStop(ctx, runtime, cluster); err != nil {
+		return fmt.Errorf("failed to stop cluster: %w", err)
+	}
+	if err := clusterRestart(ctx, runtime, cluster); err != nil {
+		return fmt.Errorf("failed to restart cluster: %w", err)
+	}
+
+	return nil
+}
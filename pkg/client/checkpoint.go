@@ -0,0 +1,520 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/rancher/k3d/v5/pkg/util"
+	"gopkg.in/yaml.v2"
+)
+
+const checkpointMetadataFile = "checkpoint.yaml"
+
+// checkpoint names are used as image tags
+var checkpointNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,63}$`)
+
+// ClusterCheckpointCreate saves the state of all k3s nodes of a cluster:
+// the node containers are committed to new images and the k3s data volumes are archived to the k3d config directory
+// The nodes are stopped during the checkpoint to get a consistent state and restarted afterwards, if they were running before
+// If anything fails, the images and archives created so far are removed again.
+func ClusterCheckpointCreate(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster, name string) (_ *k3d.ClusterCheckpoint, err error) {
+	if !checkpointNameRegexp.MatchString(name) {
+		return nil, fmt.Errorf("invalid checkpoint name '%s': must match %s", name, checkpointNameRegexp.String())
+	}
+
+	unlock, err := ClusterLock(ctx, cluster.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	cluster, err = ClusterGet(ctx, runtime, cluster)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster: %w", err)
+	}
+
+	checkpointDir, err := checkpointDirectory(cluster.Name, name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(checkpointDir); err == nil {
+		return nil, fmt.Errorf("checkpoint '%s' of cluster '%s' already exists in '%s'", name, cluster.Name, checkpointDir)
+	}
+	if err := os.MkdirAll(checkpointDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint directory '%s': %w", checkpointDir, err)
+	}
+
+	wasRunning := false
+	for _, node := range cluster.Nodes {
+		wasRunning = wasRunning || node.State.Running
+	}
+
+	checkpoint := &k3d.ClusterCheckpoint{
+		Name:    name,
+		Cluster: cluster.Name,
+		Created: time.Now(),
+	}
+
+	// roll back everything on failure, so that a failed checkpoint leaves no traces and the cluster as it was
+	completed := false
+	defer func() {
+		if err == nil || completed {
+			return
+		}
+		for _, checkpointNode := range checkpoint.Nodes {
+			if deleteErr := runtime.DeleteImage(context.Background(), checkpointNode.Image); deleteErr != nil {
+				l.Log().Warnf("Failed to delete checkpoint image '%s': %v", checkpointNode.Image, deleteErr)
+			}
+		}
+		if removeErr := os.RemoveAll(checkpointDir); removeErr != nil {
+			l.Log().Warnf("Failed to remove checkpoint directory '%s': %v", checkpointDir, removeErr)
+		}
+		if wasRunning {
+			if restartErr := clusterRestart(context.Background(), runtime, cluster); restartErr != nil {
+				l.Log().Warnf("Failed to restart cluster '%s' after failed checkpoint: %v", cluster.Name, restartErr)
+			}
+		}
+	}()
+
+	if wasRunning {
+		if err = clusterStop(ctx, runtime, cluster); err != nil {
+			return nil, fmt.Errorf("failed to stop cluster for checkpoint: %w", err)
+		}
+	}
+
+	for _, node := range cluster.Nodes {
+		if node.Role != k3d.ServerRole && node.Role != k3d.AgentRole {
+			continue
+		}
+		l.Log().Infof("Saving node '%s'...", node.Name)
+		checkpointNode, err := nodeCheckpointCreate(ctx, runtime, node, name, checkpointDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to checkpoint node '%s': %w", node.Name, err)
+		}
+		checkpoint.Nodes = append(checkpoint.Nodes, *checkpointNode)
+	}
+
+	if err = writeCheckpointMetadata(checkpointDir, checkpoint); err != nil {
+		return nil, err
+	}
+	completed = true
+
+	if wasRunning {
+		if err := clusterRestart(ctx, runtime, cluster); err != nil {
+			return checkpoint, fmt.Errorf("checkpoint created, but failed to restart cluster: %w", err)
+		}
+	}
+
+	return checkpoint, nil
+}
+
+// nodeCheckpointCreate commits a single (stopped) node and archives its k3s data directories
+// The committed image is deleted again if archiving fails.
+func nodeCheckpointCreate(ctx context.Context, runtime runtimes.Runtime, node *k3d.Node, name string, checkpointDir string) (*k3d.CheckpointNode, error) {
+	checkpointNode := &k3d.CheckpointNode{
+		Name:     node.Name,
+		Role:     node.Role,
+		Image:    fmt.Sprintf("%s:checkpoint-%s", node.Name, name),
+		Archives: map[string]string{},
+	}
+
+	if err := runtime.CommitNode(ctx, node, checkpointNode.Image, map[string]string{k3d.LabelCheckpoint: name, k3d.LabelClusterName: node.RuntimeLabels[k3d.LabelClusterName]}); err != nil {
+		return nil, err
+	}
+
+	for _, p := range k3d.CheckpointPaths {
+		archiveName := fmt.Sprintf("%s%s.tar", node.Name, strings.ReplaceAll(p, "/", "_"))
+		if err := func() error {
+			reader, err := runtime.ReadFromNode(ctx, p, node)
+			if err != nil {
+				return fmt.Errorf("failed to read '%s': %w", p, err)
+			}
+			defer reader.Close()
+
+			archive, err := os.Create(path.Join(checkpointDir, archiveName))
+			if err != nil {
+				return fmt.Errorf("failed to create archive file: %w", err)
+			}
+			defer archive.Close()
+
+			if _, err := io.Copy(archive, reader); err != nil {
+				return fmt.Errorf("failed to archive '%s': %w", p, err)
+			}
+			return nil
+		}(); err != nil {
+			if deleteErr := runtime.DeleteImage(context.Background(), checkpointNode.Image); deleteErr != nil {
+				l.Log().Warnf("Failed to delete checkpoint image '%s': %v", checkpointNode.Image, deleteErr)
+			}
+			return nil, err
+		}
+		checkpointNode.Archives[p] = archiveName
+	}
+
+	return checkpointNode, nil
+}
+
+// writeCheckpointMetadata writes the checkpoint description next to the archives, where ClusterCheckpointGet reads it from
+func writeCheckpointMetadata(checkpointDir string, checkpoint *k3d.ClusterCheckpoint) error {
+	metadata, err := yaml.Marshal(checkpoint)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint metadata: %w", err)
+	}
+	if err := ioutil.WriteFile(path.Join(checkpointDir, checkpointMetadataFile), metadata, 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint metadata: %w", err)
+	}
+	return nil
+}
+
+// ClusterCheckpointRollback replaces the k3s nodes of a cluster with the state saved in a checkpoint
+func ClusterCheckpointRollback(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster, name string) error {
+	unlock, err := ClusterLock(ctx, cluster.Name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cluster, err = ClusterGet(ctx, runtime, cluster)
+	if err != nil {
+		return fmt.Errorf("failed to get cluster: %w", err)
+	}
+
+	checkpoint, checkpointDir, err := ClusterCheckpointGet(cluster.Name, name)
+	if err != nil {
+		return err
+	}
+
+	if err := clusterStop(ctx, runtime, cluster); err != nil {
+		return fmt.Errorf("failed to stop cluster for rollback: %w", err)
+	}
+
+	for _, checkpointNode := range checkpoint.Nodes {
+		var existing *k3d.Node
+		for _, node := range cluster.Nodes {
+			if node.Name == checkpointNode.Name {
+				existing = node
+				break
+			}
+		}
+		if existing == nil {
+			return fmt.Errorf("node '%s' from checkpoint does not exist in cluster '%s' anymore", checkpointNode.Name, cluster.Name)
+		}
+
+		l.Log().Infof("Restoring node '%s'...", existing.Name)
+		if err := nodeCheckpointRestore(ctx, runtime, existing, checkpointNode, checkpointDir); err != nil {
+			return fmt.Errorf("failed to restore node '%s': %w", existing.Name, err)
+		}
+	}
+
+	cluster, err = ClusterGet(ctx, runtime, cluster)
+	if err != nil {
+		return fmt.Errorf("failed to get cluster after rollback: %w", err)
+	}
+
+	return clusterRestart(ctx, runtime, cluster)
+}
+
+// nodeCheckpointRestore re-creates a node from its checkpoint image and restores the archived data directories
+func nodeCheckpointRestore(ctx context.Context, runtime runtimes.Runtime, existing *k3d.Node, checkpointNode k3d.CheckpointNode, checkpointDir string) error {
+	restored, err := CopyNode(ctx, existing, CopyNodeOpts{keepState: false})
+	if err != nil {
+		return err
+	}
+	restored.Image = checkpointNode.Image
+
+	// keep the current spec to re-create the node as it was, if the checkpoint image can't be used
+	original, err := CopyNode(ctx, existing, CopyNodeOpts{keepState: false})
+	if err != nil {
+		return err
+	}
+
+	if err := NodeDelete(ctx, runtime, existing, k3d.NodeDeleteOpts{SkipLBUpdate: true}); err != nil {
+		return fmt.Errorf("failed to delete current node: %w", err)
+	}
+
+	if err := NodeCreate(ctx, runtime, restored, k3d.NodeCreateOpts{}); err != nil {
+		if recreateErr := NodeCreate(context.Background(), runtime, original, k3d.NodeCreateOpts{}); recreateErr != nil {
+			return fmt.Errorf("failed to create node from checkpoint image '%s' (%v) and failed to re-create it from image '%s': %w", checkpointNode.Image, err, original.Image, recreateErr)
+		}
+		return fmt.Errorf("failed to create node from checkpoint image '%s' (re-created it from image '%s'): %w", checkpointNode.Image, original.Image, err)
+	}
+
+	for p, archiveName := range checkpointNode.Archives {
+		if err := func() error {
+			archive, err := os.Open(path.Join(checkpointDir, archiveName))
+			if err != nil {
+				return fmt.Errorf("failed to open archive: %w", err)
+			}
+			defer archive.Close()
+			// the archive contains the directory itself, so it has to be extracted into the parent directory
+			return runtime.WriteArchiveToNode(ctx, archive, path.Dir(p), restored)
+		}(); err != nil {
+			return fmt.Errorf("failed to restore '%s': %w", p, err)
+		}
+	}
+
+	return nil
+}
+
+// ClusterCheckpointGet reads the metadata of an existing checkpoint and returns it along with the directory it's stored in
+func ClusterCheckpointGet(clusterName string, name string) (*k3d.ClusterCheckpoint, string, error) {
+	checkpointDir, err := checkpointDirectory(clusterName, name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	metadata, err := ioutil.ReadFile(path.Join(checkpointDir, checkpointMetadataFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, "", fmt.Errorf("checkpoint '%s' of cluster '%s' does not exist", name, clusterName)
+		}
+		return nil, "", fmt.Errorf("failed to read checkpoint metadata: %w", err)
+	}
+
+	checkpoint := &k3d.ClusterCheckpoint{}
+	if err := yaml.Unmarshal(metadata, checkpoint); err != nil {
+		return nil, "", fmt.Errorf("failed to parse checkpoint metadata: %w", err)
+	}
+
+	return checkpoint, checkpointDir, nil
+}
+
+func checkpointDirectory(clusterName string, name string) (string, error) {
+	configDir, err := util.GetConfigDirOrCreate()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config directory: %w", err)
+	}
+	return path.Join(configDir, "checkpoints", clusterName, name), nil
+}
+
+// clusterRestart starts a (stopped) cluster the same way 'k3d cluster start' does
+func clusterRestart(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster) error {
+	envInfo, err := GatherEnvironmentInfo(ctx, runtime, cluster)
+	if err != nil {
+		return fmt.Errorf("failed to gather environment info: %w", err)
+	}
+	return clusterStart(ctx, runtime, cluster, k3d.ClusterStartOpts{WaitForServer: true, EnvironmentInfo: envInfo})
+}
+
+// frozenCluster records which nodes were suspended by ClusterFreeze, so that ClusterThaw only resumes those
+// (nodes that were stopped before have no checkpoint to restore)
+type frozenCluster struct {
+	Nodes         []string `yaml:"nodes"`         // k3s nodes frozen via CRIU checkpoint
+	LoadBalancers []string `yaml:"loadbalancers"` // helper nodes that were simply stopped
+}
+
+// frozenClusterPath returns the path of the freeze record of a cluster in the k3d config directory
+func frozenClusterPath(clusterName string) (string, error) {
+	configDir, err := util.GetConfigDirOrCreate()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config directory: %w", err)
+	}
+	return path.Join(configDir, "frozen", fmt.Sprintf("%s.yaml", clusterName)), nil
+}
+
+func writeFrozenCluster(clusterName string, frozen *frozenCluster) error {
+	recordPath, err := frozenClusterPath(clusterName)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(path.Dir(recordPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for freeze record: %w", err)
+	}
+	content, err := yaml.Marshal(frozen)
+	if err != nil {
+		return fmt.Errorf("failed to marshal freeze record: %w", err)
+	}
+	if err := ioutil.WriteFile(recordPath, content, 0644); err != nil {
+		return fmt.Errorf("failed to write freeze record '%s': %w", recordPath, err)
+	}
+	return nil
+}
+
+func readFrozenCluster(clusterName string) (*frozenCluster, error) {
+	recordPath, err := frozenClusterPath(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	content, err := ioutil.ReadFile(recordPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("cluster '%s' was not frozen via 'k3d freeze'", clusterName)
+		}
+		return nil, fmt.Errorf("failed to read freeze record '%s': %w", recordPath, err)
+	}
+	frozen := &frozenCluster{}
+	if err := yaml.Unmarshal(content, frozen); err != nil {
+		return nil, fmt.Errorf("failed to parse freeze record '%s': %w", recordPath, err)
+	}
+	return frozen, nil
+}
+
+// ClusterFreeze suspends a running cluster including the memory state of all processes (CRIU-based, requires experimental runtime features)
+// k3s nodes are checkpointed, while helper nodes (e.g. the loadbalancer) are simply stopped
+// If a node fails to freeze, the nodes frozen so far are resumed again.
+func ClusterFreeze(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster) (err error) {
+	if err := checkFreezeSupported(runtime); err != nil {
+		return err
+	}
+
+	unlock, err := ClusterLock(ctx, cluster.Name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cluster, err = ClusterGet(ctx, runtime, cluster)
+	if err != nil {
+		return fmt.Errorf("failed to get cluster: %w", err)
+	}
+
+	// freeze the loadbalancer and agents before the servers, so no requests hit frozen servers
+	nodes := append(util.FilterNodesByRole(cluster.Nodes, k3d.LoadBalancerRole), util.FilterNodesByRole(cluster.Nodes, k3d.AgentRole)...)
+	nodes = append(nodes, util.FilterNodesByRole(cluster.Nodes, k3d.ServerRole)...)
+
+	frozen := &frozenCluster{}
+	defer func() {
+		if err == nil {
+			return
+		}
+		if thawErr := thawNodes(context.Background(), runtime, cluster, frozen); thawErr != nil {
+			l.Log().Warnf("Failed to resume the already frozen nodes of cluster '%s': %v", cluster.Name, thawErr)
+		}
+	}()
+
+	for _, node := range nodes {
+		if !node.State.Running {
+			l.Log().Debugf("Node '%s' is not running, skipping", node.Name)
+			continue
+		}
+		if node.Role == k3d.LoadBalancerRole {
+			if err := runtime.StopNode(ctx, node); err != nil {
+				return fmt.Errorf("failed to stop loadbalancer '%s': %w", node.Name, err)
+			}
+			frozen.LoadBalancers = append(frozen.LoadBalancers, node.Name)
+			continue
+		}
+		if err := runtime.FreezeNode(ctx, node, k3d.DefaultFreezeCheckpointID); err != nil {
+			return fmt.Errorf("failed to freeze node '%s': %w", node.Name, err)
+		}
+		frozen.Nodes = append(frozen.Nodes, node.Name)
+	}
+
+	return writeFrozenCluster(cluster.Name, frozen)
+}
+
+// ClusterThaw resumes the nodes of a cluster frozen by ClusterFreeze
+func ClusterThaw(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster) error {
+	if err := checkFreezeSupported(runtime); err != nil {
+		return err
+	}
+
+	unlock, err := ClusterLock(ctx, cluster.Name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cluster, err = ClusterGet(ctx, runtime, cluster)
+	if err != nil {
+		return fmt.Errorf("failed to get cluster: %w", err)
+	}
+
+	frozen, err := readFrozenCluster(cluster.Name)
+	if err != nil {
+		return err
+	}
+
+	if err := thawNodes(ctx, runtime, cluster, frozen); err != nil {
+		// remember what's still frozen, so that thawing can be retried
+		if writeErr := writeFrozenCluster(cluster.Name, frozen); writeErr != nil {
+			l.Log().Warnf("Failed to update freeze record: %v", writeErr)
+		}
+		return err
+	}
+
+	recordPath, err := frozenClusterPath(cluster.Name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(recordPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove freeze record '%s': %w", recordPath, err)
+	}
+	return nil
+}
+
+// thawNodes resumes the given frozen nodes in reverse freeze order (servers, agents, loadbalancer)
+// Resumed nodes are removed from the record.
+func thawNodes(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster, frozen *frozenCluster) error {
+	byName := map[string]*k3d.Node{}
+	for _, node := range cluster.Nodes {
+		byName[node.Name] = node
+	}
+
+	for len(frozen.Nodes) > 0 {
+		name := frozen.Nodes[len(frozen.Nodes)-1]
+		if node, ok := byName[name]; ok {
+			if err := runtime.ThawNode(ctx, node, k3d.DefaultFreezeCheckpointID); err != nil {
+				return fmt.Errorf("failed to thaw node '%s': %w", name, err)
+			}
+		} else {
+			l.Log().Warnf("Frozen node '%s' does not exist anymore, skipping", name)
+		}
+		frozen.Nodes = frozen.Nodes[:len(frozen.Nodes)-1]
+	}
+
+	for len(frozen.LoadBalancers) > 0 {
+		name := frozen.LoadBalancers[len(frozen.LoadBalancers)-1]
+		if node, ok := byName[name]; ok {
+			if err := NodeStart(ctx, runtime, node, &k3d.NodeStartOpts{Wait: true, NodeHooks: node.HookActions}); err != nil {
+				return fmt.Errorf("failed to start loadbalancer '%s': %w", name, err)
+			}
+		} else {
+			l.Log().Warnf("Loadbalancer '%s' does not exist anymore, skipping", name)
+		}
+		frozen.LoadBalancers = frozen.LoadBalancers[:len(frozen.LoadBalancers)-1]
+	}
+
+	return nil
+}
+
+func checkFreezeSupported(runtime runtimes.Runtime) error {
+	info, err := runtime.Info()
+	if err != nil {
+		return fmt.Errorf("failed to get runtime info: %w", err)
+	}
+	if !info.Experimental {
+		return fmt.Errorf("runtime '%s' does not have experimental features enabled, which are required for CRIU-based checkpoints (for docker: set '\"experimental\": true' in daemon.json and install CRIU)", info.Name)
+	}
+	return nil
+}
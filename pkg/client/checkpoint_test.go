@@ -0,0 +1,190 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestCheckpointNameRegexp(t *testing.T) {
+	valid := []string{"before-upgrade", "v1.2.3", "_tmp", "a", strings.Repeat("a", 64)}
+	invalid := []string{"", "-leading-dash", ".leading-dot", "with space", "with/slash", "with:colon", strings.Repeat("a", 65)}
+
+	for _, name := range valid {
+		if !checkpointNameRegexp.MatchString(name) {
+			t.Errorf("expected '%s' to be a valid checkpoint name", name)
+		}
+	}
+	for _, name := range invalid {
+		if checkpointNameRegexp.MatchString(name) {
+			t.Errorf("expected '%s' to be an invalid checkpoint name", name)
+		}
+	}
+}
+
+func TestCheckpointMetadataRoundTrip(t *testing.T) {
+	useTempConfigDir(t)
+
+	checkpoint := &k3d.ClusterCheckpoint{
+		Name:    "before-upgrade",
+		Cluster: "test",
+		Created: time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC),
+		Nodes: []k3d.CheckpointNode{
+			{
+				Name:     "k3d-test-server-0",
+				Role:     k3d.ServerRole,
+				Image:    "k3d-test-server-0:checkpoint-before-upgrade",
+				Archives: map[string]string{"/var/lib/rancher/k3s": "k3d-test-server-0_var_lib_rancher_k3s.tar"},
+			},
+		},
+	}
+
+	checkpointDir, err := checkpointDirectory(checkpoint.Cluster, checkpoint.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(checkpointDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeCheckpointMetadata(checkpointDir, checkpoint); err != nil {
+		t.Fatalf("failed to write metadata: %v", err)
+	}
+
+	read, readDir, err := ClusterCheckpointGet(checkpoint.Cluster, checkpoint.Name)
+	if err != nil {
+		t.Fatalf("failed to read metadata: %v", err)
+	}
+	if readDir != checkpointDir {
+		t.Errorf("expected checkpoint directory '%s', got '%s'", checkpointDir, readDir)
+	}
+	if !reflect.DeepEqual(read, checkpoint) {
+		t.Errorf("checkpoint changed in round trip:\nwritten: %+v\nread:    %+v", checkpoint, read)
+	}
+
+	if _, _, err := ClusterCheckpointGet(checkpoint.Cluster, "missing"); err == nil {
+		t.Errorf("expected an error for a missing checkpoint")
+	}
+}
+
+func TestClusterCheckpointCreateCleansUpOnFailure(t *testing.T) {
+	useTempConfigDir(t)
+
+	runtime := &fakeRuntime{
+		nodes: []*k3d.Node{
+			newFakeNode("test", "k3d-test-server-0", k3d.ServerRole, false),
+			newFakeNode("test", "k3d-test-agent-0", k3d.AgentRole, false),
+		},
+		failOn: map[string]error{"ReadFromNode:k3d-test-agent-0": errors.New("read failed")},
+	}
+
+	if _, err := ClusterCheckpointCreate(context.Background(), runtime, &k3d.Cluster{Name: "test"}, "broken"); err == nil {
+		t.Fatalf("expected checkpoint creation to fail")
+	}
+
+	committed := runtime.callsOf("CommitNode")
+	deleted := runtime.callsOf("DeleteImage")
+	if len(committed) != 2 {
+		t.Fatalf("expected both nodes to be committed, got %v", committed)
+	}
+	sort.Strings(committed)
+	sort.Strings(deleted)
+	if !reflect.DeepEqual(committed, deleted) {
+		t.Errorf("expected all committed images %v to be deleted, got %v", committed, deleted)
+	}
+
+	checkpointDir, err := checkpointDirectory("test", "broken")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(checkpointDir); !os.IsNotExist(err) {
+		t.Errorf("expected checkpoint directory '%s' to be removed", checkpointDir)
+	}
+}
+
+func TestClusterFreezeThawOnlyFrozenNodes(t *testing.T) {
+	useTempConfigDir(t)
+
+	runtime := &fakeRuntime{
+		experimental: true,
+		nodes: []*k3d.Node{
+			newFakeNode("test", "k3d-test-server-0", k3d.ServerRole, true),
+			newFakeNode("test", "k3d-test-server-1", k3d.ServerRole, false),
+			newFakeNode("test", "k3d-test-agent-0", k3d.AgentRole, true),
+		},
+	}
+
+	if err := ClusterThaw(context.Background(), runtime, &k3d.Cluster{Name: "test"}); err == nil {
+		t.Errorf("expected thawing a cluster that was not frozen to fail")
+	}
+
+	if err := ClusterFreeze(context.Background(), runtime, &k3d.Cluster{Name: "test"}); err != nil {
+		t.Fatalf("failed to freeze cluster: %v", err)
+	}
+	if frozen := runtime.callsOf("FreezeNode"); !reflect.DeepEqual(frozen, []string{"k3d-test-agent-0", "k3d-test-server-0"}) {
+		t.Errorf("expected agents to be frozen before servers and stopped nodes to be skipped, got %v", frozen)
+	}
+
+	if err := ClusterThaw(context.Background(), runtime, &k3d.Cluster{Name: "test"}); err != nil {
+		t.Fatalf("failed to thaw cluster: %v", err)
+	}
+	if thawed := runtime.callsOf("ThawNode"); !reflect.DeepEqual(thawed, []string{"k3d-test-server-0", "k3d-test-agent-0"}) {
+		t.Errorf("expected only the frozen nodes to be thawed in reverse order, got %v", thawed)
+	}
+
+	// the record is gone after thawing
+	if err := ClusterThaw(context.Background(), runtime, &k3d.Cluster{Name: "test"}); err == nil {
+		t.Errorf("expected thawing a cluster twice to fail")
+	}
+}
+
+func TestClusterFreezeRestoresOnFailure(t *testing.T) {
+	useTempConfigDir(t)
+
+	runtime := &fakeRuntime{
+		experimental: true,
+		nodes: []*k3d.Node{
+			newFakeNode("test", "k3d-test-server-0", k3d.ServerRole, true),
+			newFakeNode("test", "k3d-test-agent-0", k3d.AgentRole, true),
+			newFakeNode("test", "k3d-test-agent-1", k3d.AgentRole, true),
+		},
+		failOn: map[string]error{"FreezeNode:k3d-test-server-0": errors.New("criu failed")},
+	}
+
+	if err := ClusterFreeze(context.Background(), runtime, &k3d.Cluster{Name: "test"}); err == nil {
+		t.Fatalf("expected freezing to fail")
+	}
+	if thawed := runtime.callsOf("ThawNode"); !reflect.DeepEqual(thawed, []string{"k3d-test-agent-1", "k3d-test-agent-0"}) {
+		t.Errorf("expected the already frozen agents to be thawed again, got %v", thawed)
+	}
+	if _, err := readFrozenCluster("test"); err == nil {
+		t.Errorf("expected no freeze record after a failed freeze")
+	}
+}
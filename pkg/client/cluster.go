@@ -24,11 +24,14 @@ package client
 import (
 	"context"
 	_ "embed"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"path"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	gort "runtime"
@@ -39,6 +42,7 @@ import (
 	"github.com/rancher/k3d/v5/pkg/actions"
 	config "github.com/rancher/k3d/v5/pkg/config/v1alpha3"
 	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/metrics"
 	k3drt "github.com/rancher/k3d/v5/pkg/runtimes"
 	"github.com/rancher/k3d/v5/pkg/runtimes/docker"
 	runtimeErr "github.com/rancher/k3d/v5/pkg/runtimes/errors"
@@ -51,14 +55,82 @@ import (
 	"gopkg.in/yaml.v2"
 )
 
+// ErrClusterAlreadyExists is returned by ClusterRun if the cluster exists already (e.g. because another process created it in the meantime).
+// The existing cluster must not be rolled back (deleted) in that case.
+var ErrClusterAlreadyExists = errors.New("cluster already exists")
+
 // ClusterRun orchestrates the steps of cluster creation, configuration and starting
-func ClusterRun(ctx context.Context, runtime k3drt.Runtime, clusterConfig *config.ClusterConfig) error {
+func ClusterRun(ctx context.Context, runtime k3drt.Runtime, clusterConfig *config.ClusterConfig) (err error) {
+	timings := clusterConfig.ClusterCreateOpts.Timings
+
+	startTime := time.Now()
+	defer func() {
+		metrics.ClustersCreated.WithLabelValues(metrics.Result(err)).Inc()
+		if err == nil {
+			metrics.ClusterBootDuration.Observe(time.Since(startTime).Seconds())
+			metrics.ObserveStages("create", timings)
+		}
+	}()
+
+	// library users may pass specs that never went through config.ValidateClusterConfig
+	if err := ClusterValidate(&clusterConfig.Cluster); err != nil {
+		return err
+	}
+
+	// fail before creating anything, if the runtime is too old for the nodes
+	if err := RuntimeCheckVersion(runtime); err != nil {
+		return err
+	}
+
+	// fail before creating anything, if images would have to be pulled, but mustn't be
+	if err := clusterPullImages(ctx, runtime, &clusterConfig.Cluster, clusterConfig.ClusterCreateOpts.PullPolicy); err != nil {
+		return err
+	}
+
+	// don't let concurrent k3d processes interfere with this cluster
+	unlock, err := ClusterLock(ctx, clusterConfig.Cluster.Name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// another process may have created the cluster while we were waiting for the lock
+	if _, err := ClusterGet(ctx, runtime, &k3d.Cluster{Name: clusterConfig.Cluster.Name}); err == nil {
+		return fmt.Errorf("cluster '%s': %w", clusterConfig.Cluster.Name, ErrClusterAlreadyExists)
+	}
+
+	// the network may be shared with other clusters, which must not delete it before our nodes are attached to it
+	unlockNetwork, err := networkLock(ctx, clusterConfig.Cluster.Network.Name)
+	if err != nil {
+		return err
+	}
+	networkLocked := true
+	defer func() {
+		if networkLocked {
+			unlockNetwork()
+		}
+	}()
+
+	// nodes running images built for another platform usually crash-loop, so we check the images that are present already
+	// before creating anything (the remaining ones are checked after they've been pulled)
+	logRequestedPlatform(runtime)
+	checkedImages := map[string]struct{}{}
+	if err := checkNodeImagePlatforms(ctx, runtime, &clusterConfig.Cluster, checkedImages); err != nil {
+		return err
+	}
+
+	if err := ClusterRunHooks(ctx, runtime, &clusterConfig.Cluster, clusterConfig.ClusterCreateOpts.Hooks, k3d.ClusterHookPreCreate); err != nil {
+		return err
+	}
+
 	/*
 	 * Step 0: (Infrastructure) Preparation
 	 */
+	stopTiming := timings.Track("preparation")
 	if err := ClusterPrep(ctx, runtime, clusterConfig); err != nil {
 		return fmt.Errorf("Failed Cluster Preparation: %+v", err)
 	}
+	stopTiming()
 
 	// Create tools-node for later steps
 	go EnsureToolsNode(ctx, runtime, &clusterConfig.Cluster)
@@ -66,29 +138,81 @@ func ClusterRun(ctx context.Context, runtime k3drt.Runtime, clusterConfig *confi
 	/*
 	 * Step 1: Create Containers
 	 */
+	stopTiming = timings.Track("create containers")
 	if err := ClusterCreate(ctx, runtime, &clusterConfig.Cluster, &clusterConfig.ClusterCreateOpts); err != nil {
 		return fmt.Errorf("Failed Cluster Creation: %+v", err)
 	}
+	stopTiming()
+	unlockNetwork()
+	networkLocked = false
+
+	// images are present now, so we can tell if they'll run on this host
+	if err := checkNodeImagePlatforms(ctx, runtime, &clusterConfig.Cluster, checkedImages); err != nil {
+		return err
+	}
+
+	if clusterConfig.ClusterCreateOpts.NoStart {
+		// warm standby: the files usually written right before the first start are written to the stopped containers already,
+		// so that a plain 'k3d cluster start' brings the cluster up later on
+		stopTiming = timings.Track("prepare stopped nodes")
+		for _, node := range clusterConfig.Cluster.Nodes {
+			if node.Role == k3d.ServerRole || node.Role == k3d.AgentRole {
+				runPreStartHooks(ctx, node, withNodeHooks(clusterConfig.ClusterCreateOpts.NodeHooks, node))
+			} else {
+				runPreStartHooks(ctx, node, node.HookActions)
+			}
+		}
+		stopTiming()
+		if len(clusterConfig.ClusterCreateOpts.Registries.Use) > 0 {
+			l.Log().Warnf("Not creating the LocalRegistryHosting ConfigMap, as the cluster is not started (--no-start)")
+		}
+		for _, hook := range clusterConfig.ClusterCreateOpts.Hooks {
+			if hook.Phase == k3d.ClusterHookPostServerReady || hook.Phase == k3d.ClusterHookPostClusterReady {
+				l.Log().Warnf("Not running the %s hook '%s', as the cluster is not started (--no-start)", hook.Phase, hook.Command)
+			}
+		}
+		l.Log().Infof("Leaving the nodes of cluster '%s' stopped (--no-start)", clusterConfig.Cluster.Name)
+		return nil
+	}
 
 	/*
 	 * Step 2: Pre-Start Configuration
 	 */
+	stopTiming = timings.Track("gather environment info")
 	envInfo, err := GatherEnvironmentInfo(ctx, runtime, &clusterConfig.Cluster)
 	if err != nil {
 		return fmt.Errorf("failed to gather environment information used for cluster creation: %w", err)
 	}
+	stopTiming()
 
 	/*
 	 * Step 3: Start Containers
 	 */
-	if err := ClusterStart(ctx, runtime, &clusterConfig.Cluster, k3d.ClusterStartOpts{
-		WaitForServer:   clusterConfig.ClusterCreateOpts.WaitForServer,
-		Timeout:         clusterConfig.ClusterCreateOpts.Timeout, // TODO: here we should consider the time used so far
-		NodeHooks:       clusterConfig.ClusterCreateOpts.NodeHooks,
-		EnvironmentInfo: envInfo,
+	stopTiming = timings.Track("start nodes")
+	if err := clusterStart(ctx, runtime, &clusterConfig.Cluster, k3d.ClusterStartOpts{
+		WaitForServer:    clusterConfig.ClusterCreateOpts.WaitForServer,
+		DeferWorkers:     clusterConfig.ClusterCreateOpts.DeferWorkers,
+		ReadinessTimeout: clusterConfig.ClusterCreateOpts.ReadinessTimeout,
+		Timeout:          clusterConfig.ClusterCreateOpts.Timeout, // TODO: here we should consider the time used so far
+		NodeHooks:        clusterConfig.ClusterCreateOpts.NodeHooks,
+		EnvironmentInfo:  envInfo,
 	}); err != nil {
 		return fmt.Errorf("Failed Cluster Start: %+v", err)
 	}
+	stopTiming()
+
+	// check the kernel parameters of the container host, which are shared by all nodes and whose defaults break clusters with more than a few pods
+	stopTiming = timings.Track("sysctl checks")
+	if err := ClusterCheckSysctls(ctx, runtime, &clusterConfig.Cluster, clusterConfig.ClusterCreateOpts.FixSysctls); err != nil {
+		l.Log().Warnf("Failed to check the kernel parameters of the container host: %v", err)
+	}
+	stopTiming()
+
+	stopTiming = timings.Track("postServerReady hooks")
+	if err := ClusterRunHooks(ctx, runtime, &clusterConfig.Cluster, clusterConfig.ClusterCreateOpts.Hooks, k3d.ClusterHookPostServerReady); err != nil {
+		return err
+	}
+	stopTiming()
 
 	/*
 	 * Post-Start Configuration
@@ -99,11 +223,55 @@ func ClusterRun(ctx context.Context, runtime k3drt.Runtime, clusterConfig *confi
 
 	// create the registry hosting configmap
 	if len(clusterConfig.ClusterCreateOpts.Registries.Use) > 0 {
+		stopTiming = timings.Track("registry configmap")
 		if err := prepCreateLocalRegistryHostingConfigMap(ctx, runtime, &clusterConfig.Cluster); err != nil {
 			l.Log().Warnf("Failed to create LocalRegistryHosting ConfigMap: %+v", err)
 		}
+		stopTiming()
+	}
+
+	// create the user-defined Secrets and ConfigMaps (only once, so they're not reset on restarts like auto-deploy manifests would be)
+	if len(clusterConfig.ClusterCreateOpts.SeedObjects) > 0 {
+		stopTiming = timings.Track("seed objects")
+		if err := ClusterSeedObjects(ctx, runtime, &clusterConfig.Cluster, clusterConfig.ClusterCreateOpts.SeedObjects, clusterConfig.ClusterCreateOpts.Timeout); err != nil {
+			return fmt.Errorf("Failed to create seed objects: %w", err)
+		}
+		stopTiming()
+	}
+
+	// create the namespace the kubeconfig context(s) point to, so that kubectl can be used with it right away
+	if ns := clusterConfig.Cluster.DefaultNamespace; ns != "" {
+		stopTiming = timings.Track("default namespace")
+		if err := ClusterCreateNamespace(ctx, runtime, &clusterConfig.Cluster, ns, clusterConfig.ClusterCreateOpts.Timeout); err != nil {
+			return fmt.Errorf("Failed to create default namespace: %w", err)
+		}
+		stopTiming()
+	}
+
+	// wait for user-defined Kubernetes resources to be ready
+	if len(clusterConfig.ClusterCreateOpts.WaitFor) > 0 {
+		stopTiming = timings.Track("wait for resources")
+		if err := ClusterWaitForResources(ctx, runtime, &clusterConfig.Cluster, clusterConfig.ClusterCreateOpts.WaitFor, clusterConfig.ClusterCreateOpts.Timeout); err != nil {
+			return fmt.Errorf("Failed waiting for resources: %w", err)
+		}
+		stopTiming()
+	}
+
+	// the first cluster using a k3s image fills the image cache for all later ones
+	if clusterConfig.ClusterCreateOpts.ImageCache {
+		stopTiming = timings.Track("fill image cache")
+		if err := ClusterFillImageCache(ctx, runtime, &clusterConfig.Cluster); err != nil {
+			l.Log().Warnf("Failed to fill the image cache: %v", err)
+		}
+		stopTiming()
 	}
 
+	stopTiming = timings.Track("postClusterReady hooks")
+	if err := ClusterRunHooks(ctx, runtime, &clusterConfig.Cluster, clusterConfig.ClusterCreateOpts.Hooks, k3d.ClusterHookPostClusterReady); err != nil {
+		return err
+	}
+	stopTiming()
+
 	return nil
 }
 
@@ -132,6 +300,10 @@ func ClusterPrep(ctx context.Context, runtime k3drt.Runtime, clusterConfig *conf
 		return fmt.Errorf("Failed Network Preparation: %+v", err)
 	}
 
+	if err := ClusterPrepRunnerContainer(clusterPrepCtx, runtime, &clusterConfig.Cluster, &clusterConfig.ClusterCreateOpts); err != nil {
+		return fmt.Errorf("Failed Runner Container Preparation: %+v", err)
+	}
+
 	/*
 	 * Step 2: Volume(s)
 	 */
@@ -141,6 +313,18 @@ func ClusterPrep(ctx context.Context, runtime k3drt.Runtime, clusterConfig *conf
 		}
 	}
 
+	if clusterConfig.ClusterCreateOpts.ImageCache {
+		if err := ClusterPrepImageCache(ctx, runtime, &clusterConfig.Cluster); err != nil {
+			return fmt.Errorf("Failed Image Cache Preparation: %+v", err)
+		}
+	}
+
+	if len(clusterConfig.ClusterCreateOpts.CacheVolumes) > 0 {
+		if err := ClusterPrepCacheVolumes(ctx, runtime, &clusterConfig.Cluster, clusterConfig.ClusterCreateOpts.CacheVolumes); err != nil {
+			return fmt.Errorf("Failed Cache Volume Preparation: %+v", err)
+		}
+	}
+
 	/*
 	 * Step 3: Registries
 	 */
@@ -186,6 +370,8 @@ func ClusterPrep(ctx context.Context, runtime k3drt.Runtime, clusterConfig *conf
 			if err := RegistryConnectNetworks(ctx, runtime, regNode, []string{clusterConfig.Cluster.Network.Name}); err != nil {
 				return fmt.Errorf("Failed to connect registry node '%s' to cluster network: %+v", regNode.Name, err)
 			}
+			// the nodes pull from it, so 'cluster start' has to bring it up first
+			ClusterDependencyAdd(&clusterConfig.Cluster, k3d.ClusterDependency{Kind: k3d.ClusterDependencyRegistry, Name: regNode.Name})
 		}
 
 		// generate the registries.yaml
@@ -224,6 +410,177 @@ func ClusterPrep(ctx context.Context, runtime k3drt.Runtime, clusterConfig *conf
 			registryConfig = clusterConfig.ClusterCreateOpts.Registries.Config
 		}
 	}
+	/*
+	 * Virtual Workers
+	 */
+	if clusterConfig.ClusterCreateOpts.VirtualWorkers > 0 {
+		virtualWorkersManifest, err := VirtualWorkersGenerateManifest(clusterConfig.Cluster.Name, clusterConfig.ClusterCreateOpts.VirtualWorkers)
+		if err != nil {
+			return fmt.Errorf("failed to generate virtual workers manifest: %w", err)
+		}
+		// k3s servers auto-deploy everything in the manifests directory, agents simply ignore it
+		clusterConfig.ClusterCreateOpts.NodeHooks = append(clusterConfig.ClusterCreateOpts.NodeHooks, k3d.NodeHook{
+			Stage: k3d.LifecycleStagePreStart,
+			Action: actions.WriteFileAction{
+				Runtime: runtime,
+				Content: virtualWorkersManifest,
+				Dest:    k3d.DefaultVirtualWorkersManifestPath,
+				Mode:    0644,
+			},
+		})
+	}
+
+	/*
+	 * Custom CA: has to be in place before k3s starts for the first time, otherwise it generates its own
+	 */
+	if clusterConfig.ClusterCreateOpts.CustomCA != nil {
+		for _, node := range clusterConfig.Cluster.Nodes {
+			if node.Role != k3d.ServerRole {
+				continue
+			}
+			node.HookActions = append(node.HookActions,
+				k3d.NodeHook{
+					Stage: k3d.LifecycleStagePreStart,
+					Action: actions.WriteFileAction{
+						Runtime: runtime,
+						Content: clusterConfig.ClusterCreateOpts.CustomCA.Cert,
+						Dest:    k3d.DefaultServerCACertPath,
+						Mode:    0644,
+					},
+				},
+				k3d.NodeHook{
+					Stage: k3d.LifecycleStagePreStart,
+					Action: actions.WriteFileAction{
+						Runtime: runtime,
+						Content: clusterConfig.ClusterCreateOpts.CustomCA.Key,
+						Dest:    k3d.DefaultServerCAKeyPath,
+						Mode:    0600,
+					},
+				},
+			)
+		}
+	}
+
+	/*
+	 * Trusted CAs: k3s and containerd (Go) load all certificates found in the system certificate directory
+	 */
+	for _, trustedCA := range clusterConfig.ClusterCreateOpts.TrustedCAs {
+		clusterConfig.ClusterCreateOpts.NodeHooks = append(clusterConfig.ClusterCreateOpts.NodeHooks, k3d.NodeHook{
+			Stage: k3d.LifecycleStagePreStart,
+			Action: actions.WriteFileAction{
+				Runtime: runtime,
+				Content: trustedCA.Cert,
+				Dest:    path.Join(k3d.DefaultTrustedCADir, trustedCA.Name),
+				Mode:    0644,
+			},
+		})
+	}
+
+	/*
+	 * Host DNS: referenced by the kubelet's --resolv-conf flag
+	 */
+	if len(clusterConfig.ClusterCreateOpts.HostResolvConf) > 0 {
+		clusterConfig.ClusterCreateOpts.NodeHooks = append(clusterConfig.ClusterCreateOpts.NodeHooks, k3d.NodeHook{
+			Stage: k3d.LifecycleStagePreStart,
+			Action: actions.WriteFileAction{
+				Runtime: runtime,
+				Content: clusterConfig.ClusterCreateOpts.HostResolvConf,
+				Dest:    k3d.DefaultHostResolvConfPath,
+				Mode:    0644,
+			},
+		})
+	}
+
+	/*
+	 * Datastore Backup: has to be in place before k3s starts for the first time, otherwise it initializes an empty datastore
+	 */
+	if len(clusterConfig.ClusterCreateOpts.DatastoreBackup) > 0 {
+		for _, node := range clusterConfig.Cluster.Nodes {
+			if node.Role != k3d.ServerRole {
+				continue
+			}
+			node.HookActions = append(node.HookActions, k3d.NodeHook{
+				Stage: k3d.LifecycleStagePreStart,
+				Action: actions.WriteFileAction{
+					Runtime: runtime,
+					Content: clusterConfig.ClusterCreateOpts.DatastoreBackup,
+					Dest:    k3d.DefaultServerDatastorePath,
+					Mode:    0600,
+				},
+			})
+		}
+	}
+
+	/*
+	 * Audit Policy: the API server refuses to start if the referenced policy file is missing
+	 */
+	if len(clusterConfig.ClusterCreateOpts.AuditPolicy) > 0 {
+		for _, node := range clusterConfig.Cluster.Nodes {
+			if node.Role != k3d.ServerRole {
+				continue
+			}
+			node.HookActions = append(node.HookActions, k3d.NodeHook{
+				Stage: k3d.LifecycleStagePreStart,
+				Action: actions.WriteFileAction{
+					Runtime: runtime,
+					Content: clusterConfig.ClusterCreateOpts.AuditPolicy,
+					Dest:    k3d.DefaultAuditPolicyPath,
+					Mode:    0644,
+				},
+			})
+		}
+	}
+
+	/*
+	 * Manifests: deployed by k3s on startup, so that e.g. a CNI or an ingress controller are there right away
+	 */
+	for _, manifest := range clusterConfig.ClusterCreateOpts.Manifests {
+		for _, node := range clusterConfig.Cluster.Nodes {
+			if node.Role != k3d.ServerRole {
+				continue
+			}
+			node.HookActions = append(node.HookActions, k3d.NodeHook{
+				Stage: k3d.LifecycleStagePreStart,
+				Action: actions.WriteFileAction{
+					Runtime: runtime,
+					Content: manifest.Content,
+					Dest:    path.Join(k3d.DefaultManifestsDir, manifest.Name),
+					Mode:    0644,
+				},
+			})
+		}
+	}
+
+	/*
+	 * Node Init: kept in a label (instead of a hook), so that it's also written to recreated nodes and nodes added later on
+	 */
+	if len(clusterConfig.ClusterCreateOpts.NodeInit) > 0 {
+		for _, node := range clusterConfig.Cluster.Nodes {
+			if node.Role != k3d.ServerRole && node.Role != k3d.AgentRole {
+				continue
+			}
+			if node.RuntimeLabels == nil {
+				node.RuntimeLabels = map[string]string{}
+			}
+			node.RuntimeLabels[k3d.LabelNodeInit] = string(clusterConfig.ClusterCreateOpts.NodeInit)
+		}
+	}
+
+	/*
+	 * CRI: kept in a label, so that the docker daemon is also started in recreated nodes and nodes added later on
+	 */
+	if clusterConfig.ClusterCreateOpts.CRI == k3d.CRIDockerd {
+		for _, node := range clusterConfig.Cluster.Nodes {
+			if node.Role != k3d.ServerRole && node.Role != k3d.AgentRole {
+				continue
+			}
+			if node.RuntimeLabels == nil {
+				node.RuntimeLabels = map[string]string{}
+			}
+			node.RuntimeLabels[k3d.LabelNodeCRI] = string(k3d.CRIDockerd)
+		}
+	}
+
 	if registryConfig != nil {
 		regConfBytes, err := yaml.Marshal(&registryConfig)
 		if err != nil {
@@ -257,6 +614,10 @@ func ClusterPrepNetwork(ctx context.Context, runtime k3drt.Runtime, cluster *k3d
 		return fmt.Errorf("cannot specify subnet for exiting network")
 	}
 
+	if cluster.Network.Name != "" && cluster.Network.External && (!cluster.Network.IPAM.Gateway.IsZero() || cluster.Network.IPv6.Enabled) {
+		return fmt.Errorf("cannot specify gateway or IPv6 for existing network '%s'", cluster.Network.Name)
+	}
+
 	// generate cluster network name, if not set
 	if cluster.Network.Name == "" && !cluster.Network.External {
 		cluster.Network.Name = fmt.Sprintf("%s-%s", k3d.DefaultObjectNamePrefix, cluster.Name)
@@ -341,7 +702,7 @@ ClusterCreatOpts:
 	/*
 	 * Docker Machine Special Configuration
 	 */
-	if cluster.KubeAPI.Host == k3d.DefaultAPIHost && runtime == k3drt.Docker {
+	if cluster.KubeAPI.Host == k3d.DefaultAPIHost && runtime == k3drt.Docker && !Offline {
 		if gort.GOOS == "windows" || gort.GOOS == "darwin" {
 			l.Log().Tracef("Running on %s: checking if it's using docker-machine", gort.GOOS)
 			machineIP, err := runtime.(docker.Docker).GetDockerMachineIP()
@@ -371,10 +732,45 @@ ClusterCreatOpts:
 	 */
 
 	clusterCreateOpts.GlobalLabels[k3d.LabelClusterName] = cluster.Name
+	if cluster.Description != "" {
+		clusterCreateOpts.GlobalLabels[k3d.LabelClusterDescription] = cluster.Description
+	}
+	if cluster.DefaultNamespace != "" {
+		clusterCreateOpts.GlobalLabels[k3d.LabelClusterNamespace] = cluster.DefaultNamespace
+	}
+	if cluster.ContextTemplate != "" {
+		clusterCreateOpts.GlobalLabels[k3d.LabelClusterContextTmpl] = cluster.ContextTemplate
+	}
+	if clusterCreateOpts.DisableHostIP {
+		clusterCreateOpts.GlobalLabels[k3d.LabelHostIPDisabled] = "true"
+	}
+	if len(cluster.DependsOn) > 0 {
+		clusterCreateOpts.GlobalLabels[k3d.LabelClusterDependsOn] = clusterDependenciesLabel(cluster.DependsOn)
+	}
+	preDeleteHooks := []string{}
+	for _, hook := range clusterCreateOpts.Hooks {
+		if hook.Phase == k3d.ClusterHookPreDelete {
+			preDeleteHooks = append(preDeleteHooks, hook.Command)
+		}
+	}
+	if len(preDeleteHooks) > 0 {
+		preDeleteHooksJSON, err := json.Marshal(preDeleteHooks)
+		if err != nil {
+			return fmt.Errorf("failed to marshal preDelete hooks: %w", err)
+		}
+		clusterCreateOpts.GlobalLabels[k3d.LabelClusterPreDeleteHooks] = string(preDeleteHooksJSON)
+	}
 
 	// agent defaults (per cluster)
 	// connection url is always the name of the first server node (index 0) // TODO: change this to the server loadbalancer
-	connectionURL := fmt.Sprintf("https://%s:%s", GenerateNodeName(cluster.Name, k3d.ServerRole, 0), k3d.DefaultAPIPort)
+	firstServerName := GenerateNodeName(cluster.Name, k3d.ServerRole, 0)
+	for _, node := range cluster.Nodes {
+		if node.Role == k3d.ServerRole {
+			firstServerName = node.Name // may differ from the default, if a node name template was used
+			break
+		}
+	}
+	connectionURL := fmt.Sprintf("https://%s:%s", firstServerName, k3d.DefaultAPIPort)
 	clusterCreateOpts.GlobalLabels[k3d.LabelClusterURL] = connectionURL
 	clusterCreateOpts.GlobalEnv = append(clusterCreateOpts.GlobalEnv, fmt.Sprintf("%s=%s", k3d.K3sEnvClusterToken, cluster.Token))
 
@@ -461,7 +857,8 @@ ClusterCreatOpts:
 
 	}
 
-	// create all other nodes, but skip the init node
+	// create all other servers one after another, but skip the init node, and collect the agents to create them concurrently afterwards
+	agents := []*k3d.Node{}
 	for _, node := range cluster.Nodes {
 		if node.Role == k3d.ServerRole {
 
@@ -481,12 +878,19 @@ ClusterCreatOpts:
 			serverCount++
 
 		}
-		if node.Role == k3d.ServerRole || node.Role == k3d.AgentRole {
+		if node.Role == k3d.ServerRole {
 			if err := nodeSetup(node); err != nil {
-				return fmt.Errorf("failed setup of server/agent node %s: %w", node.Name, err)
+				return fmt.Errorf("failed setup of server node %s: %w", node.Name, err)
 			}
+		} else if node.Role == k3d.AgentRole {
+			agents = append(agents, node)
 		}
 	}
+	if err := nodesParallel(clusterCreateCtx, "create", agents, func(_ context.Context, node *k3d.Node) error {
+		return nodeSetup(node)
+	}); err != nil {
+		return fmt.Errorf("failed setup of agent nodes: %w", err)
+	}
 
 	// WARN, if there are exactly two server nodes: that means we're using etcd, but don't have fault tolerance
 	if serverCount == 2 {
@@ -546,17 +950,45 @@ ClusterCreatOpts:
 }
 
 // ClusterDelete deletes an existing cluster
-func ClusterDelete(ctx context.Context, runtime k3drt.Runtime, cluster *k3d.Cluster, opts k3d.ClusterDeleteOpts) error {
+func ClusterDelete(ctx context.Context, runtime k3drt.Runtime, cluster *k3d.Cluster, opts k3d.ClusterDeleteOpts) (err error) {
+
+	defer func() {
+		metrics.ClustersDeleted.WithLabelValues(metrics.Result(err)).Inc()
+		if err == nil {
+			metrics.ObserveStages("delete", opts.Timings)
+		}
+	}()
+
+	unlock, err := ClusterLock(ctx, cluster.Name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
 	l.Log().Infof("Deleting cluster '%s'", cluster.Name)
-	cluster, err := ClusterGet(ctx, runtime, cluster)
+	cluster, err = ClusterGet(ctx, runtime, cluster)
 	if err != nil {
 		return fmt.Errorf("failed to get cluster: %w", err)
 	}
 	l.Log().Debugf("Cluster Details: %+v", cluster)
 
-	failed := 0
-	for _, node := range cluster.Nodes {
+	// the hooks usually clean up things outside of the cluster, so a failing hook shouldn't keep the cluster around
+	preDeleteHooks, err := clusterPreDeleteHooks(cluster)
+	if err == nil {
+		err = ClusterRunHooks(ctx, runtime, cluster, preDeleteHooks, k3d.ClusterHookPreDelete)
+	}
+	if err != nil {
+		l.Log().Warnln(err)
+	}
+
+	stopTiming := opts.Timings.Track("delete nodes")
+	deleteCtx := ctx
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		deleteCtx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	deleteErr := nodesParallel(deleteCtx, "delete", cluster.Nodes, func(ctx context.Context, node *k3d.Node) error {
 		// registry: only delete, if not connected to other networks
 		if node.Role == k3d.RegistryRole && !opts.SkipRegistryCheck {
 			l.Log().Tracef("Registry Node has %d networks: %+v", len(node.Networks), node)
@@ -582,20 +1014,25 @@ func ClusterDelete(ctx context.Context, runtime k3drt.Runtime, cluster *k3d.Clus
 				if err := runtime.DisconnectNodeFromNetwork(ctx, node, cluster.Network.Name); err != nil {
 					l.Log().Warnf("Failed to disconnect registry %s from cluster network %s", node.Name, cluster.Network.Name)
 				}
-				continue
+				return nil
 			}
 		}
 
-		if err := NodeDelete(ctx, runtime, node, k3d.NodeDeleteOpts{SkipLBUpdate: true}); err != nil {
-			l.Log().Warningf("Failed to delete node '%s': Try to delete it manually", node.Name)
-			failed++
-			continue
-		}
-	}
+		return NodeDelete(ctx, runtime, node, k3d.NodeDeleteOpts{SkipLBUpdate: true})
+	})
+
+	stopTiming()
 
 	// Delete the cluster network, if it was created for/by this cluster (and if it's not in use anymore)
+	stopTiming = opts.Timings.Track("delete network")
 	if cluster.Network.Name != "" {
+		clusterDisconnectRunnerContainer(ctx, runtime, cluster)
 		if !cluster.Network.External {
+			unlockNetwork, err := networkLock(ctx, cluster.Network.Name)
+			if err != nil {
+				return err
+			}
+			defer unlockNetwork()
 			l.Log().Infof("Deleting cluster network '%s'", cluster.Network.Name)
 			if err := runtime.DeleteNetwork(ctx, cluster.Network.Name); err != nil {
 				if errors.Is(err, runtimeErr.ErrRuntimeNetworkNotEmpty) { // there are still containers connected to that network
@@ -633,17 +1070,21 @@ func ClusterDelete(ctx context.Context, runtime k3drt.Runtime, cluster *k3d.Clus
 		}
 	}
 
+	stopTiming()
+
 	// delete image volume
+	stopTiming = opts.Timings.Track("delete image volume")
 	if cluster.ImageVolume != "" {
 		l.Log().Infof("Deleting image volume '%s'", cluster.ImageVolume)
 		if err := runtime.DeleteVolume(ctx, cluster.ImageVolume); err != nil {
 			l.Log().Warningf("Failed to delete image volume '%s' of cluster '%s': Try to delete it manually", cluster.ImageVolume, cluster.Name)
 		}
 	}
+	stopTiming()
 
 	// return error if we failed to delete a node
-	if failed > 0 {
-		return fmt.Errorf("Failed to delete %d nodes: Try to delete them manually", failed)
+	if deleteErr != nil {
+		return fmt.Errorf("%w\nTry to delete them manually", deleteErr)
 	}
 	return nil
 }
@@ -727,6 +1168,24 @@ func populateClusterFieldsFromLabels(cluster *k3d.Cluster) error {
 			}
 		}
 
+		// get the description given at creation time (see ClusterDescription for later updates)
+		if cluster.Description == "" {
+			cluster.Description = node.RuntimeLabels[k3d.LabelClusterDescription]
+		}
+
+		// get the namespace of the kubeconfig context
+		if cluster.DefaultNamespace == "" {
+			cluster.DefaultNamespace = node.RuntimeLabels[k3d.LabelClusterNamespace]
+		}
+		if cluster.ContextTemplate == "" {
+			cluster.ContextTemplate = node.RuntimeLabels[k3d.LabelClusterContextTmpl]
+		}
+
+		// get the registries and clusters it depends on
+		if len(cluster.DependsOn) == 0 {
+			cluster.DependsOn = clusterDependenciesFromLabel(node.RuntimeLabels[k3d.LabelClusterDependsOn])
+		}
+
 		// get image volume // TODO: enable external image volumes the same way we do it with networks
 		if cluster.ImageVolume == "" {
 			if imageVolumeName, ok := node.RuntimeLabels[k3d.LabelImageVolume]; ok {
@@ -806,17 +1265,97 @@ func ClusterGet(ctx context.Context, runtime k3drt.Runtime, cluster *k3d.Cluster
 	return cluster, nil
 }
 
+// agentStartAttempts is the number of times an agent node is started before it's considered to be broken
+const agentStartAttempts = 2
+
+// agentNodePasswordPath is where k3s persists the password the agent registers with, which has to survive the recreation of the agent
+const agentNodePasswordPath = "/etc/rancher/node/password"
+
+// agentStart starts an agent node, retrying once if it fails to start and recreating it from its spec if requested and it keeps failing
+func agentStart(ctx context.Context, runtime k3drt.Runtime, node *k3d.Node, clusterStartOpts types.ClusterStartOpts) error {
+	var err error
+	for attempt := 1; attempt <= agentStartAttempts; attempt++ {
+		if err = NodeStart(ctx, runtime, node, &k3d.NodeStartOpts{
+			Wait:            true,
+			NodeHooks:       withNodeHooks(clusterStartOpts.NodeHooks, node),
+			EnvironmentInfo: clusterStartOpts.EnvironmentInfo,
+		}); err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		l.Log().Warnf("Failed to start agent %s (attempt %d/%d): %v", node.Name, attempt, agentStartAttempts, err)
+		// the container may be up but stuck, so stop it before the next attempt
+		if err := runtime.StopNode(ctx, node); err != nil {
+			l.Log().Debugf("Failed to stop agent %s after failed start: %v", node.Name, err)
+		}
+		node.State.Running = false
+	}
+
+	if !clusterStartOpts.RecreateFailed {
+		return fmt.Errorf("%w\nUse '--recreate-failed' to recreate the agent from its spec", err)
+	}
+
+	l.Log().Infof("Recreating failed agent %s...", node.Name)
+	newNode, err := CopyNode(ctx, node, CopyNodeOpts{})
+	if err != nil {
+		return fmt.Errorf("failed to copy spec of agent %s: %w", node.Name, err)
+	}
+	newNode.HookActions = withNodeHooks(clusterStartOpts.NodeHooks, node)
+
+	// k3s rejects an agent that registers with a different password than before, so we take it over from the old container
+	password, err := nodeReadFile(ctx, runtime, node, agentNodePasswordPath)
+	if err != nil {
+		l.Log().Warnf("Failed to read the node password of agent %s, the recreated agent may be rejected by the server: %v", node.Name, err)
+	} else if len(password) > 0 {
+		newNode.HookActions = append(newNode.HookActions, k3d.NodeHook{
+			Stage: k3d.LifecycleStagePreStart,
+			Action: actions.WriteFileAction{
+				Runtime: runtime,
+				Content: password,
+				Dest:    agentNodePasswordPath,
+				Mode:    0600,
+			},
+		})
+	}
+
+	if err := NodeReplace(ctx, runtime, node, newNode); err != nil {
+		return fmt.Errorf("failed to recreate agent %s: %w", node.Name, err)
+	}
+	l.Log().Infof("Recreated agent %s", newNode.Name)
+	return nil
+}
+
+// withNodeHooks combines the cluster-wide node hooks with the ones specific to a single node (in a new slice, as nodes may be started concurrently)
+func withNodeHooks(clusterHooks []k3d.NodeHook, node *k3d.Node) []k3d.NodeHook {
+	hooks := make([]k3d.NodeHook, 0, len(clusterHooks)+len(node.HookActions))
+	hooks = append(hooks, clusterHooks...)
+	return append(hooks, node.HookActions...)
+}
+
 // GenerateClusterToken generates a random 20 character string
 func GenerateClusterToken() string {
 	return util.GenerateRandomString(20)
 }
 
+// GenerateNodeName generates a node name following the default naming scheme (see k3d.DefaultNodeNameTemplate)
 func GenerateNodeName(cluster string, role k3d.Role, suffix int) string {
 	return fmt.Sprintf("%s-%s-%s-%d", k3d.DefaultObjectNamePrefix, cluster, role, suffix)
 }
 
 // ClusterStart starts a whole cluster (i.e. all nodes of the cluster)
 func ClusterStart(ctx context.Context, runtime k3drt.Runtime, cluster *k3d.Cluster, clusterStartOpts types.ClusterStartOpts) error {
+	unlock, err := ClusterLock(ctx, cluster.Name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return clusterStart(ctx, runtime, cluster, clusterStartOpts)
+}
+
+// clusterStart starts a whole cluster (i.e. all nodes of the cluster) without locking it
+func clusterStart(ctx context.Context, runtime k3drt.Runtime, cluster *k3d.Cluster, clusterStartOpts types.ClusterStartOpts) error {
 	l.Log().Infof("Starting cluster '%s'", cluster.Name)
 
 	if clusterStartOpts.Timeout > 0*time.Second {
@@ -864,7 +1403,7 @@ func ClusterStart(ctx context.Context, runtime k3drt.Runtime, cluster *k3d.Clust
 		l.Log().Infoln("Starting the initializing server...")
 		if err := NodeStart(ctx, runtime, initNode, &k3d.NodeStartOpts{
 			Wait:            true, // always wait for the init node
-			NodeHooks:       clusterStartOpts.NodeHooks,
+			NodeHooks:       withNodeHooks(clusterStartOpts.NodeHooks, initNode),
 			ReadyLogMessage: "Running kube-apiserver", // initNode means, that we're using etcd -> this will need quorum, so "k3s is up and running" won't happen right now
 			EnvironmentInfo: clusterStartOpts.EnvironmentInfo,
 		}); err != nil {
@@ -879,7 +1418,7 @@ func ClusterStart(ctx context.Context, runtime k3drt.Runtime, cluster *k3d.Clust
 	for _, serverNode := range servers {
 		if err := NodeStart(ctx, runtime, serverNode, &k3d.NodeStartOpts{
 			Wait:            true,
-			NodeHooks:       clusterStartOpts.NodeHooks,
+			NodeHooks:       withNodeHooks(clusterStartOpts.NodeHooks, serverNode),
 			EnvironmentInfo: clusterStartOpts.EnvironmentInfo,
 		}); err != nil {
 			return fmt.Errorf("Failed to start server %s: %+v", serverNode.Name, err)
@@ -890,17 +1429,26 @@ func ClusterStart(ctx context.Context, runtime k3drt.Runtime, cluster *k3d.Clust
 	 * Agent Nodes
 	 */
 
+	if clusterStartOpts.DeferWorkers && len(agents) > 0 {
+		readyServers := servers
+		if initNode != nil {
+			readyServers = append([]*k3d.Node{initNode}, servers...)
+		}
+		l.Log().Infoln("Waiting for the servers to pass their readiness checks before starting agents...")
+		for _, serverNode := range readyServers {
+			if err := serverWaitForReadiness(ctx, runtime, serverNode); err != nil {
+				return err
+			}
+		}
+	}
+
 	agentWG, aCtx := errgroup.WithContext(ctx)
 
 	l.Log().Infoln("Starting agents...")
 	for _, agentNode := range agents {
 		currentAgentNode := agentNode
 		agentWG.Go(func() error {
-			return NodeStart(aCtx, runtime, currentAgentNode, &k3d.NodeStartOpts{
-				Wait:            true,
-				NodeHooks:       clusterStartOpts.NodeHooks,
-				EnvironmentInfo: clusterStartOpts.EnvironmentInfo,
-			})
+			return agentStart(aCtx, runtime, currentAgentNode, clusterStartOpts)
 		})
 	}
 	if err := agentWG.Wait(); err != nil {
@@ -937,6 +1485,11 @@ func ClusterStart(ctx context.Context, runtime k3drt.Runtime, cluster *k3d.Clust
 	 * Additional Cluster Preparation
 	 */
 
+	// open the SSH tunnel to the API on the remote docker host (before the readiness checks, which connect through it)
+	if err := ClusterAPITunnel(ctx, runtime, cluster); err != nil {
+		return err
+	}
+
 	/*** DNS ***/
 
 	// add /etc/hosts and CoreDNS entry for host.k3d.internal, referring to the host system
@@ -949,24 +1502,74 @@ func ClusterStart(ctx context.Context, runtime k3drt.Runtime, cluster *k3d.Clust
 		return fmt.Errorf("failed to patch CoreDNS with network members: %w", err)
 	}
 
+	// make the nodes drained by a graceful stop schedulable again
+	if err := clusterUncordon(ctx, runtime, cluster); err != nil {
+		l.Log().Warnf("Failed to uncordon the nodes drained when stopping the cluster: %v", err)
+	}
+
+	// the log messages we waited for above don't guarantee that the API is reachable via the published port or that the agents registered
+	if clusterStartOpts.WaitForServer {
+		if err := ClusterWaitForReadiness(ctx, runtime, cluster, clusterStartOpts.ReadinessTimeout); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// serverReadinessCmd queries the readiness endpoint of the API server in a server node, printing "ok" once it's ready
+var serverReadinessCmd = []string{"kubectl", "get", "--raw", "/readyz"}
+
+// serverWaitForReadiness polls the readiness endpoint of the API server running in the given server node until it reports "ok".
+// The "k3s is up and running" log message is printed before the API server is actually ready to accept agent registrations,
+// so waiting for this avoids agents retrying (and backing off) their registration against the server.
+func serverWaitForReadiness(ctx context.Context, runtime k3drt.Runtime, node *k3d.Node) error {
+	for {
+		logreader, err := runtime.ExecInNodeGetLogs(ctx, node, serverReadinessCmd)
+		if err == nil {
+			output, _ := ioutil.ReadAll(logreader)
+			if strings.TrimSpace(string(output)) == "ok" {
+				l.Log().Debugf("Server '%s' is ready", node.Name)
+				return nil
+			}
+		} else {
+			l.Log().Tracef("Server '%s' not ready yet: %v", node.Name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for server '%s' to be ready: %w", node.Name, ctx.Err())
+		case <-time.After(WaitPollInterval):
+		}
+	}
+}
+
 // ClusterStop stops a whole cluster (i.e. all nodes of the cluster)
-func ClusterStop(ctx context.Context, runtime k3drt.Runtime, cluster *k3d.Cluster) error {
-	l.Log().Infof("Stopping cluster '%s'", cluster.Name)
+func ClusterStop(ctx context.Context, runtime k3drt.Runtime, cluster *k3d.Cluster, clusterStopOpts k3d.ClusterStopOpts) error {
+	unlock, err := ClusterLock(ctx, cluster.Name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
-	failed := 0
-	for _, node := range cluster.Nodes {
-		if err := runtime.StopNode(ctx, node); err != nil {
-			l.Log().Warningf("Failed to stop node '%s': Try to stop it manually", node.Name)
-			failed++
-			continue
+	if clusterStopOpts.Graceful {
+		// a failed drain shouldn't keep the cluster running: the remaining pods are simply killed as without --graceful
+		if err := clusterDrain(ctx, runtime, cluster, clusterStopOpts.GracefulTimeout); err != nil {
+			l.Log().Warnf("Failed to drain cluster '%s' gracefully, stopping it anyway: %v", cluster.Name, err)
 		}
 	}
 
-	if failed > 0 {
-		return fmt.Errorf("Failed to stop %d nodes: Try to stop them manually", failed)
+	return clusterStop(ctx, runtime, cluster)
+}
+
+// clusterStop stops a whole cluster (i.e. all nodes of the cluster) without locking it
+func clusterStop(ctx context.Context, runtime k3drt.Runtime, cluster *k3d.Cluster) error {
+	l.Log().Infof("Stopping cluster '%s'", cluster.Name)
+
+	if err := nodesParallel(ctx, "stop", cluster.Nodes, func(ctx context.Context, node *k3d.Node) error {
+		return runtime.StopNode(ctx, node)
+	}); err != nil {
+		return fmt.Errorf("%w\nTry to stop them manually", err)
 	}
 
 	l.Log().Infof("Stopped cluster '%s'", cluster.Name)
@@ -1016,6 +1619,12 @@ func prepInjectHostIP(ctx context.Context, runtime k3drt.Runtime, cluster *k3d.C
 		l.Log().Tracef("Not injecting hostIP as clusternetwork is 'host'")
 		return nil
 	}
+	for _, node := range cluster.Nodes {
+		if node.RuntimeLabels[k3d.LabelHostIPDisabled] == "true" {
+			l.Log().Debugf("Not injecting hostIP as it was disabled at cluster creation")
+			return nil
+		}
+	}
 
 	hostIP := clusterStartOpts.EnvironmentInfo.HostGateway
 	hostsEntry := fmt.Sprintf("%s %s", hostIP.String(), k3d.DefaultK3dInternalHostRecord)
@@ -1078,30 +1687,34 @@ func prepCreateLocalRegistryHostingConfigMap(ctx context.Context, runtime k3drt.
 
 // ClusterEditChangesetSimple modifies an existing cluster with a given SimpleConfig changeset
 func ClusterEditChangesetSimple(ctx context.Context, runtime k3drt.Runtime, cluster *k3d.Cluster, changeset *config.SimpleConfig) error {
+
+	// === Env & K3s Args ===
+	if len(changeset.Env) > 0 || len(changeset.Options.K3sOptions.ExtraArgs) > 0 {
+		if err := clusterEditNodeSpecs(ctx, runtime, cluster, changeset); err != nil {
+			return err
+		}
+		if len(changeset.Ports) == 0 {
+			return nil
+		}
+		// nodes were replaced: continue with the current ones
+		var err error
+		cluster, err = ClusterGet(ctx, runtime, cluster)
+		if err != nil {
+			return fmt.Errorf("failed to get cluster after recreating nodes: %w", err)
+		}
+	}
+
 	// nodeCount := len(cluster.Nodes)
 	nodeList := cluster.Nodes
 
 	// === Ports ===
 
 	existingLB := cluster.ServerLoadBalancer
-	lbChangeset := &k3d.Loadbalancer{}
-
-	// copy existing loadbalancer
-	lbChangesetNode, err := CopyNode(ctx, existingLB.Node, CopyNodeOpts{keepState: false})
-	if err != nil {
-		return fmt.Errorf("error copying existing loadbalancer: %w", err)
-	}
-
-	lbChangeset.Node = lbChangesetNode
-
-	// copy config from existing loadbalancer
-	lbChangesetConfig, err := copystruct.Copy(existingLB.Config)
+	lbChangeset, err := loadbalancerCopy(ctx, existingLB)
 	if err != nil {
-		return fmt.Errorf("error copying config from existing loadbalancer: %w", err)
+		return err
 	}
 
-	lbChangeset.Config = lbChangesetConfig.(*k3d.LoadbalancerConfig)
-
 	// loop over ports
 	if len(changeset.Ports) > 0 {
 		// 1. ensure that there are only supported suffices in the node filters // TODO: overly complex right now, needs simplification
@@ -1128,6 +1741,225 @@ func ClusterEditChangesetSimple(ctx context.Context, runtime k3drt.Runtime, clus
 		}
 	}
 
+	return loadbalancerReplace(ctx, runtime, existingLB, lbChangeset)
+}
+
+// clusterEditNodeSpecs adds the env vars and k3s args of a changeset to the k3s nodes matched by their node filters (default: all)
+// by recreating the affected nodes one after another (servers first), so that a cluster with multiple servers stays available.
+// The recreated nodes carry an updated copy of the config recorded at cluster creation.
+func clusterEditNodeSpecs(ctx context.Context, runtime k3drt.Runtime, cluster *k3d.Cluster, changeset *config.SimpleConfig) error {
+	k3sNodes := NodeFilterByRoles(cluster.Nodes, []k3d.Role{k3d.ServerRole, k3d.AgentRole}, nil)
+	edited := map[string]*k3d.Node{} // node name -> new spec
+
+	editNodes := func(nodeFilters []string, edit func(node *k3d.Node)) error {
+		nodes := k3sNodes
+		if len(nodeFilters) > 0 {
+			var err error
+			nodes, err = util.FilterNodes(k3sNodes, nodeFilters)
+			if err != nil {
+				return fmt.Errorf("failed to filter nodes: %w", err)
+			}
+		}
+		for _, node := range nodes {
+			if _, ok := edited[node.Name]; !ok {
+				newNode, err := CopyNode(ctx, node, CopyNodeOpts{keepState: false})
+				if err != nil {
+					return err
+				}
+				edited[node.Name] = newNode
+			}
+			edit(edited[node.Name])
+		}
+		return nil
+	}
+
+	for _, envVar := range changeset.Env {
+		if err := editNodes(envVar.NodeFilters, func(node *k3d.Node) { node.Env = envSet(node.Env, envVar.EnvVar) }); err != nil {
+			return fmt.Errorf("failed to add env var '%s': %w", envVar.EnvVar, err)
+		}
+	}
+	for _, arg := range changeset.Options.K3sOptions.ExtraArgs {
+		if err := editNodes(arg.NodeFilters, func(node *k3d.Node) {
+			for _, existing := range node.Args {
+				if existing == arg.Arg {
+					return
+				}
+			}
+			node.Args = append(node.Args, arg.Arg)
+		}); err != nil {
+			return fmt.Errorf("failed to add k3s arg '%s': %w", arg.Arg, err)
+		}
+	}
+
+	if _, recorded, ok := ClusterRecordedConfig(cluster); ok {
+		updated, err := recordedConfigAppend(recorded, changeset)
+		if err != nil {
+			l.Log().Warnf("Failed to update the recorded config of cluster '%s': %v", cluster.Name, err)
+		} else {
+			for _, node := range edited {
+				node.RuntimeLabels[k3d.LabelClusterCreateConfig] = updated
+			}
+		}
+	}
+
+	// servers first, as agents need them to (re-)join
+	sort.SliceStable(k3sNodes, func(i, j int) bool {
+		if k3sNodes[i].Role != k3sNodes[j].Role {
+			return k3sNodes[i].Role == k3d.ServerRole
+		}
+		return k3sNodes[i].Name < k3sNodes[j].Name
+	})
+	for _, node := range k3sNodes {
+		newNode, ok := edited[node.Name]
+		if !ok {
+			continue
+		}
+		l.Log().Infof("Recreating node '%s' with the updated spec...", node.Name)
+		if err := NodeReplace(ctx, runtime, node, newNode); err != nil {
+			return fmt.Errorf("failed to recreate node '%s': %w", node.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// envSet sets an env var (KEY=VALUE or just KEY) in a list of env vars, replacing an existing value of the same key
+func envSet(env []string, envVar string) []string {
+	key := strings.SplitN(envVar, "=", 2)[0]
+	result := make([]string, 0, len(env)+1)
+	for _, existing := range env {
+		if strings.SplitN(existing, "=", 2)[0] != key {
+			result = append(result, existing)
+		}
+	}
+	return append(result, envVar)
+}
+
+// ClusterRecordedConfig returns the config a cluster was created with (recorded in the node labels, see 'k3d record') and the node it's read from.
+// Nodes recreated by 'k3d cluster edit' carry an updated copy, so the most recently created node wins.
+func ClusterRecordedConfig(cluster *k3d.Cluster) (*k3d.Node, string, bool) {
+	var recordedNode *k3d.Node
+	var recordedCreated time.Time
+	for _, node := range cluster.Nodes {
+		if _, ok := node.RuntimeLabels[k3d.LabelClusterCreateConfig]; !ok {
+			continue
+		}
+		created, _ := time.Parse(time.RFC3339Nano, node.Created)
+		if recordedNode == nil || created.After(recordedCreated) {
+			recordedNode = node
+			recordedCreated = created
+		}
+	}
+	if recordedNode == nil {
+		return nil, "", false
+	}
+	return recordedNode, recordedNode.RuntimeLabels[k3d.LabelClusterCreateConfig], true
+}
+
+// recordedConfigAppend adds the env vars and k3s args of a changeset to a recorded config, leaving everything else as it is
+func recordedConfigAppend(recorded string, changeset *config.SimpleConfig) (string, error) {
+	var fields yaml.MapSlice
+	if err := yaml.Unmarshal([]byte(recorded), &fields); err != nil {
+		return "", fmt.Errorf("failed to parse recorded config: %w", err)
+	}
+
+	appendItems := func(fields yaml.MapSlice, key string, items ...interface{}) yaml.MapSlice {
+		for i := range fields {
+			if fields[i].Key == key {
+				existing, _ := fields[i].Value.([]interface{})
+				fields[i].Value = append(existing, items...)
+				return fields
+			}
+		}
+		return append(fields, yaml.MapItem{Key: key, Value: items})
+	}
+	subMap := func(fields yaml.MapSlice, key string) (yaml.MapSlice, func(yaml.MapSlice) yaml.MapSlice) {
+		for i := range fields {
+			if fields[i].Key == key {
+				value, _ := fields[i].Value.(yaml.MapSlice)
+				return value, func(value yaml.MapSlice) yaml.MapSlice { fields[i].Value = value; return fields }
+			}
+		}
+		return nil, func(value yaml.MapSlice) yaml.MapSlice { return append(fields, yaml.MapItem{Key: key, Value: value}) }
+	}
+
+	defaultFilters := func(nodeFilters []string) []string {
+		if len(nodeFilters) == 0 {
+			return []string{"server:*", "agent:*"}
+		}
+		return nodeFilters
+	}
+
+	for _, envVar := range changeset.Env {
+		fields = appendItems(fields, "env", config.EnvVarWithNodeFilters{EnvVar: envVar.EnvVar, NodeFilters: defaultFilters(envVar.NodeFilters)})
+	}
+	if len(changeset.Options.K3sOptions.ExtraArgs) > 0 {
+		options, setOptions := subMap(fields, "options")
+		k3sOptions, setK3sOptions := subMap(options, "k3s")
+		for _, arg := range changeset.Options.K3sOptions.ExtraArgs {
+			k3sOptions = appendItems(k3sOptions, "extraArgs", config.K3sArgWithNodeFilters{Arg: arg.Arg, NodeFilters: defaultFilters(arg.NodeFilters)})
+		}
+		fields = setOptions(setK3sOptions(k3sOptions))
+	}
+
+	updated, err := yaml.Marshal(fields)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal recorded config: %w", err)
+	}
+	return string(updated), nil
+}
+
+// ClusterRemovePorts removes port mappings published via the loadbalancer of a cluster by recreating the loadbalancer
+// (format: [HOST:][HOSTPORT:]CONTAINERPORT[/PROTOCOL], like for adding them). Ports published directly by the k3s nodes can't be removed.
+func ClusterRemovePorts(ctx context.Context, runtime k3drt.Runtime, cluster *k3d.Cluster, portSpecs []string) error {
+	existingLB := cluster.ServerLoadBalancer
+	if existingLB == nil || existingLB.Node == nil {
+		return fmt.Errorf("cluster '%s' has no loadbalancer", cluster.Name)
+	}
+	lbChangeset, err := loadbalancerCopy(ctx, existingLB)
+	if err != nil {
+		return err
+	}
+
+	for _, spec := range portSpecs {
+		portmappings, err := nat.ParsePortSpec(spec)
+		if err != nil {
+			return fmt.Errorf("failed to parse port spec '%s': %w", spec, err)
+		}
+		for _, pm := range portmappings {
+			if !loadbalancerRemovePort(lbChangeset, pm) {
+				return fmt.Errorf("port mapping '%s' is not published via the loadbalancer of cluster '%s'", spec, cluster.Name)
+			}
+		}
+	}
+
+	return loadbalancerReplace(ctx, runtime, existingLB, lbChangeset)
+}
+
+// loadbalancerCopy copies the node and config of a loadbalancer to a changeset, which replaces it using loadbalancerReplace
+func loadbalancerCopy(ctx context.Context, existingLB *k3d.Loadbalancer) (*k3d.Loadbalancer, error) {
+	lbChangeset := &k3d.Loadbalancer{}
+
+	// copy existing loadbalancer
+	lbChangesetNode, err := CopyNode(ctx, existingLB.Node, CopyNodeOpts{keepState: false})
+	if err != nil {
+		return nil, fmt.Errorf("error copying existing loadbalancer: %w", err)
+	}
+
+	lbChangeset.Node = lbChangesetNode
+
+	// copy config from existing loadbalancer
+	lbChangesetConfig, err := copystruct.Copy(existingLB.Config)
+	if err != nil {
+		return nil, fmt.Errorf("error copying config from existing loadbalancer: %w", err)
+	}
+
+	lbChangeset.Config = lbChangesetConfig.(*k3d.LoadbalancerConfig)
+	return lbChangeset, nil
+}
+
+// loadbalancerReplace replaces the loadbalancer container with one created from the changeset, writing its config before it starts
+func loadbalancerReplace(ctx context.Context, runtime k3drt.Runtime, existingLB *k3d.Loadbalancer, lbChangeset *k3d.Loadbalancer) error {
 	l.Log().Debugf("ORIGINAL:\n> Ports: %+v\n> Config: %+v\nCHANGESET:\n> Ports: %+v\n> Config: %+v", existingLB.Node.Ports, existingLB.Config, lbChangeset.Node.Ports, lbChangeset.Config)
 
 	// prepare to write config to lb container
@@ -1149,7 +1981,35 @@ func ClusterEditChangesetSimple(ctx context.Context, runtime k3drt.Runtime, clus
 	}
 	lbChangeset.Node.HookActions = append(lbChangeset.Node.HookActions, writeLbConfigAction)
 
-	NodeReplace(ctx, runtime, existingLB.Node, lbChangeset.Node)
+	if err := NodeReplace(ctx, runtime, existingLB.Node, lbChangeset.Node); err != nil {
+		return fmt.Errorf("failed to replace loadbalancer: %w", err)
+	}
 
 	return nil
 }
+
+// loadbalancerRemovePort removes the host bindings of a container port matching the port mapping (host IP and port only if given)
+// from the loadbalancer node and drops the port from its config, if no binding is left. It reports whether it found a matching binding.
+func loadbalancerRemovePort(lb *k3d.Loadbalancer, pm nat.PortMapping) bool {
+	bindings := lb.Node.Ports[pm.Port]
+	remaining := []nat.PortBinding{}
+	for _, binding := range bindings {
+		if (pm.Binding.HostPort == "" || binding.HostPort == pm.Binding.HostPort) && (pm.Binding.HostIP == "" || binding.HostIP == pm.Binding.HostIP) {
+			continue
+		}
+		remaining = append(remaining, binding)
+	}
+	if len(remaining) == len(bindings) {
+		return false
+	}
+
+	if len(remaining) > 0 {
+		lb.Node.Ports[pm.Port] = remaining
+		return true
+	}
+	delete(lb.Node.Ports, pm.Port)
+	if lb.Config != nil {
+		delete(lb.Config.Ports, fmt.Sprintf("%s.%s", pm.Port.Port(), pm.Port.Proto()))
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rancher/k3d/v5/pkg/util"
+)
+
+// lockPath returns the path of an advisory lock file in the k3d config directory ($HOME/.k3d/locks/<kind>-<name>.lock)
+func lockPath(kind string, name string) (string, error) {
+	configDir, err := util.GetConfigDirOrCreate()
+	if err != nil {
+		return "", err
+	}
+	lockDir := filepath.Join(configDir, "locks")
+	if err := os.MkdirAll(lockDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create lock directory '%s': %w", lockDir, err)
+	}
+	return filepath.Join(lockDir, fmt.Sprintf("%s-%s.lock", kind, name)), nil
+}
+
+// ClusterLock acquires the advisory lock for mutations of a cluster (create, start, stop, delete, ...), so that concurrent k3d processes don't interfere
+// Nested operations of the client package (e.g. ClusterRun starting the cluster) don't acquire the lock again.
+func ClusterLock(ctx context.Context, clusterName string) (func(), error) {
+	path, err := lockPath("cluster", clusterName)
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := util.LockFile(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock cluster '%s': %w", clusterName, err)
+	}
+	return unlock, nil
+}
+
+// networkLock acquires the advisory lock for creating/deleting a network, which may be shared by multiple clusters
+// It must only be acquired while holding the lock of a cluster (never the other way round) to prevent deadlocks.
+func networkLock(ctx context.Context, networkName string) (func(), error) {
+	path, err := lockPath("network", networkName)
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := util.LockFile(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock network '%s': %w", networkName, err)
+	}
+	return unlock, nil
+}
@@ -22,11 +22,16 @@ THE SOFTWARE.
 package client
 
 import (
+	"bytes"
 	"fmt"
+	"text/template"
 
 	"github.com/rancher/k3d/v5/pkg/types"
 )
 
+// maxHostnameLength is the maximum length of a single DNS label according to RFC 1123
+const maxHostnameLength = 63
+
 // CheckName ensures that a cluster name is also a valid host name according to RFC 1123.
 // We further restrict the length of the cluster name to maximum 'clusterNameMaxSize'
 // so that we can construct the host names based on the cluster name, and still stay
@@ -67,3 +72,38 @@ func ValidateHostname(name string) error {
 
 	return nil
 }
+
+// NodeNameTemplateValues are the values available in a node name template (see types.DefaultNodeNameTemplate)
+type NodeNameTemplateValues struct {
+	Prefix  string
+	Cluster string
+	Role    types.Role
+	Index   int
+}
+
+// GenerateNodeNameFromTemplate renders a node name (which is also used as the node's hostname) from a text/template
+func GenerateNodeNameFromTemplate(tmpl string, cluster string, role types.Role, index int) (string, error) {
+	t, err := template.New("nodeName").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse node name template '%s': %w", tmpl, err)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, NodeNameTemplateValues{
+		Prefix:  types.DefaultObjectNamePrefix,
+		Cluster: cluster,
+		Role:    role,
+		Index:   index,
+	}); err != nil {
+		return "", fmt.Errorf("failed to render node name template '%s': %w", tmpl, err)
+	}
+
+	name := buf.String()
+	if err := ValidateHostname(name); err != nil {
+		return "", fmt.Errorf("node name template '%s' generated an invalid name: %w", tmpl, err)
+	}
+	if len(name) > maxHostnameLength {
+		return "", fmt.Errorf("node name '%s' generated from template '%s' must be <= %d characters, but has %d", name, tmpl, maxHostnameLength, len(name))
+	}
+	return name, nil
+}
@@ -0,0 +1,57 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"testing"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestGenerateNodeNameFromTemplate(t *testing.T) {
+	// the default template must match the default naming scheme
+	name, err := GenerateNodeNameFromTemplate(k3d.DefaultNodeNameTemplate, "test", k3d.AgentRole, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := GenerateNodeName("test", k3d.AgentRole, 1); name != expected {
+		t.Errorf("expected default template to generate '%s', got '%s'", expected, name)
+	}
+
+	name, err = GenerateNodeNameFromTemplate("{{.Cluster}}-{{.Role}}{{.Index}}", "test", k3d.ServerRole, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if name != "test-server0" {
+		t.Errorf("expected 'test-server0', got '%s'", name)
+	}
+
+	for _, tmpl := range []string{
+		"{{.Cluster}}_{{.Index}}", // invalid hostname
+		"{{.Unknown}}",            // unknown field
+		"{{.Cluster",              // parse error
+	} {
+		if _, err := GenerateNodeNameFromTemplate(tmpl, "test", k3d.ServerRole, 0); err == nil {
+			t.Errorf("expected error for template '%s', got none", tmpl)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	config "github.com/rancher/k3d/v5/pkg/config/v1alpha3"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/rancher/k3d/v5/pkg/types/fixes"
+	"gopkg.in/yaml.v2"
+)
+
+func TestAgentStartRetriesBeforeFailing(t *testing.T) {
+	t.Setenv(string(fixes.EnvFixCgroupV2), "false")
+	agent := newFakeNode("test", "k3d-test-agent-0", k3d.AgentRole, false)
+	rt := &fakeRuntime{
+		nodes:  []*k3d.Node{agent},
+		failOn: map[string]error{"StartNode:k3d-test-agent-0": errors.New("exec format error")},
+	}
+
+	err := agentStart(context.Background(), rt, agent, k3d.ClusterStartOpts{})
+	if err == nil || !strings.Contains(err.Error(), "--recreate-failed") {
+		t.Fatalf("expected an error hinting at --recreate-failed, got %v", err)
+	}
+	if starts := rt.callsOf("StartNode"); len(starts) != agentStartAttempts {
+		t.Errorf("expected %d start attempts, got %v", agentStartAttempts, starts)
+	}
+	if stops := rt.callsOf("StopNode"); len(stops) != agentStartAttempts {
+		t.Errorf("expected the agent to be stopped after each failed attempt, got %v", stops)
+	}
+}
+
+func TestEnvSet(t *testing.T) {
+	env := envSet([]string{"K3S_TOKEN=secret", "HTTP_PROXY=old", "NO_VALUE"}, "HTTP_PROXY=new")
+	if strings.Join(env, ",") != "K3S_TOKEN=secret,NO_VALUE,HTTP_PROXY=new" {
+		t.Errorf("expected HTTP_PROXY to be replaced, got %v", env)
+	}
+	env = envSet(env, "NO_VALUE=set")
+	if strings.Join(env, ",") != "K3S_TOKEN=secret,HTTP_PROXY=new,NO_VALUE=set" {
+		t.Errorf("expected NO_VALUE to be replaced, got %v", env)
+	}
+}
+
+func TestClusterRecordedConfig(t *testing.T) {
+	newNode := func(name, created, recorded string) *k3d.Node {
+		node := &k3d.Node{Name: name, Created: created, RuntimeLabels: map[string]string{}}
+		if recorded != "" {
+			node.RuntimeLabels[k3d.LabelClusterCreateConfig] = recorded
+		}
+		return node
+	}
+
+	cluster := &k3d.Cluster{Nodes: []*k3d.Node{
+		newNode("k3d-test-serverlb", "2021-10-01T12:00:03Z", ""),
+		newNode("k3d-test-agent-0", "2021-10-02T08:00:00.5Z", "edited"),
+		newNode("k3d-test-server-0", "2021-10-01T12:00:00.123456789Z", "original"),
+	}}
+	node, recorded, ok := ClusterRecordedConfig(cluster)
+	if !ok || recorded != "edited" || node.Name != "k3d-test-agent-0" {
+		t.Errorf("expected the config of the most recently created node, got '%s' (found: %t)", recorded, ok)
+	}
+
+	if _, _, ok := ClusterRecordedConfig(&k3d.Cluster{Nodes: []*k3d.Node{newNode("k3d-test-server-0", "", "")}}); ok {
+		t.Errorf("expected no recorded config")
+	}
+}
+
+func TestRecordedConfigAppend(t *testing.T) {
+	recorded := `apiVersion: k3d.io/v1alpha3
+kind: Simple
+name: test
+env:
+- envVar: FOO=bar
+  nodeFilters:
+  - server:0
+options:
+  k3d:
+    timeout: 60s
+  k3s:
+    extraArgs: []
+`
+	changeset := &config.SimpleConfig{}
+	changeset.Env = []config.EnvVarWithNodeFilters{{EnvVar: "HTTP_PROXY=my.proxy.com"}}
+	changeset.Options.K3sOptions.ExtraArgs = []config.K3sArgWithNodeFilters{{Arg: "--disable=traefik", NodeFilters: []string{"server"}}}
+
+	updated, err := recordedConfigAppend(recorded, changeset)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var result config.SimpleConfig
+	if err := yaml.Unmarshal([]byte(updated), &result); err != nil {
+		t.Fatalf("failed to parse updated config: %v\n%s", err, updated)
+	}
+	if len(result.Env) != 2 || result.Env[1].EnvVar != "HTTP_PROXY=my.proxy.com" || strings.Join(result.Env[1].NodeFilters, ",") != "server:*,agent:*" {
+		t.Errorf("expected the env var to be appended for all nodes, got %+v", result.Env)
+	}
+	if len(result.Options.K3sOptions.ExtraArgs) != 1 || result.Options.K3sOptions.ExtraArgs[0].Arg != "--disable=traefik" {
+		t.Errorf("expected the k3s arg to be appended, got %+v", result.Options.K3sOptions.ExtraArgs)
+	}
+	if !strings.Contains(updated, "timeout: 60s") || !strings.HasPrefix(updated, "apiVersion: k3d.io/v1alpha3\n") {
+		t.Errorf("expected the rest of the config to be unchanged, got:\n%s", updated)
+	}
+
+	// options may be missing entirely
+	updated, err = recordedConfigAppend("name: test\n", changeset)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(updated, "extraArgs:\n    - arg: --disable=traefik") {
+		t.Errorf("expected the k3s arg to be added in new options, got:\n%s", updated)
+	}
+}
+
+func TestPrepInjectHostIP(t *testing.T) {
+	server := newFakeNode("test", "k3d-test-server-0", k3d.ServerRole, true)
+	agent := newFakeNode("test", "k3d-test-agent-0", k3d.AgentRole, true)
+	cluster := &k3d.Cluster{Name: "test", Network: k3d.ClusterNetwork{Name: "k3d-test"}, Nodes: []*k3d.Node{server, agent}}
+	opts := &k3d.ClusterStartOpts{EnvironmentInfo: &k3d.EnvironmentInfo{HostGateway: net.ParseIP("172.18.0.1")}}
+
+	rt := &fakeRuntime{}
+	if err := prepInjectHostIP(context.Background(), rt, cluster, opts); err != nil {
+		t.Fatal(err)
+	}
+	for _, node := range cluster.Nodes {
+		if execs := strings.Join(rt.execs[node.Name], "\n"); !strings.Contains(execs, "echo '172.18.0.1 host.k3d.internal' >> /etc/hosts") {
+			t.Errorf("expected the host entry in /etc/hosts of node '%s', got %s", node.Name, execs)
+		}
+	}
+	if execs := strings.Join(rt.execs[server.Name], "\n"); !strings.Contains(execs, "kubectl patch cm coredns") {
+		t.Errorf("expected the CoreDNS NodeHosts to be patched, got %s", execs)
+	}
+
+	// opted out at creation time
+	server.RuntimeLabels[k3d.LabelHostIPDisabled] = "true"
+	agent.RuntimeLabels[k3d.LabelHostIPDisabled] = "true"
+	rt = &fakeRuntime{}
+	if err := prepInjectHostIP(context.Background(), rt, cluster, opts); err != nil {
+		t.Fatal(err)
+	}
+	if len(rt.calls) != 0 {
+		t.Errorf("expected no host entries, got calls %v", rt.calls)
+	}
+}
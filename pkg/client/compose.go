@@ -0,0 +1,213 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	k3drt "github.com/rancher/k3d/v5/pkg/runtimes"
+	runtimeErr "github.com/rancher/k3d/v5/pkg/runtimes/errors"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"gopkg.in/yaml.v2"
+)
+
+// composeProjectNameInvalidChars matches all characters that docker compose removes from project names
+var composeProjectNameInvalidChars = regexp.MustCompile(`[^a-z0-9_-]`)
+
+// composeFile is the part of a compose file that's relevant for attaching its services to a cluster
+type composeFile struct {
+	Name     string `yaml:"name"`
+	Services map[string]struct {
+		ContainerName string `yaml:"container_name"`
+	} `yaml:"services"`
+}
+
+// ComposeProjectFromFile reads the services of a compose file.
+// The project name is determined like docker compose does: projectName (-p) > $COMPOSE_PROJECT_NAME > 'name' in the file > name of the file's directory
+func ComposeProjectFromFile(path string, projectName string) (*k3d.ComposeProject, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read compose file '%s': %w", path, err)
+	}
+
+	var file composeFile
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse compose file '%s': %w", path, err)
+	}
+	if len(file.Services) == 0 {
+		return nil, fmt.Errorf("compose file '%s' doesn't define any services", path)
+	}
+
+	if projectName == "" {
+		projectName = os.Getenv("COMPOSE_PROJECT_NAME")
+	}
+	if projectName == "" {
+		projectName = file.Name
+	}
+	if projectName == "" {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the directory of compose file '%s': %w", path, err)
+		}
+		projectName = filepath.Base(filepath.Dir(abs))
+	}
+	projectName = composeProjectNameInvalidChars.ReplaceAllString(strings.ToLower(projectName), "")
+	if projectName == "" {
+		return nil, fmt.Errorf("failed to determine the compose project name for '%s': please set it explicitly", path)
+	}
+
+	project := &k3d.ComposeProject{Name: projectName}
+	for name, service := range file.Services {
+		project.Services = append(project.Services, k3d.ComposeService{Name: name, ContainerName: service.ContainerName})
+	}
+	sort.Slice(project.Services, func(i, j int) bool { return project.Services[i].Name < project.Services[j].Name })
+
+	return project, nil
+}
+
+// composeServiceContainerNames returns the possible names of the n-th container of a service (compose v2 and v1 naming)
+func composeServiceContainerNames(project *k3d.ComposeProject, service k3d.ComposeService, n int) []string {
+	if service.ContainerName != "" {
+		if n > 1 {
+			return nil // fixed container names can't be scaled
+		}
+		return []string{service.ContainerName}
+	}
+	return []string{
+		fmt.Sprintf("%s-%s-%d", project.Name, service.Name, n),
+		fmt.Sprintf("%s_%s_%d", project.Name, service.Name, n),
+	}
+}
+
+// ComposeAttach connects all (running) containers of a compose project to the cluster network and injects host entries both ways:
+// the compose service names into the nodes' /etc/hosts and the CoreDNS ConfigMap (so that pods can reach them)
+// and the node names into the /etc/hosts of the compose containers (if they have a shell).
+func ComposeAttach(ctx context.Context, runtime k3drt.Runtime, cluster *k3d.Cluster, project *k3d.ComposeProject) error {
+	if cluster.Network.Name == "host" {
+		return fmt.Errorf("cannot attach compose services to cluster '%s' as it uses the host network", cluster.Name)
+	}
+
+	// (1) connect the service containers to the cluster network
+	serviceContainers := map[string][]string{} // service name -> container names
+	for _, service := range project.Services {
+		for n := 1; ; n++ {
+			candidates := composeServiceContainerNames(project, service, n)
+			connected := ""
+			for _, container := range candidates {
+				err := runtime.ConnectContainerToNetwork(ctx, container, cluster.Network.Name)
+				if err == nil {
+					connected = container
+					break
+				}
+				if !errors.Is(err, runtimeErr.ErrRuntimeContainerNotExists) {
+					return fmt.Errorf("failed to connect container '%s' of service '%s' to the cluster network '%s': %w", container, service.Name, cluster.Network.Name, err)
+				}
+			}
+			if connected == "" {
+				break
+			}
+			l.Log().Infof("Connected container '%s' of service '%s' to the cluster network '%s'", connected, service.Name, cluster.Network.Name)
+			serviceContainers[service.Name] = append(serviceContainers[service.Name], connected)
+		}
+		if len(serviceContainers[service.Name]) == 0 {
+			l.Log().Warnf("No container found for service '%s' of compose project '%s': is it running?", service.Name, project.Name)
+		}
+	}
+	if len(serviceContainers) == 0 {
+		return fmt.Errorf("no containers found for compose project '%s': did you run 'docker compose up'?", project.Name)
+	}
+
+	// (2) get the IPs of all containers in the cluster network
+	network, err := runtime.GetNetwork(ctx, &cluster.Network)
+	if err != nil {
+		return fmt.Errorf("failed to get cluster network '%s': %w", cluster.Network.Name, err)
+	}
+	memberIPs := map[string]string{}
+	for _, member := range network.Members {
+		memberIPs[member.Name] = member.IP.String()
+	}
+
+	// (3) compose services -> cluster: the service name resolves to the IP of its first container, the container names to their own IPs
+	serviceEntries := [][2]string{} // ip, name
+	for _, service := range project.Services {
+		for i, container := range serviceContainers[service.Name] {
+			ip, ok := memberIPs[container]
+			if !ok {
+				return fmt.Errorf("failed to get the IP of container '%s' in the cluster network '%s'", container, cluster.Network.Name)
+			}
+			if i == 0 {
+				serviceEntries = append(serviceEntries, [2]string{ip, service.Name})
+			}
+			if container != service.Name {
+				serviceEntries = append(serviceEntries, [2]string{ip, container})
+			}
+		}
+	}
+	for _, entry := range serviceEntries {
+		hostsEntry := fmt.Sprintf("%s %s", entry[0], entry[1])
+		l.Log().Infof("Injecting record '%s' into the cluster...", hostsEntry)
+		for _, node := range cluster.Nodes {
+			if node.Role != k3d.ServerRole && node.Role != k3d.AgentRole {
+				continue
+			}
+			if err := runtime.ExecInNode(ctx, node, []string{"sh", "-c", fmt.Sprintf("sed -i -E '/\\s%s$/d' /etc/hosts && echo '%s' >> /etc/hosts", entry[1], hostsEntry)}); err != nil {
+				return fmt.Errorf("failed to add hosts entry '%s' to node '%s': %w", hostsEntry, node.Name, err)
+			}
+		}
+		if err := corednsAddHost(ctx, runtime, cluster, entry[0], entry[1]); err != nil {
+			return fmt.Errorf("failed to inject record '%s' into CoreDNS: %w", hostsEntry, err)
+		}
+	}
+
+	// (4) cluster -> compose services: the node names (e.g. the loadbalancer as API endpoint)
+	// Those also resolve via the network's DNS, so containers without a shell are skipped.
+	nodeEntries := []string{}
+	for _, node := range cluster.Nodes {
+		if ip, ok := memberIPs[node.Name]; ok {
+			nodeEntries = append(nodeEntries, fmt.Sprintf("%s %s", ip, node.Name))
+		}
+	}
+	if len(nodeEntries) == 0 {
+		return nil
+	}
+	for _, service := range project.Services {
+		for _, container := range serviceContainers[service.Name] {
+			cmd := fmt.Sprintf("printf '%%s\\n' '%s' >> /etc/hosts", strings.Join(nodeEntries, "' '"))
+			if err := runtime.ExecInNode(ctx, &k3d.Node{Name: container}, []string{"sh", "-c", cmd}); err != nil {
+				l.Log().Debugf("Failed to add the cluster's hosts entries to container '%s' (it still resolves the node names via the network's DNS): %v", container, err)
+				continue
+			}
+			l.Log().Debugf("Added %d hosts entries for the cluster nodes to container '%s'", len(nodeEntries), container)
+		}
+	}
+
+	return nil
+}
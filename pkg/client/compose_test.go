@@ -0,0 +1,148 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	runtimeErr "github.com/rancher/k3d/v5/pkg/runtimes/errors"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"inet.af/netaddr"
+)
+
+func TestComposeProjectFromFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "My App")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "docker-compose.yml")
+	write := func(content string) {
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("services:\n  web:\n    image: nginx\n  db:\n    image: postgres\n    container_name: postgres\n")
+	project, err := ComposeProjectFromFile(file, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &k3d.ComposeProject{Name: "myapp", Services: []k3d.ComposeService{{Name: "db", ContainerName: "postgres"}, {Name: "web"}}}
+	if !reflect.DeepEqual(project, expected) {
+		t.Errorf("expected project %+v (named after the directory), got %+v", expected, project)
+	}
+
+	write("name: shop\nservices:\n  web:\n    image: nginx\n")
+	if project, err := ComposeProjectFromFile(file, ""); err != nil || project.Name != "shop" {
+		t.Errorf("expected the project name from the file, got %+v (%v)", project, err)
+	}
+	os.Setenv("COMPOSE_PROJECT_NAME", "fromenv")
+	defer os.Unsetenv("COMPOSE_PROJECT_NAME")
+	if project, err := ComposeProjectFromFile(file, ""); err != nil || project.Name != "fromenv" {
+		t.Errorf("expected the project name from the environment, got %+v (%v)", project, err)
+	}
+	if project, err := ComposeProjectFromFile(file, "Explicit"); err != nil || project.Name != "explicit" {
+		t.Errorf("expected the explicit project name, got %+v (%v)", project, err)
+	}
+
+	write("version: '3'\n")
+	if _, err := ComposeProjectFromFile(file, ""); err == nil {
+		t.Errorf("expected an error for a compose file without services")
+	}
+}
+
+func TestComposeAttach(t *testing.T) {
+	cluster := &k3d.Cluster{
+		Name:    "test",
+		Network: k3d.ClusterNetwork{Name: "k3d-test"},
+		Nodes: []*k3d.Node{
+			{Name: "k3d-test-server-0", Role: k3d.ServerRole},
+			{Name: "k3d-test-serverlb", Role: k3d.LoadBalancerRole},
+		},
+	}
+	project := &k3d.ComposeProject{Name: "app", Services: []k3d.ComposeService{{Name: "db", ContainerName: "postgres"}, {Name: "web"}}}
+
+	notExists := func(container string) error {
+		return fmt.Errorf("container '%s': %w", container, runtimeErr.ErrRuntimeContainerNotExists)
+	}
+	runtime := &fakeRuntime{
+		failOn: map[string]error{
+			// web is scaled to two compose v1 containers
+			"ConnectContainerToNetwork:app-web-1@k3d-test": notExists("app-web-1"),
+			"ConnectContainerToNetwork:app-web-2@k3d-test": notExists("app-web-2"),
+			"ConnectContainerToNetwork:app-web-3@k3d-test": notExists("app-web-3"),
+			"ConnectContainerToNetwork:app_web_3@k3d-test": notExists("app_web_3"),
+		},
+		network: &k3d.ClusterNetwork{Name: "k3d-test", Members: []*k3d.NetworkMember{
+			{Name: "k3d-test-server-0", IP: netaddr.MustParseIP("172.18.0.2")},
+			{Name: "k3d-test-serverlb", IP: netaddr.MustParseIP("172.18.0.3")},
+			{Name: "postgres", IP: netaddr.MustParseIP("172.18.0.4")},
+			{Name: "app_web_1", IP: netaddr.MustParseIP("172.18.0.5")},
+			{Name: "app_web_2", IP: netaddr.MustParseIP("172.18.0.6")},
+		}},
+	}
+
+	if err := ComposeAttach(context.Background(), runtime, cluster, project); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedConnects := []string{"postgres@k3d-test", "app-web-1@k3d-test", "app_web_1@k3d-test", "app-web-2@k3d-test", "app_web_2@k3d-test", "app-web-3@k3d-test", "app_web_3@k3d-test"}
+	if calls := runtime.callsOf("ConnectContainerToNetwork"); !reflect.DeepEqual(calls, expectedConnects) {
+		t.Errorf("expected connects %v, got %v", expectedConnects, calls)
+	}
+
+	// cluster side: /etc/hosts + CoreDNS for the service names (first container) and all container names
+	serverExecs := strings.Join(runtime.execs["k3d-test-server-0"], "\n")
+	for _, entry := range []string{"172.18.0.4 db", "172.18.0.4 postgres", "172.18.0.5 web", "172.18.0.5 app_web_1", "172.18.0.6 app_web_2"} {
+		if strings.Count(serverExecs, entry) != 2 {
+			t.Errorf("expected entry '%s' in /etc/hosts and CoreDNS of the server, got execs:\n%s", entry, serverExecs)
+		}
+	}
+	if _, ok := runtime.execs["k3d-test-serverlb"]; ok {
+		t.Errorf("expected no execs in the loadbalancer")
+	}
+
+	// compose side: /etc/hosts entries for the nodes
+	for _, container := range []string{"postgres", "app_web_1", "app_web_2"} {
+		execs := strings.Join(runtime.execs[container], "\n")
+		if !strings.Contains(execs, "172.18.0.2 k3d-test-server-0") || !strings.Contains(execs, "172.18.0.3 k3d-test-serverlb") {
+			t.Errorf("expected node entries in /etc/hosts of container '%s', got execs:\n%s", container, execs)
+		}
+	}
+}
+
+func TestComposeAttachNoContainers(t *testing.T) {
+	cluster := &k3d.Cluster{Name: "test", Network: k3d.ClusterNetwork{Name: "k3d-test"}}
+	project := &k3d.ComposeProject{Name: "app", Services: []k3d.ComposeService{{Name: "db", ContainerName: "postgres"}}}
+	runtime := &fakeRuntime{failOn: map[string]error{"ConnectContainerToNetwork:postgres@k3d-test": fmt.Errorf("container 'postgres': %w", runtimeErr.ErrRuntimeContainerNotExists)}}
+
+	if err := ComposeAttach(context.Background(), runtime, cluster, project); err == nil {
+		t.Errorf("expected an error if none of the services is running")
+	}
+}
@@ -0,0 +1,48 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+// dockerdEntrypoint starts the docker daemon inside a node (in the background) and waits for its socket, before the k3d entrypoint
+// starts k3s with --docker, which talks to it via cri-dockerd. The stock k3s images don't ship dockerd, so this fails with a hint
+// (in the entrypoint log /var/log/k3d-entrypoints_*.log of the node) unless the image has been extended with docker.
+const dockerdEntrypoint = `#!/bin/sh
+set -o errexit
+
+if ! command -v dockerd >/dev/null 2>&1; then
+  echo "[ERROR] the node image doesn't ship dockerd, which the CRI 'cri-dockerd' requires: use an image with docker installed or the default CRI 'containerd'"
+  exit 1
+fi
+
+dockerd --host=unix:///var/run/docker.sock >/var/log/dockerd.log 2>&1 &
+
+i=0
+while [ "$i" -lt 60 ]; do
+  if [ -S /var/run/docker.sock ]; then
+    echo "docker daemon is up"
+    exit 0
+  fi
+  i=$((i + 1))
+  sleep 1
+done
+echo "[ERROR] the docker daemon didn't come up within 60s, see /var/log/dockerd.log"
+exit 1
+`
@@ -0,0 +1,191 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// ClusterDependencyParse parses a dependency of a cluster given as KIND:NAME, e.g. 'registry:k3d-myregistry' or 'cluster:database'
+func ClusterDependencyParse(s string) (k3d.ClusterDependency, error) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return k3d.ClusterDependency{}, fmt.Errorf("invalid dependency '%s': expected KIND:NAME, e.g. 'cluster:database' or 'registry:k3d-myregistry'", s)
+	}
+	kind, name := parts[0], parts[1]
+	switch kind {
+	case k3d.ClusterDependencyRegistry, k3d.ClusterDependencyCluster:
+	default:
+		return k3d.ClusterDependency{}, fmt.Errorf("invalid dependency '%s': unknown kind '%s' (expected '%s' or '%s')", s, kind, k3d.ClusterDependencyRegistry, k3d.ClusterDependencyCluster)
+	}
+	return k3d.ClusterDependency{Kind: kind, Name: name}, nil
+}
+
+// ClusterDependencyAdd adds a dependency to the cluster, unless it's already declared
+func ClusterDependencyAdd(cluster *k3d.Cluster, dep k3d.ClusterDependency) {
+	for _, existing := range cluster.DependsOn {
+		if existing == dep {
+			return
+		}
+	}
+	cluster.DependsOn = append(cluster.DependsOn, dep)
+}
+
+// clusterDependenciesLabel formats the dependencies of a cluster for the LabelClusterDependsOn label
+func clusterDependenciesLabel(deps []k3d.ClusterDependency) string {
+	formatted := make([]string, 0, len(deps))
+	for _, dep := range deps {
+		formatted = append(formatted, dep.String())
+	}
+	return strings.Join(formatted, ",")
+}
+
+// clusterDependenciesFromLabel parses the LabelClusterDependsOn label, skipping invalid entries
+func clusterDependenciesFromLabel(label string) []k3d.ClusterDependency {
+	var deps []k3d.ClusterDependency
+	for _, s := range strings.Split(label, ",") {
+		if s == "" {
+			continue
+		}
+		dep, err := ClusterDependencyParse(s)
+		if err != nil {
+			l.Log().Debugf("Ignoring dependency label entry: %v", err)
+			continue
+		}
+		deps = append(deps, dep)
+	}
+	return deps
+}
+
+// ClusterStartOrder computes the order in which the given clusters have to be started, so that each cluster comes up after the registries and clusters it depends on.
+// Clusters the given ones depend on are included, even if they weren't given; dependencies which don't exist (anymore) are ignored with a warning.
+// Registries bound to one of the clusters are included as well, as they're needed by the nodes right away.
+func ClusterStartOrder(ctx context.Context, runtime runtimes.Runtime, clusters []*k3d.Cluster) (*k3d.ClusterStartPlan, error) {
+	plan := &k3d.ClusterStartPlan{
+		Required: map[string]bool{},
+	}
+
+	// collect the clusters incl. the ones they (transitively) depend on, keeping the given order
+	byName := map[string]*k3d.Cluster{}
+	var all []*k3d.Cluster
+	for _, c := range clusters {
+		if _, ok := byName[c.Name]; !ok {
+			byName[c.Name] = c
+			all = append(all, c)
+		}
+	}
+	missing := map[string]bool{}
+	for i := 0; i < len(all); i++ {
+		for _, dep := range all[i].DependsOn {
+			if dep.Kind != k3d.ClusterDependencyCluster || missing[dep.Name] {
+				continue
+			}
+			if _, ok := byName[dep.Name]; !ok {
+				depCluster, err := ClusterGet(ctx, runtime, &k3d.Cluster{Name: dep.Name})
+				if err != nil {
+					l.Log().Warnf("Cluster '%s' depends on cluster '%s', which doesn't exist: ignoring the dependency", all[i].Name, dep.Name)
+					missing[dep.Name] = true
+					continue
+				}
+				l.Log().Infof("Including cluster '%s', as cluster '%s' depends on it", dep.Name, all[i].Name)
+				byName[dep.Name] = depCluster
+				all = append(all, depCluster)
+			}
+			plan.Required[dep.Name] = true
+		}
+	}
+
+	// order them depth-first, so that dependencies come first
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	var path []string
+	var visit func(c *k3d.Cluster) error
+	visit = func(c *k3d.Cluster) error {
+		switch state[c.Name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle between clusters: %s -> %s", strings.Join(path, " -> "), c.Name)
+		}
+		state[c.Name] = visiting
+		path = append(path, c.Name)
+		for _, dep := range c.DependsOn {
+			if depCluster, ok := byName[dep.Name]; ok && dep.Kind == k3d.ClusterDependencyCluster {
+				if err := visit(depCluster); err != nil {
+					return err
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[c.Name] = visited
+		plan.Clusters = append(plan.Clusters, c)
+		return nil
+	}
+	for _, c := range all {
+		if err := visit(c); err != nil {
+			return nil, err
+		}
+	}
+
+	// collect the registries of all clusters
+	registries := map[string]*k3d.Node{}
+	for _, c := range plan.Clusters {
+		for _, node := range c.Nodes {
+			if node.Role == k3d.RegistryRole {
+				registries[node.Name] = node
+			}
+		}
+		for _, dep := range c.DependsOn {
+			if dep.Kind != k3d.ClusterDependencyRegistry {
+				continue
+			}
+			if _, ok := registries[dep.Name]; ok {
+				continue
+			}
+			regNode, err := runtime.GetNode(ctx, &k3d.Node{Name: dep.Name})
+			if err != nil {
+				l.Log().Warnf("Cluster '%s' depends on registry '%s', which doesn't exist: ignoring the dependency", c.Name, dep.Name)
+				continue
+			}
+			registries[dep.Name] = regNode
+		}
+	}
+	for _, reg := range registries {
+		plan.Registries = append(plan.Registries, reg)
+	}
+	sort.Slice(plan.Registries, func(i, j int) bool {
+		return plan.Registries[i].Name < plan.Registries[j].Name
+	})
+
+	return plan, nil
+}
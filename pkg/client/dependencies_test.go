@@ -0,0 +1,104 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestClusterDependencyParse(t *testing.T) {
+	dep, err := ClusterDependencyParse("registry:k3d-registry.localhost")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dep != (k3d.ClusterDependency{Kind: k3d.ClusterDependencyRegistry, Name: "k3d-registry.localhost"}) {
+		t.Errorf("unexpected dependency %+v", dep)
+	}
+	for _, invalid := range []string{"database", "cluster:", "volume:data"} {
+		if _, err := ClusterDependencyParse(invalid); err == nil {
+			t.Errorf("expected an error for '%s'", invalid)
+		}
+	}
+
+	deps := []k3d.ClusterDependency{dep, {Kind: k3d.ClusterDependencyCluster, Name: "database"}}
+	if parsed := clusterDependenciesFromLabel(clusterDependenciesLabel(deps)); !reflect.DeepEqual(parsed, deps) {
+		t.Errorf("expected %+v from the label, got %+v", deps, parsed)
+	}
+}
+
+func TestClusterStartOrder(t *testing.T) {
+	registry := newFakeNode("", "k3d-shared-registry", k3d.RegistryRole, false)
+	delete(registry.RuntimeLabels, k3d.LabelClusterName)
+	database := newFakeNode("database", "k3d-database-server-0", k3d.ServerRole, false)
+	runtime := &fakeRuntime{nodes: []*k3d.Node{registry, database}}
+
+	app := &k3d.Cluster{
+		Name: "app",
+		Nodes: []*k3d.Node{
+			newFakeNode("app", "k3d-app-server-0", k3d.ServerRole, false),
+			newFakeNode("app", "k3d-app-registry", k3d.RegistryRole, false),
+		},
+		DependsOn: []k3d.ClusterDependency{
+			{Kind: k3d.ClusterDependencyCluster, Name: "database"},
+			{Kind: k3d.ClusterDependencyRegistry, Name: "k3d-shared-registry"},
+			{Kind: k3d.ClusterDependencyCluster, Name: "gone"},
+		},
+	}
+	frontend := &k3d.Cluster{
+		Name:      "frontend",
+		DependsOn: []k3d.ClusterDependency{{Kind: k3d.ClusterDependencyCluster, Name: "app"}},
+	}
+
+	// the database cluster isn't selected, but has to be started first
+	plan, err := ClusterStartOrder(context.Background(), runtime, []*k3d.Cluster{frontend, app})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var order []string
+	for _, c := range plan.Clusters {
+		order = append(order, c.Name)
+	}
+	if strings.Join(order, ",") != "database,app,frontend" {
+		t.Errorf("expected the clusters to be started as database,app,frontend, got %v", order)
+	}
+	if !plan.Required["database"] || !plan.Required["app"] || plan.Required["frontend"] {
+		t.Errorf("expected database and app to be waited for, got %v", plan.Required)
+	}
+	var registries []string
+	for _, reg := range plan.Registries {
+		registries = append(registries, reg.Name)
+	}
+	if strings.Join(registries, ",") != "k3d-app-registry,k3d-shared-registry" {
+		t.Errorf("expected the bound and the shared registry to be started first, got %v", registries)
+	}
+
+	// cycles can't be resolved
+	app.DependsOn = append(app.DependsOn, k3d.ClusterDependency{Kind: k3d.ClusterDependencyCluster, Name: "frontend"})
+	if _, err := ClusterStartOrder(context.Background(), runtime, []*k3d.Cluster{frontend, app}); err == nil || !strings.Contains(err.Error(), "frontend -> app -> frontend") {
+		t.Errorf("expected a dependency cycle error, got %v", err)
+	}
+}
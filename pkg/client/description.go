@@ -0,0 +1,70 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// ClusterDescription returns the current description of a cluster: the one set via ClusterAnnotate or, if it was never changed, the one given at creation time.
+// The cluster is expected to be populated by ClusterGet (incl. the description label).
+// Servers added after the last annotation don't have the file, so all of them are checked.
+func ClusterDescription(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster) (string, error) {
+	for _, node := range cluster.Nodes {
+		if node.Role != k3d.ServerRole {
+			continue
+		}
+		content, err := nodeReadFile(ctx, runtime, node, k3d.DefaultClusterDescriptionPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read description of cluster '%s' from node '%s': %w", cluster.Name, node.Name, err)
+		}
+		if content != nil {
+			return strings.TrimSuffix(string(content), "\n"), nil // written with a trailing newline, so that an empty description can be told apart from none
+		}
+	}
+	return cluster.Description, nil
+}
+
+// ClusterAnnotate replaces the description of a cluster.
+// Container labels can't be changed, so it's stored in a file in all server nodes, which takes precedence over the label set at creation time.
+func ClusterAnnotate(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster, description string) error {
+	annotated := false
+	for _, node := range cluster.Nodes {
+		if node.Role != k3d.ServerRole {
+			continue
+		}
+		if err := runtime.WriteToNode(ctx, []byte(description+"\n"), k3d.DefaultClusterDescriptionPath, 0644, node); err != nil {
+			return fmt.Errorf("failed to write description of cluster '%s' to node '%s': %w", cluster.Name, node.Name, err)
+		}
+		annotated = true
+	}
+	if !annotated {
+		return fmt.Errorf("cluster '%s' has no server nodes to store the description in", cluster.Name)
+	}
+	cluster.Description = description
+	return nil
+}
@@ -0,0 +1,67 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"testing"
+
+	runtimeErrors "github.com/rancher/k3d/v5/pkg/runtimes/errors"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestClusterDescription(t *testing.T) {
+	cluster := &k3d.Cluster{
+		Name:        "test",
+		Description: "payment team e2e", // from the label set at creation time
+		Nodes: []*k3d.Node{
+			{Name: "k3d-test-server-0", Role: k3d.ServerRole},
+			{Name: "k3d-test-agent-0", Role: k3d.AgentRole},
+		},
+	}
+	runtime := &fakeRuntime{failOn: map[string]error{"ReadFromNode:k3d-test-server-0": runtimeErrors.ErrRuntimeFileNotFound}}
+
+	description, err := ClusterDescription(context.Background(), runtime, cluster)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if description != "payment team e2e" {
+		t.Errorf("expected the description from the label, got %q", description)
+	}
+
+	if err := ClusterAnnotate(context.Background(), runtime, cluster, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if written := runtime.callsOf("WriteToNode"); len(written) != 1 || written[0] != k3d.DefaultClusterDescriptionPath {
+		t.Errorf("expected the description to be written to the server only, got %v", written)
+	}
+
+	delete(runtime.failOn, "ReadFromNode:k3d-test-server-0")
+	cluster.Description = "payment team e2e" // as read from the label again
+	description, err = ClusterDescription(context.Background(), runtime, cluster)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if description != "" {
+		t.Errorf("expected the cleared description to take precedence over the label, got %q", description)
+	}
+}
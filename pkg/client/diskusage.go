@@ -0,0 +1,88 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	runtimeTypes "github.com/rancher/k3d/v5/pkg/runtimes/types"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// ClusterGetDiskUsage returns the disk space consumed by the given clusters
+func ClusterGetDiskUsage(ctx context.Context, runtime runtimes.Runtime, clusters []*k3d.Cluster) ([]*k3d.ClusterDiskUsage, error) {
+	du, err := runtime.GetDiskUsage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get disk usage from runtime: %w", err)
+	}
+
+	usages := make([]*k3d.ClusterDiskUsage, 0, len(clusters))
+	for _, cluster := range clusters {
+		usages = append(usages, clusterDiskUsage(cluster, du))
+	}
+	return usages, nil
+}
+
+// clusterDiskUsage sums up the disk usage of all containers and volumes belonging to a cluster
+// Each volume is only counted once per cluster, even if it's mounted into multiple nodes
+func clusterDiskUsage(cluster *k3d.Cluster, du *runtimeTypes.DiskUsage) *k3d.ClusterDiskUsage {
+	usage := &k3d.ClusterDiskUsage{Cluster: cluster.Name}
+
+	volumeSize := func(name string) int64 {
+		if size, ok := du.Volumes[name]; ok && size > 0 {
+			return size
+		}
+		return 0
+	}
+
+	seenVolumes := map[string]struct{}{}
+	if cluster.ImageVolume != "" {
+		seenVolumes[cluster.ImageVolume] = struct{}{}
+		usage.ImageVolume = volumeSize(cluster.ImageVolume)
+	}
+
+	for _, node := range cluster.Nodes {
+		cont, ok := du.Containers[node.Name]
+		if !ok {
+			continue
+		}
+		var volumes int64
+		for _, vol := range cont.Volumes {
+			if _, seen := seenVolumes[vol]; seen {
+				continue
+			}
+			seenVolumes[vol] = struct{}{}
+			volumes += volumeSize(vol)
+		}
+		if node.Role == k3d.RegistryRole {
+			usage.Registries += cont.SizeRw + volumes
+		} else {
+			usage.Nodes += cont.SizeRw
+			usage.Volumes += volumes
+		}
+	}
+
+	usage.Total = usage.Nodes + usage.ImageVolume + usage.Volumes + usage.Registries
+	return usage
+}
@@ -0,0 +1,69 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+	runtimeTypes "github.com/rancher/k3d/v5/pkg/runtimes/types"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func Test_clusterDiskUsage(t *testing.T) {
+	cluster := &k3d.Cluster{
+		Name:        "test",
+		ImageVolume: "k3d-test-images",
+		Nodes: []*k3d.Node{
+			{Name: "k3d-test-server-0", Role: k3d.ServerRole},
+			{Name: "k3d-test-agent-0", Role: k3d.AgentRole},
+			{Name: "k3d-test-registry", Role: k3d.RegistryRole},
+			{Name: "k3d-test-gone", Role: k3d.AgentRole},
+		},
+	}
+	du := &runtimeTypes.DiskUsage{
+		Containers: map[string]runtimeTypes.ContainerDiskUsage{
+			"k3d-test-server-0": {SizeRw: 100, Volumes: []string{"k3d-test-images", "shared", "anon-server"}},
+			"k3d-test-agent-0":  {SizeRw: 50, Volumes: []string{"k3d-test-images", "shared"}},
+			"k3d-test-registry": {SizeRw: 5, Volumes: []string{"registry-data"}},
+		},
+		Volumes: map[string]int64{
+			"k3d-test-images": 1000,
+			"shared":          20,
+			"anon-server":     -1,
+			"registry-data":   300,
+		},
+	}
+
+	expected := &k3d.ClusterDiskUsage{
+		Cluster:     "test",
+		Nodes:       150,
+		ImageVolume: 1000,
+		Volumes:     20,
+		Registries:  305,
+		Total:       1475,
+	}
+
+	if diff := deep.Equal(clusterDiskUsage(cluster, du), expected); diff != nil {
+		t.Errorf("unexpected disk usage: %+v", diff)
+	}
+}
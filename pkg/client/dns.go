@@ -0,0 +1,137 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/docker/go-connections/nat"
+	config "github.com/rancher/k3d/v5/pkg/config/v1alpha3"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/rest"
+)
+
+// DNSExpose publishes the cluster DNS (CoreDNS) on the host and returns the address it can be reached at.
+// A NodePort Service selecting the CoreDNS pods is created and the loadbalancer gets a port mapping (UDP and TCP) to its node port,
+// which recreates the loadbalancer container, just like `k3d cluster edit --port-add` does.
+// If the cluster DNS is exposed already, the existing address is returned and nothing is changed.
+func DNSExpose(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster, opts k3d.DNSExposeOpts) (string, error) {
+	if cluster.ServerLoadBalancer == nil || cluster.ServerLoadBalancer.Node == nil {
+		return "", fmt.Errorf("cluster '%s' has no loadbalancer to publish the cluster DNS on", cluster.Name)
+	}
+	if opts.HostIP == "" {
+		opts.HostIP = k3d.DefaultDNSExposeHostIP
+	}
+	if opts.HostPort == 0 {
+		opts.HostPort = k3d.DefaultDNSExposeHostPort
+	}
+
+	client, err := KubeRESTClient(ctx, runtime, cluster, "v1")
+	if err != nil {
+		return "", err
+	}
+	if err := dnsExposeService(ctx, client); err != nil {
+		return "", err
+	}
+
+	if addr := dnsExposedAddress(cluster.ServerLoadBalancer.Node); addr != "" {
+		l.Log().Infof("Cluster DNS of '%s' is exposed already", cluster.Name)
+		return addr, nil
+	}
+
+	changeset := &config.SimpleConfig{}
+	for _, proto := range []string{"udp", "tcp"} {
+		changeset.Ports = append(changeset.Ports, config.PortWithNodeFilters{
+			Port:        fmt.Sprintf("%s:%d:%d/%s", opts.HostIP, opts.HostPort, k3d.DefaultDNSExposeNodePort, proto),
+			NodeFilters: []string{"loadbalancer"},
+		})
+	}
+	l.Log().Infof("Adding port mapping %s:%d -> %d (udp+tcp) to the loadbalancer...", opts.HostIP, opts.HostPort, k3d.DefaultDNSExposeNodePort)
+	if err := ClusterEditChangesetSimple(ctx, runtime, cluster, changeset); err != nil {
+		return "", fmt.Errorf("failed to add the DNS port mapping to the loadbalancer: %w", err)
+	}
+
+	return net.JoinHostPort(opts.HostIP, strconv.Itoa(opts.HostPort)), nil
+}
+
+// dnsExposeService creates the NodePort Service for the CoreDNS pods, if it doesn't exist yet
+func dnsExposeService(ctx context.Context, client *rest.RESTClient) error {
+	ports := []map[string]interface{}{}
+	for _, port := range [][2]string{{"dns", "UDP"}, {"dns-tcp", "TCP"}} {
+		ports = append(ports, map[string]interface{}{
+			"name":       port[0],
+			"protocol":   port[1],
+			"port":       53,
+			"targetPort": 53,
+			"nodePort":   k3d.DefaultDNSExposeNodePort,
+		})
+	}
+	svc := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Service",
+		"metadata": map[string]interface{}{
+			"name":      k3d.DefaultDNSExposeServiceName,
+			"namespace": "kube-system",
+			"labels":    map[string]string{"app.kubernetes.io/managed-by": "k3d"},
+		},
+		"spec": map[string]interface{}{
+			"type":     "NodePort",
+			"selector": map[string]string{"k8s-app": "kube-dns"},
+			"ports":    ports,
+		},
+	}
+	body, err := json.Marshal(svc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal service: %w", err)
+	}
+
+	err = client.Post().Namespace("kube-system").Resource("services").Body(body).Do(ctx).Error()
+	if apierrors.IsAlreadyExists(err) {
+		l.Log().Debugf("Service '%s' exists already", k3d.DefaultDNSExposeServiceName)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create service '%s': %w", k3d.DefaultDNSExposeServiceName, err)
+	}
+	l.Log().Debugf("Created service '%s' (node port %d)", k3d.DefaultDNSExposeServiceName, k3d.DefaultDNSExposeNodePort)
+	return nil
+}
+
+// dnsExposedAddress returns the host address the node port of the cluster DNS is mapped to on the given (loadbalancer) node, if any
+func dnsExposedAddress(node *k3d.Node) string {
+	bindings := node.Ports[nat.Port(fmt.Sprintf("%d/udp", k3d.DefaultDNSExposeNodePort))]
+	if len(bindings) == 0 {
+		return ""
+	}
+	hostIP := bindings[0].HostIP
+	if hostIP == "" {
+		hostIP = "0.0.0.0"
+	}
+	return net.JoinHostPort(hostIP, bindings[0].HostPort)
+}
@@ -0,0 +1,97 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/docker/go-connections/nat"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"k8s.io/client-go/rest"
+)
+
+func TestDNSExposeService(t *testing.T) {
+	var posted map[string]interface{}
+	exists := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/namespaces/kube-system/services" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if exists {
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"kind": "Status", "apiVersion": "v1", "status": "Failure", "reason": "AlreadyExists", "code": 409}`))
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &posted)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	client, err := newKubeRESTClient(&rest.Config{Host: server.URL}, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := dnsExposeService(context.Background(), client); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	spec := posted["spec"].(map[string]interface{})
+	if spec["type"] != "NodePort" || spec["selector"].(map[string]interface{})["k8s-app"] != "kube-dns" {
+		t.Errorf("expected a NodePort service selecting the CoreDNS pods, got %+v", spec)
+	}
+	ports := spec["ports"].([]interface{})
+	if len(ports) != 2 {
+		t.Fatalf("expected a UDP and a TCP port, got %+v", ports)
+	}
+	for i, proto := range []string{"UDP", "TCP"} {
+		port := ports[i].(map[string]interface{})
+		if port["protocol"] != proto || port["nodePort"] != float64(k3d.DefaultDNSExposeNodePort) {
+			t.Errorf("expected %s port on node port %d, got %+v", proto, k3d.DefaultDNSExposeNodePort, port)
+		}
+	}
+
+	// exposing again must not fail
+	exists = true
+	if err := dnsExposeService(context.Background(), client); err != nil {
+		t.Errorf("expected an existing service to be tolerated, got %v", err)
+	}
+}
+
+func TestDNSExposedAddress(t *testing.T) {
+	lb := &k3d.Node{Name: "k3d-test-serverlb", Ports: nat.PortMap{"6443/tcp": {{HostIP: "0.0.0.0", HostPort: "45555"}}}}
+	if addr := dnsExposedAddress(lb); addr != "" {
+		t.Errorf("expected no address without DNS port mapping, got %s", addr)
+	}
+
+	lb.Ports["30053/udp"] = []nat.PortBinding{{HostIP: "127.0.0.1", HostPort: "10053"}}
+	if addr := dnsExposedAddress(lb); addr != "127.0.0.1:10053" {
+		t.Errorf("expected address 127.0.0.1:10053, got %s", addr)
+	}
+}
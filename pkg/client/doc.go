@@ -0,0 +1,43 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+// Package client implements the operations of k3d on clusters, nodes, registries and images. The k3d CLI is only a thin layer on
+// top of it, so tools embedding k3d (e.g. IDE integrations or test harnesses) use the same functions instead of shelling out to the binary.
+// All of them take a context.Context and the runtime to use (usually runtimes.SelectedRuntime) and work on the plain structs of the types package.
+//
+// A cluster is created from a configuration the same way 'k3d cluster create' does it:
+//
+//	clusterConfig, err := config.TransformSimpleToClusterConfig(ctx, runtimes.SelectedRuntime, simpleCfg)
+//	// handle err
+//	clusterConfig, err = config.ProcessClusterConfig(*clusterConfig)
+//	// handle err
+//	if err := config.ValidateClusterConfig(ctx, runtimes.SelectedRuntime, *clusterConfig); err != nil {
+//		// handle err
+//	}
+//	if err := client.ClusterRun(ctx, runtimes.SelectedRuntime, clusterConfig); err != nil {
+//		// handle err
+//	}
+//
+// Existing clusters are looked up with ClusterGet and passed to the other operations, e.g. ClusterStart, ClusterStop, ClusterDelete,
+// NodeAddToCluster, ImageImportIntoClusterMulti or KubeconfigGet.
+// For Go tests that just need a cluster, see package github.com/rancher/k3d/v5/pkg/k3dtest.
+package client
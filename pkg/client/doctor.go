@@ -0,0 +1,265 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	runtimeErr "github.com/rancher/k3d/v5/pkg/runtimes/errors"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// ClusterDoctor checks the cluster's containers, network, volumes and kubeconfigs for inconsistencies that k3d doesn't recover from
+// by itself, e.g. agents left without a server or kubeconfigs that don't match the cluster anymore.
+// Unlike ClusterVerify, it doesn't need the cluster to be running (or to exist at all) and doesn't modify anything.
+func ClusterDoctor(ctx context.Context, runtime runtimes.Runtime, clusterName string) (*k3d.ClusterDoctorReport, error) {
+	report := &k3d.ClusterDoctorReport{
+		Cluster: clusterName,
+		Healthy: true,
+	}
+
+	allNodes, err := runtime.GetNodesByLabel(ctx, map[string]string{k3d.LabelClusterName: clusterName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nodes for cluster '%s': %w", clusterName, err)
+	}
+	nodes := []*k3d.Node{}
+	servers, running := 0, false
+	for _, node := range allNodes {
+		if node.Role == k3d.RegistryRole {
+			continue
+		}
+		nodes = append(nodes, node)
+		if node.Role == k3d.ServerRole {
+			servers++
+			running = running || node.State.Running
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+
+	checks := []*k3d.VerifyCheck{
+		doctorServers(clusterName, nodes, servers),
+		doctorNodeStates(clusterName, nodes, servers),
+		doctorNetwork(ctx, runtime, nodes, servers),
+		doctorImageVolume(runtime, nodes, servers),
+		doctorKubeconfig(ctx, runtime, clusterName, "kubeconfig", servers > 0, running,
+			func() (*clientcmdapi.Config, error) { return KubeconfigGetDefaultFile() },
+			fmt.Sprintf("update it with 'k3d kubeconfig merge %s --kubeconfig-merge-default'", clusterName),
+			fmt.Sprintf("remove them with 'kubectl config delete-context %[1]s-%[2]s' and 'kubectl config delete-cluster %[1]s-%[2]s'", k3d.DefaultObjectNamePrefix, clusterName)),
+		doctorKubeconfig(ctx, runtime, clusterName, "kubeconfig file", servers > 0, running,
+			func() (*clientcmdapi.Config, error) {
+				path, err := KubeconfigClusterFilePath(clusterName)
+				if err != nil {
+					return nil, err
+				}
+				return clientcmd.LoadFromFile(path)
+			},
+			fmt.Sprintf("update it with 'k3d kubeconfig merge %s'", clusterName),
+			"remove it with 'k3d prune --orphans'"),
+	}
+
+	for _, check := range checks {
+		l.Log().Debugf("Doctor check '%s': %s (%s)", check.Name, check.Result, check.Details)
+		if check.Result == k3d.VerifyResultFail {
+			report.Healthy = false
+		}
+		report.Checks = append(report.Checks, check)
+	}
+
+	return report, nil
+}
+
+func nodeNames(nodes []*k3d.Node) string {
+	names := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		names = append(names, node.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// doctorServers checks that the cluster has a server node, without which the remaining nodes are left dangling
+func doctorServers(clusterName string, nodes []*k3d.Node, servers int) *k3d.VerifyCheck {
+	check := &k3d.VerifyCheck{Name: "servers"}
+	switch {
+	case len(nodes) == 0:
+		check.Result = k3d.VerifyResultFail
+		check.Details = fmt.Sprintf("no nodes found: cluster '%s' doesn't exist", clusterName)
+	case servers == 0:
+		check.Result = k3d.VerifyResultFail
+		check.Details = fmt.Sprintf("no server node: %s left dangling (remove them with 'k3d prune --orphans')", nodeNames(nodes))
+	default:
+		check.Result = k3d.VerifyResultPass
+		check.Details = fmt.Sprintf("%d server node(s)", servers)
+	}
+	return check
+}
+
+// doctorNodeStates checks that the cluster's nodes are either all running or all stopped
+func doctorNodeStates(clusterName string, nodes []*k3d.Node, servers int) *k3d.VerifyCheck {
+	check := &k3d.VerifyCheck{Name: "nodes"}
+	if servers == 0 {
+		check.Result = k3d.VerifyResultSkip
+		check.Details = "no server node"
+		return check
+	}
+	stopped := []*k3d.Node{}
+	for _, node := range nodes {
+		if !node.State.Running {
+			stopped = append(stopped, node)
+		}
+	}
+	switch len(stopped) {
+	case 0:
+		check.Result = k3d.VerifyResultPass
+		check.Details = fmt.Sprintf("all %d node(s) running", len(nodes))
+	case len(nodes):
+		check.Result = k3d.VerifyResultPass
+		check.Details = fmt.Sprintf("all %d node(s) stopped", len(nodes))
+	default:
+		check.Result = k3d.VerifyResultFail
+		check.Details = fmt.Sprintf("not running: %s (start them with 'k3d cluster start %s')", nodeNames(stopped), clusterName)
+	}
+	return check
+}
+
+// doctorNetwork checks that the cluster network exists and all nodes are attached to it
+func doctorNetwork(ctx context.Context, runtime runtimes.Runtime, nodes []*k3d.Node, servers int) *k3d.VerifyCheck {
+	check := &k3d.VerifyCheck{Name: "network"}
+	networkName := ""
+	if servers > 0 {
+		networkName = nodes[0].RuntimeLabels[k3d.LabelNetwork]
+	}
+	if networkName == "" {
+		check.Result = k3d.VerifyResultSkip
+		check.Details = "no cluster network known"
+		return check
+	}
+
+	if _, err := runtime.GetNetwork(ctx, &k3d.ClusterNetwork{Name: networkName}); err != nil {
+		check.Result = k3d.VerifyResultFail
+		if errors.Is(err, runtimeErr.ErrRuntimeNetworkNotExists) {
+			check.Details = fmt.Sprintf("network '%s' doesn't exist anymore (recreate the cluster)", networkName)
+		} else {
+			check.Details = fmt.Sprintf("failed to get network '%s': %v", networkName, err)
+		}
+		return check
+	}
+
+	detached := []*k3d.Node{}
+nodeLoop:
+	for _, node := range nodes {
+		for _, network := range node.Networks {
+			if network == networkName {
+				continue nodeLoop
+			}
+		}
+		detached = append(detached, node)
+	}
+	if len(detached) > 0 {
+		check.Result = k3d.VerifyResultFail
+		check.Details = fmt.Sprintf("not attached to network '%s': %s (attach them with 'docker network connect %s NODE')", networkName, nodeNames(detached), networkName)
+		return check
+	}
+	check.Result = k3d.VerifyResultPass
+	check.Details = fmt.Sprintf("all nodes attached to network '%s'", networkName)
+	return check
+}
+
+// doctorImageVolume checks that the volume used for 'k3d image import' exists
+func doctorImageVolume(runtime runtimes.Runtime, nodes []*k3d.Node, servers int) *k3d.VerifyCheck {
+	check := &k3d.VerifyCheck{Name: "image volume"}
+	volumeName := ""
+	if servers > 0 {
+		volumeName = nodes[0].RuntimeLabels[k3d.LabelImageVolume]
+	}
+	if volumeName == "" {
+		check.Result = k3d.VerifyResultSkip
+		check.Details = "no image volume known"
+		return check
+	}
+	if _, err := runtime.GetVolume(volumeName); err != nil {
+		check.Result = k3d.VerifyResultFail
+		check.Details = fmt.Sprintf("volume '%s' doesn't exist anymore, so 'k3d image import' will fail (recreate the cluster)", volumeName)
+		return check
+	}
+	check.Result = k3d.VerifyResultPass
+	check.Details = fmt.Sprintf("volume '%s' exists", volumeName)
+	return check
+}
+
+// doctorKubeconfig checks that a kubeconfig's entries for the cluster still match it: they are stale, if the cluster doesn't exist
+// (anymore), was recreated with the same name or got a different API port
+func doctorKubeconfig(ctx context.Context, runtime runtimes.Runtime, clusterName string, checkName string, exists bool, running bool,
+	load func() (*clientcmdapi.Config, error), updateHint string, removeHint string) *k3d.VerifyCheck {
+	check := &k3d.VerifyCheck{Name: checkName}
+	stored, err := load()
+	if err != nil {
+		if os.IsNotExist(err) {
+			check.Result = k3d.VerifyResultSkip
+			check.Details = "no kubeconfig"
+			return check
+		}
+		check.Result = k3d.VerifyResultFail
+		check.Details = fmt.Sprintf("failed to load kubeconfig: %v", err)
+		return check
+	}
+
+	if _, ok := stored.Clusters[fmt.Sprintf("%s-%s", k3d.DefaultObjectNamePrefix, clusterName)]; !ok {
+		check.Result = k3d.VerifyResultSkip
+		check.Details = "cluster not in kubeconfig"
+		return check
+	}
+	if !exists {
+		check.Result = k3d.VerifyResultFail
+		check.Details = fmt.Sprintf("stale entries for a cluster without server node (%s)", removeHint)
+		return check
+	}
+	if !running {
+		check.Result = k3d.VerifyResultSkip
+		check.Details = "cluster not running"
+		return check
+	}
+
+	cluster := &k3d.Cluster{Name: clusterName}
+	current, err := KubeconfigGet(ctx, runtime, cluster)
+	if err != nil {
+		check.Result = k3d.VerifyResultFail
+		check.Details = fmt.Sprintf("failed to get the cluster's current kubeconfig: %v", err)
+		return check
+	}
+	if kubeconfigStale(stored, current, cluster) {
+		check.Result = k3d.VerifyResultFail
+		check.Details = fmt.Sprintf("entries don't match the cluster anymore (%s)", updateHint)
+		return check
+	}
+	check.Result = k3d.VerifyResultPass
+	check.Details = "up to date"
+	return check
+}
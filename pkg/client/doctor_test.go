@@ -0,0 +1,135 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	runtimeErr "github.com/rancher/k3d/v5/pkg/runtimes/errors"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const doctorTestKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: k3d-test
+  cluster:
+    server: https://0.0.0.0:6443
+`
+
+func doctorResults(report *k3d.ClusterDoctorReport) map[string]k3d.VerifyResult {
+	results := map[string]k3d.VerifyResult{}
+	for _, check := range report.Checks {
+		results[check.Name] = check.Result
+	}
+	return results
+}
+
+func TestClusterDoctor(t *testing.T) {
+	home := useTempConfigDir(t)
+	t.Setenv("KUBECONFIG", filepath.Join(home, "kubeconfig"))
+	if err := ioutil.WriteFile(filepath.Join(home, "kubeconfig"), []byte(doctorTestKubeconfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	node := func(name string, role k3d.Role, running bool) *k3d.Node {
+		n := newFakeNode("test", name, role, running)
+		n.RuntimeLabels[k3d.LabelNetwork] = "k3d-test"
+		n.RuntimeLabels[k3d.LabelImageVolume] = "k3d-test-images"
+		n.Networks = []string{"k3d-test"}
+		return n
+	}
+
+	// stopped cluster with an agent that's still running and detached from the network, whose image volume is gone
+	server := node("k3d-test-server-0", k3d.ServerRole, false)
+	agent := node("k3d-test-agent-0", k3d.AgentRole, true)
+	agent.Networks = nil
+	runtime := &fakeRuntime{nodes: []*k3d.Node{server, agent}, network: &k3d.ClusterNetwork{Name: "k3d-test"}}
+	report, err := ClusterDoctor(context.Background(), runtime, "test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]k3d.VerifyResult{
+		"servers":         k3d.VerifyResultPass,
+		"nodes":           k3d.VerifyResultFail,
+		"network":         k3d.VerifyResultFail,
+		"image volume":    k3d.VerifyResultFail,
+		"kubeconfig":      k3d.VerifyResultSkip, // not running
+		"kubeconfig file": k3d.VerifyResultSkip, // no file
+	}
+	if results := doctorResults(report); report.Healthy || !reflect.DeepEqual(results, expected) {
+		t.Errorf("expected an unhealthy report with %v, got %v", expected, results)
+	}
+	if details := report.Checks[1].Details; details != "not running: k3d-test-server-0 (start them with 'k3d cluster start test')" {
+		t.Errorf("unexpected details '%s'", details)
+	}
+
+	// the server was removed and took the network with it
+	runtime = &fakeRuntime{nodes: []*k3d.Node{agent}, failOn: map[string]error{"GetNetwork:k3d-test": runtimeErr.ErrRuntimeNetworkNotExists}}
+	report, err = ClusterDoctor(context.Background(), runtime, "test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = map[string]k3d.VerifyResult{
+		"servers":         k3d.VerifyResultFail,
+		"nodes":           k3d.VerifyResultSkip,
+		"network":         k3d.VerifyResultSkip,
+		"image volume":    k3d.VerifyResultSkip,
+		"kubeconfig":      k3d.VerifyResultFail, // stale entries
+		"kubeconfig file": k3d.VerifyResultSkip,
+	}
+	if results := doctorResults(report); report.Healthy || !reflect.DeepEqual(results, expected) {
+		t.Errorf("expected an unhealthy report with %v, got %v", expected, results)
+	}
+	if details := report.Checks[0].Details; !strings.Contains(details, "k3d-test-agent-0 left dangling") {
+		t.Errorf("unexpected details '%s'", details)
+	}
+
+	// network check with a missing network
+	server.State.Running, agent.Networks = true, []string{"k3d-test"}
+	runtime = &fakeRuntime{
+		nodes:   []*k3d.Node{server, agent},
+		volumes: map[string]map[string]string{"k3d-test-images": {}},
+		failOn:  map[string]error{"GetNetwork:k3d-test": runtimeErr.ErrRuntimeNetworkNotExists},
+	}
+	check := doctorNetwork(context.Background(), runtime, runtime.nodes, 1)
+	if check.Result != k3d.VerifyResultFail || check.Details != "network 'k3d-test' doesn't exist anymore (recreate the cluster)" {
+		t.Errorf("unexpected network check %+v", check)
+	}
+	if check := doctorImageVolume(runtime, runtime.nodes, 1); check.Result != k3d.VerifyResultPass {
+		t.Errorf("unexpected image volume check %+v", check)
+	}
+
+	if err := os.Remove(filepath.Join(home, "kubeconfig")); err != nil {
+		t.Fatal(err)
+	}
+	if check := doctorKubeconfig(context.Background(), runtime, "test", "kubeconfig", false, false, func() (*clientcmdapi.Config, error) { return KubeconfigGetDefaultFile() }, "", ""); check.Result != k3d.VerifyResultSkip {
+		t.Errorf("expected a missing kubeconfig to be skipped, got %+v", check)
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	k3drt "github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// LabelGracefulStop marks the Kubernetes nodes cordoned by a graceful cluster stop, so that only those are uncordoned on the next start
+// (nodes that were cordoned by the user before stay unschedulable)
+const LabelGracefulStop = "k3d.io/stopped-gracefully"
+
+// drainCmd cordons all schedulable nodes and evicts their pods, which get terminated with their termination grace period.
+// DaemonSet pods are left running, as they'd be recreated right away anyway.
+const drainCmd = `set -e
+nodes=$(kubectl get nodes --field-selector spec.unschedulable=false -o name)
+[ -z "$nodes" ] || kubectl label $nodes ` + LabelGracefulStop + `=true --overwrite
+kubectl drain --selector ` + LabelGracefulStop + `=true --ignore-daemonsets --delete-emptydir-data --force --timeout=%s`
+
+// uncordonCmd reverts drainCmd
+const uncordonCmd = `set -e
+nodes=$(kubectl get nodes --selector ` + LabelGracefulStop + `=true -o name)
+[ -z "$nodes" ] || { kubectl uncordon $nodes && kubectl label $nodes ` + LabelGracefulStop + `-; }`
+
+// clusterDrain drains all nodes of a cluster from inside a running server node before the cluster is stopped
+func clusterDrain(ctx context.Context, runtime k3drt.Runtime, cluster *k3d.Cluster, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = k3d.DefaultGracefulStopTimeout
+	}
+	l.Log().Infof("Draining cluster '%s' (timeout: %s)...", cluster.Name, timeout)
+	if err := execInRunningServer(ctx, runtime, cluster, fmt.Sprintf(drainCmd, timeout)); err != nil {
+		return fmt.Errorf("failed to drain the nodes: %w", err)
+	}
+	l.Log().Infof("Drained cluster '%s'", cluster.Name)
+	return nil
+}
+
+// clusterUncordon makes the nodes drained by a graceful stop schedulable again
+func clusterUncordon(ctx context.Context, runtime k3drt.Runtime, cluster *k3d.Cluster) error {
+	return execInRunningServer(ctx, runtime, cluster, uncordonCmd)
+}
+
+// execInRunningServer runs a shell script in the first running server node of the cluster, returning its output with the error
+func execInRunningServer(ctx context.Context, runtime k3drt.Runtime, cluster *k3d.Cluster, script string) error {
+	for _, node := range cluster.Nodes {
+		if node.Role != k3d.ServerRole || !node.State.Running {
+			continue
+		}
+		logreader, err := runtime.ExecInNodeGetLogs(ctx, node, []string{"sh", "-c", script})
+		if err != nil {
+			if logreader != nil {
+				if logs, rerr := ioutil.ReadAll(logreader); rerr == nil && len(logs) > 0 {
+					return fmt.Errorf("%w\nLogs from %s:\n%s", err, node.Name, strings.TrimSpace(string(logs)))
+				}
+			}
+			return err
+		}
+		return nil
+	}
+	return fmt.Errorf("no running server node in cluster '%s'", cluster.Name)
+}
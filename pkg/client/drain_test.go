@@ -0,0 +1,92 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestClusterStopGraceful(t *testing.T) {
+	useTempConfigDir(t)
+	server := newFakeNode("test", "k3d-test-server-0", k3d.ServerRole, true)
+	agent := newFakeNode("test", "k3d-test-agent-0", k3d.AgentRole, true)
+	cluster := &k3d.Cluster{Name: "test", Nodes: []*k3d.Node{server, agent}}
+	runtime := &fakeRuntime{nodes: cluster.Nodes}
+
+	if err := ClusterStop(context.Background(), runtime, cluster, k3d.ClusterStopOpts{Graceful: true, GracefulTimeout: 30 * time.Second}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if execs := runtime.execs[server.Name]; len(execs) != 1 || !strings.Contains(execs[0], "kubectl drain --selector "+LabelGracefulStop+"=true") || !strings.Contains(execs[0], "--timeout=30s") {
+		t.Errorf("expected the nodes to be drained from the server, got %v", execs)
+	}
+	if len(runtime.execs[agent.Name]) != 0 {
+		t.Errorf("expected nothing to be executed in the agent, got %v", runtime.execs[agent.Name])
+	}
+	if server.State.Running || agent.State.Running {
+		t.Errorf("expected all nodes to be stopped")
+	}
+
+	// a failed drain doesn't keep the cluster running
+	server.State.Running, agent.State.Running = true, true
+	runtime = &fakeRuntime{nodes: cluster.Nodes, failOn: map[string]error{"ExecInNode:" + server.Name: errors.New("timed out waiting for the condition")}}
+	if err := ClusterStop(context.Background(), runtime, cluster, k3d.ClusterStopOpts{Graceful: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stopped := runtime.callsOf("StopNode"); len(stopped) != 2 {
+		t.Errorf("expected both nodes to be stopped after the failed drain, got %v", stopped)
+	}
+
+	// without --graceful, nothing is executed
+	server.State.Running, agent.State.Running = true, true
+	runtime = &fakeRuntime{nodes: cluster.Nodes}
+	if err := ClusterStop(context.Background(), runtime, cluster, k3d.ClusterStopOpts{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(runtime.execs) != 0 {
+		t.Errorf("expected no execs without graceful stop, got %v", runtime.execs)
+	}
+}
+
+func TestClusterUncordon(t *testing.T) {
+	server := newFakeNode("test", "k3d-test-server-0", k3d.ServerRole, true)
+	cluster := &k3d.Cluster{Name: "test", Nodes: []*k3d.Node{newFakeNode("test", "k3d-test-server-1", k3d.ServerRole, false), server}}
+	runtime := &fakeRuntime{nodes: cluster.Nodes}
+
+	if err := clusterUncordon(context.Background(), runtime, cluster); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if execs := runtime.execs; !reflect.DeepEqual(execs, map[string][]string{server.Name: {"sh -c " + uncordonCmd}}) {
+		t.Errorf("expected the nodes to be uncordoned from the running server, got %v", execs)
+	}
+
+	cluster.Nodes = cluster.Nodes[:1]
+	if err := clusterUncordon(context.Background(), runtime, cluster); err == nil {
+		t.Errorf("expected an error without a running server")
+	}
+}
@@ -0,0 +1,115 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/docker/go-connections/nat"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// Endpoint kinds
+const (
+	EndpointAPI     = "api"
+	EndpointIngress = "ingress"
+	EndpointPort    = "port"
+)
+
+// Endpoint is an address on the host, under which a part of a cluster can be reached
+type Endpoint struct {
+	Kind    string `yaml:"kind" json:"kind"`
+	Address string `yaml:"address" json:"address"`
+	Target  string `yaml:"target" json:"target"` // node and container port behind the address
+}
+
+// ClusterEndpoints lists the host addresses of the Kubernetes API, the ingress (ports 80/443 of the loadbalancer) and every other published port of a cluster.
+// Unspecified host IPs are replaced by the host the kubeconfig uses for the API.
+func ClusterEndpoints(cluster *k3d.Cluster) []Endpoint {
+	apiHost := k3d.DefaultAPIHost
+	endpoints := []Endpoint{}
+
+	for _, node := range cluster.Nodes {
+		if node.Role != k3d.ServerRole || node.ServerOpts.KubeAPI == nil || node.ServerOpts.KubeAPI.Binding.HostPort == "" {
+			continue
+		}
+		if node.ServerOpts.KubeAPI.Host != "" {
+			apiHost = node.ServerOpts.KubeAPI.Host
+		}
+		target := node.Name
+		if lb := clusterLoadBalancerNode(cluster); lb != nil {
+			target = lb.Name
+		}
+		endpoints = append(endpoints, Endpoint{
+			Kind:    EndpointAPI,
+			Address: "https://" + net.JoinHostPort(apiHost, node.ServerOpts.KubeAPI.Binding.HostPort),
+			Target:  fmt.Sprintf("%s:%s/tcp", target, k3d.DefaultAPIPort),
+		})
+		break
+	}
+
+	for _, mapping := range ClusterPortMappings(cluster) {
+		if mapping.ContainerPort.Port() == k3d.DefaultAPIPort && len(endpoints) > 0 && endpoints[0].Kind == EndpointAPI {
+			continue // already listed as the API endpoint
+		}
+		hostIP := mapping.HostIP
+		if ip := net.ParseIP(hostIP); hostIP == "" || (ip != nil && ip.IsUnspecified()) {
+			hostIP = apiHost
+		}
+		hostPort := mapping.HostPort
+		if hostPort == "" || hostPort == "0" {
+			hostPort = "random"
+		}
+		endpoint := Endpoint{
+			Kind:    EndpointPort,
+			Address: net.JoinHostPort(hostIP, hostPort),
+			Target:  fmt.Sprintf("%s:%s", mapping.Node, mapping.ContainerPort),
+		}
+		if lb := clusterLoadBalancerNode(cluster); lb != nil && mapping.Node == lb.Name && isIngressPort(mapping.ContainerPort) {
+			endpoint.Kind = EndpointIngress
+			scheme := "http://"
+			if mapping.ContainerPort.Port() == "443" {
+				scheme = "https://"
+			}
+			endpoint.Address = scheme + endpoint.Address
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+
+	return endpoints
+}
+
+// clusterLoadBalancerNode returns the loadbalancer node of a cluster or nil, if it doesn't have one
+func clusterLoadBalancerNode(cluster *k3d.Cluster) *k3d.Node {
+	for _, node := range cluster.Nodes {
+		if node.Role == k3d.LoadBalancerRole {
+			return node
+		}
+	}
+	return nil
+}
+
+// isIngressPort checks if a port of the loadbalancer reaches the ingress controller, i.e. it's one of the http(s) ports
+func isIngressPort(port nat.Port) bool {
+	return port.Proto() == "tcp" && (port.Port() == "80" || port.Port() == "443")
+}
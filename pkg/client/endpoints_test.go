@@ -0,0 +1,77 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"testing"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/go-test/deep"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestClusterEndpoints(t *testing.T) {
+	cluster := &k3d.Cluster{
+		Name: "test",
+		Nodes: []*k3d.Node{
+			{
+				Name: "k3d-test-server-0",
+				Role: k3d.ServerRole,
+				ServerOpts: k3d.ServerOpts{
+					KubeAPI: &k3d.ExposureOpts{Host: "0.0.0.0", PortMapping: nat.PortMapping{Binding: nat.PortBinding{HostIP: "0.0.0.0", HostPort: "6550"}}},
+				},
+			},
+			{
+				Name:  "k3d-test-agent-0",
+				Role:  k3d.AgentRole,
+				Ports: nat.PortMap{"9100/tcp": []nat.PortBinding{{HostIP: "127.0.0.1", HostPort: "9100"}}},
+			},
+			{
+				Name: "k3d-test-serverlb",
+				Role: k3d.LoadBalancerRole,
+				Ports: nat.PortMap{
+					"6443/tcp": []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: "6550"}},
+					"80/tcp":   []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: ""}},
+					"443/tcp":  []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: "8443"}},
+				},
+				State: k3d.NodeState{
+					Running: true,
+					PublishedPorts: nat.PortMap{ // random port 80 resolved by the runtime
+						"6443/tcp": []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: "6550"}},
+						"80/tcp":   []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: "49153"}},
+						"443/tcp":  []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: "8443"}},
+					},
+				},
+			},
+		},
+	}
+
+	expected := []Endpoint{
+		{Kind: EndpointAPI, Address: "https://0.0.0.0:6550", Target: "k3d-test-serverlb:6443/tcp"},
+		{Kind: EndpointPort, Address: "127.0.0.1:9100", Target: "k3d-test-agent-0:9100/tcp"},
+		{Kind: EndpointIngress, Address: "http://0.0.0.0:49153", Target: "k3d-test-serverlb:80/tcp"},
+		{Kind: EndpointIngress, Address: "https://0.0.0.0:8443", Target: "k3d-test-serverlb:443/tcp"},
+	}
+	if diff := deep.Equal(ClusterEndpoints(cluster), expected); diff != nil {
+		t.Errorf("unexpected endpoints: %v", diff)
+	}
+}
@@ -0,0 +1,482 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	runtimeTypes "github.com/rancher/k3d/v5/pkg/runtimes/types"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// fakeRuntime implements the few runtime methods needed by the tests of this package
+// Calling any other method panics (nil embedded interface), which makes unexpected runtime calls obvious.
+type fakeRuntime struct {
+	runtimes.Runtime
+
+	mu               sync.Mutex
+	nodes            []*k3d.Node
+	experimental     bool
+	arch             string            // native architecture of the runtime
+	platform         string            // DOCKER_DEFAULT_PLATFORM
+	images           map[string]string // present images -> their platform
+	digests          map[string]string // image references -> the digest they point to
+	host             string            // DOCKER_HOST without the scheme
+	failOn           map[string]error  // "<method>:<node or image>" -> error to return
+	calls            []string          // "<method>:<node or image>"
+	network          *k3d.ClusterNetwork
+	execs            map[string][]string          // node -> executed commands
+	written          map[string][]byte            // destination -> content written to a node
+	execOutputs      map[string]string            // command -> output returned by ExecInNodeGetLogs
+	volumes          map[string]map[string]string // existing volumes -> their labels
+	logStreams       map[string][]string          // node -> successive log streams returned by FollowNodeLogs (until the container "stops")
+	exports          map[string][]byte            // node -> filesystem archive returned by ExportNode
+	networks         []*k3d.ClusterNetwork        // existing networks returned by GetNetworksByLabel
+	listedImages     []runtimeTypes.Image         // images returned by ListImages
+	diskUsage        *runtimeTypes.DiskUsage      // returned by GetDiskUsage
+	version          string                       // version of the daemon reported by Info
+	apiVersion       string                       // API version of the daemon reported by Info
+	pinnedAPIVersion string                       // API version pinned by the user reported by Info
+}
+
+func (r *fakeRuntime) call(method string, target string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := fmt.Sprintf("%s:%s", method, target)
+	r.calls = append(r.calls, key)
+	return r.failOn[key]
+}
+
+// callsOf returns the targets of all calls of the given method in order
+func (r *fakeRuntime) callsOf(method string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	targets := []string{}
+	for _, c := range r.calls {
+		if strings.HasPrefix(c, method+":") {
+			targets = append(targets, strings.TrimPrefix(c, method+":"))
+		}
+	}
+	return targets
+}
+
+func (r *fakeRuntime) Info() (*runtimeTypes.RuntimeInfo, error) {
+	return &runtimeTypes.RuntimeInfo{Name: "fake", Experimental: r.experimental, OSType: "linux", Arch: r.arch, Platform: r.platform,
+		Version: r.version, APIVersion: r.apiVersion, RequiredAPIVersion: "1.41", PinnedAPIVersion: r.pinnedAPIVersion}, nil
+}
+
+func (r *fakeRuntime) GetImagePlatform(_ context.Context, image string) (string, error) {
+	if err := r.call("GetImagePlatform", image); err != nil {
+		return "", err
+	}
+	platform, ok := r.images[image]
+	if !ok {
+		return "", fmt.Errorf("no such image: %s", image)
+	}
+	return platform, nil
+}
+
+func (r *fakeRuntime) GetImageDigest(_ context.Context, image string) (string, error) {
+	if err := r.call("GetImageDigest", image); err != nil {
+		return "", err
+	}
+	digest, ok := r.digests[image]
+	if !ok {
+		return "", fmt.Errorf("no such image: %s", image)
+	}
+	return digest, nil
+}
+
+func (r *fakeRuntime) GetNodesByLabel(_ context.Context, labels map[string]string) ([]*k3d.Node, error) {
+	nodes := []*k3d.Node{}
+	for _, node := range r.nodes {
+		matches := true
+		for k, v := range labels {
+			if node.RuntimeLabels[k] != v {
+				matches = false
+			}
+		}
+		if matches {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes, nil
+}
+
+func (r *fakeRuntime) GetNode(_ context.Context, node *k3d.Node) (*k3d.Node, error) {
+	for _, n := range r.nodes {
+		if n.Name == node.Name {
+			return n, nil
+		}
+	}
+	return nil, fmt.Errorf("no such node: %s", node.Name)
+}
+
+func (r *fakeRuntime) StartNode(_ context.Context, node *k3d.Node) error {
+	if err := r.call("StartNode", node.Name); err != nil {
+		return err
+	}
+	node.State.Running = true
+	return nil
+}
+
+func (r *fakeRuntime) StopNode(_ context.Context, node *k3d.Node) error {
+	if err := r.call("StopNode", node.Name); err != nil {
+		return err
+	}
+	node.State.Running = false
+	return nil
+}
+
+func (r *fakeRuntime) FreezeNode(_ context.Context, node *k3d.Node, _ string) error {
+	if err := r.call("FreezeNode", node.Name); err != nil {
+		return err
+	}
+	node.State.Running = false
+	return nil
+}
+
+func (r *fakeRuntime) ThawNode(_ context.Context, node *k3d.Node, _ string) error {
+	if err := r.call("ThawNode", node.Name); err != nil {
+		return err
+	}
+	node.State.Running = true
+	return nil
+}
+
+func (r *fakeRuntime) PauseNode(_ context.Context, node *k3d.Node) error {
+	if err := r.call("PauseNode", node.Name); err != nil {
+		return err
+	}
+	node.State.Status = "paused"
+	return nil
+}
+
+func (r *fakeRuntime) UnpauseNode(_ context.Context, node *k3d.Node) error {
+	if err := r.call("UnpauseNode", node.Name); err != nil {
+		return err
+	}
+	node.State.Status = "running"
+	return nil
+}
+
+func (r *fakeRuntime) CommitNode(_ context.Context, node *k3d.Node, image string, _ map[string]string) error {
+	return r.call("CommitNode", image)
+}
+
+func (r *fakeRuntime) ExportNode(_ context.Context, node *k3d.Node) (io.ReadCloser, error) {
+	if err := r.call("ExportNode", node.Name); err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(r.exports[node.Name])), nil
+}
+
+// ImportImageFromArchive records the archive as written to "image:<image>" and the changes as written to "changes:<image>"
+func (r *fakeRuntime) ImportImageFromArchive(_ context.Context, archive io.Reader, image string, baseImage string, changes []string) error {
+	if err := r.call("ImportImageFromArchive", image+"<-"+baseImage); err != nil {
+		return err
+	}
+	content, err := ioutil.ReadAll(archive)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.written == nil {
+		r.written = map[string][]byte{}
+	}
+	r.written["image:"+image] = content
+	r.written["changes:"+image] = []byte(strings.Join(changes, "\n"))
+	return nil
+}
+
+func (r *fakeRuntime) DeleteImage(_ context.Context, image string) error {
+	return r.call("DeleteImage", image)
+}
+
+func (r *fakeRuntime) ListImages(_ context.Context) ([]runtimeTypes.Image, error) {
+	return r.listedImages, nil
+}
+
+func (r *fakeRuntime) GetDiskUsage(_ context.Context) (*runtimeTypes.DiskUsage, error) {
+	if r.diskUsage == nil {
+		return &runtimeTypes.DiskUsage{}, nil
+	}
+	return r.diskUsage, nil
+}
+
+func (r *fakeRuntime) TagImage(_ context.Context, source string, target string) error {
+	return r.call("TagImage", source+"->"+target)
+}
+
+func (r *fakeRuntime) PushImage(_ context.Context, image string) error {
+	return r.call("PushImage", image)
+}
+
+func (r *fakeRuntime) PullImage(_ context.Context, image string) error {
+	return r.call("PullImage", image)
+}
+
+func (r *fakeRuntime) ReadFromNode(_ context.Context, path string, node *k3d.Node) (io.ReadCloser, error) {
+	if err := r.call("ReadFromNode", node.Name); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	content, ok := r.written[path]
+	r.mu.Unlock()
+	if ok { // previously written files are returned as a tar archive, like docker does
+		buf := &bytes.Buffer{}
+		tw := tar.NewWriter(buf)
+		if err := tw.WriteHeader(&tar.Header{Name: filepath.Base(path), Mode: 0644, Size: int64(len(content))}); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(content); err != nil {
+			return nil, err
+		}
+		if err := tw.Close(); err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(buf), nil
+	}
+	return ioutil.NopCloser(strings.NewReader(path)), nil
+}
+
+func (r *fakeRuntime) GetHost() string {
+	return r.host
+}
+
+func (r *fakeRuntime) ConnectContainerToNetwork(_ context.Context, container string, network string) error {
+	return r.call("ConnectContainerToNetwork", container+"@"+network)
+}
+
+func (r *fakeRuntime) DisconnectContainerFromNetwork(_ context.Context, container string, network string) error {
+	return r.call("DisconnectContainerFromNetwork", container+"@"+network)
+}
+
+func (r *fakeRuntime) GetNetwork(_ context.Context, network *k3d.ClusterNetwork) (*k3d.ClusterNetwork, error) {
+	if err := r.call("GetNetwork", network.Name); err != nil {
+		return nil, err
+	}
+	return r.network, nil
+}
+
+func (r *fakeRuntime) ExecInNode(_ context.Context, node *k3d.Node, cmd []string) error {
+	if err := r.call("ExecInNode", node.Name); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.execs == nil {
+		r.execs = map[string][]string{}
+	}
+	r.execs[node.Name] = append(r.execs[node.Name], strings.Join(cmd, " "))
+	return nil
+}
+
+// ExecInNodeWithStdin records the input as written to "stdin:<node>" (failing nodes don't read it at all)
+func (r *fakeRuntime) ExecInNodeWithStdin(_ context.Context, node *k3d.Node, cmd []string, stdin io.Reader) error {
+	if err := r.call("ExecInNodeWithStdin", node.Name); err != nil {
+		return err
+	}
+	content, err := ioutil.ReadAll(stdin)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.execs == nil {
+		r.execs = map[string][]string{}
+	}
+	if r.written == nil {
+		r.written = map[string][]byte{}
+	}
+	r.execs[node.Name] = append(r.execs[node.Name], strings.Join(cmd, " "))
+	r.written["stdin:"+node.Name] = content
+	return nil
+}
+
+func (r *fakeRuntime) ExecInNodeGetLogs(ctx context.Context, node *k3d.Node, cmd []string) (*bufio.Reader, error) {
+	return bufio.NewReader(strings.NewReader(r.execOutputs[strings.Join(cmd, " ")])), r.ExecInNode(ctx, node, cmd)
+}
+
+func (r *fakeRuntime) WriteToNode(_ context.Context, content []byte, dest string, _ os.FileMode, node *k3d.Node) error {
+	if err := r.call("WriteToNode", dest); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.written == nil {
+		r.written = map[string][]byte{}
+	}
+	r.written[dest] = content
+	return nil
+}
+
+// GetNodeLogs returns the next log stream just like FollowNodeLogs
+func (r *fakeRuntime) GetNodeLogs(_ context.Context, node *k3d.Node, _ time.Time) (io.ReadCloser, error) {
+	return r.nodeLogs("GetNodeLogs", node)
+}
+
+func (r *fakeRuntime) FollowNodeLogs(_ context.Context, node *k3d.Node, _ time.Time) (io.ReadCloser, error) {
+	return r.nodeLogs("FollowNodeLogs", node)
+}
+
+func (r *fakeRuntime) nodeLogs(method string, node *k3d.Node) (io.ReadCloser, error) {
+	if err := r.call(method, node.Name); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	logs := ""
+	if streams := r.logStreams[node.Name]; len(streams) > 0 {
+		logs, r.logStreams[node.Name] = streams[0], streams[1:]
+	}
+	return io.NopCloser(strings.NewReader(logs)), nil
+}
+
+func (r *fakeRuntime) GetNodeStatus(_ context.Context, node *k3d.Node) (bool, string, error) {
+	return node.State.Running, node.State.Status, nil
+}
+
+func (r *fakeRuntime) GetVolume(name string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.volumes[name]; !ok {
+		return "", fmt.Errorf("failed to find named volume '%s'", name)
+	}
+	return name, nil
+}
+
+func (r *fakeRuntime) CreateVolume(_ context.Context, name string, labels map[string]string) error {
+	if err := r.call("CreateVolume", name); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.volumes == nil {
+		r.volumes = map[string]map[string]string{}
+	}
+	r.volumes[name] = labels
+	return nil
+}
+
+func (r *fakeRuntime) DeleteVolume(_ context.Context, name string) error {
+	if err := r.call("DeleteVolume", name); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.volumes, name)
+	return nil
+}
+
+func (r *fakeRuntime) GetVolumesByLabel(_ context.Context, labels map[string]string) ([]runtimeTypes.Volume, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	volumes := []runtimeTypes.Volume{}
+	for name, volumeLabels := range r.volumes {
+		matches := true
+		for k, v := range labels {
+			if volumeLabels[k] != v {
+				matches = false
+			}
+		}
+		if matches {
+			volumes = append(volumes, runtimeTypes.Volume{Name: name, Labels: volumeLabels})
+		}
+	}
+	return volumes, nil
+}
+
+func (r *fakeRuntime) GetNetworksByLabel(_ context.Context, _ map[string]string) ([]*k3d.ClusterNetwork, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*k3d.ClusterNetwork{}, r.networks...), nil
+}
+
+func (r *fakeRuntime) GetNodesInNetwork(_ context.Context, network string) ([]*k3d.Node, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	nodes := []*k3d.Node{}
+	for _, node := range r.nodes {
+		for _, n := range node.Networks {
+			if n == network {
+				nodes = append(nodes, node)
+			}
+		}
+	}
+	return nodes, nil
+}
+
+func (r *fakeRuntime) DeleteNode(_ context.Context, node *k3d.Node) error {
+	if err := r.call("DeleteNode", node.Name); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, n := range r.nodes {
+		if n.Name == node.Name {
+			r.nodes = append(r.nodes[:i], r.nodes[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (r *fakeRuntime) DeleteNetwork(_ context.Context, network string) error {
+	return r.call("DeleteNetwork", network)
+}
+
+// newFakeNode returns a node of the given cluster as returned by the runtime
+func newFakeNode(cluster string, name string, role k3d.Role, running bool) *k3d.Node {
+	return &k3d.Node{
+		Name:          name,
+		Role:          role,
+		RuntimeLabels: map[string]string{k3d.LabelClusterName: cluster, k3d.LabelRole: string(role)},
+		State:         k3d.NodeState{Running: running},
+	}
+}
+
+// useTempConfigDir points the k3d config directory ($HOME/.k3d) to a temporary directory for the duration of the test
+func useTempConfigDir(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	homedir.DisableCache = true
+	t.Cleanup(func() { homedir.DisableCache = false })
+	return home
+}
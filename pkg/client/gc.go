@@ -0,0 +1,328 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	runtimeTypes "github.com/rancher/k3d/v5/pkg/runtimes/types"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/rancher/k3d/v5/pkg/util"
+)
+
+// gcTarballMinAge keeps GC from removing the tarballs of image imports which may still be running
+const gcTarballMinAge = 10 * time.Minute
+
+// gcTarballListScript prints size, modification time (unix) and path of the tarballs kept in the image volume by 'k3d image import --keep-tarball'
+var gcTarballListScript = fmt.Sprintf(`for f in %s/k3d-*; do [ -f "$f" ] && stat -c '%%s %%Y %%n' "$f"; done; true`, k3d.DefaultImageVolumeMountPath)
+
+// diskFree returns the free space of the filesystem holding the given path (replaced in tests)
+var diskFree = util.DiskFree
+
+// gcStep removes one kind of resource and returns what it removed
+type gcStep struct {
+	name string
+	run  func() ([]*k3d.GCItem, error)
+}
+
+// GC frees disk space by removing what k3d doesn't need anymore, one kind of resource at a time until the free space reaches opts.MinFree:
+// image tarballs kept in the clusters' image volumes, exited tools nodes, k3s images not used by any cluster and (if enabled) old checkpoints.
+// Nothing is removed if there's enough free space already. Failing steps are logged and skipped, so that the others can still free space.
+func GC(ctx context.Context, runtime runtimes.Runtime, opts k3d.GCOpts) (*k3d.GCReport, error) {
+	gcPath, err := gcDefaultPath(runtime, opts.Path)
+	if err != nil {
+		return nil, err
+	}
+	free, err := diskFree(gcPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the free space of '%s': %w", gcPath, err)
+	}
+	report := &k3d.GCReport{Path: gcPath, FreeBefore: free, FreeAfter: free, Items: []*k3d.GCItem{}}
+
+	steps := []gcStep{
+		{"image tarballs", func() ([]*k3d.GCItem, error) { return gcTarballs(ctx, runtime, opts.DryRun) }},
+		{"exited tools nodes", func() ([]*k3d.GCItem, error) { return gcToolsNodes(ctx, runtime, opts.DryRun) }},
+		{"unused k3s images", func() ([]*k3d.GCItem, error) { return gcImages(ctx, runtime, opts.DryRun) }},
+	}
+	if opts.CheckpointsOlderThan > 0 {
+		steps = append(steps, gcStep{"old checkpoints", func() ([]*k3d.GCItem, error) {
+			return gcCheckpoints(ctx, runtime, opts.CheckpointsOlderThan, opts.DryRun)
+		}})
+	}
+
+	for _, step := range steps {
+		if opts.MinFree > 0 && report.FreeAfter >= opts.MinFree {
+			l.Log().Debugf("Free space of '%s' is above the threshold, not removing %s", gcPath, step.name)
+			break
+		}
+
+		l.Log().Debugf("Removing %s...", step.name)
+		items, err := step.run()
+		if err != nil {
+			l.Log().Warnf("Failed to remove %s: %v", step.name, err)
+		}
+		for _, item := range items {
+			report.Items = append(report.Items, item)
+			report.Freed += item.Size
+		}
+
+		if opts.DryRun {
+			report.FreeAfter = report.FreeBefore + uint64(report.Freed)
+		} else if free, err := diskFree(gcPath); err == nil {
+			report.FreeAfter = free
+		}
+	}
+
+	return report, nil
+}
+
+// gcDefaultPath returns the path whose free space GC checks: the given one, else the runtime's data root (if it's on this host) or the home directory
+func gcDefaultPath(runtime runtimes.Runtime, gcPath string) (string, error) {
+	if gcPath != "" {
+		return gcPath, nil
+	}
+	if info, err := runtime.Info(); err == nil && info.DataRoot != "" {
+		if _, err := os.Stat(info.DataRoot); err == nil {
+			return info.DataRoot, nil
+		}
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user's home directory: %w", err)
+	}
+	return home, nil
+}
+
+// gcTarballs removes the image tarballs kept in the image volumes of all clusters with a running k3s node
+func gcTarballs(ctx context.Context, runtime runtimes.Runtime, dryRun bool) ([]*k3d.GCItem, error) {
+	nodes, err := runtime.GetNodesByLabel(ctx, k3d.DefaultRuntimeLabels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	// the image volume is shared by all k3s nodes of a cluster, so one of them is enough
+	execNodes := map[string]*k3d.Node{}
+	clusters := []string{}
+	for _, node := range nodes {
+		cluster := node.RuntimeLabels[k3d.LabelClusterName]
+		if _, ok := execNodes[cluster]; ok || cluster == "" || !node.State.Running {
+			continue
+		}
+		if (node.Role != k3d.ServerRole && node.Role != k3d.AgentRole) || !hasVolumeMountedAt(node.Volumes, k3d.DefaultImageVolumeMountPath) {
+			continue
+		}
+		execNodes[cluster] = node
+		clusters = append(clusters, cluster)
+	}
+	sort.Strings(clusters)
+
+	items := []*k3d.GCItem{}
+	for _, cluster := range clusters {
+		node := execNodes[cluster]
+		logreader, err := runtime.ExecInNodeGetLogs(ctx, node, []string{"sh", "-c", gcTarballListScript})
+		if err != nil {
+			return items, fmt.Errorf("failed to list image tarballs in node '%s': %w", node.Name, err)
+		}
+		out, err := ioutil.ReadAll(logreader)
+		if err != nil {
+			return items, fmt.Errorf("failed to read image tarball listing of node '%s': %w", node.Name, err)
+		}
+
+		tarballs := parseTarballListing(out, time.Now())
+		if len(tarballs) == 0 {
+			continue
+		}
+		if !dryRun {
+			paths := make([]string, 0, len(tarballs))
+			for _, tarball := range tarballs {
+				paths = append(paths, tarball.Name)
+			}
+			if err := runtime.ExecInNode(ctx, node, append([]string{"rm", "-f"}, paths...)); err != nil {
+				return items, fmt.Errorf("failed to remove image tarballs of cluster '%s': %w", cluster, err)
+			}
+		}
+		for _, tarball := range tarballs {
+			tarball.Cluster = cluster
+			items = append(items, tarball)
+		}
+	}
+	return items, nil
+}
+
+// parseTarballListing parses the output of gcTarballListScript, skipping tarballs modified within gcTarballMinAge
+func parseTarballListing(out []byte, now time.Time) []*k3d.GCItem {
+	tarballs := []*k3d.GCItem{}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), " ", 3) // TTY output uses CRLF line endings
+		if len(fields) != 3 {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		modified, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		if now.Sub(time.Unix(modified, 0)) < gcTarballMinAge {
+			l.Log().Debugf("Keeping recent image tarball '%s', an import may still be running", fields[2])
+			continue
+		}
+		tarballs = append(tarballs, &k3d.GCItem{Kind: k3d.GCKindTarball, Name: fields[2], Size: size})
+	}
+	return tarballs
+}
+
+// gcToolsNodes removes exited tools nodes, which 'k3d image import' creates again when needed
+func gcToolsNodes(ctx context.Context, runtime runtimes.Runtime, dryRun bool) ([]*k3d.GCItem, error) {
+	nodes, err := runtime.GetNodesByLabel(ctx, k3d.DefaultRuntimeLabels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	exited := []*k3d.Node{}
+	for _, node := range nodes {
+		// cluster nodes and registries may just be stopped, the tools nodes are the only k3d containers without a known role
+		if _, known := k3d.NodeRoles[string(node.Role)]; known || node.State.Running {
+			continue
+		}
+		exited = append(exited, node)
+	}
+	if len(exited) == 0 {
+		return nil, nil
+	}
+
+	du, err := runtime.GetDiskUsage(ctx)
+	if err != nil {
+		l.Log().Debugf("Failed to get the disk usage of the containers: %v", err)
+		du = &runtimeTypes.DiskUsage{}
+	}
+
+	items := []*k3d.GCItem{}
+	for _, node := range exited {
+		if !dryRun {
+			if err := runtime.DeleteNode(ctx, node); err != nil {
+				l.Log().Warnf("Failed to remove exited container '%s': %v", node.Name, err)
+				continue
+			}
+		}
+		items = append(items, &k3d.GCItem{Kind: k3d.GCKindContainer, Name: node.Name, Cluster: node.RuntimeLabels[k3d.LabelClusterName], Size: du.Containers[node.Name].SizeRw})
+	}
+	return items, nil
+}
+
+// gcImages removes the k3s images not used by any cluster (see ImagePruneUnused)
+func gcImages(ctx context.Context, runtime runtimes.Runtime, dryRun bool) ([]*k3d.GCItem, error) {
+	pruned, err := ImagePruneUnused(ctx, runtime, k3d.ImagePruneOpts{DryRun: dryRun})
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*k3d.GCItem, 0, len(pruned))
+	for _, image := range pruned {
+		items = append(items, &k3d.GCItem{Kind: k3d.GCKindImage, Name: strings.Join(image.Tags, ", "), Size: image.Size})
+	}
+	return items, nil
+}
+
+// gcCheckpoints removes the checkpoints (archives and node images) created longer ago than the given duration
+func gcCheckpoints(ctx context.Context, runtime runtimes.Runtime, olderThan time.Duration, dryRun bool) ([]*k3d.GCItem, error) {
+	configDir, err := util.GetConfigDirOrCreate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config directory: %w", err)
+	}
+	clusterDirs, err := ioutil.ReadDir(path.Join(configDir, "checkpoints"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list checkpoints: %w", err)
+	}
+
+	items := []*k3d.GCItem{}
+	for _, clusterDir := range clusterDirs {
+		if !clusterDir.IsDir() {
+			continue
+		}
+		checkpointDirs, err := ioutil.ReadDir(path.Join(configDir, "checkpoints", clusterDir.Name()))
+		if err != nil {
+			return items, fmt.Errorf("failed to list checkpoints of cluster '%s': %w", clusterDir.Name(), err)
+		}
+		for _, checkpointDir := range checkpointDirs {
+			if !checkpointDir.IsDir() {
+				continue
+			}
+			checkpoint, dir, err := ClusterCheckpointGet(clusterDir.Name(), checkpointDir.Name())
+			if err != nil {
+				l.Log().Debugf("Skipping checkpoint directory '%s': %v", checkpointDir.Name(), err)
+				continue
+			}
+			if time.Since(checkpoint.Created) < olderThan {
+				continue
+			}
+
+			size, err := dirSize(dir)
+			if err != nil {
+				l.Log().Debugf("Failed to get the size of checkpoint '%s' of cluster '%s': %v", checkpoint.Name, checkpoint.Cluster, err)
+			}
+			if !dryRun {
+				for _, node := range checkpoint.Nodes {
+					if err := runtime.DeleteImage(ctx, node.Image); err != nil {
+						l.Log().Warnf("Failed to remove checkpoint image '%s': %v", node.Image, err)
+					}
+				}
+				if err := os.RemoveAll(dir); err != nil {
+					return items, fmt.Errorf("failed to remove checkpoint '%s' of cluster '%s': %w", checkpoint.Name, checkpoint.Cluster, err)
+				}
+			}
+			items = append(items, &k3d.GCItem{Kind: k3d.GCKindCheckpoint, Name: checkpoint.Name, Cluster: checkpoint.Cluster, Size: size})
+		}
+	}
+	return items, nil
+}
+
+// dirSize sums up the sizes of all files in a directory tree
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,165 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+
+	runtimeTypes "github.com/rancher/k3d/v5/pkg/runtimes/types"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/rancher/k3d/v5/pkg/util"
+)
+
+// newGCTestRuntime returns a runtime with a running cluster 'foo' (with an old and a recent image tarball), its exited tools node,
+// a stopped cluster 'bar' and an unused k3s image
+func newGCTestRuntime() *fakeRuntime {
+	server := newFakeNode("foo", "k3d-foo-server-0", k3d.ServerRole, true)
+	server.Image = "rancher/k3s:v1.22.2-k3s2"
+	server.Volumes = []string{fmt.Sprintf("k3d-foo-images:%s", k3d.DefaultImageVolumeMountPath)}
+	tools := newFakeNode("foo", "k3d-foo-tools", "", false)
+	stopped := newFakeNode("bar", "k3d-bar-agent-0", k3d.AgentRole, false)
+	stopped.Image = "rancher/k3s:v1.22.2-k3s2"
+	for _, node := range []*k3d.Node{server, tools, stopped} {
+		node.RuntimeLabels["app"] = "k3d"
+	}
+
+	old := time.Now().Add(-time.Hour).Unix()
+	recent := time.Now().Unix()
+	return &fakeRuntime{
+		nodes: []*k3d.Node{server, tools, stopped},
+		execOutputs: map[string]string{
+			"sh -c " + gcTarballListScript: fmt.Sprintf("1000 %d /k3d/images/k3d-foo-images-1.tar\r\n500 %d /k3d/images/k3d-foo-images-2.tar\r\n", old, recent),
+		},
+		listedImages: []runtimeTypes.Image{
+			{ID: "sha256:used", Tags: []string{"rancher/k3s:v1.22.2-k3s2"}, Size: 3000},
+			{ID: "sha256:unused", Tags: []string{"rancher/k3s:v1.21.5-k3s1"}, Size: 2000},
+		},
+		diskUsage: &runtimeTypes.DiskUsage{Containers: map[string]runtimeTypes.ContainerDiskUsage{"k3d-foo-tools": {SizeRw: 100}}},
+	}
+}
+
+// useFakeDiskFree replaces the free space measurement with the given function for the duration of the test
+func useFakeDiskFree(t *testing.T, free func() uint64) {
+	t.Helper()
+	diskFree = func(string) (uint64, error) { return free(), nil }
+	t.Cleanup(func() { diskFree = util.DiskFree })
+}
+
+func writeTestCheckpoint(t *testing.T, checkpoint *k3d.ClusterCheckpoint) string {
+	t.Helper()
+	dir, err := checkpointDirectory(checkpoint.Cluster, checkpoint.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeCheckpointMetadata(dir, checkpoint); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path.Join(dir, "archive.tar"), make([]byte, 300), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestGC(t *testing.T) {
+	useTempConfigDir(t)
+	useFakeDiskFree(t, func() uint64 { return 0 })
+
+	oldCheckpoint := writeTestCheckpoint(t, &k3d.ClusterCheckpoint{Name: "old", Cluster: "foo", Created: time.Now().Add(-48 * time.Hour),
+		Nodes: []k3d.CheckpointNode{{Name: "k3d-foo-server-0", Image: "k3d-foo-server-0:checkpoint-old"}}})
+	recentCheckpoint := writeTestCheckpoint(t, &k3d.ClusterCheckpoint{Name: "recent", Cluster: "foo", Created: time.Now()})
+
+	runtime := newGCTestRuntime()
+	report, err := GC(context.Background(), runtime, k3d.GCOpts{Path: "/", CheckpointsOlderThan: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kinds := []string{}
+	for _, item := range report.Items {
+		kinds = append(kinds, fmt.Sprintf("%s:%s", item.Kind, item.Name))
+	}
+	expected := []string{"tarball:/k3d/images/k3d-foo-images-1.tar", "container:k3d-foo-tools", "image:rancher/k3s:v1.21.5-k3s1", "checkpoint:old"}
+	if !reflect.DeepEqual(kinds, expected) {
+		t.Errorf("expected removed items %v, got %v", expected, kinds)
+	}
+	if report.Freed < 1000+100+2000+300 {
+		t.Errorf("expected at least %d bytes to be freed, got %d", 1000+100+2000+300, report.Freed)
+	}
+
+	if execs := runtime.execs["k3d-foo-server-0"]; len(execs) != 2 || execs[1] != "rm -f /k3d/images/k3d-foo-images-1.tar" {
+		t.Errorf("expected only the old tarball to be removed, got %v", execs)
+	}
+	if deleted := runtime.callsOf("DeleteNode"); !reflect.DeepEqual(deleted, []string{"k3d-foo-tools"}) {
+		t.Errorf("expected only the exited tools node to be removed, got %v", deleted)
+	}
+	if deleted := runtime.callsOf("DeleteImage"); !reflect.DeepEqual(deleted, []string{"rancher/k3s:v1.21.5-k3s1", "k3d-foo-server-0:checkpoint-old"}) {
+		t.Errorf("expected the unused k3s image and the old checkpoint image to be removed, got %v", deleted)
+	}
+	if _, err := os.Stat(oldCheckpoint); !os.IsNotExist(err) {
+		t.Errorf("expected the old checkpoint to be removed, got %v", err)
+	}
+	if _, err := os.Stat(recentCheckpoint); err != nil {
+		t.Errorf("expected the recent checkpoint to be kept, got %v", err)
+	}
+}
+
+func TestGCMinFree(t *testing.T) {
+	useTempConfigDir(t)
+
+	// enough free space: nothing to do
+	useFakeDiskFree(t, func() uint64 { return 5000 })
+	runtime := newGCTestRuntime()
+	report, err := GC(context.Background(), runtime, k3d.GCOpts{Path: "/", MinFree: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Items) != 0 || len(runtime.calls) != 0 {
+		t.Errorf("expected nothing to be removed with enough free space, got %v (calls: %v)", report.Items, runtime.calls)
+	}
+
+	// removing the tarballs frees enough space (estimated in a dry-run)
+	useFakeDiskFree(t, func() uint64 { return 0 })
+	runtime = newGCTestRuntime()
+	report, err = GC(context.Background(), runtime, k3d.GCOpts{Path: "/", MinFree: 1000, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Items) != 1 || report.Items[0].Kind != k3d.GCKindTarball {
+		t.Errorf("expected only the tarball to be removed, got %+v", report.Items)
+	}
+	if report.FreeAfter != 1000 {
+		t.Errorf("expected an estimated free space of 1000 bytes, got %d", report.FreeAfter)
+	}
+	if len(runtime.callsOf("DeleteNode")) != 0 || len(runtime.execs["k3d-foo-server-0"]) != 1 {
+		t.Errorf("expected nothing to be removed in a dry-run, got calls %v and execs %v", runtime.calls, runtime.execs)
+	}
+}
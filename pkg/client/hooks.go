@@ -0,0 +1,124 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	goruntime "runtime"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// hookKubeconfig writes the kubeconfig of the cluster to a temporary file for the hooks and returns its path (variable for tests)
+var hookKubeconfig = func(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster) (string, error) {
+	kubeconfig, err := KubeconfigGet(ctx, runtime, cluster)
+	if err != nil {
+		return "", fmt.Errorf("failed to get kubeconfig: %w", err)
+	}
+	tmpfile, err := ioutil.TempFile("", fmt.Sprintf("%s-%s-hook-kubeconfig-*.yaml", k3d.DefaultObjectNamePrefix, cluster.Name))
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary kubeconfig file: %w", err)
+	}
+	tmpfile.Close()
+	if err := KubeconfigWrite(ctx, kubeconfig, tmpfile.Name()); err != nil {
+		os.Remove(tmpfile.Name())
+		return "", err
+	}
+	return tmpfile.Name(), nil
+}
+
+// hookShellCommand returns the command running a hook with the shell of the host
+func hookShellCommand(ctx context.Context, command string) *exec.Cmd {
+	if goruntime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+// ClusterRunHooks runs the hooks of the given phase one after another on the host, in the current working directory and with the output
+// going to the one of k3d. They get the name of the cluster ($K3D_CLUSTER), the phase ($K3D_HOOK_PHASE) and (except for preCreate)
+// a kubeconfig for the cluster ($KUBECONFIG, removed again afterwards). The first failing hook stops the remaining ones.
+func ClusterRunHooks(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster, hooks []k3d.ClusterHook, phase k3d.ClusterHookPhase) error {
+	commands := []string{}
+	for _, hook := range hooks {
+		if hook.Phase == phase {
+			commands = append(commands, hook.Command)
+		}
+	}
+	if len(commands) == 0 {
+		return nil
+	}
+
+	env := append(os.Environ(), fmt.Sprintf("K3D_CLUSTER=%s", cluster.Name), fmt.Sprintf("K3D_HOOK_PHASE=%s", phase))
+	if phase != k3d.ClusterHookPreCreate {
+		kubeconfigPath, err := hookKubeconfig(ctx, runtime, cluster)
+		if err != nil {
+			// the cluster may already be broken or stopped when it's deleted, which shouldn't prevent the cleanup the hooks do outside of it
+			if phase != k3d.ClusterHookPreDelete {
+				return fmt.Errorf("failed to provide the kubeconfig for the %s hooks: %w", phase, err)
+			}
+			l.Log().Warnf("Running the %s hooks without KUBECONFIG: %v", phase, err)
+		} else {
+			defer os.Remove(kubeconfigPath)
+			env = append(env, fmt.Sprintf("KUBECONFIG=%s", kubeconfigPath))
+		}
+	}
+
+	for _, command := range commands {
+		l.Log().Infof("Running %s hook: %s", phase, command)
+		cmd := hookShellCommand(ctx, command)
+		cmd.Env = env
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s hook '%s' failed: %w", phase, command, err)
+		}
+	}
+	return nil
+}
+
+// clusterPreDeleteHooks returns the preDelete hooks remembered in the labels of the cluster's nodes
+func clusterPreDeleteHooks(cluster *k3d.Cluster) ([]k3d.ClusterHook, error) {
+	for _, node := range cluster.Nodes {
+		label, ok := node.RuntimeLabels[k3d.LabelClusterPreDeleteHooks]
+		if !ok {
+			continue
+		}
+		commands := []string{}
+		if err := json.Unmarshal([]byte(label), &commands); err != nil {
+			return nil, fmt.Errorf("failed to parse the preDelete hooks of node '%s': %w", node.Name, err)
+		}
+		hooks := make([]k3d.ClusterHook, 0, len(commands))
+		for _, command := range commands {
+			hooks = append(hooks, k3d.ClusterHook{Phase: k3d.ClusterHookPreDelete, Command: command})
+		}
+		return hooks, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,107 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestClusterRunHooks(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	cluster := &k3d.Cluster{Name: "test"}
+	hooks := []k3d.ClusterHook{
+		{Phase: k3d.ClusterHookPreCreate, Command: "echo \"$K3D_CLUSTER $K3D_HOOK_PHASE\" >> " + out},
+		{Phase: k3d.ClusterHookPostClusterReady, Command: "echo \"$KUBECONFIG\" >> " + out},
+	}
+
+	if err := ClusterRunHooks(context.Background(), &fakeRuntime{}, cluster, hooks, k3d.ClusterHookPreCreate); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	content, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "test preCreate\n" {
+		t.Errorf("unexpected hook output '%s'", content)
+	}
+
+	kubeconfigPath := filepath.Join(dir, "kubeconfig")
+	oldHookKubeconfig := hookKubeconfig
+	defer func() { hookKubeconfig = oldHookKubeconfig }()
+	hookKubeconfig = func(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster) (string, error) {
+		return kubeconfigPath, ioutil.WriteFile(kubeconfigPath, []byte("apiVersion: v1\n"), 0600)
+	}
+	if err := ClusterRunHooks(context.Background(), &fakeRuntime{}, cluster, hooks, k3d.ClusterHookPostClusterReady); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	content, err = ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "test preCreate\n"+kubeconfigPath+"\n" {
+		t.Errorf("unexpected hook output '%s'", content)
+	}
+	if _, err := ioutil.ReadFile(kubeconfigPath); err == nil {
+		t.Errorf("expected the hook kubeconfig to be removed")
+	}
+
+	failing := []k3d.ClusterHook{{Phase: k3d.ClusterHookPreCreate, Command: "exit 3"}}
+	if err := ClusterRunHooks(context.Background(), &fakeRuntime{}, cluster, failing, k3d.ClusterHookPreCreate); err == nil {
+		t.Errorf("expected an error for a failing hook")
+	}
+}
+
+func TestClusterPreDeleteHooks(t *testing.T) {
+	server := newFakeNode("test", "k3d-test-server-0", k3d.ServerRole, true)
+	cluster := &k3d.Cluster{Name: "test", Nodes: []*k3d.Node{server}}
+
+	hooks, err := clusterPreDeleteHooks(cluster)
+	if err != nil || len(hooks) != 0 {
+		t.Errorf("expected no hooks without the label, got %v (%v)", hooks, err)
+	}
+
+	server.RuntimeLabels[k3d.LabelClusterPreDeleteHooks] = `["./cleanup.sh","echo a,b"]`
+	hooks, err = clusterPreDeleteHooks(cluster)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []k3d.ClusterHook{
+		{Phase: k3d.ClusterHookPreDelete, Command: "./cleanup.sh"},
+		{Phase: k3d.ClusterHookPreDelete, Command: "echo a,b"},
+	}
+	if !reflect.DeepEqual(hooks, expected) {
+		t.Errorf("expected %v, got %v", expected, hooks)
+	}
+
+	server.RuntimeLabels[k3d.LabelClusterPreDeleteHooks] = "./cleanup.sh"
+	if _, err := clusterPreDeleteHooks(cluster); err == nil {
+		t.Errorf("expected an error for an invalid label")
+	}
+}
@@ -0,0 +1,130 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"fmt"
+	"net"
+	"os"
+	goruntime "runtime"
+	"sort"
+	"strconv"
+
+	"github.com/docker/go-connections/nat"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/rancher/k3d/v5/pkg/util"
+)
+
+// hostOS is the operating system whose hints are given for unavailable host ports (variable for tests)
+var hostOS = goruntime.GOOS
+
+// isHostPortFree checks whether a host port can be bound (variable for tests)
+var isHostPortFree = util.IsHostPortFree
+
+// ClusterCheckHostPorts tries to bind the fixed host ports of a cluster on this machine before the cluster is created,
+// so that ports which are in use (or reserved) are reported with a hint for the platform and a free alternative,
+// instead of the runtime's raw error after half of the cluster was created.
+// Only ports on all interfaces or loopback addresses are checked and remote docker hosts are skipped, as their ports can't be checked from here.
+func ClusterCheckHostPorts(runtime runtimes.Runtime, cluster *k3d.Cluster) error {
+	problems := &ValidationError{}
+	if cluster.Network.Name == "host" || RemoteDockerHost(runtime) != nil {
+		return nil
+	}
+
+	bound := map[string]bool{} // bindings checked already: the same host port may be listed for the API and the loadbalancer
+	for _, node := range cluster.Nodes {
+		ports := make([]nat.Port, 0, len(node.Ports))
+		for port := range node.Ports {
+			ports = append(ports, port)
+		}
+		sort.Slice(ports, func(i, j int) bool { return ports[i] < ports[j] })
+		for _, port := range ports {
+			for _, binding := range node.Ports[port] {
+				hostPort, err := strconv.Atoi(binding.HostPort)
+				if err != nil || hostPort == 0 || !isCheckableHostIP(binding.HostIP) {
+					continue // random host port or address of another machine (e.g. docker-machine)
+				}
+				key := fmt.Sprintf("%s:%d/%s", binding.HostIP, hostPort, port.Proto())
+				if bound[key] {
+					continue
+				}
+				bound[key] = true
+				if privilegedPortUncheckable(hostPort) {
+					l.Log().Debugf("Not checking privileged host port %d, which the docker daemon binds as root", hostPort)
+					continue
+				}
+				if !isHostPortFree(binding.HostIP, hostPort, port.Proto()) {
+					problems.Add("host port %d/%s of node '%s' is not available: %s; try a free port like %d instead", hostPort, port.Proto(), node.Name, hostPortHint(hostPort, port.Proto()), freeHostPortNear(binding.HostIP, hostPort, port.Proto(), bound))
+				}
+			}
+		}
+	}
+	return problems.ErrorOrNil()
+}
+
+// isCheckableHostIP returns true for addresses of this machine, whose ports can be checked by binding them
+func isCheckableHostIP(hostIP string) bool {
+	if hostIP == "" || hostIP == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(hostIP)
+	return ip != nil && (ip.IsUnspecified() || ip.IsLoopback())
+}
+
+// privilegedPortUncheckable returns true for ports below 1024 on Linux, which only root may bind, while the docker daemon can
+func privilegedPortUncheckable(port int) bool {
+	return port < 1024 && hostOS == "linux" && os.Geteuid() != 0
+}
+
+// hostPortHint explains what usually keeps a host port from being available on the current platform and how to find out
+func hostPortHint(port int, proto string) string {
+	switch hostOS {
+	case "windows":
+		return fmt.Sprintf("it's in use or in a port range reserved by Hyper-V/WSL (check with 'netstat -ano | findstr :%d' and 'netsh interface ipv4 show excludedportrange protocol=%s')", port, proto)
+	case "darwin":
+		hint := fmt.Sprintf("it's in use by another process or a port mapping of Docker Desktop/another VM (check with 'lsof -nP -i :%d')", port)
+		if port == 5000 || port == 7000 {
+			hint += ", e.g. the AirPlay Receiver (System Settings > General > AirDrop & Handoff)"
+		}
+		return hint
+	default:
+		return fmt.Sprintf("it's in use by another process or container (check with 'ss -lnp | grep :%d' and 'docker ps')", port)
+	}
+}
+
+// freeHostPortNear returns the first available port above the given one, falling back to a port picked by the OS
+func freeHostPortNear(hostIP string, port int, proto string, bound map[string]bool) int {
+	for candidate := port + 1; candidate <= 65535 && candidate <= port+100; candidate++ {
+		if bound[fmt.Sprintf("%s:%d/%s", hostIP, candidate, proto)] || privilegedPortUncheckable(candidate) {
+			continue
+		}
+		if isHostPortFree(hostIP, candidate, proto) {
+			return candidate
+		}
+	}
+	if free, err := util.GetFreePort(); err == nil {
+		return free
+	}
+	return 0
+}
@@ -0,0 +1,74 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"errors"
+	goruntime "runtime"
+	"strings"
+	"testing"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/rancher/k3d/v5/pkg/util"
+)
+
+func TestClusterCheckHostPorts(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "")
+	used := map[int]bool{8080: true, 8081: true, 5000: true}
+	isHostPortFree = func(_ string, port int, _ string) bool { return !used[port] }
+	defer func() { isHostPortFree = util.IsHostPortFree; hostOS = goruntime.GOOS }()
+	hostOS = "darwin"
+
+	lb := &k3d.Node{Name: "k3d-test-serverlb", Ports: nat.PortMap{
+		"6443/tcp": {{HostIP: "0.0.0.0", HostPort: "6550"}},
+		"80/tcp":   {{HostIP: "0.0.0.0", HostPort: "8080"}},
+		"5000/tcp": {{HostIP: "192.168.99.100", HostPort: "5000"}}, // docker-machine address: not checked
+	}}
+	agent := &k3d.Node{Name: "k3d-test-agent-0", Ports: nat.PortMap{
+		"8443/tcp": {{HostIP: "127.0.0.1", HostPort: "5000"}},
+		"9000/tcp": {{HostPort: ""}}, // random
+	}}
+	cluster := &k3d.Cluster{Name: "test", Network: k3d.ClusterNetwork{Name: "k3d-test"}, Nodes: []*k3d.Node{lb, agent}}
+
+	var problems *ValidationError
+	if err := ClusterCheckHostPorts(runtimes.Docker, cluster); !errors.As(err, &problems) || len(problems.Problems) != 2 {
+		t.Fatalf("expected two unavailable ports, got %v", err)
+	}
+	if p := problems.Problems[0]; !strings.Contains(p, "host port 8080/tcp of node 'k3d-test-serverlb'") || !strings.Contains(p, "lsof -nP -i :8080") || !strings.Contains(p, "like 8082 instead") {
+		t.Errorf("unexpected problem '%s'", p)
+	}
+	if p := problems.Problems[1]; !strings.Contains(p, "5000/tcp") || !strings.Contains(p, "AirPlay Receiver") {
+		t.Errorf("expected the AirPlay hint for port 5000 on macOS, got '%s'", p)
+	}
+
+	hostOS = "windows"
+	if err := ClusterCheckHostPorts(runtimes.Docker, cluster); err == nil || !strings.Contains(err.Error(), "excludedportrange") {
+		t.Errorf("expected the Hyper-V hint on Windows, got %v", err)
+	}
+
+	t.Setenv("DOCKER_HOST", "ssh://me@buildhost")
+	if err := ClusterCheckHostPorts(runtimes.Docker, cluster); err != nil {
+		t.Errorf("expected ports of remote docker hosts not to be checked, got %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	k3drt "github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// imageCacheFile is the image tarball in the image cache volume, which k3s imports on startup
+const imageCacheFile = "k3d-image-cache.tar"
+
+// imageCacheFillScript pulls the images of the k3s system components (as referenced in the auto-deploy manifests) and exports
+// all images of the node to the image cache volume, unless that's been done before (e.g. by another cluster).
+// The tarball is written to a directory first (which k3s skips) and moved into place, so concurrently starting nodes never see a partial file.
+var imageCacheFillScript = fmt.Sprintf(`set -e
+cd %[1]s
+[ -f %[2]s ] && exit 0
+for image in $(sed -n 's/^ *image: *"\{0,1\}\([^" ]*\)"\{0,1\} *$/\1/p' /var/lib/rancher/k3s/server/manifests/*.yaml | sort -u); do
+  k3s crictl pull "$image" >/dev/null || echo "failed to pull $image"
+done
+mkdir -p .k3d-tmp
+tmp=".k3d-tmp/$(hostname).tar"
+k3s ctr -n k8s.io images export "$tmp" $(k3s ctr -n k8s.io images ls -q | grep -v '^sha256:')
+mv "$tmp" %[2]s
+echo "filled image cache"
+`, k3d.DefaultImageCacheMountPath, imageCacheFile)
+
+// ImageCacheVolumeName returns the name of the host-wide volume caching the system images used by the given k3s image
+func ImageCacheVolumeName(image string) string {
+	sum := sha256.Sum256([]byte(image))
+	return fmt.Sprintf("%s-image-cache-%x", k3d.DefaultObjectNamePrefix, sum[:6])
+}
+
+// ClusterPrepImageCache mounts the image cache volume of their k3s image into all server and agent nodes, so that k3s imports
+// the system images (pause, coredns, ...) from there instead of pulling them in every single node.
+// The volumes are not bound to a cluster, so they survive cluster deletion and speed up all later clusters using the same k3s image.
+func ClusterPrepImageCache(ctx context.Context, runtime k3drt.Runtime, cluster *k3d.Cluster) error {
+	volumes := map[string]struct{}{}
+	for _, node := range cluster.Nodes {
+		if node.Role != k3d.ServerRole && node.Role != k3d.AgentRole {
+			continue
+		}
+		if hasVolumeMountedAt(node.Volumes, k3d.DefaultImageCacheMountPath) {
+			l.Log().Warnf("Not using the image cache in node '%s', as there's a volume mounted at '%s' already", node.Name, k3d.DefaultImageCacheMountPath)
+			continue
+		}
+
+		volume := ImageCacheVolumeName(node.Image)
+		if _, ok := volumes[volume]; !ok {
+			if _, err := runtime.GetVolume(volume); err != nil {
+				if err := runtime.CreateVolume(ctx, volume, map[string]string{k3d.LabelImageCache: node.Image}); err != nil {
+					return fmt.Errorf("failed to create image cache volume '%s': %w", volume, err)
+				}
+			}
+			volumes[volume] = struct{}{}
+		}
+		node.Volumes = append(node.Volumes, fmt.Sprintf("%s:%s", volume, k3d.DefaultImageCacheMountPath))
+	}
+	return nil
+}
+
+// ClusterFillImageCache exports the system images from the first running server to its image cache volume, if it's still empty
+func ClusterFillImageCache(ctx context.Context, runtime k3drt.Runtime, cluster *k3d.Cluster) error {
+	var server *k3d.Node
+	for _, node := range cluster.Nodes {
+		if node.Role == k3d.ServerRole && hasVolumeMountedAt(node.Volumes, k3d.DefaultImageCacheMountPath) {
+			server = node
+			break
+		}
+	}
+	if server == nil {
+		return nil // no node uses the image cache
+	}
+
+	logreader, err := runtime.ExecInNodeGetLogs(ctx, server, []string{"sh", "-c", imageCacheFillScript})
+	if err != nil {
+		return fmt.Errorf("failed to export the images of node '%s': %w", server.Name, err)
+	}
+	output, err := ioutil.ReadAll(logreader)
+	if err != nil {
+		return fmt.Errorf("failed to read the output of the image export: %w", err)
+	}
+	if strings.Contains(string(output), "filled image cache") {
+		l.Log().Infof("Filled image cache '%s' for later clusters using image '%s'", ImageCacheVolumeName(server.Image), server.Image)
+	}
+	return nil
+}
+
+// hasVolumeMountedAt tells whether one of the volume specs (SRC:DEST[:MODE]) mounts something at the given path
+func hasVolumeMountedAt(volumes []string, path string) bool {
+	for _, volume := range volumes {
+		parts := strings.Split(volume, ":")
+		if len(parts) > 1 && parts[1] == path {
+			return true
+		}
+	}
+	return false
+}
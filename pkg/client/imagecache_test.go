@@ -0,0 +1,102 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestClusterPrepImageCache(t *testing.T) {
+	server0 := newFakeNode("test", "k3d-test-server-0", k3d.ServerRole, false)
+	server1 := newFakeNode("test", "k3d-test-server-1", k3d.ServerRole, false)
+	agent0 := newFakeNode("test", "k3d-test-agent-0", k3d.AgentRole, false)
+	agent1 := newFakeNode("test", "k3d-test-agent-1", k3d.AgentRole, false)
+	lb := newFakeNode("test", "k3d-test-serverlb", k3d.LoadBalancerRole, false)
+	for _, node := range []*k3d.Node{server0, server1, agent0, agent1} {
+		node.Image = "rancher/k3s:v1.21.4-k3s2"
+	}
+	agent1.Image = "rancher/k3s:v1.22.2-k3s1"
+	agent1.Volumes = []string{"/airgap:" + k3d.DefaultImageCacheMountPath}
+
+	cachedVolume := ImageCacheVolumeName(server0.Image)
+	runtime := &fakeRuntime{volumes: map[string]map[string]string{}}
+	cluster := &k3d.Cluster{Name: "test", Nodes: []*k3d.Node{server0, server1, agent0, agent1, lb}}
+
+	if err := ClusterPrepImageCache(context.Background(), runtime, cluster); err != nil {
+		t.Fatal(err)
+	}
+
+	if created := runtime.callsOf("CreateVolume"); !reflect.DeepEqual(created, []string{cachedVolume}) {
+		t.Errorf("expected a single cache volume '%s' to be created, got %v", cachedVolume, created)
+	}
+	if labels := runtime.volumes[cachedVolume]; labels[k3d.LabelImageCache] != server0.Image {
+		t.Errorf("expected the cache volume to be labeled with its image, got %v", labels)
+	}
+	mount := fmt.Sprintf("%s:%s", cachedVolume, k3d.DefaultImageCacheMountPath)
+	for _, node := range []*k3d.Node{server0, server1, agent0} {
+		if !reflect.DeepEqual(node.Volumes, []string{mount}) {
+			t.Errorf("%s: expected the cache volume to be mounted, got %v", node.Name, node.Volumes)
+		}
+	}
+	if len(agent1.Volumes) != 1 || len(lb.Volumes) != 0 {
+		t.Errorf("expected no cache volume in nodes with a volume at the import path or without k3s, got %v and %v", agent1.Volumes, lb.Volumes)
+	}
+
+	// existing caches are reused
+	runtime = &fakeRuntime{volumes: map[string]map[string]string{cachedVolume: {}}}
+	if err := ClusterPrepImageCache(context.Background(), runtime, &k3d.Cluster{Name: "test", Nodes: []*k3d.Node{newFakeNode("test", "k3d-test-server-0", k3d.ServerRole, false)}}); err != nil {
+		t.Fatal(err)
+	}
+	if created := runtime.callsOf("CreateVolume"); len(created) != 1 || created[0] == cachedVolume {
+		t.Errorf("expected only the cache volume of the empty image to be created, got %v", created)
+	}
+}
+
+func TestClusterFillImageCache(t *testing.T) {
+	lb := newFakeNode("test", "k3d-test-serverlb", k3d.LoadBalancerRole, true)
+	server := newFakeNode("test", "k3d-test-server-0", k3d.ServerRole, true)
+	server.Image = "rancher/k3s:v1.21.4-k3s2"
+	server.Volumes = []string{fmt.Sprintf("%s:%s", ImageCacheVolumeName(server.Image), k3d.DefaultImageCacheMountPath)}
+
+	fillCmd := "sh -c " + imageCacheFillScript
+	runtime := &fakeRuntime{execOutputs: map[string]string{fillCmd: "filled image cache\n"}}
+	if err := ClusterFillImageCache(context.Background(), runtime, &k3d.Cluster{Name: "test", Nodes: []*k3d.Node{lb, server}}); err != nil {
+		t.Fatal(err)
+	}
+	if execs := runtime.execs[server.Name]; !reflect.DeepEqual(execs, []string{fillCmd}) {
+		t.Errorf("expected the images to be exported from the server, got %v", runtime.execs)
+	}
+
+	// nothing to do without cache volumes
+	runtime = &fakeRuntime{}
+	if err := ClusterFillImageCache(context.Background(), runtime, &k3d.Cluster{Name: "test", Nodes: []*k3d.Node{newFakeNode("test", "k3d-test-server-0", k3d.ServerRole, true)}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(runtime.calls) != 0 {
+		t.Errorf("expected no runtime calls, got %v", runtime.calls)
+	}
+}
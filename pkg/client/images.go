@@ -0,0 +1,153 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	digest "github.com/opencontainers/go-digest"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	runtimeTypes "github.com/rancher/k3d/v5/pkg/runtimes/types"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// ImagePruneUnused removes k3s images from the runtime, that are not referenced by any existing k3d node
+// It returns the list of (tagged) images that were (or would be, in case of a dry-run) removed
+func ImagePruneUnused(ctx context.Context, runtime runtimes.Runtime, opts k3d.ImagePruneOpts) ([]runtimeTypes.Image, error) {
+	images, err := runtime.ListImages(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+
+	nodes, err := runtime.GetNodesByLabel(ctx, k3d.DefaultRuntimeLabels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	unused := findUnusedImages(images, nodes, imageRepository(normalizeImageName(k3d.DefaultK3sImageRepo)))
+
+	pruned := []runtimeTypes.Image{}
+	for _, image := range unused {
+		if opts.DryRun {
+			pruned = append(pruned, image)
+			continue
+		}
+		removed := runtimeTypes.Image{ID: image.ID, Size: image.Size}
+		for _, tag := range image.Tags {
+			l.Log().Debugf("Removing unused image '%s'", tag)
+			if err := runtime.DeleteImage(ctx, tag); err != nil {
+				l.Log().Warnf("Failed to remove image '%s': %v", tag, err)
+				continue
+			}
+			removed.Tags = append(removed.Tags, tag)
+		}
+		if len(removed.Tags) > 0 {
+			pruned = append(pruned, removed)
+		}
+	}
+
+	return pruned, nil
+}
+
+// findUnusedImages returns all images from the given repository which are not referenced (by name or ID) by any of the given nodes
+func findUnusedImages(images []runtimeTypes.Image, nodes []*k3d.Node, repository string) []runtimeTypes.Image {
+	inUse := make(map[string]struct{}, len(nodes))
+	for _, node := range nodes {
+		inUse[node.Image] = struct{}{}
+		inUse[normalizeImageName(node.Image)] = struct{}{}
+	}
+
+	unused := []runtimeTypes.Image{}
+imageLoop:
+	for _, image := range images {
+		if _, ok := inUse[image.ID]; ok {
+			continue
+		}
+
+		var matchingTags []string
+		for _, tag := range image.Tags {
+			normalized := normalizeImageName(tag)
+			if _, ok := inUse[normalized]; ok {
+				continue imageLoop
+			}
+			if imageRepository(normalized) == repository {
+				matchingTags = append(matchingTags, tag)
+			}
+		}
+
+		if len(matchingTags) > 0 {
+			unused = append(unused, runtimeTypes.Image{ID: image.ID, Tags: matchingTags, Size: image.Size})
+		}
+	}
+
+	return unused
+}
+
+// normalizeImageName strips the default registry and namespace from an image name and ensures a tag
+// e.g. `docker.io/rancher/k3s` -> `rancher/k3s:latest`, `docker.io/library/alpine:3` -> `alpine:3`
+func normalizeImageName(image string) string {
+	image = strings.TrimPrefix(image, "docker.io/")
+	image = strings.TrimPrefix(image, "library/")
+	return canonicalImageName(image)
+}
+
+// imageRepository returns the repository part of an image name, i.e. without the tag (or digest)
+func imageRepository(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image
+}
+
+// ImageResolveDigest pins an image reference to the digest it currently points to, e.g. rancher/k3s:v1.21.4-k3s1 -> rancher/k3s:v1.21.4-k3s1@sha256:...
+// The tag is kept for readability, but the runtime only uses the digest. References that already contain a digest are returned as they are.
+func ImageResolveDigest(ctx context.Context, runtime runtimes.Runtime, image string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse image reference '%s': %w", image, err)
+	}
+	if _, ok := named.(reference.Canonical); ok {
+		return image, nil
+	}
+
+	dgst, err := runtime.GetImageDigest(ctx, image)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve the digest of image '%s': %w", image, err)
+	}
+	parsedDigest, err := digest.Parse(dgst)
+	if err != nil {
+		return "", fmt.Errorf("runtime returned an invalid digest '%s' for image '%s': %w", dgst, image, err)
+	}
+	pinned, err := reference.WithDigest(reference.TagNameOnly(named), parsedDigest)
+	if err != nil {
+		return "", fmt.Errorf("failed to add digest to image reference '%s': %w", image, err)
+	}
+	l.Log().Infof("Pinned image '%s' to '%s'", image, reference.FamiliarString(pinned))
+	return reference.FamiliarString(pinned), nil
+}
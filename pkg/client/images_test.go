@@ -0,0 +1,93 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-test/deep"
+	runtimeTypes "github.com/rancher/k3d/v5/pkg/runtimes/types"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func Test_normalizeImageName(t *testing.T) {
+	tests := map[string]string{
+		"docker.io/rancher/k3s:v1.21.4-k3s2": "rancher/k3s:v1.21.4-k3s2",
+		"rancher/k3s":                        "rancher/k3s:latest",
+		"docker.io/library/alpine":           "alpine:latest",
+		"registry:1234/rancher/k3s:v1":       "registry:1234/rancher/k3s:v1",
+	}
+	for given, expected := range tests {
+		if actual := normalizeImageName(given); actual != expected {
+			t.Errorf("normalizeImageName(%s): expected '%s', got '%s'", given, expected, actual)
+		}
+	}
+}
+
+func Test_findUnusedImages(t *testing.T) {
+	images := []runtimeTypes.Image{
+		{ID: "sha256:inuse-by-name", Tags: []string{"rancher/k3s:v1.21.4-k3s2"}},
+		{ID: "sha256:inuse-by-id", Tags: []string{"rancher/k3s:v1.20.0-k3s1"}},
+		{ID: "sha256:unused", Tags: []string{"rancher/k3s:v1.19.1-k3s1", "myrepo/k3s:custom"}},
+		{ID: "sha256:other", Tags: []string{"alpine:3"}},
+		{ID: "sha256:registry", Tags: []string{"registry:1234/rancher/k3s:v1"}},
+	}
+	nodes := []*k3d.Node{
+		{Name: "k3d-a-server-0", Image: "docker.io/rancher/k3s:v1.21.4-k3s2"},
+		{Name: "k3d-b-server-0", Image: "sha256:inuse-by-id"},
+	}
+
+	expected := []runtimeTypes.Image{
+		{ID: "sha256:unused", Tags: []string{"rancher/k3s:v1.19.1-k3s1"}},
+	}
+
+	if diff := deep.Equal(findUnusedImages(images, nodes, "rancher/k3s"), expected); diff != nil {
+		t.Errorf("unexpected unused images: %+v", diff)
+	}
+}
+
+func TestImageResolveDigest(t *testing.T) {
+	const dgst = "sha256:6dd3b6e7a6dc2b2a6e5d2d7b3b6d2e2b0c8a3f5e4d6c7b8a9f0e1d2c3b4a5f6e"
+	runtime := &fakeRuntime{digests: map[string]string{"rancher/k3s:v1.21.4-k3s1": dgst}}
+
+	pinned, err := ImageResolveDigest(context.Background(), runtime, "rancher/k3s:v1.21.4-k3s1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "rancher/k3s:v1.21.4-k3s1@" + dgst; pinned != expected {
+		t.Errorf("expected %q, got %q", expected, pinned)
+	}
+
+	// already pinned references are kept without asking the runtime
+	again, err := ImageResolveDigest(context.Background(), runtime, pinned)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if again != pinned || len(runtime.callsOf("GetImageDigest")) != 1 {
+		t.Errorf("expected %q to be kept as is, got %q (runtime calls: %v)", pinned, again, runtime.callsOf("GetImageDigest"))
+	}
+
+	if _, err := ImageResolveDigest(context.Background(), runtime, "rancher/k3s:unknown"); err == nil {
+		t.Errorf("expected an error for an image without digest")
+	}
+}
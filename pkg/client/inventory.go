@@ -0,0 +1,157 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// Inventory lists all k3d-managed containers, networks and volumes with their labels and relates them to each other and to the clusters.
+// Unlike ClusterList, it includes resources of broken clusters (e.g. without a server), so that external tools can detect and clean them up.
+func Inventory(ctx context.Context, runtime runtimes.Runtime) (*k3d.Inventory, error) {
+	nodes, err := runtime.GetNodesByLabel(ctx, k3d.DefaultRuntimeLabels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	networks, err := runtime.GetNetworksByLabel(ctx, k3d.DefaultRuntimeLabels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networks: %w", err)
+	}
+	volumes, err := runtime.GetVolumesByLabel(ctx, k3d.DefaultRuntimeLabels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volumes: %w", err)
+	}
+
+	inventory := &k3d.Inventory{
+		APIVersion: k3d.InventoryAPIVersion,
+		Clusters:   []*k3d.InventoryCluster{},
+		Nodes:      []*k3d.InventoryNode{},
+		Registries: []*k3d.InventoryNode{},
+		Networks:   []*k3d.InventoryNetwork{},
+		Volumes:    []*k3d.InventoryVolume{},
+	}
+
+	clusters := map[string]*k3d.InventoryCluster{}
+	clusterFor := func(name string) *k3d.InventoryCluster {
+		if _, ok := clusters[name]; !ok {
+			clusters[name] = &k3d.InventoryCluster{Name: name, Nodes: []string{}, Registries: []string{}, Volumes: []string{}}
+		}
+		return clusters[name]
+	}
+
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	mountedBy := map[string][]string{}
+	for _, node := range nodes {
+		role := node.Role
+		if role == "" {
+			role = k3d.NoRole
+		}
+		item := &k3d.InventoryNode{
+			Name:     node.Name,
+			Role:     string(role),
+			Status:   node.State.Status,
+			Networks: append([]string{}, node.Networks...),
+			Volumes:  []string{},
+			Labels:   inventoryLabels(node.RuntimeLabels),
+		}
+		for _, volume := range node.Volumes {
+			source := strings.SplitN(volume, ":", 2)[0]
+			if source == "" || strings.HasPrefix(source, "/") || strings.HasPrefix(source, ".") {
+				continue // bind mount of a host path
+			}
+			item.Volumes = append(item.Volumes, source)
+			mountedBy[source] = append(mountedBy[source], node.Name)
+		}
+
+		if role == k3d.RegistryRole {
+			inventory.Registries = append(inventory.Registries, item)
+			continue
+		}
+		inventory.Nodes = append(inventory.Nodes, item)
+		if clusterName := node.RuntimeLabels[k3d.LabelClusterName]; clusterName != "" {
+			item.Cluster = clusterName
+			cluster := clusterFor(clusterName)
+			cluster.Nodes = append(cluster.Nodes, node.Name)
+			if network := node.RuntimeLabels[k3d.LabelNetwork]; network != "" {
+				cluster.Network = network
+			}
+		}
+	}
+
+	// registries belong to the clusters whose network they're connected to
+	for _, registry := range inventory.Registries {
+		for _, network := range registry.Networks {
+			for _, cluster := range clusters {
+				if cluster.Network == network {
+					cluster.Registries = append(cluster.Registries, registry.Name)
+				}
+			}
+		}
+	}
+
+	sort.Slice(networks, func(i, j int) bool { return networks[i].Name < networks[j].Name })
+	for _, network := range networks {
+		item := &k3d.InventoryNetwork{Name: network.Name, ID: network.ID, Clusters: []string{}, Labels: inventoryLabels(network.Labels)}
+		for _, cluster := range clusters {
+			if cluster.Network == network.Name || cluster.Network == network.ID {
+				item.Clusters = append(item.Clusters, cluster.Name)
+			}
+		}
+		sort.Strings(item.Clusters)
+		inventory.Networks = append(inventory.Networks, item)
+	}
+
+	sort.Slice(volumes, func(i, j int) bool { return volumes[i].Name < volumes[j].Name })
+	for _, volume := range volumes {
+		item := &k3d.InventoryVolume{Name: volume.Name, MountedBy: []string{}, Labels: inventoryLabels(volume.Labels)}
+		item.MountedBy = append(item.MountedBy, mountedBy[volume.Name]...)
+		if clusterName := volume.Labels[k3d.LabelClusterName]; clusterName != "" {
+			item.Cluster = clusterName
+			cluster := clusterFor(clusterName)
+			cluster.Volumes = append(cluster.Volumes, volume.Name)
+		}
+		inventory.Volumes = append(inventory.Volumes, item)
+	}
+
+	for _, cluster := range clusters {
+		sort.Strings(cluster.Registries)
+		inventory.Clusters = append(inventory.Clusters, cluster)
+	}
+	sort.Slice(inventory.Clusters, func(i, j int) bool { return inventory.Clusters[i].Name < inventory.Clusters[j].Name })
+
+	return inventory, nil
+}
+
+// inventoryLabels returns a copy of the labels that's never nil, as the inventory schema requires an object
+func inventoryLabels(labels map[string]string) map[string]string {
+	copied := make(map[string]string, len(labels))
+	for k, v := range labels {
+		copied[k] = v
+	}
+	return copied
+}
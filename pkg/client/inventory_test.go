@@ -0,0 +1,84 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestInventory(t *testing.T) {
+	server := newFakeNode("foo", "k3d-foo-server-0", k3d.ServerRole, true)
+	server.Networks = []string{"k3d-foo"}
+	server.Volumes = []string{"k3d-foo-images:/k3d/images", "/home/me/data:/data"}
+	server.State.Status = "running"
+	broken := newFakeNode("bar", "k3d-bar-agent-0", k3d.AgentRole, false) // cluster without a server
+	broken.State.Status = "exited"
+	registry := &k3d.Node{Name: "k3d-registry", Role: k3d.RegistryRole, Networks: []string{"bridge", "k3d-foo"}, RuntimeLabels: map[string]string{k3d.LabelRole: string(k3d.RegistryRole)}}
+	for _, node := range []*k3d.Node{server, broken, registry} {
+		node.RuntimeLabels["app"] = "k3d"
+	}
+	server.RuntimeLabels[k3d.LabelNetwork] = "k3d-foo"
+
+	runtime := &fakeRuntime{
+		nodes:    []*k3d.Node{server, broken, registry},
+		networks: []*k3d.ClusterNetwork{{Name: "k3d-foo", ID: "abc", Labels: map[string]string{"app": "k3d"}}},
+		volumes: map[string]map[string]string{
+			"k3d-foo-images": {"app": "k3d", k3d.LabelClusterName: "foo"},
+			"k3d-bar-images": {"app": "k3d", k3d.LabelClusterName: "bar"},
+		},
+	}
+
+	inventory, err := Inventory(context.Background(), runtime)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if inventory.APIVersion != k3d.InventoryAPIVersion {
+		t.Errorf("expected apiVersion '%s', got '%s'", k3d.InventoryAPIVersion, inventory.APIVersion)
+	}
+	expectedClusters := []*k3d.InventoryCluster{
+		{Name: "bar", Nodes: []string{"k3d-bar-agent-0"}, Registries: []string{}, Volumes: []string{"k3d-bar-images"}},
+		{Name: "foo", Network: "k3d-foo", Nodes: []string{"k3d-foo-server-0"}, Registries: []string{"k3d-registry"}, Volumes: []string{"k3d-foo-images"}},
+	}
+	if !reflect.DeepEqual(inventory.Clusters, expectedClusters) {
+		for _, c := range inventory.Clusters {
+			t.Logf("%+v", *c)
+		}
+		t.Errorf("unexpected clusters")
+	}
+	if len(inventory.Nodes) != 2 || len(inventory.Registries) != 1 || inventory.Registries[0].Name != "k3d-registry" {
+		t.Errorf("expected 2 nodes and the registry to be listed separately, got %d nodes and %d registries", len(inventory.Nodes), len(inventory.Registries))
+	}
+	if !reflect.DeepEqual(inventory.Nodes[1].Volumes, []string{"k3d-foo-images"}) {
+		t.Errorf("expected only the named volume of the server (no bind mount), got %v", inventory.Nodes[1].Volumes)
+	}
+	if len(inventory.Networks) != 1 || !reflect.DeepEqual(inventory.Networks[0].Clusters, []string{"foo"}) {
+		t.Errorf("expected network 'k3d-foo' to belong to cluster 'foo', got %+v", inventory.Networks)
+	}
+	if len(inventory.Volumes) != 2 || !reflect.DeepEqual(inventory.Volumes[1].MountedBy, []string{"k3d-foo-server-0"}) || inventory.Volumes[0].MountedBy == nil {
+		t.Errorf("expected volume 'k3d-foo-images' to be mounted by the server, got %+v", inventory.Volumes)
+	}
+}
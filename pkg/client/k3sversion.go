@@ -0,0 +1,183 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/rancher/k3d/v5/pkg/util"
+	"github.com/rancher/k3d/v5/version"
+)
+
+// K3sChannelServerURL is the k3s update channel server, which maps channels (stable, latest, v1.21, ...) to k3s releases (variable for tests)
+var K3sChannelServerURL = "https://update.k3s.io/v1-release/channels"
+
+const (
+	k3sChannelServerTimeout = 10 * time.Second
+	k3sChannelsCacheFile    = "k3s-channels.json" // in the k3d config directory, used when the channel server can't be reached
+)
+
+// K3sChannel is a release channel of k3s and the k3s version it currently points to
+type K3sChannel struct {
+	Name   string `json:"id" yaml:"name"`
+	Latest string `json:"latest" yaml:"latest"`
+}
+
+// k3sVersionRegexp matches full k3s release versions, e.g. v1.21.4+k3s1 (the '+' becomes a '-' in image tags)
+var k3sVersionRegexp = regexp.MustCompile(`^v?(\d+\.\d+\.\d+)[+-](k3s\d+)$`)
+
+// K3sImageForVersion returns the k3s image for a k3s release version, e.g. v1.21.4+k3s1 -> rancher/k3s:v1.21.4-k3s1
+func K3sImageForVersion(k3sVersion string) (string, error) {
+	match := k3sVersionRegexp.FindStringSubmatch(k3sVersion)
+	if match == nil {
+		return "", fmt.Errorf("invalid k3s version '%s': expected a release like 'v1.21.4+k3s1' (use a channel like 'v1.21' for the latest patch release)", k3sVersion)
+	}
+	return fmt.Sprintf("%s:v%s-%s", k3d.DefaultK3sImageRepo, match[1], match[2]), nil
+}
+
+// K3sImageForChannel returns the k3s image of the release a k3s channel currently points to.
+// Without access to the channel server, the channels last fetched are used, or, for 'stable' and 'latest', the default k3s version of k3d.
+func K3sImageForChannel(ctx context.Context, channel string) (string, error) {
+	channels, err := K3sChannelsGet(ctx)
+	if err != nil {
+		if channel != "stable" && channel != "latest" {
+			return "", err
+		}
+		l.Log().Warnf("%v: using the default k3s version %s for channel '%s'", err, version.K3sVersion, channel)
+		return fmt.Sprintf("%s:%s", k3d.DefaultK3sImageRepo, version.K3sVersion), nil
+	}
+	names := make([]string, 0, len(channels))
+	for _, c := range channels {
+		if c.Name == channel {
+			l.Log().Infof("Channel '%s' resolves to k3s %s", channel, c.Latest)
+			return K3sImageForVersion(c.Latest)
+		}
+		names = append(names, c.Name)
+	}
+	return "", fmt.Errorf("unknown k3s channel '%s' (one of %s)", channel, strings.Join(names, ", "))
+}
+
+// K3sChannelsGet fetches the k3s channels from the channel server (or, offline or if it can't be reached, from the local cache)
+func K3sChannelsGet(ctx context.Context) ([]K3sChannel, error) {
+	var fetchErr error
+	if Offline {
+		fetchErr = fmt.Errorf("offline mode")
+	} else {
+		channels, err := fetchK3sChannels(ctx)
+		if err == nil {
+			if err := writeK3sChannelsCache(channels); err != nil {
+				l.Log().Debugf("Failed to cache the k3s channels: %v", err)
+			}
+			return channels, nil
+		}
+		fetchErr = err
+	}
+
+	channels, err := readK3sChannelsCache()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the k3s channels (%v) and no cached ones are available: %w", fetchErr, err)
+	}
+	l.Log().Warnf("Using the last fetched k3s channels, as the channel server can't be used: %v", fetchErr)
+	return channels, nil
+}
+
+func fetchK3sChannels(ctx context.Context) ([]K3sChannel, error) {
+	ctx, cancel := context.WithTimeout(ctx, k3sChannelServerTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, K3sChannelServerURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the k3s channel server: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to query the k3s channel server: %s", resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the response of the k3s channel server: %w", err)
+	}
+	return parseK3sChannels(body)
+}
+
+func parseK3sChannels(content []byte) ([]K3sChannel, error) {
+	var response struct {
+		Data []K3sChannel `json:"data"`
+	}
+	if err := json.Unmarshal(content, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse the k3s channels: %w", err)
+	}
+	if len(response.Data) == 0 {
+		return nil, fmt.Errorf("no k3s channels found")
+	}
+	return response.Data, nil
+}
+
+func k3sChannelsCachePath() (string, error) {
+	configDir, err := util.GetConfigDirOrCreate()
+	if err != nil {
+		return "", err
+	}
+	return path.Join(configDir, k3sChannelsCacheFile), nil
+}
+
+func writeK3sChannelsCache(channels []K3sChannel) error {
+	cachePath, err := k3sChannelsCachePath()
+	if err != nil {
+		return err
+	}
+	content, err := json.Marshal(struct {
+		Data []K3sChannel `json:"data"`
+	}{channels})
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(cachePath, content, 0644)
+}
+
+func readK3sChannelsCache() ([]K3sChannel, error) {
+	cachePath, err := k3sChannelsCachePath()
+	if err != nil {
+		return nil, err
+	}
+	content, err := ioutil.ReadFile(cachePath)
+	if err != nil {
+		return nil, err
+	}
+	return parseK3sChannels(content)
+}
@@ -0,0 +1,97 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/rancher/k3d/v5/version"
+)
+
+func TestK3sImageForVersion(t *testing.T) {
+	for input, expected := range map[string]string{
+		"v1.21.4+k3s1": k3d.DefaultK3sImageRepo + ":v1.21.4-k3s1",
+		"v1.21.4-k3s2": k3d.DefaultK3sImageRepo + ":v1.21.4-k3s2",
+		"1.22.2+k3s1":  k3d.DefaultK3sImageRepo + ":v1.22.2-k3s1",
+	} {
+		image, err := K3sImageForVersion(input)
+		if err != nil {
+			t.Errorf("unexpected error for '%s': %v", input, err)
+		} else if image != expected {
+			t.Errorf("expected '%s' for '%s', got '%s'", expected, input, image)
+		}
+	}
+
+	for _, invalid := range []string{"v1.21", "v1.21.4", "latest", "v1.21.4+rke2r1"} {
+		if _, err := K3sImageForVersion(invalid); err == nil {
+			t.Errorf("expected an error for '%s'", invalid)
+		}
+	}
+}
+
+func TestK3sImageForChannel(t *testing.T) {
+	useTempConfigDir(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"type":"collection","data":[{"id":"stable","name":"stable","latest":"v1.21.5+k3s2"},{"id":"v1.22","name":"v1.22","latest":"v1.22.2+k3s1"}]}`))
+	}))
+	oldURL := K3sChannelServerURL
+	defer func() { K3sChannelServerURL = oldURL }()
+	K3sChannelServerURL = server.URL
+
+	image, err := K3sImageForChannel(context.Background(), "v1.22")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if image != k3d.DefaultK3sImageRepo+":v1.22.2-k3s1" {
+		t.Errorf("unexpected image '%s'", image)
+	}
+	if _, err := K3sImageForChannel(context.Background(), "v1.99"); err == nil {
+		t.Errorf("expected an error for an unknown channel")
+	}
+
+	// the channels fetched before are used without the channel server
+	server.Close()
+	image, err = K3sImageForChannel(context.Background(), "stable")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if image != k3d.DefaultK3sImageRepo+":v1.21.5-k3s2" {
+		t.Errorf("expected the cached channel, got '%s'", image)
+	}
+
+	// without any cache, only stable and latest fall back to the default version
+	useTempConfigDir(t)
+	image, err = K3sImageForChannel(context.Background(), "latest")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if image != k3d.DefaultK3sImageRepo+":"+version.K3sVersion {
+		t.Errorf("expected the default k3s version, got '%s'", image)
+	}
+	if _, err := K3sImageForChannel(context.Background(), "v1.22"); err == nil {
+		t.Errorf("expected an error for a channel that can't be looked up")
+	}
+}
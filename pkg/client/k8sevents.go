@@ -0,0 +1,189 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"k8s.io/client-go/rest"
+)
+
+// k8sEvent is the part of a core/v1 Event we're interested in
+type k8sEvent struct {
+	Metadata struct {
+		Namespace         string    `json:"namespace"`
+		ResourceVersion   string    `json:"resourceVersion"`
+		CreationTimestamp time.Time `json:"creationTimestamp"`
+	} `json:"metadata"`
+	InvolvedObject struct {
+		Kind string `json:"kind"`
+		Name string `json:"name"`
+	} `json:"involvedObject"`
+	Type               string    `json:"type"`
+	Reason             string    `json:"reason"`
+	Message            string    `json:"message"`
+	Count              int32     `json:"count"`
+	LastTimestamp      time.Time `json:"lastTimestamp"`
+	EventTime          time.Time `json:"eventTime"`
+	ReportingComponent string    `json:"reportingComponent"`
+	Source             struct {
+		Component string `json:"component"`
+	} `json:"source"`
+}
+
+// ClusterK8sEvents passes the Kubernetes events of a cluster to the handler (oldest first).
+// If opts.Watch is set, it keeps passing new (and repeated) events to the handler until the context is cancelled.
+func ClusterK8sEvents(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster, opts k3d.K8sEventsOpts, handler func(*k3d.K8sEvent)) error {
+	core, err := KubeRESTClient(ctx, runtime, cluster, "v1")
+	if err != nil {
+		return err
+	}
+
+	if err := k8sEventsStream(ctx, core, opts, handler); err != nil {
+		return fmt.Errorf("failed to get events of cluster '%s': %w", cluster.Name, err)
+	}
+
+	return nil
+}
+
+// k8sEventsStream lists the events and, if requested, watches for new ones starting at the resource version of the list
+func k8sEventsStream(ctx context.Context, core rest.Interface, opts k3d.K8sEventsOpts, handler func(*k3d.K8sEvent)) error {
+	var eventList struct {
+		Metadata struct {
+			ResourceVersion string `json:"resourceVersion"`
+		} `json:"metadata"`
+		Items []k8sEvent `json:"items"`
+	}
+
+	raw, err := core.Get().Namespace(opts.Namespace).Resource("events").Do(ctx).Raw()
+	if err != nil {
+		return fmt.Errorf("failed to list events: %w", err)
+	}
+	if err := json.Unmarshal(raw, &eventList); err != nil {
+		return fmt.Errorf("failed to unmarshal event list: %w", err)
+	}
+
+	events := make([]*k3d.K8sEvent, 0, len(eventList.Items))
+	for _, item := range eventList.Items {
+		events = append(events, item.toK3d())
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].LastSeen.Before(events[j].LastSeen) })
+	for _, event := range events {
+		handler(event)
+	}
+
+	if !opts.Watch {
+		return nil
+	}
+
+	// the API server closes watches after a while (--min-request-timeout), so we resume from the last seen resource version
+	resourceVersion := eventList.Metadata.ResourceVersion
+	for {
+		var err error
+		resourceVersion, err = k8sEventsWatch(ctx, core, opts.Namespace, resourceVersion, handler)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		l.Log().Debugf("Event watch closed by the API server, resuming at resource version %s", resourceVersion)
+	}
+}
+
+// k8sEventsWatch passes added and modified events to the handler until the watch ends and returns the last seen resource version
+func k8sEventsWatch(ctx context.Context, core rest.Interface, namespace string, resourceVersion string, handler func(*k3d.K8sEvent)) (string, error) {
+	stream, err := core.Get().Namespace(namespace).Resource("events").
+		Param("watch", "true").
+		Param("resourceVersion", resourceVersion).
+		Stream(ctx)
+	if err != nil {
+		return resourceVersion, fmt.Errorf("failed to watch events: %w", err)
+	}
+	defer stream.Close()
+
+	decoder := json.NewDecoder(stream)
+	for {
+		var watchEvent struct {
+			Type   string          `json:"type"`
+			Object json.RawMessage `json:"object"`
+		}
+		if err := decoder.Decode(&watchEvent); err != nil {
+			if err == io.EOF {
+				return resourceVersion, nil
+			}
+			return resourceVersion, fmt.Errorf("failed to read event watch: %w", err)
+		}
+
+		switch watchEvent.Type {
+		case "ADDED", "MODIFIED":
+			var event k8sEvent
+			if err := json.Unmarshal(watchEvent.Object, &event); err != nil {
+				return resourceVersion, fmt.Errorf("failed to unmarshal event: %w", err)
+			}
+			resourceVersion = event.Metadata.ResourceVersion
+			handler(event.toK3d())
+		case "ERROR":
+			var status struct {
+				Message string `json:"message"`
+			}
+			_ = json.Unmarshal(watchEvent.Object, &status)
+			return resourceVersion, fmt.Errorf("event watch failed: %s", status.Message)
+		}
+	}
+}
+
+// toK3d reduces the event to what we print, falling back to older timestamps for the time it was last seen
+func (e *k8sEvent) toK3d() *k3d.K8sEvent {
+	event := &k3d.K8sEvent{
+		Namespace: e.Metadata.Namespace,
+		Object:    fmt.Sprintf("%s/%s", strings.ToLower(e.InvolvedObject.Kind), e.InvolvedObject.Name),
+		Type:      e.Type,
+		Reason:    e.Reason,
+		Message:   strings.TrimSpace(e.Message),
+		Source:    e.Source.Component,
+		Count:     e.Count,
+		LastSeen:  e.LastTimestamp,
+	}
+	if event.Source == "" {
+		event.Source = e.ReportingComponent
+	}
+	if event.Count == 0 {
+		event.Count = 1
+	}
+	if event.LastSeen.IsZero() {
+		event.LastSeen = e.EventTime
+	}
+	if event.LastSeen.IsZero() {
+		event.LastSeen = e.Metadata.CreationTimestamp
+	}
+	return event
+}
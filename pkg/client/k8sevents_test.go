@@ -0,0 +1,102 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"k8s.io/client-go/rest"
+)
+
+// fakeEventsAPI serves an event list and a watch stream with a single (repeated) warning event
+type fakeEventsAPI struct {
+	watchResourceVersion string
+}
+
+func (f *fakeEventsAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("watch") == "true" {
+		f.watchResourceVersion = r.URL.Query().Get("resourceVersion")
+		fmt.Fprint(w, `{"type": "MODIFIED", "object": {"metadata": {"namespace": "default", "resourceVersion": "12"},
+			"involvedObject": {"kind": "Pod", "name": "web"}, "type": "Warning", "reason": "BackOff", "message": "Back-off restarting failed container",
+			"count": 3, "lastTimestamp": "2021-06-01T10:00:30Z", "source": {"component": "kubelet"}}}`)
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		<-r.Context().Done()
+		return
+	}
+
+	_, _ = w.Write([]byte(`{"metadata": {"resourceVersion": "10"}, "items": [
+		{"metadata": {"namespace": "default", "resourceVersion": "9"}, "involvedObject": {"kind": "Pod", "name": "web"},
+			"type": "Normal", "reason": "Pulled", "message": "Container image pulled\n", "lastTimestamp": "2021-06-01T10:00:10Z"},
+		{"metadata": {"namespace": "kube-system", "resourceVersion": "8", "creationTimestamp": "2021-06-01T10:00:00Z"}, "involvedObject": {"kind": "Node", "name": "k3d-foo-server-0"},
+			"type": "Normal", "reason": "Starting", "message": "Starting kubelet.", "eventTime": null, "reportingComponent": "kubelet"}
+	]}`))
+}
+
+func TestK8sEventsStream(t *testing.T) {
+	api := &fakeEventsAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+	core, err := newKubeRESTClient(&rest.Config{Host: server.URL}, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	events := []*k3d.K8sEvent{}
+	handler := func(event *k3d.K8sEvent) {
+		events = append(events, event)
+		if len(events) == 3 {
+			cancel()
+		}
+	}
+
+	if err := k8sEventsStream(ctx, core, k3d.K8sEventsOpts{Watch: true}, handler); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(events))
+	}
+	if events[0].Object != "node/k3d-foo-server-0" || events[0].Source != "kubelet" || events[0].Count != 1 ||
+		!events[0].LastSeen.Equal(time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the oldest event first, falling back to the creation timestamp, got %+v", events[0])
+	}
+	if events[1].Object != "pod/web" || events[1].Message != "Container image pulled" {
+		t.Errorf("unexpected second event %+v", events[1])
+	}
+	if events[2].Type != k3d.K8sEventTypeWarning || events[2].Reason != "BackOff" || events[2].Count != 3 {
+		t.Errorf("expected the watched warning event, got %+v", events[2])
+	}
+	if api.watchResourceVersion != "10" {
+		t.Errorf("expected the watch to start at the resource version of the list, got '%s'", api.watchResourceVersion)
+	}
+}
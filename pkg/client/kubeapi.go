@@ -0,0 +1,88 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// KubeRESTClient returns a client for the given API group version (e.g. "v1" for the core API) of the cluster's Kubernetes API,
+// using the cluster's kubeconfig without touching any kubeconfig file on disk
+func KubeRESTClient(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster, groupVersion string) (*rest.RESTClient, error) {
+	restConfig, err := KubeRESTConfig(ctx, runtime, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newKubeRESTClient(restConfig, groupVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create REST client for cluster '%s': %w", cluster.Name, err)
+	}
+
+	return client, nil
+}
+
+// KubeRESTConfig returns the REST config for the cluster's Kubernetes API, built from the cluster's kubeconfig
+func KubeRESTConfig(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster) (*rest.Config, error) {
+	kubeconfig, err := KubeconfigGet(ctx, runtime, cluster)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubeconfig for cluster '%s': %w", cluster.Name, err)
+	}
+
+	restConfig, err := clientcmd.NewDefaultClientConfig(*kubeconfig, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create REST config for cluster '%s': %w", cluster.Name, err)
+	}
+
+	return restConfig, nil
+}
+
+// newKubeRESTClient returns a REST client for raw JSON requests against the given API group version.
+// The given config is copied, so it can be shared between clients for different group versions.
+func newKubeRESTClient(restConfig *rest.Config, groupVersion string) (*rest.RESTClient, error) {
+	restConfig = rest.CopyConfig(restConfig)
+	gv, err := schema.ParseGroupVersion(groupVersion)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API group version '%s': %w", groupVersion, err)
+	}
+	restConfig.GroupVersion = &gv
+	restConfig.APIPath = "/apis"
+	if gv.Group == "" {
+		restConfig.APIPath = "/api"
+	}
+	// we only deal with raw JSON, so we only need the meta types (e.g. to decode Status responses into proper API errors)
+	scheme := k8sruntime.NewScheme()
+	metav1.AddToGroupVersion(scheme, schema.GroupVersion{Version: "v1"})
+	restConfig.NegotiatedSerializer = serializer.NewCodecFactory(scheme).WithoutConversion()
+
+	return rest.RESTClientFor(restConfig)
+}
@@ -27,12 +27,18 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/user"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
 	"time"
 
 	l "github.com/rancher/k3d/v5/pkg/logger"
 	"github.com/rancher/k3d/v5/pkg/runtimes"
 	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/rancher/k3d/v5/pkg/util"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
@@ -140,6 +146,12 @@ func KubeconfigGet(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.C
 	if chosenServer == nil {
 		chosenServer = serverNodes[0]
 	}
+
+	// k3d runs in a container connected to the cluster network: use the API endpoint within that network
+	if runnerAPIHost, ok := chosenServer.RuntimeLabels[k3d.LabelRunnerAPIHost]; ok && runnerAPIHost != "" {
+		APIHost = runnerAPIHost
+		APIPort = k3d.DefaultAPIPort
+	}
 	// get the kubeconfig from the first server node
 	reader, err := runtime.GetKubeconfig(ctx, chosenServer)
 	if err != nil {
@@ -177,23 +189,99 @@ func KubeconfigGet(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.C
 	kc.Clusters[newClusterName] = kc.Clusters["default"]
 	delete(kc.Clusters, "default")
 
-	// rename context from default to clustername
-	newContextName := fmt.Sprintf("%s-%s", k3d.DefaultObjectNamePrefix, cluster.Name)
+	// rename context from default to the name rendered from the cluster's context template
+	newContextName, err := KubeconfigContextName(cluster)
+	if err != nil {
+		return nil, err
+	}
 	kc.Contexts[newContextName] = kc.Contexts["default"]
 	delete(kc.Contexts, "default")
 
 	// update context with new values for cluster and user
 	kc.Contexts[newContextName].AuthInfo = newAuthInfoName
 	kc.Contexts[newContextName].Cluster = newClusterName
+	kc.Contexts[newContextName].Namespace = cluster.DefaultNamespace
 
 	// set current-context to new context name
 	kc.CurrentContext = newContextName
 
+	// add a context authenticating via OIDC, if the API server is configured for it
+	if oidc := oidcFromServerArgs(chosenServer.Cmd); oidc != nil {
+		kubeconfigAddOIDC(kc, newClusterName, cluster.DefaultNamespace, oidc)
+	}
+
 	l.Log().Tracef("Modified Kubeconfig: %+v", kc)
 
 	return kc, nil
 }
 
+// KubeconfigContextTemplateValues are the values available in a kubeconfig context name template (see k3d.DefaultKubeconfigContextTemplate)
+type KubeconfigContextTemplateValues struct {
+	Prefix  string
+	Cluster string
+	User    string
+}
+
+// KubeconfigContextName renders the name of the cluster's kubeconfig context from its context template,
+// falling back to the default naming scheme <prefix>-<cluster>
+func KubeconfigContextName(cluster *k3d.Cluster) (string, error) {
+	tmpl := cluster.ContextTemplate
+	if tmpl == "" {
+		tmpl = k3d.DefaultKubeconfigContextTemplate
+	}
+	return GenerateContextNameFromTemplate(tmpl, cluster.Name)
+}
+
+// GenerateContextNameFromTemplate renders a kubeconfig context name from a text/template
+func GenerateContextNameFromTemplate(tmpl string, cluster string) (string, error) {
+	t, err := template.New("contextName").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse kubeconfig context template '%s': %w", tmpl, err)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, KubeconfigContextTemplateValues{
+		Prefix:  k3d.DefaultObjectNamePrefix,
+		Cluster: cluster,
+		User:    localUsername(),
+	}); err != nil {
+		return "", fmt.Errorf("failed to render kubeconfig context template '%s': %w", tmpl, err)
+	}
+
+	name := buf.String()
+	if name == "" || strings.ContainsAny(name, " \t\n") {
+		return "", fmt.Errorf("kubeconfig context template '%s' generated an invalid name '%s' (empty or containing whitespace)", tmpl, name)
+	}
+	return name, nil
+}
+
+// localUsername returns the name of the user running k3d (without the domain on Windows), as used in kubeconfig context templates
+func localUsername() string {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		name = u.Username
+	} else if name == "" {
+		name = os.Getenv("USERNAME")
+	}
+	if i := strings.LastIndex(name, "\\"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// kubeconfigClusterContexts returns the names of all contexts in the kubeconfig that use the given cluster entry,
+// as their names may follow a custom context template
+func kubeconfigClusterContexts(kubeconfig *clientcmdapi.Config, clusterEntryName string) []string {
+	contexts := []string{}
+	for name, context := range kubeconfig.Contexts {
+		if context != nil && context.Cluster == clusterEntryName {
+			contexts = append(contexts, name)
+		}
+	}
+	sort.Strings(contexts)
+	return contexts
+}
+
 // KubeconfigWriteToPath takes a kubeconfig and writes it to some path, which can be '-' for os.Stdout
 func KubeconfigWriteToPath(ctx context.Context, kubeconfig *clientcmdapi.Config, path string) error {
 	var output *os.File
@@ -307,6 +395,83 @@ func KubeconfigGetDefaultPath() (string, error) {
 	return defaultKubeConfigLoadingRules.GetDefaultFilename(), nil
 }
 
+// KubeconfigRefreshDefault updates the cluster's entry in the default kubeconfig, e.g. after a restart gave the API server a different port.
+// It doesn't add clusters that aren't in the default kubeconfig yet and reports whether it refreshed anything.
+func KubeconfigRefreshDefault(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster) (bool, error) {
+	kubeconfig, err := KubeconfigGetDefaultFile()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get default kubeconfig file: %w", err)
+	}
+	if _, ok := kubeconfig.Clusters[fmt.Sprintf("%s-%s", k3d.DefaultObjectNamePrefix, cluster.Name)]; !ok {
+		return false, nil
+	}
+	if _, err := KubeconfigGetWrite(ctx, runtime, cluster, "", &WriteKubeConfigOptions{UpdateExisting: true}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// KubeconfigClusterFilePath returns the path of the cluster's dedicated kubeconfig file in the k3d config directory
+// (written e.g. by 'k3d kubeconfig merge' and 'k3d env', removed by 'k3d cluster delete')
+func KubeconfigClusterFilePath(clusterName string) (string, error) {
+	configDir, err := util.GetConfigDirOrCreate()
+	if err != nil {
+		return "", fmt.Errorf("failed to get the k3d config directory: %w", err)
+	}
+	return path.Join(configDir, fmt.Sprintf("kubeconfig-%s.yaml", clusterName)), nil
+}
+
+// KubeconfigRefreshClusterFile rewrites the cluster's dedicated kubeconfig file, if its entries don't match the cluster anymore,
+// e.g. because the cluster was recreated with the same name (new CA and credentials) or got a different API port.
+// It doesn't create the file if there is none and reports whether it refreshed it.
+func KubeconfigRefreshClusterFile(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster) (bool, error) {
+	output, err := KubeconfigClusterFilePath(cluster.Name)
+	if err != nil {
+		return false, err
+	}
+	stored, err := clientcmd.LoadFromFile(output)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to load kubeconfig file '%s': %w", output, err)
+	}
+
+	current, err := KubeconfigGet(ctx, runtime, cluster)
+	if err != nil {
+		return false, fmt.Errorf("failed to get kubeconfig for cluster '%s': %w", cluster.Name, err)
+	}
+	if !kubeconfigStale(stored, current, cluster) {
+		return false, nil
+	}
+	l.Log().Debugf("Kubeconfig file '%s' doesn't match cluster '%s' anymore", output, cluster.Name)
+	if err := KubeconfigWriteToPath(ctx, current, output); err != nil {
+		return false, fmt.Errorf("failed to refresh kubeconfig file '%s': %w", output, err)
+	}
+	return true, nil
+}
+
+// kubeconfigStale checks whether the cluster's entries in a stored kubeconfig differ from the current ones (API server URL,
+// CA and credentials), so it can't be used to access the cluster anymore
+func kubeconfigStale(stored, current *clientcmdapi.Config, cluster *k3d.Cluster) bool {
+	clusterName := fmt.Sprintf("%s-%s", k3d.DefaultObjectNamePrefix, cluster.Name)
+	authInfoName := fmt.Sprintf("admin@%s-%s", k3d.DefaultObjectNamePrefix, cluster.Name)
+
+	storedCluster, currentCluster := stored.Clusters[clusterName], current.Clusters[clusterName]
+	storedAuth, currentAuth := stored.AuthInfos[authInfoName], current.AuthInfos[authInfoName]
+	if storedCluster == nil || storedAuth == nil || currentCluster == nil || currentAuth == nil {
+		return true
+	}
+	return storedCluster.Server != currentCluster.Server ||
+		!bytes.Equal(storedCluster.CertificateAuthorityData, currentCluster.CertificateAuthorityData) ||
+		!bytes.Equal(storedAuth.ClientCertificateData, currentAuth.ClientCertificateData) ||
+		!bytes.Equal(storedAuth.ClientKeyData, currentAuth.ClientKeyData) ||
+		storedAuth.Token != currentAuth.Token
+}
+
 // KubeconfigRemoveClusterFromDefaultConfig removes a cluster's details from the default kubeconfig
 func KubeconfigRemoveClusterFromDefaultConfig(ctx context.Context, cluster *k3d.Cluster) error {
 	defaultKubeConfigPath, err := KubeconfigGetDefaultPath()
@@ -324,24 +489,29 @@ func KubeconfigRemoveClusterFromDefaultConfig(ctx context.Context, cluster *k3d.
 // KubeconfigRemoveCluster removes a cluster's details from a given kubeconfig
 func KubeconfigRemoveCluster(ctx context.Context, cluster *k3d.Cluster, kubeconfig *clientcmdapi.Config) *clientcmdapi.Config {
 	clusterName := fmt.Sprintf("%s-%s", k3d.DefaultObjectNamePrefix, cluster.Name)
-	contextName := fmt.Sprintf("%s-%s", k3d.DefaultObjectNamePrefix, cluster.Name)
 	authInfoName := fmt.Sprintf("admin@%s-%s", k3d.DefaultObjectNamePrefix, cluster.Name)
 
+	// the context names may follow a custom template, so remove all contexts using the cluster (and the default one)
+	removedCurrentContext := kubeconfig.CurrentContext == fmt.Sprintf("%s-%s", k3d.DefaultObjectNamePrefix, cluster.Name)
+	delete(kubeconfig.Contexts, fmt.Sprintf("%s-%s", k3d.DefaultObjectNamePrefix, cluster.Name))
+	for _, contextName := range kubeconfigClusterContexts(kubeconfig, clusterName) {
+		if kubeconfig.CurrentContext == contextName {
+			removedCurrentContext = true
+		}
+		delete(kubeconfig.Contexts, contextName)
+	}
+
 	// delete elements from kubeconfig if they're present
-	delete(kubeconfig.Contexts, contextName)
 	delete(kubeconfig.Clusters, clusterName)
 	delete(kubeconfig.AuthInfos, authInfoName)
 
-	// set current-context to any other context, if it was set to the given cluster before
-	if kubeconfig.CurrentContext == contextName {
+	// set current-context to any other context, if it was set to the given cluster before (or unset it, if there's none)
+	if removedCurrentContext {
+		kubeconfig.CurrentContext = ""
 		for k := range kubeconfig.Contexts {
 			kubeconfig.CurrentContext = k
 			break
 		}
-		// if current-context didn't change, unset it
-		if kubeconfig.CurrentContext == contextName {
-			kubeconfig.CurrentContext = ""
-		}
 	}
 	return kubeconfig
 }
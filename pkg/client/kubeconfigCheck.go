@@ -0,0 +1,124 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// kubeconfigCheckTimeout is the maximum time to wait for the Kubernetes API when checking a recorded kubeconfig
+var kubeconfigCheckTimeout = 3 * time.Second
+
+// KubeconfigCheck checks whether the kubeconfig recorded for the cluster (its entry in the default kubeconfig or else its kubeconfig file
+// in the k3d config directory) still authenticates against the cluster, using a quick /version request.
+// Entries get stale e.g. when a cluster is recreated with the same name (new CA and credentials) or gets a different API port.
+func KubeconfigCheck(ctx context.Context, cluster *k3d.Cluster) *k3d.KubeconfigCheck {
+	path, kubeconfig, err := recordedKubeconfig(cluster.Name)
+	if err != nil {
+		return &k3d.KubeconfigCheck{Validity: k3d.KubeconfigUnchecked, Path: path, Reason: err.Error()}
+	}
+	if kubeconfig == nil {
+		return &k3d.KubeconfigCheck{Validity: k3d.KubeconfigMissing}
+	}
+
+	serverUp := false
+	for _, node := range cluster.Nodes {
+		if node.Role == k3d.ServerRole && node.State.Running && node.State.Status != nodeStatusPaused {
+			serverUp = true
+		}
+	}
+	if !serverUp {
+		return &k3d.KubeconfigCheck{Validity: k3d.KubeconfigUnchecked, Path: path, Reason: "cluster not running"}
+	}
+
+	check := kubeconfigAuthenticates(ctx, kubeconfig, cluster.Name)
+	check.Path = path
+	return check
+}
+
+// recordedKubeconfig returns the first kubeconfig with an entry for the cluster: the default one or the cluster's kubeconfig file
+func recordedKubeconfig(clusterName string) (string, *clientcmdapi.Config, error) {
+	paths := []string{}
+	if path, err := KubeconfigGetDefaultPath(); err == nil {
+		paths = append(paths, path)
+	} else {
+		l.Log().Debugf("Not checking the default kubeconfig: %v", err)
+	}
+	path, err := KubeconfigClusterFilePath(clusterName)
+	if err != nil {
+		return "", nil, err
+	}
+	paths = append(paths, path)
+
+	for _, path := range paths {
+		kubeconfig, err := clientcmd.LoadFromFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return path, nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+		}
+		if _, ok := kubeconfig.Clusters[fmt.Sprintf("%s-%s", k3d.DefaultObjectNamePrefix, clusterName)]; ok {
+			return path, kubeconfig, nil
+		}
+	}
+	return "", nil, nil
+}
+
+// kubeconfigAuthenticates requests the API server version using the cluster's context in the given kubeconfig
+func kubeconfigAuthenticates(ctx context.Context, kubeconfig *clientcmdapi.Config, clusterName string) *k3d.KubeconfigCheck {
+	// the context name may follow a custom template, so look for the admin context using the cluster entry
+	clusterEntryName := fmt.Sprintf("%s-%s", k3d.DefaultObjectNamePrefix, clusterName)
+	contextName := clusterEntryName
+	for _, name := range kubeconfigClusterContexts(kubeconfig, clusterEntryName) {
+		if kubeconfig.Contexts[name].AuthInfo == fmt.Sprintf("admin@%s", clusterEntryName) {
+			contextName = name
+			break
+		}
+	}
+	restConfig, err := clientcmd.NewDefaultClientConfig(*kubeconfig, &clientcmd.ConfigOverrides{CurrentContext: contextName}).ClientConfig()
+	if err != nil {
+		return &k3d.KubeconfigCheck{Validity: k3d.KubeconfigStale, Reason: fmt.Sprintf("invalid entries: %v", err)}
+	}
+	restConfig.Timeout = kubeconfigCheckTimeout
+
+	client, err := newKubeRESTClient(restConfig, "v1")
+	if err != nil {
+		return &k3d.KubeconfigCheck{Validity: k3d.KubeconfigStale, Reason: fmt.Sprintf("invalid entries: %v", err)}
+	}
+	if _, err := client.Get().AbsPath("/version").Do(ctx).Raw(); err != nil {
+		if apierrors.IsUnauthorized(err) || apierrors.IsForbidden(err) {
+			return &k3d.KubeconfigCheck{Validity: k3d.KubeconfigStale, Reason: "credentials rejected"}
+		}
+		return &k3d.KubeconfigCheck{Validity: k3d.KubeconfigStale, Reason: err.Error()}
+	}
+	return &k3d.KubeconfigCheck{Validity: k3d.KubeconfigValid}
+}
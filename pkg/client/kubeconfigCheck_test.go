@@ -0,0 +1,98 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestKubeconfigCheck(t *testing.T) {
+	home := useTempConfigDir(t)
+	t.Setenv("KUBECONFIG", filepath.Join(home, "kubeconfig"))
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer current" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"gitVersion": "v1.21.5+k3s2"}`)
+	}))
+	defer server.Close()
+
+	writeKubeconfig := func(path string, token string) {
+		content := fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: k3d-test
+  cluster:
+    server: %s
+    insecure-skip-tls-verify: true
+users:
+- name: admin@k3d-test
+  user:
+    token: %s
+contexts:
+- name: k3d-test
+  context:
+    cluster: k3d-test
+    user: admin@k3d-test
+`, server.URL, token)
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server0 := newFakeNode("test", "k3d-test-server-0", k3d.ServerRole, true)
+	cluster := &k3d.Cluster{Name: "test", Nodes: []*k3d.Node{server0}}
+
+	if check := KubeconfigCheck(context.Background(), cluster); check.Validity != k3d.KubeconfigMissing {
+		t.Errorf("expected a missing kubeconfig, got %+v", check)
+	}
+
+	// the cluster's kubeconfig file is checked if the default kubeconfig has no entry for the cluster
+	clusterFile, err := KubeconfigClusterFilePath("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeKubeconfig(clusterFile, "current")
+	if check := KubeconfigCheck(context.Background(), cluster); check.Validity != k3d.KubeconfigValid || check.Path != clusterFile {
+		t.Errorf("expected the kubeconfig file to be valid, got %+v", check)
+	}
+
+	writeKubeconfig(filepath.Join(home, "kubeconfig"), "old")
+	if check := KubeconfigCheck(context.Background(), cluster); check.Validity != k3d.KubeconfigStale || check.Reason != "credentials rejected" {
+		t.Errorf("expected the default kubeconfig to be stale, got %+v", check)
+	}
+
+	server0.State.Running = false
+	if check := KubeconfigCheck(context.Background(), cluster); check.Validity != k3d.KubeconfigUnchecked {
+		t.Errorf("expected the kubeconfig of a stopped cluster not to be checked, got %+v", check)
+	}
+}
@@ -0,0 +1,141 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// kubeconfigWatchDebounce is the time to wait for more changes to the kubeconfig files before notifying about them
+var kubeconfigWatchDebounce = 500 * time.Millisecond
+
+// KubeconfigLocate returns where the kubeconfig of the cluster can be found: its dedicated kubeconfig file (written if it doesn't exist yet,
+// refreshed if it's stale) and the default kubeconfig, if that has contexts for the cluster
+func KubeconfigLocate(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster) (*k3d.KubeconfigLocation, error) {
+	path, err := KubeconfigClusterFilePath(cluster.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if _, err := KubeconfigGetWrite(ctx, runtime, cluster, path, &WriteKubeConfigOptions{UpdateExisting: true, OverwriteExisting: true, UpdateCurrentContext: true}); err != nil {
+			return nil, fmt.Errorf("failed to write kubeconfig file of cluster '%s': %w", cluster.Name, err)
+		}
+	} else if _, err := KubeconfigRefreshClusterFile(ctx, runtime, cluster); err != nil {
+		return nil, err
+	}
+
+	kubeconfig, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig file '%s': %w", path, err)
+	}
+	clusterEntryName := fmt.Sprintf("%s-%s", k3d.DefaultObjectNamePrefix, cluster.Name)
+	location := &k3d.KubeconfigLocation{
+		Path:     path,
+		Contexts: kubeconfigClusterContexts(kubeconfig, clusterEntryName),
+	}
+
+	defaultPath, err := KubeconfigGetDefaultPath()
+	if err != nil {
+		l.Log().Debugf("Not looking for cluster '%s' in the default kubeconfig: %v", cluster.Name, err)
+		return location, nil
+	}
+	defaultKubeconfig, err := clientcmd.LoadFromFile(defaultPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			l.Log().Warnf("Failed to load default kubeconfig '%s': %v", defaultPath, err)
+		}
+		return location, nil
+	}
+	if contexts := kubeconfigClusterContexts(defaultKubeconfig, clusterEntryName); len(contexts) > 0 {
+		location.DefaultPath = defaultPath
+		location.DefaultContexts = contexts
+	}
+
+	return location, nil
+}
+
+// KubeconfigWatch calls onChange whenever the dedicated kubeconfig files of clusters (in the k3d config directory)
+// or the default kubeconfig change, until the context is cancelled. Bursts of changes result in a single call.
+func KubeconfigWatch(ctx context.Context, onChange func()) error {
+	configFile, err := KubeconfigClusterFilePath("*")
+	if err != nil {
+		return err
+	}
+	patterns := []string{configFile}
+	if defaultPath, err := KubeconfigGetDefaultPath(); err == nil {
+		patterns = append(patterns, defaultPath)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	// watch the directories, as the files may not exist yet and are often replaced instead of written to
+	for _, pattern := range patterns {
+		dir := filepath.Dir(pattern)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create directory '%s': %w", dir, err)
+		}
+		if err := watcher.Add(dir); err != nil {
+			return fmt.Errorf("failed to watch directory '%s': %w", dir, err)
+		}
+	}
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-watcher.Errors:
+			l.Log().Warnf("File watcher error: %v", err)
+		case event := <-watcher.Events:
+			if kubeconfigWatched(patterns, event.Name) {
+				debounce = time.After(kubeconfigWatchDebounce)
+			}
+		case <-debounce:
+			onChange()
+		}
+	}
+}
+
+// kubeconfigWatched returns true if the file matches one of the watched patterns (and isn't e.g. a lock file next to them)
+func kubeconfigWatched(patterns []string, file string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, file); ok {
+			return true
+		}
+	}
+	return false
+}
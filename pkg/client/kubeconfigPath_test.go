@@ -0,0 +1,75 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestKubeconfigWatch(t *testing.T) {
+	kubeconfigWatchDebounce = 10 * time.Millisecond
+
+	home := useTempConfigDir(t)
+	defaultKubeconfig := filepath.Join(home, ".kube", "config")
+	t.Setenv("KUBECONFIG", defaultKubeconfig)
+	clusterFile, err := KubeconfigClusterFilePath("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan struct{}, 10)
+	done := make(chan error)
+	go func() { done <- KubeconfigWatch(ctx, func() { changes <- struct{}{} }) }()
+	time.Sleep(100 * time.Millisecond) // let the watcher start
+
+	expectChange := func(file string, expected bool) {
+		t.Helper()
+		if err := ioutil.WriteFile(file, []byte("apiVersion: v1\nkind: Config\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-changes:
+			if !expected {
+				t.Errorf("expected no notification for '%s'", file)
+			}
+		case <-time.After(500 * time.Millisecond):
+			if expected {
+				t.Errorf("expected a notification for '%s'", file)
+			}
+		}
+	}
+
+	expectChange(clusterFile, true)
+	expectChange(defaultKubeconfig, true)
+	expectChange(filepath.Join(filepath.Dir(clusterFile), "unrelated.yaml"), false)
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,237 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// serviceAccountTokenPollInterval is the interval in which the token secret is checked while waiting for it to be populated
+var serviceAccountTokenPollInterval = 1 * time.Second
+
+// KubeconfigCreateServiceAccount creates a ServiceAccount (and its namespace, if required),
+// binds the given ClusterRole to it within the namespace and returns a kubeconfig authenticating as that ServiceAccount
+func KubeconfigCreateServiceAccount(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster, opts k3d.ServiceAccountKubeconfigOpts) (*clientcmdapi.Config, error) {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	adminKubeconfig, err := KubeconfigGet(ctx, runtime, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	core, err := KubeRESTClient(ctx, runtime, cluster, "v1")
+	if err != nil {
+		return nil, err
+	}
+	rbac, err := KubeRESTClient(ctx, runtime, cluster, "rbac.authorization.k8s.io/v1")
+	if err != nil {
+		return nil, err
+	}
+
+	secretName := fmt.Sprintf("%s-token", opts.ServiceAccount)
+	bindingResource, bindingNamespace, binding := serviceAccountBinding(opts)
+
+	type object struct {
+		client    *rest.RESTClient
+		resource  string
+		namespace string
+		obj       map[string]interface{}
+	}
+	objects := []object{
+		{core, "namespaces", "", map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Namespace",
+			"metadata":   map[string]interface{}{"name": opts.Namespace},
+		}},
+		{core, "serviceaccounts", opts.Namespace, map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ServiceAccount",
+			"metadata":   map[string]interface{}{"name": opts.ServiceAccount, "namespace": opts.Namespace},
+		}},
+		{rbac, bindingResource, bindingNamespace, binding},
+	}
+	if opts.TokenDuration == 0 {
+		// token secrets are not created automatically anymore since Kubernetes 1.24, so we request one explicitly
+		objects = append(objects, object{core, "secrets", opts.Namespace, map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"type":       "kubernetes.io/service-account-token",
+			"metadata": map[string]interface{}{
+				"name":        secretName,
+				"namespace":   opts.Namespace,
+				"annotations": map[string]interface{}{"kubernetes.io/service-account.name": opts.ServiceAccount},
+			},
+		}})
+	}
+
+	for _, o := range objects {
+		body, err := json.Marshal(o.obj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s: %w", o.resource, err)
+		}
+		req := o.client.Post().Resource(o.resource).Body(body)
+		if o.namespace != "" {
+			req = req.Namespace(o.namespace)
+		}
+		if err := req.Do(ctx).Error(); err != nil {
+			if !apierrors.IsAlreadyExists(err) {
+				return nil, fmt.Errorf("failed to create %s: %w", o.resource, err)
+			}
+			l.Log().Debugf("%s already exists, reusing it", o.resource)
+		}
+	}
+
+	var token string
+	if opts.TokenDuration > 0 {
+		token, err = serviceAccountRequestToken(ctx, core, opts.Namespace, opts.ServiceAccount, opts.TokenDuration)
+	} else {
+		token, err = serviceAccountToken(ctx, core, opts.Namespace, secretName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// re-use the cluster entry (server & CA) of the admin kubeconfig, but authenticate as the service account
+	contextName := fmt.Sprintf("%s-%s-%s@%s", k3d.DefaultObjectNamePrefix, cluster.Name, opts.ServiceAccount, opts.Namespace)
+	adminContext, ok := adminKubeconfig.Contexts[adminKubeconfig.CurrentContext]
+	if !ok {
+		return nil, fmt.Errorf("admin kubeconfig of cluster '%s' has no current context", cluster.Name)
+	}
+
+	kubeconfig := clientcmdapi.NewConfig()
+	kubeconfig.Clusters[adminContext.Cluster] = adminKubeconfig.Clusters[adminContext.Cluster]
+	kubeconfig.AuthInfos[contextName] = &clientcmdapi.AuthInfo{Token: token}
+	kubeconfig.Contexts[contextName] = &clientcmdapi.Context{
+		Cluster:   adminContext.Cluster,
+		AuthInfo:  contextName,
+		Namespace: opts.Namespace,
+	}
+	kubeconfig.CurrentContext = contextName
+
+	return kubeconfig, nil
+}
+
+// serviceAccountBinding returns the resource, namespace and manifest of the binding of the ClusterRole to the service account:
+// a RoleBinding within its namespace or, if requested, a ClusterRoleBinding (named after the namespace as well, as it isn't namespaced)
+func serviceAccountBinding(opts k3d.ServiceAccountKubeconfigOpts) (string, string, map[string]interface{}) {
+	resource, namespace, kind := "rolebindings", opts.Namespace, "RoleBinding"
+	metadata := map[string]interface{}{"name": fmt.Sprintf("%s-%s", opts.ServiceAccount, opts.ClusterRole), "namespace": opts.Namespace}
+	if opts.ClusterWide {
+		resource, namespace, kind = "clusterrolebindings", "", "ClusterRoleBinding"
+		metadata = map[string]interface{}{"name": fmt.Sprintf("%s-%s-%s", opts.Namespace, opts.ServiceAccount, opts.ClusterRole)}
+	}
+
+	return resource, namespace, map[string]interface{}{
+		"apiVersion": "rbac.authorization.k8s.io/v1",
+		"kind":       kind,
+		"metadata":   metadata,
+		"roleRef": map[string]interface{}{
+			"apiGroup": "rbac.authorization.k8s.io",
+			"kind":     "ClusterRole",
+			"name":     opts.ClusterRole,
+		},
+		"subjects": []interface{}{map[string]interface{}{
+			"kind":      "ServiceAccount",
+			"name":      opts.ServiceAccount,
+			"namespace": opts.Namespace,
+		}},
+	}
+}
+
+// serviceAccountRequestToken requests a token for the service account expiring after the given duration (TokenRequest API).
+// It isn't stored in the cluster, so it can only be revoked by deleting the service account.
+func serviceAccountRequestToken(ctx context.Context, client *rest.RESTClient, namespace string, serviceAccount string, duration time.Duration) (string, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"apiVersion": "authentication.k8s.io/v1",
+		"kind":       "TokenRequest",
+		"spec":       map[string]interface{}{"expirationSeconds": int64(duration.Seconds())},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal token request: %w", err)
+	}
+
+	raw, err := client.Post().Namespace(namespace).Resource("serviceaccounts").Name(serviceAccount).SubResource("token").Body(body).Do(ctx).Raw()
+	if err != nil {
+		return "", fmt.Errorf("failed to request token for service account '%s': %w", serviceAccount, err)
+	}
+
+	var tokenRequest struct {
+		Status struct {
+			Token               string `json:"token"`
+			ExpirationTimestamp string `json:"expirationTimestamp"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal(raw, &tokenRequest); err != nil {
+		return "", fmt.Errorf("failed to unmarshal token request of service account '%s': %w", serviceAccount, err)
+	}
+	if tokenRequest.Status.Token == "" {
+		return "", fmt.Errorf("got no token for service account '%s'", serviceAccount)
+	}
+	l.Log().Infof("Token of service account '%s' expires at %s", serviceAccount, tokenRequest.Status.ExpirationTimestamp)
+	return tokenRequest.Status.Token, nil
+}
+
+// serviceAccountToken waits for the token controller to populate the given service account token secret and returns the token
+func serviceAccountToken(ctx context.Context, client *rest.RESTClient, namespace string, secretName string) (string, error) {
+	for {
+		raw, err := client.Get().Namespace(namespace).Resource("secrets").Name(secretName).Do(ctx).Raw()
+		if err != nil {
+			return "", fmt.Errorf("failed to get token secret '%s': %w", secretName, err)
+		}
+
+		var secret struct {
+			Data map[string]string `json:"data"`
+		}
+		if err := json.Unmarshal(raw, &secret); err != nil {
+			return "", fmt.Errorf("failed to unmarshal token secret '%s': %w", secretName, err)
+		}
+
+		if encoded, ok := secret.Data["token"]; ok && encoded != "" {
+			token, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				return "", fmt.Errorf("failed to decode token of secret '%s': %w", secretName, err)
+			}
+			return string(token), nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("timed out waiting for the token of secret '%s': %w", secretName, ctx.Err())
+		case <-time.After(serviceAccountTokenPollInterval):
+		}
+	}
+}
@@ -0,0 +1,51 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"testing"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestServiceAccountBinding(t *testing.T) {
+	opts := k3d.ServiceAccountKubeconfigOpts{Namespace: "ci", ServiceAccount: "deployer", ClusterRole: "edit"}
+
+	resource, namespace, binding := serviceAccountBinding(opts)
+	if resource != "rolebindings" || namespace != "ci" || binding["kind"] != "RoleBinding" {
+		t.Errorf("expected a RoleBinding in namespace 'ci', got %s in '%s': %+v", resource, namespace, binding)
+	}
+
+	opts.ClusterWide = true
+	resource, namespace, binding = serviceAccountBinding(opts)
+	if resource != "clusterrolebindings" || namespace != "" || binding["kind"] != "ClusterRoleBinding" {
+		t.Errorf("expected a ClusterRoleBinding, got %s in '%s': %+v", resource, namespace, binding)
+	}
+	metadata := binding["metadata"].(map[string]interface{})
+	if _, ok := metadata["namespace"]; ok || metadata["name"] != "ci-deployer-edit" {
+		t.Errorf("expected a non-namespaced binding named 'ci-deployer-edit', got %+v", metadata)
+	}
+	subject := binding["subjects"].([]interface{})[0].(map[string]interface{})
+	if subject["namespace"] != "ci" || subject["name"] != "deployer" {
+		t.Errorf("expected the service account 'ci/deployer' as subject, got %+v", subject)
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"testing"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestKubeconfigStale(t *testing.T) {
+	cluster := &k3d.Cluster{Name: "test"}
+	kubeconfig := func(server string, ca string, cert string) *clientcmdapi.Config {
+		kc := clientcmdapi.NewConfig()
+		kc.Clusters["k3d-test"] = &clientcmdapi.Cluster{Server: server, CertificateAuthorityData: []byte(ca)}
+		kc.AuthInfos["admin@k3d-test"] = &clientcmdapi.AuthInfo{ClientCertificateData: []byte(cert), ClientKeyData: []byte("key")}
+		return kc
+	}
+	current := kubeconfig("https://0.0.0.0:6443", "ca", "cert")
+
+	tests := []struct {
+		name   string
+		stored *clientcmdapi.Config
+		stale  bool
+	}{
+		{"same", kubeconfig("https://0.0.0.0:6443", "ca", "cert"), false},
+		{"recreated cluster", kubeconfig("https://0.0.0.0:6443", "old-ca", "old-cert"), true},
+		{"new client certificate", kubeconfig("https://0.0.0.0:6443", "ca", "old-cert"), true},
+		{"new API port", kubeconfig("https://0.0.0.0:6550", "ca", "cert"), true},
+		{"other cluster", clientcmdapi.NewConfig(), true},
+	}
+	for _, tt := range tests {
+		if stale := kubeconfigStale(tt.stored, current, cluster); stale != tt.stale {
+			t.Errorf("%s: expected stale=%t, got %t", tt.name, tt.stale, stale)
+		}
+	}
+}
+
+func TestGenerateContextNameFromTemplate(t *testing.T) {
+	tests := []struct {
+		tmpl    string
+		want    string
+		wantErr bool
+	}{
+		{k3d.DefaultKubeconfigContextTemplate, "k3d-test", false},
+		{"{{.Cluster}}-{{.User}}", "test-" + localUsername(), false},
+		{"{{.Cluster}}", "test", false},
+		{"{{.Team}}-{{.Cluster}}", "", true},
+		{"{{.Cluster", "", true},
+		{"{{.Cluster}} dev", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		name, err := GenerateContextNameFromTemplate(tt.tmpl, "test")
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected an error, got name '%s'", tt.tmpl, name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.tmpl, err)
+		} else if name != tt.want {
+			t.Errorf("%q: expected '%s', got '%s'", tt.tmpl, tt.want, name)
+		}
+	}
+}
+
+func TestKubeconfigRemoveClusterCustomContext(t *testing.T) {
+	kc := clientcmdapi.NewConfig()
+	kc.Clusters["k3d-test"] = &clientcmdapi.Cluster{Server: "https://0.0.0.0:6443"}
+	kc.AuthInfos["admin@k3d-test"] = &clientcmdapi.AuthInfo{Token: "token"}
+	kc.Contexts["test-alice"] = &clientcmdapi.Context{Cluster: "k3d-test", AuthInfo: "admin@k3d-test"}
+	kc.Clusters["other"] = &clientcmdapi.Cluster{Server: "https://other:6443"}
+	kc.Contexts["other"] = &clientcmdapi.Context{Cluster: "other"}
+	kc.CurrentContext = "test-alice"
+
+	kc = KubeconfigRemoveCluster(context.Background(), &k3d.Cluster{Name: "test", ContextTemplate: "{{.Cluster}}-{{.User}}"}, kc)
+
+	if _, ok := kc.Contexts["test-alice"]; ok {
+		t.Errorf("expected context 'test-alice' to be removed")
+	}
+	if _, ok := kc.Clusters["k3d-test"]; ok {
+		t.Errorf("expected cluster entry 'k3d-test' to be removed")
+	}
+	if _, ok := kc.Contexts["other"]; !ok {
+		t.Errorf("expected context 'other' to be kept")
+	}
+	if kc.CurrentContext != "other" {
+		t.Errorf("expected current-context 'other', got '%s'", kc.CurrentContext)
+	}
+}
@@ -0,0 +1,115 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// NodeLogs writes the logs of the given nodes to out. With multiple nodes, their logs are read concurrently (which keeps them
+// roughly in order when following them) and every line is prefixed with the name of its node.
+// Nodes whose logs can't be read (e.g. when following stopped nodes) are skipped with a warning, unless that's the case for all of them.
+func NodeLogs(ctx context.Context, runtime runtimes.Runtime, nodes []*k3d.Node, opts k3d.NodeLogsOpts, out io.Writer) error {
+	if len(nodes) == 0 {
+		return fmt.Errorf("no nodes to read the logs from")
+	}
+
+	width := 0
+	for _, node := range nodes {
+		if len(node.Name) > width {
+			width = len(node.Name)
+		}
+	}
+
+	var mu sync.Mutex // serializes the lines of all nodes
+	var wg sync.WaitGroup
+	errs := make([]error, len(nodes))
+	for i, node := range nodes {
+		prefix := ""
+		if len(nodes) > 1 {
+			prefix = fmt.Sprintf("%-*s | ", width, node.Name)
+		}
+		wg.Add(1)
+		go func(i int, node *k3d.Node, prefix string) {
+			defer wg.Done()
+			errs[i] = nodeLogs(ctx, runtime, node, opts, prefix, out, &mu)
+		}(i, node, prefix)
+	}
+	wg.Wait()
+
+	failed := []string{}
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, nodes[i].Name)
+			if len(nodes) > 1 {
+				l.Log().Warnln(err)
+			}
+		}
+	}
+	if len(failed) == len(nodes) {
+		if len(nodes) == 1 {
+			return errs[0]
+		}
+		return fmt.Errorf("failed to read the logs of all nodes (%s)", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// nodeLogs copies the logs of a single node line by line to out, prefixing each line
+func nodeLogs(ctx context.Context, runtime runtimes.Runtime, node *k3d.Node, opts k3d.NodeLogsOpts, prefix string, out io.Writer, mu *sync.Mutex) error {
+	var logs io.ReadCloser
+	var err error
+	if opts.Follow {
+		logs, err = runtime.FollowNodeLogs(ctx, node, opts.Since)
+	} else {
+		logs, err = runtime.GetNodeLogs(ctx, node, opts.Since)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get the logs of node '%s': %w", node.Name, err)
+	}
+	defer logs.Close()
+
+	scanner := bufio.NewScanner(logs)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		mu.Lock()
+		_, err := fmt.Fprintf(out, "%s%s\n", prefix, scanner.Text())
+		mu.Unlock()
+		if err != nil {
+			return fmt.Errorf("failed to write the logs of node '%s': %w", node.Name, err)
+		}
+	}
+	// the stream breaks off when following the logs is interrupted, which is no error
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("failed to read the logs of node '%s': %w", node.Name, err)
+	}
+	return nil
+}
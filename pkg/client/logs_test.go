@@ -0,0 +1,76 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestNodeLogs(t *testing.T) {
+	server := newFakeNode("test", "k3d-test-server-0", k3d.ServerRole, true)
+	lb := newFakeNode("test", "k3d-test-serverlb", k3d.LoadBalancerRole, true)
+	runtime := &fakeRuntime{logStreams: map[string][]string{
+		server.Name: {"starting k3s\nk3s is up and running\n"},
+		lb.Name:     {"nginx started\n"},
+	}}
+
+	var out bytes.Buffer
+	if err := NodeLogs(context.Background(), runtime, []*k3d.Node{server}, k3d.NodeLogsOpts{}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "starting k3s\nk3s is up and running\n" {
+		t.Errorf("expected the logs of a single node without prefix, got %q", out.String())
+	}
+
+	// multiple nodes get their (aligned) names as prefix
+	runtime.logStreams = map[string][]string{server.Name: {"starting k3s\n"}, lb.Name: {"nginx started\n"}}
+	out.Reset()
+	if err := NodeLogs(context.Background(), runtime, []*k3d.Node{server, lb}, k3d.NodeLogsOpts{Follow: true}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	sort.Strings(lines)
+	expected := []string{"k3d-test-server-0 | starting k3s", "k3d-test-serverlb | nginx started"}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+	if follows := runtime.callsOf("FollowNodeLogs"); len(follows) != 2 {
+		t.Errorf("expected the logs of both nodes to be followed, got %v", follows)
+	}
+
+	// failing nodes are skipped, unless all of them fail
+	runtime.failOn = map[string]error{"GetNodeLogs:" + lb.Name: errors.New("no such container")}
+	out.Reset()
+	if err := NodeLogs(context.Background(), runtime, []*k3d.Node{server, lb}, k3d.NodeLogsOpts{}, &out); err != nil {
+		t.Errorf("expected the logs of the remaining node, got error: %v", err)
+	}
+	if err := NodeLogs(context.Background(), runtime, []*k3d.Node{lb}, k3d.NodeLogsOpts{}, &out); err == nil || !strings.Contains(err.Error(), "no such container") {
+		t.Errorf("expected the error of the only node, got %v", err)
+	}
+}
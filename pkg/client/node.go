@@ -23,18 +23,21 @@ THE SOFTWARE.
 package client
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"os"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
 	copystruct "github.com/mitchellh/copystructure"
-	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
 
 	"github.com/docker/go-connections/nat"
@@ -42,6 +45,7 @@ import (
 	"github.com/imdario/mergo"
 	"github.com/rancher/k3d/v5/pkg/actions"
 	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/metrics"
 	"github.com/rancher/k3d/v5/pkg/runtimes"
 	"github.com/rancher/k3d/v5/pkg/runtimes/docker"
 	runtimeErrors "github.com/rancher/k3d/v5/pkg/runtimes/errors"
@@ -59,6 +63,10 @@ func NodeAddToCluster(ctx context.Context, runtime runtimes.Runtime, node *k3d.N
 		return fmt.Errorf("Failed to find specified cluster '%s': %w", targetClusterName, err)
 	}
 
+	if node.Role == k3d.ServerRole && !clusterHasSharedDatastore(cluster) {
+		return fmt.Errorf("cannot add server node to cluster '%s': its server uses the default SQLite datastore, which can't be shared with other servers (create the cluster with '--servers 2' or more for embedded etcd, or with an external datastore)", cluster.Name)
+	}
+
 	// networks: ensure that cluster network is on index 0
 	networks := []string{cluster.Network.Name}
 	if node.Networks != nil {
@@ -142,24 +150,20 @@ func NodeAddToCluster(ctx context.Context, runtime runtimes.Runtime, node *k3d.N
 	l.Log().Tracef("Sanitized Source Node: %+v\nNew Node: %+v", srcNode, node)
 
 	// fetch registry config
-	registryConfigBytes := []byte{}
-	registryConfigReader, err := runtime.ReadFromNode(ctx, k3d.DefaultRegistriesFilePath, srcNode)
+	registryConfigBytes, err := nodeReadFile(ctx, runtime, srcNode, k3d.DefaultRegistriesFilePath)
 	if err != nil {
-		if !errors.Is(err, runtimeErrors.ErrRuntimeFileNotFound) {
-			l.Log().Warnf("Failed to read registry config from node %s: %+v", node.Name, err)
-		}
-	} else {
-		defer registryConfigReader.Close()
+		l.Log().Warnf("Failed to read registry config from node %s: %+v", srcNode.Name, err)
+	}
 
-		var err error
-		registryConfigBytes, err = ioutil.ReadAll(registryConfigReader)
-		if err != nil {
-			l.Log().Warnf("Failed to read registry config from node %s: %+v", node.Name, err)
-		}
-		registryConfigReader.Close()
-		registryConfigBytes = bytes.Trim(registryConfigBytes[512:], "\x00") // trim control characters, etc.
+	// fetch host resolver config (referenced by the kubelet's --resolv-conf flag, if the cluster was created with --host-dns)
+	hostResolvConfBytes, err := nodeReadFile(ctx, runtime, srcNode, k3d.DefaultHostResolvConfPath)
+	if err != nil {
+		l.Log().Warnf("Failed to read host resolver config from node %s: %+v", srcNode.Name, err)
 	}
 
+	// clusters created with --resolve-digest pin the image of their nodes
+	pinnedImage := srcNode.RuntimeLabels[k3d.LabelImagePinned]
+
 	// merge node config of new node into existing node config
 	if err := mergo.MergeWithOverwrite(srcNode, *node); err != nil {
 		return fmt.Errorf("failed to merge new node config into existing node config: %w", err)
@@ -167,6 +171,14 @@ func NodeAddToCluster(ctx context.Context, runtime runtimes.Runtime, node *k3d.N
 
 	node = srcNode
 
+	if pinnedImage != "" {
+		if node.Image != "" && node.Image != pinnedImage {
+			l.Log().Infof("Cluster '%s' pins the image of its nodes: using '%s' instead of '%s'", cluster.Name, pinnedImage, node.Image)
+		}
+		node.Image = pinnedImage
+		node.RuntimeLabels[k3d.LabelImagePinned] = pinnedImage
+	}
+
 	l.Log().Tracef("Resulting node %+v", node)
 
 	k3sURLEnvFound := false
@@ -207,6 +219,17 @@ func NodeAddToCluster(ctx context.Context, runtime runtimes.Runtime, node *k3d.N
 			},
 		})
 	}
+	if len(hostResolvConfBytes) != 0 {
+		createNodeOpts.NodeHooks = append(createNodeOpts.NodeHooks, k3d.NodeHook{
+			Stage: k3d.LifecycleStagePreStart,
+			Action: actions.WriteFileAction{
+				Runtime: runtime,
+				Content: hostResolvConfBytes,
+				Dest:    k3d.DefaultHostResolvConfPath,
+				Mode:    0644,
+			},
+		})
+	}
 
 	// clear status fields
 	node.State.Running = false
@@ -370,14 +393,7 @@ func NodeStart(ctx context.Context, runtime runtimes.Runtime, node *k3d.Node, no
 	l.Log().Debugf("Node %s Start Time: %+v", node.Name, startTime)
 
 	// execute lifecycle hook actions
-	for _, hook := range nodeStartOpts.NodeHooks {
-		if hook.Stage == k3d.LifecycleStagePreStart {
-			l.Log().Tracef("Node %s: Executing preStartAction '%s'", node.Name, reflect.TypeOf(hook))
-			if err := hook.Action.Run(ctx, node); err != nil {
-				l.Log().Errorf("Node %s: Failed executing preStartAction '%+v': %+v", node.Name, hook, err)
-			}
-		}
-	}
+	runPreStartHooks(ctx, node, nodeStartOpts.NodeHooks)
 
 	// start the node
 	l.Log().Tracef("Starting node '%s'", node.Name)
@@ -385,6 +401,10 @@ func NodeStart(ctx context.Context, runtime runtimes.Runtime, node *k3d.Node, no
 	if err := runtime.StartNode(ctx, node); err != nil {
 		return fmt.Errorf("runtime failed to start node '%s': %w", node.Name, err)
 	}
+	metrics.NodeStarts.WithLabelValues(string(node.Role)).Inc()
+	if node.State.Started != "" && node.State.Started != (time.Time{}).Format(time.RFC3339) {
+		metrics.NodeRestarts.WithLabelValues(string(node.Role)).Inc()
+	}
 
 	if node.State.Started != "" {
 		ts, err := time.Parse("2006-01-02T15:04:05.999999999Z", node.State.Started)
@@ -412,6 +432,18 @@ func NodeStart(ctx context.Context, runtime runtimes.Runtime, node *k3d.Node, no
 	return nil
 }
 
+// runPreStartHooks executes the preStart lifecycle hook actions of a node, which usually write files into the (not yet started) container
+func runPreStartHooks(ctx context.Context, node *k3d.Node, hooks []k3d.NodeHook) {
+	for _, hook := range hooks {
+		if hook.Stage == k3d.LifecycleStagePreStart {
+			l.Log().Tracef("Node %s: Executing preStartAction '%s'", node.Name, reflect.TypeOf(hook))
+			if err := hook.Action.Run(ctx, node); err != nil {
+				l.Log().Errorf("Node %s: Failed executing preStartAction '%+v': %+v", node.Name, hook, err)
+			}
+		}
+	}
+}
+
 func enableFixes(ctx context.Context, runtime runtimes.Runtime, node *k3d.Node, nodeStartOpts *k3d.NodeStartOpts) error {
 
 	if node.Role == k3d.ServerRole || node.Role == k3d.AgentRole {
@@ -420,8 +452,10 @@ func enableFixes(ctx context.Context, runtime runtimes.Runtime, node *k3d.Node,
 		// auto-enable, if needed
 		EnableCgroupV2FixIfNeeded(runtime)
 
-		// early exit if we don't need any fix
-		if !fixes.FixEnabledAny() {
+		// early exit if we don't need any fix (or the entrypoint for the node init script or the docker daemon)
+		nodeInit := node.RuntimeLabels[k3d.LabelNodeInit]
+		dockerd := node.RuntimeLabels[k3d.LabelNodeCRI] == string(k3d.CRIDockerd)
+		if !fixes.FixEnabledAny() && nodeInit == "" && !dockerd {
 			l.Log().Debugln("No fix enabled.")
 			return nil
 		}
@@ -481,10 +515,81 @@ func enableFixes(ctx context.Context, runtime runtimes.Runtime, node *k3d.Node,
 				},
 			})
 		}
+
+		// docker daemon for k3s' --docker (cri-dockerd): runs after the fixes, as the entrypoint scripts are executed in alphabetical order
+		if dockerd {
+			nodeStartOpts.NodeHooks = append(nodeStartOpts.NodeHooks, k3d.NodeHook{
+				Stage: k3d.LifecycleStagePreStart,
+				Action: actions.WriteFileAction{
+					Runtime: runtime,
+					Content: []byte(dockerdEntrypoint),
+					Dest:    k3d.DefaultDockerdEntrypointPath,
+					Mode:    0744,
+				},
+			})
+		}
+
+		// Node Init: runs after the fixes, as the entrypoint scripts are executed in alphabetical order
+		if nodeInit != "" {
+			nodeStartOpts.NodeHooks = append(nodeStartOpts.NodeHooks, k3d.NodeHook{
+				Stage: k3d.LifecycleStagePreStart,
+				Action: actions.WriteFileAction{
+					Runtime: runtime,
+					Content: []byte(nodeInit),
+					Dest:    k3d.DefaultNodeInitPath,
+					Mode:    0744,
+				},
+			})
+		}
 	}
 	return nil
 }
 
+// clusterHasSharedDatastore checks if the servers of a cluster use embedded etcd (HA mode) or an external datastore, which is required to add more servers.
+// The init server was started with --cluster-init and all others joined it, so any server that isn't standalone qualifies.
+func clusterHasSharedDatastore(cluster *k3d.Cluster) bool {
+	for _, node := range cluster.Nodes {
+		if node.Role != k3d.ServerRole {
+			continue
+		}
+		if node.ServerOpts.IsInit {
+			return true
+		}
+		for _, arg := range append(append([]string{}, node.Cmd...), node.Args...) {
+			if strings.HasPrefix(arg, "--cluster-init") || strings.HasPrefix(arg, "--datastore-endpoint") {
+				return true
+			}
+		}
+		for _, env := range node.Env {
+			if strings.HasPrefix(env, k3d.K3sEnvClusterConnectURL+"=") || strings.HasPrefix(env, "K3S_DATASTORE_ENDPOINT=") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// nodeReadFile reads a file from a node, returning no content (and no error) if it doesn't exist
+func nodeReadFile(ctx context.Context, runtime runtimes.Runtime, node *k3d.Node, path string) ([]byte, error) {
+	reader, err := runtime.ReadFromNode(ctx, path, node)
+	if err != nil {
+		if errors.Is(err, runtimeErrors.ErrRuntimeFileNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer reader.Close()
+
+	content, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if len(content) < 512 {
+		return nil, fmt.Errorf("unexpected content of %s (no tar archive)", path)
+	}
+	return bytes.Trim(content[512:], "\x00"), nil // trim the tar header and padding
+}
+
 // NodeCreate creates a new containerized k3s node
 func NodeCreate(ctx context.Context, runtime runtimes.Runtime, node *k3d.Node, createNodeOpts k3d.NodeCreateOpts) error {
 	// FIXME: FixCgroupV2 - to be removed when fixed upstream
@@ -518,12 +623,16 @@ func NodeCreate(ctx context.Context, runtime runtimes.Runtime, node *k3d.Node, c
 		}
 	}
 
-	// memory limits
-	if node.Memory != "" {
+	// memory limits and faked memory capacity (the latter takes precedence for the fake meminfo)
+	fakeMemory := node.Memory
+	if node.FakeMemory != "" {
+		fakeMemory = node.FakeMemory
+	}
+	if fakeMemory != "" {
 		if runtime != runtimes.Docker {
 			l.Log().Warn("ignoring specified memory limits as runtime is not Docker")
 		} else {
-			memory, err := dockerunits.RAMInBytes(node.Memory)
+			memory, err := dockerunits.RAMInBytes(fakeMemory)
 			if err != nil {
 				return fmt.Errorf("invalid memory limit format: %w", err)
 			}
@@ -567,7 +676,7 @@ func NodeDelete(ctx context.Context, runtime runtimes.Runtime, node *k3d.Node, o
 	}
 
 	// delete fake folder created for limits
-	if node.Memory != "" {
+	if node.Memory != "" || node.FakeMemory != "" {
 		l.Log().Debug("Cleaning fake files folder from k3d config dir for this node...")
 		filepath, err := util.GetNodeFakerDirOrCreate(node.Name)
 		err = os.RemoveAll(filepath)
@@ -619,11 +728,15 @@ func patchServerSpec(node *k3d.Node, runtime runtimes.Runtime) error {
 	node.RuntimeLabels[k3d.LabelServerAPIHost] = node.ServerOpts.KubeAPI.Host
 	node.RuntimeLabels[k3d.LabelServerAPIPort] = node.ServerOpts.KubeAPI.Binding.HostPort
 
-	// If the runtime is docker, attempt to use the docker host
-	if runtime == runtimes.Docker {
+	// If the runtime is docker, attempt to use the docker host (unless the API is reached through a tunnel or via an explicitly given host name)
+	if node.ServerOpts.KubeAPI.Tunnel {
+		node.RuntimeLabels[k3d.LabelServerAPITunnel] = "true"
+	} else if runtime == runtimes.Docker && node.ServerOpts.KubeAPI.Host == node.ServerOpts.KubeAPI.Binding.HostIP {
 		dockerHost := runtime.GetHost()
 		if dockerHost != "" {
-			dockerHost = strings.Split(dockerHost, ":")[0] // remove the port
+			if host, _, err := net.SplitHostPort(dockerHost); err == nil {
+				dockerHost = host // remove the port (works for IPv6 addresses as well)
+			}
 			l.Log().Tracef("Using docker host %s", dockerHost)
 			node.RuntimeLabels[k3d.LabelServerAPIHostIP] = dockerHost
 			node.RuntimeLabels[k3d.LabelServerAPIHost] = dockerHost
@@ -631,6 +744,9 @@ func patchServerSpec(node *k3d.Node, runtime runtimes.Runtime) error {
 	}
 
 	node.Args = append(node.Args, "--tls-san", node.RuntimeLabels[k3d.LabelServerAPIHost]) // add TLS SAN for non default host name
+	if runnerAPIHost, ok := node.RuntimeLabels[k3d.LabelRunnerAPIHost]; ok && runnerAPIHost != "" {
+		node.Args = append(node.Args, "--tls-san", runnerAPIHost) // API endpoint used by k3d running in a container connected to the cluster network
+	}
 
 	return nil
 }
@@ -656,7 +772,45 @@ func NodeGet(ctx context.Context, runtime runtimes.Runtime, node *k3d.Node) (*k3
 	return node, nil
 }
 
+// NodeGetInCluster returns the node of the cluster clusterName referenced by ref, which is either the node name,
+// the node name without the cluster prefix (e.g. 'agent-1') or ROLE:INDEX (e.g. 'agent:1')
+func NodeGetInCluster(ctx context.Context, runtime runtimes.Runtime, clusterName string, ref string) (*k3d.Node, error) {
+	cluster, err := ClusterGet(ctx, runtime, &k3d.Cluster{Name: clusterName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster '%s': %w", clusterName, err)
+	}
+	return clusterNodeByRef(cluster, ref)
+}
+
+// clusterNodeByRef looks up a node of the cluster by name, short name or ROLE:INDEX
+func clusterNodeByRef(cluster *k3d.Cluster, ref string) (*k3d.Node, error) {
+	prefix := fmt.Sprintf("%s-%s-", k3d.DefaultObjectNamePrefix, cluster.Name)
+	for _, node := range cluster.Nodes {
+		if node.Name == ref || node.Name == prefix+ref {
+			return node, nil
+		}
+	}
+
+	parts := strings.SplitN(ref, ":", 2)
+	if len(parts) == 2 {
+		index, err := strconv.Atoi(parts[1])
+		if err != nil || index < 0 {
+			return nil, fmt.Errorf("invalid node index '%s' in '%s'", parts[1], ref)
+		}
+		role := k3d.Role(strings.TrimSuffix(parts[0], "s"))
+		for _, node := range cluster.Nodes {
+			if node.Role == role && strings.HasSuffix(node.Name, fmt.Sprintf("-%d", index)) {
+				return node, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("no node '%s' in cluster '%s' (use the node name, e.g. 'agent-0', or ROLE:INDEX, e.g. 'agent:0')", ref, cluster.Name)
+}
+
 // NodeWaitForLogMessage follows the logs of a node container and returns if it finds a specific line in there (or timeout is reached)
+// The logs are streamed, so the message is found as soon as it's logged. If the log stream ends before (e.g. because the container
+// is restarting while waiting to join), it's followed again after WaitPollInterval.
 func NodeWaitForLogMessage(ctx context.Context, runtime runtimes.Runtime, node *k3d.Node, message string, since time.Time) error {
 	l.Log().Tracef("NodeWaitForLogMessage: Node '%s' waiting for log message '%s' since '%+v'", node.Name, message, since)
 	for {
@@ -673,49 +827,52 @@ func NodeWaitForLogMessage(ctx context.Context, runtime runtimes.Runtime, node *
 		default:
 		}
 
-		// read the logs
-		out, err := runtime.GetNodeLogs(ctx, node, since)
+		// follow the logs
+		out, err := runtime.FollowNodeLogs(ctx, node, since)
 		if err != nil {
 			if out != nil {
 				out.Close()
 			}
 			return fmt.Errorf("Failed waiting for log message '%s' from node '%s': %w", message, node.Name, err)
 		}
-		defer out.Close()
-
-		buf := new(bytes.Buffer)
-		nRead, _ := buf.ReadFrom(out)
+		found := logStreamContains(out, message, strings.Contains(os.Getenv("K3D_LOG_NODE_WAIT_LOGS"), string(node.Role)))
 		out.Close()
-		output := buf.String()
-
-		if nRead > 0 && strings.Contains(os.Getenv("K3D_LOG_NODE_WAIT_LOGS"), string(node.Role)) {
-			l.Log().Tracef("=== Read logs since %s ===\n%s\n", since, output)
-		}
-		// check if we can find the specified line in the log
-		if nRead > 0 && strings.Contains(output, message) {
-			if l.Log().GetLevel() >= logrus.TraceLevel {
-				temp := strings.Split(output, "\n")
-				for _, t := range temp {
-					if strings.Contains(t, message) {
-						l.Log().Tracef("Found target log line: `%s`", t)
-					}
-				}
-			}
+		if found {
 			break
 		}
 
-		// check if the container is restarting
+		// the log stream ended (or the context is done), so check if the container is restarting
 		running, status, _ := runtime.GetNodeStatus(ctx, node)
 		if running && status == k3d.NodeStatusRestarting && time.Now().Sub(since) > k3d.NodeWaitForLogMessageRestartWarnTime {
 			l.Log().Warnf("Node '%s' is restarting for more than a minute now. Possibly it will recover soon (e.g. when it's waiting to join). Consider using a creation timeout to avoid waiting forever in a Restart Loop.", node.Name)
 		}
 
-		time.Sleep(500 * time.Millisecond) // wait for half a second to avoid overloading docker (error `socket: too many open files`)
+		select {
+		case <-ctx.Done():
+		case <-time.After(WaitPollInterval): // avoid overloading docker (error `socket: too many open files`) with a container in a restart loop
+		}
 	}
 	l.Log().Debugf("Finished waiting for log message '%s' from node '%s'", message, node.Name)
 	return nil
 }
 
+// logStreamContains reads the given log stream line by line until it finds the message or the stream ends
+func logStreamContains(logs io.Reader, message string, traceLogs bool) bool {
+	scanner := bufio.NewScanner(logs)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if traceLogs {
+			l.Log().Tracef("=== Node log: %s", line)
+		}
+		if strings.Contains(line, message) {
+			l.Log().Tracef("Found target log line: `%s`", line)
+			return true
+		}
+	}
+	return false
+}
+
 // NodeFilterByRoles filters a list of nodes by their roles
 func NodeFilterByRoles(nodes []*k3d.Node, includeRoles, excludeRoles []k3d.Role) []*k3d.Node {
 	// check for conflicting filters
@@ -0,0 +1,142 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rancher/k3d/v5/pkg/actions"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestNodeWaitForLogMessage(t *testing.T) {
+	WaitPollInterval = time.Millisecond
+	node := newFakeNode("test", "k3d-test-agent-0", k3d.AgentRole, true)
+
+	// the first stream ends without the message (container restarting), the second one contains it
+	runtime := &fakeRuntime{logStreams: map[string][]string{node.Name: {
+		"level=info msg=\"Starting k3s agent\"\nlevel=error msg=\"failed to get CA certs\"\n",
+		"level=info msg=\"Starting k3s agent\"\nlevel=info msg=\"Successfully registered node k3d-test-agent-0\"\n",
+	}}}
+	if err := NodeWaitForLogMessage(context.Background(), runtime, node, "Successfully registered node", time.Time{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if follows := runtime.callsOf("FollowNodeLogs"); len(follows) != 2 {
+		t.Errorf("expected the logs to be followed again after the stream ended, got %v", follows)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	runtime = &fakeRuntime{}
+	if err := NodeWaitForLogMessage(ctx, runtime, node, "Successfully registered node", time.Time{}); err == nil {
+		t.Errorf("expected a timeout error")
+	}
+}
+
+func TestClusterHasSharedDatastore(t *testing.T) {
+	tests := []struct {
+		name   string
+		server k3d.Node
+		want   bool
+	}{
+		{"single server with sqlite", k3d.Node{Role: k3d.ServerRole, Cmd: []string{"server", "--tls-san", "0.0.0.0"}}, false},
+		{"init server", k3d.Node{Role: k3d.ServerRole, ServerOpts: k3d.ServerOpts{IsInit: true}}, true},
+		{"cluster-init via k3s arg", k3d.Node{Role: k3d.ServerRole, Cmd: []string{"server", "--cluster-init"}}, true},
+		{"joined server", k3d.Node{Role: k3d.ServerRole, Env: []string{"K3S_URL=https://k3d-test-server-0:6443"}}, true},
+		{"external datastore", k3d.Node{Role: k3d.ServerRole, Cmd: []string{"server", "--datastore-endpoint=mysql://db:3306/k3s"}}, true},
+	}
+	for _, tt := range tests {
+		server := tt.server
+		cluster := &k3d.Cluster{Nodes: []*k3d.Node{&server, {Role: k3d.AgentRole, Env: []string{"K3S_URL=https://k3d-test-server-0:6443"}}}}
+		if got := clusterHasSharedDatastore(cluster); got != tt.want {
+			t.Errorf("%s: expected %t, got %t", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestClusterNodeByRef(t *testing.T) {
+	cluster := &k3d.Cluster{Name: "test", Nodes: []*k3d.Node{
+		newFakeNode("test", "k3d-test-server-0", k3d.ServerRole, true),
+		newFakeNode("test", "k3d-test-agent-1", k3d.AgentRole, true),
+		newFakeNode("test", "k3d-test-agent-11", k3d.AgentRole, true),
+	}}
+
+	tests := []struct {
+		ref      string
+		expected string
+	}{
+		{"k3d-test-agent-1", "k3d-test-agent-1"},
+		{"agent-11", "k3d-test-agent-11"},
+		{"server:0", "k3d-test-server-0"},
+		{"agents:1", "k3d-test-agent-1"},
+		{"agent:0", ""},
+		{"agent:x", ""},
+		{"0", ""},
+	}
+	for _, tt := range tests {
+		node, err := clusterNodeByRef(cluster, tt.ref)
+		if tt.expected == "" {
+			if err == nil {
+				t.Errorf("%s: expected an error, got node '%s'", tt.ref, node.Name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.ref, err)
+		} else if node.Name != tt.expected {
+			t.Errorf("%s: expected node '%s', got '%s'", tt.ref, tt.expected, node.Name)
+		}
+	}
+}
+
+func TestEnableFixesNodeInit(t *testing.T) {
+	t.Setenv("K3D_FIX_CGROUPV2", "false")
+	t.Setenv("K3D_FIX_DNS", "false")
+	runtime := &fakeRuntime{}
+
+	node := newFakeNode("test", "k3d-test-agent-0", k3d.AgentRole, false)
+	opts := &k3d.NodeStartOpts{}
+	if err := enableFixes(context.Background(), runtime, node, opts); err != nil {
+		t.Fatal(err)
+	}
+	if len(opts.NodeHooks) != 0 {
+		t.Errorf("expected no hooks without fixes and node init, got %+v", opts.NodeHooks)
+	}
+
+	node.RuntimeLabels[k3d.LabelNodeInit] = "#!/bin/sh\napk add curl\n"
+	if err := enableFixes(context.Background(), runtime, node, opts); err != nil {
+		t.Fatal(err)
+	}
+	dests := map[string]string{}
+	for _, hook := range opts.NodeHooks {
+		action := hook.Action.(actions.WriteFileAction)
+		dests[action.Dest] = string(action.Content)
+	}
+	if _, ok := dests["/bin/k3d-entrypoint.sh"]; !ok || len(dests) != 2 {
+		t.Errorf("expected the k3d entrypoint and the node init script to be written, got %v", dests)
+	}
+	if dests[k3d.DefaultNodeInitPath] != node.RuntimeLabels[k3d.LabelNodeInit] {
+		t.Errorf("expected the node init script at %s, got '%s'", k3d.DefaultNodeInitPath, dests[k3d.DefaultNodeInitPath])
+	}
+}
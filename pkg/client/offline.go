@@ -0,0 +1,99 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// Offline forbids network access (set by the global --offline flag): images are never pulled, the latest k3s version isn't looked up
+// and the docker-machine IP isn't queried. Operations needing images that aren't present locally fail right away.
+var Offline bool
+
+// ImagesCheckPresent returns an error listing all of the given images that aren't present in the runtime
+func ImagesCheckPresent(ctx context.Context, runtime runtimes.Runtime, images []string) error {
+	missing := map[string]struct{}{}
+	for _, image := range images {
+		if image == "" {
+			continue
+		}
+		if _, err := runtime.GetImagePlatform(ctx, image); err != nil {
+			missing[image] = struct{}{}
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	missingList := make([]string, 0, len(missing))
+	for image := range missing {
+		missingList = append(missingList, image)
+	}
+	sort.Strings(missingList)
+	return fmt.Errorf("the following images are not present locally and can't be pulled: %s (pull or load them first, e.g. with 'docker load')", strings.Join(missingList, ", "))
+}
+
+// clusterPullImages applies the pull policy of a cluster to the images of its nodes before they're created
+func clusterPullImages(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster, policy k3d.ImagePullPolicy) error {
+	switch {
+	case Offline:
+		if err := ImagesCheckPresent(ctx, runtime, clusterImages(cluster)); err != nil {
+			return fmt.Errorf("offline mode: %w", err)
+		}
+	case policy == k3d.ImagePullPolicyNever:
+		if err := ImagesCheckPresent(ctx, runtime, clusterImages(cluster)); err != nil {
+			return fmt.Errorf("pull policy '%s': %w", policy, err)
+		}
+	case policy == k3d.ImagePullPolicyAlways:
+		pulled := map[string]struct{}{}
+		for _, node := range cluster.Nodes {
+			if node.Role != k3d.ServerRole && node.Role != k3d.AgentRole {
+				continue
+			}
+			if _, ok := pulled[node.Image]; ok {
+				continue
+			}
+			pulled[node.Image] = struct{}{}
+			if err := runtime.PullImage(ctx, node.Image); err != nil {
+				return fmt.Errorf("failed to pull image '%s' (pull policy '%s'): %w", node.Image, policy, err)
+			}
+		}
+	}
+	return nil
+}
+
+// clusterImages returns the images of all nodes of a cluster, incl. the loadbalancer
+func clusterImages(cluster *k3d.Cluster) []string {
+	images := []string{}
+	for _, node := range cluster.Nodes {
+		images = append(images, node.Image)
+	}
+	if cluster.ServerLoadBalancer != nil && cluster.ServerLoadBalancer.Node != nil {
+		images = append(images, cluster.ServerLoadBalancer.Node.Image)
+	}
+	return images
+}
@@ -0,0 +1,85 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestImagesCheckPresent(t *testing.T) {
+	runtime := &fakeRuntime{images: map[string]string{"rancher/k3s:v1.21.4-k3s1": "linux/amd64"}}
+	cluster := &k3d.Cluster{
+		Nodes: []*k3d.Node{
+			{Name: "k3d-test-server-0", Image: "rancher/k3s:v1.21.4-k3s1"},
+			{Name: "k3d-test-agent-0", Image: "rancher/k3s:v1.21.4-k3s1"},
+		},
+		ServerLoadBalancer: &k3d.Loadbalancer{Node: &k3d.Node{Name: "k3d-test-serverlb", Image: "rancher/k3d-proxy:5.0.0"}},
+	}
+
+	err := ImagesCheckPresent(context.Background(), runtime, clusterImages(cluster))
+	if err == nil {
+		t.Fatalf("expected an error for the missing loadbalancer image")
+	}
+	if !strings.Contains(err.Error(), "rancher/k3d-proxy:5.0.0") || strings.Contains(err.Error(), "rancher/k3s") {
+		t.Errorf("expected only the loadbalancer image to be reported as missing, got: %v", err)
+	}
+
+	runtime.images["rancher/k3d-proxy:5.0.0"] = "linux/amd64"
+	if err := ImagesCheckPresent(context.Background(), runtime, clusterImages(cluster)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestClusterPullImages(t *testing.T) {
+	cluster := &k3d.Cluster{
+		Nodes: []*k3d.Node{
+			{Name: "k3d-test-server-0", Role: k3d.ServerRole, Image: "rancher/k3s:latest"},
+			{Name: "k3d-test-agent-0", Role: k3d.AgentRole, Image: "rancher/k3s:latest"},
+			{Name: "k3d-test-agent-1", Role: k3d.AgentRole, Image: "rancher/k3s:v1.21.4-k3s1"},
+		},
+		ServerLoadBalancer: &k3d.Loadbalancer{Node: &k3d.Node{Name: "k3d-test-serverlb", Role: k3d.LoadBalancerRole, Image: "rancher/k3d-proxy:5.0.0"}},
+	}
+
+	runtime := &fakeRuntime{images: map[string]string{}}
+	if err := clusterPullImages(context.Background(), runtime, cluster, k3d.ImagePullPolicyAlways); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pulled := runtime.callsOf("PullImage"); !reflect.DeepEqual(pulled, []string{"rancher/k3s:latest", "rancher/k3s:v1.21.4-k3s1"}) {
+		t.Errorf("expected each k3s image to be pulled once, got %v", pulled)
+	}
+
+	runtime = &fakeRuntime{images: map[string]string{}}
+	if err := clusterPullImages(context.Background(), runtime, cluster, k3d.ImagePullPolicyMissing); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := clusterPullImages(context.Background(), runtime, cluster, k3d.ImagePullPolicyNever); err == nil || !strings.Contains(err.Error(), "rancher/k3d-proxy:5.0.0") {
+		t.Errorf("expected an error listing the missing images, got: %v", err)
+	}
+	if pulled := runtime.callsOf("PullImage"); len(pulled) != 0 {
+		t.Errorf("expected no pulls, got %v", pulled)
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"fmt"
+	"strings"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const kubeAPIServerArgFlag = "--kube-apiserver-arg"
+
+// OIDCServerArgs translates the OIDC settings into the k3s server arguments configuring the kube-apiserver
+func OIDCServerArgs(oidc *k3d.OIDCOpts) []string {
+	args := []string{
+		fmt.Sprintf("%s=oidc-issuer-url=%s", kubeAPIServerArgFlag, oidc.IssuerURL),
+		fmt.Sprintf("%s=oidc-client-id=%s", kubeAPIServerArgFlag, oidc.ClientID),
+	}
+	if oidc.UsernameClaim != "" {
+		args = append(args, fmt.Sprintf("%s=oidc-username-claim=%s", kubeAPIServerArgFlag, oidc.UsernameClaim))
+	}
+	if oidc.GroupsClaim != "" {
+		args = append(args, fmt.Sprintf("%s=oidc-groups-claim=%s", kubeAPIServerArgFlag, oidc.GroupsClaim))
+	}
+	return args
+}
+
+// oidcFromServerArgs extracts the OIDC settings from the command of a server node (nil, if OIDC is not configured)
+func oidcFromServerArgs(args []string) *k3d.OIDCOpts {
+	oidc := &k3d.OIDCOpts{}
+	for i, arg := range args {
+		var value string
+		if strings.HasPrefix(arg, kubeAPIServerArgFlag+"=") {
+			value = strings.TrimPrefix(arg, kubeAPIServerArgFlag+"=")
+		} else if arg == kubeAPIServerArgFlag && i+1 < len(args) {
+			value = args[i+1]
+		} else {
+			continue
+		}
+
+		kv := strings.SplitN(value, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "oidc-issuer-url":
+			oidc.IssuerURL = kv[1]
+		case "oidc-client-id":
+			oidc.ClientID = kv[1]
+		case "oidc-username-claim":
+			oidc.UsernameClaim = kv[1]
+		case "oidc-groups-claim":
+			oidc.GroupsClaim = kv[1]
+		}
+	}
+
+	if oidc.IssuerURL == "" || oidc.ClientID == "" {
+		return nil
+	}
+	return oidc
+}
+
+// kubeconfigAddOIDC adds a user authenticating via the kubectl oidc-login plugin (https://github.com/int128/kubelogin)
+// and a context using it to the given kubeconfig
+func kubeconfigAddOIDC(kc *clientcmdapi.Config, clusterEntryName string, namespace string, oidc *k3d.OIDCOpts) {
+	authInfoName := fmt.Sprintf("oidc@%s", clusterEntryName)
+
+	args := []string{
+		"oidc-login",
+		"get-token",
+		fmt.Sprintf("--oidc-issuer-url=%s", oidc.IssuerURL),
+		fmt.Sprintf("--oidc-client-id=%s", oidc.ClientID),
+	}
+	if oidc.UsernameClaim == "email" {
+		args = append(args, "--oidc-extra-scope=email")
+	}
+	if oidc.GroupsClaim != "" {
+		args = append(args, fmt.Sprintf("--oidc-extra-scope=%s", oidc.GroupsClaim))
+	}
+
+	kc.AuthInfos[authInfoName] = &clientcmdapi.AuthInfo{
+		Exec: &clientcmdapi.ExecConfig{
+			APIVersion:      "client.authentication.k8s.io/v1beta1",
+			Command:         "kubectl",
+			Args:            args,
+			InstallHint:     "The OIDC context requires the kubectl oidc-login plugin: https://github.com/int128/kubelogin",
+			InteractiveMode: clientcmdapi.IfAvailableExecInteractiveMode,
+		},
+	}
+	kc.Contexts[fmt.Sprintf("%s-oidc", clusterEntryName)] = &clientcmdapi.Context{
+		Cluster:   clusterEntryName,
+		AuthInfo:  authInfoName,
+		Namespace: namespace,
+	}
+}
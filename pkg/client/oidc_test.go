@@ -0,0 +1,54 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestOIDCFromServerArgs(t *testing.T) {
+	oidc := &k3d.OIDCOpts{
+		IssuerURL:     "https://dex.example.com",
+		ClientID:      "k3d",
+		UsernameClaim: "email",
+	}
+
+	// generated args
+	args := append([]string{"server", "--tls-san", "0.0.0.0"}, OIDCServerArgs(oidc)...)
+	if diff := deep.Equal(oidcFromServerArgs(args), oidc); diff != nil {
+		t.Errorf("unexpected OIDC settings from generated args: %+v", diff)
+	}
+
+	// user-provided args with separate values
+	args = []string{"server", "--kube-apiserver-arg", "oidc-issuer-url=https://dex.example.com", "--kube-apiserver-arg", "oidc-client-id=k3d", "--kube-apiserver-arg=oidc-username-claim=email"}
+	if diff := deep.Equal(oidcFromServerArgs(args), oidc); diff != nil {
+		t.Errorf("unexpected OIDC settings from separate args: %+v", diff)
+	}
+
+	// incomplete configuration
+	if got := oidcFromServerArgs([]string{"server", "--kube-apiserver-arg=oidc-client-id=k3d"}); got != nil {
+		t.Errorf("expected no OIDC settings without issuer URL, got %+v", got)
+	}
+}
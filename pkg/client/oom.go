@@ -0,0 +1,61 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"fmt"
+	"strings"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// OOMKilledHint explains the OOM kills in the given nodes (e.g. from Cluster.OOMKilledNodes) and how to give them more memory.
+// It returns an empty string if there are none.
+func OOMKilledHint(nodes []*k3d.Node) string {
+	if len(nodes) == 0 {
+		return ""
+	}
+	names := []string{}
+	servers, agents := false, false
+	for _, node := range nodes {
+		limit := ""
+		if node.Memory != "" && node.Memory != "0B" {
+			limit = fmt.Sprintf(" (limit: %s)", node.Memory)
+		}
+		names = append(names, node.Name+limit)
+		if node.Role == k3d.ServerRole {
+			servers = true
+		} else {
+			agents = true
+		}
+	}
+	flags := []string{}
+	if servers {
+		flags = append(flags, "--servers-memory")
+	}
+	if agents {
+		flags = append(flags, "--agents-memory")
+	}
+	return fmt.Sprintf("Processes in %d node(s) were killed for running out of memory: %s\n"+
+		"This makes pods (or k3s itself) restart seemingly at random. Recreate the cluster with more memory via %s or lower the load on the nodes.",
+		len(nodes), strings.Join(names, ", "), strings.Join(flags, "/"))
+}
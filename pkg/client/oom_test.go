@@ -0,0 +1,53 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"strings"
+	"testing"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestOOMKilledHint(t *testing.T) {
+	if hint := OOMKilledHint(nil); hint != "" {
+		t.Errorf("expected no hint without OOM-killed nodes, got '%s'", hint)
+	}
+
+	agent := newFakeNode("test", "k3d-test-agent-0", k3d.AgentRole, true)
+	agent.Memory = "1GiB"
+	agent.State.OOMKilled = true
+	server := newFakeNode("test", "k3d-test-server-0", k3d.ServerRole, true)
+	server.Memory = "0B"
+	cluster := &k3d.Cluster{Name: "test", Nodes: []*k3d.Node{server, agent, newFakeNode("test", "k3d-test-agent-1", k3d.AgentRole, true)}}
+
+	hint := OOMKilledHint(cluster.OOMKilledNodes())
+	if !strings.Contains(hint, "1 node(s)") || !strings.Contains(hint, "k3d-test-agent-0 (limit: 1GiB)") || !strings.Contains(hint, "via --agents-memory ") {
+		t.Errorf("unexpected hint for an OOM-killed agent: %s", hint)
+	}
+
+	server.State.OOMKilled = true
+	hint = OOMKilledHint(cluster.OOMKilledNodes())
+	if !strings.Contains(hint, "2 node(s)") || !strings.Contains(hint, "k3d-test-server-0, ") || !strings.Contains(hint, "--servers-memory/--agents-memory") {
+		t.Errorf("unexpected hint for an OOM-killed server and agent: %s", hint)
+	}
+}
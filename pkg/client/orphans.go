@@ -0,0 +1,252 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/rancher/k3d/v5/pkg/util"
+)
+
+// OrphansFind cross-references all k3d-labeled containers, volumes and networks and the cluster files in the k3d config directory
+// against the existing clusters. A cluster only counts as existing, if it has a server node: the remaining nodes of a cluster
+// without servers (e.g. after a failed creation or a 'docker rm' of the server) can't form a working cluster anymore.
+func OrphansFind(ctx context.Context, runtime runtimes.Runtime) ([]*k3d.OrphanedResource, error) {
+	nodes, err := runtime.GetNodesByLabel(ctx, k3d.DefaultRuntimeLabels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	clusterNodes := map[string][]*k3d.Node{}
+	alive := map[string]bool{}
+	for _, node := range nodes {
+		clusterName := node.RuntimeLabels[k3d.LabelClusterName]
+		if node.Role == k3d.RegistryRole || clusterName == "" {
+			continue
+		}
+		clusterNodes[clusterName] = append(clusterNodes[clusterName], node)
+		if node.Role == k3d.ServerRole {
+			alive[clusterName] = true
+		}
+	}
+	reason := func(clusterName string) string {
+		if len(clusterNodes[clusterName]) > 0 {
+			return fmt.Sprintf("cluster '%s' has no server node", clusterName)
+		}
+		return fmt.Sprintf("cluster '%s' doesn't exist", clusterName)
+	}
+
+	orphans := []*k3d.OrphanedResource{}
+	orphanedNodes := map[string]bool{}
+
+	// containers
+	clusterNames := make([]string, 0, len(clusterNodes))
+	for clusterName := range clusterNodes {
+		clusterNames = append(clusterNames, clusterName)
+	}
+	sort.Strings(clusterNames)
+	for _, clusterName := range clusterNames {
+		if alive[clusterName] {
+			continue
+		}
+		for _, node := range clusterNodes[clusterName] {
+			orphanedNodes[node.Name] = true
+			orphans = append(orphans, &k3d.OrphanedResource{Kind: k3d.OrphanKindContainer, Name: node.Name, Cluster: clusterName, Reason: reason(clusterName)})
+		}
+	}
+
+	// volumes (the image cache volumes are shared by all clusters)
+	volumes, err := runtime.GetVolumesByLabel(ctx, k3d.DefaultRuntimeLabels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volumes: %w", err)
+	}
+	sort.Slice(volumes, func(i, j int) bool { return volumes[i].Name < volumes[j].Name })
+	for _, volume := range volumes {
+		clusterName := volume.Labels[k3d.LabelClusterName]
+		if clusterName == "" || alive[clusterName] {
+			continue
+		}
+		orphans = append(orphans, &k3d.OrphanedResource{Kind: k3d.OrphanKindVolume, Name: volume.Name, Cluster: clusterName, Reason: reason(clusterName)})
+	}
+
+	// networks, that aren't used by any (remaining) k3d node or other container
+	usedNetworks := map[string]bool{}
+	for _, node := range nodes {
+		if orphanedNodes[node.Name] {
+			continue
+		}
+		for _, network := range node.Networks {
+			usedNetworks[network] = true
+		}
+		if network := node.RuntimeLabels[k3d.LabelNetwork]; network != "" {
+			usedNetworks[network] = true
+		}
+	}
+	networks, err := runtime.GetNetworksByLabel(ctx, k3d.DefaultRuntimeLabels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networks: %w", err)
+	}
+	sort.Slice(networks, func(i, j int) bool { return networks[i].Name < networks[j].Name })
+networkLoop:
+	for _, network := range networks {
+		if usedNetworks[network.Name] || usedNetworks[network.ID] {
+			continue
+		}
+		for _, member := range network.Members {
+			if !orphanedNodes[strings.TrimPrefix(member.Name, "/")] {
+				continue networkLoop
+			}
+		}
+		orphans = append(orphans, &k3d.OrphanedResource{Kind: k3d.OrphanKindNetwork, Name: network.Name, Reason: "not used by any k3d cluster or other container"})
+	}
+
+	// files in the k3d config directory
+	files, err := orphanedClusterFiles(alive)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		file.Reason = reason(file.Cluster)
+		orphans = append(orphans, file)
+	}
+
+	return orphans, nil
+}
+
+// orphanedClusterFiles returns the files kept per cluster in the k3d config directory (dedicated kubeconfig, checkpoints, freeze record)
+// belonging to clusters that don't exist
+func orphanedClusterFiles(alive map[string]bool) ([]*k3d.OrphanedResource, error) {
+	configDir, err := util.GetConfigDirOrCreate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config directory: %w", err)
+	}
+
+	orphans := []*k3d.OrphanedResource{}
+	for _, pattern := range []struct {
+		dir    string
+		prefix string
+		suffix string
+	}{
+		{dir: configDir, prefix: "kubeconfig-", suffix: ".yaml"},
+		{dir: filepath.Join(configDir, "checkpoints")},
+		{dir: filepath.Join(configDir, "frozen"), suffix: ".yaml"},
+	} {
+		entries, err := ioutil.ReadDir(pattern.dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read directory '%s': %w", pattern.dir, err)
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if !strings.HasPrefix(name, pattern.prefix) || !strings.HasSuffix(name, pattern.suffix) || (pattern.suffix == "") != entry.IsDir() {
+				continue
+			}
+			clusterName := strings.TrimSuffix(strings.TrimPrefix(name, pattern.prefix), pattern.suffix)
+			if clusterName == "" || alive[clusterName] {
+				continue
+			}
+			orphans = append(orphans, &k3d.OrphanedResource{Kind: k3d.OrphanKindFile, Name: filepath.Join(pattern.dir, name), Cluster: clusterName})
+		}
+	}
+	return orphans, nil
+}
+
+// OrphansPrune removes the resources found by OrphansFind (containers first, so that their volumes and networks can be removed as well)
+// and returns the ones that were (or would be, in case of a dry-run) removed. Failing removals are only logged.
+func OrphansPrune(ctx context.Context, runtime runtimes.Runtime, opts k3d.OrphanPruneOpts) ([]*k3d.OrphanedResource, error) {
+	orphans, err := OrphansFind(ctx, runtime)
+	if err != nil || opts.DryRun || len(orphans) == 0 {
+		return orphans, err
+	}
+
+	// a cluster that's being created right now doesn't have all of its nodes yet: wait for concurrent operations and look again
+	locked := map[string]bool{}
+	for _, orphan := range orphans {
+		if orphan.Cluster == "" || locked[orphan.Cluster] {
+			continue
+		}
+		unlock, err := ClusterLock(ctx, orphan.Cluster)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+		locked[orphan.Cluster] = true
+	}
+	orphans, err = OrphansFind(ctx, runtime)
+	if err != nil {
+		return nil, err
+	}
+
+	pruned := []*k3d.OrphanedResource{}
+	for _, kind := range []string{k3d.OrphanKindContainer, k3d.OrphanKindVolume, k3d.OrphanKindNetwork, k3d.OrphanKindFile} {
+		for _, orphan := range orphans {
+			if orphan.Kind != kind {
+				continue
+			}
+			if err := orphanRemove(ctx, runtime, orphan); err != nil {
+				l.Log().Warnf("Failed to remove orphaned %s '%s': %v", orphan.Kind, orphan.Name, err)
+				continue
+			}
+			l.Log().Debugf("Removed orphaned %s '%s'", orphan.Kind, orphan.Name)
+			pruned = append(pruned, orphan)
+		}
+	}
+	return pruned, nil
+}
+
+func orphanRemove(ctx context.Context, runtime runtimes.Runtime, orphan *k3d.OrphanedResource) error {
+	switch orphan.Kind {
+	case k3d.OrphanKindContainer:
+		return runtime.DeleteNode(ctx, &k3d.Node{Name: orphan.Name})
+	case k3d.OrphanKindVolume:
+		return runtime.DeleteVolume(ctx, orphan.Name)
+	case k3d.OrphanKindNetwork:
+		// a cluster may just be about to attach its nodes to the network
+		unlock, err := networkLock(ctx, orphan.Name)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		nodes, err := runtime.GetNodesInNetwork(ctx, orphan.Name)
+		if err != nil {
+			return err
+		}
+		if len(nodes) > 0 {
+			return fmt.Errorf("network is used by %d k3d node(s) by now", len(nodes))
+		}
+		return runtime.DeleteNetwork(ctx, orphan.Name)
+	case k3d.OrphanKindFile:
+		return os.RemoveAll(orphan.Name)
+	}
+	return fmt.Errorf("unknown kind of resource '%s'", orphan.Kind)
+}
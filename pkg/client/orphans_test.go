@@ -0,0 +1,162 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// newOrphanTestRuntime returns a runtime with the intact cluster 'alive' and the remains of the cluster 'broken', which lost its server
+func newOrphanTestRuntime() *fakeRuntime {
+	node := func(cluster string, name string, role k3d.Role) *k3d.Node {
+		n := newFakeNode(cluster, name, role, true)
+		n.RuntimeLabels["app"] = "k3d"
+		n.RuntimeLabels[k3d.LabelNetwork] = "k3d-" + cluster
+		n.Networks = []string{"k3d-" + cluster}
+		return n
+	}
+	registry := node("", "k3d-registry", k3d.RegistryRole)
+	registry.Networks = []string{"k3d-alive"}
+	return &fakeRuntime{
+		nodes: []*k3d.Node{
+			node("alive", "k3d-alive-server-0", k3d.ServerRole),
+			node("alive", "k3d-alive-agent-0", k3d.AgentRole),
+			node("broken", "k3d-broken-agent-0", k3d.AgentRole),
+			node("broken", "k3d-broken-serverlb", k3d.LoadBalancerRole),
+			registry,
+		},
+		volumes: map[string]map[string]string{
+			"k3d-alive-images":  {"app": "k3d", k3d.LabelClusterName: "alive"},
+			"k3d-broken-images": {"app": "k3d", k3d.LabelClusterName: "broken"},
+			"k3d-gone-images":   {"app": "k3d", k3d.LabelClusterName: "gone"},
+			"k3d-image-cache":   {"app": "k3d"},
+			"unrelated":         {},
+		},
+		networks: []*k3d.ClusterNetwork{
+			{Name: "k3d-alive", Members: []*k3d.NetworkMember{{Name: "k3d-alive-server-0"}, {Name: "k3d-alive-agent-0"}}},
+			{Name: "k3d-broken", Members: []*k3d.NetworkMember{{Name: "k3d-broken-agent-0"}, {Name: "k3d-broken-serverlb"}}},
+			{Name: "k3d-gone"},
+			{Name: "k3d-shared", Members: []*k3d.NetworkMember{{Name: "my-app"}}},
+		},
+	}
+}
+
+func orphanNames(orphans []*k3d.OrphanedResource) []string {
+	names := []string{}
+	for _, orphan := range orphans {
+		names = append(names, orphan.Kind+":"+filepath.Base(orphan.Name))
+	}
+	return names
+}
+
+func TestOrphansFind(t *testing.T) {
+	home := useTempConfigDir(t)
+	configDir := filepath.Join(home, ".k3d")
+	for _, dir := range []string{filepath.Join(configDir, "checkpoints", "broken"), filepath.Join(configDir, "checkpoints", "alive"), filepath.Join(configDir, "frozen")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"kubeconfig-alive.yaml", "kubeconfig-gone.yaml", "frozen/gone.yaml", "registries.yaml"} {
+		if err := ioutil.WriteFile(filepath.Join(configDir, file), []byte{}, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	orphans, err := OrphansFind(context.Background(), newOrphanTestRuntime())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{
+		"container:k3d-broken-agent-0",
+		"container:k3d-broken-serverlb",
+		"volume:k3d-broken-images",
+		"volume:k3d-gone-images",
+		"network:k3d-broken",
+		"network:k3d-gone",
+		"file:kubeconfig-gone.yaml",
+		"file:broken",
+		"file:gone.yaml",
+	}
+	if names := orphanNames(orphans); !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected orphans %v, got %v", expected, names)
+	}
+	if orphans[0].Cluster != "broken" || orphans[0].Reason != "cluster 'broken' has no server node" {
+		t.Errorf("unexpected orphan %+v", orphans[0])
+	}
+	if orphans[3].Reason != "cluster 'gone' doesn't exist" {
+		t.Errorf("unexpected orphan %+v", orphans[3])
+	}
+}
+
+func TestOrphansPrune(t *testing.T) {
+	home := useTempConfigDir(t)
+	kubeconfig := filepath.Join(home, ".k3d", "kubeconfig-gone.yaml")
+	if err := os.MkdirAll(filepath.Dir(kubeconfig), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(kubeconfig, []byte{}, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	runtime := newOrphanTestRuntime()
+	orphans, err := OrphansPrune(context.Background(), runtime, k3d.OrphanPruneOpts{DryRun: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(orphans) != 7 || len(runtime.calls) != 0 {
+		t.Errorf("expected the dry-run to report 7 orphans without removing anything, got %v and calls %v", orphanNames(orphans), runtime.calls)
+	}
+
+	runtime.failOn = map[string]error{"DeleteVolume:k3d-gone-images": os.ErrPermission}
+	pruned, err := OrphansPrune(context.Background(), runtime, k3d.OrphanPruneOpts{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pruned) != 6 {
+		t.Errorf("expected 6 pruned resources, got %v", orphanNames(pruned))
+	}
+	expectedCalls := []string{
+		"DeleteNode:k3d-broken-agent-0",
+		"DeleteNode:k3d-broken-serverlb",
+		"DeleteVolume:k3d-broken-images",
+		"DeleteVolume:k3d-gone-images",
+		"DeleteNetwork:k3d-broken",
+		"DeleteNetwork:k3d-gone",
+	}
+	if !reflect.DeepEqual(runtime.calls, expectedCalls) {
+		t.Errorf("expected calls %v, got %v", expectedCalls, runtime.calls)
+	}
+	if _, err := os.Stat(kubeconfig); !os.IsNotExist(err) {
+		t.Errorf("expected the orphaned kubeconfig to be removed")
+	}
+	if _, ok := runtime.volumes["k3d-alive-images"]; !ok || len(runtime.nodes) != 3 {
+		t.Errorf("expected the resources of the intact cluster to remain")
+	}
+}
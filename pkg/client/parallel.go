@@ -0,0 +1,95 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// maxParallelNodeOps limits the number of nodes created, stopped or deleted at the same time, so that large clusters don't overwhelm the runtime
+const maxParallelNodeOps = 8
+
+// NodeOpsError combines the errors of an operation (e.g. 'delete'), which failed for some nodes of a cluster
+type NodeOpsError struct {
+	Op     string
+	Errors map[string]error // node name -> error
+}
+
+func (e *NodeOpsError) Error() string {
+	names := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	problems := make([]string, 0, len(names))
+	for _, name := range names {
+		problems = append(problems, fmt.Sprintf("%s: %v", name, e.Errors[name]))
+	}
+	return fmt.Sprintf("failed to %s %d node(s):\n - %s", e.Op, len(names), strings.Join(problems, "\n - "))
+}
+
+// nodesParallel runs an operation for all nodes concurrently (at most maxParallelNodeOps at a time).
+// Unlike an errgroup, it doesn't stop at the first failure, but tries all nodes and returns the errors of all failed ones in a NodeOpsError.
+// Nodes not processed yet when the context is done fail with the context's error.
+func nodesParallel(ctx context.Context, op string, nodes []*k3d.Node, fn func(ctx context.Context, node *k3d.Node) error) error {
+	opsErr := &NodeOpsError{Op: op, Errors: map[string]error{}}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, maxParallelNodeOps)
+
+	for _, node := range nodes {
+		node := node
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			select {
+			case slots <- struct{}{}:
+				err = ctx.Err() // the context may be done anyway, if a slot got free at the same time
+				if err == nil {
+					err = fn(ctx, node)
+				}
+				<-slots
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+			if err != nil {
+				mu.Lock()
+				opsErr.Errors[node.Name] = err
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(opsErr.Errors) > 0 {
+		return opsErr
+	}
+	return nil
+}
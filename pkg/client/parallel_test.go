@@ -0,0 +1,84 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestNodesParallel(t *testing.T) {
+	nodes := []*k3d.Node{}
+	for i := 0; i < 3*maxParallelNodeOps; i++ {
+		nodes = append(nodes, &k3d.Node{Name: fmt.Sprintf("k3d-test-agent-%d", i)})
+	}
+
+	var running, maxRunning, done int32
+	err := nodesParallel(context.Background(), "delete", nodes, func(_ context.Context, node *k3d.Node) error {
+		current := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if current <= max || atomic.CompareAndSwapInt32(&maxRunning, max, current) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&done, 1)
+		if node.Name == "k3d-test-agent-1" || node.Name == "k3d-test-agent-7" {
+			return errors.New("container is paused")
+		}
+		return nil
+	})
+
+	if done != int32(len(nodes)) {
+		t.Errorf("expected all %d nodes to be processed despite failures, got %d", len(nodes), done)
+	}
+	if maxRunning > maxParallelNodeOps {
+		t.Errorf("expected at most %d concurrent operations, got %d", maxParallelNodeOps, maxRunning)
+	}
+	var opsErr *NodeOpsError
+	if !errors.As(err, &opsErr) || len(opsErr.Errors) != 2 {
+		t.Fatalf("expected a NodeOpsError with 2 failed nodes, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "failed to delete 2 node(s)") || !strings.Contains(err.Error(), "k3d-test-agent-7: container is paused") {
+		t.Errorf("unexpected error message: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	called := false
+	err = nodesParallel(ctx, "stop", nodes[:1], func(context.Context, *k3d.Node) error {
+		called = true
+		return nil
+	})
+	if called || !errors.As(err, &opsErr) || !errors.Is(opsErr.Errors[nodes[0].Name], context.Canceled) {
+		t.Errorf("expected nodes not to be processed after the context is done, got %v (called: %t)", err, called)
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/rancher/k3d/v5/pkg/util"
+)
+
+// nodeStatusPaused is the status the runtime reports for paused node containers
+const nodeStatusPaused = "paused"
+
+// statusAPITimeout is the maximum time to wait for the Kubernetes API when checking whether it's reachable
+var statusAPITimeout = 5 * time.Second
+
+// ClusterPause suspends all processes of a running cluster (cgroup freezer, i.e. without the requirements of the CRIU-based ClusterFreeze),
+// so that it can be resumed within seconds via ClusterUnpause. Nodes that aren't running are skipped.
+// If a node fails to pause, the nodes paused so far are resumed again.
+func ClusterPause(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster) (err error) {
+	unlock, err := ClusterLock(ctx, cluster.Name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cluster, err = ClusterGet(ctx, runtime, cluster)
+	if err != nil {
+		return fmt.Errorf("failed to get cluster: %w", err)
+	}
+
+	// pause the loadbalancer and agents before the servers, so no requests hit paused servers
+	nodes := append(util.FilterNodesByRole(cluster.Nodes, k3d.LoadBalancerRole), util.FilterNodesByRole(cluster.Nodes, k3d.AgentRole)...)
+	nodes = append(nodes, util.FilterNodesByRole(cluster.Nodes, k3d.ServerRole)...)
+
+	paused := []*k3d.Node{}
+	defer func() {
+		if err == nil {
+			return
+		}
+		for i := len(paused) - 1; i >= 0; i-- {
+			if unpauseErr := runtime.UnpauseNode(context.Background(), paused[i]); unpauseErr != nil {
+				l.Log().Warnf("Failed to resume the already paused node '%s': %v", paused[i].Name, unpauseErr)
+			}
+		}
+	}()
+
+	for _, node := range nodes {
+		if !node.State.Running || node.State.Status == nodeStatusPaused {
+			l.Log().Debugf("Node '%s' is not running or already paused, skipping", node.Name)
+			continue
+		}
+		if err := runtime.PauseNode(ctx, node); err != nil {
+			return fmt.Errorf("failed to pause node '%s': %w", node.Name, err)
+		}
+		paused = append(paused, node)
+	}
+	return nil
+}
+
+// ClusterUnpause resumes the nodes of a cluster paused by ClusterPause in reverse order (servers, agents, loadbalancer)
+func ClusterUnpause(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster) error {
+	unlock, err := ClusterLock(ctx, cluster.Name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cluster, err = ClusterGet(ctx, runtime, cluster)
+	if err != nil {
+		return fmt.Errorf("failed to get cluster: %w", err)
+	}
+
+	nodes := append(util.FilterNodesByRole(cluster.Nodes, k3d.ServerRole), util.FilterNodesByRole(cluster.Nodes, k3d.AgentRole)...)
+	nodes = append(nodes, util.FilterNodesByRole(cluster.Nodes, k3d.LoadBalancerRole)...)
+
+	for _, node := range nodes {
+		if node.State.Status != nodeStatusPaused {
+			continue
+		}
+		if err := runtime.UnpauseNode(ctx, node); err != nil {
+			return fmt.Errorf("failed to unpause node '%s': %w", node.Name, err)
+		}
+	}
+	return nil
+}
+
+// ClusterStatus reports the state and start time of the cluster's node containers and whether its Kubernetes API is reachable
+func ClusterStatus(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster) *k3d.ClusterStatus {
+	status := &k3d.ClusterStatus{Cluster: cluster.Name, Nodes: []k3d.NodeStatus{}}
+
+	serverUp := false
+	for _, node := range cluster.Nodes {
+		if node.Role == k3d.RegistryRole {
+			continue
+		}
+		nodeStatus := k3d.NodeStatus{Name: node.Name, Role: node.Role, State: node.State.Status}
+		if node.State.Running {
+			if started, err := time.Parse(time.RFC3339Nano, node.State.Started); err == nil {
+				nodeStatus.Started = started
+			}
+			serverUp = serverUp || (node.Role == k3d.ServerRole && node.State.Status != nodeStatusPaused)
+		}
+		status.Nodes = append(status.Nodes, nodeStatus)
+	}
+	sort.Slice(status.Nodes, func(i, j int) bool { return status.Nodes[i].Name < status.Nodes[j].Name })
+
+	if !serverUp {
+		status.APIError = "no running server node"
+		return status
+	}
+	if err := clusterAPIReachable(ctx, runtime, cluster); err != nil {
+		status.APIError = err.Error()
+		return status
+	}
+	status.APIReachable = true
+	return status
+}
+
+// clusterAPIReachable checks whether the Kubernetes API answers requests via the published API port (replaced in tests)
+var clusterAPIReachable = func(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster) error {
+	ctx, cancel := context.WithTimeout(ctx, statusAPITimeout)
+	defer cancel()
+	client, err := KubeRESTClient(ctx, runtime, cluster, "v1")
+	if err != nil {
+		return err
+	}
+	_, err = client.Get().AbsPath("/readyz").Do(ctx).Raw()
+	return err
+}
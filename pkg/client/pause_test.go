@@ -0,0 +1,123 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestClusterPauseUnpause(t *testing.T) {
+	useTempConfigDir(t)
+
+	runtime := &fakeRuntime{
+		nodes: []*k3d.Node{
+			newFakeNode("test", "k3d-test-server-0", k3d.ServerRole, true),
+			newFakeNode("test", "k3d-test-server-1", k3d.ServerRole, false),
+			newFakeNode("test", "k3d-test-agent-0", k3d.AgentRole, true),
+		},
+	}
+
+	if err := ClusterPause(context.Background(), runtime, &k3d.Cluster{Name: "test"}); err != nil {
+		t.Fatalf("failed to pause cluster: %v", err)
+	}
+	if paused := runtime.callsOf("PauseNode"); !reflect.DeepEqual(paused, []string{"k3d-test-agent-0", "k3d-test-server-0"}) {
+		t.Errorf("expected agents to be paused before servers and stopped nodes to be skipped, got %v", paused)
+	}
+
+	// pausing again doesn't touch the paused nodes
+	if err := ClusterPause(context.Background(), runtime, &k3d.Cluster{Name: "test"}); err != nil {
+		t.Fatalf("failed to pause cluster: %v", err)
+	}
+	if paused := runtime.callsOf("PauseNode"); len(paused) != 2 {
+		t.Errorf("expected paused nodes to be skipped, got %v", paused)
+	}
+
+	if err := ClusterUnpause(context.Background(), runtime, &k3d.Cluster{Name: "test"}); err != nil {
+		t.Fatalf("failed to unpause cluster: %v", err)
+	}
+	if unpaused := runtime.callsOf("UnpauseNode"); !reflect.DeepEqual(unpaused, []string{"k3d-test-server-0", "k3d-test-agent-0"}) {
+		t.Errorf("expected only the paused nodes to be unpaused, servers first, got %v", unpaused)
+	}
+}
+
+func TestClusterPauseRestoresOnFailure(t *testing.T) {
+	useTempConfigDir(t)
+
+	runtime := &fakeRuntime{
+		nodes: []*k3d.Node{
+			newFakeNode("test", "k3d-test-server-0", k3d.ServerRole, true),
+			newFakeNode("test", "k3d-test-agent-0", k3d.AgentRole, true),
+			newFakeNode("test", "k3d-test-agent-1", k3d.AgentRole, true),
+		},
+		failOn: map[string]error{"PauseNode:k3d-test-server-0": errors.New("cgroup freezer unavailable")},
+	}
+
+	if err := ClusterPause(context.Background(), runtime, &k3d.Cluster{Name: "test"}); err == nil {
+		t.Fatalf("expected pausing to fail")
+	}
+	if unpaused := runtime.callsOf("UnpauseNode"); !reflect.DeepEqual(unpaused, []string{"k3d-test-agent-1", "k3d-test-agent-0"}) {
+		t.Errorf("expected the already paused agents to be resumed again, got %v", unpaused)
+	}
+}
+
+func TestClusterStatus(t *testing.T) {
+	oldAPIReachable := clusterAPIReachable
+	defer func() { clusterAPIReachable = oldAPIReachable }()
+	apiErr := errors.New("connection refused")
+	clusterAPIReachable = func(context.Context, runtimes.Runtime, *k3d.Cluster) error { return apiErr }
+
+	server := newFakeNode("test", "k3d-test-server-0", k3d.ServerRole, true)
+	server.State.Status, server.State.Started = "running", "2021-06-01T10:00:00.123456789Z"
+	agent := newFakeNode("test", "k3d-test-agent-0", k3d.AgentRole, false)
+	agent.State.Status, agent.State.Started = "exited", "2021-06-01T09:00:00Z"
+	registry := newFakeNode("", "k3d-registry", k3d.RegistryRole, true)
+	cluster := &k3d.Cluster{Name: "test", Nodes: []*k3d.Node{server, agent, registry}}
+
+	status := ClusterStatus(context.Background(), &fakeRuntime{}, cluster)
+	expected := []k3d.NodeStatus{
+		{Name: "k3d-test-agent-0", Role: k3d.AgentRole, State: "exited"},
+		{Name: "k3d-test-server-0", Role: k3d.ServerRole, State: "running", Started: time.Date(2021, 6, 1, 10, 0, 0, 123456789, time.UTC)},
+	}
+	if !reflect.DeepEqual(status.Nodes, expected) {
+		t.Errorf("expected nodes %+v, got %+v", expected, status.Nodes)
+	}
+	if status.APIReachable || status.APIError != "connection refused" {
+		t.Errorf("expected the API to be unreachable, got %+v", status)
+	}
+
+	apiErr = nil
+	if status := ClusterStatus(context.Background(), &fakeRuntime{}, cluster); !status.APIReachable {
+		t.Errorf("expected the API to be reachable, got %+v", status)
+	}
+
+	server.State.Status = nodeStatusPaused
+	if status := ClusterStatus(context.Background(), &fakeRuntime{}, cluster); status.APIReachable || status.APIError != "no running server node" {
+		t.Errorf("expected the API of a paused cluster not to be checked, got %+v", status)
+	}
+}
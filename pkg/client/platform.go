@@ -0,0 +1,113 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/containerd/containerd/platforms"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	k3drt "github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// checkNodeImagePlatforms fails for node images, which are built for another platform than the requested one
+// or the one the runtime runs on natively (unless DOCKER_DEFAULT_PLATFORM opts into emulation), as such nodes usually crash-loop
+// without any helpful error message.
+// Images that are not present (yet) can't be checked and are skipped, all others are added to the checked set,
+// so that the check can be run before and after the images were pulled.
+func checkNodeImagePlatforms(ctx context.Context, runtime k3drt.Runtime, cluster *k3d.Cluster, checked map[string]struct{}) error {
+	info, err := runtime.Info()
+	if err != nil {
+		l.Log().Debugf("Skipping image platform check: %v", err)
+		return nil
+	}
+
+	osType := info.OSType
+	if osType == "" {
+		osType = "linux"
+	}
+	native := platforms.Normalize(specs.Platform{OS: osType, Architecture: info.Arch})
+
+	for _, node := range cluster.Nodes {
+		if node.Role != k3d.ServerRole && node.Role != k3d.AgentRole {
+			continue
+		}
+		if _, ok := checked[node.Image]; ok {
+			continue
+		}
+
+		imagePlatform, err := runtime.GetImagePlatform(ctx, node.Image)
+		if err != nil {
+			l.Log().Debugf("Skipping platform check of image '%s' for now: %v", node.Image, err)
+			continue
+		}
+		checked[node.Image] = struct{}{}
+
+		if msg := imagePlatformMismatch(imagePlatform, native, info.Platform); msg != "" {
+			return fmt.Errorf("image '%s' (node '%s'): %s", node.Image, node.Name, msg)
+		}
+	}
+
+	return nil
+}
+
+// logRequestedPlatform informs about nodes running under emulation, as requested via DOCKER_DEFAULT_PLATFORM
+func logRequestedPlatform(runtime k3drt.Runtime) {
+	info, err := runtime.Info()
+	if err != nil || info.Platform == "" {
+		return
+	}
+	osType := info.OSType
+	if osType == "" {
+		osType = "linux"
+	}
+	native := platforms.Normalize(specs.Platform{OS: osType, Architecture: info.Arch})
+	if requested, err := platforms.Parse(info.Platform); err == nil && !platforms.Only(native).Match(requested) {
+		l.Log().Infof("Nodes run as '%s' (DOCKER_DEFAULT_PLATFORM) on a '%s' host, which relies on emulation and may be very slow", platforms.Format(requested), platforms.Format(native))
+	}
+}
+
+// imagePlatformMismatch returns a description of the problem, if an image with the given platform doesn't match
+// the requested platform (if set) or the native platform of the runtime, or an empty string if it does.
+func imagePlatformMismatch(imagePlatform string, native specs.Platform, requested string) string {
+	image, err := platforms.Parse(imagePlatform)
+	if err != nil {
+		return ""
+	}
+
+	if requested != "" {
+		req, err := platforms.Parse(requested)
+		if err == nil && !platforms.Only(req).Match(image) {
+			return fmt.Sprintf("it is built for '%s', but DOCKER_DEFAULT_PLATFORM requests '%s': remove the local image, so that the right one gets pulled", platforms.Format(image), platforms.Format(req))
+		}
+		return ""
+	}
+
+	if !platforms.Only(native).Match(image) {
+		return fmt.Sprintf("it is built for '%s', but the container runtime runs on '%s', so the node will most likely crash-loop: pull the image for the right platform (e.g. 'docker pull --platform %s IMAGE') or set DOCKER_DEFAULT_PLATFORM=%s if you want to run it under emulation", platforms.Format(image), platforms.Format(native), platforms.Format(native), platforms.Format(image))
+	}
+
+	return ""
+}
@@ -0,0 +1,88 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestImagePlatformMismatch(t *testing.T) {
+	amd64 := specs.Platform{OS: "linux", Architecture: "amd64"}
+	arm64 := specs.Platform{OS: "linux", Architecture: "arm64"}
+
+	if msg := imagePlatformMismatch("linux/amd64", amd64, ""); msg != "" {
+		t.Errorf("expected no mismatch for native image, got '%s'", msg)
+	}
+	if msg := imagePlatformMismatch("linux/arm/v7", arm64, ""); msg != "" {
+		t.Errorf("expected arm/v7 image to run on arm64, got '%s'", msg)
+	}
+	if msg := imagePlatformMismatch("linux/arm64", amd64, ""); msg == "" {
+		t.Errorf("expected mismatch for arm64 image on amd64")
+	}
+	if msg := imagePlatformMismatch("linux/arm64", amd64, "linux/arm64"); msg != "" {
+		t.Errorf("expected no mismatch for explicitly requested platform, got '%s'", msg)
+	}
+	if msg := imagePlatformMismatch("linux/amd64", amd64, "linux/arm64"); msg == "" {
+		t.Errorf("expected mismatch for image not matching the requested platform")
+	}
+}
+
+func TestCheckNodeImagePlatforms(t *testing.T) {
+	cluster := &k3d.Cluster{Nodes: []*k3d.Node{
+		{Name: "k3d-test-server-0", Role: k3d.ServerRole, Image: "rancher/k3s:arm64"},
+		{Name: "k3d-test-agent-0", Role: k3d.AgentRole, Image: "rancher/k3s:missing"},
+		{Name: "k3d-test-serverlb", Role: k3d.LoadBalancerRole, Image: "ghcr.io/rancher/k3d-proxy"},
+	}}
+
+	// an image built for another platform fails the check, unless it's explicitly requested
+	runtime := &fakeRuntime{arch: "amd64", images: map[string]string{"rancher/k3s:arm64": "linux/arm64"}}
+	checked := map[string]struct{}{}
+	if err := checkNodeImagePlatforms(context.Background(), runtime, cluster, checked); err == nil {
+		t.Errorf("expected an error for an arm64 image on an amd64 host")
+	}
+
+	runtime.platform = "linux/arm64"
+	checked = map[string]struct{}{}
+	if err := checkNodeImagePlatforms(context.Background(), runtime, cluster, checked); err != nil {
+		t.Errorf("expected no error for an image matching DOCKER_DEFAULT_PLATFORM, got %v", err)
+	}
+
+	// images that are not present can't be checked yet, but are checked once they've been pulled
+	if _, ok := checked["rancher/k3s:missing"]; ok {
+		t.Errorf("expected the missing image not to be marked as checked")
+	}
+	runtime.images["rancher/k3s:missing"] = "linux/amd64"
+	if err := checkNodeImagePlatforms(context.Background(), runtime, cluster, checked); err == nil {
+		t.Errorf("expected an error for the pulled amd64 image not matching DOCKER_DEFAULT_PLATFORM")
+	}
+
+	// the first check stops at the mismatch, the loadbalancer image is never checked and already checked images are not inspected again
+	expected := []string{"rancher/k3s:arm64", "rancher/k3s:arm64", "rancher/k3s:missing", "rancher/k3s:missing"}
+	if calls := runtime.callsOf("GetImagePlatform"); !reflect.DeepEqual(calls, expected) {
+		t.Errorf("expected image inspections %v, got %v", expected, calls)
+	}
+}
@@ -0,0 +1,261 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/rancher/k3d/v5/pkg/util"
+	"gopkg.in/yaml.v2"
+)
+
+// ErrPoolExhausted is returned when all clusters of a pool are leased (or being re-created)
+var ErrPoolExhausted = errors.New("no free cluster in pool")
+
+// ErrPoolNotFound is returned for operations on pools that don't exist
+var ErrPoolNotFound = errors.New("pool not found")
+
+// PoolClusterName returns the name of the i-th cluster of a pool
+func PoolClusterName(pool string, i int) string {
+	return fmt.Sprintf("%s-%d", pool, i)
+}
+
+// poolStatePath returns the path of the file holding the state of a pool ($HOME/.k3d/pools/<name>.yaml)
+func poolStatePath(name string) (string, error) {
+	configDir, err := util.GetConfigDirOrCreate()
+	if err != nil {
+		return "", err
+	}
+	poolDir := filepath.Join(configDir, "pools")
+	if err := os.MkdirAll(poolDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create pool directory '%s': %w", poolDir, err)
+	}
+	return filepath.Join(poolDir, name+".yaml"), nil
+}
+
+// poolRead reads the state of a pool (without locking)
+func poolRead(path string) (*k3d.Pool, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrPoolNotFound
+		}
+		return nil, fmt.Errorf("failed to read pool state '%s': %w", path, err)
+	}
+	pool := &k3d.Pool{}
+	if err := yaml.Unmarshal(content, pool); err != nil {
+		return nil, fmt.Errorf("failed to parse pool state '%s': %w", path, err)
+	}
+	return pool, nil
+}
+
+// poolWrite replaces the state of a pool (without locking)
+func poolWrite(path string, pool *k3d.Pool) error {
+	content, err := yaml.Marshal(pool)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pool state: %w", err)
+	}
+	// write to a temporary file first, so that readers never see a partially written state
+	tmpPath := path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, content, 0600); err != nil {
+		return fmt.Errorf("failed to write pool state: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to write pool state: %w", err)
+	}
+	return nil
+}
+
+// PoolGet returns the current state of a pool
+func PoolGet(name string) (*k3d.Pool, error) {
+	path, err := poolStatePath(name)
+	if err != nil {
+		return nil, err
+	}
+	return poolRead(path)
+}
+
+// PoolList returns the state of all pools
+func PoolList() ([]*k3d.Pool, error) {
+	path, err := poolStatePath("")
+	if err != nil {
+		return nil, err
+	}
+	entries, err := ioutil.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pools: %w", err)
+	}
+	pools := []*k3d.Pool{}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".yaml" {
+			continue
+		}
+		pool, err := PoolGet(strings.TrimSuffix(entry.Name(), ".yaml"))
+		if err != nil {
+			return nil, err
+		}
+		pools = append(pools, pool)
+	}
+	sort.Slice(pools, func(i, j int) bool { return pools[i].Name < pools[j].Name })
+	return pools, nil
+}
+
+// PoolUpdate modifies the state of a pool while holding its lock, so that concurrent k3d processes (e.g. parallel CI jobs) don't interfere
+// If the pool doesn't exist yet and create is set, update gets an empty pool with only the name set.
+func PoolUpdate(ctx context.Context, name string, create bool, update func(pool *k3d.Pool) error) (*k3d.Pool, error) {
+	path, err := poolStatePath(name)
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := util.LockFile(ctx, path+".lock")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	pool, err := poolRead(path)
+	if err != nil {
+		if !errors.Is(err, ErrPoolNotFound) || !create {
+			return nil, err
+		}
+		pool = &k3d.Pool{Name: name}
+	}
+
+	if err := update(pool); err != nil {
+		return nil, err
+	}
+
+	if err := poolWrite(path, pool); err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+// PoolRemove deletes the state of a pool (but not its clusters)
+func PoolRemove(ctx context.Context, name string) error {
+	path, err := poolStatePath(name)
+	if err != nil {
+		return err
+	}
+	unlock, err := util.LockFile(ctx, path+".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrPoolNotFound
+		}
+		return fmt.Errorf("failed to remove pool state: %w", err)
+	}
+	return nil
+}
+
+// PoolAcquire leases a free cluster of a pool to the given holder
+func PoolAcquire(ctx context.Context, name string, holder string) (*k3d.PoolCluster, error) {
+	var acquired *k3d.PoolCluster
+	_, err := PoolUpdate(ctx, name, false, func(pool *k3d.Pool) error {
+		for _, cluster := range pool.Clusters {
+			if cluster.State != k3d.PoolClusterStateFree {
+				continue
+			}
+			now := time.Now().UTC()
+			cluster.State = k3d.PoolClusterStateLeased
+			cluster.Holder = holder
+			cluster.AcquiredAt = &now
+			acquired = cluster
+			return nil
+		}
+		return fmt.Errorf("%w '%s' (%d clusters)", ErrPoolExhausted, name, len(pool.Clusters))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return acquired, nil
+}
+
+// PoolSetClusterState changes the state of a cluster of a pool (dropping the lease)
+// If the cluster is not part of the pool yet, it is added.
+func PoolSetClusterState(ctx context.Context, name string, clusterName string, state k3d.PoolClusterState) error {
+	_, err := PoolUpdate(ctx, name, false, func(pool *k3d.Pool) error {
+		for _, cluster := range pool.Clusters {
+			if cluster.Name == clusterName {
+				cluster.State = state
+				cluster.Holder = ""
+				cluster.AcquiredAt = nil
+				return nil
+			}
+		}
+		pool.Clusters = append(pool.Clusters, &k3d.PoolCluster{Name: clusterName, State: state})
+		return nil
+	})
+	return err
+}
+
+// PoolRemoveCluster drops a cluster from the state of a pool (e.g. when its re-creation failed)
+func PoolRemoveCluster(ctx context.Context, name string, clusterName string) error {
+	_, err := PoolUpdate(ctx, name, false, func(pool *k3d.Pool) error {
+		clusters := []*k3d.PoolCluster{}
+		for _, cluster := range pool.Clusters {
+			if cluster.Name != clusterName {
+				clusters = append(clusters, cluster)
+			}
+		}
+		pool.Clusters = clusters
+		return nil
+	})
+	return err
+}
+
+// PoolRelease ends the lease of a cluster of a pool
+// The cluster is either free right away (reuse) or marked for re-creation, which is up to the caller.
+func PoolRelease(ctx context.Context, name string, clusterName string, reuse bool) error {
+	_, err := PoolUpdate(ctx, name, false, func(pool *k3d.Pool) error {
+		for _, cluster := range pool.Clusters {
+			if cluster.Name != clusterName {
+				continue
+			}
+			if cluster.State != k3d.PoolClusterStateLeased {
+				return fmt.Errorf("cluster '%s' of pool '%s' is not leased (state: %s)", clusterName, pool.Name, cluster.State)
+			}
+			cluster.State = k3d.PoolClusterStateCreating
+			if reuse {
+				cluster.State = k3d.PoolClusterStateFree
+			}
+			cluster.Holder = ""
+			cluster.AcquiredAt = nil
+			return nil
+		}
+		return fmt.Errorf("cluster '%s' is not part of pool '%s'", clusterName, pool.Name)
+	})
+	return err
+}
@@ -0,0 +1,160 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// newTestPool creates a pool with the given clusters (all free) in a temporary config dir
+func newTestPool(t *testing.T, name string, clusters int) {
+	t.Helper()
+	useTempConfigDir(t)
+	_, err := PoolUpdate(context.Background(), name, true, func(pool *k3d.Pool) error {
+		for i := 0; i < clusters; i++ {
+			pool.Clusters = append(pool.Clusters, &k3d.PoolCluster{Name: PoolClusterName(name, i), State: k3d.PoolClusterStateFree})
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+}
+
+func TestPoolAcquireExhausted(t *testing.T) {
+	ctx := context.Background()
+	newTestPool(t, "ci", 2)
+
+	first, err := PoolAcquire(ctx, "ci", "job-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := PoolAcquire(ctx, "ci", "job-2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first.Name == second.Name {
+		t.Fatalf("expected different clusters, got '%s' twice", first.Name)
+	}
+	if second.State != k3d.PoolClusterStateLeased || second.Holder != "job-2" || second.AcquiredAt == nil {
+		t.Errorf("expected a lease for job-2, got %+v", second)
+	}
+
+	if _, err := PoolAcquire(ctx, "ci", "job-3"); !errors.Is(err, ErrPoolExhausted) {
+		t.Errorf("expected ErrPoolExhausted, got %v", err)
+	}
+
+	// the failed acquisition must not have changed the state
+	pool, err := PoolGet("ci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cluster := range pool.Clusters {
+		if cluster.State != k3d.PoolClusterStateLeased || cluster.Holder == "job-3" {
+			t.Errorf("unexpected state of cluster '%s': %+v", cluster.Name, cluster)
+		}
+	}
+
+	if _, err := PoolAcquire(ctx, "unknown", "job-1"); !errors.Is(err, ErrPoolNotFound) {
+		t.Errorf("expected ErrPoolNotFound, got %v", err)
+	}
+}
+
+func TestPoolRelease(t *testing.T) {
+	ctx := context.Background()
+	newTestPool(t, "ci", 2)
+
+	leased, err := PoolAcquire(ctx, "ci", "job-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// releasing a cluster that is not leased is an error and leaves the state untouched
+	if err := PoolRelease(ctx, "ci", PoolClusterName("ci", 1), true); err == nil {
+		t.Errorf("expected an error releasing a free cluster")
+	}
+	if err := PoolRelease(ctx, "ci", "ci-42", true); err == nil {
+		t.Errorf("expected an error releasing a cluster that's not part of the pool")
+	}
+
+	if err := PoolRelease(ctx, "ci", leased.Name, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pool, err := PoolGet("ci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	states := map[string]k3d.PoolClusterState{}
+	for _, cluster := range pool.Clusters {
+		states[cluster.Name] = cluster.State
+		if cluster.Holder != "" || cluster.AcquiredAt != nil {
+			t.Errorf("expected no lease on cluster '%s', got %+v", cluster.Name, cluster)
+		}
+	}
+	if states[leased.Name] != k3d.PoolClusterStateCreating || states[PoolClusterName("ci", 1)] != k3d.PoolClusterStateFree {
+		t.Errorf("expected the released cluster to be re-created and the other one to stay free, got %v", states)
+	}
+
+	// releasing it twice is an error as well
+	if err := PoolRelease(ctx, "ci", leased.Name, true); err == nil {
+		t.Errorf("expected an error releasing a cluster twice")
+	}
+}
+
+func TestPoolSetClusterState(t *testing.T) {
+	ctx := context.Background()
+	newTestPool(t, "ci", 1)
+
+	if _, err := PoolAcquire(ctx, "ci", "job-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := PoolSetClusterState(ctx, "ci", PoolClusterName("ci", 0), k3d.PoolClusterStateFree); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// unknown clusters are added to the pool
+	if err := PoolSetClusterState(ctx, "ci", "ci-1", k3d.PoolClusterStateCreating); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pool, err := PoolGet("ci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pool.Clusters) != 2 {
+		t.Fatalf("expected 2 clusters, got %d", len(pool.Clusters))
+	}
+	if c := pool.Clusters[0]; c.State != k3d.PoolClusterStateFree || c.Holder != "" || c.AcquiredAt != nil {
+		t.Errorf("expected the lease of the first cluster to be dropped, got %+v", c)
+	}
+	if c := pool.Clusters[1]; c.Name != "ci-1" || c.State != k3d.PoolClusterStateCreating {
+		t.Errorf("expected the unknown cluster to be added, got %+v", c)
+	}
+
+	if err := PoolSetClusterState(ctx, "unknown", "x", k3d.PoolClusterStateFree); !errors.Is(err, ErrPoolNotFound) {
+		t.Errorf("expected ErrPoolNotFound for an unknown pool, got %v", err)
+	}
+}
@@ -25,6 +25,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/docker/go-connections/nat"
@@ -61,6 +64,10 @@ func TransformPorts(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.
 			}
 		}
 
+		if err := validatePortRanges(portWithNodeFilters.Port); err != nil {
+			return err
+		}
+
 		filteredNodes, err := util.FilterNodesWithSuffix(nodeList, portWithNodeFilters.NodeFilters)
 		if err != nil {
 			return err
@@ -76,6 +83,12 @@ func TransformPorts(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.
 				if cluster.ServerLoadBalancer == nil {
 					return fmt.Errorf("port-mapping of type 'proxy' specified, but loadbalancer is disabled")
 				}
+				for _, pm := range portmappings {
+					// the loadbalancer (nginx stream module) can only proxy TCP and UDP
+					if pm.Port.Proto() == "sctp" {
+						return fmt.Errorf("the loadbalancer can't proxy SCTP port '%s': map it to a single node directly instead (e.g. '%s@agent:0:direct')", pm.Port, portWithNodeFilters.Port)
+					}
+				}
 				if err := addPortMappings(cluster.ServerLoadBalancer.Node, portmappings); err != nil {
 					return err
 				}
@@ -86,7 +99,10 @@ func TransformPorts(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.
 				}
 			} else if suffix == "direct" {
 				if len(nodes) > 1 {
-					return fmt.Errorf("error: cannot apply a direct port-mapping (%s) to more than one node", portmappings)
+					if err := assignDirectHostPorts(cluster, nodes, portmappings); err != nil {
+						return err
+					}
+					continue
 				}
 				for _, node := range nodes {
 					if err := addPortMappings(node, portmappings); err != nil {
@@ -112,6 +128,164 @@ func TransformPorts(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.
 	return nil
 }
 
+// assignDirectHostPorts maps a single container port directly on multiple nodes, giving each node its own host port:
+// the first free ports counting upwards from the given host port, or ports picked by the OS if none is given.
+// Only the local host is checked for free ports, so they may be taken already on a remote docker host.
+func assignDirectHostPorts(cluster *k3d.Cluster, nodes []*k3d.Node, portmappings []nat.PortMapping) error {
+	if len(portmappings) != 1 || strings.Contains(portmappings[0].Binding.HostPort, "-") {
+		return fmt.Errorf("error: cannot apply a direct port-mapping with port ranges (%s) to more than one node", portmappings)
+	}
+	pm := portmappings[0]
+
+	usedHostPorts := map[int]bool{}
+	for _, node := range cluster.Nodes {
+		for _, bindings := range node.Ports {
+			for _, binding := range bindings {
+				if port, err := strconv.Atoi(binding.HostPort); err == nil {
+					usedHostPorts[port] = true
+				}
+			}
+		}
+	}
+
+	nextPort := 0
+	if pm.Binding.HostPort != "" {
+		port, err := strconv.Atoi(pm.Binding.HostPort)
+		if err != nil {
+			return fmt.Errorf("invalid host port '%s': %w", pm.Binding.HostPort, err)
+		}
+		nextPort = port
+	}
+
+	for _, node := range nodes {
+		hostPort := 0
+		if nextPort == 0 {
+			for hostPort == 0 || usedHostPorts[hostPort] {
+				port, err := util.GetFreePort()
+				if err != nil {
+					return fmt.Errorf("failed to get a free host port for port %s of node '%s': %w", pm.Port, node.Name, err)
+				}
+				hostPort = port
+			}
+		} else {
+			for nextPort <= 65535 && (usedHostPorts[nextPort] || !util.IsHostPortFree(pm.Binding.HostIP, nextPort, pm.Port.Proto())) {
+				nextPort++
+			}
+			if nextPort > 65535 {
+				return fmt.Errorf("no free host port left for port %s of node '%s'", pm.Port, node.Name)
+			}
+			hostPort = nextPort
+			nextPort++
+		}
+		usedHostPorts[hostPort] = true
+
+		binding := pm.Binding
+		binding.HostPort = strconv.Itoa(hostPort)
+		if err := addPortMappings(node, []nat.PortMapping{{Port: pm.Port, Binding: binding}}); err != nil {
+			return err
+		}
+		l.Log().Infof("Mapping port %s of node '%s' to host port %d", pm.Port, node.Name, hostPort)
+	}
+	return nil
+}
+
+// PortMapping is a port of a node published on the host
+type PortMapping struct {
+	Node          string
+	HostIP        string
+	HostPort      string // empty, if docker picks a random port
+	ContainerPort nat.Port
+}
+
+func (m PortMapping) String() string {
+	return fmt.Sprintf("%s -> %s:%s", m.hostAddress(), m.Node, m.ContainerPort)
+}
+
+// Short returns the mapping in the compact form used by docker ps, e.g. 0.0.0.0:8080->80/tcp
+func (m PortMapping) Short() string {
+	return fmt.Sprintf("%s->%s", m.hostAddress(), m.ContainerPort)
+}
+
+func (m PortMapping) hostAddress() string {
+	hostIP := m.HostIP
+	if hostIP == "" {
+		hostIP = "0.0.0.0"
+	}
+	hostPort := m.HostPort
+	if hostPort == "" || hostPort == "0" {
+		hostPort = "random"
+	}
+	return net.JoinHostPort(hostIP, hostPort)
+}
+
+// ClusterPortMappings lists the ports published by the nodes of a cluster, sorted by node and container port
+func ClusterPortMappings(cluster *k3d.Cluster) []PortMapping {
+	mappings := []PortMapping{}
+	for _, node := range cluster.Nodes {
+		mappings = append(mappings, NodePortMappings(node)...)
+	}
+	sortPortMappings(mappings)
+	return mappings
+}
+
+// NodePortMappings lists the ports published by a node, sorted by container port.
+// While the node is running, the host ports actually bound by the runtime are preferred over the configured ones.
+func NodePortMappings(node *k3d.Node) []PortMapping {
+	ports := node.Ports
+	if len(node.State.PublishedPorts) > 0 {
+		ports = node.State.PublishedPorts
+	}
+	mappings := []PortMapping{}
+	for port, bindings := range ports {
+		for _, binding := range bindings {
+			mappings = append(mappings, PortMapping{Node: node.Name, HostIP: binding.HostIP, HostPort: binding.HostPort, ContainerPort: port})
+		}
+	}
+	sortPortMappings(mappings)
+	return mappings
+}
+
+func sortPortMappings(mappings []PortMapping) {
+	sort.SliceStable(mappings, func(i, j int) bool {
+		if mappings[i].Node != mappings[j].Node {
+			return mappings[i].Node < mappings[j].Node
+		}
+		if mappings[i].ContainerPort.Int() != mappings[j].ContainerPort.Int() {
+			return mappings[i].ContainerPort.Int() < mappings[j].ContainerPort.Int()
+		}
+		return mappings[i].ContainerPort.Proto() < mappings[j].ContainerPort.Proto()
+	})
+}
+
+// portRangeWarnThreshold is the number of ports in a single mapping, above which creating the containers gets noticeably slower
+const portRangeWarnThreshold = 100
+
+// validatePortRanges checks that the host and container port ranges of a port spec ([HOST:][HOSTPORT:]CONTAINERPORT[/PROTOCOL]) have the same length.
+// A host port range with a single container port is fine though: docker picks a free host port from the range.
+func validatePortRanges(portSpec string) error {
+	parts := strings.Split(strings.SplitN(portSpec, "/", 2)[0], ":")
+	containerStart, containerEnd, err := nat.ParsePortRange(parts[len(parts)-1])
+	if err != nil {
+		return fmt.Errorf("error parsing port spec '%s': invalid container port (range): %w", portSpec, err)
+	}
+	containerPorts := containerEnd - containerStart + 1
+
+	if len(parts) > 1 && parts[len(parts)-2] != "" {
+		hostStart, hostEnd, err := nat.ParsePortRange(parts[len(parts)-2])
+		if err != nil {
+			return fmt.Errorf("error parsing port spec '%s': invalid host port (range): %w", portSpec, err)
+		}
+		if hostPorts := hostEnd - hostStart + 1; containerPorts > 1 && hostPorts != containerPorts {
+			return fmt.Errorf("error parsing port spec '%s': host port range (%d ports) and container port range (%d ports) must have the same length", portSpec, hostPorts, containerPorts)
+		}
+	}
+
+	if containerPorts > portRangeWarnThreshold {
+		l.Log().Warnf("Port mapping '%s' maps %d ports: docker creates a proxy process and iptables rules per port, so this may take a long time", portSpec, containerPorts)
+	}
+	return nil
+}
+
 func addPortMappings(node *k3d.Node, portmappings []nat.PortMapping) error {
 
 	if node.Ports == nil {
@@ -0,0 +1,210 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/docker/go-connections/nat"
+	config "github.com/rancher/k3d/v5/pkg/config/v1alpha3"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestTransformPortsProtocols(t *testing.T) {
+	newCluster := func() *k3d.Cluster {
+		lb := k3d.NewLoadbalancer()
+		lb.Node.Name = "k3d-test-serverlb"
+		return &k3d.Cluster{
+			Name:               "test",
+			ServerLoadBalancer: lb,
+			Nodes: []*k3d.Node{
+				{Name: "k3d-test-server-0", Role: k3d.ServerRole},
+				{Name: "k3d-test-agent-0", Role: k3d.AgentRole},
+				lb.Node,
+			},
+		}
+	}
+
+	cluster := newCluster()
+	ports := []config.PortWithNodeFilters{
+		{Port: "5353:30053/udp", NodeFilters: []string{"agent:0"}},
+		{Port: "9999:30999/sctp", NodeFilters: []string{"agent:0:direct"}},
+	}
+	if err := TransformPorts(context.Background(), nil, cluster, ports); err != nil {
+		t.Fatal(err)
+	}
+
+	if bindings := cluster.ServerLoadBalancer.Node.Ports[nat.Port("30053/udp")]; !reflect.DeepEqual(bindings, []nat.PortBinding{{HostPort: "5353"}}) {
+		t.Errorf("expected the UDP port to be published on the loadbalancer, got %v", cluster.ServerLoadBalancer.Node.Ports)
+	}
+	if targets := cluster.ServerLoadBalancer.Config.Ports["30053.udp"]; !reflect.DeepEqual(targets, []string{"k3d-test-agent-0"}) {
+		t.Errorf("expected the loadbalancer to proxy the UDP port to the agent, got %v", cluster.ServerLoadBalancer.Config.Ports)
+	}
+	if bindings := cluster.Nodes[1].Ports[nat.Port("30999/sctp")]; !reflect.DeepEqual(bindings, []nat.PortBinding{{HostPort: "9999"}}) {
+		t.Errorf("expected the SCTP port to be published on the agent directly, got %v", cluster.Nodes[1].Ports)
+	}
+
+	// the loadbalancer can't proxy SCTP
+	err := TransformPorts(context.Background(), nil, newCluster(), []config.PortWithNodeFilters{{Port: "9999:30999/sctp", NodeFilters: []string{"agent:0"}}})
+	if err == nil || !strings.Contains(err.Error(), "direct") {
+		t.Errorf("expected an error suggesting a direct mapping for SCTP via the loadbalancer, got %v", err)
+	}
+}
+
+func TestTransformPortsRanges(t *testing.T) {
+	cluster := &k3d.Cluster{
+		Name: "test",
+		Nodes: []*k3d.Node{
+			{Name: "k3d-test-server-0", Role: k3d.ServerRole},
+		},
+	}
+	ports := []config.PortWithNodeFilters{{Port: "127.0.0.1:8000-8010:9000-9010", NodeFilters: []string{"server:0:direct"}}}
+	if err := TransformPorts(context.Background(), nil, cluster, ports); err != nil {
+		t.Fatal(err)
+	}
+	if len(cluster.Nodes[0].Ports) != 11 {
+		t.Errorf("expected 11 port mappings, got %d", len(cluster.Nodes[0].Ports))
+	}
+	if bindings := cluster.Nodes[0].Ports[nat.Port("9005/tcp")]; !reflect.DeepEqual(bindings, []nat.PortBinding{{HostIP: "127.0.0.1", HostPort: "8005"}}) {
+		t.Errorf("expected container port 9005 to be mapped to host port 8005, got %v", bindings)
+	}
+
+	for spec, valid := range map[string]bool{
+		"8000-8010:8000-8010":     true,
+		"8000-8010:80":            true, // docker picks a free host port from the range
+		"8000-8005:8000-8010/udp": false,
+		"8000-8010:8000-8005":     false,
+		"8000-7000:80":            false,
+	} {
+		err := validatePortRanges(spec)
+		if valid && err != nil {
+			t.Errorf("%s: unexpected error: %v", spec, err)
+		} else if !valid && err == nil {
+			t.Errorf("%s: expected an error", spec)
+		}
+	}
+}
+
+func TestTransformPortsDirectOnMultipleNodes(t *testing.T) {
+	// occupy a port, which must be skipped
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	taken := listener.Addr().(*net.TCPAddr).Port
+
+	cluster := &k3d.Cluster{
+		Name: "test",
+		Nodes: []*k3d.Node{
+			{Name: "k3d-test-server-0", Role: k3d.ServerRole},
+			{Name: "k3d-test-agent-0", Role: k3d.AgentRole},
+			{Name: "k3d-test-agent-1", Role: k3d.AgentRole},
+		},
+	}
+	ports := []config.PortWithNodeFilters{
+		{Port: fmt.Sprintf("127.0.0.1:%d:9100", taken), NodeFilters: []string{"agent:*:direct"}},
+		{Port: "80", NodeFilters: []string{"agent:*:direct"}},
+	}
+	if err := TransformPorts(context.Background(), nil, cluster, ports); err != nil {
+		t.Fatal(err)
+	}
+
+	agent0 := cluster.Nodes[1].Ports[nat.Port("9100/tcp")]
+	agent1 := cluster.Nodes[2].Ports[nat.Port("9100/tcp")]
+	if len(agent0) != 1 || len(agent1) != 1 {
+		t.Fatalf("expected port 9100 to be mapped on both agents, got %v and %v", cluster.Nodes[1].Ports, cluster.Nodes[2].Ports)
+	}
+	port0, _ := strconv.Atoi(agent0[0].HostPort)
+	port1, _ := strconv.Atoi(agent1[0].HostPort)
+	if port0 <= taken || port1 <= port0 {
+		t.Errorf("expected free host ports counting upwards from the taken port %d, got %d and %d", taken, port0, port1)
+	}
+
+	random0 := cluster.Nodes[1].Ports[nat.Port("80/tcp")]
+	random1 := cluster.Nodes[2].Ports[nat.Port("80/tcp")]
+	if len(random0) != 1 || len(random1) != 1 || random0[0].HostPort == "" || random0[0].HostPort == random1[0].HostPort {
+		t.Errorf("expected distinct free host ports for port 80, got %v and %v", random0, random1)
+	}
+
+	mappings := ClusterPortMappings(cluster)
+	if len(mappings) != 4 || mappings[0].Node != "k3d-test-agent-0" || mappings[0].ContainerPort != "80/tcp" {
+		t.Errorf("expected the mappings sorted by node and port, got %v", mappings)
+	}
+	if expected := fmt.Sprintf("127.0.0.1:%d -> k3d-test-agent-0:9100/tcp", port0); mappings[1].String() != expected {
+		t.Errorf("expected %q, got %q", expected, mappings[1].String())
+	}
+
+	// port ranges can't be spread across nodes
+	if err := TransformPorts(context.Background(), nil, cluster, []config.PortWithNodeFilters{{Port: "8000-8001:8000-8001", NodeFilters: []string{"agent:*:direct"}}}); err == nil {
+		t.Errorf("expected an error for a direct port range on multiple nodes")
+	}
+}
+
+func TestLoadbalancerRemovePort(t *testing.T) {
+	lb := &k3d.Loadbalancer{
+		Node: &k3d.Node{Role: k3d.LoadBalancerRole, Ports: nat.PortMap{
+			"80/tcp":  {{HostIP: "0.0.0.0", HostPort: "8080"}, {HostIP: "0.0.0.0", HostPort: "8081"}},
+			"443/tcp": {{HostIP: "0.0.0.0", HostPort: "8443"}},
+		}},
+		Config: &k3d.LoadbalancerConfig{Ports: map[string][]string{
+			"80.tcp":  {"k3d-test-agent-0"},
+			"443.tcp": {"k3d-test-agent-0"},
+		}},
+	}
+	parse := func(spec string) nat.PortMapping {
+		pms, err := nat.ParsePortSpec(spec)
+		if err != nil || len(pms) != 1 {
+			t.Fatalf("failed to parse port spec '%s': %v", spec, err)
+		}
+		return pms[0]
+	}
+
+	if loadbalancerRemovePort(lb, parse("9090:80")) {
+		t.Errorf("expected no match for a host port that isn't mapped")
+	}
+	if !loadbalancerRemovePort(lb, parse("8080:80")) {
+		t.Fatalf("expected the mapping 8080:80 to be removed")
+	}
+	if bindings := lb.Node.Ports["80/tcp"]; len(bindings) != 1 || bindings[0].HostPort != "8081" {
+		t.Errorf("expected only the mapping to host port 8081 to be left, got %+v", bindings)
+	}
+	if _, ok := lb.Config.Ports["80.tcp"]; !ok {
+		t.Errorf("expected port 80 to stay in the loadbalancer config while it's still mapped")
+	}
+
+	if !loadbalancerRemovePort(lb, parse("443")) {
+		t.Fatalf("expected the mapping of port 443 to be removed")
+	}
+	if _, ok := lb.Node.Ports["443/tcp"]; ok {
+		t.Errorf("expected port 443 to be removed from the loadbalancer node")
+	}
+	if _, ok := lb.Config.Ports["443.tcp"]; ok {
+		t.Errorf("expected port 443 to be removed from the loadbalancer config")
+	}
+}
@@ -0,0 +1,174 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// ClusterWaitForReadiness waits until the Kubernetes API reports to be ready (/readyz) when accessed via the published API port
+// and afterwards until all server and agent nodes registered and are Ready.
+// The timeout applies to each of both phases separately (0 means no timeout apart from the one of the given context).
+func ClusterWaitForReadiness(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster, phaseTimeout time.Duration) error {
+	client, err := KubeRESTClient(ctx, runtime, cluster, "v1")
+	if err != nil {
+		return err
+	}
+
+	l.Log().Infoln("Waiting for the Kubernetes API to be ready...")
+	if err := waitForReadinessPhase(ctx, phaseTimeout, func(ctx context.Context) (bool, string) {
+		// verbose, so that the response lists the single checks, which are the best hint at what's wrong if it doesn't get ready
+		body, err := client.Get().AbsPath("/readyz").Param("verbose", "").Do(ctx).Raw()
+		if err == nil {
+			return true, ""
+		}
+		if failed := readyzFailedChecks(string(body)); len(failed) > 0 {
+			return false, fmt.Sprintf("failed checks: %s", strings.Join(failed, ", "))
+		}
+		return false, err.Error()
+	}); err != nil {
+		return fmt.Errorf("Kubernetes API of cluster '%s' not ready: %w", cluster.Name, err)
+	}
+
+	expected := []string{}
+	for _, node := range cluster.Nodes {
+		if node.Role == k3d.ServerRole || node.Role == k3d.AgentRole {
+			expected = append(expected, node.Name)
+		}
+	}
+
+	l.Log().Infof("Waiting for %d node(s) to be Ready...", len(expected))
+	if err := waitForReadinessPhase(ctx, phaseTimeout, func(ctx context.Context) (bool, string) {
+		raw, err := client.Get().Resource("nodes").Do(ctx).Raw()
+		if err != nil {
+			return false, err.Error()
+		}
+		notReady, err := nodesNotReady(raw, expected)
+		if err != nil {
+			return false, err.Error()
+		}
+		return len(notReady) == 0, strings.Join(notReady, "\n")
+	}); err != nil {
+		return fmt.Errorf("nodes of cluster '%s' not ready: %w", cluster.Name, err)
+	}
+
+	return nil
+}
+
+// waitForReadinessPhase polls the given check until it reports to be ready, returning the diagnostics of the last check if it didn't within the timeout
+func waitForReadinessPhase(ctx context.Context, timeout time.Duration, check func(context.Context) (bool, string)) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	for {
+		ready, diagnostics := check(ctx)
+		if ready {
+			return nil
+		}
+		l.Log().Tracef("Not ready yet: %s", diagnostics)
+
+		select {
+		case <-ctx.Done():
+			if diagnostics == "" {
+				return ctx.Err()
+			}
+			return fmt.Errorf("%w:\n%s", ctx.Err(), diagnostics)
+		case <-time.After(WaitPollInterval):
+		}
+	}
+}
+
+// readyzFailedChecks returns the names of the failed checks listed in a verbose /readyz response (lines like '[-]etcd failed: reason withheld')
+func readyzFailedChecks(body string) []string {
+	failed := []string{}
+	for _, line := range strings.Split(body, "\n") {
+		if fields := strings.Fields(strings.TrimPrefix(line, "[-]")); strings.HasPrefix(line, "[-]") && len(fields) > 0 {
+			failed = append(failed, fields[0])
+		}
+	}
+	return failed
+}
+
+// nodeList is the part of a Kubernetes NodeList we need to check the readiness of the nodes
+type nodeList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Status struct {
+			Conditions []struct {
+				Type    string `json:"type"`
+				Status  string `json:"status"`
+				Reason  string `json:"reason"`
+				Message string `json:"message"`
+			} `json:"conditions"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// nodesNotReady returns a description of each expected node which didn't register yet or isn't Ready, given the raw JSON NodeList
+func nodesNotReady(raw []byte, expected []string) ([]string, error) {
+	list := nodeList{}
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal node list: %w", err)
+	}
+
+	notReady := map[string]string{}
+	for _, name := range expected {
+		notReady[name] = "not registered"
+	}
+	for _, item := range list.Items {
+		if _, ok := notReady[item.Metadata.Name]; !ok {
+			continue // e.g. virtual workers
+		}
+		notReady[item.Metadata.Name] = "no Ready condition"
+		for _, cond := range item.Status.Conditions {
+			if cond.Type != "Ready" {
+				continue
+			}
+			if cond.Status == "True" {
+				delete(notReady, item.Metadata.Name)
+			} else {
+				notReady[item.Metadata.Name] = fmt.Sprintf("NotReady (%s: %s)", cond.Reason, cond.Message)
+			}
+		}
+	}
+
+	descriptions := make([]string, 0, len(notReady))
+	for name, reason := range notReady {
+		descriptions = append(descriptions, fmt.Sprintf(" - %s: %s", name, reason))
+	}
+	sort.Strings(descriptions)
+	return descriptions, nil
+}
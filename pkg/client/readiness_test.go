@@ -0,0 +1,88 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadyzFailedChecks(t *testing.T) {
+	body := "[+]ping ok\n[+]log ok\n[-]etcd failed: reason withheld\n[+]informer-sync ok\n[-]poststarthook/rbac/bootstrap-roles failed: reason withheld\nreadyz check failed\n"
+
+	failed := readyzFailedChecks(body)
+	if expected := []string{"etcd", "poststarthook/rbac/bootstrap-roles"}; !reflect.DeepEqual(failed, expected) {
+		t.Errorf("expected failed checks %v, got %v", expected, failed)
+	}
+	if failed := readyzFailedChecks("ok"); len(failed) != 0 {
+		t.Errorf("expected no failed checks, got %v", failed)
+	}
+}
+
+func TestNodesNotReady(t *testing.T) {
+	raw := []byte(`{"kind": "NodeList", "items": [
+		{"metadata": {"name": "k3d-test-server-0"}, "status": {"conditions": [{"type": "MemoryPressure", "status": "False"}, {"type": "Ready", "status": "True"}]}},
+		{"metadata": {"name": "k3d-test-agent-0"}, "status": {"conditions": [{"type": "Ready", "status": "False", "reason": "KubeletNotReady", "message": "container runtime network not ready"}]}},
+		{"metadata": {"name": "kwok-test-0"}, "status": {"conditions": [{"type": "Ready", "status": "False"}]}}
+	]}`)
+
+	notReady, err := nodesNotReady(raw, []string{"k3d-test-server-0", "k3d-test-agent-0", "k3d-test-agent-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		" - k3d-test-agent-0: NotReady (KubeletNotReady: container runtime network not ready)",
+		" - k3d-test-agent-1: not registered",
+	}
+	if !reflect.DeepEqual(notReady, expected) {
+		t.Errorf("expected\n%v\ngot\n%v", expected, notReady)
+	}
+
+	if _, err := nodesNotReady([]byte("<html>"), nil); err == nil {
+		t.Error("expected an error for an invalid node list")
+	}
+}
+
+func TestWaitForReadinessPhase(t *testing.T) {
+	interval := WaitPollInterval
+	WaitPollInterval = time.Millisecond
+	defer func() { WaitPollInterval = interval }()
+
+	checks := 0
+	if err := waitForReadinessPhase(context.Background(), time.Second, func(context.Context) (bool, string) {
+		checks++
+		return checks == 3, "not yet"
+	}); err != nil || checks != 3 {
+		t.Errorf("expected the phase to succeed on the third check, got %v after %d checks", err, checks)
+	}
+
+	err := waitForReadinessPhase(context.Background(), 10*time.Millisecond, func(context.Context) (bool, string) {
+		return false, " - k3d-test-agent-1: not registered"
+	})
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "k3d-test-agent-1: not registered") {
+		t.Errorf("expected a timeout including the last diagnostics, got %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/rancher/k3d/v5/pkg/util"
+)
+
+// nodeStatusRestarting is the status the runtime reports for node containers that are being restarted by their restart policy
+const nodeStatusRestarting = "restarting"
+
+// ClusterReconcile restarts the agents of a running cluster that came up before its servers, which happens when the docker daemon
+// restarts and brings back the node containers (restart policy) in arbitrary order: such agents may fail to rejoin the cluster.
+// The agents are restarted once all running servers are ready. It returns the (with DryRun: only detected) agents.
+func ClusterReconcile(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster, opts k3d.ClusterReconcileOpts) ([]*k3d.Node, error) {
+	unlock, err := ClusterLock(ctx, cluster.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	cluster, err = ClusterGet(ctx, runtime, cluster)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster: %w", err)
+	}
+
+	agents := misorderedAgents(cluster)
+	if len(agents) == 0 || opts.DryRun {
+		return agents, nil
+	}
+
+	if opts.Timeout > 0*time.Second {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	l.Log().Infof("Waiting for the servers of cluster '%s' to be ready before restarting %d agent(s)...", cluster.Name, len(agents))
+	for _, server := range util.FilterNodesByRole(cluster.Nodes, k3d.ServerRole) {
+		if !server.State.Running || server.State.Status == nodeStatusPaused {
+			continue
+		}
+		if err := serverWaitForReadiness(ctx, runtime, server); err != nil {
+			return agents, err
+		}
+	}
+
+	agentWG, aCtx := errgroup.WithContext(ctx)
+	for _, agent := range agents {
+		currentAgent := agent
+		agentWG.Go(func() error {
+			l.Log().Infof("Restarting agent %s...", currentAgent.Name)
+			if err := runtime.StopNode(aCtx, currentAgent); err != nil {
+				return fmt.Errorf("failed to stop agent %s: %w", currentAgent.Name, err)
+			}
+			currentAgent.State.Running = false
+			return agentStart(aCtx, runtime, currentAgent, k3d.ClusterStartOpts{})
+		})
+	}
+	if err := agentWG.Wait(); err != nil {
+		return agents, fmt.Errorf("failed to restart one or more agents: %w", err)
+	}
+	return agents, nil
+}
+
+// misorderedAgents returns the agents (sorted by name) that are stuck restarting or were started before the last (re-)start of a running server.
+// Stopped agents are left alone, as they may have been stopped on purpose. Clusters without a running server aren't reconciled at all.
+func misorderedAgents(cluster *k3d.Cluster) []*k3d.Node {
+	var lastServerStart time.Time
+	for _, server := range util.FilterNodesByRole(cluster.Nodes, k3d.ServerRole) {
+		if !server.State.Running || server.State.Status == nodeStatusPaused {
+			continue
+		}
+		started, err := time.Parse(time.RFC3339Nano, server.State.Started)
+		if err != nil {
+			l.Log().Debugf("Failed to parse start time '%s' of server '%s': %v", server.State.Started, server.Name, err)
+			continue
+		}
+		if started.After(lastServerStart) {
+			lastServerStart = started
+		}
+	}
+	if lastServerStart.IsZero() {
+		l.Log().Debugf("Cluster '%s' has no running server, nothing to reconcile", cluster.Name)
+		return nil
+	}
+
+	agents := []*k3d.Node{}
+	for _, agent := range util.FilterNodesByRole(cluster.Nodes, k3d.AgentRole) {
+		if agent.State.Status == nodeStatusRestarting {
+			agents = append(agents, agent)
+			continue
+		}
+		if !agent.State.Running || agent.State.Status == nodeStatusPaused {
+			continue
+		}
+		started, err := time.Parse(time.RFC3339Nano, agent.State.Started)
+		if err != nil {
+			l.Log().Debugf("Failed to parse start time '%s' of agent '%s': %v", agent.State.Started, agent.Name, err)
+			continue
+		}
+		if started.Before(lastServerStart) {
+			agents = append(agents, agent)
+		}
+	}
+	sort.Slice(agents, func(i, j int) bool { return agents[i].Name < agents[j].Name })
+	return agents
+}
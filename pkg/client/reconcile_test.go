@@ -0,0 +1,99 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestMisorderedAgents(t *testing.T) {
+	newNode := func(name string, role k3d.Role, status string, started string) *k3d.Node {
+		node := newFakeNode("test", name, role, status == "running")
+		node.State.Status = status
+		node.State.Started = started
+		return node
+	}
+
+	cluster := &k3d.Cluster{Name: "test", Nodes: []*k3d.Node{
+		newNode("k3d-test-server-0", k3d.ServerRole, "running", "2026-10-16T10:00:05.000000001Z"),
+		newNode("k3d-test-server-1", k3d.ServerRole, "running", "2026-10-16T10:00:03Z"),
+		newNode("k3d-test-agent-0", k3d.AgentRole, "running", "2026-10-16T10:00:01Z"),    // started before server-0
+		newNode("k3d-test-agent-1", k3d.AgentRole, "running", "2026-10-16T10:00:10Z"),    // started after the servers
+		newNode("k3d-test-agent-2", k3d.AgentRole, "restarting", "2026-10-16T10:00:20Z"), // crash-looping
+		newNode("k3d-test-agent-3", k3d.AgentRole, "exited", "2026-10-16T09:00:00Z"),     // stopped on purpose
+	}}
+
+	names := []string{}
+	for _, agent := range misorderedAgents(cluster) {
+		names = append(names, agent.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"k3d-test-agent-0", "k3d-test-agent-2"}) {
+		t.Errorf("expected agent-0 and agent-2 to be misordered, got %v", names)
+	}
+
+	// without a running server, there's nothing to reconcile
+	cluster.Nodes[0].State.Running = false
+	cluster.Nodes[1].State.Status = nodeStatusPaused
+	if agents := misorderedAgents(cluster); len(agents) != 0 {
+		t.Errorf("expected no misordered agents without running servers, got %d", len(agents))
+	}
+}
+
+func TestClusterReconcile(t *testing.T) {
+	useTempConfigDir(t)
+
+	server := newFakeNode("test", "k3d-test-server-0", k3d.ServerRole, true)
+	server.State.Status = "running"
+	server.State.Started = "2026-10-16T10:00:05Z"
+	agent := newFakeNode("test", "k3d-test-agent-0", k3d.AgentRole, true)
+	agent.State.Status = "running"
+	agent.State.Started = "2026-10-16T10:00:01Z"
+
+	runtime := &fakeRuntime{
+		nodes:       []*k3d.Node{server, agent},
+		execOutputs: map[string]string{strings.Join(serverReadinessCmd, " "): "ok"},
+		logStreams:  map[string][]string{agent.Name: {k3d.ReadyLogMessageByRole[k3d.AgentRole]}},
+	}
+
+	agents, err := ClusterReconcile(context.Background(), runtime, &k3d.Cluster{Name: "test"}, k3d.ClusterReconcileOpts{DryRun: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(agents) != 1 || len(runtime.callsOf("StopNode")) != 0 {
+		t.Fatalf("expected the agent to be detected but not restarted in a dry run, got %d agents and calls %v", len(agents), runtime.calls)
+	}
+
+	if _, err := ClusterReconcile(context.Background(), runtime, &k3d.Cluster{Name: "test"}, k3d.ClusterReconcileOpts{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if execs := runtime.callsOf("ExecInNode"); !reflect.DeepEqual(execs, []string{server.Name}) {
+		t.Errorf("expected to wait for the server's readiness, got %v", execs)
+	}
+	if stops, starts := runtime.callsOf("StopNode"), runtime.callsOf("StartNode"); !reflect.DeepEqual(stops, []string{agent.Name}) || !reflect.DeepEqual(starts, []string{agent.Name}) {
+		t.Errorf("expected the agent to be restarted, got stops %v and starts %v", stops, starts)
+	}
+}
@@ -285,7 +285,7 @@ func RegistryGenerateLocalRegistryHostingConfigMapYAML(ctx context.Context, runt
 	}
 
 	// if the host is now 0.0.0.0, check if we can set it to the IP of the docker-machine, if it's used
-	if host == k3d.DefaultAPIHost && runtime == runtimes.Docker {
+	if host == k3d.DefaultAPIHost && runtime == runtimes.Docker && !Offline {
 		if gort.GOOS == "windows" || gort.GOOS == "darwin" {
 			l.Log().Tracef("Running on %s: checking if it's using docker-machine", gort.GOOS)
 			machineIP, err := runtime.(docker.Docker).GetDockerMachineIP()
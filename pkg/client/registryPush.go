@@ -0,0 +1,162 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// RegistryList returns all k3d-managed registries
+func RegistryList(ctx context.Context, runtime runtimes.Runtime) ([]*k3d.Registry, error) {
+	nodes, err := NodeList(ctx, runtime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	registries := []*k3d.Registry{}
+	for _, node := range NodeFilterByRoles(nodes, []k3d.Role{k3d.RegistryRole}, []k3d.Role{}) {
+		reg, err := RegistryFromNode(node)
+		if err != nil {
+			return nil, err
+		}
+		registries = append(registries, reg)
+	}
+	return registries, nil
+}
+
+// RegistryListForCluster returns the k3d-managed registries connected to the network of the cluster,
+// i.e. those created with it and those used via --registry-use
+func RegistryListForCluster(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster) ([]*k3d.Registry, error) {
+	nodes, err := NodeList(ctx, runtime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	registries := []*k3d.Registry{}
+	for _, node := range NodeFilterByRoles(nodes, []k3d.Role{k3d.RegistryRole}, []k3d.Role{}) {
+		connected := false
+		for _, network := range node.Networks {
+			connected = connected || network == cluster.Network.Name
+		}
+		if !connected {
+			continue
+		}
+		reg, err := RegistryFromNode(node)
+		if err != nil {
+			return nil, err
+		}
+		registries = append(registries, reg)
+	}
+	return registries, nil
+}
+
+// RegistryLookup finds a k3d-managed registry by name (with or without the 'k3d-' prefix).
+// Without a name, it returns the only existing registry and fails if there are none or several.
+func RegistryLookup(ctx context.Context, runtime runtimes.Runtime, name string) (*k3d.Registry, error) {
+	registries, err := RegistryList(ctx, runtime)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []*k3d.Registry
+	names := []string{}
+	for _, reg := range registries {
+		names = append(names, reg.Host)
+		if name == "" || reg.Host == name || reg.Host == fmt.Sprintf("%s-%s", k3d.DefaultObjectNamePrefix, name) {
+			found = append(found, reg)
+		}
+	}
+
+	switch {
+	case len(found) == 1:
+		return found[0], nil
+	case name != "":
+		return nil, fmt.Errorf("no registry '%s' found (existing registries: [%s])", name, strings.Join(names, ", "))
+	case len(found) == 0:
+		return nil, fmt.Errorf("no registries found: create one using 'k3d registry create'")
+	default:
+		return nil, fmt.Errorf("found multiple registries ([%s]), please choose one", strings.Join(names, ", "))
+	}
+}
+
+// RegistryHostAddress returns the address (host:port), under which the container runtime reaches the registry's published port.
+// Registries published on all or loopback interfaces are addressed via 'localhost', which docker treats as insecure registry,
+// so pushing over plain HTTP works without changing the daemon configuration.
+func RegistryHostAddress(reg *k3d.Registry) string {
+	host := reg.ExposureOpts.Binding.HostIP
+	if ip := net.ParseIP(host); host == "" || ip.IsUnspecified() || ip.IsLoopback() {
+		host = "localhost"
+	}
+	return net.JoinHostPort(host, reg.ExposureOpts.Binding.HostPort)
+}
+
+// RegistryClusterAddress returns the address (host:port), under which the nodes of connected clusters pull from the registry
+// (see RegistryGenerateK3sConfig): this is the one to use in manifests.
+func RegistryClusterAddress(reg *k3d.Registry) string {
+	return fmt.Sprintf("%s:%s", reg.Host, reg.ExposureOpts.Binding.HostPort)
+}
+
+// RegistryImagePath returns the repository path and tag of an image without its registry, e.g. 'ghcr.io/org/app:v1' -> 'org/app:v1',
+// so it can be moved to another registry. Images without tag get the 'latest' tag, images only referenced by digest are rejected.
+func RegistryImagePath(image string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse image reference '%s': %w", image, err)
+	}
+	tagged, ok := reference.TagNameOnly(named).(reference.Tagged)
+	if !ok {
+		return "", fmt.Errorf("image '%s' has no tag", image)
+	}
+	path := reference.Path(named)
+	if reference.Domain(named) == "docker.io" {
+		path = reference.FamiliarName(named) // no 'library/' prefix for official images
+	}
+	return fmt.Sprintf("%s:%s", path, tagged.Tag()), nil
+}
+
+// RegistryPushImage tags a local image for a k3d-managed registry and pushes it there.
+// It returns the reference to use in connected clusters.
+func RegistryPushImage(ctx context.Context, runtime runtimes.Runtime, reg *k3d.Registry, image string) (string, error) {
+	path, err := RegistryImagePath(image)
+	if err != nil {
+		return "", err
+	}
+
+	target := fmt.Sprintf("%s/%s", RegistryHostAddress(reg), path)
+	if err := runtime.TagImage(ctx, image, target); err != nil {
+		return "", fmt.Errorf("failed to tag image '%s' for registry '%s': %w", image, reg.Host, err)
+	}
+	l.Log().Infof("Pushing image '%s' as '%s'...", image, target)
+	if err := runtime.PushImage(ctx, target); err != nil {
+		return "", fmt.Errorf("failed to push image '%s' to registry '%s': %w", image, reg.Host, err)
+	}
+
+	return fmt.Sprintf("%s/%s", RegistryClusterAddress(reg), path), nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/docker/go-connections/nat"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func fakeRegistryNode(name string, hostIP string, hostPort string) *k3d.Node {
+	return &k3d.Node{
+		Name:          name,
+		Role:          k3d.RegistryRole,
+		RuntimeLabels: map[string]string{"app": "k3d", k3d.LabelRole: string(k3d.RegistryRole)},
+		Ports: nat.PortMap{
+			"5000/tcp": []nat.PortBinding{{HostIP: hostIP, HostPort: hostPort}},
+		},
+	}
+}
+
+const zeroDigest = "0000000000000000000000000000000000000000000000000000000000000000"
+
+func TestRegistryImagePath(t *testing.T) {
+	for image, expected := range map[string]string{
+		"myapp":                          "myapp:latest",
+		"myapp:dev":                      "myapp:dev",
+		"docker.io/library/nginx:1.21":   "nginx:1.21",
+		"ghcr.io/org/app:v1":             "org/app:v1",
+		"localhost:5000/team/app:v2":     "team/app:v2",
+		"myapp:dev@sha256:" + zeroDigest: "myapp:dev",
+	} {
+		path, err := RegistryImagePath(image)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", image, err)
+		} else if path != expected {
+			t.Errorf("%s: expected '%s', got '%s'", image, expected, path)
+		}
+	}
+
+	if _, err := RegistryImagePath("myapp@sha256:" + zeroDigest); err == nil {
+		t.Errorf("expected an error for an image without tag")
+	}
+}
+
+func TestRegistryPushImage(t *testing.T) {
+	runtime := &fakeRuntime{nodes: []*k3d.Node{
+		fakeRegistryNode("k3d-registry.localhost", "0.0.0.0", "5432"),
+		fakeRegistryNode("k3d-lan", "192.168.1.10", "5000"),
+	}}
+
+	reg, err := RegistryLookup(context.Background(), runtime, "registry.localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := RegistryPushImage(context.Background(), runtime, reg, "myapp:dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref != "k3d-registry.localhost:5432/myapp:dev" {
+		t.Errorf("expected the reference used by the cluster nodes, got '%s'", ref)
+	}
+	if tags := runtime.callsOf("TagImage"); !reflect.DeepEqual(tags, []string{"myapp:dev->localhost:5432/myapp:dev"}) {
+		t.Errorf("expected the image to be tagged for localhost, got %v", tags)
+	}
+	if pushes := runtime.callsOf("PushImage"); !reflect.DeepEqual(pushes, []string{"localhost:5432/myapp:dev"}) {
+		t.Errorf("expected the tagged image to be pushed, got %v", pushes)
+	}
+
+	lan, err := RegistryLookup(context.Background(), runtime, "k3d-lan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr := RegistryHostAddress(lan); addr != "192.168.1.10:5000" {
+		t.Errorf("expected the registry to be addressed via its host IP, got '%s'", addr)
+	}
+
+	if _, err := RegistryLookup(context.Background(), runtime, ""); err == nil {
+		t.Errorf("expected an error without name for multiple registries")
+	}
+	if _, err := RegistryLookup(context.Background(), runtime, "unknown"); err == nil {
+		t.Errorf("expected an error for an unknown registry")
+	}
+
+	runtime.failOn = map[string]error{"PushImage:localhost:5432/myapp:dev": errors.New("connection refused")}
+	if _, err := RegistryPushImage(context.Background(), runtime, reg, "myapp:dev"); err == nil {
+		t.Errorf("expected push errors to be returned")
+	}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+
+	"github.com/docker/distribution/reference"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// imageLineRegexp matches the image references of Kubernetes manifests (e.g. '  - image: "localhost:5000/app:v1" # comment'),
+// capturing everything before, the reference itself and everything after it
+var imageLineRegexp = regexp.MustCompile(`(?m)^(\s*(?:-\s+)?image:\s*["']?)([^"'\s#]+)(.*)$`)
+
+// registryLocalAddresses returns the addresses, under which a registry is reachable from the host (but not from inside the cluster)
+func registryLocalAddresses(reg *k3d.Registry) []string {
+	port := reg.ExposureOpts.Binding.HostPort
+	return []string{
+		RegistryHostAddress(reg),
+		net.JoinHostPort("localhost", port),
+		net.JoinHostPort("127.0.0.1", port),
+		net.JoinHostPort("0.0.0.0", port),
+		net.JoinHostPort("::1", port),
+	}
+}
+
+// RegistryRewriteImages rewrites the image references in (YAML) manifests, which point to one of the registries via a host-only
+// address like 'localhost:5000', to the address the cluster nodes pull from (see RegistryClusterAddress), so that manifests written
+// for 'docker push localhost:5000/...' work in the cluster as well. Everything else (incl. formatting and comments) is kept as is.
+// It returns the rewritten manifests and the number of rewritten references.
+func RegistryRewriteImages(manifests []byte, registries []*k3d.Registry) ([]byte, int) {
+	replacements := map[string]string{}
+	for _, reg := range registries {
+		for _, addr := range registryLocalAddresses(reg) {
+			replacements[addr] = RegistryClusterAddress(reg)
+		}
+	}
+
+	count := 0
+	rewritten := imageLineRegexp.ReplaceAllFunc(manifests, func(line []byte) []byte {
+		parts := imageLineRegexp.FindSubmatch(line)
+		named, err := reference.ParseNormalizedNamed(string(parts[2]))
+		if err != nil {
+			return line
+		}
+		replacement, ok := replacements[reference.Domain(named)]
+		if !ok {
+			return line
+		}
+		count++
+		image := replacement + string(parts[2])[len(reference.Domain(named)):]
+		return []byte(fmt.Sprintf("%s%s%s", parts[1], image, parts[3]))
+	})
+
+	return rewritten, count
+}
@@ -0,0 +1,74 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"testing"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestRegistryRewriteImages(t *testing.T) {
+	reg, err := RegistryFromNode(fakeRegistryNode("k3d-registry.localhost", "0.0.0.0", "5000"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifests := `apiVersion: apps/v1
+kind: Deployment
+spec:
+  template:
+    spec:
+      initContainers:
+        - image: "127.0.0.1:5000/init:v1" # comment
+      containers:
+        - name: app
+          image: localhost:5000/team/app:dev
+        - name: other-port
+          image: localhost:5001/app:dev
+        - name: public
+          image: nginx:1.21
+`
+	expected := `apiVersion: apps/v1
+kind: Deployment
+spec:
+  template:
+    spec:
+      initContainers:
+        - image: "k3d-registry.localhost:5000/init:v1" # comment
+      containers:
+        - name: app
+          image: k3d-registry.localhost:5000/team/app:dev
+        - name: other-port
+          image: localhost:5001/app:dev
+        - name: public
+          image: nginx:1.21
+`
+
+	rewritten, count := RegistryRewriteImages([]byte(manifests), []*k3d.Registry{reg})
+	if string(rewritten) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, string(rewritten))
+	}
+	if count != 2 {
+		t.Errorf("expected 2 rewritten references, got %d", count)
+	}
+}
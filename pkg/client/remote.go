@@ -0,0 +1,131 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// RemoteDockerHost returns the DOCKER_HOST URL, if the docker runtime talks to a daemon on another machine (via ssh:// or tcp://), nil otherwise
+func RemoteDockerHost(runtime runtimes.Runtime) *url.URL {
+	if runtime != runtimes.Docker {
+		return nil
+	}
+	return parseRemoteDockerHost(os.Getenv("DOCKER_HOST"))
+}
+
+// parseRemoteDockerHost returns the parsed docker host, if it's an ssh:// or tcp:// URL pointing to a host other than the local one
+func parseRemoteDockerHost(dockerHost string) *url.URL {
+	u, err := url.Parse(dockerHost)
+	if err != nil || (u.Scheme != "ssh" && u.Scheme != "tcp") || u.Hostname() == "" {
+		return nil
+	}
+	if u.Hostname() == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return u
+}
+
+// ValidateAPIExposure checks that the Kubernetes API will be reachable from this machine, if the docker daemon runs on another one
+func ValidateAPIExposure(runtime runtimes.Runtime, api *k3d.ExposureOpts) error {
+	remote := RemoteDockerHost(runtime)
+	if api.Tunnel {
+		if remote == nil || remote.Scheme != "ssh" {
+			return fmt.Errorf("tunneling the Kubernetes API requires a remote docker host reached via SSH (DOCKER_HOST=ssh://[USER@]HOST[:PORT]), got DOCKER_HOST='%s'", os.Getenv("DOCKER_HOST"))
+		}
+		return nil
+	}
+	if remote == nil {
+		return nil
+	}
+	if ip := net.ParseIP(api.Binding.HostIP); ip != nil && ip.IsLoopback() {
+		return fmt.Errorf("the Kubernetes API port would be bound to the loopback interface of the remote docker host %s, where it's not reachable from this machine: bind it to an address of the remote host (e.g. '--api-port %s:%s') or tunnel it via SSH ('--api-tunnel')", remote.Hostname(), remote.Hostname(), api.Binding.HostPort)
+	}
+	return nil
+}
+
+// ClusterAPITunnel opens an SSH port-forward from the API port on this machine to the one published on the remote docker host,
+// if the cluster was created with '--api-tunnel' (and the port isn't forwarded already).
+// The tunnel is run by a background ssh process (like docker does for ssh:// hosts), so it outlives k3d.
+func ClusterAPITunnel(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster) error {
+	var server *k3d.Node
+	for _, node := range cluster.Nodes {
+		if node.Role == k3d.ServerRole && node.RuntimeLabels[k3d.LabelServerAPITunnel] == "true" {
+			server = node
+			break
+		}
+	}
+	if server == nil {
+		return nil
+	}
+
+	remote := RemoteDockerHost(runtime)
+	if remote == nil || remote.Scheme != "ssh" {
+		return fmt.Errorf("cluster '%s' is reached through an SSH tunnel, but DOCKER_HOST ('%s') is no remote ssh:// host", cluster.Name, os.Getenv("DOCKER_HOST"))
+	}
+
+	port := server.RuntimeLabels[k3d.LabelServerAPIPort]
+	local := net.JoinHostPort("127.0.0.1", port)
+	if conn, err := net.DialTimeout("tcp", local, time.Second); err == nil {
+		conn.Close()
+		l.Log().Debugf("API port %s of cluster '%s' is already forwarded", local, cluster.Name)
+		return nil
+	}
+
+	args := sshTunnelArgs(remote, port, server.RuntimeLabels[k3d.LabelServerAPIHostIP])
+	l.Log().Infof("Opening SSH tunnel to the Kubernetes API on %s: ssh %s", remote.Hostname(), strings.Join(args, " "))
+	cmd := exec.CommandContext(ctx, "ssh", args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to open SSH tunnel to %s: %w: %s", remote.Host, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// sshTunnelArgs returns the arguments for a backgrounded ssh process forwarding the local port to the same port on the given address of the remote host
+func sshTunnelArgs(remote *url.URL, port string, remoteIP string) []string {
+	if remoteIP == "" || remoteIP == k3d.DefaultAPIHost {
+		remoteIP = "127.0.0.1"
+	}
+	args := []string{"-f", "-N", "-o", "ExitOnForwardFailure=yes", "-L", fmt.Sprintf("127.0.0.1:%s:%s", port, net.JoinHostPort(remoteIP, port))}
+	if remote.Port() != "" {
+		args = append(args, "-p", remote.Port())
+	}
+	target := remote.Hostname()
+	if remote.User != nil && remote.User.Username() != "" {
+		target = remote.User.Username() + "@" + target
+	}
+	return append(args, target)
+}
@@ -0,0 +1,90 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestParseRemoteDockerHost(t *testing.T) {
+	remote := map[string]string{
+		"ssh://me@buildhost":        "buildhost",
+		"ssh://buildhost:2222":      "buildhost",
+		"tcp://192.168.99.100:2376": "192.168.99.100",
+	}
+	for dockerHost, host := range remote {
+		u := parseRemoteDockerHost(dockerHost)
+		if u == nil || u.Hostname() != host {
+			t.Errorf("expected '%s' to be the remote host %s, got %v", dockerHost, host, u)
+		}
+	}
+
+	for _, local := range []string{"", "unix:///var/run/docker.sock", "npipe:////./pipe/docker_engine", "tcp://localhost:2375", "tcp://127.0.0.1:2375", "ssh://[::1]"} {
+		if u := parseRemoteDockerHost(local); u != nil {
+			t.Errorf("expected '%s' to be no remote host, got %v", local, u)
+		}
+	}
+}
+
+func TestSSHTunnelArgs(t *testing.T) {
+	remote, _ := url.Parse("ssh://me@buildhost:2222")
+	expected := []string{"-f", "-N", "-o", "ExitOnForwardFailure=yes", "-L", "127.0.0.1:6550:127.0.0.1:6550", "-p", "2222", "me@buildhost"}
+	if args := sshTunnelArgs(remote, "6550", "0.0.0.0"); !reflect.DeepEqual(args, expected) {
+		t.Errorf("expected %v, got %v", expected, args)
+	}
+
+	remote, _ = url.Parse("ssh://buildhost")
+	expected = []string{"-f", "-N", "-o", "ExitOnForwardFailure=yes", "-L", "127.0.0.1:6550:10.0.0.5:6550", "buildhost"}
+	if args := sshTunnelArgs(remote, "6550", "10.0.0.5"); !reflect.DeepEqual(args, expected) {
+		t.Errorf("expected %v, got %v", expected, args)
+	}
+}
+
+func TestValidateAPIExposure(t *testing.T) {
+	api := func(hostIP string, tunnel bool) *k3d.ExposureOpts {
+		return &k3d.ExposureOpts{PortMapping: nat.PortMapping{Binding: nat.PortBinding{HostIP: hostIP, HostPort: "6550"}}, Tunnel: tunnel}
+	}
+
+	t.Setenv("DOCKER_HOST", "unix:///var/run/docker.sock")
+	if err := ValidateAPIExposure(runtimes.Docker, api("127.0.0.1", false)); err != nil {
+		t.Errorf("unexpected error for a local docker host: %v", err)
+	}
+	if err := ValidateAPIExposure(runtimes.Docker, api("127.0.0.1", true)); err == nil {
+		t.Errorf("expected tunneling to require a remote ssh:// docker host")
+	}
+
+	t.Setenv("DOCKER_HOST", "ssh://me@buildhost")
+	if err := ValidateAPIExposure(runtimes.Docker, api("127.0.0.1", false)); err == nil {
+		t.Errorf("expected an error for an API bound to the loopback interface of a remote host")
+	}
+	for _, ok := range []*k3d.ExposureOpts{api("0.0.0.0", false), api("127.0.0.1", true)} {
+		if err := ValidateAPIExposure(runtimes.Docker, ok); err != nil {
+			t.Errorf("unexpected error for %+v: %v", ok, err)
+		}
+	}
+}
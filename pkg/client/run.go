@@ -0,0 +1,195 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/rancher/k3d/v5/pkg/util"
+	"k8s.io/client-go/rest"
+)
+
+type runPodContainer struct {
+	Name    string   `json:"name"`
+	Image   string   `json:"image"`
+	Command []string `json:"command,omitempty"`
+}
+
+type runPod struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Metadata   struct {
+		Name      string            `json:"name"`
+		Namespace string            `json:"namespace"`
+		Labels    map[string]string `json:"labels,omitempty"`
+	} `json:"metadata"`
+	Spec struct {
+		RestartPolicy string            `json:"restartPolicy"`
+		Containers    []runPodContainer `json:"containers"`
+	} `json:"spec"`
+}
+
+type runPodStatus struct {
+	Status struct {
+		Phase             string `json:"phase"`
+		ContainerStatuses []struct {
+			State struct {
+				Waiting *struct {
+					Reason  string `json:"reason"`
+					Message string `json:"message"`
+				} `json:"waiting"`
+				Terminated *struct {
+					ExitCode int `json:"exitCode"`
+				} `json:"terminated"`
+			} `json:"state"`
+		} `json:"containerStatuses"`
+	} `json:"status"`
+}
+
+// podRunPollInterval is the interval in which the pod status is checked while waiting for it to start/finish
+var podRunPollInterval = 1 * time.Second
+
+// PodRun runs a one-off pod in the cluster, streams its logs to out and returns the exit code of its container
+// If no name is given, a random one is generated.
+func PodRun(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster, opts k3d.PodRunOpts, out io.Writer) (int, error) {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	client, err := KubeRESTClient(ctx, runtime, cluster, "v1")
+	if err != nil {
+		return -1, err
+	}
+
+	return podRun(ctx, client, opts, out)
+}
+
+// podRun creates the pod using the given client of the core API group, waits for it to finish and cleans up
+func podRun(ctx context.Context, client *rest.RESTClient, opts k3d.PodRunOpts, out io.Writer) (int, error) {
+	if opts.Namespace == "" {
+		opts.Namespace = "default"
+	}
+	if opts.Name == "" {
+		opts.Name = fmt.Sprintf("%s-run-%s", k3d.DefaultObjectNamePrefix, strings.ToLower(util.GenerateRandomString(5)))
+	}
+
+	pod := runPod{APIVersion: "v1", Kind: "Pod"}
+	pod.Metadata.Name = opts.Name
+	pod.Metadata.Namespace = opts.Namespace
+	pod.Metadata.Labels = map[string]string{"app.kubernetes.io/managed-by": "k3d"}
+	pod.Spec.RestartPolicy = "Never"
+	pod.Spec.Containers = []runPodContainer{{Name: opts.Name, Image: opts.Image, Command: opts.Command}}
+
+	podJSON, err := json.Marshal(pod)
+	if err != nil {
+		return -1, fmt.Errorf("failed to marshal pod '%s': %w", opts.Name, err)
+	}
+
+	l.Log().Infof("Creating pod '%s/%s' with image '%s'...", opts.Namespace, opts.Name, opts.Image)
+	if err := client.Post().Namespace(opts.Namespace).Resource("pods").Body(podJSON).Do(ctx).Error(); err != nil {
+		return -1, fmt.Errorf("failed to create pod '%s': %w", opts.Name, err)
+	}
+
+	if !opts.Keep {
+		defer func() {
+			// use a fresh context, so that we clean up even after a timeout
+			if err := client.Delete().Namespace(opts.Namespace).Resource("pods").Name(opts.Name).Do(context.Background()).Error(); err != nil {
+				l.Log().Warnf("Failed to delete pod '%s': %v", opts.Name, err)
+			}
+		}()
+	}
+
+	// wait for the container to start (or to finish right away)
+	if _, err := podRunWait(ctx, client, opts, false); err != nil {
+		return -1, err
+	}
+
+	logs, err := client.Get().Namespace(opts.Namespace).Resource("pods").Name(opts.Name).SubResource("log").Param("follow", "true").Stream(ctx)
+	if err != nil {
+		return -1, fmt.Errorf("failed to stream logs of pod '%s': %w", opts.Name, err)
+	}
+	defer logs.Close()
+	if _, err := io.Copy(out, logs); err != nil {
+		return -1, fmt.Errorf("failed to stream logs of pod '%s': %w", opts.Name, err)
+	}
+
+	status, err := podRunWait(ctx, client, opts, true)
+	if err != nil {
+		return -1, err
+	}
+
+	exitCode := 0
+	if len(status.Status.ContainerStatuses) > 0 && status.Status.ContainerStatuses[0].State.Terminated != nil {
+		exitCode = status.Status.ContainerStatuses[0].State.Terminated.ExitCode
+	} else if status.Status.Phase == "Failed" {
+		exitCode = 1
+	}
+	l.Log().Infof("Pod '%s/%s' finished with phase '%s' (exit code %d)", opts.Namespace, opts.Name, status.Status.Phase, exitCode)
+
+	return exitCode, nil
+}
+
+// podRunWait polls the pod until it left the Pending phase or, if finished is set, until it's Succeeded or Failed
+func podRunWait(ctx context.Context, client *rest.RESTClient, opts k3d.PodRunOpts, finished bool) (*runPodStatus, error) {
+	for {
+		raw, err := client.Get().Namespace(opts.Namespace).Resource("pods").Name(opts.Name).Do(ctx).Raw()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get status of pod '%s': %w", opts.Name, err)
+		}
+
+		status := &runPodStatus{}
+		if err := json.Unmarshal(raw, status); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal status of pod '%s': %w", opts.Name, err)
+		}
+
+		switch status.Status.Phase {
+		case "Succeeded", "Failed":
+			return status, nil
+		case "Pending":
+			for _, cs := range status.Status.ContainerStatuses {
+				if cs.State.Waiting != nil && (cs.State.Waiting.Reason == "ErrImagePull" || cs.State.Waiting.Reason == "ImagePullBackOff" || cs.State.Waiting.Reason == "InvalidImageName") {
+					return nil, fmt.Errorf("pod '%s' failed to start: %s: %s", opts.Name, cs.State.Waiting.Reason, cs.State.Waiting.Message)
+				}
+			}
+		default:
+			if !finished {
+				return status, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out waiting for pod '%s': %w", opts.Name, ctx.Err())
+		case <-time.After(podRunPollInterval):
+		}
+	}
+}
@@ -0,0 +1,162 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"k8s.io/client-go/rest"
+)
+
+// fakePodAPI serves the few pod endpoints used by PodRun, letting the pod pass through the given phases on each status request
+type fakePodAPI struct {
+	mu       sync.Mutex
+	phases   []string
+	exitCode int
+	created  *runPod
+	deleted  bool
+}
+
+func (f *fakePodAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/pods"):
+		body, _ := ioutil.ReadAll(r.Body)
+		f.created = &runPod{}
+		if err := json.Unmarshal(body, f.created); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(body)
+	case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/log"):
+		_, _ = w.Write([]byte("hello from the pod\n"))
+	case r.Method == http.MethodGet:
+		phase := f.phases[0]
+		if len(f.phases) > 1 {
+			f.phases = f.phases[1:]
+		}
+		terminated := ""
+		if phase == "Succeeded" || phase == "Failed" {
+			terminated = fmt.Sprintf(`"terminated": {"exitCode": %d}`, f.exitCode)
+		}
+		fmt.Fprintf(w, `{"status": {"phase": "%s", "containerStatuses": [{"state": {%s}}]}}`, phase, terminated)
+	case r.Method == http.MethodDelete:
+		f.deleted = true
+		_, _ = w.Write([]byte(`{}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newFakePodAPIClient(t *testing.T, api *fakePodAPI) *rest.RESTClient {
+	t.Helper()
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+	client, err := newKubeRESTClient(&rest.Config{Host: server.URL}, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestPodRun(t *testing.T) {
+	podRunPollInterval = 10 * time.Millisecond
+
+	api := &fakePodAPI{phases: []string{"Pending", "Running", "Succeeded"}, exitCode: 3}
+	client := newFakePodAPIClient(t, api)
+
+	var out bytes.Buffer
+	exitCode, err := podRun(context.Background(), client, k3d.PodRunOpts{Image: "busybox", Command: []string{"sh", "-c", "exit 3"}}, &out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if exitCode != 3 {
+		t.Errorf("expected exit code 3, got %d", exitCode)
+	}
+	if out.String() != "hello from the pod\n" {
+		t.Errorf("expected the pod logs to be streamed, got '%s'", out.String())
+	}
+
+	if api.created == nil {
+		t.Fatalf("expected a pod to be created")
+	}
+	if !strings.HasPrefix(api.created.Metadata.Name, "k3d-run-") || api.created.Metadata.Namespace != "default" {
+		t.Errorf("expected a generated pod name in the default namespace, got '%s/%s'", api.created.Metadata.Namespace, api.created.Metadata.Name)
+	}
+	if api.created.Spec.RestartPolicy != "Never" || len(api.created.Spec.Containers) != 1 || api.created.Spec.Containers[0].Image != "busybox" {
+		t.Errorf("unexpected pod spec: %+v", api.created.Spec)
+	}
+	if !api.deleted {
+		t.Errorf("expected the pod to be deleted")
+	}
+}
+
+func TestPodRunKeep(t *testing.T) {
+	podRunPollInterval = 10 * time.Millisecond
+
+	api := &fakePodAPI{phases: []string{"Succeeded"}}
+	client := newFakePodAPIClient(t, api)
+
+	exitCode, err := podRun(context.Background(), client, k3d.PodRunOpts{Name: "smoke", Namespace: "test", Image: "busybox", Keep: true}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if exitCode != 0 {
+		t.Errorf("expected exit code 0, got %d", exitCode)
+	}
+	if api.created.Metadata.Name != "smoke" || api.created.Metadata.Namespace != "test" {
+		t.Errorf("expected pod 'test/smoke', got '%s/%s'", api.created.Metadata.Namespace, api.created.Metadata.Name)
+	}
+	if api.deleted {
+		t.Errorf("expected the pod to be kept")
+	}
+}
+
+func TestPodRunTimeout(t *testing.T) {
+	podRunPollInterval = 10 * time.Millisecond
+
+	api := &fakePodAPI{phases: []string{"Pending"}}
+	client := newFakePodAPIClient(t, api)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := podRun(ctx, client, k3d.PodRunOpts{Image: "busybox"}, &bytes.Buffer{}); err == nil {
+		t.Fatalf("expected a timeout error for a pod that never starts")
+	}
+	if !api.deleted {
+		t.Errorf("expected the pod to be deleted after a timeout")
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	k3drt "github.com/rancher/k3d/v5/pkg/runtimes"
+	runtimeErr "github.com/rancher/k3d/v5/pkg/runtimes/errors"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/rancher/k3d/v5/pkg/util"
+)
+
+// RunnerContainerAuto lets k3d detect the container it's running in
+const RunnerContainerAuto = "auto"
+
+// ownContainerID is replaced in tests
+var ownContainerID = util.OwnContainerID
+
+// ClusterPrepRunnerContainer connects the container k3d is running in (e.g. a CI job) to the cluster network,
+// so that it can reach the nodes (and the Kubernetes API) via their container names instead of the ports published on the runtime host.
+// In 'auto' mode, a container that's unknown to the runtime (e.g. when talking to a docker:dind sidecar) is left alone:
+// the Kubernetes API is then reached via the runtime host (DOCKER_HOST) and the published API port.
+func ClusterPrepRunnerContainer(ctx context.Context, runtime k3drt.Runtime, cluster *k3d.Cluster, clusterCreateOpts *k3d.ClusterCreateOpts) error {
+	if clusterCreateOpts.RunnerContainer == "" {
+		return nil
+	}
+
+	auto := clusterCreateOpts.RunnerContainer == RunnerContainerAuto
+	container := clusterCreateOpts.RunnerContainer
+	if auto {
+		id, err := ownContainerID()
+		if err != nil {
+			l.Log().Debugf("Not connecting to the cluster network: %v", err)
+			return nil
+		}
+		container = id
+	}
+
+	if cluster.Network.Name == "host" {
+		l.Log().Infof("Not connecting container '%s' to the cluster network: the cluster uses the host network", container)
+		return nil
+	}
+
+	l.Log().Infof("Connecting container '%s' (running k3d) to the cluster network '%s'", container, cluster.Network.Name)
+	if err := runtime.ConnectContainerToNetwork(ctx, container, cluster.Network.Name); err != nil {
+		if auto && errors.Is(err, runtimeErr.ErrRuntimeContainerNotExists) {
+			if host := runtime.GetHost(); host != "" {
+				l.Log().Infof("Container '%s' is not managed by the runtime at '%s' (e.g. docker:dind sidecar): using that host to reach the Kubernetes API", container, host)
+			} else {
+				l.Log().Warnf("Container '%s' is not managed by the runtime: the Kubernetes API may not be reachable from here", container)
+			}
+			return nil
+		}
+		return fmt.Errorf("failed to connect container '%s' to the cluster network '%s': %w", container, cluster.Network.Name, err)
+	}
+
+	clusterCreateOpts.GlobalLabels[k3d.LabelRunnerContainer] = container
+	clusterCreateOpts.GlobalLabels[k3d.LabelRunnerAPIHost] = runnerAPIHost(cluster, clusterCreateOpts)
+
+	return nil
+}
+
+// runnerAPIHost returns the name of the container that serves the Kubernetes API within the cluster network: the loadbalancer or the first server
+func runnerAPIHost(cluster *k3d.Cluster, clusterCreateOpts *k3d.ClusterCreateOpts) string {
+	if !clusterCreateOpts.DisableLoadBalancer {
+		if cluster.ServerLoadBalancer != nil && cluster.ServerLoadBalancer.Node != nil && cluster.ServerLoadBalancer.Node.Name != "" {
+			return cluster.ServerLoadBalancer.Node.Name
+		}
+		return fmt.Sprintf("%s-%s-serverlb", k3d.DefaultObjectNamePrefix, cluster.Name)
+	}
+	for _, node := range cluster.Nodes {
+		if node.Role == k3d.ServerRole {
+			return node.Name
+		}
+	}
+	return GenerateNodeName(cluster.Name, k3d.ServerRole, 0)
+}
+
+// clusterDisconnectRunnerContainer disconnects the container, which was connected by ClusterPrepRunnerContainer, from the cluster network, so that the network can be deleted
+func clusterDisconnectRunnerContainer(ctx context.Context, runtime k3drt.Runtime, cluster *k3d.Cluster) {
+	for _, node := range cluster.Nodes {
+		container, ok := node.RuntimeLabels[k3d.LabelRunnerContainer]
+		if !ok || container == "" {
+			continue
+		}
+		if err := runtime.DisconnectContainerFromNetwork(ctx, container, cluster.Network.Name); err != nil && !errors.Is(err, runtimeErr.ErrRuntimeContainerNotExists) {
+			l.Log().Warnf("Failed to disconnect container '%s' from the cluster network '%s': %v", container, cluster.Network.Name, err)
+		}
+		return
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	runtimeErr "github.com/rancher/k3d/v5/pkg/runtimes/errors"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestClusterPrepRunnerContainer(t *testing.T) {
+	defer func(orig func() (string, error)) { ownContainerID = orig }(ownContainerID)
+
+	newCluster := func() (*k3d.Cluster, *k3d.ClusterCreateOpts) {
+		cluster := &k3d.Cluster{
+			Name:    "test",
+			Network: k3d.ClusterNetwork{Name: "k3d-test"},
+			Nodes:   []*k3d.Node{{Name: "k3d-test-server-0", Role: k3d.ServerRole}},
+		}
+		return cluster, &k3d.ClusterCreateOpts{GlobalLabels: map[string]string{}}
+	}
+	unknown := fmt.Errorf("container 'abc': %w", runtimeErr.ErrRuntimeContainerNotExists)
+
+	testCases := []struct {
+		name            string
+		runnerContainer string
+		ownContainerID  error
+		disableLB       bool
+		network         string
+		failOn          map[string]error
+		expectErr       bool
+		expectConnects  []string
+		expectAPIHost   string
+	}{
+		{name: "disabled", expectConnects: []string{}},
+		{name: "explicit", runnerContainer: "ci-job", expectConnects: []string{"ci-job@k3d-test"}, expectAPIHost: "k3d-test-serverlb"},
+		{name: "explicit without loadbalancer", runnerContainer: "ci-job", disableLB: true, expectConnects: []string{"ci-job@k3d-test"}, expectAPIHost: "k3d-test-server-0"},
+		{name: "explicit unknown", runnerContainer: "ci-job", failOn: map[string]error{"ConnectContainerToNetwork:ci-job@k3d-test": unknown}, expectErr: true, expectConnects: []string{"ci-job@k3d-test"}},
+		{name: "auto", runnerContainer: RunnerContainerAuto, expectConnects: []string{"abc@k3d-test"}, expectAPIHost: "k3d-test-serverlb"},
+		{name: "auto outside of a container", runnerContainer: RunnerContainerAuto, ownContainerID: errors.New("not running inside of a container"), expectConnects: []string{}},
+		{name: "auto with dind sidecar", runnerContainer: RunnerContainerAuto, failOn: map[string]error{"ConnectContainerToNetwork:abc@k3d-test": unknown}, expectConnects: []string{"abc@k3d-test"}},
+		{name: "host network", runnerContainer: "ci-job", network: "host", expectConnects: []string{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ownContainerID = func() (string, error) { return "abc", tc.ownContainerID }
+			runtime := &fakeRuntime{host: "docker:2375", failOn: tc.failOn}
+			cluster, opts := newCluster()
+			opts.RunnerContainer = tc.runnerContainer
+			opts.DisableLoadBalancer = tc.disableLB
+			if tc.network != "" {
+				cluster.Network.Name = tc.network
+			}
+
+			err := ClusterPrepRunnerContainer(context.Background(), runtime, cluster, opts)
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("expected error: %t, got %v", tc.expectErr, err)
+			}
+			if calls := runtime.callsOf("ConnectContainerToNetwork"); !reflect.DeepEqual(calls, tc.expectConnects) {
+				t.Errorf("expected connects %v, got %v", tc.expectConnects, calls)
+			}
+			if apiHost := opts.GlobalLabels[k3d.LabelRunnerAPIHost]; apiHost != tc.expectAPIHost {
+				t.Errorf("expected API host label '%s', got '%s'", tc.expectAPIHost, apiHost)
+			}
+			if _, ok := opts.GlobalLabels[k3d.LabelRunnerContainer]; ok != (tc.expectAPIHost != "") {
+				t.Errorf("expected runner container label to be set: %t, got labels %v", tc.expectAPIHost != "", opts.GlobalLabels)
+			}
+		})
+	}
+}
+
+func TestClusterDisconnectRunnerContainer(t *testing.T) {
+	runtime := &fakeRuntime{}
+	cluster := &k3d.Cluster{
+		Name:    "test",
+		Network: k3d.ClusterNetwork{Name: "k3d-test"},
+		Nodes: []*k3d.Node{
+			{Name: "k3d-test-server-0", Role: k3d.ServerRole, RuntimeLabels: map[string]string{k3d.LabelRunnerContainer: "ci-job"}},
+			{Name: "k3d-test-agent-0", Role: k3d.AgentRole, RuntimeLabels: map[string]string{k3d.LabelRunnerContainer: "ci-job"}},
+		},
+	}
+
+	clusterDisconnectRunnerContainer(context.Background(), runtime, cluster)
+	if calls := runtime.callsOf("DisconnectContainerFromNetwork"); !reflect.DeepEqual(calls, []string{"ci-job@k3d-test"}) {
+		t.Errorf("expected a single disconnect of the runner container, got %v", calls)
+	}
+
+	// clusters created without a runner container don't disconnect anything
+	runtime = &fakeRuntime{}
+	cluster.Nodes = []*k3d.Node{{Name: "k3d-test-server-0", Role: k3d.ServerRole, RuntimeLabels: map[string]string{}}}
+	clusterDisconnectRunnerContainer(context.Background(), runtime, cluster)
+	if calls := runtime.callsOf("DisconnectContainerFromNetwork"); len(calls) != 0 {
+		t.Errorf("expected no disconnects, got %v", calls)
+	}
+}
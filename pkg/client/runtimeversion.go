@@ -0,0 +1,48 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"fmt"
+
+	"github.com/docker/docker/api/types/versions"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+)
+
+// RuntimeCheckVersion fails, if the runtime daemon is older than k3d requires, as that would only show up as cryptic errors when creating the nodes.
+// With an API version pinned by the user (e.g. --docker-api-version for an old daemon), it only warns: whether k3d works is up to them then.
+func RuntimeCheckVersion(runtime runtimes.Runtime) error {
+	info, err := runtime.Info()
+	if err != nil {
+		return fmt.Errorf("failed to get runtime info: %w", err)
+	}
+	if info.APIVersion == "" || info.RequiredAPIVersion == "" || !versions.LessThan(info.APIVersion, info.RequiredAPIVersion) {
+		return nil
+	}
+	problem := fmt.Sprintf("the %s daemon %s only supports API version %s, but k3d requires API version %s or newer", info.Name, info.Version, info.APIVersion, info.RequiredAPIVersion)
+	if info.PinnedAPIVersion != "" {
+		l.Log().Warnf("%s: continuing, as API version %s is pinned", problem, info.PinnedAPIVersion)
+		return nil
+	}
+	return fmt.Errorf("%s: please upgrade to a %s release supporting API version %s or newer (or pin the API version with --docker-api-version to try anyway)", problem, info.Name, info.RequiredAPIVersion)
+}
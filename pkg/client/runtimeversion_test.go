@@ -0,0 +1,58 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRuntimeCheckVersion(t *testing.T) {
+	testCases := []struct {
+		name             string
+		version          string
+		apiVersion       string
+		pinnedAPIVersion string
+		expectedError    string
+	}{
+		{name: "unknown", version: "20.10.0"},
+		{name: "minimum (docker 20.10.0)", version: "20.10.0", apiVersion: "1.41"},
+		{name: "newer", version: "23.0.1", apiVersion: "1.43"},
+		{name: "too old (API 1.40)", version: "19.03.15", apiVersion: "1.40", expectedError: "upgrade to a fake release supporting API version 1.41 or newer"},
+		{name: "too old but pinned", version: "19.03.15", apiVersion: "1.40", pinnedAPIVersion: "1.40"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := RuntimeCheckVersion(&fakeRuntime{version: tc.version, apiVersion: tc.apiVersion, pinnedAPIVersion: tc.pinnedAPIVersion})
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+				t.Errorf("expected an error containing '%s', got %v", tc.expectedError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/rest"
+)
+
+// seedObjectsBuiltinNamespaces are the namespaces that exist in every cluster and don't have to be created
+var seedObjectsBuiltinNamespaces = map[string]bool{
+	"default":     true,
+	"kube-system": true,
+	"kube-public": true,
+}
+
+// seedObjectsPollInterval is the interval in which the creation is retried while the Kubernetes API is not available yet
+var seedObjectsPollInterval = 2 * time.Second
+
+// seedObjectsDefaultTimeout limits the time waiting for the Kubernetes API if no timeout was given
+const seedObjectsDefaultTimeout = 2 * time.Minute
+
+// seedObject is a Kubernetes object to be created in the cluster
+type seedObject struct {
+	resource  string
+	namespace string
+	obj       map[string]interface{}
+}
+
+// ClusterSeedObjects creates the given Secrets and ConfigMaps (and the non-default namespaces they live in) once via the Kubernetes API.
+// Objects that already exist are left untouched, so the user can modify them afterwards.
+func ClusterSeedObjects(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster, objects []k3d.SeedObject, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = seedObjectsDefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	seeds, err := seedObjectsGenerate(objects)
+	if err != nil {
+		return err
+	}
+
+	client, err := seedObjectsClient(ctx, runtime, cluster)
+	if err != nil {
+		return err
+	}
+
+	return seedObjectsCreate(ctx, client, seeds)
+}
+
+// ClusterCreateNamespace creates the given namespace once via the Kubernetes API, unless it's one that exists in every cluster anyway
+func ClusterCreateNamespace(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster, namespace string, timeout time.Duration) error {
+	if seedObjectsBuiltinNamespaces[namespace] {
+		return nil
+	}
+	if timeout <= 0 {
+		timeout = seedObjectsDefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	client, err := seedObjectsClient(ctx, runtime, cluster)
+	if err != nil {
+		return err
+	}
+
+	return seedObjectsCreate(ctx, client, []seedObject{seedNamespace(namespace)})
+}
+
+// seedObjectsClient returns a client for the core API, waiting for the kubeconfig, which may not be available yet if we didn't wait for the server(s) to be ready
+func seedObjectsClient(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster) (*rest.RESTClient, error) {
+	for {
+		client, err := KubeRESTClient(ctx, runtime, cluster, "v1")
+		if err == nil {
+			return client, nil
+		}
+		l.Log().Tracef("Kubernetes API not available yet: %v", err)
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out waiting for the Kubernetes API: %w", err)
+		case <-time.After(seedObjectsPollInterval):
+		}
+	}
+}
+
+// seedObjectsCreate creates the given objects in order, retrying while the Kubernetes API is not (fully) available
+func seedObjectsCreate(ctx context.Context, client *rest.RESTClient, seeds []seedObject) error {
+	for _, seed := range seeds {
+		body, err := json.Marshal(seed.obj)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s: %w", seed.resource, err)
+		}
+		name := seed.obj["metadata"].(map[string]interface{})["name"]
+
+		for {
+			req := client.Post().Resource(seed.resource).Body(body)
+			if seed.namespace != "" {
+				req = req.Namespace(seed.namespace)
+			}
+			err := req.Do(ctx).Error()
+			if err == nil {
+				l.Log().Debugf("Created %s '%s'", seed.resource, name)
+				break
+			}
+			if apierrors.IsAlreadyExists(err) {
+				l.Log().Debugf("%s '%s' already exists, leaving it untouched", seed.resource, name)
+				break
+			}
+			if !seedObjectsRetryable(err) {
+				return fmt.Errorf("failed to create %s '%s': %w", seed.resource, name, err)
+			}
+
+			l.Log().Tracef("Kubernetes API not ready to create %s '%s' yet: %v", seed.resource, name, err)
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("timed out creating %s '%s': %w", seed.resource, name, err)
+			case <-time.After(seedObjectsPollInterval):
+			}
+		}
+	}
+	return nil
+}
+
+// seedNamespace returns the namespace with the given name to be created
+func seedNamespace(name string) seedObject {
+	return seedObject{resource: "namespaces", obj: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Namespace",
+		"metadata":   map[string]interface{}{"name": name},
+	}}
+}
+
+// seedObjectsRetryable tells if an error may be caused by a Kubernetes API server that is still starting up
+func seedObjectsRetryable(err error) bool {
+	if _, ok := err.(apierrors.APIStatus); !ok {
+		return true // e.g. connection refused
+	}
+	return apierrors.IsServiceUnavailable(err) || apierrors.IsInternalError(err) || apierrors.IsTimeout(err) || apierrors.IsServerTimeout(err) ||
+		apierrors.IsTooManyRequests(err) || apierrors.IsForbidden(err) // RBAC may not be bootstrapped yet
+}
+
+// seedObjectsGenerate generates the given Secrets and ConfigMaps as well as the (non-default) namespaces they live in
+func seedObjectsGenerate(objects []k3d.SeedObject) ([]seedObject, error) {
+	seeds := []seedObject{}
+
+	namespaces := map[string]bool{}
+	for _, obj := range objects {
+		if !seedObjectsBuiltinNamespaces[obj.Namespace] && !namespaces[obj.Namespace] {
+			namespaces[obj.Namespace] = true
+			seeds = append(seeds, seedNamespace(obj.Namespace))
+		}
+	}
+
+	for _, obj := range objects {
+		manifest := map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       obj.Kind,
+			"metadata":   map[string]interface{}{"name": obj.Name, "namespace": obj.Namespace},
+		}
+		data := map[string]string{}
+		binaryData := map[string]string{}
+		switch obj.Kind {
+		case "Secret":
+			manifest["type"] = "Opaque"
+			for k, v := range obj.Data {
+				data[k] = base64.StdEncoding.EncodeToString(v)
+			}
+		case "ConfigMap":
+			// ConfigMap data must be valid UTF-8, anything else has to go into binaryData
+			for k, v := range obj.Data {
+				if utf8.Valid(v) {
+					data[k] = string(v)
+				} else {
+					binaryData[k] = base64.StdEncoding.EncodeToString(v)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("unsupported seed object kind '%s' (only Secret and ConfigMap)", obj.Kind)
+		}
+		if len(data) > 0 {
+			manifest["data"] = data
+		}
+		if len(binaryData) > 0 {
+			manifest["binaryData"] = binaryData
+		}
+
+		resource := "secrets"
+		if obj.Kind == "ConfigMap" {
+			resource = "configmaps"
+		}
+		seeds = append(seeds, seedObject{resource: resource, namespace: obj.Namespace, obj: manifest})
+	}
+
+	return seeds, nil
+}
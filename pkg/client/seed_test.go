@@ -0,0 +1,151 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"k8s.io/client-go/rest"
+)
+
+func TestSeedObjectsGenerate(t *testing.T) {
+	binary := []byte{0xff, 0xfe, 0x00}
+	seeds, err := seedObjectsGenerate([]k3d.SeedObject{
+		{Kind: "Secret", Name: "db", Namespace: "myapp", Data: map[string][]byte{"PASSWORD": []byte("s3cr3t")}},
+		{Kind: "ConfigMap", Name: "app", Namespace: "myapp", Data: map[string][]byte{"config.yaml": []byte("key: välue"), "logo.png": binary}},
+		{Kind: "ConfigMap", Name: "other", Namespace: "default", Data: map[string][]byte{"a": []byte("b")}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedResources := []string{"namespaces", "secrets", "configmaps", "configmaps"}
+	if len(seeds) != len(expectedResources) {
+		t.Fatalf("expected %d objects (incl. one namespace), got %d: %+v", len(expectedResources), len(seeds), seeds)
+	}
+	for i, resource := range expectedResources {
+		if seeds[i].resource != resource {
+			t.Errorf("expected object %d to be one of %s, got %s", i, resource, seeds[i].resource)
+		}
+	}
+
+	secretData := seeds[1].obj["data"].(map[string]string)
+	if secretData["PASSWORD"] != base64.StdEncoding.EncodeToString([]byte("s3cr3t")) {
+		t.Errorf("expected base64 encoded secret data, got '%s'", secretData["PASSWORD"])
+	}
+
+	cm := seeds[2].obj
+	if cm["data"].(map[string]string)["config.yaml"] != "key: välue" {
+		t.Errorf("expected UTF-8 content in data, got %+v", cm["data"])
+	}
+	binaryData, ok := cm["binaryData"].(map[string]string)
+	if !ok || binaryData["logo.png"] != base64.StdEncoding.EncodeToString(binary) {
+		t.Errorf("expected non-UTF-8 content in binaryData, got %+v", cm["binaryData"])
+	}
+	if _, ok := cm["data"].(map[string]string)["logo.png"]; ok {
+		t.Errorf("non-UTF-8 content must not be in data")
+	}
+
+	if _, err := seedObjectsGenerate([]k3d.SeedObject{{Kind: "Service", Name: "x", Namespace: "default"}}); err == nil {
+		t.Errorf("expected an error for an unsupported kind")
+	}
+}
+
+func TestSeedObjectsCreate(t *testing.T) {
+	seedObjectsPollInterval = time.Millisecond
+
+	var mu sync.Mutex
+	created := []string{}
+	unavailable := 2
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		// the API server is still starting up for the first requests
+		if unavailable > 0 {
+			unavailable--
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"kind": "Status", "apiVersion": "v1", "status": "Failure", "reason": "ServiceUnavailable", "code": 503}`))
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		obj := map[string]interface{}{}
+		_ = json.Unmarshal(body, &obj)
+		name := obj["metadata"].(map[string]interface{})["name"].(string)
+		if name == "existing" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"kind": "Status", "apiVersion": "v1", "status": "Failure", "reason": "AlreadyExists", "code": 409}`))
+			return
+		}
+		if name == "Invalid_Name" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = w.Write([]byte(`{"kind": "Status", "apiVersion": "v1", "status": "Failure", "reason": "Invalid", "code": 422}`))
+			return
+		}
+		created = append(created, r.URL.Path)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	client, err := newKubeRESTClient(&rest.Config{Host: server.URL}, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seeds, err := seedObjectsGenerate([]k3d.SeedObject{
+		{Kind: "Secret", Name: "existing", Namespace: "default", Data: map[string][]byte{"a": []byte("b")}},
+		{Kind: "ConfigMap", Name: "app", Namespace: "myapp", Data: map[string][]byte{"a": []byte("b")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := seedObjectsCreate(context.Background(), client, seeds); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"/api/v1/namespaces", "/api/v1/namespaces/myapp/configmaps"}
+	if len(created) != len(expected) || created[0] != expected[0] || created[1] != expected[1] {
+		t.Errorf("expected %v to be created, got %v", expected, created)
+	}
+
+	invalid, err := seedObjectsGenerate([]k3d.SeedObject{{Kind: "ConfigMap", Name: "Invalid_Name", Namespace: "default"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := seedObjectsCreate(ctx, client, invalid); err == nil || ctx.Err() != nil {
+		t.Errorf("expected an immediate error for an invalid object, got %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// nodeStressDir holds the files and PIDs of the injected load. It's on the filesystem of the kubelet directory,
+// so that filling it causes disk pressure (nodefs) on the node.
+const nodeStressDir = "/var/lib/kubelet/k3d-stress"
+
+// NodeStress starts the given load in the node in the background (stress-ng, if the node image ships it, else busy loops / dd)
+// and fills its disk right away. The load keeps running until it's stopped via NodeStressStop (or the node is restarted).
+func NodeStress(ctx context.Context, runtime runtimes.Runtime, node *k3d.Node, opts k3d.NodeStressOpts) error {
+	if opts.CPU < 0 {
+		return fmt.Errorf("invalid number of CPU workers %d", opts.CPU)
+	}
+	if opts.FillDisk < 0 || opts.FillDisk >= 100 {
+		return fmt.Errorf("invalid disk usage %d%% to fill up to (must be between 1 and 99)", opts.FillDisk)
+	}
+	if opts.CPU == 0 && !opts.IO && opts.FillDisk == 0 {
+		return fmt.Errorf("no load to inject into node '%s' (choose CPU workers, IO and/or disk usage)", node.Name)
+	}
+
+	if err := runtime.ExecInNode(ctx, node, []string{"mkdir", "-p", nodeStressDir}); err != nil {
+		return fmt.Errorf("failed to create directory '%s' in node '%s': %w", nodeStressDir, node.Name, err)
+	}
+
+	if opts.FillDisk > 0 {
+		logreader, err := runtime.ExecInNodeGetLogs(ctx, node, []string{"df", "-Pk", nodeStressDir})
+		if err != nil {
+			return fmt.Errorf("failed to get disk usage in node '%s': %w", node.Name, err)
+		}
+		output, err := ioutil.ReadAll(logreader)
+		if err != nil {
+			return fmt.Errorf("failed to get disk usage in node '%s': %w", node.Name, err)
+		}
+		fill, err := diskFillBytes(string(output), opts.FillDisk)
+		if err != nil {
+			return fmt.Errorf("failed to get disk usage in node '%s': %w", node.Name, err)
+		}
+		if fill > 0 {
+			l.Log().Infof("Filling the disk of node '%s' up to %d%% (%d MiB)", node.Name, opts.FillDisk, fill/1024/1024)
+			fillCmd := fmt.Sprintf("fallocate -l %[1]d %[2]s/fill-$$ 2>/dev/null || dd if=/dev/zero of=%[2]s/fill-$$ bs=1M count=%[3]d 2>/dev/null", fill, nodeStressDir, fill/1024/1024)
+			if err := runtime.ExecInNode(ctx, node, []string{"sh", "-c", fillCmd}); err != nil {
+				return fmt.Errorf("failed to fill the disk of node '%s': %w", node.Name, err)
+			}
+		} else {
+			l.Log().Infof("The disk of node '%s' is already filled to %d%% or more", node.Name, opts.FillDisk)
+		}
+	}
+
+	if script := nodeStressScript(opts); script != "" {
+		l.Log().Infof("Starting load in node '%s' (CPU workers: %d, IO: %t)", node.Name, opts.CPU, opts.IO)
+		if err := runtime.ExecInNode(ctx, node, []string{"sh", "-c", script}); err != nil {
+			return fmt.Errorf("failed to start load in node '%s': %w", node.Name, err)
+		}
+	}
+	return nil
+}
+
+// nodeStressScript returns the shell script starting the background workers, which records their PIDs for NodeStressStop
+func nodeStressScript(opts k3d.NodeStressOpts) string {
+	workers := []string{}
+	if opts.CPU > 0 {
+		workers = append(workers, fmt.Sprintf(
+			`if command -v stress-ng >/dev/null 2>&1; then stress-ng --cpu %[1]d >/dev/null 2>&1 & echo $! >> %[2]s/pids; `+
+				`else i=0; while [ $i -lt %[1]d ]; do (while :; do :; done) >/dev/null 2>&1 & echo $! >> %[2]s/pids; i=$((i+1)); done; fi`,
+			opts.CPU, nodeStressDir))
+	}
+	if opts.IO {
+		workers = append(workers, fmt.Sprintf(
+			`(while :; do dd if=/dev/zero of=%[1]s/io bs=1M count=256 conv=fsync 2>/dev/null; rm -f %[1]s/io; done) >/dev/null 2>&1 & echo $! >> %[1]s/pids`,
+			nodeStressDir))
+	}
+	return strings.Join(workers, "; ")
+}
+
+// diskFillBytes returns how many bytes have to be written to fill the filesystem up to the given percentage, based on 'df -Pk' output
+func diskFillBytes(dfOutput string, percent int) (int64, error) {
+	lines := strings.Split(strings.TrimSpace(dfOutput), "\n")
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(lines) < 2 || len(fields) < 3 {
+		return 0, fmt.Errorf("unexpected df output '%s'", dfOutput)
+	}
+	total, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected df output '%s': %w", dfOutput, err)
+	}
+	used, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected df output '%s': %w", dfOutput, err)
+	}
+	fill := total*int64(percent)/100 - used
+	if fill < 0 {
+		return 0, nil
+	}
+	return fill * 1024, nil
+}
+
+// NodeStressStop stops the load started by NodeStress and frees the disk space it filled
+func NodeStressStop(ctx context.Context, runtime runtimes.Runtime, node *k3d.Node) error {
+	script := fmt.Sprintf("if [ -f %[1]s/pids ]; then kill $(cat %[1]s/pids) 2>/dev/null; fi; rm -rf %[1]s", nodeStressDir)
+	if err := runtime.ExecInNode(ctx, node, []string{"sh", "-c", script}); err != nil {
+		return fmt.Errorf("failed to stop load in node '%s': %w", node.Name, err)
+	}
+	return nil
+}
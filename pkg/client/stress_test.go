@@ -0,0 +1,83 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+const stressTestDf = `Filesystem     1024-blocks      Used Available Capacity Mounted on
+overlay          100000000  40000000  60000000      40% /
+`
+
+func TestDiskFillBytes(t *testing.T) {
+	fill, err := diskFillBytes(stressTestDf, 90)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fill != 50000000*1024 {
+		t.Errorf("expected 50000000 KiB to be filled, got %d bytes", fill)
+	}
+	if fill, err := diskFillBytes(stressTestDf, 30); err != nil || fill != 0 {
+		t.Errorf("expected nothing to fill below the current usage, got %d (%v)", fill, err)
+	}
+	if _, err := diskFillBytes("df: /var/lib/kubelet: No such file or directory", 90); err == nil {
+		t.Errorf("expected an error for unexpected df output")
+	}
+}
+
+func TestNodeStress(t *testing.T) {
+	node := newFakeNode("test", "k3d-test-agent-0", k3d.AgentRole, true)
+	runtime := &fakeRuntime{execOutputs: map[string]string{"df -Pk " + nodeStressDir: stressTestDf}}
+
+	if err := NodeStress(context.Background(), runtime, node, k3d.NodeStressOpts{}); err == nil {
+		t.Errorf("expected an error without any load")
+	}
+	if err := NodeStress(context.Background(), runtime, node, k3d.NodeStressOpts{FillDisk: 100}); err == nil {
+		t.Errorf("expected an error for filling the disk completely")
+	}
+
+	if err := NodeStress(context.Background(), runtime, node, k3d.NodeStressOpts{CPU: 2, IO: true, FillDisk: 90}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	execs := runtime.execs[node.Name]
+	if len(execs) != 4 {
+		t.Fatalf("expected mkdir, df, fill and the workers to be executed, got %v", execs)
+	}
+	if !strings.Contains(execs[2], "fallocate -l 51200000000 ") {
+		t.Errorf("expected the disk to be filled up to 90%%, got '%s'", execs[2])
+	}
+	if !strings.Contains(execs[3], "stress-ng --cpu 2") || !strings.Contains(execs[3], "[ $i -lt 2 ]") || !strings.Contains(execs[3], "conv=fsync") {
+		t.Errorf("expected CPU and IO workers to be started, got '%s'", execs[3])
+	}
+
+	if err := NodeStressStop(context.Background(), runtime, node); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stop := runtime.execs[node.Name][4]; !strings.Contains(stop, "kill $(cat "+nodeStressDir+"/pids)") || !strings.Contains(stop, "rm -rf "+nodeStressDir) {
+		t.Errorf("unexpected stop command '%s'", stop)
+	}
+}
@@ -0,0 +1,420 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/rancher/k3d/v5/pkg/util"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/rest"
+)
+
+// syncKinds are the kinds of resources whose pods can be synced into
+var syncKinds = map[string]bool{"deployment": true, "statefulset": true, "daemonset": true, "pod": true}
+
+// syncDebounce is the time to wait for further changes before syncing, so that e.g. saving many files at once results in a single sync
+var syncDebounce = 300 * time.Millisecond
+
+// SyncTarget is a parsed sync target: the pods of a resource and the directory in their containers
+type SyncTarget struct {
+	Resource WaitForResource
+	Path     string
+}
+
+func (t SyncTarget) String() string {
+	return fmt.Sprintf("%s:%s", t.Resource, t.Path)
+}
+
+// ParseSyncTarget parses a sync target in the format [NAMESPACE/]KIND/NAME:PATH, where KIND is one of deployment, statefulset, daemonset or pod
+func ParseSyncTarget(ref string) (*SyncTarget, error) {
+	idx := strings.LastIndex(ref, ":")
+	if idx <= 0 || idx == len(ref)-1 {
+		return nil, fmt.Errorf("invalid sync target '%s': expected format [NAMESPACE/]KIND/NAME:PATH (e.g. deployment/myapp:/app)", ref)
+	}
+
+	targetPath := ref[idx+1:]
+	if !path.IsAbs(targetPath) {
+		return nil, fmt.Errorf("invalid sync target '%s': the path in the container must be absolute", ref)
+	}
+
+	resource, err := ParseWaitForResource(ref[:idx])
+	if err != nil {
+		return nil, err
+	}
+	if !syncKinds[resource.Kind] {
+		return nil, fmt.Errorf("invalid sync target '%s': cannot sync into a %s", ref, resource.Kind)
+	}
+
+	return &SyncTarget{Resource: *resource, Path: path.Clean(targetPath)}, nil
+}
+
+// syncer syncs files into the pods of a target by streaming a tar archive into 'kubectl exec' inside of a server node
+type syncer struct {
+	runtime runtimes.Runtime
+	node    *k3d.Node // server node running kubectl
+	source  string
+	target  *SyncTarget
+	opts    k3d.SyncOpts
+	getter  *waitForGetter
+}
+
+// Sync copies the source directory into the containers of all running pods of the target and, unless opts.Once is set,
+// keeps watching the directory and syncing changes (incl. deletions) until the context is canceled.
+func Sync(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster, source string, target *SyncTarget, opts k3d.SyncOpts) error {
+	info, err := os.Stat(source)
+	if err != nil {
+		return fmt.Errorf("failed to read sync source '%s': %w", source, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("sync source '%s' is not a directory", source)
+	}
+
+	var node *k3d.Node
+	for _, n := range cluster.Nodes {
+		if n.Role == k3d.ServerRole && (node == nil || (n.State.Running && !node.State.Running)) {
+			node = n
+		}
+	}
+	if node == nil {
+		return fmt.Errorf("failed to find a server node in cluster '%s'", cluster.Name)
+	}
+
+	s := &syncer{
+		runtime: runtime,
+		node:    node,
+		source:  source,
+		target:  target,
+		opts:    opts,
+		getter:  &waitForGetter{runtime: runtime, cluster: cluster, clients: map[string]*rest.RESTClient{}},
+	}
+
+	l.Log().Infof("Syncing '%s' to %s...", source, target)
+	if err := s.sync(ctx, nil, nil); err != nil {
+		return err
+	}
+	if opts.Once {
+		return nil
+	}
+
+	return s.watch(ctx)
+}
+
+// watch syncs the changes in the source directory until the context is canceled
+func (s *syncer) watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := s.watchDir(watcher, s.source); err != nil {
+		return err
+	}
+	l.Log().Infof("Watching '%s' for changes (press Ctrl+C to stop)...", s.source)
+
+	changed := map[string]struct{}{}
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-watcher.Errors:
+			l.Log().Warnf("File watcher error: %v", err)
+		case event := <-watcher.Events:
+			rel, err := filepath.Rel(s.source, event.Name)
+			if err != nil || s.excluded(rel) {
+				continue
+			}
+			if event.Op&fsnotify.Create != 0 {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := s.watchDir(watcher, event.Name); err != nil {
+						l.Log().Warnln(err)
+					}
+				}
+			}
+			changed[filepath.ToSlash(rel)] = struct{}{}
+			debounce = time.After(syncDebounce)
+		case <-debounce:
+			updated, deleted := []string{}, []string{}
+			for rel := range changed {
+				if _, err := os.Lstat(filepath.Join(s.source, filepath.FromSlash(rel))); err == nil {
+					updated = append(updated, rel)
+				} else {
+					deleted = append(deleted, rel)
+				}
+			}
+			changed = map[string]struct{}{}
+			sort.Strings(updated)
+			sort.Strings(deleted)
+			if err := s.sync(ctx, updated, deleted); err != nil {
+				l.Log().Errorln(err)
+			}
+		}
+	}
+}
+
+// watchDir adds the directory and all of its subdirectories to the watcher
+func (s *syncer) watchDir(watcher *fsnotify.Watcher, dir string) error {
+	return filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(s.source, p)
+		if !info.IsDir() {
+			return nil
+		}
+		if rel != "." && s.excluded(rel) {
+			return filepath.SkipDir
+		}
+		if err := watcher.Add(p); err != nil {
+			return fmt.Errorf("failed to watch '%s': %w", p, err)
+		}
+		return nil
+	})
+}
+
+// excluded checks whether any element of the relative path matches one of the exclude patterns
+func (s *syncer) excluded(rel string) bool {
+	for _, element := range strings.Split(filepath.ToSlash(rel), "/") {
+		for _, pattern := range s.opts.Exclude {
+			if ok, _ := path.Match(pattern, element); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sync copies the given files (all files, if nil) and removes the deleted ones in all running pods of the target
+func (s *syncer) sync(ctx context.Context, updated []string, deleted []string) error {
+	pods, err := s.pods(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pods) == 0 {
+		l.Log().Warnf("No running pods found for %s: nothing synced", s.target.Resource)
+		return nil
+	}
+
+	archive, count, err := s.archive(updated)
+	if err != nil {
+		return err
+	}
+
+	archivePath := fmt.Sprintf("/tmp/k3d-sync-%s.tar", util.GenerateRandomString(8))
+	if err := s.runtime.WriteToNode(ctx, archive, archivePath, 0600, s.node); err != nil {
+		return fmt.Errorf("failed to write sync archive to node '%s': %w", s.node.Name, err)
+	}
+	defer func() {
+		if err := s.runtime.ExecInNode(ctx, s.node, []string{"rm", "-f", archivePath}); err != nil {
+			l.Log().Debugf("Failed to remove sync archive '%s' from node '%s': %v", archivePath, s.node.Name, err)
+		}
+	}()
+
+	script := fmt.Sprintf("mkdir -p %s && tar xf - -C %s", shellQuote(s.target.Path), shellQuote(s.target.Path))
+	for _, rel := range deleted {
+		script += fmt.Sprintf(" && rm -rf %s", shellQuote(path.Join(s.target.Path, rel)))
+	}
+
+	for _, pod := range pods {
+		kubectlExec := fmt.Sprintf("kubectl exec -i -n %s %s", shellQuote(s.target.Resource.Namespace), shellQuote(pod))
+		if s.opts.Container != "" {
+			kubectlExec += fmt.Sprintf(" -c %s", shellQuote(s.opts.Container))
+		}
+		cmd := fmt.Sprintf("%s -- sh -c %s < %s", kubectlExec, shellQuote(script), archivePath)
+		if err := s.runtime.ExecInNode(ctx, s.node, []string{"sh", "-c", cmd}); err != nil {
+			return fmt.Errorf("failed to sync into pod '%s/%s': %w", s.target.Resource.Namespace, pod, err)
+		}
+		l.Log().Infof("Synced %d file(s) and %d deletion(s) into pod '%s/%s'", count, len(deleted), s.target.Resource.Namespace, pod)
+	}
+
+	return nil
+}
+
+// pods returns the names of all running pods of the target
+func (s *syncer) pods(ctx context.Context) ([]string, error) {
+	resource := s.target.Resource
+
+	selector := ""
+	if resource.Kind == "pod" {
+		selector = "metadata.name=" + resource.Name
+	} else {
+		raw, err := s.getter.get(ctx, &resource)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s: %w", resource, err)
+		}
+		obj := map[string]interface{}{}
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s: %w", resource, err)
+		}
+		if _, found, _ := unstructured.NestedSlice(obj, "spec", "selector", "matchExpressions"); found {
+			return nil, fmt.Errorf("cannot sync into %s: selectors with matchExpressions are not supported", resource)
+		}
+		matchLabels, _, _ := unstructured.NestedStringMap(obj, "spec", "selector", "matchLabels")
+		if len(matchLabels) == 0 {
+			return nil, fmt.Errorf("cannot sync into %s: it has no label selector", resource)
+		}
+		labels := make([]string, 0, len(matchLabels))
+		for k, v := range matchLabels {
+			labels = append(labels, fmt.Sprintf("%s=%s", k, v))
+		}
+		sort.Strings(labels)
+		selector = strings.Join(labels, ",")
+	}
+
+	client, err := s.getter.client(ctx, "v1")
+	if err != nil {
+		return nil, err
+	}
+	req := client.Get().Namespace(resource.Namespace).Resource("pods")
+	if resource.Kind == "pod" {
+		req = req.Param("fieldSelector", selector)
+	} else {
+		req = req.Param("labelSelector", selector)
+	}
+	raw, err := req.Do(ctx).Raw()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods of %s: %w", resource, err)
+	}
+
+	list := struct {
+		Items []struct {
+			Metadata struct {
+				Name              string  `json:"name"`
+				DeletionTimestamp *string `json:"deletionTimestamp"`
+			} `json:"metadata"`
+			Status struct {
+				Phase string `json:"phase"`
+			} `json:"status"`
+		} `json:"items"`
+	}{}
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal pods of %s: %w", resource, err)
+	}
+
+	pods := []string{}
+	for _, pod := range list.Items {
+		if pod.Status.Phase == "Running" && pod.Metadata.DeletionTimestamp == nil {
+			pods = append(pods, pod.Metadata.Name)
+		}
+	}
+	return pods, nil
+}
+
+// archive creates a tar archive of the given files and directories (relative to the source, all if nil) and returns the number of files in it
+func (s *syncer) archive(files []string) ([]byte, int, error) {
+	if files == nil {
+		files = []string{"."}
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	count := 0
+	added := map[string]struct{}{}
+
+	for _, rel := range files {
+		root := filepath.Join(s.source, filepath.FromSlash(rel))
+		err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil // removed in the meantime
+				}
+				return err
+			}
+			name, err := filepath.Rel(s.source, p)
+			if err != nil {
+				return err
+			}
+			name = filepath.ToSlash(name)
+			if name == "." {
+				return nil
+			}
+			if s.excluded(name) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if _, ok := added[name]; ok {
+				return nil
+			}
+			added[name] = struct{}{}
+
+			link := ""
+			if info.Mode()&os.ModeSymlink != 0 {
+				if link, err = os.Readlink(p); err != nil {
+					return err
+				}
+			}
+			header, err := tar.FileInfoHeader(info, link)
+			if err != nil {
+				return err
+			}
+			header.Name = name
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			if _, err := io.Copy(tw, f); err != nil {
+				return err
+			}
+			count++
+			return nil
+		})
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to archive '%s': %w", root, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, 0, fmt.Errorf("failed to write sync archive: %w", err)
+	}
+	return buf.Bytes(), count, nil
+}
+
+// shellQuote quotes the string for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
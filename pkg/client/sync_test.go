@@ -0,0 +1,196 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"k8s.io/client-go/rest"
+)
+
+func TestParseSyncTarget(t *testing.T) {
+	tests := map[string]struct {
+		ref       string
+		expected  SyncTarget
+		expectErr bool
+	}{
+		"deployment":     {ref: "deployment/myapp:/app", expected: SyncTarget{Resource: WaitForResource{Namespace: "default", Kind: "deployment", Name: "myapp"}, Path: "/app"}},
+		"namespaced pod": {ref: "staging/po/web-0:/usr/share/nginx/html/", expected: SyncTarget{Resource: WaitForResource{Namespace: "staging", Kind: "pod", Name: "web-0"}, Path: "/usr/share/nginx/html"}},
+		"no path":        {ref: "deployment/myapp", expectErr: true},
+		"empty path":     {ref: "deployment/myapp:", expectErr: true},
+		"relative path":  {ref: "deployment/myapp:app", expectErr: true},
+		"no pods":        {ref: "node/k3d-test-server-0:/app", expectErr: true},
+		"invalid ref":    {ref: "myapp:/app", expectErr: true},
+	}
+
+	for name, tc := range tests {
+		target, err := ParseSyncTarget(tc.ref)
+		if tc.expectErr {
+			if err == nil {
+				t.Errorf("%s: expected an error for '%s'", name, tc.ref)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+			continue
+		}
+		if *target != tc.expected {
+			t.Errorf("%s: expected %+v, got %+v", name, tc.expected, *target)
+		}
+	}
+}
+
+// newSyncSource creates a source directory with some files
+func newSyncSource(t *testing.T) string {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"main.go":           "package main",
+		"static/index.html": "<html></html>",
+		".git/HEAD":         "ref: refs/heads/main",
+	} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// tarEntries returns the names of all entries of the archive
+func tarEntries(t *testing.T, archive []byte) []string {
+	names := []string{}
+	tr := tar.NewReader(bytes.NewReader(archive))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, header.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestSyncArchive(t *testing.T) {
+	s := &syncer{source: newSyncSource(t), opts: k3d.SyncOpts{Exclude: []string{".git"}}}
+
+	archive, count, err := s.archive(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"main.go", "static", "static/index.html"}; !reflect.DeepEqual(tarEntries(t, archive), expected) || count != 2 {
+		t.Errorf("expected entries %v (2 files) without the excluded .git directory, got %v (%d files)", expected, tarEntries(t, archive), count)
+	}
+
+	// only the changed files, files removed in the meantime are skipped
+	archive, count, err = s.archive([]string{"static/index.html", "removed.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"static/index.html"}; !reflect.DeepEqual(tarEntries(t, archive), expected) || count != 1 {
+		t.Errorf("expected entries %v, got %v (%d files)", expected, tarEntries(t, archive), count)
+	}
+}
+
+func TestSyncerSync(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/apis/apps/v1/namespaces/default/deployments/myapp":
+			_, _ = w.Write([]byte(`{"spec": {"selector": {"matchLabels": {"app": "myapp", "tier": "web"}}}}`))
+		case r.URL.Path == "/api/v1/namespaces/default/pods" && r.URL.Query().Get("labelSelector") == "app=myapp,tier=web":
+			_, _ = w.Write([]byte(`{"items": [
+				{"metadata": {"name": "myapp-1"}, "status": {"phase": "Running"}},
+				{"metadata": {"name": "myapp-2"}, "status": {"phase": "Pending"}},
+				{"metadata": {"name": "myapp-3", "deletionTimestamp": "2021-10-01T00:00:00Z"}, "status": {"phase": "Running"}},
+				{"metadata": {"name": "myapp-4"}, "status": {"phase": "Running"}}
+			]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"kind": "Status", "apiVersion": "v1", "status": "Failure", "reason": "NotFound", "code": 404}`))
+		}
+	}))
+	defer server.Close()
+
+	runtime := &fakeRuntime{}
+	node := &k3d.Node{Name: "k3d-test-server-0", Role: k3d.ServerRole}
+	target, err := ParseSyncTarget("deployment/myapp:/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &syncer{
+		runtime: runtime,
+		node:    node,
+		source:  newSyncSource(t),
+		target:  target,
+		opts:    k3d.SyncOpts{Container: "web", Exclude: []string{".git"}},
+		getter:  &waitForGetter{restConfig: &rest.Config{Host: server.URL}, clients: map[string]*rest.RESTClient{}},
+	}
+
+	if err := s.sync(context.Background(), []string{"main.go"}, []string{"old dir/it's.txt"}); err != nil {
+		t.Fatal(err)
+	}
+
+	written := runtime.callsOf("WriteToNode")
+	if len(written) != 1 || !strings.HasPrefix(written[0], "/tmp/k3d-sync-") {
+		t.Fatalf("expected the archive to be written to the node once, got %v", written)
+	}
+	if entries := tarEntries(t, runtime.written[written[0]]); !reflect.DeepEqual(entries, []string{"main.go"}) {
+		t.Errorf("expected only the changed file in the archive, got %v", entries)
+	}
+
+	// one exec per running pod (the terminating one is skipped), then the archive is removed
+	execs := runtime.execs[node.Name]
+	if len(execs) != 3 {
+		t.Fatalf("expected 3 execs in the server node, got %d:\n%s", len(execs), strings.Join(execs, "\n"))
+	}
+	expectedExec := `sh -c kubectl exec -i -n 'default' 'myapp-1' -c 'web' -- sh -c 'mkdir -p '\''/app'\'' && tar xf - -C '\''/app'\'' && rm -rf '\''/app/old dir/it'\''\'\'''\''s.txt'\''' < ` + written[0]
+	if execs[0] != expectedExec {
+		t.Errorf("expected exec\n%s\ngot\n%s", expectedExec, execs[0])
+	}
+	if !strings.Contains(execs[1], "'myapp-4'") {
+		t.Errorf("expected the second exec to sync into myapp-4, got %s", execs[1])
+	}
+	if execs[2] != "rm -f "+written[0] {
+		t.Errorf("expected the archive to be removed from the node, got %s", execs[2])
+	}
+}
@@ -0,0 +1,110 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// sysctlRecommendation is the recommended minimum value of a kernel parameter, which is not namespaced and thus shared by all nodes (and pods) on the container host
+type sysctlRecommendation struct {
+	name   string
+	min    int64
+	reason string
+}
+
+// sysctlRecommendations are the kernel parameters whose distribution defaults are known to break clusters with more than a few pods
+var sysctlRecommendations = []sysctlRecommendation{
+	{name: "kernel.pid_max", min: 4194304, reason: "pods may fail to start ('fork: retry: Resource temporarily unavailable')"},
+	{name: "fs.inotify.max_user_watches", min: 524288, reason: "log streaming and file watching may fail ('no space left on device')"},
+	{name: "fs.inotify.max_user_instances", min: 512, reason: "pods may crash ('too many open files')"},
+	{name: "fs.file-max", min: 1048576, reason: "processes may fail to open files ('too many open files in system')"},
+}
+
+// sysctlPath returns the path of a kernel parameter in /proc/sys, e.g. kernel.pid_max -> /proc/sys/kernel/pid_max
+func sysctlPath(name string) string {
+	return "/proc/sys/" + strings.ReplaceAll(name, ".", "/")
+}
+
+// ClusterCheckSysctls warns about kernel parameters of the container host that are below the recommended values.
+// They are read (and if fix is set, raised) via the first running server node, so this works for remote and VM-based runtimes as well.
+// Raising them requires the (privileged) nodes to be able to write to /proc/sys, which is not permitted e.g. with rootless runtimes.
+func ClusterCheckSysctls(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster, fix bool) error {
+	var node *k3d.Node
+	for _, n := range cluster.Nodes {
+		if n.Role == k3d.ServerRole && n.State.Running {
+			node = n
+			break
+		}
+	}
+	if node == nil {
+		return fmt.Errorf("no running server node in cluster '%s'", cluster.Name)
+	}
+
+	paths := []string{}
+	for _, rec := range sysctlRecommendations {
+		paths = append(paths, sysctlPath(rec.name))
+	}
+	logreader, err := runtime.ExecInNodeGetLogs(ctx, node, append([]string{"cat"}, paths...))
+	if err != nil {
+		return fmt.Errorf("failed to read kernel parameters in node %s: %w", node.Name, err)
+	}
+	output, err := ioutil.ReadAll(logreader)
+	if err != nil {
+		return fmt.Errorf("failed to read kernel parameters in node %s: %w", node.Name, err)
+	}
+	values := strings.Fields(string(output))
+	if len(values) != len(sysctlRecommendations) {
+		return fmt.Errorf("unexpected output reading kernel parameters in node %s: '%s'", node.Name, string(output))
+	}
+
+	for i, rec := range sysctlRecommendations {
+		value, err := strconv.ParseInt(values[i], 10, 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse value '%s' of %s: %w", values[i], rec.name, err)
+		}
+		if value >= rec.min {
+			l.Log().Tracef("%s=%d (recommended: >= %d)", rec.name, value, rec.min)
+			continue
+		}
+
+		if fix {
+			err := runtime.ExecInNode(ctx, node, []string{"sh", "-c", fmt.Sprintf("echo %d > %s", rec.min, sysctlPath(rec.name))})
+			if err == nil {
+				l.Log().Infof("Raised %s of the container host from %d to %d", rec.name, value, rec.min)
+				continue
+			}
+			l.Log().Warnf("Failed to raise %s of the container host (not permitted, e.g. with a rootless runtime): %v", rec.name, err)
+		}
+		l.Log().Warnf("%s=%d of the container host is below the recommended %d and %s: raise it with 'sudo sysctl -w %s=%d' on the container host or use --fix-sysctls", rec.name, value, rec.min, rec.reason, rec.name, rec.min)
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestClusterCheckSysctls(t *testing.T) {
+	server := newFakeNode("test", "k3d-test-server-0", k3d.ServerRole, true)
+	cluster := &k3d.Cluster{Name: "test", Nodes: []*k3d.Node{server}}
+	readCmd := "cat /proc/sys/kernel/pid_max /proc/sys/fs/inotify/max_user_watches /proc/sys/fs/inotify/max_user_instances /proc/sys/fs/file-max"
+
+	runtime := &fakeRuntime{execOutputs: map[string]string{readCmd: "32768\n8192\n128\n9223372036854775807\n"}}
+	if err := ClusterCheckSysctls(context.Background(), runtime, cluster, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if execs := runtime.execs[server.Name]; len(execs) != 1 || execs[0] != readCmd {
+		t.Errorf("expected only the kernel parameters to be read without fix, got %v", execs)
+	}
+
+	runtime.execs = nil
+	if err := ClusterCheckSysctls(context.Background(), runtime, cluster, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{
+		readCmd,
+		"sh -c echo 4194304 > /proc/sys/kernel/pid_max",
+		"sh -c echo 524288 > /proc/sys/fs/inotify/max_user_watches",
+		"sh -c echo 512 > /proc/sys/fs/inotify/max_user_instances",
+	}
+	if execs := runtime.execs[server.Name]; strings.Join(execs, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected the parameters below the recommendation to be raised:\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(execs, "\n"))
+	}
+
+	runtime.execOutputs[readCmd] = "cat: can't open '/proc/sys/fs/file-max'\n"
+	if err := ClusterCheckSysctls(context.Background(), runtime, cluster, false); err == nil {
+		t.Errorf("expected an error for unexpected output")
+	}
+
+	server.State.Running = false
+	if err := ClusterCheckSysctls(context.Background(), runtime, cluster, false); err == nil {
+		t.Errorf("expected an error without running server node")
+	}
+}
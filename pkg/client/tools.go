@@ -25,6 +25,7 @@ package client
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"strings"
@@ -36,8 +37,9 @@ import (
 	k3d "github.com/rancher/k3d/v5/pkg/types"
 )
 
-// ImageImportIntoClusterMulti starts up a k3d tools container for the selected cluster and uses it to export
-// images from the runtime to import them into the nodes of the selected cluster
+// ImageImportIntoClusterMulti imports images from the runtime and/or tarballs into the k3s nodes of the selected cluster:
+// by default, they're streamed into all nodes at once (ImportModeDirect), while ImportModeToolsNode uses a k3d tools container
+// to save them to the shared image volume first (implied by keeping the tarball or the tools node)
 func ImageImportIntoClusterMulti(ctx context.Context, runtime runtimes.Runtime, images []string, cluster *k3d.Cluster, opts k3d.ImageImportOpts) error {
 	imagesFromRuntime, imagesFromTar, err := findImages(ctx, runtime, images)
 	if err != nil {
@@ -49,20 +51,150 @@ func ImageImportIntoClusterMulti(ctx context.Context, runtime runtimes.Runtime,
 		return fmt.Errorf("No valid images specified")
 	}
 
+	cluster, err = ClusterGet(ctx, runtime, cluster)
+	if err != nil {
+		return fmt.Errorf("failed to get cluster: %w", err)
+	}
+
+	// only import images into server and agent nodes (i.e. ignoring auxiliary nodes like the server loadbalancer)
+	nodes := NodeFilterByRoles(cluster.Nodes, []k3d.Role{k3d.ServerRole, k3d.AgentRole}, nil)
+
+	if opts.Mode == k3d.ImportModeToolsNode || opts.KeepTar || opts.KeepToolsNode {
+		err = imageImportViaToolsNode(ctx, runtime, cluster, nodes, imagesFromRuntime, imagesFromTar, opts)
+	} else {
+		err = imageImportDirect(ctx, runtime, nodes, imagesFromRuntime, imagesFromTar)
+	}
+	if err != nil {
+		return err
+	}
+
+	l.Log().Infoln("Successfully imported image(s)")
+
+	return nil
+}
+
+// imageImportDirect streams the images into 'ctr image import' in all nodes at once, without storing tarballs in the shared image volume:
+// images from the runtime are saved only once and the stream is split between the nodes.
+// A node failing to import doesn't stop the import into the others, but the errors of all nodes are returned.
+func imageImportDirect(ctx context.Context, runtime runtimes.Runtime, nodes []*k3d.Node, imagesFromRuntime, imagesFromTar []string) error {
+	type imageSource struct {
+		name string
+		open func() (io.ReadCloser, error)
+	}
+	sources := []imageSource{}
+	if len(imagesFromRuntime) > 0 {
+		sources = append(sources, imageSource{
+			name: fmt.Sprintf("%d image(s) from runtime", len(imagesFromRuntime)),
+			open: func() (io.ReadCloser, error) { return runtime.GetImageStream(ctx, imagesFromRuntime) },
+		})
+	}
+	for _, file := range imagesFromTar {
+		file := file
+		sources = append(sources, imageSource{
+			name: fmt.Sprintf("tarball '%s'", file),
+			open: func() (io.ReadCloser, error) { return os.Open(file) },
+		})
+	}
+
+	var errs []string
+	for _, source := range sources {
+		l.Log().Infof("Streaming %s into %d node(s)...", source.name, len(nodes))
+		stream, err := source.open()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("failed to read %s: %v", source.name, err))
+			continue
+		}
+		err = imageStreamIntoNodes(ctx, runtime, nodes, stream)
+		stream.Close()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("failed to import %s: %v", source.name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// imageStreamIntoNodes copies an image tarball stream to 'ctr image import' in all nodes concurrently
+func imageStreamIntoNodes(ctx context.Context, runtime runtimes.Runtime, nodes []*k3d.Node, stream io.Reader) error {
+	writers := make([]io.Writer, len(nodes))
+	pipeWriters := make([]*io.PipeWriter, len(nodes))
+	nodeErrs := make([]error, len(nodes))
+
+	var importWaitgroup sync.WaitGroup
+	for i, node := range nodes {
+		pipeReader, pipeWriter := io.Pipe()
+		writers[i] = pipeWriter
+		pipeWriters[i] = pipeWriter
+		importWaitgroup.Add(1)
+		go func(i int, node *k3d.Node, pipeReader *io.PipeReader) {
+			defer importWaitgroup.Done()
+			err := runtime.ExecInNodeWithStdin(ctx, node, []string{"ctr", "image", "import", "-"}, pipeReader)
+			if err == nil {
+				err = io.ErrClosedPipe
+			}
+			pipeReader.CloseWithError(err) // unblock the writer, if the import stopped reading early
+			if err != io.ErrClosedPipe {
+				nodeErrs[i] = err
+			}
+		}(i, node, pipeReader)
+	}
+
+	_, copyErr := io.Copy(&fanoutWriter{writers: writers, failed: make([]bool, len(writers))}, stream)
+	for _, pipeWriter := range pipeWriters {
+		pipeWriter.CloseWithError(copyErr) // nil closes the pipe regularly
+	}
+	importWaitgroup.Wait()
+
+	var errs []string
+	for i, err := range nodeErrs {
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("node '%s': %v", nodes[i].Name, err))
+		}
+	}
+	if copyErr != nil && len(errs) == 0 {
+		return copyErr
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// fanoutWriter writes to all of its writers and drops those that fail, so that a single failing node doesn't stop the others
+type fanoutWriter struct {
+	writers []io.Writer
+	failed  []bool
+}
+
+func (w *fanoutWriter) Write(p []byte) (int, error) {
+	active := 0
+	for i, writer := range w.writers {
+		if w.failed[i] {
+			continue
+		}
+		if _, err := writer.Write(p); err != nil {
+			w.failed[i] = true
+			continue
+		}
+		active++
+	}
+	if active == 0 {
+		return 0, fmt.Errorf("import failed in all nodes")
+	}
+	return len(p), nil
+}
+
+// imageImportViaToolsNode starts up a k3d tools container for the selected cluster and uses it to export
+// images from the runtime to the shared image volume to import them into the nodes of the selected cluster from there
+func imageImportViaToolsNode(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster, nodes []*k3d.Node, imagesFromRuntime, imagesFromTar []string, opts k3d.ImageImportOpts) error {
 	// create tools node to export images
 	toolsNode, err := EnsureToolsNode(ctx, runtime, cluster)
 	if err != nil {
 		return fmt.Errorf("failed to ensure that tools node is running: %w", err)
 	}
 
-	/* TODO:
-	 * Loop over list of images and check, whether they are files (tar archives) and sort them respectively
-	 * Special case: '-' means "read from stdin"
-	 * 1. From daemon: save images -> import
-	 * 2. From file: copy file -> import
-	 * 3. From stdin: save to tar -> import
-	 * Note: temporary storage location is always the shared image volume and actions are always executed by the tools node
-	 */
 	var importTarNames []string
 
 	if len(imagesFromRuntime) > 0 {
@@ -91,19 +223,21 @@ func ImageImportIntoClusterMulti(ctx context.Context, runtime runtimes.Runtime,
 	// import image in each node
 	l.Log().Infoln("Importing images into nodes...")
 	var importWaitgroup sync.WaitGroup
+	var importErrsMutex sync.Mutex
+	var importErrs []string
 	for _, tarName := range importTarNames {
-		for _, node := range cluster.Nodes {
-			// only import image in server and agent nodes (i.e. ignoring auxiliary nodes like the server loadbalancer)
-			if node.Role == k3d.ServerRole || node.Role == k3d.AgentRole {
-				importWaitgroup.Add(1)
-				go func(node *k3d.Node, wg *sync.WaitGroup, tarPath string) {
-					l.Log().Infof("Importing images from tarball '%s' into node '%s'...", tarPath, node.Name)
-					if err := runtime.ExecInNode(ctx, node, []string{"ctr", "image", "import", tarPath}); err != nil {
-						l.Log().Errorf("failed to import images in node '%s': %v", node.Name, err)
-					}
-					wg.Done()
-				}(node, &importWaitgroup, tarName)
-			}
+		for _, node := range nodes {
+			importWaitgroup.Add(1)
+			go func(node *k3d.Node, wg *sync.WaitGroup, tarPath string) {
+				l.Log().Infof("Importing images from tarball '%s' into node '%s'...", tarPath, node.Name)
+				if err := runtime.ExecInNode(ctx, node, []string{"ctr", "image", "import", tarPath}); err != nil {
+					l.Log().Errorf("failed to import images in node '%s': %v", node.Name, err)
+					importErrsMutex.Lock()
+					importErrs = append(importErrs, fmt.Sprintf("node '%s': %v", node.Name, err))
+					importErrsMutex.Unlock()
+				}
+				wg.Done()
+			}(node, &importWaitgroup, tarName)
 		}
 	}
 	importWaitgroup.Wait()
@@ -111,7 +245,7 @@ func ImageImportIntoClusterMulti(ctx context.Context, runtime runtimes.Runtime,
 	// remove tarball
 	if !opts.KeepTar && len(importTarNames) > 0 {
 		l.Log().Infoln("Removing the tarball(s) from image volume...")
-		if err := runtime.ExecInNode(ctx, toolsNode, []string{"rm", "-f", strings.Join(importTarNames, " ")}); err != nil {
+		if err := runtime.ExecInNode(ctx, toolsNode, append([]string{"rm", "-f"}, importTarNames...)); err != nil {
 			l.Log().Errorf("failed to delete one or more tarballs from '%+v': %v", importTarNames, err)
 		}
 	}
@@ -124,10 +258,10 @@ func ImageImportIntoClusterMulti(ctx context.Context, runtime runtimes.Runtime,
 		}
 	}
 
-	l.Log().Infoln("Successfully imported image(s)")
-
+	if len(importErrs) > 0 {
+		return fmt.Errorf("failed to import images: %s", strings.Join(importErrs, ", "))
+	}
 	return nil
-
 }
 
 type runtimeImageGetter interface {
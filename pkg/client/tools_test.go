@@ -23,12 +23,17 @@ THE SOFTWARE.
 package client
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/go-test/deep"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
 )
 
 func Test_findRuntimeImage(T *testing.T) {
@@ -216,3 +221,36 @@ type FakeRuntimeImageGetter struct {
 func (f *FakeRuntimeImageGetter) GetImages(_ context.Context) ([]string, error) {
 	return f.runtimeImages, nil
 }
+
+func TestImageStreamIntoNodes(t *testing.T) {
+	runtime := &fakeRuntime{
+		failOn: map[string]error{"ExecInNodeWithStdin:k3d-test-agent-0": errors.New("ctr failed")},
+	}
+	nodes := []*k3d.Node{
+		newFakeNode("test", "k3d-test-server-0", k3d.ServerRole, true),
+		newFakeNode("test", "k3d-test-agent-0", k3d.AgentRole, true),
+		newFakeNode("test", "k3d-test-agent-1", k3d.AgentRole, true),
+	}
+	tarball := bytes.Repeat([]byte("layer"), 100000)
+
+	err := imageStreamIntoNodes(context.Background(), runtime, nodes, bytes.NewReader(tarball))
+	if err == nil || !strings.Contains(err.Error(), "k3d-test-agent-0") {
+		t.Fatalf("expected an error naming the failing node, got %v", err)
+	}
+	for _, node := range []string{"k3d-test-server-0", "k3d-test-agent-1"} {
+		if !bytes.Equal(runtime.written["stdin:"+node], tarball) {
+			t.Errorf("expected node '%s' to receive the whole tarball, got %d bytes", node, len(runtime.written["stdin:"+node]))
+		}
+		if cmds := runtime.execs[node]; len(cmds) != 1 || cmds[0] != "ctr image import -" {
+			t.Errorf("expected node '%s' to run 'ctr image import -', got %v", node, cmds)
+		}
+	}
+
+	runtime.failOn = map[string]error{}
+	for _, node := range nodes {
+		runtime.failOn["ExecInNodeWithStdin:"+node.Name] = errors.New("ctr failed")
+	}
+	if err := imageStreamIntoNodes(context.Background(), runtime, nodes, bytes.NewReader(tarball)); err == nil {
+		t.Errorf("expected an error if all nodes fail")
+	}
+}
@@ -0,0 +1,431 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"time"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/rest"
+)
+
+// metricsPollInterval is the time between two checks whether the metrics API became available
+var metricsPollInterval = 2 * time.Second
+
+// ClusterTop returns the current CPU and memory usage of the cluster's nodes and pods as reported by metrics-server.
+// If the metrics API is not available (e.g. because metrics-server was disabled), metrics-server is installed on demand (see opts).
+func ClusterTop(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster, opts k3d.ClusterTopOpts) (*k3d.ClusterTop, error) {
+	restConfig, err := KubeRESTConfig(ctx, runtime, cluster)
+	if err != nil {
+		return nil, err
+	}
+	core, err := newKubeRESTClient(restConfig, "v1")
+	if err != nil {
+		return nil, err
+	}
+	metrics, err := newKubeRESTClient(restConfig, "metrics.k8s.io/v1beta1")
+	if err != nil {
+		return nil, err
+	}
+
+	install := func() error {
+		if !opts.InstallMetricsServer {
+			return fmt.Errorf("metrics API not available in cluster '%s' (was it created with '--k3s-arg --disable=metrics-server'?)", cluster.Name)
+		}
+		return metricsServerInstall(ctx, runtime, cluster)
+	}
+	if err := metricsWaitForAPI(ctx, metrics, opts.Timeout, install); err != nil {
+		return nil, err
+	}
+
+	top, err := topCollect(ctx, core, metrics, opts.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get resource usage of cluster '%s': %w", cluster.Name, err)
+	}
+	top.Cluster = cluster.Name
+
+	return top, nil
+}
+
+// metricsWaitForAPI waits until the metrics API serves node metrics, calling install once if the API is not registered at all
+func metricsWaitForAPI(ctx context.Context, metrics rest.Interface, timeout time.Duration, install func() error) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	installed := false
+	for {
+		err := metrics.Get().Resource("nodes").Do(ctx).Error()
+		switch {
+		case err == nil:
+			return nil
+		case apierrors.IsNotFound(err) && !installed:
+			if err := install(); err != nil {
+				return err
+			}
+			installed = true
+			l.Log().Infof("Waiting for metrics-server to report metrics (this may take a minute)...")
+		case apierrors.IsNotFound(err), apierrors.IsServiceUnavailable(err), apierrors.IsTimeout(err), apierrors.IsServerTimeout(err):
+			l.Log().Debugf("Metrics API not available yet: %v", err)
+		default:
+			return fmt.Errorf("failed to query metrics API: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("metrics API didn't become available within %s: %w", timeout, ctx.Err())
+		case <-time.After(metricsPollInterval):
+		}
+	}
+}
+
+// metricsServerInstall writes the metrics-server manifest into the auto-deploy directory of a running server node,
+// so that k3s applies it like one of its own addons
+func metricsServerInstall(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster) error {
+	var server *k3d.Node
+	for _, node := range cluster.Nodes {
+		if node.Role == k3d.ServerRole && node.State.Running {
+			server = node
+			break
+		}
+	}
+	if server == nil {
+		return fmt.Errorf("failed to install metrics-server: no running server node in cluster '%s'", cluster.Name)
+	}
+
+	l.Log().Infof("Metrics API not available: installing metrics-server into cluster '%s'...", cluster.Name)
+	manifest := fmt.Sprintf(metricsServerManifest, k3d.DefaultMetricsServerImage)
+	if err := runtime.WriteToNode(ctx, []byte(manifest), path.Join(k3d.DefaultManifestsDir, k3d.DefaultMetricsServerManifest), 0644, server); err != nil {
+		return fmt.Errorf("failed to install metrics-server via node '%s': %w", server.Name, err)
+	}
+
+	return nil
+}
+
+// topCollect queries node and pod metrics and relates node usage to the allocatable resources of the nodes
+func topCollect(ctx context.Context, core rest.Interface, metrics rest.Interface, namespace string) (*k3d.ClusterTop, error) {
+	type usage struct {
+		CPU    string `json:"cpu"`
+		Memory string `json:"memory"`
+	}
+	var nodeMetrics struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Usage usage `json:"usage"`
+		} `json:"items"`
+	}
+	var podMetrics struct {
+		Items []struct {
+			Metadata struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+			Containers []struct {
+				Usage usage `json:"usage"`
+			} `json:"containers"`
+		} `json:"items"`
+	}
+	var nodeList struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Status struct {
+				Allocatable usage `json:"allocatable"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+
+	raw, err := metrics.Get().Resource("nodes").Do(ctx).Raw()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node metrics: %w", err)
+	}
+	if err := json.Unmarshal(raw, &nodeMetrics); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal node metrics: %w", err)
+	}
+
+	raw, err = metrics.Get().Namespace(namespace).Resource("pods").Do(ctx).Raw()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pod metrics: %w", err)
+	}
+	if err := json.Unmarshal(raw, &podMetrics); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal pod metrics: %w", err)
+	}
+
+	raw, err = core.Get().Resource("nodes").Do(ctx).Raw()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	if err := json.Unmarshal(raw, &nodeList); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal node list: %w", err)
+	}
+	allocatable := map[string]usage{}
+	for _, node := range nodeList.Items {
+		allocatable[node.Metadata.Name] = node.Status.Allocatable
+	}
+
+	top := &k3d.ClusterTop{Nodes: []*k3d.NodeUsage{}, Pods: []*k3d.PodUsage{}}
+
+	for _, item := range nodeMetrics.Items {
+		node := &k3d.NodeUsage{
+			Name:        item.Metadata.Name,
+			CPUMillis:   quantityMillis(item.Usage.CPU),
+			MemoryBytes: quantityValue(item.Usage.Memory),
+		}
+		if alloc, ok := allocatable[node.Name]; ok {
+			if cpu := quantityMillis(alloc.CPU); cpu > 0 {
+				node.CPUPercent = node.CPUMillis * 100 / cpu
+			}
+			if memory := quantityValue(alloc.Memory); memory > 0 {
+				node.MemoryPercent = node.MemoryBytes * 100 / memory
+			}
+		}
+		top.Nodes = append(top.Nodes, node)
+	}
+	sort.Slice(top.Nodes, func(i, j int) bool { return top.Nodes[i].Name < top.Nodes[j].Name })
+
+	for _, item := range podMetrics.Items {
+		pod := &k3d.PodUsage{Namespace: item.Metadata.Namespace, Name: item.Metadata.Name}
+		for _, container := range item.Containers {
+			pod.CPUMillis += quantityMillis(container.Usage.CPU)
+			pod.MemoryBytes += quantityValue(container.Usage.Memory)
+		}
+		top.Pods = append(top.Pods, pod)
+	}
+	// busiest pods first, like 'kubectl top pods --sort-by=cpu'
+	sort.SliceStable(top.Pods, func(i, j int) bool {
+		if top.Pods[i].CPUMillis != top.Pods[j].CPUMillis {
+			return top.Pods[i].CPUMillis > top.Pods[j].CPUMillis
+		}
+		if top.Pods[i].Namespace != top.Pods[j].Namespace {
+			return top.Pods[i].Namespace < top.Pods[j].Namespace
+		}
+		return top.Pods[i].Name < top.Pods[j].Name
+	})
+
+	return top, nil
+}
+
+// quantityMillis parses a Kubernetes quantity (e.g. "250m"), returning its value in thousandths or 0 if it's invalid
+func quantityMillis(s string) int64 {
+	q, err := resource.ParseQuantity(s)
+	if err != nil {
+		return 0
+	}
+	return q.MilliValue()
+}
+
+// quantityValue parses a Kubernetes quantity (e.g. "128Mi"), returning its value or 0 if it's invalid
+func quantityValue(s string) int64 {
+	q, err := resource.ParseQuantity(s)
+	if err != nil {
+		return 0
+	}
+	return q.Value()
+}
+
+// metricsServerManifest is a trimmed down version of the upstream metrics-server deployment (components.yaml),
+// talking to the kubelets without verifying their self-signed serving certificates. The image is filled in via fmt.Sprintf.
+const metricsServerManifest = `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: metrics-server
+  namespace: kube-system
+  labels:
+    k8s-app: metrics-server
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: system:aggregated-metrics-reader
+  labels:
+    k8s-app: metrics-server
+    rbac.authorization.k8s.io/aggregate-to-admin: "true"
+    rbac.authorization.k8s.io/aggregate-to-edit: "true"
+    rbac.authorization.k8s.io/aggregate-to-view: "true"
+rules:
+- apiGroups: ["metrics.k8s.io"]
+  resources: ["pods", "nodes"]
+  verbs: ["get", "list", "watch"]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: system:metrics-server
+  labels:
+    k8s-app: metrics-server
+rules:
+- apiGroups: [""]
+  resources: ["nodes/metrics"]
+  verbs: ["get"]
+- apiGroups: [""]
+  resources: ["pods", "nodes"]
+  verbs: ["get", "list", "watch"]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: metrics-server-auth-reader
+  namespace: kube-system
+  labels:
+    k8s-app: metrics-server
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: Role
+  name: extension-apiserver-authentication-reader
+subjects:
+- kind: ServiceAccount
+  name: metrics-server
+  namespace: kube-system
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: metrics-server:system:auth-delegator
+  labels:
+    k8s-app: metrics-server
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: system:auth-delegator
+subjects:
+- kind: ServiceAccount
+  name: metrics-server
+  namespace: kube-system
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: system:metrics-server
+  labels:
+    k8s-app: metrics-server
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: system:metrics-server
+subjects:
+- kind: ServiceAccount
+  name: metrics-server
+  namespace: kube-system
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: metrics-server
+  namespace: kube-system
+  labels:
+    k8s-app: metrics-server
+spec:
+  selector:
+    k8s-app: metrics-server
+  ports:
+  - name: https
+    port: 443
+    protocol: TCP
+    targetPort: https
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: metrics-server
+  namespace: kube-system
+  labels:
+    k8s-app: metrics-server
+spec:
+  selector:
+    matchLabels:
+      k8s-app: metrics-server
+  template:
+    metadata:
+      name: metrics-server
+      labels:
+        k8s-app: metrics-server
+    spec:
+      serviceAccountName: metrics-server
+      priorityClassName: system-cluster-critical
+      tolerations:
+      - key: CriticalAddonsOnly
+        operator: Exists
+      - key: node-role.kubernetes.io/control-plane
+        operator: Exists
+        effect: NoSchedule
+      - key: node-role.kubernetes.io/master
+        operator: Exists
+        effect: NoSchedule
+      containers:
+      - name: metrics-server
+        image: %s
+        args:
+        - --cert-dir=/tmp
+        - --secure-port=4443
+        - --kubelet-preferred-address-types=InternalIP,ExternalIP,Hostname
+        - --kubelet-use-node-status-port
+        - --kubelet-insecure-tls
+        - --metric-resolution=15s
+        ports:
+        - name: https
+          containerPort: 4443
+          protocol: TCP
+        readinessProbe:
+          httpGet:
+            path: /readyz
+            port: https
+            scheme: HTTPS
+          periodSeconds: 2
+          failureThreshold: 3
+        securityContext:
+          readOnlyRootFilesystem: true
+          runAsNonRoot: true
+          runAsUser: 1000
+        volumeMounts:
+        - name: tmp-dir
+          mountPath: /tmp
+      volumes:
+      - name: tmp-dir
+        emptyDir: {}
+---
+apiVersion: apiregistration.k8s.io/v1
+kind: APIService
+metadata:
+  name: v1beta1.metrics.k8s.io
+  labels:
+    k8s-app: metrics-server
+spec:
+  service:
+    name: metrics-server
+    namespace: kube-system
+  group: metrics.k8s.io
+  version: v1beta1
+  insecureSkipTLSVerify: true
+  groupPriorityMinVersion: 100
+  versionPriority: 100
+`
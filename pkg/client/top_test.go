@@ -0,0 +1,176 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"k8s.io/client-go/rest"
+)
+
+// fakeMetricsAPI serves the node and pod metrics of the metrics API and the core node list,
+// answering 404 for the metrics API until it's marked available
+type fakeMetricsAPI struct {
+	mu        sync.Mutex
+	available bool
+	podsPath  string
+}
+
+func (f *fakeMetricsAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/api/v1/nodes":
+		_, _ = w.Write([]byte(`{"items": [
+			{"metadata": {"name": "k3d-foo-server-0"}, "status": {"allocatable": {"cpu": "4", "memory": "8Gi"}}},
+			{"metadata": {"name": "k3d-foo-agent-0"}, "status": {"allocatable": {"cpu": "2", "memory": "4Gi"}}}
+		]}`))
+		return
+	}
+
+	if !f.available {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"kind": "Status", "apiVersion": "v1", "status": "Failure", "reason": "NotFound", "code": 404}`))
+		return
+	}
+
+	switch r.URL.Path {
+	case "/apis/metrics.k8s.io/v1beta1/nodes":
+		_, _ = w.Write([]byte(`{"items": [
+			{"metadata": {"name": "k3d-foo-server-0"}, "usage": {"cpu": "1", "memory": "2Gi"}},
+			{"metadata": {"name": "k3d-foo-agent-0"}, "usage": {"cpu": "500m", "memory": "1Gi"}}
+		]}`))
+	default:
+		f.podsPath = r.URL.Path
+		_, _ = w.Write([]byte(`{"items": [
+			{"metadata": {"name": "coredns", "namespace": "kube-system"}, "containers": [{"usage": {"cpu": "3m", "memory": "12Mi"}}]},
+			{"metadata": {"name": "web", "namespace": "default"}, "containers": [{"usage": {"cpu": "100m", "memory": "64Mi"}}, {"usage": {"cpu": "20m", "memory": "16Mi"}}]}
+		]}`))
+	}
+}
+
+func newFakeMetricsAPIClients(t *testing.T, api *fakeMetricsAPI) (*rest.RESTClient, *rest.RESTClient) {
+	t.Helper()
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+	core, err := newKubeRESTClient(&rest.Config{Host: server.URL}, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics, err := newKubeRESTClient(&rest.Config{Host: server.URL}, "metrics.k8s.io/v1beta1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return core, metrics
+}
+
+func TestMetricsWaitForAPIInstalls(t *testing.T) {
+	metricsPollInterval = 10 * time.Millisecond
+
+	api := &fakeMetricsAPI{}
+	_, metrics := newFakeMetricsAPIClients(t, api)
+
+	installs := 0
+	install := func() error {
+		installs++
+		api.mu.Lock()
+		api.available = true
+		api.mu.Unlock()
+		return nil
+	}
+
+	if err := metricsWaitForAPI(context.Background(), metrics, time.Second, install); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if installs != 1 {
+		t.Errorf("expected metrics-server to be installed once, got %d", installs)
+	}
+}
+
+func TestMetricsWaitForAPITimeout(t *testing.T) {
+	metricsPollInterval = 10 * time.Millisecond
+
+	_, metrics := newFakeMetricsAPIClients(t, &fakeMetricsAPI{})
+
+	installs := 0
+	err := metricsWaitForAPI(context.Background(), metrics, 100*time.Millisecond, func() error { installs++; return nil })
+	if err == nil {
+		t.Fatalf("expected an error if the metrics API never becomes available")
+	}
+	if installs != 1 {
+		t.Errorf("expected metrics-server to be installed only once, got %d", installs)
+	}
+}
+
+func TestTopCollect(t *testing.T) {
+	api := &fakeMetricsAPI{available: true}
+	core, metrics := newFakeMetricsAPIClients(t, api)
+
+	top, err := topCollect(context.Background(), core, metrics, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if api.podsPath != "/apis/metrics.k8s.io/v1beta1/pods" {
+		t.Errorf("expected pods of all namespaces to be queried, got '%s'", api.podsPath)
+	}
+
+	expectedNodes := []k3d.NodeUsage{
+		{Name: "k3d-foo-agent-0", CPUMillis: 500, CPUPercent: 25, MemoryBytes: 1 << 30, MemoryPercent: 25},
+		{Name: "k3d-foo-server-0", CPUMillis: 1000, CPUPercent: 25, MemoryBytes: 2 << 30, MemoryPercent: 25},
+	}
+	if len(top.Nodes) != len(expectedNodes) {
+		t.Fatalf("expected %d nodes, got %d", len(expectedNodes), len(top.Nodes))
+	}
+	for i, expected := range expectedNodes {
+		if *top.Nodes[i] != expected {
+			t.Errorf("expected node usage %+v, got %+v", expected, *top.Nodes[i])
+		}
+	}
+
+	expectedPods := []k3d.PodUsage{
+		{Namespace: "default", Name: "web", CPUMillis: 120, MemoryBytes: 80 << 20},
+		{Namespace: "kube-system", Name: "coredns", CPUMillis: 3, MemoryBytes: 12 << 20},
+	}
+	if len(top.Pods) != len(expectedPods) {
+		t.Fatalf("expected %d pods, got %d", len(expectedPods), len(top.Pods))
+	}
+	for i, expected := range expectedPods {
+		if *top.Pods[i] != expected {
+			t.Errorf("expected pod usage %+v, got %+v", expected, *top.Pods[i])
+		}
+	}
+
+	if _, err := topCollect(context.Background(), core, metrics, "default"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if api.podsPath != "/apis/metrics.k8s.io/v1beta1/namespaces/default/pods" {
+		t.Errorf("expected pods of namespace 'default' to be queried, got '%s'", api.podsPath)
+	}
+}
@@ -0,0 +1,135 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	"github.com/docker/go-connections/nat"
+	runtimeutil "github.com/rancher/k3d/v5/pkg/runtimes/util"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// ValidationError collects all problems found while validating a cluster spec, so that they can be fixed at once
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return fmt.Sprintf("invalid cluster spec: %s", e.Problems[0])
+	}
+	return fmt.Sprintf("invalid cluster spec (%d problems):\n - %s", len(e.Problems), strings.Join(e.Problems, "\n - "))
+}
+
+// Add records a problem
+func (e *ValidationError) Add(format string, args ...interface{}) {
+	e.Problems = append(e.Problems, fmt.Sprintf(format, args...))
+}
+
+// ErrorOrNil returns the ValidationError, if any problem was recorded, and nil otherwise
+func (e *ValidationError) ErrorOrNil() error {
+	if len(e.Problems) == 0 {
+		return nil
+	}
+	return e
+}
+
+// ClusterValidate checks a cluster spec for problems that don't need the runtime to be detected (name rules, host port collisions,
+// volume syntax, environment variable format, image references) and returns all of them in a single ValidationError.
+// It's used before creating a cluster, no matter if the spec comes from flags, a config file or a library user.
+func ClusterValidate(cluster *k3d.Cluster) error {
+	problems := &ValidationError{}
+	clusterValidate(cluster, problems)
+	return problems.ErrorOrNil()
+}
+
+// clusterValidate records the problems of the cluster spec in the given ValidationError
+func clusterValidate(cluster *k3d.Cluster, problems *ValidationError) {
+	if err := CheckName(cluster.Name); err != nil {
+		problems.Add("cluster name '%s': %v", cluster.Name, err)
+	}
+
+	var hostPorts []hostPortBinding
+	for _, node := range cluster.Nodes {
+		if err := ValidateHostname(node.Name); err != nil {
+			problems.Add("node name '%s': %v", node.Name, err)
+		}
+
+		if node.Image != "" {
+			if _, err := reference.ParseNormalizedNamed(node.Image); err != nil {
+				problems.Add("node '%s': invalid image reference '%s': %v", node.Name, node.Image, err)
+			}
+		}
+
+		for _, volume := range node.Volumes {
+			_, dest, _, err := runtimeutil.SplitVolumeMount(volume)
+			if err != nil {
+				problems.Add("node '%s': %v", node.Name, err)
+			} else if !strings.HasPrefix(dest, "/") {
+				problems.Add("node '%s': destination of volume mount '%s' is not an absolute path", node.Name, volume)
+			}
+		}
+
+		for _, env := range node.Env {
+			if key := strings.SplitN(env, "=", 2)[0]; key == "" || strings.ContainsAny(key, " \t\n") {
+				problems.Add("node '%s': invalid environment variable '%s' (format: 'KEY[=VALUE]')", node.Name, env)
+			}
+		}
+
+		ports := make([]nat.Port, 0, len(node.Ports))
+		for port := range node.Ports {
+			ports = append(ports, port)
+		}
+		sort.Slice(ports, func(i, j int) bool { return ports[i] < ports[j] })
+		for _, port := range ports {
+			for _, binding := range node.Ports[port] {
+				if binding.HostPort == "" || binding.HostPort == "0" {
+					continue // random host port
+				}
+				current := hostPortBinding{ip: binding.HostIP, port: binding.HostPort, proto: port.Proto(), node: node.Name}
+				if current.ip == "0.0.0.0" || current.ip == "::" {
+					current.ip = ""
+				}
+				for _, other := range hostPorts {
+					if other.collidesWith(current) {
+						problems.Add("host port %s/%s is mapped more than once (nodes '%s' and '%s')", current.port, current.proto, other.node, current.node)
+						break
+					}
+				}
+				hostPorts = append(hostPorts, current)
+			}
+		}
+	}
+}
+
+// hostPortBinding is a host port mapped by a node (an empty ip means all interfaces)
+type hostPortBinding struct {
+	ip, port, proto, node string
+}
+
+func (b hostPortBinding) collidesWith(other hostPortBinding) bool {
+	return b.port == other.port && b.proto == other.proto && (b.ip == other.ip || b.ip == "" || other.ip == "")
+}
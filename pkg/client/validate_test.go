@@ -0,0 +1,78 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/docker/go-connections/nat"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestClusterValidate(t *testing.T) {
+	validNode := func(name string) *k3d.Node {
+		return &k3d.Node{
+			Name:    name,
+			Image:   "rancher/k3s:v1.21.4-k3s2",
+			Volumes: []string{"/tmp/src:/src:ro", "k3d-test-images:/k3d/images"},
+			Env:     []string{"K3S_TOKEN=abc", "DEBUG"},
+			Ports:   nat.PortMap{"80/tcp": {{HostIP: "127.0.0.1", HostPort: "8080"}}, "6443/tcp": {{HostIP: "0.0.0.0", HostPort: ""}}},
+		}
+	}
+
+	valid := &k3d.Cluster{Name: "test", Nodes: []*k3d.Node{validNode("k3d-test-server-0")}}
+	if err := ClusterValidate(valid); err != nil {
+		t.Errorf("expected a valid cluster spec, got %v", err)
+	}
+
+	server := validNode("k3d-test-server-0")
+	server.Image = "rancher/K3S:latest"
+	server.Volumes = append(server.Volumes, "/tmp/src:relative")
+	server.Env = append(server.Env, "=value")
+	agent := validNode("k3d_test_agent_0")
+	agent.Ports = nat.PortMap{"80/tcp": {{HostPort: "8080"}}, "80/udp": {{HostPort: "8080"}}}
+	invalid := &k3d.Cluster{Name: "-test", Nodes: []*k3d.Node{server, agent}}
+
+	err := ClusterValidate(invalid)
+	var problems *ValidationError
+	if !errors.As(err, &problems) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+	expected := []string{
+		"cluster name '-test'",
+		"invalid image reference 'rancher/K3S:latest'",
+		"volume mount '/tmp/src:relative' is not an absolute path",
+		"invalid environment variable '=value'",
+		"node name 'k3d_test_agent_0'",
+		"host port 8080/tcp is mapped more than once (nodes 'k3d-test-server-0' and 'k3d_test_agent_0')",
+	}
+	if len(problems.Problems) != len(expected) {
+		t.Errorf("expected %d problems, got %d:\n%s", len(expected), len(problems.Problems), err)
+	}
+	for _, e := range expected {
+		if !strings.Contains(err.Error(), e) {
+			t.Errorf("expected the problems to include %q, got:\n%s", e, err)
+		}
+	}
+}
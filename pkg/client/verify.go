@@ -0,0 +1,232 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// verifyPodTimeout is the maximum time a single verification pod may take (including the image pull)
+var verifyPodTimeout = 2 * time.Minute
+
+// verifyHTTPTimeout is the maximum time to wait for a response when checking the published ingress ports
+var verifyHTTPTimeout = 5 * time.Second
+
+// ClusterVerify runs a set of smoke tests against a running cluster:
+// node readiness, in-cluster DNS resolution, service connectivity and ingress reachability via the published ports
+func ClusterVerify(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster) (*k3d.ClusterVerifyReport, error) {
+	report := &k3d.ClusterVerifyReport{
+		Cluster: cluster.Name,
+		Passed:  true,
+	}
+
+	suffix := fmt.Sprintf("%d", time.Now().Unix())
+
+	checks := []func() *k3d.VerifyCheck{
+		func() *k3d.VerifyCheck { return verifyNodesReady(ctx, runtime, cluster) },
+		func() *k3d.VerifyCheck {
+			return verifyInPod(ctx, runtime, cluster, "dns", "k3d-verify-dns-"+suffix, "nslookup kubernetes.default.svc.cluster.local")
+		},
+		func() *k3d.VerifyCheck {
+			return verifyInPod(ctx, runtime, cluster, "service", "k3d-verify-svc-"+suffix, "nc -w 5 kubernetes.default.svc.cluster.local 443 </dev/null")
+		},
+		func() *k3d.VerifyCheck { return verifyIngress(ctx, cluster) },
+	}
+
+	for _, check := range checks {
+		result := check()
+		l.Log().Debugf("Verification check '%s': %s (%s)", result.Name, result.Result, result.Details)
+		if result.Result == k3d.VerifyResultFail {
+			report.Passed = false
+		}
+		report.Checks = append(report.Checks, result)
+	}
+
+	return report, nil
+}
+
+// verifyNodesReady checks that all k3s nodes of the cluster are registered and Ready
+func verifyNodesReady(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster) *k3d.VerifyCheck {
+	check := &k3d.VerifyCheck{Name: "nodes"}
+
+	client, err := KubeRESTClient(ctx, runtime, cluster, "v1")
+	if err != nil {
+		check.Result, check.Details = k3d.VerifyResultFail, err.Error()
+		return check
+	}
+
+	raw, err := client.Get().Resource("nodes").Do(ctx).Raw()
+	if err != nil {
+		check.Result, check.Details = k3d.VerifyResultFail, fmt.Sprintf("failed to list nodes: %v", err)
+		return check
+	}
+
+	expected := 0
+	for _, node := range cluster.Nodes {
+		if node.Role == k3d.ServerRole || node.Role == k3d.AgentRole {
+			expected++
+		}
+	}
+
+	return evaluateNodeList(raw, expected)
+}
+
+// evaluateNodeList checks a Kubernetes node list (raw JSON) for the expected number of Ready nodes
+func evaluateNodeList(raw []byte, expected int) *k3d.VerifyCheck {
+	check := &k3d.VerifyCheck{Name: "nodes"}
+
+	var nodeList struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Status struct {
+				Conditions []struct {
+					Type   string `json:"type"`
+					Status string `json:"status"`
+				} `json:"conditions"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(raw, &nodeList); err != nil {
+		check.Result, check.Details = k3d.VerifyResultFail, fmt.Sprintf("failed to unmarshal node list: %v", err)
+		return check
+	}
+
+	notReady := []string{}
+	for _, node := range nodeList.Items {
+		ready := false
+		for _, cond := range node.Status.Conditions {
+			if cond.Type == "Ready" && cond.Status == "True" {
+				ready = true
+			}
+		}
+		if !ready {
+			notReady = append(notReady, node.Metadata.Name)
+		}
+	}
+
+	switch {
+	case len(notReady) > 0:
+		check.Result, check.Details = k3d.VerifyResultFail, fmt.Sprintf("nodes not ready: %s", strings.Join(notReady, ", "))
+	case len(nodeList.Items) < expected:
+		check.Result, check.Details = k3d.VerifyResultFail, fmt.Sprintf("only %d of %d nodes registered", len(nodeList.Items), expected)
+	default:
+		check.Result, check.Details = k3d.VerifyResultPass, fmt.Sprintf("%d nodes ready", len(nodeList.Items))
+	}
+
+	return check
+}
+
+// verifyInPod runs the given shell command in a short-lived pod and passes if it exits with 0
+func verifyInPod(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster, checkName string, podName string, command string) *k3d.VerifyCheck {
+	check := &k3d.VerifyCheck{Name: checkName}
+
+	var out bytes.Buffer
+	exitCode, err := PodRun(ctx, runtime, cluster, k3d.PodRunOpts{
+		Name:      podName,
+		Image:     k3d.DefaultVerifyImage,
+		Namespace: "default",
+		Command:   []string{"sh", "-c", command},
+		Timeout:   verifyPodTimeout,
+	}, &out)
+
+	switch {
+	case err != nil:
+		check.Result, check.Details = k3d.VerifyResultFail, err.Error()
+	case exitCode != 0:
+		check.Result, check.Details = k3d.VerifyResultFail, fmt.Sprintf("'%s' exited with code %d: %s", command, exitCode, strings.TrimSpace(out.String()))
+	default:
+		check.Result, check.Details = k3d.VerifyResultPass, fmt.Sprintf("'%s' succeeded", command)
+	}
+
+	return check
+}
+
+// verifyIngress checks that the ports published for the ingress (80/443 on the loadbalancer) answer HTTP requests
+func verifyIngress(ctx context.Context, cluster *k3d.Cluster) *k3d.VerifyCheck {
+	check := &k3d.VerifyCheck{Name: "ingress"}
+
+	if cluster.ServerLoadBalancer == nil || cluster.ServerLoadBalancer.Node == nil {
+		check.Result, check.Details = k3d.VerifyResultSkip, "cluster has no loadbalancer"
+		return check
+	}
+
+	urls := []string{}
+	for port, bindings := range cluster.ServerLoadBalancer.Node.Ports {
+		scheme := ""
+		switch port.Port() {
+		case "80":
+			scheme = "http"
+		case "443":
+			scheme = "https"
+		default:
+			continue
+		}
+		for _, binding := range bindings {
+			host := binding.HostIP
+			if host == "" || host == k3d.DefaultAPIHost {
+				host = "localhost"
+			}
+			urls = append(urls, fmt.Sprintf("%s://%s:%s/", scheme, host, binding.HostPort))
+		}
+	}
+
+	if len(urls) == 0 {
+		check.Result, check.Details = k3d.VerifyResultSkip, "no ports published for the ingress (80/443)"
+		return check
+	}
+
+	// the ingress controller serves a self-signed default certificate, so we only care about reachability here
+	httpClient := &http.Client{
+		Timeout:   verifyHTTPTimeout,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+	}
+	for _, url := range urls {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			check.Result, check.Details = k3d.VerifyResultFail, err.Error()
+			return check
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			check.Result, check.Details = k3d.VerifyResultFail, fmt.Sprintf("%s unreachable: %v", url, err)
+			return check
+		}
+		resp.Body.Close()
+	}
+
+	// any HTTP response (even a 404 from the ingress controller) means that the traffic made it into the cluster
+	check.Result, check.Details = k3d.VerifyResultPass, fmt.Sprintf("%s reachable", strings.Join(urls, ", "))
+	return check
+}
@@ -0,0 +1,110 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/docker/go-connections/nat"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestEvaluateNodeList(t *testing.T) {
+	nodeList := func(nodes ...string) []byte {
+		raw := `{"items": [`
+		for i, node := range nodes {
+			if i > 0 {
+				raw += ","
+			}
+			raw += node
+		}
+		return []byte(raw + `]}`)
+	}
+	ready := `{"metadata": {"name": "%s"}, "status": {"conditions": [{"type": "Ready", "status": "True"}]}}`
+	notReady := `{"metadata": {"name": "k3d-test-agent-0"}, "status": {"conditions": [{"type": "Ready", "status": "False"}]}}`
+
+	tests := map[string]struct {
+		raw      []byte
+		expected int
+		result   k3d.VerifyResult
+	}{
+		"all ready":      {raw: nodeList(fmt.Sprintf(ready, "k3d-test-server-0"), fmt.Sprintf(ready, "k3d-test-agent-0")), expected: 2, result: k3d.VerifyResultPass},
+		"not ready":      {raw: nodeList(fmt.Sprintf(ready, "k3d-test-server-0"), notReady), expected: 2, result: k3d.VerifyResultFail},
+		"not registered": {raw: nodeList(fmt.Sprintf(ready, "k3d-test-server-0")), expected: 2, result: k3d.VerifyResultFail},
+		"invalid json":   {raw: []byte("{"), expected: 1, result: k3d.VerifyResultFail},
+	}
+
+	for name, tc := range tests {
+		check := evaluateNodeList(tc.raw, tc.expected)
+		if check.Result != tc.result {
+			t.Errorf("%s: expected result '%s', got '%s' (%s)", name, tc.result, check.Result, check.Details)
+		}
+	}
+}
+
+func TestVerifyIngress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r) // any HTTP response counts as reachable
+	}))
+	defer server.Close()
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clusterWithPort := func(hostPort string) *k3d.Cluster {
+		return &k3d.Cluster{
+			Name: "test",
+			ServerLoadBalancer: &k3d.Loadbalancer{
+				Node: &k3d.Node{
+					Name:  "k3d-test-serverlb",
+					Role:  k3d.LoadBalancerRole,
+					Ports: nat.PortMap{"80/tcp": []nat.PortBinding{{HostIP: "127.0.0.1", HostPort: hostPort}}},
+				},
+			},
+		}
+	}
+
+	if check := verifyIngress(context.Background(), clusterWithPort(port)); check.Result != k3d.VerifyResultPass {
+		t.Errorf("expected reachable ingress to pass, got '%s' (%s)", check.Result, check.Details)
+	}
+
+	server.Close()
+	if check := verifyIngress(context.Background(), clusterWithPort(port)); check.Result != k3d.VerifyResultFail {
+		t.Errorf("expected unreachable ingress to fail, got '%s' (%s)", check.Result, check.Details)
+	}
+
+	if check := verifyIngress(context.Background(), &k3d.Cluster{Name: "test"}); check.Result != k3d.VerifyResultSkip {
+		t.Errorf("expected cluster without loadbalancer to be skipped, got '%s'", check.Result)
+	}
+
+	noIngressPorts := clusterWithPort(port)
+	noIngressPorts.ServerLoadBalancer.Node.Ports = nat.PortMap{"6443/tcp": []nat.PortBinding{{HostPort: "6550"}}}
+	if check := verifyIngress(context.Background(), noIngressPorts); check.Result != k3d.VerifyResultSkip {
+		t.Errorf("expected loadbalancer without ingress ports to be skipped, got '%s'", check.Result)
+	}
+}
@@ -0,0 +1,152 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"bytes"
+	"fmt"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// virtualWorkersControllerManifest deploys kwok, which keeps the fake nodes (and the pods scheduled onto them) alive
+var virtualWorkersControllerManifest = `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: kwok-controller
+  namespace: kube-system
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: kwok-controller
+rules:
+- apiGroups: [""]
+  resources: ["nodes", "nodes/status", "pods", "pods/status"]
+  verbs: ["get", "list", "watch", "create", "update", "patch", "delete"]
+- apiGroups: ["coordination.k8s.io"]
+  resources: ["leases"]
+  verbs: ["get", "list", "watch", "create", "update", "patch", "delete"]
+- apiGroups: [""]
+  resources: ["events"]
+  verbs: ["create", "patch", "update"]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: kwok-controller
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: kwok-controller
+subjects:
+- kind: ServiceAccount
+  name: kwok-controller
+  namespace: kube-system
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: kwok-controller
+  namespace: kube-system
+  labels:
+    app: kwok-controller
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: kwok-controller
+  template:
+    metadata:
+      labels:
+        app: kwok-controller
+    spec:
+      serviceAccountName: kwok-controller
+      containers:
+      - name: kwok-controller
+        image: %s
+        args:
+        - --manage-all-nodes=false
+        - --manage-nodes-with-annotation-selector=%s=fake
+        - --node-lease-duration-seconds=40
+        - --cidr=10.0.0.1/24
+        - --node-ip=$(POD_IP)
+        env:
+        - name: POD_IP
+          valueFrom:
+            fieldRef:
+              fieldPath: status.podIP
+`
+
+// virtualWorkerNodeManifest describes a single fake node, which is only backed by the kwok controller
+var virtualWorkerNodeManifest = `apiVersion: v1
+kind: Node
+metadata:
+  name: %s
+  annotations:
+    %s: fake
+  labels:
+    type: kwok
+    kubernetes.io/hostname: %s
+    kubernetes.io/os: linux
+    kubernetes.io/role: agent
+    node-role.kubernetes.io/agent: ""
+spec:
+  taints:
+  - key: %s
+    value: fake
+    effect: NoSchedule
+status:
+  allocatable:
+    cpu: "%s"
+    memory: %s
+    pods: "110"
+  capacity:
+    cpu: "%s"
+    memory: %s
+    pods: "110"
+`
+
+// VirtualWorkersGenerateManifest generates the manifest deploying the kwok controller and registering count fake nodes for the given cluster
+func VirtualWorkersGenerateManifest(clusterName string, count int) ([]byte, error) {
+	if count < 1 {
+		return nil, fmt.Errorf("number of virtual workers must be greater than 0 (got %d)", count)
+	}
+
+	var manifest bytes.Buffer
+	manifest.WriteString(fmt.Sprintf(virtualWorkersControllerManifest, k3d.DefaultVirtualWorkersImage, k3d.VirtualWorkerAnnotation))
+
+	for i := 0; i < count; i++ {
+		name := fmt.Sprintf("%s-%s-virtual-%d", k3d.DefaultObjectNamePrefix, clusterName, i)
+		manifest.WriteString("---\n")
+		manifest.WriteString(fmt.Sprintf(virtualWorkerNodeManifest,
+			name,
+			k3d.VirtualWorkerAnnotation,
+			name,
+			k3d.VirtualWorkerAnnotation,
+			k3d.DefaultVirtualWorkerCPUs, k3d.DefaultVirtualWorkerMemory,
+			k3d.DefaultVirtualWorkerCPUs, k3d.DefaultVirtualWorkerMemory,
+		))
+	}
+
+	return manifest.Bytes(), nil
+}
@@ -0,0 +1,48 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVirtualWorkersGenerateManifest(t *testing.T) {
+	manifest, err := VirtualWorkersGenerateManifest("test", 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if count := strings.Count(string(manifest), "\nkind: Node\n"); count != 3 {
+		t.Errorf("expected 3 virtual nodes, got %d", count)
+	}
+
+	for _, name := range []string{"k3d-test-virtual-0", "k3d-test-virtual-2"} {
+		if !strings.Contains(string(manifest), "name: "+name+"\n") {
+			t.Errorf("manifest is missing node %s", name)
+		}
+	}
+
+	if _, err := VirtualWorkersGenerateManifest("test", 0); err == nil {
+		t.Errorf("expected an error for zero virtual workers")
+	}
+}
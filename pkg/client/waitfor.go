@@ -0,0 +1,273 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/rest"
+)
+
+// waitForKind describes how to fetch a kind of Kubernetes resource and when to consider it ready
+type waitForKind struct {
+	groupVersion string
+	resource     string
+	namespaced   bool
+	ready        func(obj map[string]interface{}) bool
+}
+
+// waitForKinds maps the supported kinds (incl. their common short names) to their readiness definition
+var waitForKinds = map[string]waitForKind{
+	"deployment":               {"apps/v1", "deployments", true, replicasReady("availableReplicas")},
+	"statefulset":              {"apps/v1", "statefulsets", true, replicasReady("readyReplicas")},
+	"daemonset":                {"apps/v1", "daemonsets", true, daemonSetReady},
+	"pod":                      {"v1", "pods", true, conditionTrue("Ready")},
+	"job":                      {"batch/v1", "jobs", true, conditionTrue("Complete")},
+	"node":                     {"v1", "nodes", false, conditionTrue("Ready")},
+	"customresourcedefinition": {"apiextensions.k8s.io/v1", "customresourcedefinitions", false, conditionTrue("Established")},
+}
+
+var waitForKindAliases = map[string]string{
+	"deploy": "deployment",
+	"sts":    "statefulset",
+	"ds":     "daemonset",
+	"po":     "pod",
+	"no":     "node",
+	"crd":    "customresourcedefinition",
+}
+
+// WaitPollInterval is the interval in which resources, servers and restarting nodes are checked again while waiting for them to become ready
+var WaitPollInterval = 2 * time.Second
+
+// WaitForResource is a parsed reference to a Kubernetes resource to wait for
+type WaitForResource struct {
+	Namespace string
+	Kind      string
+	Name      string
+}
+
+func (r WaitForResource) String() string {
+	if r.Namespace == "" {
+		return fmt.Sprintf("%s/%s", r.Kind, r.Name)
+	}
+	return fmt.Sprintf("%s/%s/%s", r.Namespace, r.Kind, r.Name)
+}
+
+// ParseWaitForResource parses a resource reference in the format [NAMESPACE/]KIND/NAME and resolves the kind.
+// Namespaced resources default to the 'default' namespace, cluster-scoped resources must not have a namespace.
+func ParseWaitForResource(ref string) (*WaitForResource, error) {
+	parts := strings.Split(ref, "/")
+	if len(parts) == 2 {
+		parts = append([]string{""}, parts...)
+	}
+	if len(parts) != 3 || parts[1] == "" || parts[2] == "" {
+		return nil, fmt.Errorf("invalid resource '%s': expected format [NAMESPACE/]KIND/NAME (e.g. kube-system/deployment/traefik)", ref)
+	}
+
+	kind, ok := resolveWaitForKind(parts[1])
+	if !ok {
+		return nil, fmt.Errorf("unsupported kind '%s' in resource '%s'", parts[1], ref)
+	}
+
+	resource := &WaitForResource{Namespace: parts[0], Kind: kind, Name: parts[2]}
+	if waitForKinds[kind].namespaced {
+		if resource.Namespace == "" {
+			resource.Namespace = "default"
+		}
+	} else if resource.Namespace != "" {
+		return nil, fmt.Errorf("invalid resource '%s': %s is not namespaced", ref, kind)
+	}
+
+	return resource, nil
+}
+
+// resolveWaitForKind resolves a (possibly plural or abbreviated) kind to one of the supported kinds
+func resolveWaitForKind(kind string) (string, bool) {
+	kind = strings.ToLower(kind)
+	// try the plain kind first, as some short names end with an "s" themselves (e.g. ds, sts)
+	for _, candidate := range []string{kind, strings.TrimSuffix(kind, "s")} {
+		if alias, ok := waitForKindAliases[candidate]; ok {
+			candidate = alias
+		}
+		if _, ok := waitForKinds[candidate]; ok {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// ClusterWaitForResources blocks until all given resources (format [NAMESPACE/]KIND/NAME) are ready or the timeout is reached.
+func ClusterWaitForResources(ctx context.Context, runtime runtimes.Runtime, cluster *k3d.Cluster, refs []string, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	resources := make([]*WaitForResource, 0, len(refs))
+	for _, ref := range refs {
+		resource, err := ParseWaitForResource(ref)
+		if err != nil {
+			return err
+		}
+		resources = append(resources, resource)
+	}
+
+	getter := &waitForGetter{runtime: runtime, cluster: cluster, clients: map[string]*rest.RESTClient{}}
+
+	for _, resource := range resources {
+		l.Log().Infof("Waiting for %s to be ready...", resource)
+		if err := waitForReady(ctx, getter.get, resource); err != nil {
+			return err
+		}
+		l.Log().Infof("%s is ready", resource)
+	}
+
+	return nil
+}
+
+// waitForReady polls the given resource until it is ready or the context is done
+func waitForReady(ctx context.Context, get func(context.Context, *WaitForResource) ([]byte, error), resource *WaitForResource) error {
+	for {
+		// neither the kubeconfig nor the resource may exist yet (e.g. while k3s is still starting or deploying its manifests),
+		// so errors only mean "not ready"
+		raw, err := get(ctx, resource)
+		if err == nil {
+			obj := map[string]interface{}{}
+			if err := json.Unmarshal(raw, &obj); err != nil {
+				return fmt.Errorf("failed to unmarshal %s: %w", resource, err)
+			}
+			if waitForKinds[resource.Kind].ready(obj) {
+				return nil
+			}
+		} else {
+			l.Log().Tracef("%s not available yet: %v", resource, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for %s to be ready: %w", resource, ctx.Err())
+		case <-time.After(WaitPollInterval):
+		}
+	}
+}
+
+// waitForGetter fetches resources from the cluster, creating the REST config once and one REST client per API group version
+type waitForGetter struct {
+	runtime    runtimes.Runtime
+	cluster    *k3d.Cluster
+	restConfig *rest.Config
+	clients    map[string]*rest.RESTClient
+}
+
+// client returns the (cached) REST client for the given API group version
+func (g *waitForGetter) client(ctx context.Context, groupVersion string) (*rest.RESTClient, error) {
+	if client, ok := g.clients[groupVersion]; ok {
+		return client, nil
+	}
+	if g.restConfig == nil {
+		restConfig, err := KubeRESTConfig(ctx, g.runtime, g.cluster)
+		if err != nil {
+			return nil, err
+		}
+		g.restConfig = restConfig
+	}
+	client, err := newKubeRESTClient(g.restConfig, groupVersion)
+	if err != nil {
+		return nil, err
+	}
+	g.clients[groupVersion] = client
+	return client, nil
+}
+
+// get fetches the raw JSON representation of the given resource
+func (g *waitForGetter) get(ctx context.Context, resource *WaitForResource) ([]byte, error) {
+	waitFor := waitForKinds[resource.Kind]
+
+	client, err := g.client(ctx, waitFor.groupVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	req := client.Get().Resource(waitFor.resource).Name(resource.Name)
+	if waitFor.namespaced {
+		req = req.Namespace(resource.Namespace)
+	}
+
+	return req.Do(ctx).Raw()
+}
+
+// replicasReady returns a readiness check comparing the given status field to the desired number of replicas
+func replicasReady(statusField string) func(map[string]interface{}) bool {
+	return func(obj map[string]interface{}) bool {
+		desired, found, _ := unstructured.NestedFieldNoCopy(obj, "spec", "replicas")
+		if !found {
+			desired = float64(1)
+		}
+		current, _, _ := unstructured.NestedFieldNoCopy(obj, "status", statusField)
+		desiredNum, _ := desired.(float64)
+		currentNum, _ := current.(float64)
+		return observedLatest(obj) && currentNum >= desiredNum
+	}
+}
+
+func daemonSetReady(obj map[string]interface{}) bool {
+	desired, _, _ := unstructured.NestedFieldNoCopy(obj, "status", "desiredNumberScheduled")
+	ready, _, _ := unstructured.NestedFieldNoCopy(obj, "status", "numberReady")
+	desiredNum, _ := desired.(float64)
+	readyNum, _ := ready.(float64)
+	return observedLatest(obj) && readyNum >= desiredNum
+}
+
+// observedLatest checks that the controller has seen the latest generation of the object
+func observedLatest(obj map[string]interface{}) bool {
+	generation, _, _ := unstructured.NestedFieldNoCopy(obj, "metadata", "generation")
+	observed, found, _ := unstructured.NestedFieldNoCopy(obj, "status", "observedGeneration")
+	if !found {
+		return false
+	}
+	generationNum, _ := generation.(float64)
+	observedNum, _ := observed.(float64)
+	return observedNum >= generationNum
+}
+
+// conditionTrue returns a readiness check for the given status condition
+func conditionTrue(conditionType string) func(map[string]interface{}) bool {
+	return func(obj map[string]interface{}) bool {
+		conditions, _, _ := unstructured.NestedSlice(obj, "status", "conditions")
+		for _, c := range conditions {
+			condition, ok := c.(map[string]interface{})
+			if ok && condition["type"] == conditionType && condition["status"] == "True" {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,160 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestParseWaitForResource(t *testing.T) {
+	tests := map[string]struct {
+		ref       string
+		expected  WaitForResource
+		expectErr bool
+	}{
+		"full kind":         {ref: "deployment/traefik", expected: WaitForResource{Namespace: "default", Kind: "deployment", Name: "traefik"}},
+		"plural kind":       {ref: "Deployments/traefik", expected: WaitForResource{Namespace: "default", Kind: "deployment", Name: "traefik"}},
+		"with namespace":    {ref: "kube-system/deployment/traefik", expected: WaitForResource{Namespace: "kube-system", Kind: "deployment", Name: "traefik"}},
+		"alias":             {ref: "crd/helmcharts.helm.cattle.io", expected: WaitForResource{Kind: "customresourcedefinition", Name: "helmcharts.helm.cattle.io"}},
+		"plural alias":      {ref: "crds/helmcharts.helm.cattle.io", expected: WaitForResource{Kind: "customresourcedefinition", Name: "helmcharts.helm.cattle.io"}},
+		"plural deploy":     {ref: "deploys/traefik", expected: WaitForResource{Namespace: "default", Kind: "deployment", Name: "traefik"}},
+		"alias ending in s": {ref: "ds/svclb", expected: WaitForResource{Namespace: "default", Kind: "daemonset", Name: "svclb"}},
+		"sts alias":         {ref: "monitoring/sts/prometheus", expected: WaitForResource{Namespace: "monitoring", Kind: "statefulset", Name: "prometheus"}},
+		"cluster-scoped":    {ref: "nodes/k3d-test-server-0", expected: WaitForResource{Kind: "node", Name: "k3d-test-server-0"}},
+		"namespaced node":   {ref: "default/node/k3d-test-server-0", expectErr: true},
+		"no name":           {ref: "deployment/", expectErr: true},
+		"no kind":           {ref: "traefik", expectErr: true},
+		"empty kind":        {ref: "kube-system//traefik", expectErr: true},
+		"too many segments": {ref: "a/kube-system/deployment/traefik", expectErr: true},
+		"unsupported":       {ref: "service/traefik", expectErr: true},
+	}
+
+	for name, tc := range tests {
+		resource, err := ParseWaitForResource(tc.ref)
+		if tc.expectErr {
+			if err == nil {
+				t.Errorf("%s: expected an error for '%s'", name, tc.ref)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+			continue
+		}
+		if *resource != tc.expected {
+			t.Errorf("%s: expected %+v, got %+v", name, tc.expected, *resource)
+		}
+	}
+}
+
+func TestWaitForReadyPolls(t *testing.T) {
+	WaitPollInterval = time.Millisecond
+
+	responses := []string{
+		`{"metadata": {"generation": 1}, "status": {}}`,
+		`{"metadata": {"generation": 1}, "spec": {"replicas": 1}, "status": {"observedGeneration": 1, "availableReplicas": 1}}`,
+	}
+	calls := 0
+	get := func(ctx context.Context, resource *WaitForResource) ([]byte, error) {
+		calls++
+		if calls == 1 {
+			return nil, fmt.Errorf("not found")
+		}
+		raw := responses[0]
+		if len(responses) > 1 {
+			responses = responses[1:]
+		}
+		return []byte(raw), nil
+	}
+
+	resource := &WaitForResource{Namespace: "kube-system", Kind: "deployment", Name: "traefik"}
+	if err := waitForReady(context.Background(), get, resource); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 polls until the deployment is ready, got %d", calls)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	notFound := func(ctx context.Context, resource *WaitForResource) ([]byte, error) {
+		return nil, fmt.Errorf("not found")
+	}
+	if err := waitForReady(ctx, notFound, resource); err == nil {
+		t.Errorf("expected a timeout error")
+	}
+}
+
+func TestWaitForReadiness(t *testing.T) {
+	deployment := map[string]interface{}{
+		"metadata": map[string]interface{}{"generation": float64(2)},
+		"spec":     map[string]interface{}{"replicas": float64(2)},
+		"status":   map[string]interface{}{"observedGeneration": float64(2), "availableReplicas": float64(1)},
+	}
+	if waitForKinds["deployment"].ready(deployment) {
+		t.Errorf("deployment with 1/2 available replicas must not be ready")
+	}
+	deployment["status"].(map[string]interface{})["availableReplicas"] = float64(2)
+	if !waitForKinds["deployment"].ready(deployment) {
+		t.Errorf("deployment with 2/2 available replicas must be ready")
+	}
+
+	crd := map[string]interface{}{
+		"status": map[string]interface{}{"conditions": []interface{}{
+			map[string]interface{}{"type": "NamesAccepted", "status": "True"},
+			map[string]interface{}{"type": "Established", "status": "True"},
+		}},
+	}
+	if !waitForKinds["customresourcedefinition"].ready(crd) {
+		t.Errorf("established CRD must be ready")
+	}
+}
+
+func TestServerWaitForReadiness(t *testing.T) {
+	WaitPollInterval = time.Millisecond
+	server := newFakeNode("test", "k3d-test-server-0", k3d.ServerRole, true)
+	readyzCmd := strings.Join(serverReadinessCmd, " ")
+
+	runtime := &fakeRuntime{execOutputs: map[string]string{readyzCmd: "ok\n"}}
+	if err := serverWaitForReadiness(context.Background(), runtime, server); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(runtime.execs[server.Name]) != 1 {
+		t.Errorf("expected a single readiness check, got %v", runtime.execs[server.Name])
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	runtime = &fakeRuntime{execOutputs: map[string]string{readyzCmd: "[-]poststarthook/rbac/bootstrap-roles failed: not finished\nreadyz check failed\n"}}
+	if err := serverWaitForReadiness(ctx, runtime, server); err == nil {
+		t.Errorf("expected a timeout error")
+	}
+	if len(runtime.execs[server.Name]) < 2 {
+		t.Errorf("expected the readiness check to be polled, got %v", runtime.execs[server.Name])
+	}
+}
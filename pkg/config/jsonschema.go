@@ -23,6 +23,7 @@ package config
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -33,6 +34,7 @@ import (
 	"github.com/xeipuuv/gojsonschema"
 
 	l "github.com/rancher/k3d/v5/pkg/logger"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
 )
 
 // ValidateSchemaFile takes a filepath, reads the file and validates it against a JSON schema
@@ -88,3 +90,19 @@ func ValidateSchema(content map[string]interface{}, schemaJSON []byte) error {
 
 	return nil
 }
+
+// ValidateInventory validates an inventory against its JSON schema (k3d.InventorySchema), so that external tools can rely on its format
+func ValidateInventory(inventory *k3d.Inventory) error {
+	inventoryJSON, err := json.Marshal(inventory)
+	if err != nil {
+		return fmt.Errorf("failed to marshal inventory: %w", err)
+	}
+	var content map[string]interface{}
+	if err := json.Unmarshal(inventoryJSON, &content); err != nil {
+		return fmt.Errorf("failed to unmarshal inventory: %w", err)
+	}
+	if err := ValidateSchema(content, k3d.InventorySchema); err != nil {
+		return fmt.Errorf("inventory doesn't match its schema:\n%w", err)
+	}
+	return nil
+}
@@ -25,6 +25,7 @@ import (
 	"testing"
 
 	"github.com/rancher/k3d/v5/pkg/config/v1alpha3"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
 )
 
 func TestValidateSchema(t *testing.T) {
@@ -54,3 +55,24 @@ func TestValidateSchemaFail(t *testing.T) {
 	}
 
 }
+
+func TestValidateInventory(t *testing.T) {
+	inventory := &k3d.Inventory{
+		APIVersion: k3d.InventoryAPIVersion,
+		Clusters:   []*k3d.InventoryCluster{{Name: "foo", Network: "k3d-foo", Nodes: []string{"k3d-foo-server-0"}, Registries: []string{}, Volumes: []string{}}},
+		Nodes: []*k3d.InventoryNode{{Name: "k3d-foo-server-0", Role: string(k3d.ServerRole), Cluster: "foo", Status: "running",
+			Networks: []string{"k3d-foo"}, Volumes: []string{}, Labels: map[string]string{"app": "k3d"}}},
+		Registries: []*k3d.InventoryNode{},
+		Networks:   []*k3d.InventoryNetwork{{Name: "k3d-foo", ID: "abc", Clusters: []string{"foo"}, Labels: map[string]string{}}},
+		Volumes:    []*k3d.InventoryVolume{},
+	}
+	if err := ValidateInventory(inventory); err != nil {
+		t.Errorf("expected the inventory to be valid, got: %v", err)
+	}
+
+	inventory.Nodes[0].Role = "worker"
+	inventory.Volumes = nil
+	if err := ValidateInventory(inventory); err == nil {
+		t.Errorf("expected an unknown role and missing volumes to be rejected")
+	}
+}
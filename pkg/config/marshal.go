@@ -0,0 +1,123 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package config
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+
+	conf "github.com/rancher/k3d/v5/pkg/config/v1alpha3"
+)
+
+// MarshalSimpleConfig renders a SimpleConfig as a config file, which can be passed to 'k3d cluster create --config':
+// unset options are left out (so that the defaults apply) and the cluster token is never included.
+func MarshalSimpleConfig(cfg conf.SimpleConfig) ([]byte, error) {
+	cfg.ClusterToken = ""
+	cfg.Options.K3dOptions.NodeHookActions = nil // not configurable via config files
+	if cfg.APIVersion == "" {
+		cfg.APIVersion = DefaultConfigApiVersion
+	}
+	if cfg.Kind == "" {
+		cfg.Kind = "Simple"
+	}
+
+	content, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	// go through a MapSlice to keep the order of the fields
+	var fields yaml.MapSlice
+	if err := yaml.Unmarshal(content, &fields); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+
+	// durations are marshaled as nanoseconds, but config files use strings like '60s'
+	if options, ok := lookupMapSlice(fields, "options"); ok {
+		if k3dOptions, ok := lookupMapSlice(options, "k3d"); ok {
+			timeout := interface{}(nil)
+			if cfg.Options.K3dOptions.Timeout != 0 {
+				timeout = cfg.Options.K3dOptions.Timeout.String()
+			}
+			setMapSliceValue(k3dOptions, "timeout", timeout)
+		}
+	}
+	fields = pruneEmptyFields(fields)
+
+	// config files start with the apiVersion (TypeMeta has the kind first)
+	if len(fields) > 1 && fields[0].Key == "kind" && fields[1].Key == "apiVersion" {
+		fields[0], fields[1] = fields[1], fields[0]
+	}
+
+	return yaml.Marshal(fields)
+}
+
+// pruneEmptyFields recursively removes all fields with empty strings, nil values or empty lists/maps
+func pruneEmptyFields(fields yaml.MapSlice) yaml.MapSlice {
+	pruned := yaml.MapSlice{}
+	for _, field := range fields {
+		switch value := field.Value.(type) {
+		case nil:
+			continue
+		case string:
+			if value == "" {
+				continue
+			}
+		case yaml.MapSlice:
+			value = pruneEmptyFields(value)
+			if len(value) == 0 {
+				continue
+			}
+			field.Value = value
+		case []interface{}:
+			if len(value) == 0 {
+				continue
+			}
+			for i, item := range value {
+				if itemFields, ok := item.(yaml.MapSlice); ok {
+					value[i] = pruneEmptyFields(itemFields)
+				}
+			}
+		}
+		pruned = append(pruned, field)
+	}
+	return pruned
+}
+
+func lookupMapSlice(fields yaml.MapSlice, key string) (yaml.MapSlice, bool) {
+	for _, field := range fields {
+		if field.Key == key {
+			value, ok := field.Value.(yaml.MapSlice)
+			return value, ok
+		}
+	}
+	return nil, false
+}
+
+func setMapSliceValue(fields yaml.MapSlice, key string, value interface{}) {
+	for i := range fields {
+		if fields[i].Key == key {
+			fields[i].Value = value
+		}
+	}
+}
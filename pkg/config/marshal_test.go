@@ -0,0 +1,87 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package config
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+	conf "github.com/rancher/k3d/v5/pkg/config/v1alpha3"
+	"github.com/spf13/viper"
+	"sigs.k8s.io/yaml"
+)
+
+func readSimpleConfig(t *testing.T, config *viper.Viper) conf.SimpleConfig {
+	cfg, err := FromViper(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg.(conf.SimpleConfig)
+}
+
+func TestMarshalSimpleConfig(t *testing.T) {
+	config := viper.New()
+	config.SetConfigFile("./test_assets/config_test_simple.yaml")
+	if err := config.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	original := readSimpleConfig(t, config)
+	original.ClusterToken = "secret"
+
+	content, err := MarshalSimpleConfig(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("Marshaled config:\n%s", content)
+
+	if strings.Contains(string(content), "secret") {
+		t.Errorf("the cluster token must not be part of the marshaled config")
+	}
+	if !strings.Contains(string(content), "timeout: 1m0s") {
+		t.Errorf("expected the timeout as duration string")
+	}
+	if strings.Contains(string(content), `""`) || strings.Contains(string(content), "[]") {
+		t.Errorf("expected empty fields to be left out")
+	}
+
+	// the result has to be a valid config file...
+	var fields map[string]interface{}
+	if err := yaml.Unmarshal(content, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateSchema(fields, []byte(conf.JSONSchema)); err != nil {
+		t.Fatalf("marshaled config doesn't match the schema: %v", err)
+	}
+
+	// ... which reads back to the same config
+	readBack := viper.New()
+	readBack.SetConfigType("yaml")
+	if err := readBack.ReadConfig(bytes.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+	original.ClusterToken = ""
+	if diff := deep.Equal(readSimpleConfig(t, readBack), original); diff != nil {
+		t.Errorf("config read back from the marshaled config differs: %+v", diff)
+	}
+}
@@ -0,0 +1,161 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// builtinTemplates are the templates shipped with k3d
+//
+//go:embed assets/*.yaml
+var builtinTemplates embed.FS
+
+// SourceBuiltin is the source of the templates shipped with k3d
+const SourceBuiltin = "builtin"
+
+// Template is a k3d config file that's expanded using the Values given to Render
+type Template struct {
+	Name        string
+	Description string // the first comment line of the template
+	Source      string // SourceBuiltin or the path of the template file
+	Content     []byte
+}
+
+// Values are available in templates, e.g. as {{ .Name }}
+type Values struct {
+	Name    string // cluster name
+	Image   string // k3s image
+	WorkDir string // current working directory, for absolute volume mount paths
+}
+
+// List returns all templates sorted by name: the '*.yaml' files in the given directories and the builtin ones.
+// Like with $PATH, the first template with a given name wins, so user templates can replace builtin ones.
+func List(dirs []string) ([]*Template, error) {
+	templates := map[string]*Template{}
+
+	for _, dir := range dirs {
+		paths, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list templates in '%s': %w", dir, err)
+		}
+		for _, path := range paths {
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read template '%s': %w", path, err)
+			}
+			tpl := newTemplate(path, content)
+			if _, exists := templates[tpl.Name]; !exists {
+				templates[tpl.Name] = tpl
+			}
+		}
+	}
+
+	builtins, err := fs.Glob(builtinTemplates, "assets/*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range builtins {
+		content, err := builtinTemplates.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read builtin template '%s': %w", path, err)
+		}
+		tpl := newTemplate(path, content)
+		tpl.Source = SourceBuiltin
+		if _, exists := templates[tpl.Name]; !exists {
+			templates[tpl.Name] = tpl
+		}
+	}
+
+	result := make([]*Template, 0, len(templates))
+	for _, tpl := range templates {
+		result = append(result, tpl)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// Get returns the template with the given name (see List for the lookup order)
+func Get(name string, dirs []string) (*Template, error) {
+	templates, err := List(dirs)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(templates))
+	for _, tpl := range templates {
+		if tpl.Name == name {
+			return tpl, nil
+		}
+		names = append(names, tpl.Name)
+	}
+	return nil, fmt.Errorf("template '%s' not found (available: %s)", name, strings.Join(names, ", "))
+}
+
+// Render expands the template to a k3d config file
+func (t *Template) Render(values Values) ([]byte, error) {
+	tpl, err := template.New(t.Name).Option("missingkey=error").Parse(string(t.Content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template '%s': %w", t.Name, err)
+	}
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, values); err != nil {
+		return nil, fmt.Errorf("failed to render template '%s': %w", t.Name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+func newTemplate(path string, content []byte) *Template {
+	tpl := &Template{
+		Name:    strings.TrimSuffix(filepath.Base(path), ".yaml"),
+		Source:  path,
+		Content: content,
+	}
+	if firstLine := strings.SplitN(string(content), "\n", 2)[0]; strings.HasPrefix(firstLine, "#") {
+		tpl.Description = strings.TrimSpace(strings.TrimPrefix(firstLine, "#"))
+	}
+	return tpl
+}
+
+// Dirs returns the user template directories: the given ones, the ones in $K3D_TEMPLATE_PATH (separated like $PATH) and CONFIGDIR/templates
+func Dirs(dirs []string, configDir string) []string {
+	dirs = append([]string{}, dirs...)
+	for _, dir := range filepath.SplitList(os.Getenv("K3D_TEMPLATE_PATH")) {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	if configDir != "" {
+		dirs = append(dirs, filepath.Join(configDir, "templates"))
+	}
+	return dirs
+}
@@ -0,0 +1,108 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package templates
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/rancher/k3d/v5/pkg/config"
+	"github.com/rancher/k3d/v5/pkg/config/v1alpha3"
+)
+
+func TestBuiltinTemplatesRenderValidConfigs(t *testing.T) {
+	templates, err := List(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) < 4 {
+		t.Fatalf("expected at least 4 builtin templates, got %d", len(templates))
+	}
+
+	for _, tpl := range templates {
+		if tpl.Source != SourceBuiltin || tpl.Description == "" {
+			t.Errorf("%s: expected builtin template with description, got source '%s' and description '%s'", tpl.Name, tpl.Source, tpl.Description)
+		}
+		content, err := tpl.Render(Values{Name: "demo", Image: "rancher/k3s:latest", WorkDir: "/home/demo"})
+		if err != nil {
+			t.Fatalf("%s: %v", tpl.Name, err)
+		}
+		var cfg map[string]interface{}
+		if err := yaml.Unmarshal(content, &cfg); err != nil {
+			t.Fatalf("%s: rendered config is no valid yaml: %v", tpl.Name, err)
+		}
+		if cfg["name"] != "demo" {
+			t.Errorf("%s: expected cluster name 'demo', got '%v'", tpl.Name, cfg["name"])
+		}
+		if err := config.ValidateSchema(cfg, []byte(v1alpha3.JSONSchema)); err != nil {
+			t.Errorf("%s: rendered config doesn't match the schema: %v", tpl.Name, err)
+		}
+	}
+}
+
+func TestUserTemplates(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	files := map[string]string{
+		filepath.Join(first, "gpu.yaml"):     "# my gpu setup\nname: {{ .Name }}\n",
+		filepath.Join(second, "gpu.yaml"):    "# shadowed\n",
+		filepath.Join(second, "custom.yaml"): "name: {{ .Name }}-custom\n",
+		filepath.Join(second, "ignored.txt"): "not a template\n",
+	}
+	for path, content := range files {
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dirs := []string{first, second}
+
+	gpu, err := Get("gpu", dirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gpu.Description != "my gpu setup" || gpu.Source != filepath.Join(first, "gpu.yaml") {
+		t.Errorf("expected the gpu template of the first directory to win, got '%s' from '%s'", gpu.Description, gpu.Source)
+	}
+
+	custom, err := Get("custom", dirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := custom.Render(Values{Name: "demo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "name: demo-custom\n" {
+		t.Errorf("unexpected rendered content '%s'", content)
+	}
+
+	if _, err := Get("ignored", dirs); err == nil || !strings.Contains(err.Error(), "custom, gpu, ha-3server") {
+		t.Errorf("expected a not found error listing the available templates, got '%v'", err)
+	}
+
+	if _, err := (&Template{Name: "broken", Content: []byte("{{ .Unknown }}")}).Render(Values{}); err == nil {
+		t.Errorf("expected an error for an unknown value")
+	}
+}
@@ -23,17 +23,29 @@ THE SOFTWARE.
 package config
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
 	"io/ioutil"
+	"net/url"
 	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/docker/go-connections/nat"
+	dockerunits "github.com/docker/go-units"
 	cliutil "github.com/rancher/k3d/v5/cmd/util" // TODO: move parseapiport to pkg
 	"github.com/rancher/k3d/v5/pkg/client"
 	conf "github.com/rancher/k3d/v5/pkg/config/v1alpha3"
 	"github.com/rancher/k3d/v5/pkg/runtimes"
+	runtimeutil "github.com/rancher/k3d/v5/pkg/runtimes/util"
 	k3d "github.com/rancher/k3d/v5/pkg/types"
 	"github.com/rancher/k3d/v5/pkg/types/k3s"
 	"github.com/rancher/k3d/v5/pkg/util"
@@ -48,6 +60,15 @@ var (
 	DefaultTargetsNodefiltersPortMappings = []string{"servers:*:proxy", "agents:*:proxy"}
 )
 
+// TransformUntrustedSimpleToClusterConfig is TransformSimpleToClusterConfig for configs that don't come from the user running k3d (e.g. via the API of 'k3d serve'):
+// configs running commands on the host or reading host files are rejected (see ValidateUntrustedSimpleConfig)
+func TransformUntrustedSimpleToClusterConfig(ctx context.Context, runtime runtimes.Runtime, simpleConfig conf.SimpleConfig) (*conf.ClusterConfig, error) {
+	if err := ValidateUntrustedSimpleConfig(simpleConfig); err != nil {
+		return nil, err
+	}
+	return TransformSimpleToClusterConfig(ctx, runtime, simpleConfig)
+}
+
 // TransformSimpleToClusterConfig transforms a simple configuration to a full-fledged cluster configuration
 func TransformSimpleToClusterConfig(ctx context.Context, runtime runtimes.Runtime, simpleConfig conf.SimpleConfig) (*conf.ClusterConfig, error) {
 
@@ -56,9 +77,30 @@ func TransformSimpleToClusterConfig(ctx context.Context, runtime runtimes.Runtim
 		simpleConfig.Name = k3d.DefaultClusterName
 	}
 
-	// fetch latest image
+	// split the cluster memory budget across the nodes
+	if simpleConfig.Options.Runtime.MemoryBudget != "" {
+		serversMemory, agentsMemory, err := splitMemoryBudget(simpleConfig.Options.Runtime.MemoryBudget, simpleConfig.Servers, simpleConfig.Options.Runtime.ServersMemory, simpleConfig.Agents, simpleConfig.Options.Runtime.AgentsMemory)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply memory budget: %w", err)
+		}
+		l.Log().Debugf("Memory budget %s: servers=%s, agents=%s", simpleConfig.Options.Runtime.MemoryBudget, serversMemory, agentsMemory)
+		simpleConfig.Options.Runtime.ServersMemory = serversMemory
+		simpleConfig.Options.Runtime.AgentsMemory = agentsMemory
+	}
+
+	// resolve the k3s version/channel and fetch latest image
+	if err := ResolveK3sImage(ctx, &simpleConfig); err != nil {
+		return nil, err
+	}
 	if simpleConfig.Image == "latest" {
-		simpleConfig.Image = version.GetK3sVersion(true)
+		simpleConfig.Image = latestK3sImage(ctx)
+	}
+
+	// pin the k3s image(s) to the digest they currently point to
+	if simpleConfig.Options.K3dOptions.ResolveDigest {
+		if err := ResolveImageDigests(ctx, runtime, &simpleConfig); err != nil {
+			return nil, err
+		}
 	}
 
 	clusterNetwork := k3d.ClusterNetwork{}
@@ -81,7 +123,52 @@ func TransformSimpleToClusterConfig(ctx context.Context, runtime runtimes.Runtim
 		clusterNetwork.IPAM.Managed = true
 	}
 
+	if simpleConfig.Gateway != "" {
+		if clusterNetwork.IPAM.IPPrefix.IsZero() {
+			return nil, fmt.Errorf("gateway '%s' requires a subnet (not 'auto')", simpleConfig.Gateway)
+		}
+		gateway, err := netaddr.ParseIP(simpleConfig.Gateway)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gateway '%s': %w", simpleConfig.Gateway, err)
+		}
+		if !clusterNetwork.IPAM.IPPrefix.Contains(gateway) {
+			return nil, fmt.Errorf("gateway '%s' is not part of subnet '%s'", gateway, clusterNetwork.IPAM.IPPrefix)
+		}
+		clusterNetwork.IPAM.Gateway = gateway
+	}
+
+	if simpleConfig.IPv6 != "" {
+		clusterNetwork.IPv6.Enabled = true
+		if simpleConfig.IPv6 != "auto" {
+			subnet, err := netaddr.ParseIPPrefix(simpleConfig.IPv6)
+			if err != nil || !subnet.IP().Is6() {
+				return nil, fmt.Errorf("invalid IPv6 subnet '%s' (or 'auto')", simpleConfig.IPv6)
+			}
+			clusterNetwork.IPv6.IPPrefix = subnet
+		}
+	}
+
 	// -> API
+	if simpleConfig.Options.K3dOptions.APIDNS {
+		// a loopback address of its own lets every cluster use the default port, so the kubeconfig doesn't change when it's recreated
+		if loopbackIP := client.ClusterAPILoopbackIP(simpleConfig.Name); loopbackIP != "" && simpleConfig.ExposeAPI.HostIP == "" {
+			simpleConfig.ExposeAPI.HostIP = loopbackIP
+			if simpleConfig.ExposeAPI.HostPort == "" {
+				simpleConfig.ExposeAPI.HostPort = k3d.DefaultAPIPort
+			}
+		}
+		simpleConfig.ExposeAPI.Host = client.ClusterAPIDNSName(simpleConfig.Name)
+	}
+	if simpleConfig.ExposeAPI.Tunnel {
+		if simpleConfig.Options.K3dOptions.APIDNS {
+			return nil, fmt.Errorf("the Kubernetes API can't be tunneled and use a stable DNS name at the same time")
+		}
+		// the API is only reached through the tunnel: keep it on the remote host's loopback interface and connect to the local end
+		if simpleConfig.ExposeAPI.HostIP == "" || simpleConfig.ExposeAPI.HostIP == k3d.DefaultAPIHost {
+			simpleConfig.ExposeAPI.HostIP = "127.0.0.1"
+		}
+		simpleConfig.ExposeAPI.Host = "127.0.0.1"
+	}
 	if simpleConfig.ExposeAPI.HostIP == "" {
 		simpleConfig.ExposeAPI.HostIP = k3d.DefaultAPIHost
 	}
@@ -90,20 +177,39 @@ func TransformSimpleToClusterConfig(ctx context.Context, runtime runtimes.Runtim
 	}
 
 	kubeAPIExposureOpts := &k3d.ExposureOpts{
-		Host: simpleConfig.ExposeAPI.Host,
+		Host:   simpleConfig.ExposeAPI.Host,
+		Tunnel: simpleConfig.ExposeAPI.Tunnel,
 	}
 	kubeAPIExposureOpts.Port = k3d.DefaultAPIPort
 	kubeAPIExposureOpts.Binding = nat.PortBinding{
 		HostIP:   simpleConfig.ExposeAPI.HostIP,
 		HostPort: simpleConfig.ExposeAPI.HostPort,
 	}
+	if err := client.ValidateAPIExposure(runtime, kubeAPIExposureOpts); err != nil {
+		return nil, err
+	}
 
 	// FILL CLUSTER CONFIG
 	newCluster := k3d.Cluster{
-		Name:    simpleConfig.Name,
-		Network: clusterNetwork,
-		Token:   simpleConfig.ClusterToken,
-		KubeAPI: kubeAPIExposureOpts,
+		Name:             simpleConfig.Name,
+		Network:          clusterNetwork,
+		Token:            simpleConfig.ClusterToken,
+		KubeAPI:          kubeAPIExposureOpts,
+		Description:      simpleConfig.Description,
+		DefaultNamespace: simpleConfig.Options.KubeconfigOptions.DefaultNamespace,
+		ContextTemplate:  simpleConfig.Options.KubeconfigOptions.ContextTemplate,
+	}
+
+	// -> DEPENDENCIES
+	for _, s := range simpleConfig.DependsOn {
+		dep, err := client.ClusterDependencyParse(s)
+		if err != nil {
+			return nil, err
+		}
+		if dep.Kind == k3d.ClusterDependencyCluster && dep.Name == simpleConfig.Name {
+			return nil, fmt.Errorf("cluster '%s' cannot depend on itself", dep.Name)
+		}
+		client.ClusterDependencyAdd(&newCluster, dep)
 	}
 
 	// -> NODES
@@ -129,13 +235,34 @@ func TransformSimpleToClusterConfig(ctx context.Context, runtime runtimes.Runtim
 	 * Add Nodes *
 	 *************/
 
+	nodeNames := map[string]struct{}{}
+	nodeName := func(role k3d.Role, index int) (string, error) {
+		name := client.GenerateNodeName(newCluster.Name, role, index)
+		if tmpl := simpleConfig.Options.K3dOptions.NodeNameTemplate; tmpl != "" {
+			var err error
+			if name, err = client.GenerateNodeNameFromTemplate(tmpl, newCluster.Name, role, index); err != nil {
+				return "", err
+			}
+		}
+		if _, exists := nodeNames[name]; exists {
+			return "", fmt.Errorf("node name '%s' is not unique: make sure that the node name template includes the role and the index", name)
+		}
+		nodeNames[name] = struct{}{}
+		return name, nil
+	}
+
 	for i := 0; i < simpleConfig.Servers; i++ {
+		name, err := nodeName(k3d.ServerRole, i)
+		if err != nil {
+			return nil, err
+		}
 		serverNode := k3d.Node{
-			Name:       client.GenerateNodeName(newCluster.Name, k3d.ServerRole, i),
+			Name:       name,
 			Role:       k3d.ServerRole,
 			Image:      simpleConfig.Image,
 			ServerOpts: k3d.ServerOpts{},
 			Memory:     simpleConfig.Options.Runtime.ServersMemory,
+			CPUs:       simpleConfig.Options.Runtime.ServersCPUs,
 		}
 
 		// first server node will be init node if we have more than one server specified but no external datastore
@@ -152,11 +279,16 @@ func TransformSimpleToClusterConfig(ctx context.Context, runtime runtimes.Runtim
 	}
 
 	for i := 0; i < simpleConfig.Agents; i++ {
+		name, err := nodeName(k3d.AgentRole, i)
+		if err != nil {
+			return nil, err
+		}
 		agentNode := k3d.Node{
-			Name:   client.GenerateNodeName(newCluster.Name, k3d.AgentRole, i),
+			Name:   name,
 			Role:   k3d.AgentRole,
 			Image:  simpleConfig.Image,
 			Memory: simpleConfig.Options.Runtime.AgentsMemory,
+			CPUs:   simpleConfig.Options.Runtime.AgentsCPUs,
 		}
 		newCluster.Nodes = append(newCluster.Nodes, &agentNode)
 	}
@@ -174,8 +306,18 @@ func TransformSimpleToClusterConfig(ctx context.Context, runtime runtimes.Runtim
 			return nil, fmt.Errorf("failed to filter nodes for volume mapping '%s': %w", volumeWithNodeFilters.Volume, err)
 		}
 
+		volume, err := runtimeutil.TranslateVolumeMount(volumeWithNodeFilters.Volume)
+		if err != nil {
+			return nil, fmt.Errorf("failed to translate volume mapping '%s': %w", volumeWithNodeFilters.Volume, err)
+		}
+		if simpleConfig.Options.Runtime.SELinux {
+			if volume, err = runtimeutil.SELinuxRelabelVolumeMount(volume); err != nil {
+				return nil, fmt.Errorf("failed to relabel volume mapping '%s': %w", volumeWithNodeFilters.Volume, err)
+			}
+		}
+
 		for _, node := range nodes {
-			node.Volumes = append(node.Volumes, volumeWithNodeFilters.Volume)
+			node.Volumes = append(node.Volumes, volume)
 		}
 	}
 
@@ -228,6 +370,64 @@ func TransformSimpleToClusterConfig(ctx context.Context, runtime runtimes.Runtim
 		}
 	}
 
+	// -> FAKE NODE MEMORY
+	for _, memoryWithNodeFilters := range simpleConfig.Options.Runtime.FakeNodeMemory {
+		if len(memoryWithNodeFilters.NodeFilters) == 0 && nodeCount > 1 {
+			return nil, fmt.Errorf("fake node memory mapping '%s' lacks a node filter, but there's more than one node", memoryWithNodeFilters.Memory)
+		}
+
+		if _, err := dockerunits.RAMInBytes(memoryWithNodeFilters.Memory); err != nil {
+			return nil, fmt.Errorf("invalid fake node memory '%s': %w", memoryWithNodeFilters.Memory, err)
+		}
+
+		nodes, err := util.FilterNodes(nodeList, memoryWithNodeFilters.NodeFilters)
+		if err != nil {
+			return nil, fmt.Errorf("failed to filter nodes for fake node memory mapping '%s': %w", memoryWithNodeFilters.Memory, err)
+		}
+
+		for _, node := range nodes {
+			node.FakeMemory = memoryWithNodeFilters.Memory
+		}
+	}
+
+	// -> NODE IMAGES
+	for _, imageWithNodeFilters := range simpleConfig.Options.Runtime.NodeImages {
+		if len(imageWithNodeFilters.NodeFilters) == 0 {
+			return nil, fmt.Errorf("node image '%s' lacks a node filter (use the cluster-wide image option instead)", imageWithNodeFilters.Image)
+		}
+
+		nodes, err := util.FilterNodes(nodeList, imageWithNodeFilters.NodeFilters)
+		if err != nil {
+			return nil, fmt.Errorf("failed to filter nodes for node image '%s': %w", imageWithNodeFilters.Image, err)
+		}
+
+		image := imageWithNodeFilters.Image
+		if image == "latest" {
+			image = latestK3sImage(ctx)
+		}
+
+		for _, node := range nodes {
+			if node.Role != k3d.ServerRole && node.Role != k3d.AgentRole {
+				return nil, fmt.Errorf("node image '%s' can only be used for server and agent nodes, but the node filter matched the %s node '%s'", imageWithNodeFilters.Image, node.Role, node.Name)
+			}
+			node.Image = image
+		}
+	}
+
+	// -> PINNED IMAGES
+	// nodes added to the cluster later on use the same image as the node they're copied from
+	if simpleConfig.Options.K3dOptions.ResolveDigest {
+		for _, node := range newCluster.Nodes {
+			if node.Role != k3d.ServerRole && node.Role != k3d.AgentRole {
+				continue
+			}
+			if node.RuntimeLabels == nil {
+				node.RuntimeLabels = map[string]string{}
+			}
+			node.RuntimeLabels[k3d.LabelImagePinned] = node.Image
+		}
+	}
+
 	// -> ENV
 	for _, envVarWithNodeFilters := range simpleConfig.Env {
 		if len(envVarWithNodeFilters.NodeFilters) == 0 && nodeCount > 1 {
@@ -260,6 +460,227 @@ func TransformSimpleToClusterConfig(ctx context.Context, runtime runtimes.Runtim
 		}
 	}
 
+	// -> OIDC
+	if simpleConfig.Options.K3sOptions.OIDC.IssuerURL != "" || simpleConfig.Options.K3sOptions.OIDC.ClientID != "" {
+		oidc := &k3d.OIDCOpts{
+			IssuerURL:     simpleConfig.Options.K3sOptions.OIDC.IssuerURL,
+			ClientID:      simpleConfig.Options.K3sOptions.OIDC.ClientID,
+			UsernameClaim: simpleConfig.Options.K3sOptions.OIDC.UsernameClaim,
+			GroupsClaim:   simpleConfig.Options.K3sOptions.OIDC.GroupsClaim,
+		}
+		if oidc.IssuerURL == "" || oidc.ClientID == "" {
+			return nil, fmt.Errorf("OIDC requires both an issuer URL and a client ID")
+		}
+		if !strings.HasPrefix(oidc.IssuerURL, "https://") {
+			return nil, fmt.Errorf("OIDC issuer URL '%s' must use the https scheme", oidc.IssuerURL)
+		}
+		for _, node := range nodeList {
+			if node.Role == k3d.ServerRole {
+				node.Args = append(node.Args, client.OIDCServerArgs(oidc)...)
+			}
+		}
+	}
+
+	// -> SERVER TAINT
+	if simpleConfig.Options.K3sOptions.NoScheduleOnServer {
+		if simpleConfig.Agents == 0 && simpleConfig.Options.K3dOptions.VirtualWorkers == 0 {
+			l.Log().Warnln("Servers won't run any regular workloads, but there are no agents: only system components tolerating the taint will be scheduled")
+		}
+		for _, node := range nodeList {
+			if node.Role == k3d.ServerRole {
+				node.Args = append(node.Args, fmt.Sprintf("--node-taint=%s", k3d.DefaultServerTaint))
+			}
+		}
+	}
+
+	// -> CRI
+	cri := k3d.CRI(simpleConfig.Options.K3sOptions.CRI)
+	switch cri {
+	case "", k3d.CRIContainerd:
+	case k3d.CRIDockerd:
+		l.Log().Warnln("With the CRI 'cri-dockerd', pods run in the docker daemon of the nodes: images imported via 'k3d image import' (into k3s' containerd) aren't available to them")
+		for _, node := range nodeList {
+			if node.Role == k3d.ServerRole || node.Role == k3d.AgentRole {
+				node.Args = append(node.Args, "--docker")
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown CRI '%s' (one of %v)", cri, k3d.CRIs)
+	}
+
+	// -> ETCD
+	externalDatastore := false
+	for _, extraArg := range simpleConfig.Options.K3sOptions.ExtraArgs {
+		if strings.Contains(extraArg.Arg, "datastore-endpoint") {
+			externalDatastore = true
+		}
+	}
+	if simpleConfig.Servers > 1 && !externalDatastore {
+		etcdArgs := etcdServerArgs(simpleConfig.Options.K3sOptions.EtcdArgs, simpleConfig.Options.K3sOptions.ExtraArgs)
+		for _, node := range nodeList {
+			if node.Role == k3d.ServerRole {
+				node.Args = append(node.Args, etcdArgs...)
+			}
+		}
+		if simpleConfig.Options.Runtime.ServersMemory == "" && simpleConfig.Options.Runtime.MemoryBudget == "" {
+			l.Log().Infof("Creating %d servers with embedded etcd: consider limiting their memory using --servers-memory or --memory-budget on machines with little RAM", simpleConfig.Servers)
+		}
+	} else if len(simpleConfig.Options.K3sOptions.EtcdArgs) > 0 {
+		return nil, fmt.Errorf("etcd args require multiple servers with embedded etcd (no external datastore), but %d server(s) were requested", simpleConfig.Servers)
+	}
+
+	// -> DATASTORE BACKUP
+	var datastoreBackup []byte
+	if simpleConfig.Options.K3sOptions.FromBackup != "" {
+		clusterInit := false
+		for _, extraArg := range simpleConfig.Options.K3sOptions.ExtraArgs {
+			if strings.Contains(extraArg.Arg, "cluster-init") {
+				clusterInit = true
+			}
+		}
+		if simpleConfig.Servers > 1 || externalDatastore || clusterInit {
+			return nil, fmt.Errorf("restoring a backup requires a single server with the default SQLite datastore (no embedded etcd or external datastore)")
+		}
+		var err error
+		datastoreBackup, err = readDatastoreBackup(simpleConfig.Options.K3sOptions.FromBackup)
+		if err != nil {
+			return nil, err
+		}
+		if simpleConfig.ClusterToken == "" {
+			l.Log().Warnln("Restoring a backup without --token: k3s refuses to start, if the backup contains bootstrap data encrypted with the token of the original cluster")
+		}
+	}
+
+	// -> AUDIT POLICY
+	var auditPolicy []byte
+	if simpleConfig.Options.K3sOptions.AuditPolicy != "" {
+		var err error
+		auditPolicy, err = readAuditPolicy(simpleConfig.Options.K3sOptions.AuditPolicy)
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range nodeList {
+			if node.Role == k3d.ServerRole {
+				node.Args = append(node.Args, client.AuditServerArgs()...)
+			}
+		}
+	}
+
+	// -> MANIFESTS
+	var manifests []k3d.Manifest
+	if len(simpleConfig.Options.K3sOptions.Manifests) > 0 {
+		var err error
+		manifests, err = readManifests(simpleConfig.Options.K3sOptions.Manifests)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// -> HOOKS
+	hooks, err := parseClusterHooks(simpleConfig.Hooks)
+	if err != nil {
+		return nil, err
+	}
+
+	// -> FEATURE GATES
+	if len(simpleConfig.Options.K3sOptions.FeatureGates) > 0 {
+		featureGates, err := parseFeatureGates(simpleConfig.Options.K3sOptions.FeatureGates)
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range nodeList {
+			components := featureGateAgentComponents
+			if node.Role == k3d.ServerRole {
+				components = featureGateServerComponents
+			} else if node.Role != k3d.AgentRole {
+				continue
+			}
+			for _, component := range components {
+				node.Args = append(node.Args, fmt.Sprintf("--%s-arg=feature-gates=%s", component, featureGates))
+			}
+		}
+	}
+
+	// -> SHM SIZE
+	if simpleConfig.Options.Runtime.ShmSize != "" {
+		for _, node := range nodeList {
+			if node.Role == k3d.ServerRole || node.Role == k3d.AgentRole {
+				node.ShmSize = simpleConfig.Options.Runtime.ShmSize
+			}
+		}
+	}
+
+	// -> LOGGING
+	if simpleConfig.Options.Runtime.LogDriver != "" || len(simpleConfig.Options.Runtime.LogOpts) > 0 {
+		logOpts, err := parseLogOpts(simpleConfig.Options.Runtime.LogOpts)
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range nodeList {
+			if node.Role == k3d.ServerRole || node.Role == k3d.AgentRole {
+				node.LogDriver = simpleConfig.Options.Runtime.LogDriver
+				node.LogOpts = logOpts
+			}
+		}
+	}
+
+	// -> EPHEMERAL STATE
+	if simpleConfig.Options.Runtime.EphemeralState {
+		l.Log().Infoln("Keeping the kubelet and containerd state of the nodes in memory (--ephemeral-state): images and pods don't survive a restart of the nodes")
+		for _, node := range nodeList {
+			if node.Role == k3d.ServerRole || node.Role == k3d.AgentRole {
+				if node.Tmpfs == nil {
+					node.Tmpfs = map[string]string{}
+				}
+				for mnt, opts := range k3d.EphemeralStateTmpfsMounts {
+					node.Tmpfs[mnt] = opts
+				}
+			}
+		}
+	}
+
+	// -> SELINUX / APPARMOR
+	securityOpts := []string{}
+	if simpleConfig.Options.Runtime.SELinux {
+		securityOpts = append(securityOpts, "label=disable")
+	} else if runtimeInfo, err := runtime.Info(); err == nil {
+		for _, feature := range runtimeInfo.Security {
+			if feature == "selinux" {
+				l.Log().Warnln("SELinux is enabled on the container host: if the nodes fail to start or can't access bind-mounted volumes, use --selinux")
+			}
+		}
+	}
+	if simpleConfig.Options.Runtime.AppArmor != "" {
+		securityOpts = append(securityOpts, fmt.Sprintf("apparmor=%s", simpleConfig.Options.Runtime.AppArmor))
+	}
+	if len(securityOpts) > 0 {
+		for _, node := range nodeList {
+			if node.Role == k3d.ServerRole || node.Role == k3d.AgentRole {
+				node.SecurityOpts = append(node.SecurityOpts, securityOpts...)
+			}
+		}
+	}
+
+	// -> HOST DNS
+	var hostResolvConf []byte
+	if simpleConfig.Options.K3dOptions.HostDNS {
+		resolvConf, err := util.HostResolvConf()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the host's resolver configuration: %w", err)
+		}
+		l.Log().Infof("Using the host's nameservers %v (search domains %v) in the nodes", resolvConf.Nameservers, resolvConf.Search)
+		hostResolvConf = resolvConf.Bytes()
+		for _, node := range nodeList {
+			if node.Role != k3d.ServerRole && node.Role != k3d.AgentRole {
+				continue
+			}
+			node.DNS = resolvConf.Nameservers
+			node.DNSSearch = resolvConf.Search
+			// the kubelet passes this to pods with dnsPolicy 'Default', so CoreDNS forwards to the host's nameservers instead of Docker's embedded DNS
+			node.Args = append(node.Args, fmt.Sprintf("--resolv-conf=%s", k3d.DefaultHostResolvConfPath))
+		}
+	}
+
 	/**************************
 	 * Cluster Create Options *
 	 **************************/
@@ -268,14 +689,37 @@ func TransformSimpleToClusterConfig(ctx context.Context, runtime runtimes.Runtim
 		DisableImageVolume:  simpleConfig.Options.K3dOptions.DisableImageVolume,
 		WaitForServer:       simpleConfig.Options.K3dOptions.Wait,
 		Timeout:             simpleConfig.Options.K3dOptions.Timeout,
+		ReadinessTimeout:    simpleConfig.Options.K3dOptions.ReadinessTimeout,
 		DisableLoadBalancer: simpleConfig.Options.K3dOptions.DisableLoadbalancer,
 		GPURequest:          simpleConfig.Options.Runtime.GPURequest,
 		ServersMemory:       simpleConfig.Options.Runtime.ServersMemory,
 		AgentsMemory:        simpleConfig.Options.Runtime.AgentsMemory,
+		ServersCPUs:         simpleConfig.Options.Runtime.ServersCPUs,
+		AgentsCPUs:          simpleConfig.Options.Runtime.AgentsCPUs,
+		MemoryBudget:        simpleConfig.Options.Runtime.MemoryBudget,
+		VirtualWorkers:      simpleConfig.Options.K3dOptions.VirtualWorkers,
+		WaitFor:             simpleConfig.Options.K3dOptions.WaitFor,
+		RunnerContainer:     simpleConfig.Options.K3dOptions.RunnerContainer,
+		HostResolvConf:      hostResolvConf,
+		FixSysctls:          simpleConfig.Options.K3dOptions.FixSysctls,
+		NoStart:             simpleConfig.Options.K3dOptions.NoStart,
+		ImageCache:          simpleConfig.Options.K3dOptions.ImageCache,
+		DeferWorkers:        simpleConfig.Options.K3dOptions.DeferWorkers,
+		DisableHostIP:       simpleConfig.Options.K3dOptions.DisableHostIP,
+		PullPolicy:          k3d.ImagePullPolicy(simpleConfig.Options.Runtime.PullPolicy),
+		CRI:                 cri,
+		AuditPolicy:         auditPolicy,
+		DatastoreBackup:     datastoreBackup,
+		Hooks:               hooks,
+		Manifests:           manifests,
 		GlobalLabels:        map[string]string{}, // empty init
 		GlobalEnv:           []string{},          // empty init
 	}
 
+	if clusterCreateOpts.PullPolicy == "" {
+		clusterCreateOpts.PullPolicy = k3d.ImagePullPolicyMissing
+	}
+
 	// ensure, that we have the default object labels
 	for k, v := range k3d.DefaultRuntimeLabels {
 		clusterCreateOpts.GlobalLabels[k] = v
@@ -350,6 +794,73 @@ func TransformSimpleToClusterConfig(ctx context.Context, runtime runtimes.Runtim
 		clusterCreateOpts.Registries.Config = k3sRegistry
 	}
 
+	// mirrors given as REGISTRY=ENDPOINT take precedence over the ones of the same registry in the registries.yaml
+	if len(simpleConfig.Registries.Mirrors) > 0 {
+		mirrors, err := parseRegistryMirrors(simpleConfig.Registries.Mirrors)
+		if err != nil {
+			return nil, err
+		}
+		if clusterCreateOpts.Registries.Config == nil {
+			clusterCreateOpts.Registries.Config = &k3s.Registry{}
+		}
+		if clusterCreateOpts.Registries.Config.Mirrors == nil {
+			clusterCreateOpts.Registries.Config.Mirrors = map[string]k3s.Mirror{}
+		}
+		for registry, mirror := range mirrors {
+			clusterCreateOpts.Registries.Config.Mirrors[registry] = mirror
+		}
+	}
+
+	// -> SEED OBJECTS (Secrets/ConfigMaps)
+	for _, secret := range simpleConfig.Options.K3sOptions.Secrets {
+		obj, err := readSeedObject("Secret", secret)
+		if err != nil {
+			return nil, err
+		}
+		clusterCreateOpts.SeedObjects = append(clusterCreateOpts.SeedObjects, obj)
+	}
+	for _, cm := range simpleConfig.Options.K3sOptions.ConfigMaps {
+		obj, err := readSeedObject("ConfigMap", cm)
+		if err != nil {
+			return nil, err
+		}
+		clusterCreateOpts.SeedObjects = append(clusterCreateOpts.SeedObjects, obj)
+	}
+
+	// -> TRUSTED CAs
+	for i, caPath := range simpleConfig.Options.K3dOptions.TrustCAs {
+		trustedCA, err := readTrustedCA(i, caPath)
+		if err != nil {
+			return nil, err
+		}
+		clusterCreateOpts.TrustedCAs = append(clusterCreateOpts.TrustedCAs, trustedCA)
+	}
+
+	// -> CACHE VOLUMES
+	cacheVolumes, err := parseCacheVolumes(simpleConfig.Options.K3dOptions.CacheVolumes)
+	if err != nil {
+		return nil, err
+	}
+	clusterCreateOpts.CacheVolumes = cacheVolumes
+
+	// -> NODE INIT
+	if simpleConfig.Options.K3dOptions.NodeInit != "" {
+		nodeInit, err := readNodeInit(simpleConfig.Options.K3dOptions.NodeInit)
+		if err != nil {
+			return nil, err
+		}
+		clusterCreateOpts.NodeInit = nodeInit
+	}
+
+	// -> CUSTOM CA
+	if simpleConfig.Options.K3sOptions.CustomCA.Cert != "" || simpleConfig.Options.K3sOptions.CustomCA.Key != "" {
+		customCA, err := readCustomCA(simpleConfig.Options.K3sOptions.CustomCA.Cert, simpleConfig.Options.K3sOptions.CustomCA.Key)
+		if err != nil {
+			return nil, err
+		}
+		clusterCreateOpts.CustomCA = customCA
+	}
+
 	/**********************
 	 * Kubeconfig Options *
 	 **********************/
@@ -368,3 +879,491 @@ func TransformSimpleToClusterConfig(ctx context.Context, runtime runtimes.Runtim
 
 	return clusterConfig, nil
 }
+
+// parseRegistryMirrors parses mirror definitions in the format REGISTRY=ENDPOINT[,ENDPOINT...], e.g. 'docker.io=https://mirror.corp'.
+// Multiple definitions for the same registry add endpoints, which containerd tries in the given order.
+func parseRegistryMirrors(mirrors []string) (map[string]k3s.Mirror, error) {
+	result := map[string]k3s.Mirror{}
+	for _, mirror := range mirrors {
+		parts := strings.SplitN(mirror, "=", 2)
+		registry := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || registry == "" || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("invalid registry mirror '%s' (format: REGISTRY=ENDPOINT[,ENDPOINT...])", mirror)
+		}
+		m := result[registry]
+		for _, endpoint := range strings.Split(parts[1], ",") {
+			endpoint = strings.TrimSpace(endpoint)
+			u, err := url.Parse(endpoint)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, fmt.Errorf("invalid endpoint '%s' of registry mirror '%s' (must be an http(s) URL with host)", endpoint, mirror)
+			}
+			m.Endpoints = append(m.Endpoints, endpoint)
+		}
+		result[registry] = m
+	}
+	return result, nil
+}
+
+// splitMemoryBudget distributes a total memory budget across the server and agent nodes:
+// explicitly set per-role limits are subtracted from the budget, the rest is split evenly across the remaining nodes
+func splitMemoryBudget(budget string, servers int, serversMemory string, agents int, agentsMemory string) (string, string, error) {
+	budgetBytes, err := dockerunits.RAMInBytes(budget)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid memory budget '%s': %w", budget, err)
+	}
+
+	var used int64
+	unassigned := 0
+	for _, role := range []struct {
+		count  int
+		memory string
+	}{{servers, serversMemory}, {agents, agentsMemory}} {
+		if role.count == 0 {
+			continue
+		}
+		if role.memory == "" {
+			unassigned += role.count
+			continue
+		}
+		memory, err := dockerunits.RAMInBytes(role.memory)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid memory limit '%s': %w", role.memory, err)
+		}
+		used += memory * int64(role.count)
+	}
+
+	if used > budgetBytes {
+		return "", "", fmt.Errorf("memory limits of the nodes (%s) exceed the memory budget (%s)", dockerunits.BytesSize(float64(used)), dockerunits.BytesSize(float64(budgetBytes)))
+	}
+
+	if unassigned > 0 {
+		// round down to full MiB
+		perNode := fmt.Sprintf("%dm", (budgetBytes-used)/int64(unassigned)/dockerunits.MiB)
+		if perNode == "0m" {
+			return "", "", fmt.Errorf("memory budget (%s) is too small for %d nodes", dockerunits.BytesSize(float64(budgetBytes)), servers+agents)
+		}
+		if serversMemory == "" && servers > 0 {
+			serversMemory = perNode
+		}
+		if agentsMemory == "" && agents > 0 {
+			agentsMemory = perNode
+		}
+	}
+
+	return serversMemory, agentsMemory, nil
+}
+
+// ResolveImageDigests replaces the cluster-wide and the per-node k3s images of a config by references pinned to their current digest ('latest' is resolved first),
+// so that all nodes, incl. those created from a recorded config later on, run the very same image
+func ResolveImageDigests(ctx context.Context, runtime runtimes.Runtime, simpleConfig *conf.SimpleConfig) error {
+	if err := ResolveK3sImage(ctx, simpleConfig); err != nil {
+		return err
+	}
+	resolve := func(image string) (string, error) {
+		if image == "latest" {
+			image = latestK3sImage(ctx)
+		}
+		return client.ImageResolveDigest(ctx, runtime, image)
+	}
+
+	image, err := resolve(simpleConfig.Image)
+	if err != nil {
+		return err
+	}
+	simpleConfig.Image = image
+
+	for i, nodeImage := range simpleConfig.Options.Runtime.NodeImages {
+		image, err := resolve(nodeImage.Image)
+		if err != nil {
+			return err
+		}
+		simpleConfig.Options.Runtime.NodeImages[i].Image = image
+	}
+	return nil
+}
+
+// latestK3sImage returns the image of the latest k3s release (or the default one, if the latest version can't be fetched)
+func latestK3sImage(ctx context.Context) string {
+	image, err := client.K3sImageForChannel(ctx, "latest")
+	if err != nil {
+		l.Log().Warnf("%v: using the default k3s version %s", err, version.K3sVersion)
+		return fmt.Sprintf("%s:%s", k3d.DefaultK3sImageRepo, version.K3sVersion)
+	}
+	return image
+}
+
+// ResolveK3sImage replaces the cluster-wide k3s image of a config by the one of the given k3s version or channel (if any)
+func ResolveK3sImage(ctx context.Context, simpleConfig *conf.SimpleConfig) error {
+	if simpleConfig.K3sVersion != "" && simpleConfig.Channel != "" {
+		return fmt.Errorf("only one of k3sVersion (%s) and channel (%s) can be set", simpleConfig.K3sVersion, simpleConfig.Channel)
+	}
+	var image string
+	var err error
+	switch {
+	case simpleConfig.K3sVersion != "":
+		image, err = client.K3sImageForVersion(simpleConfig.K3sVersion)
+	case simpleConfig.Channel != "":
+		image, err = client.K3sImageForChannel(ctx, simpleConfig.Channel)
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	simpleConfig.Image = image
+	simpleConfig.K3sVersion, simpleConfig.Channel = "", ""
+	return nil
+}
+
+// readSeedObject reads the data of a Secret or ConfigMap from its source file:
+// .env files are split into one key per line, any other file is stored under its file name
+func readSeedObject(kind string, seed conf.SeedObjectFromSource) (k3d.SeedObject, error) {
+	obj := k3d.SeedObject{
+		Kind:      kind,
+		Name:      seed.Name,
+		Namespace: seed.Namespace,
+	}
+	if obj.Namespace == "" {
+		obj.Namespace = "default"
+	}
+
+	content, err := ioutil.ReadFile(seed.Source)
+	if err != nil {
+		return obj, fmt.Errorf("failed to read source of %s '%s': %w", kind, seed.Name, err)
+	}
+
+	if filepath.Ext(seed.Source) == ".env" {
+		obj.Data, err = parseEnvFile(content)
+		if err != nil {
+			return obj, fmt.Errorf("failed to parse env file '%s' for %s '%s': %w", seed.Source, kind, seed.Name, err)
+		}
+	} else {
+		obj.Data = map[string][]byte{filepath.Base(seed.Source): content}
+	}
+
+	return obj, nil
+}
+
+// parseEnvFile parses KEY=VALUE lines, ignoring empty lines and comments
+func parseEnvFile(content []byte) (map[string][]byte, error) {
+	data := map[string][]byte{}
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("line %d is not in the format KEY=VALUE", i+1)
+		}
+		data[kv[0]] = []byte(kv[1])
+	}
+	return data, nil
+}
+
+// readCustomCA reads the certificate and key of a custom CA and ensures that they belong together and the certificate is actually a CA
+func readCustomCA(certPath string, keyPath string) (*k3d.CustomCA, error) {
+	if certPath == "" || keyPath == "" {
+		return nil, fmt.Errorf("custom CA requires both a certificate and a key file")
+	}
+
+	certPEM, err := ioutil.ReadFile(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read custom CA certificate: %w", err)
+	}
+	keyPEM, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read custom CA key: %w", err)
+	}
+
+	keyPair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("custom CA certificate '%s' and key '%s' don't match: %w", certPath, keyPath, err)
+	}
+	cert, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse custom CA certificate: %w", err)
+	}
+	if !cert.IsCA {
+		return nil, fmt.Errorf("custom CA certificate '%s' (%s) is not a CA certificate", certPath, cert.Subject)
+	}
+
+	return &k3d.CustomCA{Cert: certPEM, Key: keyPEM}, nil
+}
+
+// readTrustedCA reads a PEM-encoded CA certificate (bundle) that should be trusted inside the nodes
+func readTrustedCA(index int, path string) (k3d.TrustedCA, error) {
+	certPEM, err := ioutil.ReadFile(path)
+	if err != nil {
+		return k3d.TrustedCA{}, fmt.Errorf("failed to read trusted CA '%s': %w", path, err)
+	}
+
+	// validate every certificate of a bundle, as a single broken one would be silently ignored inside the nodes
+	rest := certPEM
+	certs := 0
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return k3d.TrustedCA{}, fmt.Errorf("trusted CA '%s' contains a PEM block of type '%s' (only certificates are allowed)", path, block.Type)
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return k3d.TrustedCA{}, fmt.Errorf("failed to parse certificate #%d of trusted CA '%s': %w", certs+1, path, err)
+		}
+		certs++
+	}
+	if certs == 0 || len(bytes.TrimSpace(rest)) > 0 {
+		return k3d.TrustedCA{}, fmt.Errorf("trusted CA '%s' is not a PEM-encoded certificate (bundle)", path)
+	}
+
+	// prefix with the index, so that files with the same name from different directories don't overwrite each other
+	name := fmt.Sprintf("%s-%d-%s.pem", k3d.DefaultObjectNamePrefix, index, strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+
+	return k3d.TrustedCA{Name: name, Cert: certPEM}, nil
+}
+
+// sqliteHeader is the magic string every SQLite database file starts with
+const sqliteHeader = "SQLite format 3\x00"
+
+// readDatastoreBackup reads a backup of the k3s SQLite datastore (a copy of the server's state.db) and ensures that it actually is an SQLite database
+func readDatastoreBackup(backupPath string) ([]byte, error) {
+	content, err := ioutil.ReadFile(backupPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup: %w", err)
+	}
+	if !bytes.HasPrefix(content, []byte(sqliteHeader)) {
+		return nil, fmt.Errorf("backup '%s' is not an SQLite database (copy of a k3s server's %s); etcd snapshots are not supported", backupPath, k3d.DefaultServerDatastorePath)
+	}
+	return content, nil
+}
+
+// readAuditPolicy reads a Kubernetes audit policy file and ensures that it actually contains a Policy object
+func readAuditPolicy(policyPath string) ([]byte, error) {
+	content, err := ioutil.ReadFile(policyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit policy: %w", err)
+	}
+
+	var policy struct {
+		APIVersion string        `yaml:"apiVersion"`
+		Kind       string        `yaml:"kind"`
+		Rules      []interface{} `yaml:"rules"`
+	}
+	if err := yaml.Unmarshal(content, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse audit policy '%s': %w", policyPath, err)
+	}
+	if policy.Kind != "Policy" || !strings.HasPrefix(policy.APIVersion, "audit.k8s.io/") {
+		return nil, fmt.Errorf("audit policy '%s' is not an audit.k8s.io Policy (got kind '%s' in apiVersion '%s')", policyPath, policy.Kind, policy.APIVersion)
+	}
+	if len(policy.Rules) == 0 {
+		return nil, fmt.Errorf("audit policy '%s' doesn't contain any rules", policyPath)
+	}
+
+	return content, nil
+}
+
+// readManifests reads the Kubernetes manifests (files or directories containing *.yaml, *.yml and *.json files) that k3s deploys on startup
+func readManifests(paths []string) ([]k3d.Manifest, error) {
+	manifests := []k3d.Manifest{}
+	seen := map[string]string{}
+	for _, manifestPath := range paths {
+		info, err := os.Stat(manifestPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifest: %w", err)
+		}
+		files := []string{manifestPath}
+		if info.IsDir() {
+			entries, err := ioutil.ReadDir(manifestPath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read manifest directory: %w", err)
+			}
+			files = []string{}
+			for _, entry := range entries {
+				switch filepath.Ext(entry.Name()) {
+				case ".yaml", ".yml", ".json":
+					if !entry.IsDir() {
+						files = append(files, filepath.Join(manifestPath, entry.Name()))
+					}
+				}
+			}
+			if len(files) == 0 {
+				l.Log().Warnf("Manifest directory '%s' doesn't contain any *.yaml, *.yml or *.json files", manifestPath)
+			}
+		}
+		for _, file := range files {
+			name := filepath.Base(file)
+			if other, ok := seen[name]; ok {
+				return nil, fmt.Errorf("manifests '%s' and '%s' have the same file name, but k3s reads them from a single directory", other, file)
+			}
+			seen[name] = file
+			content, err := ioutil.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read manifest: %w", err)
+			}
+			manifests = append(manifests, k3d.Manifest{Name: name, Content: content})
+		}
+	}
+	return manifests, nil
+}
+
+// parseClusterHooks validates the phases of the lifecycle hooks
+func parseClusterHooks(simpleHooks []conf.SimpleConfigHook) ([]k3d.ClusterHook, error) {
+	hooks := []k3d.ClusterHook{}
+	for _, simpleHook := range simpleHooks {
+		phase := k3d.ClusterHookPhase(simpleHook.Phase)
+		valid := false
+		for _, p := range k3d.ClusterHookPhases {
+			if phase == p {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("unknown hook phase '%s' (one of %v)", simpleHook.Phase, k3d.ClusterHookPhases)
+		}
+		if strings.TrimSpace(simpleHook.Command) == "" {
+			return nil, fmt.Errorf("%s hook without a command", phase)
+		}
+		hooks = append(hooks, k3d.ClusterHook{Phase: phase, Command: simpleHook.Command})
+	}
+	return hooks, nil
+}
+
+// readNodeInit reads the script executed inside the nodes before k3s starts
+func readNodeInit(scriptPath string) ([]byte, error) {
+	content, err := ioutil.ReadFile(scriptPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read node init script: %w", err)
+	}
+	if len(bytes.TrimSpace(content)) == 0 {
+		return nil, fmt.Errorf("node init script '%s' is empty", scriptPath)
+	}
+	return content, nil
+}
+
+// Kubernetes components embedded in k3s, which take feature gates (via --<component>-arg)
+var (
+	featureGateServerComponents = []string{"kube-apiserver", "kube-controller-manager", "kube-scheduler", "kubelet", "kube-proxy"}
+	featureGateAgentComponents  = []string{"kubelet", "kube-proxy"}
+)
+
+// parseLogOpts parses the options of the nodes' logging driver (KEY=VALUE, like 'docker run --log-opt'), later ones overriding earlier ones
+func parseLogOpts(opts []string) (map[string]string, error) {
+	logOpts := map[string]string{}
+	for _, opt := range opts {
+		kv := strings.SplitN(opt, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("invalid log option '%s': expected format 'KEY=VALUE', e.g. 'max-size=10m'", opt)
+		}
+		logOpts[strings.TrimSpace(kv[0])] = kv[1]
+	}
+	return logOpts, nil
+}
+
+// parseFeatureGates validates a list of feature gates (Gate=true|false, each entry may contain multiple comma-separated gates) and joins them into a single, sorted --feature-gates value
+func parseFeatureGates(gates []string) (string, error) {
+	featureGates := map[string]bool{}
+	for _, entry := range gates {
+		for _, gate := range strings.Split(entry, ",") {
+			gate = strings.TrimSpace(gate)
+			if gate == "" {
+				continue
+			}
+			kv := strings.SplitN(gate, "=", 2)
+			if len(kv) != 2 || kv[0] == "" {
+				return "", fmt.Errorf("invalid feature gate '%s': expected format 'Gate=true|false'", gate)
+			}
+			enabled, err := strconv.ParseBool(kv[1])
+			if err != nil {
+				return "", fmt.Errorf("invalid value for feature gate '%s': %w", kv[0], err)
+			}
+			featureGates[kv[0]] = enabled
+		}
+	}
+
+	names := make([]string, 0, len(featureGates))
+	for name := range featureGates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=%t", name, featureGates[name]))
+	}
+	return strings.Join(pairs, ","), nil
+}
+
+// cacheVolumeNameRegexp matches the names of cache volumes, which become part of the volume name in the runtime
+var cacheVolumeNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// parseCacheVolumes parses the cache volume specs (NAME:PATH), rejecting duplicate names and mount paths
+func parseCacheVolumes(specs []string) ([]k3d.CacheVolume, error) {
+	cacheVolumes := []k3d.CacheVolume{}
+	names := map[string]bool{}
+	paths := map[string]bool{}
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, ":", 2)
+		if len(parts) != 2 || !cacheVolumeNameRegexp.MatchString(parts[0]) {
+			return nil, fmt.Errorf("invalid cache volume '%s': expected format 'NAME:PATH' with NAME consisting of alphanumerics, '_', '.' and '-'", spec)
+		}
+		name, mountPath := parts[0], path.Clean(parts[1])
+		if !path.IsAbs(mountPath) {
+			return nil, fmt.Errorf("invalid cache volume '%s': the path inside the nodes must be absolute", spec)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("cache volume '%s' is defined more than once", name)
+		}
+		if paths[mountPath] {
+			return nil, fmt.Errorf("more than one cache volume is mounted at '%s'", mountPath)
+		}
+		names[name], paths[mountPath] = true, true
+		cacheVolumes = append(cacheVolumes, k3d.CacheVolume{Name: name, Path: mountPath})
+	}
+	return cacheVolumes, nil
+}
+
+// etcdServerArgs returns the k3s args for the servers of a multi-server cluster: the user's etcd args plus defaults for everything they didn't set explicitly
+func etcdServerArgs(userEtcdArgs []string, extraArgs []conf.K3sArgWithNodeFilters) []string {
+	args := []string{}
+	userEtcdKeys := map[string]bool{}
+	for _, arg := range userEtcdArgs {
+		arg = strings.TrimLeft(arg, "-")
+		userEtcdKeys[strings.SplitN(arg, "=", 2)[0]] = true
+		args = append(args, fmt.Sprintf("--etcd-arg=%s", arg))
+	}
+
+	// defaults may also have been set via plain k3s args
+	userK3sArgs := []string{}
+	for _, extraArg := range extraArgs {
+		userK3sArgs = append(userK3sArgs, extraArg.Arg)
+	}
+	isSet := func(key string) bool {
+		for _, arg := range userK3sArgs {
+			if strings.Contains(arg, key+"=") {
+				return true
+			}
+		}
+		return false
+	}
+
+	keys := make([]string, 0, len(k3d.DefaultEtcdArgs))
+	for key := range k3d.DefaultEtcdArgs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !userEtcdKeys[key] && !isSet(key) {
+			args = append(args, fmt.Sprintf("--etcd-arg=%s=%s", key, k3d.DefaultEtcdArgs[key]))
+		}
+	}
+
+	if !isSet("system-reserved") {
+		args = append(args, fmt.Sprintf("--kubelet-arg=system-reserved=memory=%s", k3d.DefaultServerMemoryReservation))
+	}
+
+	return args
+}
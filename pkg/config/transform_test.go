@@ -23,11 +23,27 @@ THE SOFTWARE.
 package config
 
 import (
+	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/go-test/deep"
 	conf "github.com/rancher/k3d/v5/pkg/config/v1alpha3"
 	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/rancher/k3d/v5/pkg/types/k3s"
 	"github.com/spf13/viper"
 )
 
@@ -53,3 +69,618 @@ func TestTransformSimpleConfigToClusterConfig(t *testing.T) {
 	t.Logf("\n===== Resulting Cluster Config =====\n%+v\n===============\n", clusterCfg)
 
 }
+
+// newTestSimpleConfig returns a minimal SimpleConfig for a cluster with the given name and number of servers and agents, which transforms without touching the runtime
+func newTestSimpleConfig(name string, servers int, agents int) conf.SimpleConfig {
+	cfg := conf.SimpleConfig{
+		Name:    name,
+		Servers: servers,
+		Agents:  agents,
+		Image:   "rancher/k3s:v1.21.4-k3s1",
+	}
+	cfg.ExposeAPI.HostPort = "6443"
+	return cfg
+}
+
+func TestTransformFakeNodeMemory(t *testing.T) {
+	newSimpleConfig := func(fakeMemory ...conf.MemoryWithNodeFilters) conf.SimpleConfig {
+		cfg := newTestSimpleConfig("fake-memory", 1, 2)
+		cfg.Options.K3dOptions.DisableLoadbalancer = true
+		cfg.Options.Runtime.FakeNodeMemory = fakeMemory
+		return cfg
+	}
+
+	clusterCfg, err := TransformSimpleToClusterConfig(context.Background(), runtimes.Docker, newSimpleConfig(
+		conf.MemoryWithNodeFilters{Memory: "64Gi", NodeFilters: []string{"agent:0"}},
+		conf.MemoryWithNodeFilters{Memory: "8g", NodeFilters: []string{"server:*"}},
+	))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		"k3d-fake-memory-server-0": "8g",
+		"k3d-fake-memory-agent-0":  "64Gi",
+		"k3d-fake-memory-agent-1":  "",
+	}
+	for _, node := range clusterCfg.Cluster.Nodes {
+		want, ok := expected[node.Name]
+		if !ok {
+			continue
+		}
+		if node.FakeMemory != want {
+			t.Errorf("node '%s': expected fake memory '%s', got '%s'", node.Name, want, node.FakeMemory)
+		}
+		if node.Memory != "" {
+			t.Errorf("node '%s': expected no memory limit, got '%s'", node.Name, node.Memory)
+		}
+		delete(expected, node.Name)
+	}
+	if len(expected) > 0 {
+		t.Errorf("nodes missing from the cluster config: %v", expected)
+	}
+
+	if _, err := TransformSimpleToClusterConfig(context.Background(), runtimes.Docker, newSimpleConfig(conf.MemoryWithNodeFilters{Memory: "lots", NodeFilters: []string{"agent:0"}})); err == nil {
+		t.Errorf("expected an error for an invalid memory value")
+	}
+	if _, err := TransformSimpleToClusterConfig(context.Background(), runtimes.Docker, newSimpleConfig(conf.MemoryWithNodeFilters{Memory: "64Gi"})); err == nil {
+		t.Errorf("expected an error for a missing node filter with multiple nodes")
+	}
+}
+
+func TestTransformNetworkOptions(t *testing.T) {
+	newSimpleConfig := func(subnet, gateway, ipv6 string) conf.SimpleConfig {
+		cfg := newTestSimpleConfig("network", 1, 0)
+		cfg.Subnet = subnet
+		cfg.Gateway = gateway
+		cfg.IPv6 = ipv6
+		cfg.Options.K3dOptions.DisableLoadbalancer = true
+		return cfg
+	}
+
+	clusterCfg, err := TransformSimpleToClusterConfig(context.Background(), runtimes.Docker, newSimpleConfig("172.28.0.0/16", "172.28.0.254", "fd00:cafe::/64"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	network := clusterCfg.Cluster.Network
+	if network.IPAM.Gateway.String() != "172.28.0.254" {
+		t.Errorf("expected gateway 172.28.0.254, got %s", network.IPAM.Gateway)
+	}
+	if !network.IPv6.Enabled || network.IPv6.IPPrefix.String() != "fd00:cafe::/64" {
+		t.Errorf("expected IPv6 with subnet fd00:cafe::/64, got %+v", network.IPv6)
+	}
+
+	clusterCfg, err = TransformSimpleToClusterConfig(context.Background(), runtimes.Docker, newSimpleConfig("", "", "auto"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !clusterCfg.Cluster.Network.IPv6.Enabled || !clusterCfg.Cluster.Network.IPv6.IPPrefix.IsZero() {
+		t.Errorf("expected IPv6 without subnet, got %+v", clusterCfg.Cluster.Network.IPv6)
+	}
+
+	invalid := map[string]conf.SimpleConfig{
+		"gateway without subnet":    newSimpleConfig("", "172.28.0.254", ""),
+		"gateway with auto subnet":  newSimpleConfig("auto", "172.28.0.254", ""),
+		"gateway outside of subnet": newSimpleConfig("172.28.0.0/16", "10.0.0.1", ""),
+		"IPv4 subnet as IPv6":       newSimpleConfig("", "", "172.28.0.0/16"),
+	}
+	for name, cfg := range invalid {
+		if _, err := TransformSimpleToClusterConfig(context.Background(), runtimes.Docker, cfg); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestTransformCRI(t *testing.T) {
+	newSimpleConfig := func(cri string) conf.SimpleConfig {
+		cfg := newTestSimpleConfig("cri", 1, 1)
+		cfg.Options.K3sOptions.CRI = cri
+		return cfg
+	}
+
+	clusterCfg, err := TransformSimpleToClusterConfig(context.Background(), runtimes.Docker, newSimpleConfig("cri-dockerd"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if clusterCfg.ClusterCreateOpts.CRI != k3d.CRIDockerd {
+		t.Errorf("expected CRI '%s', got '%s'", k3d.CRIDockerd, clusterCfg.ClusterCreateOpts.CRI)
+	}
+	for _, node := range clusterCfg.Cluster.Nodes {
+		hasDockerArg := false
+		for _, arg := range node.Args {
+			hasDockerArg = hasDockerArg || arg == "--docker"
+		}
+		if isK3sNode := node.Role == k3d.ServerRole || node.Role == k3d.AgentRole; hasDockerArg != isK3sNode {
+			t.Errorf("node '%s': expected --docker only in k3s nodes, got args %v", node.Name, node.Args)
+		}
+	}
+
+	clusterCfg, err = TransformSimpleToClusterConfig(context.Background(), runtimes.Docker, newSimpleConfig("containerd"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, node := range clusterCfg.Cluster.Nodes {
+		for _, arg := range node.Args {
+			if arg == "--docker" {
+				t.Errorf("node '%s': expected no --docker with containerd", node.Name)
+			}
+		}
+	}
+
+	if _, err := TransformSimpleToClusterConfig(context.Background(), runtimes.Docker, newSimpleConfig("cri-o")); err == nil {
+		t.Errorf("expected an error for an unknown CRI")
+	}
+}
+
+func TestTransformNoScheduleOnServer(t *testing.T) {
+	cfg := newTestSimpleConfig("no-schedule", 2, 2)
+	cfg.Options.K3sOptions.NoScheduleOnServer = true
+
+	clusterCfg, err := TransformSimpleToClusterConfig(context.Background(), runtimes.Docker, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	taintArg := "--node-taint=node-role.kubernetes.io/control-plane:NoSchedule"
+	servers, agents := 0, 0
+	for _, node := range clusterCfg.Cluster.Nodes {
+		hasTaint := false
+		for _, arg := range node.Args {
+			hasTaint = hasTaint || arg == taintArg
+		}
+		switch node.Role {
+		case k3d.ServerRole:
+			servers++
+			if !hasTaint {
+				t.Errorf("server '%s': expected %s, got args %v", node.Name, taintArg, node.Args)
+			}
+		case k3d.AgentRole:
+			agents++
+			if hasTaint {
+				t.Errorf("agent '%s': expected no %s, got args %v", node.Name, taintArg, node.Args)
+			}
+		}
+	}
+	if servers != 2 || agents != 2 {
+		t.Errorf("expected 2 servers and 2 agents, got %d and %d", servers, agents)
+	}
+}
+
+func TestSplitMemoryBudget(t *testing.T) {
+	tests := map[string]struct {
+		budget                          string
+		servers                         int
+		serversMemory                   string
+		agents                          int
+		agentsMemory                    string
+		expectedServers, expectedAgents string
+		expectError                     bool
+	}{
+		"even split": {
+			budget: "8g", servers: 1, agents: 3,
+			expectedServers: "2048m", expectedAgents: "2048m",
+		},
+		"explicit servers memory": {
+			budget: "8g", servers: 1, serversMemory: "2g", agents: 2,
+			expectedServers: "2g", expectedAgents: "3072m",
+		},
+		"no agents": {
+			budget: "3g", servers: 3,
+			expectedServers: "1024m", expectedAgents: "",
+		},
+		"explicit limits exceed budget": {
+			budget: "4g", servers: 1, serversMemory: "2g", agents: 2, agentsMemory: "2g",
+			expectError: true,
+		},
+		"invalid budget": {
+			budget: "lots", servers: 1,
+			expectError: true,
+		},
+	}
+
+	for name, tc := range tests {
+		servers, agents, err := splitMemoryBudget(tc.budget, tc.servers, tc.serversMemory, tc.agents, tc.agentsMemory)
+		if tc.expectError {
+			if err == nil {
+				t.Errorf("%s: expected error, got servers=%s, agents=%s", name, servers, agents)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+			continue
+		}
+		if servers != tc.expectedServers || agents != tc.expectedAgents {
+			t.Errorf("%s: expected servers=%s, agents=%s, got servers=%s, agents=%s", name, tc.expectedServers, tc.expectedAgents, servers, agents)
+		}
+	}
+}
+
+func TestParseEnvFile(t *testing.T) {
+	content := []byte("# database credentials\nDB_USER=admin\n\nDB_PASS=s3cr=t\n")
+
+	data, err := parseEnvFile(content)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string][]byte{"DB_USER": []byte("admin"), "DB_PASS": []byte("s3cr=t")}
+	if diff := deep.Equal(data, expected); diff != nil {
+		t.Errorf("unexpected env file data: %+v", diff)
+	}
+
+	if _, err := parseEnvFile([]byte("NOVALUE\n")); err == nil {
+		t.Errorf("expected an error for a line without '='")
+	}
+}
+
+func TestReadCustomCA(t *testing.T) {
+	dir := t.TempDir()
+
+	writeCA := func(name string, isCA bool) (string, string) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		template := &x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{CommonName: name},
+			NotBefore:             time.Now(),
+			NotAfter:              time.Now().Add(time.Hour),
+			IsCA:                  isCA,
+			BasicConstraintsValid: true,
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		certPath, keyPath := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+		if err := ioutil.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return certPath, keyPath
+	}
+
+	caCert, caKey := writeCA("ca", true)
+	leafCert, leafKey := writeCA("leaf", false)
+
+	if _, err := readCustomCA(caCert, caKey); err != nil {
+		t.Errorf("unexpected error for valid CA: %v", err)
+	}
+	if _, err := readCustomCA(caCert, leafKey); err == nil {
+		t.Errorf("expected an error for mismatching certificate and key")
+	}
+	if _, err := readCustomCA(leafCert, leafKey); err == nil {
+		t.Errorf("expected an error for a non-CA certificate")
+	}
+	if _, err := readCustomCA(caCert, ""); err == nil {
+		t.Errorf("expected an error for a missing key")
+	}
+}
+
+func TestReadAuditPolicy(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := map[string]struct {
+		content string
+		wantErr bool
+	}{
+		"valid": {
+			content: "apiVersion: audit.k8s.io/v1\nkind: Policy\nrules:\n  - level: Metadata\n",
+		},
+		"wrong kind": {
+			content: "apiVersion: v1\nkind: ConfigMap\ndata: {}\n",
+			wantErr: true,
+		},
+		"no rules": {
+			content: "apiVersion: audit.k8s.io/v1\nkind: Policy\n",
+			wantErr: true,
+		},
+		"invalid yaml": {
+			content: "kind: [Policy\n",
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		policyPath := filepath.Join(dir, strings.ReplaceAll(name, " ", "-")+".yaml")
+		if err := ioutil.WriteFile(policyPath, []byte(tc.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		content, err := readAuditPolicy(policyPath)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error, got none", name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+		} else if string(content) != tc.content {
+			t.Errorf("%s: expected the policy to be returned unchanged, got '%s'", name, string(content))
+		}
+	}
+
+	if _, err := readAuditPolicy(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Errorf("expected an error for a missing policy file")
+	}
+}
+
+func TestReadDatastoreBackup(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "state.db")
+	content := append([]byte(sqliteHeader), 0x10, 0x00, 0x01, 0x01)
+	if err := ioutil.WriteFile(valid, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if backup, err := readDatastoreBackup(valid); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if !bytes.Equal(backup, content) {
+		t.Errorf("expected the backup to be returned unchanged")
+	}
+
+	snapshot := filepath.Join(dir, "etcd-snapshot")
+	if err := ioutil.WriteFile(snapshot, []byte("not a database"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readDatastoreBackup(snapshot); err == nil {
+		t.Errorf("expected an error for a file which isn't an SQLite database")
+	}
+
+	if _, err := readDatastoreBackup(filepath.Join(dir, "missing.db")); err == nil {
+		t.Errorf("expected an error for a missing backup")
+	}
+}
+
+func TestParseFeatureGates(t *testing.T) {
+	got, err := parseFeatureGates([]string{"GracefulNodeShutdown=false,EphemeralContainers=true", " CSIStorageCapacity=true", "EphemeralContainers=false"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "CSIStorageCapacity=true,EphemeralContainers=false,GracefulNodeShutdown=false"
+	if got != expected {
+		t.Errorf("expected '%s', got '%s'", expected, got)
+	}
+
+	for _, invalid := range []string{"EphemeralContainers", "=true", "EphemeralContainers=yes"} {
+		if _, err := parseFeatureGates([]string{invalid}); err == nil {
+			t.Errorf("expected an error for feature gate '%s', got none", invalid)
+		}
+	}
+}
+
+func TestParseLogOpts(t *testing.T) {
+	got, err := parseLogOpts([]string{"max-size=10m", "max-file=3", "labels=a,b", "max-size=20m"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]string{"max-size": "20m", "max-file": "3", "labels": "a,b"}
+	if diff := deep.Equal(got, expected); diff != nil {
+		t.Errorf("unexpected log options: %+v", diff)
+	}
+
+	for _, invalid := range []string{"max-size", "=10m"} {
+		if _, err := parseLogOpts([]string{invalid}); err == nil {
+			t.Errorf("expected an error for log option '%s', got none", invalid)
+		}
+	}
+}
+
+func TestParseCacheVolumes(t *testing.T) {
+	got, err := parseCacheVolumes([]string{"gomod:/root/go/pkg/mod/", "npm:/root/.npm"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []k3d.CacheVolume{{Name: "gomod", Path: "/root/go/pkg/mod"}, {Name: "npm", Path: "/root/.npm"}}
+	if diff := deep.Equal(got, expected); diff != nil {
+		t.Errorf("unexpected cache volumes: %+v", diff)
+	}
+
+	for _, invalid := range [][]string{{"gomod"}, {":/root/go"}, {"go mod:/root/go"}, {"gomod:relative/path"}, {"a:/x", "a:/y"}, {"a:/x", "b:/x/"}} {
+		if _, err := parseCacheVolumes(invalid); err == nil {
+			t.Errorf("expected an error for cache volumes %v, got none", invalid)
+		}
+	}
+}
+
+func TestEtcdServerArgs(t *testing.T) {
+	// defaults only
+	expected := []string{"--etcd-arg=snapshot-count=10000", "--kubelet-arg=system-reserved=memory=512Mi"}
+	if diff := deep.Equal(etcdServerArgs(nil, nil), expected); diff != nil {
+		t.Errorf("unexpected default etcd args: %+v", diff)
+	}
+
+	// user args override the defaults, no matter how they were passed
+	got := etcdServerArgs([]string{"--snapshot-count=5000", "quota-backend-bytes=1073741824"}, []conf.K3sArgWithNodeFilters{
+		{Arg: "--kubelet-arg=system-reserved=memory=1Gi", NodeFilters: []string{"server:*"}},
+	})
+	expected = []string{"--etcd-arg=snapshot-count=5000", "--etcd-arg=quota-backend-bytes=1073741824"}
+	if diff := deep.Equal(got, expected); diff != nil {
+		t.Errorf("unexpected etcd args with user overrides: %+v", diff)
+	}
+}
+
+func TestReadTrustedCA(t *testing.T) {
+	dir := t.TempDir()
+
+	newCertPEM := func(name string) []byte {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		template := &x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{CommonName: name},
+			NotBefore:             time.Now(),
+			NotAfter:              time.Now().Add(time.Hour),
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	}
+	writeFile := func(name string, content []byte) string {
+		p := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(p, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	single := writeFile("corp-root.pem", newCertPEM("root"))
+	bundle := writeFile("bundle.pem", append(newCertPEM("root"), newCertPEM("intermediate")...))
+	brokenBundle := writeFile("broken-bundle.pem", append(newCertPEM("root"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("garbage")})...))
+	withKey := writeFile("with-key.pem", append(newCertPEM("root"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte("key")})...))
+	trailingGarbage := writeFile("trailing.pem", append(newCertPEM("root"), []byte("not a certificate")...))
+	notPEM := writeFile("not.pem", []byte("not a certificate"))
+
+	ca, err := readTrustedCA(2, single)
+	if err != nil {
+		t.Fatalf("unexpected error for a single certificate: %v", err)
+	}
+	if ca.Name != "k3d-2-corp-root.pem" {
+		t.Errorf("expected name 'k3d-2-corp-root.pem', got '%s'", ca.Name)
+	}
+
+	if _, err := readTrustedCA(0, bundle); err != nil {
+		t.Errorf("unexpected error for a bundle: %v", err)
+	}
+
+	for _, invalid := range []string{brokenBundle, withKey, trailingGarbage, notPEM, filepath.Join(dir, "missing.pem")} {
+		if _, err := readTrustedCA(0, invalid); err == nil {
+			t.Errorf("expected an error for '%s'", filepath.Base(invalid))
+		}
+	}
+}
+
+func TestReadNodeInit(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "init.sh")
+	if err := ioutil.WriteFile(script, []byte("#!/bin/sh\nsysctl -w vm.max_map_count=262144\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(dir, "empty.sh")
+	if err := ioutil.WriteFile(empty, []byte("\n  \n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := readNodeInit(script)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(content), "vm.max_map_count") {
+		t.Errorf("unexpected content '%s'", content)
+	}
+
+	for _, invalid := range []string{empty, filepath.Join(dir, "missing.sh")} {
+		if _, err := readNodeInit(invalid); err == nil {
+			t.Errorf("expected an error for '%s'", filepath.Base(invalid))
+		}
+	}
+}
+
+func TestReadManifests(t *testing.T) {
+	dir := t.TempDir()
+	apps := filepath.Join(dir, "apps")
+	if err := os.Mkdir(apps, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		filepath.Join(dir, "ingress.yaml"):   "kind: Deployment\n",
+		filepath.Join(apps, "app.yml"):       "kind: Service\n",
+		filepath.Join(apps, "README.md"):     "not a manifest\n",
+		filepath.Join(dir, "other", "a.yml"): "kind: Secret\n",
+	}
+	for file, content := range files {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manifests, err := readManifests([]string{filepath.Join(dir, "ingress.yaml"), apps})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []k3d.Manifest{
+		{Name: "ingress.yaml", Content: []byte("kind: Deployment\n")},
+		{Name: "app.yml", Content: []byte("kind: Service\n")},
+	}
+	if diff := deep.Equal(manifests, expected); diff != nil {
+		t.Errorf("unexpected manifests: %v", diff)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "other", "app.yml"), []byte("kind: Secret\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readManifests([]string{apps, filepath.Join(dir, "other")}); err == nil {
+		t.Errorf("expected an error for manifests with the same file name")
+	}
+	if _, err := readManifests([]string{filepath.Join(dir, "missing.yaml")}); err == nil {
+		t.Errorf("expected an error for a missing manifest")
+	}
+}
+
+func TestParseClusterHooks(t *testing.T) {
+	hooks, err := parseClusterHooks([]conf.SimpleConfigHook{{Phase: "postClusterReady", Command: "kubectl apply -k ./overlays/dev"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(hooks) != 1 || hooks[0].Phase != k3d.ClusterHookPostClusterReady {
+		t.Errorf("unexpected hooks %v", hooks)
+	}
+
+	for _, invalid := range []conf.SimpleConfigHook{{Phase: "postCreate", Command: "true"}, {Phase: "preDelete", Command: " "}} {
+		if _, err := parseClusterHooks([]conf.SimpleConfigHook{invalid}); err == nil {
+			t.Errorf("expected an error for %v", invalid)
+		}
+	}
+}
+
+func TestParseRegistryMirrors(t *testing.T) {
+	mirrors, err := parseRegistryMirrors([]string{"docker.io=https://mirror.corp", "quay.io=https://quay-mirror.corp, https://quay.io", "docker.io=http://fallback.corp:5000"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]k3s.Mirror{
+		"docker.io": {Endpoints: []string{"https://mirror.corp", "http://fallback.corp:5000"}},
+		"quay.io":   {Endpoints: []string{"https://quay-mirror.corp", "https://quay.io"}},
+	}
+	if diff := deep.Equal(mirrors, expected); diff != nil {
+		t.Errorf("unexpected mirrors: %v", diff)
+	}
+
+	for _, invalid := range []string{"docker.io", "=https://mirror.corp", "docker.io=", "docker.io=mirror.corp", "docker.io=ftp://mirror.corp"} {
+		if _, err := parseRegistryMirrors([]string{invalid}); err == nil {
+			t.Errorf("expected an error for registry mirror '%s', got none", invalid)
+		}
+	}
+}
+
+func TestResolveK3sImage(t *testing.T) {
+	simpleConfig := conf.SimpleConfig{Image: "rancher/k3s:v1.20.0-k3s2", K3sVersion: "v1.21.4+k3s1"}
+	if err := ResolveK3sImage(context.Background(), &simpleConfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if simpleConfig.Image != k3d.DefaultK3sImageRepo+":v1.21.4-k3s1" || simpleConfig.K3sVersion != "" {
+		t.Errorf("unexpected config after resolving the k3s version: %+v", simpleConfig)
+	}
+
+	simpleConfig = conf.SimpleConfig{K3sVersion: "v1.21.4+k3s1", Channel: "stable"}
+	if err := ResolveK3sImage(context.Background(), &simpleConfig); err == nil {
+		t.Errorf("expected an error for a k3s version together with a channel")
+	}
+}
@@ -108,6 +108,8 @@ func MigrateV1Alpha2(input configtypes.Config) (configtypes.Config, error) {
 			})
 		}
 
+		cfg.Options.K3dOptions.DisableHostIP = input.(v1alpha2.SimpleConfig).Options.K3dOptions.PrepDisableHostIPInjection
+
 		if input.(v1alpha2.SimpleConfig).Registries.Create {
 			cfg.Registries.Create = &SimpleConfigRegistryCreateConfig{
 				Name:     fmt.Sprintf("%s-%s-registry", k3d.DefaultObjectNamePrefix, cfg.Name),
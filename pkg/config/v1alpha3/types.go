@@ -36,6 +36,7 @@ import (
 const ApiVersion = "k3d.io/v1alpha3"
 
 // JSONSchema describes the schema used to validate config files
+//
 //go:embed schema.json
 var JSONSchema string
 
@@ -71,6 +72,22 @@ type LabelWithNodeFilters struct {
 	NodeFilters []string `mapstructure:"nodeFilters" yaml:"nodeFilters" json:"nodeFilters,omitempty"`
 }
 
+type ImageWithNodeFilters struct {
+	Image       string   `mapstructure:"image" yaml:"image" json:"image,omitempty"`
+	NodeFilters []string `mapstructure:"nodeFilters" yaml:"nodeFilters" json:"nodeFilters,omitempty"`
+}
+
+type MemoryWithNodeFilters struct {
+	Memory      string   `mapstructure:"memory" yaml:"memory" json:"memory,omitempty"`
+	NodeFilters []string `mapstructure:"nodeFilters" yaml:"nodeFilters" json:"nodeFilters,omitempty"`
+}
+
+type SeedObjectFromSource struct {
+	Name      string `mapstructure:"name" yaml:"name" json:"name,omitempty"`
+	Namespace string `mapstructure:"namespace" yaml:"namespace" json:"namespace,omitempty"`
+	Source    string `mapstructure:"source" yaml:"source" json:"source,omitempty"`
+}
+
 type EnvVarWithNodeFilters struct {
 	EnvVar      string   `mapstructure:"envVar" yaml:"envVar" json:"envVar,omitempty"`
 	NodeFilters []string `mapstructure:"nodeFilters" yaml:"nodeFilters" json:"nodeFilters,omitempty"`
@@ -89,8 +106,10 @@ type SimpleConfigRegistryCreateConfig struct {
 
 // SimpleConfigOptionsKubeconfig describes the set of options referring to the kubeconfig during cluster creation.
 type SimpleConfigOptionsKubeconfig struct {
-	UpdateDefaultKubeconfig bool `mapstructure:"updateDefaultKubeconfig" yaml:"updateDefaultKubeconfig" json:"updateDefaultKubeconfig,omitempty"` // default: true
-	SwitchCurrentContext    bool `mapstructure:"switchCurrentContext" yaml:"switchCurrentContext" json:"switchCurrentContext,omitempty"`          //nolint:lll    // default: true
+	UpdateDefaultKubeconfig bool   `mapstructure:"updateDefaultKubeconfig" yaml:"updateDefaultKubeconfig" json:"updateDefaultKubeconfig,omitempty"` // default: true
+	SwitchCurrentContext    bool   `mapstructure:"switchCurrentContext" yaml:"switchCurrentContext" json:"switchCurrentContext,omitempty"`          //nolint:lll    // default: true
+	DefaultNamespace        string `mapstructure:"defaultNamespace" yaml:"defaultNamespace,omitempty" json:"defaultNamespace,omitempty"`
+	ContextTemplate         string `mapstructure:"contextTemplate" yaml:"contextTemplate,omitempty" json:"contextTemplate,omitempty"`
 }
 
 type SimpleConfigOptions struct {
@@ -101,19 +120,47 @@ type SimpleConfigOptions struct {
 }
 
 type SimpleConfigOptionsRuntime struct {
-	GPURequest    string                 `mapstructure:"gpuRequest" yaml:"gpuRequest"`
-	ServersMemory string                 `mapstructure:"serversMemory" yaml:"serversMemory"`
-	AgentsMemory  string                 `mapstructure:"agentsMemory" yaml:"agentsMemory"`
-	Labels        []LabelWithNodeFilters `mapstructure:"labels" yaml:"labels"`
+	GPURequest     string                  `mapstructure:"gpuRequest" yaml:"gpuRequest"`
+	ServersMemory  string                  `mapstructure:"serversMemory" yaml:"serversMemory"`
+	AgentsMemory   string                  `mapstructure:"agentsMemory" yaml:"agentsMemory"`
+	ServersCPUs    string                  `mapstructure:"serversCpus" yaml:"serversCpus,omitempty"`
+	AgentsCPUs     string                  `mapstructure:"agentsCpus" yaml:"agentsCpus,omitempty"`
+	MemoryBudget   string                  `mapstructure:"memoryBudget" yaml:"memoryBudget"`
+	Labels         []LabelWithNodeFilters  `mapstructure:"labels" yaml:"labels"`
+	FakeNodeMemory []MemoryWithNodeFilters `mapstructure:"fakeNodeMemory" yaml:"fakeNodeMemory"`
+	NodeImages     []ImageWithNodeFilters  `mapstructure:"nodeImages" yaml:"nodeImages,omitempty"`
+	SELinux        bool                    `mapstructure:"selinux" yaml:"selinux,omitempty"`
+	AppArmor       string                  `mapstructure:"apparmorProfile" yaml:"apparmorProfile,omitempty"`
+	ShmSize        string                  `mapstructure:"shmSize" yaml:"shmSize,omitempty"`
+	LogDriver      string                  `mapstructure:"logDriver" yaml:"logDriver,omitempty"`
+	LogOpts        []string                `mapstructure:"logOpts" yaml:"logOpts,omitempty"`
+	EphemeralState bool                    `mapstructure:"ephemeralState" yaml:"ephemeralState,omitempty"`
+	PullPolicy     string                  `mapstructure:"pullPolicy" yaml:"pullPolicy,omitempty"`
 }
 
 type SimpleConfigOptionsK3d struct {
 	Wait                bool                               `mapstructure:"wait" yaml:"wait"`
 	Timeout             time.Duration                      `mapstructure:"timeout" yaml:"timeout"`
+	ReadinessTimeout    time.Duration                      `mapstructure:"readinessTimeout" yaml:"readinessTimeout,omitempty"`
 	DisableLoadbalancer bool                               `mapstructure:"disableLoadbalancer" yaml:"disableLoadbalancer"`
 	DisableImageVolume  bool                               `mapstructure:"disableImageVolume" yaml:"disableImageVolume"`
 	NoRollback          bool                               `mapstructure:"disableRollback" yaml:"disableRollback"`
+	VirtualWorkers      int                                `mapstructure:"virtualWorkers" yaml:"virtualWorkers"`
+	WaitFor             []string                           `mapstructure:"waitFor" yaml:"waitFor,omitempty"`
+	RunnerContainer     string                             `mapstructure:"runnerContainer" yaml:"runnerContainer,omitempty"`
+	TrustCAs            []string                           `mapstructure:"trustCAs" yaml:"trustCAs,omitempty"`
+	NodeInit            string                             `mapstructure:"nodeInit" yaml:"nodeInit,omitempty"`
+	HostDNS             bool                               `mapstructure:"hostDNS" yaml:"hostDNS,omitempty"`
+	APIDNS              bool                               `mapstructure:"apiDNS" yaml:"apiDNS,omitempty"`
+	FixSysctls          bool                               `mapstructure:"fixSysctls" yaml:"fixSysctls,omitempty"`
+	NoStart             bool                               `mapstructure:"noStart" yaml:"noStart,omitempty"`
+	ImageCache          bool                               `mapstructure:"imageCache" yaml:"imageCache,omitempty"`
+	CacheVolumes        []string                           `mapstructure:"cacheVolumes" yaml:"cacheVolumes,omitempty"`
+	DeferWorkers        bool                               `mapstructure:"deferWorkers" yaml:"deferWorkers,omitempty"`
+	DisableHostIP       bool                               `mapstructure:"disableHostIPInjection" yaml:"disableHostIPInjection,omitempty"`
+	ResolveDigest       bool                               `mapstructure:"resolveDigest" yaml:"resolveDigest,omitempty"`
 	NodeHookActions     []k3d.NodeHookAction               `mapstructure:"nodeHookActions" yaml:"nodeHookActions,omitempty"`
+	NodeNameTemplate    string                             `mapstructure:"nodeNameTemplate" yaml:"nodeNameTemplate,omitempty"`
 	Loadbalancer        SimpleConfigOptionsK3dLoadbalancer `mapstructure:"loadbalancer" yaml:"loadbalancer,omitempty"`
 }
 
@@ -122,14 +169,43 @@ type SimpleConfigOptionsK3dLoadbalancer struct {
 }
 
 type SimpleConfigOptionsK3s struct {
-	ExtraArgs  []K3sArgWithNodeFilters `mapstructure:"extraArgs" yaml:"extraArgs"`
-	NodeLabels []LabelWithNodeFilters  `mapstructure:"nodeLabels" yaml:"nodeLabels"`
+	ExtraArgs          []K3sArgWithNodeFilters `mapstructure:"extraArgs" yaml:"extraArgs"`
+	NodeLabels         []LabelWithNodeFilters  `mapstructure:"nodeLabels" yaml:"nodeLabels"`
+	Secrets            []SeedObjectFromSource  `mapstructure:"secrets" yaml:"secrets,omitempty"`
+	ConfigMaps         []SeedObjectFromSource  `mapstructure:"configMaps" yaml:"configMaps,omitempty"`
+	CustomCA           SimpleConfigCustomCA    `mapstructure:"customCA" yaml:"customCA,omitempty"`
+	OIDC               SimpleConfigOIDC        `mapstructure:"oidc" yaml:"oidc,omitempty"`
+	AuditPolicy        string                  `mapstructure:"auditPolicy" yaml:"auditPolicy,omitempty"`
+	FromBackup         string                  `mapstructure:"fromBackup" yaml:"fromBackup,omitempty"`
+	FeatureGates       []string                `mapstructure:"featureGates" yaml:"featureGates,omitempty"`
+	EtcdArgs           []string                `mapstructure:"etcdArgs" yaml:"etcdArgs,omitempty"`
+	NoScheduleOnServer bool                    `mapstructure:"noScheduleOnServer" yaml:"noScheduleOnServer,omitempty"`
+	Manifests          []string                `mapstructure:"manifests" yaml:"manifests,omitempty"`
+	CRI                string                  `mapstructure:"cri" yaml:"cri,omitempty"`
+}
+
+type SimpleConfigOIDC struct {
+	IssuerURL     string `mapstructure:"issuerURL" yaml:"issuerURL,omitempty"`
+	ClientID      string `mapstructure:"clientID" yaml:"clientID,omitempty"`
+	UsernameClaim string `mapstructure:"usernameClaim" yaml:"usernameClaim,omitempty"`
+	GroupsClaim   string `mapstructure:"groupsClaim" yaml:"groupsClaim,omitempty"`
+}
+
+type SimpleConfigCustomCA struct {
+	Cert string `mapstructure:"cert" yaml:"cert,omitempty"`
+	Key  string `mapstructure:"key" yaml:"key,omitempty"`
+}
+
+type SimpleConfigHook struct {
+	Phase   string `mapstructure:"phase" yaml:"phase" json:"phase"`
+	Command string `mapstructure:"command" yaml:"command" json:"command"`
 }
 
 type SimpleConfigRegistries struct {
-	Use    []string                          `mapstructure:"use" yaml:"use,omitempty" json:"use,omitempty"`
-	Create *SimpleConfigRegistryCreateConfig `mapstructure:"create" yaml:"create,omitempty" json:"create,omitempty"`
-	Config string                            `mapstructure:"config" yaml:"config,omitempty" json:"config,omitempty"` // registries.yaml (k3s config for containerd registry override)
+	Use     []string                          `mapstructure:"use" yaml:"use,omitempty" json:"use,omitempty"`
+	Create  *SimpleConfigRegistryCreateConfig `mapstructure:"create" yaml:"create,omitempty" json:"create,omitempty"`
+	Config  string                            `mapstructure:"config" yaml:"config,omitempty" json:"config,omitempty"`    // registries.yaml (k3s config for containerd registry override)
+	Mirrors []string                          `mapstructure:"mirrors" yaml:"mirrors,omitempty" json:"mirrors,omitempty"` // REGISTRY=ENDPOINT[,ENDPOINT...], added to the registries.yaml
 }
 
 type SimpleConfigRegistriesIntermediateV1alpha2 struct {
@@ -142,18 +218,25 @@ type SimpleConfigRegistriesIntermediateV1alpha2 struct {
 type SimpleConfig struct {
 	config.TypeMeta `mapstructure:",squash" yaml:",inline"`
 	Name            string                  `mapstructure:"name" yaml:"name" json:"name,omitempty"`
+	Description     string                  `mapstructure:"description" yaml:"description,omitempty" json:"description,omitempty"`
+	DependsOn       []string                `mapstructure:"dependsOn" yaml:"dependsOn,omitempty" json:"dependsOn,omitempty"`
 	Servers         int                     `mapstructure:"servers" yaml:"servers" json:"servers,omitempty"` //nolint:lll    // default 1
 	Agents          int                     `mapstructure:"agents" yaml:"agents" json:"agents,omitempty"`    //nolint:lll    // default 0
 	ExposeAPI       SimpleExposureOpts      `mapstructure:"kubeAPI" yaml:"kubeAPI" json:"kubeAPI,omitempty"`
 	Image           string                  `mapstructure:"image" yaml:"image" json:"image,omitempty"`
+	K3sVersion      string                  `mapstructure:"k3sVersion" yaml:"k3sVersion,omitempty" json:"k3sVersion,omitempty"` // k3s release (e.g. v1.21.4+k3s1), overrides the image
+	Channel         string                  `mapstructure:"channel" yaml:"channel,omitempty" json:"channel,omitempty"`          // k3s channel (e.g. stable, latest, v1.21), overrides the image
 	Network         string                  `mapstructure:"network" yaml:"network" json:"network,omitempty"`
 	Subnet          string                  `mapstructure:"subnet" yaml:"subnet" json:"subnet,omitempty"`
-	ClusterToken    string                  `mapstructure:"token" yaml:"clusterToken" json:"clusterToken,omitempty"` // default: auto-generated
+	Gateway         string                  `mapstructure:"gateway" yaml:"gateway,omitempty" json:"gateway,omitempty"` // default: second IP in the subnet
+	IPv6            string                  `mapstructure:"ipv6" yaml:"ipv6,omitempty" json:"ipv6,omitempty"`          // IPv6 subnet or 'auto'
+	ClusterToken    string                  `mapstructure:"token" yaml:"clusterToken" json:"clusterToken,omitempty"`   // default: auto-generated
 	Volumes         []VolumeWithNodeFilters `mapstructure:"volumes" yaml:"volumes" json:"volumes,omitempty"`
 	Ports           []PortWithNodeFilters   `mapstructure:"ports" yaml:"ports" json:"ports,omitempty"`
 	Options         SimpleConfigOptions     `mapstructure:"options" yaml:"options" json:"options,omitempty"`
 	Env             []EnvVarWithNodeFilters `mapstructure:"env" yaml:"env" json:"env,omitempty"`
 	Registries      SimpleConfigRegistries  `mapstructure:"registries" yaml:"registries,omitempty" json:"registries,omitempty"`
+	Hooks           []SimpleConfigHook      `mapstructure:"hooks" yaml:"hooks,omitempty" json:"hooks,omitempty"`
 }
 
 type SimpleConfigIntermediateV1alpha2 struct {
@@ -178,6 +261,7 @@ type SimpleExposureOpts struct {
 	Host     string `mapstructure:"host" yaml:"host,omitempty" json:"host,omitempty"`
 	HostIP   string `mapstructure:"hostIP" yaml:"hostIP,omitempty" json:"hostIP,omitempty"`
 	HostPort string `mapstructure:"hostPort" yaml:"hostPort,omitempty" json:"hostPort,omitempty"`
+	Tunnel   bool   `mapstructure:"tunnel" yaml:"tunnel,omitempty" json:"tunnel,omitempty"` // SSH port-forward to the remote docker host (DOCKER_HOST=ssh://...)
 }
 
 // GetKind implements Config.GetKind
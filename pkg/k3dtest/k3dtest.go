@@ -0,0 +1,266 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+// Package k3dtest provides k3d clusters for Go integration tests.
+//
+// A test requests a cluster with a single call and gets it deleted automatically when the test (and all its subtests) finished:
+//
+//	func TestMyController(t *testing.T) {
+//		cluster := k3dtest.New(t, k3dtest.WithAgents(1))
+//		// use cluster.RESTConfig or cluster.KubeconfigPath
+//	}
+//
+// To share a cluster between multiple tests (e.g. in TestMain), use Start and Cluster.Stop instead.
+package k3dtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rancher/k3d/v5/pkg/client"
+	"github.com/rancher/k3d/v5/pkg/config"
+	configtypes "github.com/rancher/k3d/v5/pkg/config/types"
+	conf "github.com/rancher/k3d/v5/pkg/config/v1alpha3"
+	"github.com/rancher/k3d/v5/pkg/runtimes"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"github.com/rancher/k3d/v5/pkg/util"
+	"github.com/rancher/k3d/v5/version"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// DefaultNamePrefix is prepended to the random names of test clusters
+const DefaultNamePrefix = "k3dtest"
+
+// DefaultTimeout is the time a test cluster may take to come up
+const DefaultTimeout = 5 * time.Minute
+
+// Cluster is a running k3d cluster created for a test
+type Cluster struct {
+	Name           string
+	Kubeconfig     *clientcmdapi.Config
+	KubeconfigPath string // temporary file containing Kubeconfig, e.g. to pass it to external tools via $KUBECONFIG
+	RESTConfig     *rest.Config
+
+	runtime runtimes.Runtime
+	cluster *k3d.Cluster
+	tempDir string
+}
+
+// Option modifies the configuration of a test cluster
+type Option func(*conf.SimpleConfig)
+
+// WithName sets a fixed cluster name instead of a random one
+func WithName(name string) Option {
+	return func(cfg *conf.SimpleConfig) {
+		cfg.Name = name
+	}
+}
+
+// WithServers sets the number of server nodes
+func WithServers(servers int) Option {
+	return func(cfg *conf.SimpleConfig) {
+		cfg.Servers = servers
+	}
+}
+
+// WithAgents sets the number of agent nodes
+func WithAgents(agents int) Option {
+	return func(cfg *conf.SimpleConfig) {
+		cfg.Agents = agents
+	}
+}
+
+// WithImage sets the k3s image used for all nodes (e.g. to test against a specific Kubernetes version)
+func WithImage(image string) Option {
+	return func(cfg *conf.SimpleConfig) {
+		cfg.Image = image
+	}
+}
+
+// WithK3sArgs passes additional arguments to k3s on all server nodes (e.g. '--disable=traefik')
+func WithK3sArgs(args ...string) Option {
+	return func(cfg *conf.SimpleConfig) {
+		for _, arg := range args {
+			cfg.Options.K3sOptions.ExtraArgs = append(cfg.Options.K3sOptions.ExtraArgs, conf.K3sArgWithNodeFilters{
+				Arg:         arg,
+				NodeFilters: []string{"server:*"},
+			})
+		}
+	}
+}
+
+// WithConfig gives full access to the cluster configuration for everything not covered by the other options
+func WithConfig(modify func(cfg *conf.SimpleConfig)) Option {
+	return modify
+}
+
+// New creates a cluster for the given test and registers its deletion as a cleanup function of the test
+// It fails the test right away, if the cluster couldn't be created
+func New(t testing.TB, opts ...Option) *Cluster {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+
+	cluster, err := Start(ctx, opts...)
+	if err != nil {
+		t.Fatalf("failed to create k3d cluster: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := cluster.Stop(context.Background()); err != nil {
+			t.Errorf("failed to delete k3d cluster '%s': %v", cluster.Name, err)
+		}
+	})
+
+	return cluster
+}
+
+// Start creates a cluster and waits for it to be up
+// The caller is responsible for deleting it again using Cluster.Stop
+func Start(ctx context.Context, opts ...Option) (*Cluster, error) {
+	simpleCfg, err := newSimpleConfig(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	clusterConfig, err := config.TransformSimpleToClusterConfig(ctx, runtimes.SelectedRuntime, simpleCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transform config: %w", err)
+	}
+	clusterConfig, err = config.ProcessClusterConfig(*clusterConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process config: %w", err)
+	}
+	if err := config.ValidateClusterConfig(ctx, runtimes.SelectedRuntime, *clusterConfig); err != nil {
+		return nil, fmt.Errorf("failed cluster configuration validation: %w", err)
+	}
+
+	c := &Cluster{
+		Name:    clusterConfig.Cluster.Name,
+		runtime: runtimes.SelectedRuntime,
+		cluster: &clusterConfig.Cluster,
+	}
+
+	if err := client.ClusterRun(ctx, c.runtime, clusterConfig); err != nil {
+		if errors.Is(err, client.ErrClusterAlreadyExists) { // e.g. created by a parallel test, so it's not ours to delete
+			return nil, fmt.Errorf("failed to create cluster: %w", err)
+		}
+		// ClusterRun rolls back on failure, but we make sure that nothing is left behind
+		if deleteErr := c.Stop(context.Background()); deleteErr != nil {
+			return nil, fmt.Errorf("failed to create cluster (%v) and failed to clean up: %w", err, deleteErr)
+		}
+		return nil, fmt.Errorf("failed to create cluster: %w", err)
+	}
+
+	if err := c.loadKubeconfig(ctx); err != nil {
+		if deleteErr := c.Stop(context.Background()); deleteErr != nil {
+			return nil, fmt.Errorf("%v (and failed to clean up: %w)", err, deleteErr)
+		}
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Stop deletes the cluster and the temporary kubeconfig
+func (c *Cluster) Stop(ctx context.Context) error {
+	if c.tempDir != "" {
+		if err := os.RemoveAll(c.tempDir); err != nil {
+			return fmt.Errorf("failed to remove temporary kubeconfig: %w", err)
+		}
+		c.tempDir = ""
+	}
+	if err := client.ClusterDelete(ctx, c.runtime, c.cluster, k3d.ClusterDeleteOpts{SkipRegistryCheck: true}); err != nil {
+		return fmt.Errorf("failed to delete cluster '%s': %w", c.Name, err)
+	}
+	return nil
+}
+
+// loadKubeconfig fetches the admin kubeconfig of the cluster and writes it to a temporary file
+func (c *Cluster) loadKubeconfig(ctx context.Context) error {
+	kubeconfig, err := client.KubeconfigGet(ctx, c.runtime, c.cluster)
+	if err != nil {
+		return fmt.Errorf("failed to get kubeconfig: %w", err)
+	}
+	restConfig, err := clientcmd.NewDefaultClientConfig(*kubeconfig, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return fmt.Errorf("failed to create REST config from kubeconfig: %w", err)
+	}
+
+	tempDir, err := ioutil.TempDir("", fmt.Sprintf("%s-%s-", DefaultNamePrefix, c.Name))
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory for kubeconfig: %w", err)
+	}
+	c.tempDir = tempDir
+	kubeconfigPath := filepath.Join(tempDir, "kubeconfig.yaml")
+	if err := clientcmd.WriteToFile(*kubeconfig, kubeconfigPath); err != nil {
+		return fmt.Errorf("failed to write kubeconfig: %w", err)
+	}
+
+	c.Kubeconfig = kubeconfig
+	c.KubeconfigPath = kubeconfigPath
+	c.RESTConfig = restConfig
+	return nil
+}
+
+// newSimpleConfig creates the configuration of a test cluster: defaults like `k3d cluster create`, but with a random name and without touching the default kubeconfig
+func newSimpleConfig(opts ...Option) (conf.SimpleConfig, error) {
+	cfg := conf.SimpleConfig{
+		TypeMeta: configtypes.TypeMeta{
+			APIVersion: config.DefaultConfigApiVersion,
+			Kind:       "Simple",
+		},
+		Name:    fmt.Sprintf("%s-%s", DefaultNamePrefix, strings.ToLower(util.GenerateRandomString(6))),
+		Servers: 1,
+		Image:   fmt.Sprintf("%s:%s", k3d.DefaultK3sImageRepo, version.GetK3sVersion(false)),
+	}
+	cfg.Options.K3dOptions.Wait = true
+	cfg.Options.K3dOptions.Timeout = DefaultTimeout
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	// a test must never mess with the user's kubeconfig
+	cfg.Options.KubeconfigOptions.UpdateDefaultKubeconfig = false
+	cfg.Options.KubeconfigOptions.SwitchCurrentContext = false
+
+	if cfg.ExposeAPI.HostPort == "" {
+		port, err := util.GetFreePort()
+		if err != nil || port == 0 {
+			return cfg, fmt.Errorf("failed to get a free port for the Kubernetes API: %w", err)
+		}
+		cfg.ExposeAPI.HostPort = strconv.Itoa(port)
+	}
+
+	return cfg, nil
+}
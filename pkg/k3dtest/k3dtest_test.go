@@ -0,0 +1,62 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package k3dtest
+
+import (
+	"strings"
+	"testing"
+
+	conf "github.com/rancher/k3d/v5/pkg/config/v1alpha3"
+)
+
+func TestNewSimpleConfig(t *testing.T) {
+	cfg, err := newSimpleConfig(WithAgents(2), WithK3sArgs("--disable=traefik"), WithConfig(func(cfg *conf.SimpleConfig) {
+		cfg.Options.KubeconfigOptions.UpdateDefaultKubeconfig = true
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.HasPrefix(cfg.Name, DefaultNamePrefix+"-") {
+		t.Errorf("expected a random name with prefix '%s', got '%s'", DefaultNamePrefix, cfg.Name)
+	}
+	if cfg.Servers != 1 || cfg.Agents != 2 {
+		t.Errorf("expected 1 server and 2 agents, got %d servers and %d agents", cfg.Servers, cfg.Agents)
+	}
+	if len(cfg.Options.K3sOptions.ExtraArgs) != 1 || cfg.Options.K3sOptions.ExtraArgs[0].Arg != "--disable=traefik" {
+		t.Errorf("expected the k3s arg to be set, got %+v", cfg.Options.K3sOptions.ExtraArgs)
+	}
+	if cfg.Options.KubeconfigOptions.UpdateDefaultKubeconfig || cfg.Options.KubeconfigOptions.SwitchCurrentContext {
+		t.Errorf("expected the default kubeconfig to be left alone")
+	}
+	if cfg.ExposeAPI.HostPort == "" {
+		t.Errorf("expected a free port for the Kubernetes API")
+	}
+
+	other, err := newSimpleConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if other.Name == cfg.Name {
+		t.Errorf("expected different names for different clusters, got '%s' twice", cfg.Name)
+	}
+}
@@ -31,6 +31,7 @@ var Logger *logrus.Logger
 
 func init() {
 	Logger = logrus.New()
+	Logger.AddHook(Warnings)
 }
 
 // Log is used to return the default Logger.
@@ -0,0 +1,71 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package logger
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SecretMask replaces secrets in the log output
+const SecretMask = "***"
+
+var (
+	secretsMu sync.RWMutex
+	secrets   [][]byte
+)
+
+// MaskSecret registers a secret (e.g. the cluster token), which the MaskingFormatter replaces in all following log entries
+func MaskSecret(secret string) {
+	if secret == "" {
+		return
+	}
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for _, s := range secrets {
+		if string(s) == secret {
+			return
+		}
+	}
+	secrets = append(secrets, []byte(secret))
+}
+
+// MaskingFormatter wraps a formatter and replaces all secrets registered via MaskSecret in its output
+type MaskingFormatter struct {
+	logrus.Formatter
+}
+
+// Format formats the entry using the wrapped formatter and masks the secrets
+func (f *MaskingFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	out, err := f.Formatter.Format(entry)
+	if err != nil {
+		return out, err
+	}
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+	for _, s := range secrets {
+		out = bytes.ReplaceAll(out, s, []byte(SecretMask))
+	}
+	return out, nil
+}
@@ -0,0 +1,79 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package logger
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Warning is a WARNING-level condition logged during an operation
+type Warning struct {
+	Time    time.Time     `yaml:"time" json:"time"`
+	Message string        `yaml:"message" json:"message"`
+	Fields  logrus.Fields `yaml:"fields,omitempty" json:"fields,omitempty"`
+}
+
+// WarningsRecorder is a logrus hook collecting all warnings logged, e.g. to fail an operation that completed with degraded configuration
+type WarningsRecorder struct {
+	mu       sync.Mutex
+	warnings []Warning
+}
+
+// Warnings collects all warnings logged via the default Logger (as long as the log level includes warnings)
+var Warnings = &WarningsRecorder{}
+
+// Levels implements logrus.Hook
+func (r *WarningsRecorder) Levels() []logrus.Level {
+	return []logrus.Level{logrus.WarnLevel}
+}
+
+// Fire implements logrus.Hook
+func (r *WarningsRecorder) Fire(entry *logrus.Entry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var fields logrus.Fields
+	if len(entry.Data) > 0 {
+		fields = make(logrus.Fields, len(entry.Data))
+		for k, v := range entry.Data {
+			fields[k] = v
+		}
+	}
+	r.warnings = append(r.warnings, Warning{Time: entry.Time, Message: entry.Message, Fields: fields})
+	return nil
+}
+
+// List returns the warnings recorded so far in the order they were logged
+func (r *WarningsRecorder) List() []Warning {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Warning{}, r.warnings...)
+}
+
+// Reset drops the warnings recorded so far
+func (r *WarningsRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.warnings = nil
+}
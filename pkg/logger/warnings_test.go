@@ -0,0 +1,57 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package logger
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestWarningsRecorder(t *testing.T) {
+	recorder := &WarningsRecorder{}
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	logger.AddHook(recorder)
+
+	logger.Infoln("not recorded")
+	logger.Warnf("Using docker-machine, but failed to get it's IP: %s", "exit status 1")
+	logger.WithField("node", "k3d-test-server-0").Warnln("missing TLS SAN")
+	logger.Errorln("no warning")
+
+	warnings := recorder.List()
+	if len(warnings) != 2 {
+		t.Fatalf("expected 2 warnings, got %+v", warnings)
+	}
+	if warnings[0].Message != "Using docker-machine, but failed to get it's IP: exit status 1" || warnings[0].Fields != nil {
+		t.Errorf("unexpected warning %+v", warnings[0])
+	}
+	if warnings[1].Fields["node"] != "k3d-test-server-0" {
+		t.Errorf("expected the fields of the warning to be recorded, got %+v", warnings[1])
+	}
+
+	recorder.Reset()
+	if warnings := recorder.List(); len(warnings) != 0 {
+		t.Errorf("expected no warnings after the reset, got %+v", warnings)
+	}
+}
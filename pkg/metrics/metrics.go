@@ -0,0 +1,163 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// Namespace is the prefix of all metrics exposed by k3d
+const Namespace = "k3d"
+
+// Registry holds all k3d metrics
+// It's separate from the prometheus default registry, so programs embedding k3d can decide if and where to expose them
+var Registry = prometheus.NewRegistry()
+
+var (
+	// ClustersCreated counts cluster creations by result (success/failure)
+	ClustersCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "clusters_created_total",
+		Help:      "Number of cluster creations by result.",
+	}, []string{"result"})
+
+	// ClustersDeleted counts cluster deletions by result (success/failure)
+	ClustersDeleted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "clusters_deleted_total",
+		Help:      "Number of cluster deletions by result.",
+	}, []string{"result"})
+
+	// NodeStarts counts node starts by role
+	NodeStarts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "node_starts_total",
+		Help:      "Number of node starts by node role.",
+	}, []string{"role"})
+
+	// NodeRestarts counts starts of nodes that have been running before by role
+	NodeRestarts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "node_restarts_total",
+		Help:      "Number of (re-)starts of nodes that have been running before by node role.",
+	}, []string{"role"})
+
+	// ClusterBootDuration observes the time it took to create and start a cluster
+	ClusterBootDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "cluster_boot_duration_seconds",
+		Help:      "Time it took to create and start a cluster.",
+		Buckets:   []float64{5, 10, 15, 20, 30, 45, 60, 90, 120, 180, 300},
+	})
+
+	// StageDuration observes the durations of the single stages of cluster operations
+	StageDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "stage_duration_seconds",
+		Help:      "Time it took to run a single stage of a cluster operation.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 12),
+	}, []string{"operation", "stage"})
+)
+
+func init() {
+	Registry.MustRegister(
+		ClustersCreated,
+		ClustersDeleted,
+		NodeStarts,
+		NodeRestarts,
+		ClusterBootDuration,
+		StageDuration,
+	)
+}
+
+// Result returns the value of the result label for the given error
+func Result(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
+
+// ObserveStages adds the recorded stage timings of an operation to the StageDuration histogram
+func ObserveStages(operation string, timings *k3d.OperationTimings) {
+	if timings == nil {
+		return
+	}
+	for _, stage := range timings.Stages {
+		StageDuration.WithLabelValues(operation, stage.Stage).Observe(stage.Duration.Seconds())
+	}
+}
+
+// Handler returns an http.Handler serving the k3d metrics in the prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// WriteTextfile writes the current k3d metrics in the prometheus text format to the given file (e.g. for the node_exporter textfile collector)
+// The file is replaced atomically, so that collectors never read a partially written file.
+func WriteTextfile(path string) error {
+	if err := prometheus.WriteToTextfile(path, Registry); err != nil {
+		return fmt.Errorf("failed to write metrics to '%s': %w", path, err)
+	}
+	return nil
+}
+
+// Serve exposes the k3d metrics on /metrics at the given address until the context is cancelled
+// The listener only lives as long as the k3d process, so it's only useful for long-running commands (e.g. 'k3d serve').
+func Serve(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on '%s': %w", addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	server := &http.Server{Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			l.Log().Warnf("Failed to shut down metrics listener: %v", err)
+		}
+	}()
+
+	l.Log().Infof("Serving metrics on http://%s/metrics", listener.Addr())
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			l.Log().Errorf("Metrics listener failed: %v", err)
+		}
+	}()
+
+	return nil
+}
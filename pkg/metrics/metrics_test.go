@@ -0,0 +1,106 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package metrics
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestResult(t *testing.T) {
+	if result := Result(nil); result != "success" {
+		t.Errorf("expected 'success' for nil error, got '%s'", result)
+	}
+	if result := Result(errors.New("boom")); result != "failure" {
+		t.Errorf("expected 'failure' for error, got '%s'", result)
+	}
+}
+
+func TestObserveStages(t *testing.T) {
+	timings := k3d.NewOperationTimings()
+	timings.Record("test stage a", time.Second)
+	timings.Record("test stage b", 2*time.Second)
+	ObserveStages("test", timings)
+	ObserveStages("test", nil)
+
+	families, err := Registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	observed := 0
+	for _, family := range families {
+		if family.GetName() != "k3d_stage_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "operation" && label.GetValue() == "test" {
+					observed++
+				}
+			}
+		}
+	}
+	if observed != 2 {
+		t.Errorf("expected 2 stage series for operation 'test', got %d", observed)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	ClustersCreated.WithLabelValues("success").Inc()
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `k3d_clusters_created_total{result="success"}`) {
+		t.Errorf("expected the clusters created counter in the output, got:\n%s", rec.Body.String())
+	}
+}
+
+func TestWriteTextfile(t *testing.T) {
+	ClustersDeleted.WithLabelValues("failure").Inc()
+
+	path := filepath.Join(t.TempDir(), "k3d.prom")
+	if err := WriteTextfile(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), `k3d_clusters_deleted_total{result="failure"}`) {
+		t.Errorf("expected the clusters deleted counter in the textfile, got:\n%s", content)
+	}
+
+	if err := WriteTextfile(filepath.Join(t.TempDir(), "missing", "k3d.prom")); err == nil {
+		t.Errorf("expected an error for a non-existent directory")
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	goruntime "runtime"
+	"time"
+
+	"github.com/docker/docker/client"
+)
+
+// ClientOptions are connection settings for the docker client, overriding the ones of the docker context and environment
+type ClientOptions struct {
+	APIVersion string        // pin the API version instead of negotiating it with the daemon (e.g. for old daemons)
+	TLSCACert  string        // path of the CA certificate used to verify the daemon
+	TLSCert    string        // path of the client certificate (requires TLSKey)
+	TLSKey     string        // path of the client certificate's key (requires TLSCert)
+	Timeout    time.Duration // maximum time to wait for the daemon to answer a request (streamed responses like logs aren't limited)
+	Offline    bool          // never let the daemon contact a registry: missing images aren't pulled and digests are only taken from local images
+	// maximum number of container operations (create, start, stop, remove) sent to the daemon at the same time (0: DefaultMaxConcurrency),
+	// as too many parallel operations can overwhelm e.g. the VM of Docker Desktop
+	MaxConcurrency int
+}
+
+var clientOptions ClientOptions
+
+// MinimumAPIVersion is the oldest docker API version k3d works with (spoken by every Docker Engine since 20.10.0, which brought cgroup v2 support and the runc the k3s nodes need)
+const MinimumAPIVersion = "1.41"
+
+// pinnedAPIVersion returns the API version the docker client uses instead of negotiating it with the daemon (--docker-api-version or $DOCKER_API_VERSION), if any
+func pinnedAPIVersion() string {
+	dockerClientMu.Lock()
+	defer dockerClientMu.Unlock()
+	if clientOptions.APIVersion != "" {
+		return clientOptions.APIVersion
+	}
+	return os.Getenv("DOCKER_API_VERSION")
+}
+
+// DefaultMaxConcurrency returns the default for ClientOptions.MaxConcurrency: the number of CPUs
+func DefaultMaxConcurrency() int {
+	return goruntime.NumCPU()
+}
+
+// operationSlots bounds the number of container operations running at the same time (see ClientOptions.MaxConcurrency)
+var operationSlots = make(chan struct{}, DefaultMaxConcurrency())
+
+// acquireOperationSlot waits until less than ClientOptions.MaxConcurrency container operations are running (or the context is done)
+// and returns the function releasing the slot again
+func acquireOperationSlot(ctx context.Context) (func(), error) {
+	dockerClientMu.Lock()
+	slots := operationSlots
+	dockerClientMu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("failed to wait for running docker operations: %w", ctx.Err())
+	}
+}
+
+// SetClientOptions sets the connection settings of the shared docker client (see GetDockerClient), replacing an already created one
+func SetClientOptions(opts ClientOptions) error {
+	if (opts.TLSCert == "") != (opts.TLSKey == "") {
+		return fmt.Errorf("the TLS client certificate and key must be set together")
+	}
+	if opts.Timeout < 0 {
+		return fmt.Errorf("the docker client timeout must not be negative, got '%s'", opts.Timeout)
+	}
+	if opts.MaxConcurrency < 0 {
+		return fmt.Errorf("the maximum number of concurrent docker operations must not be negative, got '%d'", opts.MaxConcurrency)
+	}
+	maxConcurrency := opts.MaxConcurrency
+	if maxConcurrency == 0 {
+		maxConcurrency = DefaultMaxConcurrency()
+	}
+	dockerClientMu.Lock()
+	defer dockerClientMu.Unlock()
+	clientOptions = opts
+	if cap(operationSlots) != maxConcurrency {
+		operationSlots = make(chan struct{}, maxConcurrency) // operations already running release their slots in the old channel
+	}
+	dockerClient = nil
+	return nil
+}
+
+// apply creates a docker client with the given options, overridden by the configured connection settings
+func (o ClientOptions) apply(opts ...client.Opt) (*client.Client, error) {
+	if o.APIVersion != "" {
+		opts = append(opts, client.WithVersion(o.APIVersion))
+	}
+	if o.TLSCACert != "" || o.TLSCert != "" {
+		opts = append(opts, client.WithTLSClientConfig(o.TLSCACert, o.TLSCert, o.TLSKey))
+	}
+
+	docker, err := client.NewClientWithOpts(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.Timeout > 0 {
+		// client.WithTimeout would limit the whole request incl. reading the response, which breaks following logs or pulling images
+		transport, ok := docker.HTTPClient().Transport.(*http.Transport)
+		if !ok {
+			return nil, fmt.Errorf("cannot apply the timeout to the docker client transport %T", docker.HTTPClient().Transport)
+		}
+		transport.TLSHandshakeTimeout = o.Timeout
+		transport.ResponseHeaderTimeout = o.Timeout
+	}
+
+	return docker, nil
+}
@@ -0,0 +1,119 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package docker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/client"
+)
+
+func TestSetClientOptions(t *testing.T) {
+	defer func() {
+		_ = SetClientOptions(ClientOptions{})
+	}()
+
+	if err := SetClientOptions(ClientOptions{TLSCert: "cert.pem"}); err == nil {
+		t.Errorf("expected an error for a client certificate without key")
+	}
+	if err := SetClientOptions(ClientOptions{Timeout: -time.Second}); err == nil {
+		t.Errorf("expected an error for a negative timeout")
+	}
+
+	if err := SetClientOptions(ClientOptions{APIVersion: "1.38", Timeout: 5 * time.Second}); err != nil {
+		t.Fatal(err)
+	}
+	docker, err := clientOptions.apply(client.WithHost("unix:///var/run/docker.sock"), client.WithAPIVersionNegotiation())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if docker.ClientVersion() != "1.38" {
+		t.Errorf("expected the API version to be pinned to 1.38, got %s", docker.ClientVersion())
+	}
+	transport := docker.HTTPClient().Transport.(*http.Transport)
+	if transport.ResponseHeaderTimeout != 5*time.Second {
+		t.Errorf("expected a response header timeout of 5s, got %s", transport.ResponseHeaderTimeout)
+	}
+	if docker.HTTPClient().Timeout != 0 {
+		t.Errorf("expected no overall request timeout, which would break streamed responses, got %s", docker.HTTPClient().Timeout)
+	}
+
+	// the shared client is replaced with one using the new settings
+	shared, err := GetDockerClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetClientOptions(ClientOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if replaced, _ := GetDockerClient(); replaced == shared {
+		t.Errorf("expected the shared client to be replaced after changing the connection settings")
+	}
+}
+
+func TestAcquireOperationSlot(t *testing.T) {
+	defer func() {
+		_ = SetClientOptions(ClientOptions{})
+	}()
+
+	if err := SetClientOptions(ClientOptions{MaxConcurrency: -1}); err == nil {
+		t.Errorf("expected an error for a negative maximum concurrency")
+	}
+	if err := SetClientOptions(ClientOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if cap(operationSlots) != DefaultMaxConcurrency() {
+		t.Errorf("expected %d operation slots by default, got %d", DefaultMaxConcurrency(), cap(operationSlots))
+	}
+
+	if err := SetClientOptions(ClientOptions{MaxConcurrency: 2}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	releaseFirst, err := acquireOperationSlot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	releaseSecond, err := acquireOperationSlot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// all slots are taken, so the next operation has to wait
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := acquireOperationSlot(waitCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected to wait for a free slot until the context is done, got %v", err)
+	}
+
+	releaseFirst()
+	releaseThird, err := acquireOperationSlot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	releaseSecond()
+	releaseThird()
+}
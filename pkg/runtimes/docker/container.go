@@ -29,10 +29,12 @@ import (
 	"io/ioutil"
 	"os"
 
+	"github.com/containerd/containerd/platforms"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/client"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
 	l "github.com/rancher/k3d/v5/pkg/logger"
 	k3d "github.com/rancher/k3d/v5/pkg/types"
 	"github.com/sirupsen/logrus"
@@ -48,15 +50,24 @@ func createContainer(ctx context.Context, dockerNode *NodeInDocker, name string)
 	if err != nil {
 		return "", fmt.Errorf("failed to create docker client: %w", err)
 	}
-	defer docker.Close()
 
-	// create container
+	platform, err := defaultPlatform()
+	if err != nil {
+		return "", err
+	}
+
+	// create container (the operation slot is only held for the create call itself, not while pulling a missing image)
 	var resp container.ContainerCreateCreatedBody
 	for {
-		resp, err = docker.ContainerCreate(ctx, &dockerNode.ContainerConfig, &dockerNode.HostConfig, &dockerNode.NetworkingConfig, nil, name)
+		release, err := acquireOperationSlot(ctx)
+		if err != nil {
+			return "", err
+		}
+		resp, err = docker.ContainerCreate(ctx, &dockerNode.ContainerConfig, &dockerNode.HostConfig, &dockerNode.NetworkingConfig, platform, name)
+		release()
 		if err != nil {
 			if client.IsErrNotFound(err) {
-				if err := pullImage(ctx, docker, dockerNode.ContainerConfig.Image); err != nil {
+				if err := pullImage(ctx, docker, dockerNode.ContainerConfig.Image, platform); err != nil {
 					return "", fmt.Errorf("docker failed to pull image '%s': %w", dockerNode.ContainerConfig.Image, err)
 				}
 				continue
@@ -76,7 +87,12 @@ func startContainer(ctx context.Context, ID string) error {
 	if err != nil {
 		return fmt.Errorf("failed to get docker client: %w", err)
 	}
-	defer docker.Close()
+
+	release, err := acquireOperationSlot(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
 
 	return docker.ContainerStart(ctx, ID, types.ContainerStartOptions{})
 }
@@ -89,7 +105,6 @@ func removeContainer(ctx context.Context, ID string) error {
 	if err != nil {
 		return fmt.Errorf("failed to get docker client: %w", err)
 	}
-	defer docker.Close()
 
 	// (1) define remove options
 	options := types.ContainerRemoveOptions{
@@ -98,6 +113,11 @@ func removeContainer(ctx context.Context, ID string) error {
 	}
 
 	// (2) remove container
+	release, err := acquireOperationSlot(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
 	if err := docker.ContainerRemove(ctx, ID, options); err != nil {
 		return fmt.Errorf("docker failed to remove the container '%s': %w", ID, err)
 	}
@@ -107,16 +127,28 @@ func removeContainer(ctx context.Context, ID string) error {
 	return nil
 }
 
-// pullImage pulls a container image and outputs progress if --verbose flag is set
-func pullImage(ctx context.Context, docker *client.Client, image string) error {
+// pullImage pulls a container image (for the given platform, if not nil) and outputs progress if --verbose flag is set
+func pullImage(ctx context.Context, docker *client.Client, image string, platform *specs.Platform) error {
+	if clientOptions.Offline {
+		return fmt.Errorf("image '%s' is not present locally and pulling is disabled in offline mode", image)
+	}
 
-	resp, err := docker.ImagePull(ctx, image, types.ImagePullOptions{})
+	opts := types.ImagePullOptions{}
+	if platform != nil {
+		opts.Platform = platforms.Format(*platform)
+	}
+
+	resp, err := docker.ImagePull(ctx, image, opts)
 	if err != nil {
 		return fmt.Errorf("docker failed to pull the image '%s': %w", image, err)
 	}
 	defer resp.Close()
 
-	l.Log().Infof("Pulling image '%s'", image)
+	if platform != nil {
+		l.Log().Infof("Pulling image '%s' for platform '%s'", image, opts.Platform)
+	} else {
+		l.Log().Infof("Pulling image '%s'", image)
+	}
 
 	// in debug mode (--verbose flag set), output pull progress
 	var writer io.Writer = ioutil.Discard
@@ -139,7 +171,6 @@ func getNodeContainer(ctx context.Context, node *k3d.Node) (*types.Container, er
 	if err != nil {
 		return nil, fmt.Errorf("failed to get docker client: %w", err)
 	}
-	defer docker.Close()
 
 	// (1) list containers which have the default k3d labels attached
 	filters := filters.NewArgs()
@@ -180,7 +211,6 @@ func executeCheckInContainer(ctx context.Context, image string, cmd []string) (i
 	if err != nil {
 		return -1, fmt.Errorf("failed to create docker client: %w", err)
 	}
-	defer docker.Close()
 
 	// create container
 	var resp container.ContainerCreateCreatedBody
@@ -193,7 +223,7 @@ func executeCheckInContainer(ctx context.Context, image string, cmd []string) (i
 		}, nil, nil, nil, "")
 		if err != nil {
 			if client.IsErrNotFound(err) {
-				if err := pullImage(ctx, docker, image); err != nil {
+				if err := pullImage(ctx, docker, image, nil); err != nil {
 					return -1, fmt.Errorf("docker failed to pull image '%s': %w", image, err)
 				}
 				continue
@@ -0,0 +1,169 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/moby/term"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// execOptions configures an exec process started by startExec
+type execOptions struct {
+	tty   bool // allocate a TTY: the output is passed through as is then, otherwise stdout and stderr are multiplexed on the connection
+	stdin bool // attach the stdin of the process to the connection
+}
+
+// execProcess is an exec process running in a node, whose streams are attached to conn
+type execProcess struct {
+	docker *client.Client
+	id     string
+	node   *k3d.Node
+	conn   types.HijackedResponse
+}
+
+// startExec creates an exec process for the command in the node and attaches to it, which starts it. The caller has to close its connection.
+func startExec(ctx context.Context, node *k3d.Node, cmd []string, opts execOptions) (*execProcess, error) {
+	container, err := getNodeContainer(ctx, node)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container for node '%s': %w", node.Name, err)
+	}
+
+	docker, err := GetDockerClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get docker client: %w", err)
+	}
+
+	exec, err := docker.ContainerExecCreate(ctx, container.ID, types.ExecConfig{
+		Privileged:   true,
+		Tty:          opts.tty,
+		AttachStdin:  opts.stdin,
+		AttachStderr: true,
+		AttachStdout: true,
+		Cmd:          cmd,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("docker failed to create exec config for node '%s': %+v", node.Name, err)
+	}
+
+	execConnection, err := docker.ContainerExecAttach(ctx, exec.ID, types.ExecStartCheck{Tty: opts.tty})
+	if err != nil {
+		return nil, fmt.Errorf("docker failed to attach to exec process in node '%s': %w", node.Name, err)
+	}
+
+	return &execProcess{docker: docker, id: exec.ID, node: node, conn: execConnection}, nil
+}
+
+// streamStdin copies the input to the stdin of the exec process and closes it afterwards, so that the command sees EOF
+func (p *execProcess) streamStdin(stdin io.Reader) error {
+	if _, err := io.Copy(p.conn.Conn, stdin); err != nil {
+		return fmt.Errorf("failed to stream input to exec process in node '%s': %w", p.node.Name, err)
+	}
+	if err := p.conn.CloseWrite(); err != nil {
+		return fmt.Errorf("failed to close input of exec process in node '%s': %w", p.node.Name, err)
+	}
+	return nil
+}
+
+// wait waits for the exec process to exit and returns its exit code
+func (p *execProcess) wait(ctx context.Context) (int, error) {
+	for {
+		execInfo, err := p.docker.ContainerExecInspect(ctx, p.id)
+		if err != nil {
+			return -1, fmt.Errorf("docker failed to inspect exec process in node '%s': %w", p.node.Name, err)
+		}
+		if !execInfo.Running {
+			return execInfo.ExitCode, nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// ExecInNodeInteractive runs a command in a node with the given streams attached (like 'docker exec [-i] [-t]') and returns its exit code.
+// With a TTY, a terminal stdin is put into raw mode for the duration of the command and the size of the terminal is passed on.
+func (d Docker) ExecInNodeInteractive(ctx context.Context, node *k3d.Node, cmd []string, tty bool, stdin io.Reader, stdout io.Writer, stderr io.Writer) (int, error) {
+
+	l.Log().Debugf("Executing command '%+v' interactively in node '%s'", cmd, node.Name)
+
+	process, err := startExec(ctx, node, cmd, execOptions{tty: tty, stdin: stdin != nil})
+	if err != nil {
+		return -1, err
+	}
+	defer process.conn.Close()
+
+	if tty {
+		if inFd, isTerminal := term.GetFdInfo(stdin); isTerminal {
+			state, err := term.SetRawTerminal(inFd)
+			if err != nil {
+				return -1, fmt.Errorf("failed to set the terminal to raw mode: %w", err)
+			}
+			defer func() {
+				if err := term.RestoreTerminal(inFd, state); err != nil {
+					l.Log().Warnf("Failed to restore the terminal: %v", err)
+				}
+			}()
+		}
+		if outFd, isTerminal := term.GetFdInfo(stdout); isTerminal {
+			resize := func() {
+				size, err := term.GetWinsize(outFd)
+				if err != nil {
+					l.Log().Debugf("Failed to get the size of the terminal: %v", err)
+					return
+				}
+				if err := process.docker.ContainerExecResize(ctx, process.id, types.ResizeOptions{Height: uint(size.Height), Width: uint(size.Width)}); err != nil {
+					l.Log().Debugf("Failed to resize the TTY of the exec process in node '%s': %v", node.Name, err)
+				}
+			}
+			resize()
+			stopResizing := notifyTerminalResize(resize)
+			defer stopResizing()
+		}
+	}
+
+	if stdin != nil {
+		go func() {
+			// closing the input lets the command see EOF (e.g. for 'k3d node exec -i NODE -- sh < script.sh')
+			if err := process.streamStdin(stdin); err != nil {
+				l.Log().Debugln(err)
+			}
+		}()
+	}
+
+	if tty {
+		_, err = io.Copy(stdout, process.conn.Reader)
+	} else {
+		_, err = stdcopy.StdCopy(stdout, stderr, process.conn.Reader)
+	}
+	if err != nil {
+		return -1, fmt.Errorf("failed to read the output of the exec process in node '%s': %w", node.Name, err)
+	}
+
+	return process.wait(ctx)
+}
@@ -23,9 +23,26 @@ package docker
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 
+	"github.com/containerd/containerd/platforms"
+	"github.com/docker/distribution/reference"
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/pkg/jsonmessage"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+	l "github.com/rancher/k3d/v5/pkg/logger"
+	runtimeTypes "github.com/rancher/k3d/v5/pkg/runtimes/types"
+	"github.com/sirupsen/logrus"
 )
 
 // GetImages returns a list of images present in the runtime
@@ -35,7 +52,6 @@ func (d Docker) GetImages(ctx context.Context) ([]string, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create docker client: %w", err)
 	}
-	defer docker.Close()
 
 	imageSummary, err := docker.ImageList(ctx, types.ImageListOptions{All: true})
 	if err != nil {
@@ -49,3 +65,257 @@ func (d Docker) GetImages(ctx context.Context) ([]string, error) {
 
 	return images, nil
 }
+
+// GetImageStream returns a tarball of the given images (like 'docker save'), which is streamed from the runtime while it's read
+func (d Docker) GetImageStream(ctx context.Context, images []string) (io.ReadCloser, error) {
+	docker, err := GetDockerClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker client: %w", err)
+	}
+
+	reader, err := docker.ImageSave(ctx, images)
+	if err != nil {
+		return nil, fmt.Errorf("docker failed to save images %v: %w", images, err)
+	}
+	return reader, nil
+}
+
+// ImportImageFromArchive creates an image from a filesystem tar archive (like 'docker import'), taking the config (entrypoint, env, volumes, ...)
+// from a base image, so that the new image behaves like it, except for the given changes (Dockerfile instructions)
+func (d Docker) ImportImageFromArchive(ctx context.Context, archive io.Reader, image string, baseImage string, changes []string) error {
+	docker, err := GetDockerClient()
+	if err != nil {
+		return fmt.Errorf("failed to create docker client: %w", err)
+	}
+
+	base, _, err := docker.ImageInspectWithRaw(ctx, baseImage)
+	if err != nil {
+		return fmt.Errorf("docker failed to inspect base image '%s': %w", baseImage, err)
+	}
+
+	response, err := docker.ImageImport(ctx, types.ImageImportSource{Source: archive, SourceName: "-"}, image, types.ImageImportOptions{
+		Changes: append(imageConfigInstructions(base.Config), changes...),
+		Message: fmt.Sprintf("k3d: import based on %s", baseImage),
+	})
+	if err != nil {
+		return fmt.Errorf("docker failed to import image '%s': %w", image, err)
+	}
+	defer response.Close()
+
+	if err := jsonmessage.DisplayJSONMessagesStream(response, ioutil.Discard, 0, false, nil); err != nil {
+		return fmt.Errorf("docker failed to import image '%s': %w", image, err)
+	}
+	return nil
+}
+
+// imageConfigInstructions turns an image config into Dockerfile instructions, which reproduce it for imported images
+func imageConfigInstructions(config *container.Config) []string {
+	if config == nil {
+		return nil
+	}
+	instructions := []string{}
+	for _, env := range config.Env {
+		kv := strings.SplitN(env, "=", 2)
+		if len(kv) == 2 {
+			instructions = append(instructions, fmt.Sprintf("ENV %s=%s", kv[0], strconv.Quote(kv[1])))
+		}
+	}
+	if len(config.Entrypoint) > 0 {
+		entrypoint, _ := json.Marshal([]string(config.Entrypoint))
+		instructions = append(instructions, fmt.Sprintf("ENTRYPOINT %s", entrypoint))
+	}
+	if len(config.Cmd) > 0 {
+		cmd, _ := json.Marshal([]string(config.Cmd))
+		instructions = append(instructions, fmt.Sprintf("CMD %s", cmd))
+	}
+	if len(config.Volumes) > 0 {
+		volumes := make([]string, 0, len(config.Volumes))
+		for volume := range config.Volumes {
+			volumes = append(volumes, volume)
+		}
+		sort.Strings(volumes)
+		volumesJSON, _ := json.Marshal(volumes)
+		instructions = append(instructions, fmt.Sprintf("VOLUME %s", volumesJSON))
+	}
+	if config.WorkingDir != "" {
+		instructions = append(instructions, fmt.Sprintf("WORKDIR %s", config.WorkingDir))
+	}
+	if config.User != "" {
+		instructions = append(instructions, fmt.Sprintf("USER %s", config.User))
+	}
+	ports := make([]string, 0, len(config.ExposedPorts))
+	for port := range config.ExposedPorts {
+		ports = append(ports, string(port))
+	}
+	sort.Strings(ports)
+	for _, port := range ports {
+		instructions = append(instructions, fmt.Sprintf("EXPOSE %s", port))
+	}
+	labels := make([]string, 0, len(config.Labels))
+	for k := range config.Labels {
+		labels = append(labels, k)
+	}
+	sort.Strings(labels)
+	for _, k := range labels {
+		instructions = append(instructions, fmt.Sprintf("LABEL %s=%s", k, strconv.Quote(config.Labels[k])))
+	}
+	return instructions
+}
+
+// ListImages returns a list of images (including IDs and sizes) present in the runtime
+func (d Docker) ListImages(ctx context.Context) ([]runtimeTypes.Image, error) {
+	// create docker client
+	docker, err := GetDockerClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker client: %w", err)
+	}
+
+	imageSummary, err := docker.ImageList(ctx, types.ImageListOptions{All: true})
+	if err != nil {
+		return nil, fmt.Errorf("docker failed to list images: %w", err)
+	}
+
+	images := make([]runtimeTypes.Image, 0, len(imageSummary))
+	for _, image := range imageSummary {
+		images = append(images, runtimeTypes.Image{
+			ID:   image.ID,
+			Tags: image.RepoTags,
+			Size: image.Size,
+		})
+	}
+
+	return images, nil
+}
+
+// DeleteImage removes an image (tag) from the runtime
+func (d Docker) DeleteImage(ctx context.Context, image string) error {
+	// create docker client
+	docker, err := GetDockerClient()
+	if err != nil {
+		return fmt.Errorf("failed to create docker client: %w", err)
+	}
+
+	if _, err := docker.ImageRemove(ctx, image, types.ImageRemoveOptions{PruneChildren: true}); err != nil {
+		return fmt.Errorf("docker failed to remove image '%s': %w", image, err)
+	}
+
+	return nil
+}
+
+// TagImage adds another reference (tag) to an image present in the runtime
+func (d Docker) TagImage(ctx context.Context, source string, target string) error {
+	// create docker client
+	docker, err := GetDockerClient()
+	if err != nil {
+		return fmt.Errorf("failed to create docker client: %w", err)
+	}
+
+	if err := docker.ImageTag(ctx, source, target); err != nil {
+		return fmt.Errorf("docker failed to tag image '%s' as '%s': %w", source, target, err)
+	}
+
+	return nil
+}
+
+// PushImage pushes an image to the registry its reference points to (anonymously)
+func (d Docker) PushImage(ctx context.Context, image string) error {
+	// create docker client
+	docker, err := GetDockerClient()
+	if err != nil {
+		return fmt.Errorf("failed to create docker client: %w", err)
+	}
+
+	// the daemon requires auth, even if it's empty
+	resp, err := docker.ImagePush(ctx, image, types.ImagePushOptions{RegistryAuth: base64.URLEncoding.EncodeToString([]byte("{}"))})
+	if err != nil {
+		return fmt.Errorf("docker failed to push image '%s': %w", image, err)
+	}
+	defer resp.Close()
+
+	// errors during the push are only reported in the progress stream, which we show in debug mode (--verbose flag set)
+	var writer io.Writer = ioutil.Discard
+	if l.Log().GetLevel() >= logrus.DebugLevel {
+		writer = os.Stdout
+	}
+	if err := jsonmessage.DisplayJSONMessagesStream(resp, writer, 0, false, nil); err != nil {
+		return fmt.Errorf("docker failed to push image '%s': %w", image, err)
+	}
+
+	return nil
+}
+
+// PullImage pulls an image for the platform of the nodes, even if it's present already (e.g. to refresh tags like 'latest')
+func (d Docker) PullImage(ctx context.Context, image string) error {
+	// create docker client
+	docker, err := GetDockerClient()
+	if err != nil {
+		return fmt.Errorf("failed to create docker client: %w", err)
+	}
+
+	platform, err := defaultPlatform()
+	if err != nil {
+		return err
+	}
+
+	return pullImage(ctx, docker, image, platform)
+}
+
+// GetImagePlatform returns the platform (os/arch[/variant]) of an image present in the runtime
+func (d Docker) GetImagePlatform(ctx context.Context, image string) (string, error) {
+	// create docker client
+	docker, err := GetDockerClient()
+	if err != nil {
+		return "", fmt.Errorf("failed to create docker client: %w", err)
+	}
+
+	inspect, _, err := docker.ImageInspectWithRaw(ctx, image)
+	if err != nil {
+		return "", fmt.Errorf("docker failed to inspect image '%s': %w", image, err)
+	}
+
+	return platforms.Format(specs.Platform{
+		OS:           inspect.Os,
+		Architecture: inspect.Architecture,
+		Variant:      inspect.Variant,
+	}), nil
+}
+
+// GetImageDigest returns the digest of the manifest (list) an image reference points to.
+// It's looked up in the registry first, so that it matches what a pull would fetch right now.
+// If that fails (e.g. private registry or no network), the digest of the image present in the runtime is used.
+func (d Docker) GetImageDigest(ctx context.Context, image string) (string, error) {
+	// create docker client
+	docker, err := GetDockerClient()
+	if err != nil {
+		return "", fmt.Errorf("failed to create docker client: %w", err)
+	}
+
+	registryErr := fmt.Errorf("offline mode")
+	if !clientOptions.Offline {
+		var distribution registry.DistributionInspect
+		distribution, registryErr = docker.DistributionInspect(ctx, image, "")
+		if registryErr == nil {
+			return distribution.Descriptor.Digest.String(), nil
+		}
+		l.Log().Debugf("Failed to get the digest of image '%s' from the registry, checking the local image: %v", image, registryErr)
+	}
+
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse image reference '%s': %w", image, err)
+	}
+	inspect, _, err := docker.ImageInspectWithRaw(ctx, image)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the digest of image '%s' from the registry (%v) or from docker: %w", image, registryErr, err)
+	}
+	for _, repoDigest := range inspect.RepoDigests {
+		ref, err := reference.ParseNormalizedNamed(repoDigest)
+		if err != nil {
+			continue
+		}
+		if canonical, ok := ref.(reference.Canonical); ok && ref.Name() == named.Name() {
+			return canonical.Digest().String(), nil
+		}
+	}
+	return "", fmt.Errorf("image '%s' has no digest: it was neither pulled from nor pushed to a registry", image)
+}
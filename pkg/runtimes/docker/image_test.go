@@ -0,0 +1,58 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package docker
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+)
+
+func TestImageConfigInstructions(t *testing.T) {
+	config := &container.Config{
+		Env:          []string{"PATH=/bin:/usr/bin", `QUOTED=say "hi"`},
+		Entrypoint:   []string{"/bin/k3s"},
+		Cmd:          []string{"agent"},
+		Volumes:      map[string]struct{}{"/var/lib/kubelet": {}, "/var/lib/cni": {}},
+		ExposedPorts: nat.PortSet{"6443/tcp": {}},
+		Labels:       map[string]string{"org.opencontainers.image.version": "v1.22.2-k3s2"},
+	}
+
+	expected := []string{
+		`ENV PATH="/bin:/usr/bin"`,
+		`ENV QUOTED="say \"hi\""`,
+		`ENTRYPOINT ["/bin/k3s"]`,
+		`CMD ["agent"]`,
+		`VOLUME ["/var/lib/cni","/var/lib/kubelet"]`,
+		`EXPOSE 6443/tcp`,
+		`LABEL org.opencontainers.image.version="v1.22.2-k3s2"`,
+	}
+	if instructions := imageConfigInstructions(config); !reflect.DeepEqual(instructions, expected) {
+		t.Errorf("expected\n%v\ngot\n%v", expected, instructions)
+	}
+
+	if instructions := imageConfigInstructions(nil); len(instructions) != 0 {
+		t.Errorf("expected no instructions without a config, got %v", instructions)
+	}
+}
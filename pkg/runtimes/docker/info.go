@@ -25,8 +25,10 @@ package docker
 import (
 	"context"
 	"fmt"
+	"os"
 	"strings"
 
+	l "github.com/rancher/k3d/v5/pkg/logger"
 	runtimeTypes "github.com/rancher/k3d/v5/pkg/runtimes/types"
 )
 
@@ -36,7 +38,6 @@ func (d Docker) Info() (*runtimeTypes.RuntimeInfo, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create docker client: %w", err)
 	}
-	defer docker.Close()
 
 	info, err := docker.Info(context.Background())
 	if err != nil {
@@ -53,6 +54,27 @@ func (d Docker) Info() (*runtimeTypes.RuntimeInfo, error) {
 		CgroupVersion: info.CgroupVersion,
 		CgroupDriver:  info.CgroupDriver,
 		Filesystem:    "UNKNOWN",
+		DataRoot:      info.DockerRootDir,
+		Experimental:  info.ExperimentalBuild,
+		Platform:      os.Getenv(defaultPlatformEnv),
+	}
+
+	// the API version isn't part of the info output
+	if version, err := docker.ServerVersion(context.Background()); err == nil {
+		runtimeInfo.APIVersion = version.APIVersion
+	} else {
+		l.Log().Debugf("docker failed to provide version output: %v", err)
+	}
+	runtimeInfo.RequiredAPIVersion = MinimumAPIVersion
+	runtimeInfo.PinnedAPIVersion = pinnedAPIVersion()
+
+	// security options are reported like 'name=seccomp,profile=default'
+	for _, opt := range info.SecurityOptions {
+		for _, kv := range strings.Split(opt, ",") {
+			if strings.HasPrefix(kv, "name=") {
+				runtimeInfo.Security = append(runtimeInfo.Security, strings.TrimPrefix(kv, "name="))
+			}
+		}
 	}
 
 	// Get the backing filesystem for the storage driver
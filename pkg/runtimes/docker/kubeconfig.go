@@ -37,7 +37,6 @@ func (d Docker) GetKubeconfig(ctx context.Context, node *k3d.Node) (io.ReadClose
 	if err != nil {
 		return nil, fmt.Errorf("failed to create docker client: %w", err)
 	}
-	defer docker.Close()
 
 	container, err := getNodeContainer(ctx, node)
 	if err != nil {
@@ -30,6 +30,7 @@ import (
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
 	"inet.af/netaddr"
 
 	l "github.com/rancher/k3d/v5/pkg/logger"
@@ -38,6 +39,39 @@ import (
 	"github.com/rancher/k3d/v5/pkg/util"
 )
 
+// GetNetworksByLabel returns all networks that have the given labels (with all containers attached to them as members)
+func (d Docker) GetNetworksByLabel(ctx context.Context, labels map[string]string) ([]*k3d.ClusterNetwork, error) {
+	// (0) create new docker client
+	docker, err := GetDockerClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker client: %w", err)
+	}
+
+	filter := filters.NewArgs()
+	for k, v := range labels {
+		filter.Add("label", fmt.Sprintf("%s=%s", k, v))
+	}
+	networkList, err := docker.NetworkList(ctx, types.NetworkListOptions{Filters: filter})
+	if err != nil {
+		return nil, fmt.Errorf("docker failed to list networks: %w", err)
+	}
+
+	networks := make([]*k3d.ClusterNetwork, 0, len(networkList))
+	for _, net := range networkList {
+		// the list doesn't contain the attached containers
+		inspect, err := docker.NetworkInspect(ctx, net.ID, types.NetworkInspectOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("docker failed to inspect network %s: %w", net.Name, err)
+		}
+		network := &k3d.ClusterNetwork{Name: inspect.Name, ID: inspect.ID, Labels: inspect.Labels}
+		for _, container := range inspect.Containers {
+			network.Members = append(network.Members, &k3d.NetworkMember{Name: container.Name})
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
 // GetNetwork returns a given network
 func (d Docker) GetNetwork(ctx context.Context, searchNet *k3d.ClusterNetwork) (*k3d.ClusterNetwork, error) {
 	// (0) create new docker client
@@ -45,7 +79,6 @@ func (d Docker) GetNetwork(ctx context.Context, searchNet *k3d.ClusterNetwork) (
 	if err != nil {
 		return nil, fmt.Errorf("failed to create docker client: %w", err)
 	}
-	defer docker.Close()
 
 	if searchNet.ID == "" && searchNet.Name == "" {
 		return nil, fmt.Errorf("failed to get network, because neither name nor ID was provided")
@@ -83,8 +116,8 @@ func (d Docker) GetNetwork(ctx context.Context, searchNet *k3d.ClusterNetwork) (
 	}
 
 	// for networks that have an IPAM config, we inspect that as well (e.g. "host" network doesn't have it)
-	if len(targetNetwork.IPAM.Config) > 0 {
-		network.IPAM, err = d.parseIPAM(targetNetwork.IPAM.Config[0])
+	if ipamConfig, ok := ipv4IPAMConfig(targetNetwork.IPAM.Config); ok {
+		network.IPAM, err = d.parseIPAM(ipamConfig)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse IPAM config: %w", err)
 		}
@@ -138,7 +171,6 @@ func (d Docker) CreateNetworkIfNotPresent(ctx context.Context, inNet *k3d.Cluste
 	if err != nil {
 		return nil, false, fmt.Errorf("failed to create docker client: %w", err)
 	}
-	defer docker.Close()
 
 	existingNet, err := d.GetNetwork(ctx, inNet)
 	if err != nil {
@@ -183,17 +215,30 @@ func (d Docker) CreateNetworkIfNotPresent(ctx context.Context, inNet *k3d.Cluste
 	}
 
 	// use user-defined subnet, if given
+	ipamConfigs := []network.IPAMConfig{}
 	if !inNet.IPAM.IPPrefix.IsZero() {
-		netCreateOpts.IPAM = &network.IPAM{
-			Config: []network.IPAMConfig{
-				{
-					Subnet:  inNet.IPAM.IPPrefix.String(),
-					Gateway: inNet.IPAM.IPPrefix.Range().From().Next().String(), // second IP in subnet will be the Gateway (Next, so we don't hit x.x.x.0)
-				},
-			},
+		gateway := inNet.IPAM.Gateway
+		if gateway.IsZero() {
+			gateway = inNet.IPAM.IPPrefix.Range().From().Next() // second IP in subnet will be the Gateway (Next, so we don't hit x.x.x.0)
+		}
+		ipamConfigs = append(ipamConfigs, network.IPAMConfig{
+			Subnet:  inNet.IPAM.IPPrefix.String(),
+			Gateway: gateway.String(),
+		})
+	}
+
+	// dual-stack network, optionally with a user-defined IPv6 subnet
+	if inNet.IPv6.Enabled {
+		netCreateOpts.EnableIPv6 = true
+		if !inNet.IPv6.IPPrefix.IsZero() {
+			ipamConfigs = append(ipamConfigs, network.IPAMConfig{Subnet: inNet.IPv6.IPPrefix.String()})
 		}
 	}
 
+	if len(ipamConfigs) > 0 {
+		netCreateOpts.IPAM = &network.IPAM{Config: ipamConfigs}
+	}
+
 	newNet, err := docker.NetworkCreate(ctx, inNet.Name, netCreateOpts)
 	if err != nil {
 		return nil, false, fmt.Errorf("docker failed to create new network '%s': %w", inNet.Name, err)
@@ -205,12 +250,16 @@ func (d Docker) CreateNetworkIfNotPresent(ctx context.Context, inNet *k3d.Cluste
 	}
 
 	l.Log().Infof("Created network '%s'", inNet.Name)
-	prefix, err := netaddr.ParseIPPrefix(networkDetails.IPAM.Config[0].Subnet)
+	ipamConfig, ok := ipv4IPAMConfig(networkDetails.IPAM.Config)
+	if !ok {
+		return nil, false, fmt.Errorf("newly created network '%s' has no IPv4 subnet", newNet.ID)
+	}
+	prefix, err := netaddr.ParseIPPrefix(ipamConfig.Subnet)
 	if err != nil {
 		return nil, false, fmt.Errorf("failed to parse IP Prefix of newly created network '%s': %w", newNet.ID, err)
 	}
 
-	newClusterNet := &k3d.ClusterNetwork{Name: inNet.Name, ID: networkDetails.ID, IPAM: k3d.IPAM{IPPrefix: prefix}}
+	newClusterNet := &k3d.ClusterNetwork{Name: inNet.Name, ID: networkDetails.ID, IPAM: k3d.IPAM{IPPrefix: prefix, Gateway: inNet.IPAM.Gateway}, IPv6: inNet.IPv6}
 
 	if !inNet.IPAM.IPPrefix.IsZero() {
 		newClusterNet.IPAM.Managed = true
@@ -226,7 +275,6 @@ func (d Docker) DeleteNetwork(ctx context.Context, ID string) error {
 	if err != nil {
 		return fmt.Errorf("failed to get docker client: %w", err)
 	}
-	defer docker.Close()
 
 	// (3) delete network
 	if err := docker.NetworkRemove(ctx, ID); err != nil {
@@ -244,7 +292,6 @@ func GetNetwork(ctx context.Context, ID string) (types.NetworkResource, error) {
 	if err != nil {
 		return types.NetworkResource{}, fmt.Errorf("failed to get docker client: %w", err)
 	}
-	defer docker.Close()
 	return docker.NetworkInspect(ctx, ID, types.NetworkInspectOptions{})
 }
 
@@ -283,7 +330,6 @@ func (d Docker) ConnectNodeToNetwork(ctx context.Context, node *k3d.Node, networ
 	if err != nil {
 		return fmt.Errorf("failed to get docker client: %w", err)
 	}
-	defer docker.Close()
 
 	// get network
 	networkResource, err := GetNetwork(ctx, networkName)
@@ -309,7 +355,6 @@ func (d Docker) DisconnectNodeFromNetwork(ctx context.Context, node *k3d.Node, n
 	if err != nil {
 		return fmt.Errorf("failed to get docker client: %w", err)
 	}
-	defer docker.Close()
 
 	// get network
 	networkResource, err := GetNetwork(ctx, networkName)
@@ -320,13 +365,64 @@ func (d Docker) DisconnectNodeFromNetwork(ctx context.Context, node *k3d.Node, n
 	return docker.NetworkDisconnect(ctx, networkResource.ID, container.ID, true)
 }
 
+// ConnectContainerToNetwork connects any container (e.g. the one k3d is running in) to a network
+func (d Docker) ConnectContainerToNetwork(ctx context.Context, containerRef string, networkName string) error {
+	docker, err := GetDockerClient()
+	if err != nil {
+		return fmt.Errorf("failed to get docker client: %w", err)
+	}
+
+	container, err := docker.ContainerInspect(ctx, containerRef)
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			return fmt.Errorf("container '%s': %w", containerRef, runtimeErr.ErrRuntimeContainerNotExists)
+		}
+		return fmt.Errorf("failed to inspect container '%s': %w", containerRef, err)
+	}
+
+	if container.NetworkSettings != nil {
+		if _, ok := container.NetworkSettings.Networks[networkName]; ok {
+			l.Log().Infof("Container '%s' is already connected to '%s'", containerRef, networkName)
+			return nil
+		}
+	}
+
+	networkResource, err := GetNetwork(ctx, networkName)
+	if err != nil {
+		return fmt.Errorf("failed to get network '%s': %w", networkName, err)
+	}
+
+	return docker.NetworkConnect(ctx, networkResource.ID, container.ID, &network.EndpointSettings{})
+}
+
+// DisconnectContainerFromNetwork disconnects any container (e.g. the one k3d is running in) from a network
+func (d Docker) DisconnectContainerFromNetwork(ctx context.Context, containerRef string, networkName string) error {
+	l.Log().Debugf("Disconnecting container %s from network %s...", containerRef, networkName)
+	docker, err := GetDockerClient()
+	if err != nil {
+		return fmt.Errorf("failed to get docker client: %w", err)
+	}
+
+	networkResource, err := GetNetwork(ctx, networkName)
+	if err != nil {
+		return fmt.Errorf("failed to get network '%s': %w", networkName, err)
+	}
+
+	if err := docker.NetworkDisconnect(ctx, networkResource.ID, containerRef, true); err != nil {
+		if client.IsErrNotFound(err) {
+			return fmt.Errorf("container '%s': %w", containerRef, runtimeErr.ErrRuntimeContainerNotExists)
+		}
+		return fmt.Errorf("failed to disconnect container '%s' from network '%s': %w", containerRef, networkName, err)
+	}
+	return nil
+}
+
 func (d Docker) getFreeSubnetPrefix(ctx context.Context) (netaddr.IPPrefix, error) {
 	// (0) create new docker client
 	docker, err := GetDockerClient()
 	if err != nil {
 		return netaddr.IPPrefix{}, fmt.Errorf("failed to create docker client %w", err)
 	}
-	defer docker.Close()
 
 	// 1. Create a fake network to get auto-generated subnet prefix
 	fakenetName := fmt.Sprintf("%s-fakenet-%s", k3d.DefaultObjectNamePrefix, util.GenerateRandomString(10))
@@ -350,6 +446,16 @@ func (d Docker) getFreeSubnetPrefix(ctx context.Context) (netaddr.IPPrefix, erro
 
 }
 
+// ipv4IPAMConfig returns the IPv4 subnet of a network's IPAM config, which may also contain an IPv6 subnet (in any order)
+func ipv4IPAMConfig(configs []network.IPAMConfig) (network.IPAMConfig, bool) {
+	for _, config := range configs {
+		if prefix, err := netaddr.ParseIPPrefix(config.Subnet); err == nil && prefix.IP().Is4() {
+			return config, true
+		}
+	}
+	return network.IPAMConfig{}, false
+}
+
 // parseIPAM Returns an IPAM structure with the subnet and gateway filled in. If some of the values
 // cannot be parsed, an error is returned. If gateway is empty, the function calculates the default gateway.
 func (d Docker) parseIPAM(config network.IPAMConfig) (ipam k3d.IPAM, err error) {
@@ -366,6 +472,7 @@ func (d Docker) parseIPAM(config network.IPAMConfig) (ipam k3d.IPAM, err error)
 	} else {
 		gateway, err = netaddr.ParseIP(config.Gateway)
 	}
+	ipam.Gateway = gateway
 	ipam.IPsUsed = append(ipam.IPsUsed, gateway)
 
 	return
@@ -0,0 +1,45 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package docker
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types/network"
+)
+
+func TestIPv4IPAMConfig(t *testing.T) {
+	config, ok := ipv4IPAMConfig([]network.IPAMConfig{
+		{Subnet: "fd00:cafe::/64"},
+		{Subnet: "172.28.0.0/16", Gateway: "172.28.0.1"},
+	})
+	if !ok || config.Subnet != "172.28.0.0/16" {
+		t.Errorf("expected the IPv4 subnet of a dual-stack network, got %+v (found: %t)", config, ok)
+	}
+
+	if _, ok := ipv4IPAMConfig([]network.IPAMConfig{{Subnet: "fd00:cafe::/64"}}); ok {
+		t.Errorf("expected no IPv4 subnet in an IPv6-only config")
+	}
+	if _, ok := ipv4IPAMConfig(nil); ok {
+		t.Errorf("expected no IPv4 subnet in an empty config")
+	}
+}
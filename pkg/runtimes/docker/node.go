@@ -24,18 +24,24 @@ package docker
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 	l "github.com/rancher/k3d/v5/pkg/logger"
 	runtimeErr "github.com/rancher/k3d/v5/pkg/runtimes/errors"
 	k3d "github.com/rancher/k3d/v5/pkg/types"
+	"golang.org/x/sync/errgroup"
 )
 
 // CreateNode creates a new container
@@ -71,32 +77,61 @@ func (d Docker) GetNodesByLabel(ctx context.Context, labels map[string]string) (
 		return nil, fmt.Errorf("docker failed to get containers with labels '%v': %w", labels, err)
 	}
 
-	// (1) convert them to node structs
-	nodes := []*k3d.Node{}
-	for _, container := range containers {
-		var node *k3d.Node
-		var err error
+	// (1) convert them to node structs, inspecting the containers concurrently, as that's one API call per container
+	translated := make([]*k3d.Node, len(containers))
+	inspections, inspectionCtx := errgroup.WithContext(ctx)
+	slots := make(chan struct{}, maxConcurrentInspections)
+	for i := range containers {
+		i, container := i, containers[i]
+		inspections.Go(func() error {
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			node, err := containerToNode(inspectionCtx, &container)
+			translated[i] = node
+			return err
+		})
+	}
+	if err := inspections.Wait(); err != nil {
+		return nil, err
+	}
 
-		containerDetails, err := getContainerDetails(ctx, container.ID)
-		if err != nil {
-			l.Log().Warnf("Failed to get details for container %s", container.Names[0])
-			node, err = TranslateContainerToNode(&container)
-			if err != nil {
-				return nil, fmt.Errorf("failed to translate container '%s' to k3d node spec: %w", container.Names[0], err)
-			}
-		} else {
-			node, err = TranslateContainerDetailsToNode(containerDetails)
-			if err != nil {
-				return nil, fmt.Errorf("failed to translate container'%s' details to k3d node spec: %w", containerDetails.Name, err)
-			}
+	nodes := []*k3d.Node{}
+	for _, node := range translated {
+		if node != nil {
+			nodes = append(nodes, node)
 		}
-		nodes = append(nodes, node)
 	}
 
 	return nodes, nil
 
 }
 
+// maxConcurrentInspections limits the number of container inspections running at the same time when listing nodes
+const maxConcurrentInspections = 16
+
+// containerToNode inspects a listed container and translates it to a node (nil, if the container vanished in the meantime)
+func containerToNode(ctx context.Context, container *types.Container) (*k3d.Node, error) {
+	containerDetails, err := getContainerDetails(ctx, container.ID)
+	if err != nil && client.IsErrNotFound(err) {
+		// the container was removed after we listed it, e.g. by a concurrent 'k3d cluster delete'
+		l.Log().Debugf("Container %s vanished while listing nodes, skipping it", container.Names[0])
+		return nil, nil
+	}
+	if err != nil {
+		l.Log().Warnf("Failed to get details for container %s", container.Names[0])
+		node, err := TranslateContainerToNode(container)
+		if err != nil {
+			return nil, fmt.Errorf("failed to translate container '%s' to k3d node spec: %w", container.Names[0], err)
+		}
+		return node, nil
+	}
+	node, err := TranslateContainerDetailsToNode(containerDetails)
+	if err != nil {
+		return nil, fmt.Errorf("failed to translate container'%s' details to k3d node spec: %w", containerDetails.Name, err)
+	}
+	return node, nil
+}
+
 // StartNode starts an existing node
 func (d Docker) StartNode(ctx context.Context, node *k3d.Node) error {
 	// (0) create docker client
@@ -104,7 +139,6 @@ func (d Docker) StartNode(ctx context.Context, node *k3d.Node) error {
 	if err != nil {
 		return fmt.Errorf("failed to create docker client. %w", err)
 	}
-	defer docker.Close()
 
 	// get container which represents the node
 	nodeContainer, err := getNodeContainer(ctx, node)
@@ -119,7 +153,13 @@ func (d Docker) StartNode(ctx context.Context, node *k3d.Node) error {
 
 	// actually start the container
 	l.Log().Infof("Starting Node '%s'", node.Name)
-	if err := docker.ContainerStart(ctx, nodeContainer.ID, types.ContainerStartOptions{}); err != nil {
+	release, err := acquireOperationSlot(ctx)
+	if err != nil {
+		return err
+	}
+	err = docker.ContainerStart(ctx, nodeContainer.ID, types.ContainerStartOptions{})
+	release()
+	if err != nil {
 		return fmt.Errorf("docker failed to start container for node '%s': %w", node.Name, err)
 	}
 
@@ -136,6 +176,91 @@ func (d Docker) StartNode(ctx context.Context, node *k3d.Node) error {
 	return nil
 }
 
+// FreezeNode checkpoints the processes running inside the node container using CRIU and stops it
+// This requires the docker daemon to run with experimental features enabled
+func (d Docker) FreezeNode(ctx context.Context, node *k3d.Node, checkpointID string) error {
+	docker, err := GetDockerClient()
+	if err != nil {
+		return fmt.Errorf("failed to create docker client. %w", err)
+	}
+
+	nodeContainer, err := getNodeContainer(ctx, node)
+	if err != nil {
+		return fmt.Errorf("failed to get container for node '%s': %w", node.Name, err)
+	}
+
+	l.Log().Infof("Freezing Node '%s'", node.Name)
+	if err := docker.CheckpointCreate(ctx, nodeContainer.ID, types.CheckpointCreateOptions{CheckpointID: checkpointID, Exit: true}); err != nil {
+		return fmt.Errorf("docker failed to checkpoint container for node '%s': %w", node.Name, err)
+	}
+
+	return nil
+}
+
+// ThawNode starts a node container from a checkpoint created by FreezeNode and removes the checkpoint afterwards
+func (d Docker) ThawNode(ctx context.Context, node *k3d.Node, checkpointID string) error {
+	docker, err := GetDockerClient()
+	if err != nil {
+		return fmt.Errorf("failed to create docker client. %w", err)
+	}
+
+	nodeContainer, err := getNodeContainer(ctx, node)
+	if err != nil {
+		return fmt.Errorf("failed to get container for node '%s': %w", node.Name, err)
+	}
+
+	l.Log().Infof("Thawing Node '%s'", node.Name)
+	if err := docker.ContainerStart(ctx, nodeContainer.ID, types.ContainerStartOptions{CheckpointID: checkpointID}); err != nil {
+		return fmt.Errorf("docker failed to restore container for node '%s' from checkpoint '%s': %w", node.Name, checkpointID, err)
+	}
+
+	if err := docker.CheckpointDelete(ctx, nodeContainer.ID, types.CheckpointDeleteOptions{CheckpointID: checkpointID}); err != nil {
+		l.Log().Warnf("Failed to delete checkpoint '%s' of node '%s': %v", checkpointID, node.Name, err)
+	}
+
+	return nil
+}
+
+// PauseNode suspends all processes of a running node container (cgroup freezer), keeping their memory state
+func (d Docker) PauseNode(ctx context.Context, node *k3d.Node) error {
+	docker, err := GetDockerClient()
+	if err != nil {
+		return fmt.Errorf("failed to create docker client. %w", err)
+	}
+
+	nodeContainer, err := getNodeContainer(ctx, node)
+	if err != nil {
+		return fmt.Errorf("failed to get container for node '%s': %w", node.Name, err)
+	}
+
+	l.Log().Infof("Pausing Node '%s'", node.Name)
+	if err := docker.ContainerPause(ctx, nodeContainer.ID); err != nil {
+		return fmt.Errorf("docker failed to pause container for node '%s': %w", node.Name, err)
+	}
+
+	return nil
+}
+
+// UnpauseNode resumes the processes of a node container paused by PauseNode
+func (d Docker) UnpauseNode(ctx context.Context, node *k3d.Node) error {
+	docker, err := GetDockerClient()
+	if err != nil {
+		return fmt.Errorf("failed to create docker client. %w", err)
+	}
+
+	nodeContainer, err := getNodeContainer(ctx, node)
+	if err != nil {
+		return fmt.Errorf("failed to get container for node '%s': %w", node.Name, err)
+	}
+
+	l.Log().Infof("Unpausing Node '%s'", node.Name)
+	if err := docker.ContainerUnpause(ctx, nodeContainer.ID); err != nil {
+		return fmt.Errorf("docker failed to unpause container for node '%s': %w", node.Name, err)
+	}
+
+	return nil
+}
+
 // StopNode stops an existing node
 func (d Docker) StopNode(ctx context.Context, node *k3d.Node) error {
 	// (0) create docker client
@@ -143,7 +268,6 @@ func (d Docker) StopNode(ctx context.Context, node *k3d.Node) error {
 	if err != nil {
 		return fmt.Errorf("Failed to create docker client. %+v", err)
 	}
-	defer docker.Close()
 
 	// get container which represents the node
 	nodeContainer, err := getNodeContainer(ctx, node)
@@ -157,6 +281,11 @@ func (d Docker) StopNode(ctx context.Context, node *k3d.Node) error {
 	}
 
 	// actually stop the container
+	release, err := acquireOperationSlot(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
 	if err := docker.ContainerStop(ctx, nodeContainer.ID, nil); err != nil {
 		return fmt.Errorf("docker failed to stop the container '%s': %w", nodeContainer.ID, err)
 	}
@@ -170,7 +299,6 @@ func getContainersByLabel(ctx context.Context, labels map[string]string) ([]type
 	if err != nil {
 		return nil, fmt.Errorf("Failed to create docker client. %+v", err)
 	}
-	defer docker.Close()
 
 	// (1) list containers which have the default k3d labels attached
 	filters := filters.NewArgs()
@@ -199,7 +327,6 @@ func getContainerDetails(ctx context.Context, containerID string) (types.Contain
 	if err != nil {
 		return types.ContainerJSON{}, fmt.Errorf("failed to create docker client. %w", err)
 	}
-	defer docker.Close()
 
 	containerDetails, err := docker.ContainerInspect(ctx, containerID)
 	if err != nil {
@@ -248,7 +375,6 @@ func (d Docker) GetNodeStatus(ctx context.Context, node *k3d.Node) (bool, string
 	if err != nil {
 		return running, stateString, fmt.Errorf("failed to get docker client: %w", err)
 	}
-	defer docker.Close()
 
 	containerInspectResponse, err := docker.ContainerInspect(ctx, container.ID)
 	if err != nil {
@@ -272,6 +398,15 @@ func (d Docker) NodeIsRunning(ctx context.Context, node *k3d.Node) (bool, error)
 
 // GetNodeLogs returns the logs from a given node
 func (d Docker) GetNodeLogs(ctx context.Context, node *k3d.Node, since time.Time) (io.ReadCloser, error) {
+	return getNodeLogs(ctx, node, since, false)
+}
+
+// FollowNodeLogs returns the logs from a given node and keeps streaming new log lines until the container stops, the context is done or the reader is closed
+func (d Docker) FollowNodeLogs(ctx context.Context, node *k3d.Node, since time.Time) (io.ReadCloser, error) {
+	return getNodeLogs(ctx, node, since, true)
+}
+
+func getNodeLogs(ctx context.Context, node *k3d.Node, since time.Time, follow bool) (io.ReadCloser, error) {
 	// get the container for the given node
 	container, err := getNodeContainer(ctx, node)
 	if err != nil {
@@ -283,14 +418,14 @@ func (d Docker) GetNodeLogs(ctx context.Context, node *k3d.Node, since time.Time
 	if err != nil {
 		return nil, fmt.Errorf("failed to get docker client; %w", err)
 	}
-	defer docker.Close()
 
 	containerInspectResponse, err := docker.ContainerInspect(ctx, container.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed ton inspect container '%s': %w", container.ID, err)
 	}
 
-	if !containerInspectResponse.ContainerJSONBase.State.Running {
+	// the logs of stopped nodes can still be read (e.g. to find out why they stopped), but not followed
+	if follow && !containerInspectResponse.ContainerJSONBase.State.Running {
 		return nil, fmt.Errorf("node '%s' (container '%s') not running", node.Name, containerInspectResponse.ID)
 	}
 
@@ -298,12 +433,30 @@ func (d Docker) GetNodeLogs(ctx context.Context, node *k3d.Node, since time.Time
 	if !since.IsZero() {
 		sinceStr = since.Format("2006-01-02T15:04:05.999999999Z")
 	}
-	logreader, err := docker.ContainerLogs(ctx, container.ID, types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true, Since: sinceStr})
+	logreader, err := docker.ContainerLogs(ctx, container.ID, types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true, Since: sinceStr, Follow: follow})
 	if err != nil {
 		return nil, fmt.Errorf("docker failed to get logs from node '%s' (container '%s'): %w", node.Name, container.ID, err)
 	}
 
-	return logreader, nil
+	// without a TTY, stdout and stderr are multiplexed with a header per frame, which would end up in between the log lines
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		_, err := stdcopy.StdCopy(pipeWriter, pipeWriter, logreader)
+		pipeWriter.CloseWithError(err)
+	}()
+
+	return &logStream{PipeReader: pipeReader, logs: logreader}, nil
+}
+
+// logStream is the demultiplexed output of a log stream, closing it closes the underlying stream as well
+type logStream struct {
+	*io.PipeReader
+	logs io.ReadCloser
+}
+
+func (s *logStream) Close() error {
+	s.PipeReader.Close()
+	return s.logs.Close()
 }
 
 // ExecInNodeGetLogs executes a command inside a node and returns the logs to the caller, e.g. to parse them
@@ -318,6 +471,74 @@ func (d Docker) ExecInNodeGetLogs(ctx context.Context, node *k3d.Node, cmd []str
 	return resp.Reader, nil
 }
 
+// ExecInNodeStream executes a command inside a node and returns its output (stdout and stderr) while it's still running, e.g. to follow a log file
+// Closing the returned reader kills the process.
+func (d Docker) ExecInNodeStream(ctx context.Context, node *k3d.Node, cmd []string) (io.ReadCloser, error) {
+
+	l.Log().Debugf("Streaming output of command '%+v' in node '%s'", cmd, node.Name)
+
+	// the shell reports its PID before it's replaced by the command, so that we can kill the process later on
+	// (closing the exec connection only detaches from it)
+	wrappedCmd := append([]string{"sh", "-c", `echo "$$" && exec "$@"`, "sh"}, cmd...)
+
+	process, err := startExec(ctx, node, wrappedCmd, execOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	// without a TTY, stdout and stderr are multiplexed on the connection
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		_, err := stdcopy.StdCopy(pipeWriter, pipeWriter, process.conn.Reader)
+		pipeWriter.CloseWithError(err)
+	}()
+
+	reader := bufio.NewReader(pipeReader)
+	pidLine, err := reader.ReadString('\n')
+	if err != nil {
+		process.conn.Close()
+		return nil, fmt.Errorf("failed to start command in node '%s': %w", node.Name, err)
+	}
+	pid := strings.TrimSpace(pidLine)
+	if _, err := strconv.Atoi(pid); err != nil {
+		process.conn.Close()
+		return nil, fmt.Errorf("failed to get PID of command in node '%s' (got '%s')", node.Name, pid)
+	}
+
+	return &execStream{
+		runtime: d,
+		node:    node,
+		conn:    process.conn,
+		reader:  reader,
+		pid:     pid,
+	}, nil
+}
+
+// execStream wraps the output of an exec process as an io.ReadCloser, which kills the process when closed
+type execStream struct {
+	runtime Docker
+	node    *k3d.Node
+	conn    types.HijackedResponse
+	reader  io.Reader
+	pid     string
+}
+
+func (s *execStream) Read(p []byte) (int, error) {
+	return s.reader.Read(p)
+}
+
+func (s *execStream) Close() error {
+	defer s.conn.Close()
+	// the context of the stream may be cancelled already (e.g. on Ctrl-C), but we still have to clean up
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s.runtime.ExecInNode(ctx, s.node, []string{"kill", s.pid}); err != nil {
+		// most likely, the process exited already
+		l.Log().Debugf("Failed to kill process %s in node '%s': %v", s.pid, s.node.Name, err)
+	}
+	return nil
+}
+
 // ExecInNode execs a command inside a node
 func (d Docker) ExecInNode(ctx context.Context, node *k3d.Node, cmd []string) error {
 	execConnection, err := executeInNode(ctx, node, cmd)
@@ -333,6 +554,59 @@ func (d Docker) ExecInNode(ctx context.Context, node *k3d.Node, cmd []string) er
 	return err
 }
 
+// ExportNode returns a tar archive of the node container's filesystem (like 'docker export', without the content of volumes)
+func (d Docker) ExportNode(ctx context.Context, node *k3d.Node) (io.ReadCloser, error) {
+	nodeContainer, err := getNodeContainer(ctx, node)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find container for node '%s': %w", node.Name, err)
+	}
+
+	docker, err := GetDockerClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get docker client: %w", err)
+	}
+
+	archive, err := docker.ContainerExport(ctx, nodeContainer.ID)
+	if err != nil {
+		return nil, fmt.Errorf("docker failed to export container '%s': %w", nodeContainer.ID, err)
+	}
+	return archive, nil
+}
+
+// ExecInNodeWithStdin executes a command in a node, streams the input to its stdin and waits for it to finish
+func (d Docker) ExecInNodeWithStdin(ctx context.Context, node *k3d.Node, cmd []string, stdin io.Reader) error {
+
+	l.Log().Debugf("Executing command '%+v' with input in node '%s'", cmd, node.Name)
+
+	process, err := startExec(ctx, node, cmd, execOptions{stdin: true})
+	if err != nil {
+		return err
+	}
+	defer process.conn.Close()
+
+	// keep the output for the error message
+	var output bytes.Buffer
+	outputDone := make(chan struct{})
+	go func() {
+		_, _ = stdcopy.StdCopy(&output, &output, process.conn.Reader)
+		close(outputDone)
+	}()
+
+	if err := process.streamStdin(stdin); err != nil {
+		return err
+	}
+	<-outputDone
+
+	exitCode, err := process.wait(ctx)
+	if err != nil {
+		return err
+	}
+	if exitCode != 0 {
+		return fmt.Errorf("Exec process in node '%s' failed with exit code '%d': %s", node.Name, exitCode, strings.TrimSpace(output.String()))
+	}
+	return nil
+}
+
 func executeInNode(ctx context.Context, node *k3d.Node, cmd []string) (*types.HijackedResponse, error) {
 
 	l.Log().Debugf("Executing command '%+v' in node '%s'", cmd, node.Name)
@@ -348,7 +622,6 @@ func executeInNode(ctx context.Context, node *k3d.Node, cmd []string) (*types.Hi
 	if err != nil {
 		return nil, fmt.Errorf("failed to get docker client: %w", err)
 	}
-	defer docker.Close()
 
 	// exec
 	exec, err := docker.ContainerExecCreate(ctx, container.ID, types.ExecConfig{
@@ -398,13 +671,6 @@ func executeInNode(ctx context.Context, node *k3d.Node, cmd []string) (*types.Hi
 
 // GetNodesInNetwork returns all the nodes connected to a given network
 func (d Docker) GetNodesInNetwork(ctx context.Context, network string) ([]*k3d.Node, error) {
-	// create docker client
-	docker, err := GetDockerClient()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create docker client: %w", err)
-	}
-	defer docker.Close()
-
 	net, err := GetNetwork(ctx, network)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get network '%s': %w", network, err)
@@ -444,7 +710,35 @@ func (d Docker) RenameNode(ctx context.Context, node *k3d.Node, newName string)
 	if err != nil {
 		return fmt.Errorf("failed to get docker client: %w", err)
 	}
-	defer docker.Close()
 
 	return docker.ContainerRename(ctx, container.ID, newName)
 }
+
+// CommitNode creates a new image from the node container's filesystem (excluding volumes)
+func (d Docker) CommitNode(ctx context.Context, node *k3d.Node, image string, labels map[string]string) error {
+	nodeContainer, err := getNodeContainer(ctx, node)
+	if err != nil {
+		return fmt.Errorf("failed to find container for node '%s': %w", node.Name, err)
+	}
+
+	docker, err := GetDockerClient()
+	if err != nil {
+		return fmt.Errorf("failed to get docker client: %w", err)
+	}
+
+	changes := make([]string, 0, len(labels))
+	for k, v := range labels {
+		changes = append(changes, fmt.Sprintf("LABEL %s=%q", k, v))
+	}
+
+	if _, err := docker.ContainerCommit(ctx, nodeContainer.ID, types.ContainerCommitOptions{
+		Reference: image,
+		Comment:   fmt.Sprintf("k3d: commit of node %s", node.Name),
+		Changes:   changes,
+		Pause:     true,
+	}); err != nil {
+		return fmt.Errorf("docker failed to commit container '%s' as '%s': %w", nodeContainer.ID, image, err)
+	}
+
+	return nil
+}
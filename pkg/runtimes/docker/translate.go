@@ -55,8 +55,11 @@ func TranslateNodeToContainer(node *k3d.Node) (*NodeInDocker, error) {
 	/* initialize everything that we need */
 	containerConfig := docker.Config{}
 	hostConfig := docker.HostConfig{
-		Init:       &init,
-		ExtraHosts: node.ExtraHosts,
+		Init:        &init,
+		ExtraHosts:  node.ExtraHosts,
+		DNS:         node.DNS,
+		DNSSearch:   node.DNSSearch,
+		SecurityOpt: node.SecurityOpts,
 	}
 	networkingConfig := network.NetworkingConfig{}
 
@@ -66,7 +69,7 @@ func TranslateNodeToContainer(node *k3d.Node) (*NodeInDocker, error) {
 
 	/* Command & Arguments */
 	// FIXME: FixCgroupV2 - to be removed when fixed upstream
-	if fixes.FixEnabledAny() {
+	if fixes.FixEnabledAny() || node.RuntimeLabels[k3d.LabelNodeInit] != "" || node.RuntimeLabels[k3d.LabelNodeCRI] == string(k3d.CRIDockerd) {
 		if node.Role == k3d.AgentRole || node.Role == k3d.ServerRole {
 			containerConfig.Entrypoint = []string{
 				"/bin/k3d-entrypoint.sh",
@@ -97,6 +100,9 @@ func TranslateNodeToContainer(node *k3d.Node) (*NodeInDocker, error) {
 	for _, mnt := range k3d.DefaultTmpfsMounts {
 		hostConfig.Tmpfs[mnt] = ""
 	}
+	for mnt, opts := range node.Tmpfs {
+		hostConfig.Tmpfs[mnt] = opts
+	}
 
 	if node.GPURequest != "" {
 		gpuopts := dockercliopts.GpuOpts{}
@@ -116,6 +122,30 @@ func TranslateNodeToContainer(node *k3d.Node) (*NodeInDocker, error) {
 		hostConfig.Memory = memory
 	}
 
+	// CPU limits
+	if node.CPUs != "" {
+		var cpus dockercliopts.NanoCPUs
+		if err := cpus.Set(node.CPUs); err != nil {
+			return nil, fmt.Errorf("Failed to set CPU limit: %+v", err)
+		}
+		hostConfig.NanoCPUs = cpus.Value()
+	}
+
+	if node.ShmSize != "" {
+		shmSize, err := dockerunits.RAMInBytes(node.ShmSize)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to set shm size: %+v", err)
+		}
+		hostConfig.ShmSize = shmSize
+	}
+
+	if node.LogDriver != "" || len(node.LogOpts) > 0 {
+		hostConfig.LogConfig = docker.LogConfig{
+			Type:   node.LogDriver,
+			Config: node.LogOpts,
+		}
+	}
+
 	/* They have to run in privileged mode */
 	// TODO: can we replace this by a reduced set of capabilities?
 	hostConfig.Privileged = true
@@ -249,6 +279,8 @@ func TranslateContainerDetailsToNode(containerDetails types.ContainerJSON) (*k3d
 			serverOpts.KubeAPI.Host = v
 		} else if k == k3d.LabelServerAPIPort {
 			serverOpts.KubeAPI.Binding.HostPort = v
+		} else if k == k3d.LabelServerAPITunnel {
+			serverOpts.KubeAPI.Tunnel = v == "true"
 		}
 	}
 
@@ -264,10 +296,43 @@ func TranslateContainerDetailsToNode(containerDetails types.ContainerJSON) (*k3d
 	nodeState := k3d.NodeState{
 		Running: containerDetails.ContainerJSONBase.State.Running,
 		Status:  containerDetails.ContainerJSONBase.State.Status,
+		Started: containerDetails.ContainerJSONBase.State.StartedAt,
+		// docker sets this on every OOM event of the container's cgroup (i.e. also for killed pods, not only when k3s itself dies) and resets it on start
+		OOMKilled: containerDetails.ContainerJSONBase.State.OOMKilled,
+	}
+	if nodeState.Running && containerDetails.NetworkSettings != nil {
+		nodeState.PublishedPorts = publishedPorts(containerDetails.NetworkSettings.Ports)
 	}
 
 	// memory limit
 	memoryStr := dockerunits.HumanSize(float64(containerDetails.HostConfig.Memory))
+
+	// CPU limit
+	cpusStr := ""
+	if containerDetails.HostConfig.NanoCPUs > 0 {
+		cpusStr = strconv.FormatFloat(float64(containerDetails.HostConfig.NanoCPUs)/1e9, 'f', -1, 64)
+	}
+
+	shmSizeStr := ""
+	if containerDetails.HostConfig.ShmSize > 0 {
+		shmSizeStr = strconv.FormatInt(containerDetails.HostConfig.ShmSize, 10)
+	}
+	// additional tmpfs mounts
+	var tmpfs map[string]string
+	defaultTmpfs := map[string]bool{}
+	for _, mnt := range k3d.DefaultTmpfsMounts {
+		defaultTmpfs[mnt] = true
+	}
+	for mnt, opts := range containerDetails.HostConfig.Tmpfs {
+		if defaultTmpfs[mnt] {
+			continue
+		}
+		if tmpfs == nil {
+			tmpfs = map[string]string{}
+		}
+		tmpfs[mnt] = opts
+	}
+
 	// no-limit is returned as 0B, filter this out
 	if memoryStr == "0B" {
 		memoryStr = ""
@@ -321,11 +386,43 @@ func TranslateContainerDetailsToNode(containerDetails types.ContainerJSON) (*k3d
 		Created:       containerDetails.Created,
 		RuntimeLabels: labels,
 		Networks:      orderedNetworks,
+		DNS:           containerDetails.HostConfig.DNS,
+		DNSSearch:     containerDetails.HostConfig.DNSSearch,
+		SecurityOpts:  containerDetails.HostConfig.SecurityOpt,
 		ServerOpts:    serverOpts,
 		AgentOpts:     k3d.AgentOpts{},
 		State:         nodeState,
 		Memory:        memoryStr,
+		CPUs:          cpusStr,
+		ShmSize:       shmSizeStr,
+		Tmpfs:         tmpfs,
+		LogDriver:     containerDetails.HostConfig.LogConfig.Type,
+		LogOpts:       containerDetails.HostConfig.LogConfig.Config,
 		IP:            nodeIP, // only valid for the cluster network
 	}
 	return node, nil
 }
+
+// publishedPorts filters the ports reported by docker down to those actually bound on the host.
+// Docker reports random ports once per address family, so IPv6 duplicates of IPv4 bindings are dropped.
+func publishedPorts(ports nat.PortMap) nat.PortMap {
+	published := nat.PortMap{}
+	for port, bindings := range ports {
+		hostPorts := map[string]bool{}
+		for _, binding := range bindings {
+			if binding.HostPort != "" && !strings.Contains(binding.HostIP, ":") {
+				hostPorts[binding.HostPort] = true
+			}
+		}
+		for _, binding := range bindings {
+			if binding.HostPort == "" || (strings.Contains(binding.HostIP, ":") && hostPorts[binding.HostPort]) {
+				continue
+			}
+			published[port] = append(published[port], binding)
+		}
+	}
+	if len(published) == 0 {
+		return nil
+	}
+	return published
+}
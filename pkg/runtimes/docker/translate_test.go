@@ -29,6 +29,7 @@ import (
 
 	"github.com/go-test/deep"
 
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/go-connections/nat"
@@ -57,6 +58,11 @@ func TestTranslateNodeToContainer(t *testing.T) {
 		Restart:       true,
 		RuntimeLabels: map[string]string{k3d.LabelRole: string(k3d.ServerRole), "test_key_1": "test_val_1"},
 		Networks:      []string{"mynet"},
+		CPUs:          "1.5",
+		ShmSize:       "1g",
+		Tmpfs:         map[string]string{"/var/lib/kubelet": "exec"},
+		LogDriver:     "local",
+		LogOpts:       map[string]string{"max-size": "10m"},
 	}
 
 	init := true
@@ -82,7 +88,11 @@ func TestTranslateNodeToContainer(t *testing.T) {
 			},
 			Init:       &init,
 			Privileged: true,
-			Tmpfs:      map[string]string{"/run": "", "/var/run": ""},
+			Tmpfs:      map[string]string{"/run": "", "/var/run": "", "/var/lib/kubelet": "exec"},
+			Resources: container.Resources{
+				NanoCPUs: 1500000000,
+			},
+			ShmSize: 1073741824,
 			PortBindings: nat.PortMap{
 				"6443/tcp": {
 					{
@@ -91,6 +101,10 @@ func TestTranslateNodeToContainer(t *testing.T) {
 					},
 				},
 			},
+			LogConfig: container.LogConfig{
+				Type:   "local",
+				Config: map[string]string{"max-size": "10m"},
+			},
 		},
 		NetworkingConfig: network.NetworkingConfig{
 			EndpointsConfig: map[string]*network.EndpointSettings{
@@ -114,3 +128,63 @@ func TestTranslateNodeToContainer(t *testing.T) {
 	}
 
 }
+
+func TestTranslateContainerDetailsToNodeShmSize(t *testing.T) {
+	newContainerDetails := func(shmSize int64) types.ContainerJSON {
+		labels := map[string]string{k3d.LabelRole: string(k3d.ServerRole)}
+		for k, v := range k3d.DefaultRuntimeLabels {
+			labels[k] = v
+		}
+		return types.ContainerJSON{
+			ContainerJSONBase: &types.ContainerJSONBase{
+				Name:       "/test",
+				State:      &types.ContainerState{},
+				HostConfig: &container.HostConfig{ShmSize: shmSize},
+			},
+			Config:          &container.Config{Labels: labels},
+			NetworkSettings: &types.NetworkSettings{},
+		}
+	}
+
+	node, err := TranslateContainerDetailsToNode(newContainerDetails(1073741824))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.ShmSize != "1073741824" {
+		t.Errorf("expected shm size '1073741824', got '%s'", node.ShmSize)
+	}
+	// the translated size has to survive the way back, e.g. when a node is re-created from an existing one
+	nodeInDocker, err := TranslateNodeToContainer(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nodeInDocker.HostConfig.ShmSize != 1073741824 {
+		t.Errorf("expected shm size 1073741824 after the round trip, got %d", nodeInDocker.HostConfig.ShmSize)
+	}
+
+	node, err = TranslateContainerDetailsToNode(newContainerDetails(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.ShmSize != "" {
+		t.Errorf("expected no shm size for the runtime's default, got '%s'", node.ShmSize)
+	}
+}
+
+func TestPublishedPorts(t *testing.T) {
+	ports := nat.PortMap{
+		"80/tcp":   []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: "49153"}, {HostIP: "::", HostPort: "49153"}},
+		"443/tcp":  []nat.PortBinding{{HostIP: "::", HostPort: "49154"}},
+		"8080/tcp": nil, // exposed, but not published
+	}
+	expected := nat.PortMap{
+		"80/tcp":  []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: "49153"}},
+		"443/tcp": []nat.PortBinding{{HostIP: "::", HostPort: "49154"}},
+	}
+	if diff := deep.Equal(publishedPorts(ports), expected); diff != nil {
+		t.Errorf("unexpected published ports: %v", diff)
+	}
+	if published := publishedPorts(nat.PortMap{"8080/tcp": nil}); published != nil {
+		t.Errorf("expected no published ports, got %v", published)
+	}
+}
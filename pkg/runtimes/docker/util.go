@@ -29,19 +29,38 @@ import (
 	"io"
 	"os"
 	"path"
+	"sync"
 
+	"github.com/containerd/containerd/platforms"
 	"github.com/docker/cli/cli/command"
 	"github.com/docker/cli/cli/flags"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/archive"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/pkg/errors"
 	l "github.com/rancher/k3d/v5/pkg/logger"
 	runtimeErrors "github.com/rancher/k3d/v5/pkg/runtimes/errors"
 	k3d "github.com/rancher/k3d/v5/pkg/types"
 )
 
+// defaultPlatformEnv is the environment variable the docker CLI reads the default platform for pulling images and creating containers from
+const defaultPlatformEnv = "DOCKER_DEFAULT_PLATFORM"
+
+// defaultPlatform returns the platform set via DOCKER_DEFAULT_PLATFORM or nil, if it's not set
+func defaultPlatform() (*specs.Platform, error) {
+	value := os.Getenv(defaultPlatformEnv)
+	if value == "" {
+		return nil, nil
+	}
+	platform, err := platforms.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid platform '%s' in %s: %w", value, defaultPlatformEnv, err)
+	}
+	return &platform, nil
+}
+
 // GetDefaultObjectLabelsFilter returns docker type filters created from k3d labels
 func GetDefaultObjectLabelsFilter(clusterName string) filters.Args {
 	filters := filters.NewArgs()
@@ -59,7 +78,6 @@ func (d Docker) CopyToNode(ctx context.Context, src string, dest string, node *k
 	if err != nil {
 		return fmt.Errorf("failed to get docker client: %w", err)
 	}
-	defer docker.Close()
 
 	container, err := getNodeContainer(ctx, node)
 	if err != nil {
@@ -106,7 +124,6 @@ func (d Docker) WriteToNode(ctx context.Context, content []byte, dest string, mo
 	if err != nil {
 		return fmt.Errorf("failed to get docker client: %w", err)
 	}
-	defer docker.Close()
 
 	buf := new(bytes.Buffer)
 	tarWriter := tar.NewWriter(buf)
@@ -161,8 +178,48 @@ func (d Docker) ReadFromNode(ctx context.Context, path string, node *k3d.Node) (
 	return reader, err
 }
 
-// GetDockerClient returns a docker client
+// WriteArchiveToNode extracts a tar archive (e.g. obtained via ReadFromNode) into the given directory inside the node container
+func (d Docker) WriteArchiveToNode(ctx context.Context, archive io.Reader, dest string, node *k3d.Node) error {
+	nodeContainer, err := getNodeContainer(ctx, node)
+	if err != nil {
+		return fmt.Errorf("failed to find container for node '%s': %w", node.Name, err)
+	}
+
+	docker, err := GetDockerClient()
+	if err != nil {
+		return fmt.Errorf("failed to get docker client: %w", err)
+	}
+
+	if err := docker.CopyToContainer(ctx, nodeContainer.ID, dest, archive, types.CopyToContainerOptions{AllowOverwriteDirWithFile: true}); err != nil {
+		return fmt.Errorf("failed to copy archive to '%s' in container '%s': %w", dest, nodeContainer.ID, err)
+	}
+
+	return nil
+}
+
+var (
+	dockerClientMu sync.Mutex
+	dockerClient   *client.Client
+)
+
+// GetDockerClient returns the docker client shared by all runtime operations, creating it on first use.
+// Reusing it avoids evaluating the docker CLI config and context for every single API call and keeps the connections to the daemon alive,
+// so callers must not close it.
 func GetDockerClient() (*client.Client, error) {
+	dockerClientMu.Lock()
+	defer dockerClientMu.Unlock()
+	if dockerClient == nil {
+		newClient, err := newDockerClient()
+		if err != nil {
+			return nil, err
+		}
+		dockerClient = newClient
+	}
+	return dockerClient, nil
+}
+
+// newDockerClient creates a docker client for the endpoint of the current docker context (incl. TLS settings), falling back to the environment
+func newDockerClient() (*client.Client, error) {
 	dockerCli, err := command.NewDockerCli(command.WithStandardStreams())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create new docker CLI with standard streams: %w", err)
@@ -209,11 +266,11 @@ func GetDockerClient() (*client.Client, error) {
 			)
 		}
 
-		return client.NewClientWithOpts(clientopts...)
+		return clientOptions.apply(clientopts...)
 	}
 
 	// fallback default client
-	return client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	return clientOptions.apply(client.FromEnv, client.WithAPIVersionNegotiation())
 }
 
 // isAttachedToNetwork return true if node is attached to network
@@ -24,10 +24,12 @@ package docker
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/volume"
 	l "github.com/rancher/k3d/v5/pkg/logger"
+	runtimeTypes "github.com/rancher/k3d/v5/pkg/runtimes/types"
 	k3d "github.com/rancher/k3d/v5/pkg/types"
 )
 
@@ -38,7 +40,6 @@ func (d Docker) CreateVolume(ctx context.Context, name string, labels map[string
 	if err != nil {
 		return fmt.Errorf("failed to get docker client: %w", err)
 	}
-	defer docker.Close()
 
 	// (1) create volume
 	volumeCreateOptions := volume.VolumeCreateBody{
@@ -70,7 +71,6 @@ func (d Docker) DeleteVolume(ctx context.Context, name string) error {
 	if err != nil {
 		return fmt.Errorf("failed to get docker client: %w", err)
 	}
-	defer docker.Close()
 
 	// get volume and delete it
 	vol, err := docker.VolumeInspect(ctx, name)
@@ -101,7 +101,6 @@ func (d Docker) GetVolume(name string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to get docker client: %w", err)
 	}
-	defer docker.Close()
 
 	filters := filters.NewArgs()
 	filters.Add("name", fmt.Sprintf("^%s$", name))
@@ -116,3 +115,69 @@ func (d Docker) GetVolume(name string) (string, error) {
 	return volumeList.Volumes[0].Name, nil
 
 }
+
+// GetVolumesByLabel returns all volumes that have the given labels
+func (d Docker) GetVolumesByLabel(ctx context.Context, labels map[string]string) ([]runtimeTypes.Volume, error) {
+	// (0) create new docker client
+	docker, err := GetDockerClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get docker client: %w", err)
+	}
+
+	filters := filters.NewArgs()
+	for k, v := range labels {
+		filters.Add("label", fmt.Sprintf("%s=%s", k, v))
+	}
+	volumeList, err := docker.VolumeList(ctx, filters)
+	if err != nil {
+		return nil, fmt.Errorf("docker failed to list volumes: %w", err)
+	}
+
+	volumes := make([]runtimeTypes.Volume, 0, len(volumeList.Volumes))
+	for _, vol := range volumeList.Volumes {
+		volumes = append(volumes, runtimeTypes.Volume{Name: vol.Name, Labels: vol.Labels})
+	}
+	return volumes, nil
+}
+
+// GetDiskUsage returns the disk space consumed by containers (writable layers) and volumes
+func (d Docker) GetDiskUsage(ctx context.Context) (*runtimeTypes.DiskUsage, error) {
+	// (0) create new docker client
+	docker, err := GetDockerClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get docker client: %w", err)
+	}
+
+	du, err := docker.DiskUsage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("docker failed to calculate disk usage: %w", err)
+	}
+
+	usage := &runtimeTypes.DiskUsage{
+		Containers: make(map[string]runtimeTypes.ContainerDiskUsage, len(du.Containers)),
+		Volumes:    make(map[string]int64, len(du.Volumes)),
+	}
+
+	for _, cont := range du.Containers {
+		if len(cont.Names) == 0 {
+			continue
+		}
+		contUsage := runtimeTypes.ContainerDiskUsage{SizeRw: cont.SizeRw}
+		for _, mount := range cont.Mounts {
+			if mount.Type == "volume" && mount.Name != "" {
+				contUsage.Volumes = append(contUsage.Volumes, mount.Name)
+			}
+		}
+		usage.Containers[strings.TrimPrefix(cont.Names[0], "/")] = contUsage
+	}
+
+	for _, vol := range du.Volumes {
+		size := int64(-1)
+		if vol.UsageData != nil {
+			size = vol.UsageData.Size
+		}
+		usage.Volumes[vol.Name] = size
+	}
+
+	return usage, nil
+}
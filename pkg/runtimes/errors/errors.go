@@ -29,6 +29,9 @@ var ErrRuntimeNetworkNotEmpty = errors.New("network not empty")
 // ErrRuntimeContainerUnknown describes the situation, where we're inspecting a container that's not obviously managed by k3d
 var ErrRuntimeContainerUnknown = errors.New("container not managed by k3d: missing default label(s)")
 
+// ErrRuntimeContainerNotExists describes an error that occurs because a container could not be found by the runtime
+var ErrRuntimeContainerNotExists = errors.New("container does not exist")
+
 // Runtime Network Errors
 var (
 	ErrRuntimeNetworkNotExists     = errors.New("network does not exist")
@@ -37,3 +40,6 @@ var (
 
 // Container Filesystem Errors
 var ErrRuntimeFileNotFound = errors.New("file not found")
+
+// ErrRuntimeReadOnly describes an error that occurs because a mutating operation was attempted on a read-only runtime
+var ErrRuntimeReadOnly = errors.New("runtime is read-only")
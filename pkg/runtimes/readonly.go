@@ -0,0 +1,182 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package runtimes
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	runtimeErr "github.com/rancher/k3d/v5/pkg/runtimes/errors"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+// readOnlyRuntime wraps a runtime and rejects every operation that could modify containers, networks, volumes or images
+type readOnlyRuntime struct {
+	Runtime
+}
+
+// ReadOnly returns a view of the given runtime that only allows inspecting resources.
+// Mutating operations fail with ErrRuntimeReadOnly, which makes it safe to hand the runtime to e.g. shared dashboards.
+func ReadOnly(runtime Runtime) Runtime {
+	if _, ok := runtime.(readOnlyRuntime); ok {
+		return runtime
+	}
+	return readOnlyRuntime{Runtime: runtime}
+}
+
+// IsReadOnly tells whether the given runtime rejects mutating operations
+func IsReadOnly(runtime Runtime) bool {
+	_, ok := runtime.(readOnlyRuntime)
+	return ok
+}
+
+func rejectReadOnly(op string) error {
+	return fmt.Errorf("cannot %s: %w", op, runtimeErr.ErrRuntimeReadOnly)
+}
+
+func (r readOnlyRuntime) CreateNode(context.Context, *k3d.Node) error {
+	return rejectReadOnly("create node")
+}
+
+func (r readOnlyRuntime) DeleteNode(context.Context, *k3d.Node) error {
+	return rejectReadOnly("delete node")
+}
+
+func (r readOnlyRuntime) RenameNode(context.Context, *k3d.Node, string) error {
+	return rejectReadOnly("rename node")
+}
+
+func (r readOnlyRuntime) CreateNetworkIfNotPresent(context.Context, *k3d.ClusterNetwork) (*k3d.ClusterNetwork, bool, error) {
+	return nil, false, rejectReadOnly("create network")
+}
+
+func (r readOnlyRuntime) DeleteNetwork(context.Context, string) error {
+	return rejectReadOnly("delete network")
+}
+
+func (r readOnlyRuntime) StartNode(context.Context, *k3d.Node) error {
+	return rejectReadOnly("start node")
+}
+
+func (r readOnlyRuntime) StopNode(context.Context, *k3d.Node) error {
+	return rejectReadOnly("stop node")
+}
+
+func (r readOnlyRuntime) FreezeNode(context.Context, *k3d.Node, string) error {
+	return rejectReadOnly("freeze node")
+}
+
+func (r readOnlyRuntime) ThawNode(context.Context, *k3d.Node, string) error {
+	return rejectReadOnly("thaw node")
+}
+
+func (r readOnlyRuntime) PauseNode(context.Context, *k3d.Node) error {
+	return rejectReadOnly("pause node")
+}
+
+func (r readOnlyRuntime) UnpauseNode(context.Context, *k3d.Node) error {
+	return rejectReadOnly("unpause node")
+}
+
+func (r readOnlyRuntime) CreateVolume(context.Context, string, map[string]string) error {
+	return rejectReadOnly("create volume")
+}
+
+func (r readOnlyRuntime) DeleteVolume(context.Context, string) error {
+	return rejectReadOnly("delete volume")
+}
+
+// ExecInNode & co. are rejected as well, since there's no telling what the command does
+func (r readOnlyRuntime) ExecInNode(context.Context, *k3d.Node, []string) error {
+	return rejectReadOnly("exec in node")
+}
+
+func (r readOnlyRuntime) ExecInNodeGetLogs(context.Context, *k3d.Node, []string) (*bufio.Reader, error) {
+	return nil, rejectReadOnly("exec in node")
+}
+
+func (r readOnlyRuntime) ExecInNodeStream(context.Context, *k3d.Node, []string) (io.ReadCloser, error) {
+	return nil, rejectReadOnly("exec in node")
+}
+
+func (r readOnlyRuntime) ExecInNodeInteractive(context.Context, *k3d.Node, []string, bool, io.Reader, io.Writer, io.Writer) (int, error) {
+	return -1, rejectReadOnly("exec in node")
+}
+
+func (r readOnlyRuntime) DeleteImage(context.Context, string) error {
+	return rejectReadOnly("delete image")
+}
+
+func (r readOnlyRuntime) TagImage(context.Context, string, string) error {
+	return rejectReadOnly("tag image")
+}
+
+func (r readOnlyRuntime) PushImage(context.Context, string) error {
+	return rejectReadOnly("push image")
+}
+
+func (r readOnlyRuntime) PullImage(context.Context, string) error {
+	return rejectReadOnly("pull image")
+}
+
+func (r readOnlyRuntime) CopyToNode(context.Context, string, string, *k3d.Node) error {
+	return rejectReadOnly("copy to node")
+}
+
+func (r readOnlyRuntime) WriteToNode(context.Context, []byte, string, os.FileMode, *k3d.Node) error {
+	return rejectReadOnly("write to node")
+}
+
+func (r readOnlyRuntime) WriteArchiveToNode(context.Context, io.Reader, string, *k3d.Node) error {
+	return rejectReadOnly("write to node")
+}
+
+func (r readOnlyRuntime) CommitNode(context.Context, *k3d.Node, string, map[string]string) error {
+	return rejectReadOnly("commit node")
+}
+
+func (r readOnlyRuntime) ExportNode(context.Context, *k3d.Node) (io.ReadCloser, error) {
+	return nil, rejectReadOnly("export node")
+}
+
+func (r readOnlyRuntime) ImportImageFromArchive(context.Context, io.Reader, string, string, []string) error {
+	return rejectReadOnly("import image")
+}
+
+func (r readOnlyRuntime) ConnectNodeToNetwork(context.Context, *k3d.Node, string) error {
+	return rejectReadOnly("connect node to network")
+}
+
+func (r readOnlyRuntime) DisconnectNodeFromNetwork(context.Context, *k3d.Node, string) error {
+	return rejectReadOnly("disconnect node from network")
+}
+
+func (r readOnlyRuntime) ConnectContainerToNetwork(context.Context, string, string) error {
+	return rejectReadOnly("connect container to network")
+}
+
+func (r readOnlyRuntime) DisconnectContainerFromNetwork(context.Context, string, string) error {
+	return rejectReadOnly("disconnect container from network")
+}
@@ -0,0 +1,64 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package runtimes
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rancher/k3d/v5/pkg/runtimes/docker"
+	runtimeErr "github.com/rancher/k3d/v5/pkg/runtimes/errors"
+	k3d "github.com/rancher/k3d/v5/pkg/types"
+)
+
+func TestReadOnly(t *testing.T) {
+	rt := ReadOnly(docker.Docker{})
+
+	if !IsReadOnly(rt) || IsReadOnly(docker.Docker{}) {
+		t.Fatalf("IsReadOnly does not detect the wrapped runtime")
+	}
+	if !IsReadOnly(ReadOnly(rt)) {
+		t.Fatalf("wrapping a read-only runtime twice should keep it read-only")
+	}
+
+	// inspecting operations are passed through
+	if id := rt.ID(); id != "docker" {
+		t.Errorf("expected ID 'docker', got '%s'", id)
+	}
+
+	node := &k3d.Node{Name: "k3d-test-server-0"}
+	for op, err := range map[string]error{
+		"CreateNode":    rt.CreateNode(context.Background(), node),
+		"DeleteNode":    rt.DeleteNode(context.Background(), node),
+		"StopNode":      rt.StopNode(context.Background(), node),
+		"ExecInNode":    rt.ExecInNode(context.Background(), node, []string{"true"}),
+		"WriteToNode":   rt.WriteToNode(context.Background(), []byte{}, "/tmp/x", 0644, node),
+		"DeleteNetwork": rt.DeleteNetwork(context.Background(), "k3d-test"),
+		"DeleteImage":   rt.DeleteImage(context.Background(), "rancher/k3s"),
+		"PushImage":     rt.PushImage(context.Background(), "localhost:5000/app"),
+	} {
+		if !errors.Is(err, runtimeErr.ErrRuntimeReadOnly) {
+			t.Errorf("%s: expected ErrRuntimeReadOnly, got '%v'", op, err)
+		}
+	}
+}
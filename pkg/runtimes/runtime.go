@@ -62,20 +62,45 @@ type Runtime interface {
 	DeleteNetwork(context.Context, string) error
 	StartNode(context.Context, *k3d.Node) error // starts an existing container
 	StopNode(context.Context, *k3d.Node) error
+	FreezeNode(context.Context, *k3d.Node, string) error // @param context, node, checkpoint ID - saves the process state (CRIU) and stops the node
+	ThawNode(context.Context, *k3d.Node, string) error   // @param context, node, checkpoint ID - restores the process state and removes the checkpoint
+	PauseNode(context.Context, *k3d.Node) error
+	UnpauseNode(context.Context, *k3d.Node) error
 	CreateVolume(context.Context, string, map[string]string) error
 	DeleteVolume(context.Context, string) error
 	GetVolume(string) (string, error)
+	GetVolumesByLabel(context.Context, map[string]string) ([]runtimeTypes.Volume, error)
+	GetNetworksByLabel(context.Context, map[string]string) ([]*k3d.ClusterNetwork, error)
 	GetRuntimePath() string // returns e.g. '/var/run/docker.sock' for a default docker setup
 	ExecInNode(context.Context, *k3d.Node, []string) error
 	ExecInNodeGetLogs(context.Context, *k3d.Node, []string) (*bufio.Reader, error)
+	ExecInNodeStream(context.Context, *k3d.Node, []string) (io.ReadCloser, error)                                   // returns the output of a (long-running) command right away, instead of waiting for it to finish; closing it kills the command
+	ExecInNodeWithStdin(context.Context, *k3d.Node, []string, io.Reader) error                                      // @param context, node, command, input streamed to the command's stdin until EOF
+	ExecInNodeInteractive(context.Context, *k3d.Node, []string, bool, io.Reader, io.Writer, io.Writer) (int, error) // @param context, node, command, allocate a TTY, stdin (nil: none), stdout, stderr - @return exit code of the command
 	GetNodeLogs(context.Context, *k3d.Node, time.Time) (io.ReadCloser, error)
+	FollowNodeLogs(context.Context, *k3d.Node, time.Time) (io.ReadCloser, error) // like GetNodeLogs, but keeps streaming new log lines until the node stops, the context is done or the reader is closed
 	GetImages(context.Context) ([]string, error)
-	CopyToNode(context.Context, string, string, *k3d.Node) error               // @param context, source, destination, node
-	WriteToNode(context.Context, []byte, string, os.FileMode, *k3d.Node) error // @param context, content, destination, filemode, node
-	ReadFromNode(context.Context, string, *k3d.Node) (io.ReadCloser, error)    // @param context, filepath, node
+	GetImageStream(context.Context, []string) (io.ReadCloser, error) // @param context, image references - @return tarball of the images (like 'docker save')
+	ListImages(context.Context) ([]runtimeTypes.Image, error)
+	DeleteImage(context.Context, string) error                // @param context, image reference (name or ID)
+	GetImagePlatform(context.Context, string) (string, error) // @param context, image reference - @return 'os/arch[/variant]'
+	GetImageDigest(context.Context, string) (string, error)   // @param context, image reference - @return digest of the manifest (list) the reference points to
+	TagImage(context.Context, string, string) error           // @param context, source image reference, target image reference
+	PushImage(context.Context, string) error                  // @param context, image reference (including the registry to push to)
+	PullImage(context.Context, string) error                  // @param context, image reference - pulls the image, even if it's present already
+	GetDiskUsage(context.Context) (*runtimeTypes.DiskUsage, error)
+	CopyToNode(context.Context, string, string, *k3d.Node) error                       // @param context, source, destination, node
+	WriteToNode(context.Context, []byte, string, os.FileMode, *k3d.Node) error         // @param context, content, destination, filemode, node
+	ReadFromNode(context.Context, string, *k3d.Node) (io.ReadCloser, error)            // @param context, filepath, node
+	WriteArchiveToNode(context.Context, io.Reader, string, *k3d.Node) error            // @param context, tar archive, destination directory, node
+	CommitNode(context.Context, *k3d.Node, string, map[string]string) error            // @param context, node, image reference, image labels
+	ExportNode(context.Context, *k3d.Node) (io.ReadCloser, error)                      // @return tar archive of the node's filesystem (without volumes)
+	ImportImageFromArchive(context.Context, io.Reader, string, string, []string) error // @param context, filesystem tar archive, image reference, base image to take the config from, Dockerfile instructions changing the config
 	GetHostIP(context.Context, string) (net.IP, error)
-	ConnectNodeToNetwork(context.Context, *k3d.Node, string) error      // @param context, node, network name
-	DisconnectNodeFromNetwork(context.Context, *k3d.Node, string) error // @param context, node, network name
+	ConnectNodeToNetwork(context.Context, *k3d.Node, string) error        // @param context, node, network name
+	DisconnectNodeFromNetwork(context.Context, *k3d.Node, string) error   // @param context, node, network name
+	ConnectContainerToNetwork(context.Context, string, string) error      // @param context, container name or ID (not necessarily managed by k3d), network name
+	DisconnectContainerFromNetwork(context.Context, string, string) error // @param context, container name or ID (not necessarily managed by k3d), network name
 	Info() (*runtimeTypes.RuntimeInfo, error)
 	GetNetwork(context.Context, *k3d.ClusterNetwork) (*k3d.ClusterNetwork, error) // @param context, network (so we can filter by name or by id)
 }
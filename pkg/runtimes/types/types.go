@@ -22,13 +22,45 @@ THE SOFTWARE.
 package types
 
 type RuntimeInfo struct {
-	Name          string
-	Endpoint      string `yaml:",omitempty" json:",omitempty"`
-	Version       string `yaml:",omitempty" json:",omitempty"`
-	OSType        string `yaml:",omitempty" json:",omitempty"`
-	OS            string `yaml:",omitempty" json:",omitempty"`
-	Arch          string `yaml:",omitempty" json:",omitempty"`
-	CgroupVersion string `yaml:",omitempty" json:",omitempty"`
-	CgroupDriver  string `yaml:",omitempty" json:",omitempty"`
-	Filesystem    string `yaml:",omitempty" json:",omitempty"`
+	Name               string
+	Endpoint           string   `yaml:",omitempty" json:",omitempty"`
+	Version            string   `yaml:",omitempty" json:",omitempty"`
+	APIVersion         string   `yaml:",omitempty" json:",omitempty"` // highest API version the runtime daemon supports
+	RequiredAPIVersion string   `yaml:",omitempty" json:",omitempty"` // oldest API version of the runtime daemon k3d works with
+	PinnedAPIVersion   string   `yaml:",omitempty" json:",omitempty"` // API version used instead of negotiating it with the daemon (e.g. via --docker-api-version), if any
+	OSType             string   `yaml:",omitempty" json:",omitempty"`
+	OS                 string   `yaml:",omitempty" json:",omitempty"`
+	Arch               string   `yaml:",omitempty" json:",omitempty"`
+	CgroupVersion      string   `yaml:",omitempty" json:",omitempty"`
+	CgroupDriver       string   `yaml:",omitempty" json:",omitempty"`
+	Filesystem         string   `yaml:",omitempty" json:",omitempty"`
+	DataRoot           string   `yaml:",omitempty" json:",omitempty"` // directory of the runtime host holding images, containers and volumes (e.g. /var/lib/docker)
+	Experimental       bool     `yaml:",omitempty" json:",omitempty"`
+	Platform           string   `yaml:",omitempty" json:",omitempty"` // platform requested for node images (e.g. via DOCKER_DEFAULT_PLATFORM), if any
+	Security           []string `yaml:",omitempty" json:",omitempty"` // enabled security features of the runtime host, e.g. selinux, apparmor, seccomp, rootless
+}
+
+// Image describes an image present in the runtime
+type Image struct {
+	ID   string
+	Tags []string
+	Size int64 // in bytes
+}
+
+// DiskUsage describes the disk space consumed by containers and volumes in the runtime
+type DiskUsage struct {
+	Containers map[string]ContainerDiskUsage // by container name
+	Volumes    map[string]int64              // size in bytes by volume name (-1 if not available)
+}
+
+// ContainerDiskUsage describes the disk space consumed by a single container
+type ContainerDiskUsage struct {
+	SizeRw  int64    // size of the writable layer in bytes
+	Volumes []string // names of the volumes mounted into the container
+}
+
+// Volume describes a volume of the runtime
+type Volume struct {
+	Name   string
+	Labels map[string]string
 }
@@ -0,0 +1,40 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"fmt"
+
+	dockercliopts "github.com/docker/cli/opts"
+)
+
+// ParseCPUs parses a CPU limit given as a (fractional) number of CPUs like docker's '--cpus' flag, e.g. '1.5', into nano CPUs
+func ParseCPUs(cpus string) (int64, error) {
+	var nanoCPUs dockercliopts.NanoCPUs
+	if err := nanoCPUs.Set(cpus); err != nil {
+		return 0, fmt.Errorf("invalid CPU limit '%s': %w", cpus, err)
+	}
+	if nanoCPUs.Value() <= 0 {
+		return 0, fmt.Errorf("invalid CPU limit '%s': must be greater than 0", cpus)
+	}
+	return nanoCPUs.Value(), nil
+}
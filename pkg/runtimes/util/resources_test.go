@@ -0,0 +1,45 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import "testing"
+
+func TestParseCPUs(t *testing.T) {
+	for cpus, expected := range map[string]int64{
+		"1":    1000000000,
+		"1.5":  1500000000,
+		"0.25": 250000000,
+	} {
+		nanoCPUs, err := ParseCPUs(cpus)
+		if err != nil {
+			t.Errorf("unexpected error for '%s': %v", cpus, err)
+		} else if nanoCPUs != expected {
+			t.Errorf("expected %d nano CPUs for '%s', got %d", expected, cpus, nanoCPUs)
+		}
+	}
+
+	for _, cpus := range []string{"", "two", "0", "-1"} {
+		if _, err := ParseCPUs(cpus); err == nil {
+			t.Errorf("expected an error for '%s'", cpus)
+		}
+	}
+}
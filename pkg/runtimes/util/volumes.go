@@ -23,7 +23,9 @@ package util
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
+	"regexp"
 	rt "runtime"
 	"strings"
 
@@ -32,41 +34,161 @@ import (
 	l "github.com/rancher/k3d/v5/pkg/logger"
 )
 
+// windowsPathRegexp matches absolute Windows paths starting with a drive designator, like 'C:\Users' or 'C:/Users'
+var windowsPathRegexp = regexp.MustCompile(`^[a-zA-Z]:[\\/]`)
+
+// wslDefaultMountRoot is the directory below which WSL mounts the Windows drives, if not configured otherwise in /etc/wsl.conf
+const wslDefaultMountRoot = "/mnt/"
+
+// IsWindowsPath checks, if a path is an absolute Windows path with a drive designator.
+// 'C:/...' is only treated as a Windows path on Windows and WSL hosts, as it could also be a named volume 'C' elsewhere.
+func IsWindowsPath(path string) bool {
+	if !windowsPathRegexp.MatchString(path) {
+		return false
+	}
+	return path[2] == '\\' || rt.GOOS == "windows" || IsWSL()
+}
+
+// IsWSL checks, if k3d is running inside the Windows Subsystem for Linux
+func IsWSL() bool {
+	if rt.GOOS != "linux" {
+		return false
+	}
+	if os.Getenv("WSL_DISTRO_NAME") != "" {
+		return true
+	}
+	osRelease, err := ioutil.ReadFile("/proc/sys/kernel/osrelease")
+	if err != nil {
+		return false
+	}
+	return strings.Contains(strings.ToLower(string(osRelease)), "microsoft")
+}
+
+// SplitVolumeMount splits a volume mount of the form [SOURCE:]DEST[:OPT[,OPT]] into its parts,
+// treating the drive designator of a Windows source path (e.g. 'C:\Users') as part of the source.
+// If only one part is given, it's used as both source and destination.
+func SplitVolumeMount(volumeMount string) (src string, dest string, opts string, err error) {
+	if volumeMount == "" {
+		return "", "", "", fmt.Errorf("No volume/path specified")
+	}
+
+	drive := ""
+	rest := volumeMount
+	if IsWindowsPath(volumeMount) {
+		drive, rest = volumeMount[:2], volumeMount[2:]
+	}
+
+	split := strings.Split(rest, ":")
+	if len(split) > 3 {
+		return "", "", "", fmt.Errorf("Invalid volume mount '%s': maximal 2 ':' allowed (not counting the drive designator of a Windows path)", volumeMount)
+	}
+
+	src = drive + split[0]
+	dest = src
+	if len(split) > 1 {
+		dest = split[1]
+	}
+	if len(split) > 2 {
+		opts = split[2]
+	}
+	return src, dest, opts, nil
+}
+
+// TranslateVolumeMount converts a Windows source path of a volume mount into a path the container runtime can use:
+// in WSL, 'C:\Users\me:/data' becomes '/mnt/c/Users/me:/data' (respecting the automount root from /etc/wsl.conf),
+// while on Windows the path is passed on as-is, since Docker Desktop translates it itself.
+func TranslateVolumeMount(volumeMount string) (string, error) {
+	src, dest, opts, err := SplitVolumeMount(volumeMount)
+	if err != nil {
+		return "", err
+	}
+	if !IsWindowsPath(src) || rt.GOOS == "windows" {
+		return volumeMount, nil
+	}
+	if !IsWSL() {
+		return "", fmt.Errorf("Windows path '%s' in volume mount '%s' can only be used on Windows or in WSL", src, volumeMount)
+	}
+
+	if dest == src { // only the Windows path was given, which can't be the destination inside the container
+		return "", fmt.Errorf("Volume mount '%s' needs an explicit destination inside the container (format 'SOURCE:DEST')", volumeMount)
+	}
+
+	translated := WSLPath(src, wslMountRoot()) + ":" + dest
+	if opts != "" {
+		translated += ":" + opts
+	}
+	l.Log().Debugf("Translated volume mount '%s' to '%s' for WSL", volumeMount, translated)
+	return translated, nil
+}
+
+// SELinuxRelabelVolumeMount adds the 'z' option to a bind mount, which makes the runtime relabel the source path (shared between containers)
+// so that it can be accessed on SELinux-enforcing hosts. Named volumes and mounts with an explicit label option (z/Z) are returned unchanged.
+func SELinuxRelabelVolumeMount(volumeMount string) (string, error) {
+	src, dest, opts, err := SplitVolumeMount(volumeMount)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(src, "/") {
+		return volumeMount, nil
+	}
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == "z" || opt == "Z" {
+			return volumeMount, nil
+		}
+	}
+	if opts == "" {
+		opts = "z"
+	} else {
+		opts += ",z"
+	}
+	return fmt.Sprintf("%s:%s:%s", src, dest, opts), nil
+}
+
+// WSLPath converts an absolute Windows path into the path it's mounted at in WSL, e.g. 'C:\Users\me' -> '/mnt/c/Users/me'
+func WSLPath(windowsPath string, mountRoot string) string {
+	drive := strings.ToLower(windowsPath[:1])
+	path := strings.ReplaceAll(windowsPath[2:], "\\", "/")
+	return strings.TrimSuffix(mountRoot, "/") + "/" + drive + path
+}
+
+// wslMountRoot reads the automount root from /etc/wsl.conf, defaulting to '/mnt/'
+func wslMountRoot() string {
+	content, err := ioutil.ReadFile("/etc/wsl.conf")
+	if err != nil {
+		return wslDefaultMountRoot
+	}
+	return parseWSLMountRoot(string(content))
+}
+
+// parseWSLMountRoot extracts the 'root' setting of the [automount] section from the content of a wsl.conf file
+func parseWSLMountRoot(content string) string {
+	section := ""
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.ToLower(strings.Trim(line, "[]"))
+			continue
+		}
+		if section != "automount" {
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) == 2 && strings.TrimSpace(kv[0]) == "root" {
+			if root := strings.Trim(strings.TrimSpace(kv[1]), `"`); root != "" {
+				return root
+			}
+		}
+	}
+	return wslDefaultMountRoot
+}
+
 // ValidateVolumeMount checks, if the source of volume mounts exists and if the destination is an absolute path
 // - SRC: source directory/file -> tests: must exist
 // - DEST: source directory/file -> tests: must be absolute path
 func ValidateVolumeMount(runtime runtimes.Runtime, volumeMount string) error {
-	src := ""
-	dest := ""
-
-	// validate 'SRC[:DEST]' substring
-	split := strings.Split(volumeMount, ":")
-	// a volume mapping can have 3 parts seperated by a ':' followed by a node filter
-	// [SOURCE:]DEST[:OPT[,OPT]][@NODEFILTER[;NODEFILTER...]]
-	// On Windows the source path needs to be an absolute path which means the path starts with
-	// a drive designator and will also have a ':' in it. So for Windows the maxParts is increased by one.
-	maxParts := 3
-	if rt.GOOS == "windows" {
-		maxParts++
-	}
-	if len(split) < 1 {
-		return fmt.Errorf("No volume/path specified")
-	}
-	if len(split) > maxParts {
-		return fmt.Errorf("Invalid volume mount '%s': maximal %d ':' allowed", volumeMount, maxParts-1)
-	}
-
-	// we only have SRC specified -> DEST = SRC
-	// On windows the first part of the SRC is the drive letter, so we need to concat the first and second parts to get the path.
-	if len(split) == 1 {
-		src = split[0]
-		dest = src
-	} else if rt.GOOS == "windows" && len(split) >= 3 {
-		src = split[0] + ":" + split[1]
-		dest = split[2]
-	} else {
-		src = split[0]
-		dest = split[1]
+	src, dest, _, err := SplitVolumeMount(volumeMount)
+	if err != nil {
+		return err
 	}
 
 	// verify that the source exists
@@ -0,0 +1,96 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestSplitVolumeMount(t *testing.T) {
+	testSets := map[string][]string{
+		"/data":                       {"/data", "/data", ""},
+		"/src:/data":                  {"/src", "/data", ""},
+		"myvol:/data:ro":              {"myvol", "/data", "ro"},
+		`C:\Users\me:/data`:           {`C:\Users\me`, "/data", ""},
+		`C:\Users\me:/data:ro,shared`: {`C:\Users\me`, "/data", "ro,shared"},
+	}
+
+	for volume, expected := range testSets {
+		src, dest, opts, err := SplitVolumeMount(volume)
+		if err != nil {
+			t.Errorf("unexpected error for '%s': %v", volume, err)
+			continue
+		}
+		if diff := deep.Equal([]string{src, dest, opts}, expected); diff != nil {
+			t.Errorf("unexpected split of '%s': %+v", volume, diff)
+		}
+	}
+
+	for _, volume := range []string{"", "/a:/b:ro:x", `C:\a:/b:ro:x`} {
+		if _, _, _, err := SplitVolumeMount(volume); err == nil {
+			t.Errorf("expected error for '%s', got none", volume)
+		}
+	}
+}
+
+func TestSELinuxRelabelVolumeMount(t *testing.T) {
+	testSets := map[string]string{
+		"/data":              "/data:/data:z",
+		"/src:/data":         "/src:/data:z",
+		"/src:/data:ro":      "/src:/data:ro,z",
+		"/src:/data:Z":       "/src:/data:Z",
+		"/src:/data:ro,z":    "/src:/data:ro,z",
+		"myvol:/data":        "myvol:/data",
+		"myvol:/data:shared": "myvol:/data:shared",
+	}
+
+	for volume, expected := range testSets {
+		relabeled, err := SELinuxRelabelVolumeMount(volume)
+		if err != nil {
+			t.Errorf("unexpected error for '%s': %v", volume, err)
+			continue
+		}
+		if relabeled != expected {
+			t.Errorf("expected '%s' to be relabeled as '%s', got '%s'", volume, expected, relabeled)
+		}
+	}
+}
+
+func TestWSLPath(t *testing.T) {
+	if path := WSLPath(`C:\Users\me`, "/mnt/"); path != "/mnt/c/Users/me" {
+		t.Errorf("expected '/mnt/c/Users/me', got '%s'", path)
+	}
+	if path := WSLPath(`D:/projects`, "/"); path != "/d/projects" {
+		t.Errorf("expected '/d/projects', got '%s'", path)
+	}
+}
+
+func TestParseWSLMountRoot(t *testing.T) {
+	if root := parseWSLMountRoot("[network]\nhostname = dev\n"); root != wslDefaultMountRoot {
+		t.Errorf("expected default mount root, got '%s'", root)
+	}
+	if root := parseWSLMountRoot("[network]\nroot = /wrong\n[automount]\nenabled = true\nroot = /win/\n"); root != "/win/" {
+		t.Errorf("expected '/win/', got '%s'", root)
+	}
+}
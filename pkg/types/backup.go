@@ -0,0 +1,37 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package types
+
+import "time"
+
+// ClusterBackup describes a cluster backup archive written by 'k3d backup'
+type ClusterBackup struct {
+	Cluster    string    `yaml:"cluster" json:"cluster"`
+	Created    time.Time `yaml:"created" json:"created"`
+	K3dVersion string    `yaml:"k3dVersion" json:"k3dVersion"`
+	K3sImage   string    `yaml:"k3sImage" json:"k3sImage"`
+	Token      string    `yaml:"token" json:"-"` // required to decrypt the bootstrap data (CA etc.) in the datastore
+
+	// set when the archive is extracted
+	ConfigPath    string `yaml:"-" json:"-"` // config file recorded at cluster creation
+	DatastorePath string `yaml:"-" json:"-"` // copy of the server's SQLite datastore
+}
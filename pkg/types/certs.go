@@ -0,0 +1,42 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package types
+
+import "time"
+
+// CertificateDirectories are the directories inside k3s nodes, where k3s stores its generated certificates
+var CertificateDirectories = []string{
+	"/var/lib/rancher/k3s/server/tls",
+	"/var/lib/rancher/k3s/server/tls/etcd",
+	"/var/lib/rancher/k3s/agent",
+}
+
+// CertificateInfo describes a single certificate found in a k3s node
+type CertificateInfo struct {
+	Node      string    `yaml:"node" json:"node"`
+	File      string    `yaml:"file" json:"file"`
+	Subject   string    `yaml:"subject" json:"subject"`
+	Issuer    string    `yaml:"issuer" json:"issuer"`
+	IsCA      bool      `yaml:"isCA" json:"isCA"`
+	NotBefore time.Time `yaml:"notBefore" json:"notBefore"`
+	NotAfter  time.Time `yaml:"notAfter" json:"notAfter"`
+}
@@ -0,0 +1,54 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package types
+
+import "time"
+
+// LabelCheckpoint is attached to images created as part of a cluster checkpoint
+const LabelCheckpoint string = "k3d.checkpoint"
+
+// DefaultFreezeCheckpointID is the ID of the (CRIU) checkpoint created when freezing a cluster
+const DefaultFreezeCheckpointID string = "k3d-freeze"
+
+// CheckpointPaths are the k3s data directories which are volumes in the k3s image
+// and thus not part of a committed container filesystem, so they're archived separately
+var CheckpointPaths = []string{
+	"/var/lib/rancher/k3s",
+	"/var/lib/kubelet",
+	"/var/lib/cni",
+}
+
+// ClusterCheckpoint describes a saved state of the k3s nodes of a cluster
+type ClusterCheckpoint struct {
+	Name    string           `yaml:"name" json:"name"`
+	Cluster string           `yaml:"cluster" json:"cluster"`
+	Created time.Time        `yaml:"created" json:"created"`
+	Nodes   []CheckpointNode `yaml:"nodes" json:"nodes"`
+}
+
+// CheckpointNode describes the saved state of a single node
+type CheckpointNode struct {
+	Name     string            `yaml:"name" json:"name"`
+	Role     Role              `yaml:"role" json:"role"`
+	Image    string            `yaml:"image" json:"image"`       // image created from the node container
+	Archives map[string]string `yaml:"archives" json:"archives"` // path inside the node -> archive file (relative to the checkpoint directory)
+}
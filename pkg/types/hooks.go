@@ -0,0 +1,54 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package types
+
+// ClusterHookPhase is a point in the lifecycle of a cluster at which the hooks for it run
+type ClusterHookPhase string
+
+// all defined cluster hook phases
+const (
+	ClusterHookPreCreate        ClusterHookPhase = "preCreate"        // before anything is created (no KUBECONFIG yet)
+	ClusterHookPostServerReady  ClusterHookPhase = "postServerReady"  // after all nodes started and the API is reachable, before seed objects and --wait-for
+	ClusterHookPostClusterReady ClusterHookPhase = "postClusterReady" // after the cluster is completely set up
+	ClusterHookPreDelete        ClusterHookPhase = "preDelete"        // before the nodes are deleted (remembered in a label)
+)
+
+// ClusterHookPhases are all valid cluster hook phases in the order they happen
+var ClusterHookPhases = []ClusterHookPhase{ClusterHookPreCreate, ClusterHookPostServerReady, ClusterHookPostClusterReady, ClusterHookPreDelete}
+
+// ClusterHook is a shell command run on the host at a phase of the cluster lifecycle
+type ClusterHook struct {
+	Phase   ClusterHookPhase `yaml:"phase" json:"phase"`
+	Command string           `yaml:"command" json:"command"`
+}
+
+// Manifest is a Kubernetes manifest file deployed by k3s from its auto-deploy directory (DefaultManifestsDir)
+type Manifest struct {
+	Name    string // file name in DefaultManifestsDir
+	Content []byte
+}
+
+// DefaultManifestsDir is the directory in the server nodes, whose manifests k3s applies on startup (and whenever they change)
+const DefaultManifestsDir = "/var/lib/rancher/k3s/server/manifests"
+
+// LabelClusterPreDeleteHooks holds the commands of the preDelete hooks (JSON list), which are only run long after the creation
+const LabelClusterPreDeleteHooks = "k3d.cluster.hooks.preDelete"
@@ -0,0 +1,79 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package types
+
+import _ "embed"
+
+// InventoryAPIVersion versions the format of the inventory ('k3d inventory'), so that external tools can rely on it
+const InventoryAPIVersion = "k3d.io/inventory/v1alpha1"
+
+// InventorySchema is the JSON schema the inventory is validated against before it's printed
+//
+//go:embed inventory.schema.json
+var InventorySchema []byte
+
+// Inventory lists all k3d-managed runtime resources with their (k3d) labels and their relationships,
+// e.g. for external drift-detection and cleanup tooling on shared hosts
+type Inventory struct {
+	APIVersion string              `yaml:"apiVersion" json:"apiVersion"`
+	Clusters   []*InventoryCluster `yaml:"clusters" json:"clusters"`
+	Nodes      []*InventoryNode    `yaml:"nodes" json:"nodes"`
+	Registries []*InventoryNode    `yaml:"registries" json:"registries"`
+	Networks   []*InventoryNetwork `yaml:"networks" json:"networks"`
+	Volumes    []*InventoryVolume  `yaml:"volumes" json:"volumes"`
+}
+
+// InventoryCluster names the resources that belong to a cluster
+type InventoryCluster struct {
+	Name       string   `yaml:"name" json:"name"`
+	Network    string   `yaml:"network,omitempty" json:"network,omitempty"`
+	Nodes      []string `yaml:"nodes" json:"nodes"`
+	Registries []string `yaml:"registries" json:"registries"` // registries connected to the cluster network
+	Volumes    []string `yaml:"volumes" json:"volumes"`       // volumes labeled with the cluster name
+}
+
+// InventoryNode is a k3d-managed container (cluster node or registry)
+type InventoryNode struct {
+	Name     string            `yaml:"name" json:"name"`
+	Role     string            `yaml:"role" json:"role"`
+	Cluster  string            `yaml:"cluster,omitempty" json:"cluster,omitempty"`
+	Status   string            `yaml:"status" json:"status"`
+	Networks []string          `yaml:"networks" json:"networks"`
+	Volumes  []string          `yaml:"volumes" json:"volumes"` // named volumes mounted into the container
+	Labels   map[string]string `yaml:"labels" json:"labels"`
+}
+
+// InventoryNetwork is a k3d-managed network
+type InventoryNetwork struct {
+	Name     string            `yaml:"name" json:"name"`
+	ID       string            `yaml:"id" json:"id"`
+	Clusters []string          `yaml:"clusters" json:"clusters"` // clusters whose nodes are attached to the network
+	Labels   map[string]string `yaml:"labels" json:"labels"`
+}
+
+// InventoryVolume is a k3d-managed volume
+type InventoryVolume struct {
+	Name      string            `yaml:"name" json:"name"`
+	Cluster   string            `yaml:"cluster,omitempty" json:"cluster,omitempty"`
+	MountedBy []string          `yaml:"mountedBy" json:"mountedBy"` // containers the volume is mounted into
+	Labels    map[string]string `yaml:"labels" json:"labels"`
+}
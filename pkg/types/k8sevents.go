@@ -0,0 +1,45 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package types
+
+import "time"
+
+// K8sEventTypeWarning is the type of Kubernetes events reporting a problem (the other type being "Normal")
+const K8sEventTypeWarning = "Warning"
+
+// K8sEventsOpts describes a set of options one can set for listing the Kubernetes events of a cluster
+type K8sEventsOpts struct {
+	Namespace string // only list events in this namespace (all namespaces if empty)
+	Watch     bool   // keep streaming new events after listing the existing ones
+}
+
+// K8sEvent is a Kubernetes event (core/v1 Event), reduced to what's interesting when a local cluster misbehaves
+type K8sEvent struct {
+	Namespace string    `yaml:"namespace" json:"namespace"`
+	Object    string    `yaml:"object" json:"object"` // KIND/NAME of the involved object
+	Type      string    `yaml:"type" json:"type"`     // Normal or Warning
+	Reason    string    `yaml:"reason" json:"reason"`
+	Message   string    `yaml:"message" json:"message"`
+	Source    string    `yaml:"source,omitempty" json:"source,omitempty"` // component that reported the event
+	Count     int32     `yaml:"count" json:"count"`
+	LastSeen  time.Time `yaml:"lastSeen" json:"lastSeen"`
+}
@@ -0,0 +1,50 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package types
+
+import "time"
+
+// PoolClusterState describes whether a cluster of a pool can be handed out
+type PoolClusterState string
+
+// existing states of clusters in a pool
+const (
+	PoolClusterStateFree     PoolClusterState = "free"
+	PoolClusterStateLeased   PoolClusterState = "leased"
+	PoolClusterStateCreating PoolClusterState = "creating" // (re-)created, e.g. after it was released
+)
+
+// Pool is a set of pre-created (warm) clusters, which are handed out to and returned by e.g. CI jobs
+type Pool struct {
+	Name     string         `yaml:"name" json:"name"`
+	Size     int            `yaml:"size" json:"size"`
+	Config   string         `yaml:"config" json:"-"` // the (YAML) SimpleConfig used to (re-)create the clusters of the pool
+	Clusters []*PoolCluster `yaml:"clusters" json:"clusters"`
+}
+
+// PoolCluster is a cluster of a pool and its current lease
+type PoolCluster struct {
+	Name       string           `yaml:"name" json:"name"`
+	State      PoolClusterState `yaml:"state" json:"state"`
+	Holder     string           `yaml:"holder,omitempty" json:"holder,omitempty"`
+	AcquiredAt *time.Time       `yaml:"acquiredAt,omitempty" json:"acquiredAt,omitempty"`
+}
@@ -0,0 +1,75 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package types
+
+import (
+	"sync"
+	"time"
+)
+
+// StageTiming describes how long a single stage of an operation took
+type StageTiming struct {
+	Stage    string        `yaml:"stage" json:"stage"`
+	Duration time.Duration `yaml:"duration" json:"duration"`
+}
+
+// OperationTimings collects the durations of the stages of a (long-running) operation like cluster creation or deletion
+// All methods are safe to call on a nil receiver, so that callers don't have to check if timings are requested at all
+type OperationTimings struct {
+	mu     sync.Mutex
+	Start  time.Time
+	Stages []StageTiming
+}
+
+// NewOperationTimings returns a new OperationTimings object with the start time set to now
+func NewOperationTimings() *OperationTimings {
+	return &OperationTimings{Start: time.Now()}
+}
+
+// Track starts timing the given stage and returns a function that stops it and records the duration
+func (t *OperationTimings) Track(stage string) func() {
+	if t == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		t.Record(stage, time.Since(start))
+	}
+}
+
+// Record adds the duration of a stage
+func (t *OperationTimings) Record(stage string, duration time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Stages = append(t.Stages, StageTiming{Stage: stage, Duration: duration})
+}
+
+// Total returns the time passed since the start of the operation
+func (t *OperationTimings) Total() time.Duration {
+	if t == nil {
+		return 0
+	}
+	return time.Since(t.Start)
+}
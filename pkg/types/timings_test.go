@@ -0,0 +1,78 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package types
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOperationTimings(t *testing.T) {
+	timings := NewOperationTimings()
+
+	timings.Record("preparation", 2*time.Second)
+	stop := timings.Track("create containers")
+	stop()
+
+	if len(timings.Stages) != 2 {
+		t.Fatalf("expected 2 stages, got %d", len(timings.Stages))
+	}
+	if timings.Stages[0].Stage != "preparation" || timings.Stages[0].Duration != 2*time.Second {
+		t.Errorf("unexpected first stage %+v", timings.Stages[0])
+	}
+	if timings.Stages[1].Stage != "create containers" {
+		t.Errorf("unexpected second stage %+v", timings.Stages[1])
+	}
+	if timings.Total() <= 0 {
+		t.Errorf("expected a positive total duration, got %s", timings.Total())
+	}
+}
+
+func TestOperationTimingsConcurrent(t *testing.T) {
+	timings := NewOperationTimings()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			timings.Track("node")()
+		}()
+	}
+	wg.Wait()
+
+	if len(timings.Stages) != 50 {
+		t.Errorf("expected 50 stages, got %d", len(timings.Stages))
+	}
+}
+
+func TestOperationTimingsNil(t *testing.T) {
+	var timings *OperationTimings
+
+	// nil timings must be usable without checks by the caller
+	timings.Track("stage")()
+	timings.Record("stage", time.Second)
+	if total := timings.Total(); total != 0 {
+		t.Errorf("expected a total of 0 for nil timings, got %s", total)
+	}
+}
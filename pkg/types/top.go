@@ -0,0 +1,62 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package types
+
+import "time"
+
+// DefaultMetricsServerImage is the image used when 'k3d top' installs metrics-server into a cluster that has it disabled
+const DefaultMetricsServerImage = "k8s.gcr.io/metrics-server/metrics-server:v0.5.2"
+
+// DefaultMetricsServerManifest is the name of the manifest file that 'k3d top' writes into DefaultManifestsDir to install metrics-server.
+// It differs from the name of k3s' own addon, so that '--disable metrics-server' doesn't remove it again.
+const DefaultMetricsServerManifest = "k3d-metrics-server.yaml"
+
+// ClusterTopOpts describes a set of options one can set for querying the resource usage of a cluster
+type ClusterTopOpts struct {
+	Namespace            string        // only show pods in this namespace (all namespaces if empty)
+	InstallMetricsServer bool          // install metrics-server if the metrics API is not available
+	Timeout              time.Duration // maximum time to wait for the metrics API to become available
+}
+
+// NodeUsage describes the current CPU and memory usage of a Kubernetes node
+type NodeUsage struct {
+	Name          string `yaml:"name" json:"name"`
+	CPUMillis     int64  `yaml:"cpuMillis" json:"cpuMillis"`
+	CPUPercent    int64  `yaml:"cpuPercent" json:"cpuPercent"` // of the node's allocatable CPU
+	MemoryBytes   int64  `yaml:"memoryBytes" json:"memoryBytes"`
+	MemoryPercent int64  `yaml:"memoryPercent" json:"memoryPercent"` // of the node's allocatable memory
+}
+
+// PodUsage describes the current CPU and memory usage of a pod (summed over its containers)
+type PodUsage struct {
+	Namespace   string `yaml:"namespace" json:"namespace"`
+	Name        string `yaml:"name" json:"name"`
+	CPUMillis   int64  `yaml:"cpuMillis" json:"cpuMillis"`
+	MemoryBytes int64  `yaml:"memoryBytes" json:"memoryBytes"`
+}
+
+// ClusterTop describes the resource usage of a cluster's nodes and pods as reported by metrics-server
+type ClusterTop struct {
+	Cluster string       `yaml:"cluster" json:"cluster"`
+	Nodes   []*NodeUsage `yaml:"nodes" json:"nodes"`
+	Pods    []*PodUsage  `yaml:"pods" json:"pods"`
+}
@@ -46,6 +46,13 @@ const DefaultClusterNameMaxLength = 32
 // DefaultObjectNamePrefix defines the name prefix for every object created by k3d
 const DefaultObjectNamePrefix = "k3d"
 
+// DefaultNodeNameTemplate is the text/template equivalent of the default node naming scheme <prefix>-<cluster>-<role>-<index>.
+// Node names are used as container names and hostnames.
+const DefaultNodeNameTemplate = "{{.Prefix}}-{{.Cluster}}-{{.Role}}-{{.Index}}"
+
+// DefaultKubeconfigContextTemplate is the text/template equivalent of the default kubeconfig context name <prefix>-<cluster>.
+const DefaultKubeconfigContextTemplate = "{{.Prefix}}-{{.Cluster}}"
+
 // ReadyLogMessageByRole defines the log messages we wait for until a server node is considered ready
 var ReadyLogMessageByRole = map[Role]string{
 	ServerRole:       "k3s is up and running",
@@ -109,12 +116,16 @@ const (
 	LabelClusterToken         string = "k3d.cluster.token"
 	LabelClusterExternal      string = "k3d.cluster.external"
 	LabelImageVolume          string = "k3d.cluster.imageVolume"
+	LabelImageCache           string = "k3d.imageCache.image"
+	LabelCacheVolume          string = "k3d.cacheVolume.name"
+	LabelCacheVolumeCluster   string = "k3d.cacheVolume.cluster"
 	LabelNetworkExternal      string = "k3d.cluster.network.external"
 	LabelNetwork              string = "k3d.cluster.network"
 	LabelNetworkID            string = "k3d.cluster.network.id"
 	LabelNetworkIPRange       string = "k3d.cluster.network.iprange"
 	LabelRole                 string = "k3d.role"
 	LabelServerAPIPort        string = "k3d.server.api.port"
+	LabelServerAPITunnel      string = "k3d.server.api.tunnel"
 	LabelServerAPIHost        string = "k3d.server.api.host"
 	LabelServerAPIHostIP      string = "k3d.server.api.hostIP"
 	LabelServerIsInit         string = "k3d.server.init"
@@ -123,6 +134,21 @@ const (
 	LabelRegistryPortExternal string = "k3s.registry.port.external"
 	LabelRegistryPortInternal string = "k3s.registry.port.internal"
 	LabelNodeStaticIP         string = "k3d.node.staticIP"
+	LabelPool                 string = "k3d.pool"
+	LabelClusterOwner         string = "k3d.cluster.owner"
+	LabelClusterDescription   string = "k3d.cluster.description"
+	LabelClusterNamespace     string = "k3d.cluster.defaultNamespace"
+	LabelClusterContextTmpl   string = "k3d.cluster.kubeconfig.contextTemplate"
+	LabelImagePinned          string = "k3d.node.image.pinned"
+	LabelClusterCreateConfig  string = "k3d.cluster.create.config"
+	LabelClusterCreateCommand string = "k3d.cluster.create.command"
+	LabelRunnerContainer      string = "k3d.cluster.runner.container"
+	LabelRunnerAPIHost        string = "k3d.cluster.runner.apiHost"
+	LabelNodeBakedFrom        string = "k3d.node.bakedFrom"
+	LabelNodeInit             string = "k3d.node.init"
+	LabelNodeCRI              string = "k3d.node.cri"
+	LabelHostIPDisabled       string = "k3d.cluster.hostIP.disabled"
+	LabelClusterDependsOn     string = "k3d.cluster.dependsOn"
 )
 
 // DefaultRoleCmds maps the node roles to their respective default commands
@@ -137,6 +163,16 @@ var DefaultTmpfsMounts = []string{
 	"/var/run",
 }
 
+// EphemeralStateTmpfsMounts are the state directories of the kubelet and containerd, which are kept in memory with --ephemeral-state (path -> mount options).
+// Docker mounts tmpfs noexec by default, but containerd runs the container images from its snapshots.
+var EphemeralStateTmpfsMounts = map[string]string{
+	"/var/lib/kubelet":                      "exec",
+	"/var/lib/rancher/k3s/agent/containerd": "exec",
+}
+
+// LogDriverNone is the logging driver discarding all container logs, which k3d can't use for its nodes, as it reads their logs to know when they're up
+const LogDriverNone = "none"
+
 // DefaultNodeEnv defines some default environment variables that should be set on every node
 var DefaultNodeEnv = []string{
 	fmt.Sprintf("%s=/output/kubeconfig.yaml", K3sEnvKubeconfigOutput),
@@ -152,9 +188,15 @@ const (
 // DefaultK3dInternalHostRecord defines the default /etc/hosts entry for the k3d host
 const DefaultK3dInternalHostRecord = "host.k3d.internal"
 
+// DefaultAPIDNSDomain is the domain of the stable DNS names of the clusters' Kubernetes APIs on the host (e.g. 'dev.k3d.internal')
+const DefaultAPIDNSDomain = "k3d.internal"
+
 // DefaultImageVolumeMountPath defines the mount path inside k3d nodes where we will mount the shared image volume by default
 const DefaultImageVolumeMountPath = "/k3d/images"
 
+// DefaultImageCacheMountPath is where k3s imports image tarballs from on startup, which is where the host-wide image cache volume is mounted
+const DefaultImageCacheMountPath = "/var/lib/rancher/k3s/agent/images"
+
 // DefaultConfigDirName defines the name of the config directory (where we'll e.g. put the kubeconfigs)
 const DefaultConfigDirName = ".k3d" // should end up in $HOME/
 
@@ -167,6 +209,9 @@ const DefaultAPIPort = "6443"
 // DefaultAPIHost defines the default host (IP) for the Kubernetes API
 const DefaultAPIHost = "0.0.0.0"
 
+// DefaultClusterDescriptionPath is where the description of a cluster is stored in its server nodes when it's changed after creation (container labels are immutable)
+const DefaultClusterDescriptionPath = "/etc/rancher/k3d/description"
+
 // DoNotCopyServerFlags defines a list of commands/args that shouldn't be copied from an existing node when adding a similar node to a cluster
 var DoNotCopyServerFlags = []string{
 	"--cluster-init",
@@ -177,10 +222,24 @@ type ClusterCreateOpts struct {
 	DisableImageVolume  bool              `yaml:"disableImageVolume" json:"disableImageVolume,omitempty"`
 	WaitForServer       bool              `yaml:"waitForServer" json:"waitForServer,omitempty"`
 	Timeout             time.Duration     `yaml:"timeout" json:"timeout,omitempty"`
+	ReadinessTimeout    time.Duration     `yaml:"readinessTimeout,omitempty" json:"readinessTimeout,omitempty"` // maximum time for each phase of the readiness check (API /readyz, nodes Ready)
 	DisableLoadBalancer bool              `yaml:"disableLoadbalancer" json:"disableLoadbalancer,omitempty"`
 	GPURequest          string            `yaml:"gpuRequest" json:"gpuRequest,omitempty"`
 	ServersMemory       string            `yaml:"serversMemory" json:"serversMemory,omitempty"`
 	AgentsMemory        string            `yaml:"agentsMemory" json:"agentsMemory,omitempty"`
+	ServersCPUs         string            `yaml:"serversCpus,omitempty" json:"serversCpus,omitempty"`
+	AgentsCPUs          string            `yaml:"agentsCpus,omitempty" json:"agentsCpus,omitempty"`
+	MemoryBudget        string            `yaml:"memoryBudget" json:"memoryBudget,omitempty"`     // total memory limit for all nodes (already split into Servers-/AgentsMemory)
+	VirtualWorkers      int               `yaml:"virtualWorkers" json:"virtualWorkers,omitempty"` // number of fake nodes (without containers) registered via kwok
+	WaitFor             []string          `yaml:"waitFor,omitempty" json:"waitFor,omitempty"`     // Kubernetes resources ([NAMESPACE/]KIND/NAME) that have to be ready before the creation is done
+	SeedObjects         []SeedObject      `yaml:"-" json:"-"`                                     // Secrets/ConfigMaps created right after the cluster started (not printed, as they may contain credentials)
+	CustomCA            *CustomCA         `yaml:"-" json:"-"`                                     // CA used by k3s to sign its serving certificates (not printed, as it contains the private key)
+	TrustedCAs          []TrustedCA       `yaml:"trustedCAs,omitempty" json:"trustedCAs,omitempty"`
+	AuditPolicy         []byte            `yaml:"-" json:"-"`                             // Kubernetes audit policy (YAML) for the API server
+	DatastoreBackup     []byte            `yaml:"-" json:"-"`                             // k3s SQLite datastore, which the server starts with instead of an empty one
+	NodeInit            []byte            `yaml:"-" json:"-"`                             // script executed inside the k3s nodes before k3s starts
+	Hooks               []ClusterHook     `yaml:"hooks,omitempty" json:"hooks,omitempty"` // commands run on the host at phases of the cluster lifecycle
+	Manifests           []Manifest        `yaml:"-" json:"-"`                             // manifests written to the auto-deploy directory of the servers
 	NodeHooks           []NodeHook        `yaml:"nodeHooks,omitempty" json:"nodeHooks,omitempty"`
 	GlobalLabels        map[string]string `yaml:"globalLabels,omitempty" json:"globalLabels,omitempty"`
 	GlobalEnv           []string          `yaml:"globalEnv,omitempty" json:"globalEnv,omitempty"`
@@ -189,7 +248,50 @@ type ClusterCreateOpts struct {
 		Use    []*Registry   `yaml:"use,omitempty" json:"use,omitempty"`
 		Config *k3s.Registry `yaml:"config,omitempty" json:"config,omitempty"` // registries.yaml (k3s config for containerd registry override)
 	} `yaml:"registries,omitempty" json:"registries,omitempty"`
-}
+	Timings         *OperationTimings `yaml:"-" json:"-"`                                                 // optional: record the duration of the single creation stages
+	RunnerContainer string            `yaml:"runnerContainer,omitempty" json:"runnerContainer,omitempty"` // container k3d itself is running in (name, ID or 'auto'), which needs to reach the nodes (e.g. in CI)
+	HostResolvConf  []byte            `yaml:"-" json:"-"`                                                 // host's resolv.conf (without loopback nameservers) written to the nodes for the kubelet/CoreDNS
+	FixSysctls      bool              `yaml:"fixSysctls,omitempty" json:"fixSysctls,omitempty"`           // raise kernel parameters of the container host that are below the recommended values
+	NoStart         bool              `yaml:"noStart,omitempty" json:"noStart,omitempty"`                 // create everything, but leave the nodes stopped (warm standby for 'k3d cluster start')
+	ImageCache      bool              `yaml:"imageCache,omitempty" json:"imageCache,omitempty"`           // import the system images from a host-wide volume (per k3s image) instead of pulling them in every node
+	DeferWorkers    bool              `yaml:"deferWorkers,omitempty" json:"deferWorkers,omitempty"`       // start the agents only after the servers passed their readiness checks
+	DisableHostIP   bool              `yaml:"disableHostIP,omitempty" json:"disableHostIP,omitempty"`     // don't inject host.k3d.internal into the nodes' /etc/hosts and CoreDNS (remembered for later starts)
+	PullPolicy      ImagePullPolicy   `yaml:"pullPolicy,omitempty" json:"pullPolicy,omitempty"`           // whether the k3s images of the nodes are (re-)pulled before the nodes are created (default: missing)
+	CacheVolumes    []CacheVolume     `yaml:"cacheVolumes,omitempty" json:"cacheVolumes,omitempty"`       // named volumes mounted into all k3s nodes, kept when the cluster is deleted
+	CRI             CRI               `yaml:"cri,omitempty" json:"cri,omitempty"`                         // container runtime k3s runs the pods with (default: containerd)
+}
+
+// CacheVolume is a named volume (e.g. a Go module or npm cache) mounted into all k3s nodes of a cluster at Path.
+// It's not deleted with the cluster, so a re-created cluster of the same name gets it back.
+type CacheVolume struct {
+	Name string `yaml:"name" json:"name"`
+	Path string `yaml:"path" json:"path"`
+}
+
+// CRI defines the container runtime k3s runs the pods with inside the nodes
+type CRI string
+
+// all supported CRIs
+const (
+	CRIContainerd CRI = "containerd"  // the containerd embedded in k3s (default)
+	CRIDockerd    CRI = "cri-dockerd" // a docker daemon inside the nodes via k3s' --docker, for dockershim-compatible behavior (requires an image shipping dockerd)
+)
+
+// CRIs lists all supported CRIs
+var CRIs = []CRI{CRIContainerd, CRIDockerd}
+
+// ImagePullPolicy defines when the images of the k3s nodes are pulled
+type ImagePullPolicy string
+
+// all defined image pull policies
+const (
+	ImagePullPolicyMissing ImagePullPolicy = "missing" // only pull images that aren't present locally (default)
+	ImagePullPolicyAlways  ImagePullPolicy = "always"  // pull the images on every creation, e.g. to keep tags like 'latest' fresh
+	ImagePullPolicyNever   ImagePullPolicy = "never"   // never pull any image, fail if one isn't present locally
+)
+
+// ImagePullPolicies lists all valid image pull policies
+var ImagePullPolicies = []ImagePullPolicy{ImagePullPolicyMissing, ImagePullPolicyAlways, ImagePullPolicyNever}
 
 // NodeHook is an action that is bound to a specifc stage of a node lifecycle
 type NodeHook struct {
@@ -208,15 +310,35 @@ const (
 
 // ClusterStartOpts describe a set of options one can set when (re-)starting a cluster
 type ClusterStartOpts struct {
-	WaitForServer   bool
-	Timeout         time.Duration
-	NodeHooks       []NodeHook `yaml:"nodeHooks,omitempty" json:"nodeHooks,omitempty"`
-	EnvironmentInfo *EnvironmentInfo
+	WaitForServer    bool
+	DeferWorkers     bool          // start the agents only after the servers passed their readiness checks
+	RecreateFailed   bool          // recreate agents from their spec if they repeatedly fail to start
+	ReadinessTimeout time.Duration // maximum time for each phase of the readiness check with WaitForServer (API /readyz, nodes Ready)
+	Timeout          time.Duration
+	NodeHooks        []NodeHook `yaml:"nodeHooks,omitempty" json:"nodeHooks,omitempty"`
+	EnvironmentInfo  *EnvironmentInfo
+}
+
+// ClusterStopOpts describe a set of options one can set when stopping a cluster
+type ClusterStopOpts struct {
+	Graceful        bool          // drain all nodes before stopping them, so that workloads are terminated with their grace period instead of being killed
+	GracefulTimeout time.Duration // maximum time to wait for the drain with Graceful (stopping proceeds afterwards)
+}
+
+// DefaultGracefulStopTimeout is the default maximum time to wait for the nodes to be drained when stopping a cluster gracefully
+const DefaultGracefulStopTimeout = 2 * time.Minute
+
+// ClusterReconcileOpts describe a set of options one can set when reconciling the start order of a cluster's nodes
+type ClusterReconcileOpts struct {
+	DryRun  bool          // only report the agents that would be restarted
+	Timeout time.Duration // maximum time to wait for the servers to be ready and the agents to be restarted
 }
 
 // ClusterDeleteOpts describe a set of options one can set when deleting a cluster
 type ClusterDeleteOpts struct {
-	SkipRegistryCheck bool // skip checking if this is a registry (and act accordingly)
+	SkipRegistryCheck bool              // skip checking if this is a registry (and act accordingly)
+	Timeout           time.Duration     // optional: maximum time for deleting all nodes (which happens concurrently)
+	Timings           *OperationTimings // optional: record the duration of the single deletion stages
 }
 
 // NodeCreateOpts describes a set of options one can set when creating a new node
@@ -242,6 +364,19 @@ type NodeDeleteOpts struct {
 	SkipLBUpdate bool // skip updating the loadbalancer
 }
 
+// NodeStressOpts describes the load injected into a node to trigger resource pressure (e.g. kubelet evictions)
+type NodeStressOpts struct {
+	CPU      int  // number of CPU-burning workers
+	IO       bool // keep writing and syncing a file
+	FillDisk int  // fill the filesystem of the kubelet directory up to this percentage (0: don't fill it)
+}
+
+// NodeLogsOpts describes a set of options one can set when reading the logs of nodes
+type NodeLogsOpts struct {
+	Follow bool      // keep streaming new log lines until the nodes stop or the context is done
+	Since  time.Time // optional: only show log lines written after this time
+}
+
 // NodeHookAction is an interface to implement actions that should trigger at specific points of the node lifecycle
 type NodeHookAction interface {
 	Run(ctx context.Context, node *Node) error
@@ -251,11 +386,218 @@ type NodeHookAction interface {
 type ImageImportOpts struct {
 	KeepTar       bool
 	KeepToolsNode bool
+	Mode          ImportMode
+}
+
+// ImportMode describes how images get into the nodes of a cluster
+type ImportMode string
+
+const (
+	ImportModeDirect    ImportMode = "direct"     // stream the images into the containerd of all nodes at once
+	ImportModeToolsNode ImportMode = "tools-node" // save the images to the shared image volume via the tools node and import them from there
+)
+
+// ImportModes are the supported import modes
+var ImportModes = map[string]ImportMode{
+	string(ImportModeDirect):    ImportModeDirect,
+	string(ImportModeToolsNode): ImportModeToolsNode,
+}
+
+// KubeconfigLocation describes where tools (e.g. IDE plugins) find the kubeconfig of a cluster
+type KubeconfigLocation struct {
+	Path            string   `yaml:"path" json:"path"`                                           // the cluster's dedicated kubeconfig file
+	Contexts        []string `yaml:"contexts" json:"contexts"`                                   // contexts for the cluster in the dedicated file
+	DefaultPath     string   `yaml:"defaultPath,omitempty" json:"defaultPath,omitempty"`         // the default kubeconfig, if it has contexts for the cluster
+	DefaultContexts []string `yaml:"defaultContexts,omitempty" json:"defaultContexts,omitempty"` // contexts for the cluster in the default kubeconfig
+}
+
+// ClusterDiskUsage describes the disk space (in bytes) consumed by a cluster
+type ClusterDiskUsage struct {
+	Cluster     string `yaml:"cluster" json:"cluster"`
+	Nodes       int64  `yaml:"nodes" json:"nodes"`             // writable layers of the node containers
+	ImageVolume int64  `yaml:"imageVolume" json:"imageVolume"` // volume used for image imports
+	Volumes     int64  `yaml:"volumes" json:"volumes"`         // other (named) volumes mounted into the nodes
+	Registries  int64  `yaml:"registries" json:"registries"`   // registry containers and their storage volumes
+	Total       int64  `yaml:"total" json:"total"`
+}
+
+// ImagePruneOpts describes a set of options one can set for removing unused images
+type ImagePruneOpts struct {
+	DryRun bool // only report, which images would be removed
+}
+
+// OrphanPruneOpts describes a set of options one can set for removing orphaned k3d resources
+type OrphanPruneOpts struct {
+	DryRun bool // only report, which resources would be removed
+}
+
+// OrphanedResource kinds
+const (
+	OrphanKindContainer = "container"
+	OrphanKindVolume    = "volume"
+	OrphanKindNetwork   = "network"
+	OrphanKindFile      = "file"
+)
+
+// OrphanedResource is a k3d resource left behind by a cluster that doesn't exist (completely) anymore,
+// e.g. because its creation failed mid-way or its containers were removed by hand
+type OrphanedResource struct {
+	Kind    string `yaml:"kind" json:"kind"`
+	Name    string `yaml:"name" json:"name"` // container, volume or network name, or file path
+	Cluster string `yaml:"cluster,omitempty" json:"cluster,omitempty"`
+	Reason  string `yaml:"reason" json:"reason"`
+}
+
+// GCOpts describes a set of options one can set for freeing disk space via 'k3d gc'
+type GCOpts struct {
+	MinFree              uint64        // only prune while the free space is below this many bytes (0: prune everything)
+	Path                 string        // path on the filesystem whose free space is checked (default: the runtime's data root, if it's on this host, or the home directory)
+	CheckpointsOlderThan time.Duration // also remove checkpoints created longer ago than this (0: keep all checkpoints)
+	DryRun               bool          // only report, what would be removed
+}
+
+// GCItem kinds
+const (
+	GCKindTarball    = "tarball"
+	GCKindContainer  = "container"
+	GCKindImage      = "image"
+	GCKindCheckpoint = "checkpoint"
+)
+
+// GCItem is a resource removed by 'k3d gc' (or to be removed, in case of a dry-run)
+type GCItem struct {
+	Kind    string `yaml:"kind" json:"kind"`
+	Name    string `yaml:"name" json:"name"` // tarball path inside the image volume, container or image name, or cluster/checkpoint
+	Cluster string `yaml:"cluster,omitempty" json:"cluster,omitempty"`
+	Size    int64  `yaml:"size" json:"size"` // bytes (approximately) freed
+}
+
+// GCReport describes the outcome of 'k3d gc'
+type GCReport struct {
+	Path       string    `yaml:"path" json:"path"`
+	FreeBefore uint64    `yaml:"freeBefore" json:"freeBefore"`
+	FreeAfter  uint64    `yaml:"freeAfter" json:"freeAfter"` // estimated in a dry-run
+	Freed      int64     `yaml:"freed" json:"freed"`         // sum of the sizes of the removed items
+	Items      []*GCItem `yaml:"items" json:"items"`
+}
+
+// CustomCA describes a user-provided certificate authority (PEM-encoded), which k3s uses instead of generating its own server CA
+type CustomCA struct {
+	Cert []byte
+	Key  []byte
+}
+
+// TrustedCA describes an additional (PEM-encoded) CA certificate, which is added to the system trust store of the nodes
+type TrustedCA struct {
+	Name string `yaml:"name" json:"name"`
+	Cert []byte `yaml:"-" json:"-"`
+}
+
+// DefaultTrustedCADir is the directory inside the nodes, from which Go programs (k3s, containerd) load additional trusted certificates
+const DefaultTrustedCADir = "/etc/ssl/certs"
+
+// DefaultNodeInitPath is the path inside the nodes, where the node init script is written to for the k3d entrypoint to execute it before k3s starts
+const DefaultNodeInitPath = "/bin/k3d-entrypoint-init.sh"
+
+// DefaultDockerdEntrypointPath is the path inside the nodes, where the script starting the docker daemon for the cri-dockerd CRI is written to
+const DefaultDockerdEntrypointPath = "/bin/k3d-entrypoint-dockerd.sh"
+
+// DefaultHostResolvConfPath is the path inside the nodes, where the host's resolver configuration is written to for the kubelet (and thus CoreDNS) to use
+const DefaultHostResolvConfPath = "/etc/k3d-resolv.conf"
+
+// Paths inside server nodes, where the API server reads the audit policy from and writes the audit log to
+const (
+	DefaultAuditPolicyPath = "/var/lib/rancher/k3s/server/audit-policy.yaml"
+	DefaultAuditLogPath    = "/var/lib/rancher/k3s/server/logs/audit.log"
+)
+
+// DefaultServerDatastorePath is the path inside server nodes, where k3s keeps its SQLite datastore (without etcd or external datastore)
+const DefaultServerDatastorePath = "/var/lib/rancher/k3s/server/db/state.db"
+
+// DefaultEtcdArgs are passed to the embedded etcd of multi-server clusters, unless set explicitly:
+// a lower snapshot count makes etcd keep fewer raft log entries in memory (the upstream default is tuned for dedicated machines)
+var DefaultEtcdArgs = map[string]string{
+	"snapshot-count": "10000",
+}
+
+// DefaultServerMemoryReservation is reserved for k3s and etcd on the servers of multi-server clusters (kubelet system-reserved),
+// so that workloads get evicted before the node runs out of memory
+const DefaultServerMemoryReservation = "512Mi"
+
+// DefaultServerTaint is added to server nodes, which should not run regular workloads (like control-plane nodes in production clusters)
+const DefaultServerTaint = "node-role.kubernetes.io/control-plane:NoSchedule"
+
+// OIDCOpts describes the OpenID Connect settings of the Kubernetes API server
+type OIDCOpts struct {
+	IssuerURL     string `yaml:"issuerURL" json:"issuerURL"`
+	ClientID      string `yaml:"clientID" json:"clientID"`
+	UsernameClaim string `yaml:"usernameClaim,omitempty" json:"usernameClaim,omitempty"`
+	GroupsClaim   string `yaml:"groupsClaim,omitempty" json:"groupsClaim,omitempty"`
+}
+
+// Paths inside server nodes, where k3s looks for an existing server CA before generating one
+const (
+	DefaultServerCACertPath = "/var/lib/rancher/k3s/server/tls/server-ca.crt"
+	DefaultServerCAKeyPath  = "/var/lib/rancher/k3s/server/tls/server-ca.key"
+)
+
+// SeedObject describes a Secret or ConfigMap that is deployed to the cluster at creation time
+type SeedObject struct {
+	Kind      string // Secret or ConfigMap
+	Name      string
+	Namespace string
+	Data      map[string][]byte
+}
+
+// ServiceAccountKubeconfigOpts describes a set of options one can set for generating a kubeconfig scoped to a service account
+type ServiceAccountKubeconfigOpts struct {
+	Namespace      string
+	ServiceAccount string
+	ClusterRole    string        // ClusterRole bound to the service account within the namespace
+	ClusterWide    bool          // bind the ClusterRole cluster-wide (ClusterRoleBinding) instead of within the namespace
+	TokenDuration  time.Duration // request a token expiring after this duration (TokenRequest API) instead of a long-lived token secret
+	Timeout        time.Duration // maximum time to wait for the service account token
+}
+
+// PodRunOpts describes a set of options one can set for running a one-off pod in a cluster
+type PodRunOpts struct {
+	Name      string
+	Image     string
+	Namespace string
+	Command   []string      // overrides the image entrypoint, if set
+	Timeout   time.Duration // overall timeout for the pod to finish
+	Keep      bool          // don't delete the pod after it finished
+}
+
+// SyncOpts describes a set of options one can set for syncing a local directory into the containers of pods
+type SyncOpts struct {
+	Container string   // container of the pods to sync into (default: the pod's default container)
+	Exclude   []string // file name patterns (path.Match) which are not synced, e.g. '.git'
+	Once      bool     // sync once and return instead of watching the directory
+}
+
+// ComposeProject describes a docker compose project, whose services can be attached to a cluster
+type ComposeProject struct {
+	Name     string
+	Services []ComposeService
+}
+
+// ComposeService describes a single service of a docker compose project
+type ComposeService struct {
+	Name          string
+	ContainerName string // fixed container name ('container_name'), if set
+}
+
+// DNSExposeOpts describes a set of options one can set for publishing the cluster DNS on the host
+type DNSExposeOpts struct {
+	HostIP   string // host IP to bind to (default: DefaultDNSExposeHostIP)
+	HostPort int    // host port to bind to (default: DefaultDNSExposeHostPort)
 }
 
 type IPAM struct {
 	IPPrefix netaddr.IPPrefix `yaml:"ipPrefix" json:"ipPrefix,omitempty"`
 	IPsUsed  []netaddr.IP     `yaml:"ipsUsed" json:"ipsUsed,omitempty"`
+	Gateway  netaddr.IP       `yaml:"gateway" json:"gateway,omitempty"` // default: second IP in the subnet
 	Managed  bool             // IPAM is done by k3d
 }
 
@@ -271,19 +613,54 @@ type ClusterNetwork struct {
 	External bool   `yaml:"external" json:"isExternal,omitempty"`
 	IPAM     IPAM   `yaml:"ipam" json:"ipam,omitempty"`
 	Members  []*NetworkMember
+	IPv6     ClusterNetworkIPv6 `yaml:"ipv6" json:"ipv6,omitempty"`
+	Labels   map[string]string  `yaml:"labels,omitempty" json:"labels,omitempty"` // only set when listing networks by label
+}
+
+// ClusterNetworkIPv6 enables IPv6 (dual-stack) in a newly created cluster network
+type ClusterNetworkIPv6 struct {
+	Enabled  bool             `yaml:"enabled" json:"enabled,omitempty"`
+	IPPrefix netaddr.IPPrefix `yaml:"ipPrefix" json:"ipPrefix,omitempty"` // default: assigned by the runtime (requires an IPv6 address pool in its config)
 }
 
 // Cluster describes a k3d cluster
 type Cluster struct {
-	Name               string             `yaml:"name" json:"name,omitempty"`
-	Network            ClusterNetwork     `yaml:"network" json:"network,omitempty"`
-	Token              string             `yaml:"clusterToken" json:"clusterToken,omitempty"`
-	Nodes              []*Node            `yaml:"nodes" json:"nodes,omitempty"`
-	InitNode           *Node              // init server node
-	ExternalDatastore  *ExternalDatastore `yaml:"externalDatastore,omitempty" json:"externalDatastore,omitempty"`
-	KubeAPI            *ExposureOpts      `yaml:"kubeAPI" json:"kubeAPI,omitempty"`
-	ServerLoadBalancer *Loadbalancer      `yaml:"serverLoadbalancer,omitempty" json:"serverLoadBalancer,omitempty"`
-	ImageVolume        string             `yaml:"imageVolume" json:"imageVolume,omitempty"`
+	Name               string              `yaml:"name" json:"name,omitempty"`
+	Network            ClusterNetwork      `yaml:"network" json:"network,omitempty"`
+	Token              string              `yaml:"clusterToken" json:"clusterToken,omitempty"`
+	Nodes              []*Node             `yaml:"nodes" json:"nodes,omitempty"`
+	InitNode           *Node               // init server node
+	ExternalDatastore  *ExternalDatastore  `yaml:"externalDatastore,omitempty" json:"externalDatastore,omitempty"`
+	KubeAPI            *ExposureOpts       `yaml:"kubeAPI" json:"kubeAPI,omitempty"`
+	ServerLoadBalancer *Loadbalancer       `yaml:"serverLoadbalancer,omitempty" json:"serverLoadBalancer,omitempty"`
+	ImageVolume        string              `yaml:"imageVolume" json:"imageVolume,omitempty"`
+	Description        string              `yaml:"description,omitempty" json:"description,omitempty"`
+	DefaultNamespace   string              `yaml:"defaultNamespace,omitempty" json:"defaultNamespace,omitempty"` // namespace of the generated kubeconfig context(s)
+	ContextTemplate    string              `yaml:"contextTemplate,omitempty" json:"contextTemplate,omitempty"`   // name of the generated kubeconfig context (see DefaultKubeconfigContextTemplate)
+	DependsOn          []ClusterDependency `yaml:"dependsOn,omitempty" json:"dependsOn,omitempty"`               // registries and clusters which have to be up before the cluster is started
+}
+
+// ClusterDependency kinds
+const (
+	ClusterDependencyRegistry = "registry"
+	ClusterDependencyCluster  = "cluster"
+)
+
+// ClusterDependency is a registry or another cluster a cluster depends on, e.g. to pull images from or to talk to via a shared network
+type ClusterDependency struct {
+	Kind string `yaml:"kind" json:"kind"`
+	Name string `yaml:"name" json:"name"` // registry (container) name or cluster name
+}
+
+func (d ClusterDependency) String() string {
+	return fmt.Sprintf("%s:%s", d.Kind, d.Name)
+}
+
+// ClusterStartPlan is the order in which clusters and the registries they depend on are started
+type ClusterStartPlan struct {
+	Registries []*Node         // started (and ready) before any of the clusters
+	Clusters   []*Cluster      // each cluster after the clusters it depends on
+	Required   map[string]bool // names of the clusters others depend on, which have to be ready before those are started
 }
 
 // ServerCountRunning returns the number of server nodes running in the cluster and the total number
@@ -316,6 +693,17 @@ func (c *Cluster) AgentCountRunning() (int, int) {
 	return agentCount, agentsRunning
 }
 
+// OOMKilledNodes returns the server and agent nodes of the cluster in which processes were killed for running out of memory
+func (c *Cluster) OOMKilledNodes() []*Node {
+	nodes := []*Node{}
+	for _, node := range c.Nodes {
+		if (node.Role == ServerRole || node.Role == AgentRole) && node.State.OOMKilled {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
 type NodeIP struct {
 	IP     netaddr.IP
 	Static bool
@@ -337,10 +725,19 @@ type Node struct {
 	K3sNodeLabels map[string]string `yaml:"k3sNodeLabels" json:"k3sNodeLabels,omitempty"`
 	Networks      []string          // filled automatically
 	ExtraHosts    []string          // filled automatically
+	DNS           []string          // nameservers used by the container instead of the runtime's defaults
+	DNSSearch     []string          // DNS search domains of the container
+	SecurityOpts  []string          // runtime security options, e.g. 'label=disable' (SELinux) or 'apparmor=PROFILE'
 	ServerOpts    ServerOpts        `yaml:"serverOpts" json:"serverOpts,omitempty"`
 	AgentOpts     AgentOpts         `yaml:"agentOpts" json:"agentOpts,omitempty"`
 	GPURequest    string            // filled automatically
 	Memory        string            // filled automatically
+	CPUs          string            // filled automatically, (fractional) number of CPUs, e.g. '1.5'
+	ShmSize       string            `yaml:"shmSize" json:"shmSize,omitempty"`       // size of /dev/shm, e.g. '1g' (default: the runtime's default, e.g. 64m for docker)
+	FakeMemory    string            `yaml:"fakeMemory" json:"fakeMemory,omitempty"` // memory capacity reported to the kubelet without limiting the container
+	Tmpfs         map[string]string `yaml:"tmpfs" json:"tmpfs,omitempty"`           // tmpfs mounts in addition to the default ones (path -> mount options)
+	LogDriver     string            `yaml:"logDriver" json:"logDriver,omitempty"`   // logging driver of the container, e.g. 'local' or 'journald' (default: the runtime's default, e.g. json-file for docker)
+	LogOpts       map[string]string `yaml:"logOpts" json:"logOpts,omitempty"`       // options of the logging driver, e.g. 'max-size' -> '10m'
 	State         NodeState         // filled automatically
 	IP            NodeIP            // filled automatically -> refers solely to the cluster network
 	HookActions   []NodeHook        `yaml:"hooks" json:"hooks,omitempty"`
@@ -356,6 +753,7 @@ type ServerOpts struct {
 type ExposureOpts struct {
 	nat.PortMapping        // filled automatically (reference to normal portmapping)
 	Host            string `yaml:"host,omitempty" json:"host,omitempty"`
+	Tunnel          bool   `yaml:"tunnel,omitempty" json:"tunnel,omitempty"` // reach the API through an SSH port-forward to the (remote) docker host
 }
 
 // ExternalDatastore describes an external datastore used for HA/multi-server clusters
@@ -377,9 +775,45 @@ func GetDefaultObjectName(name string) string {
 
 // NodeState describes the current state of a node
 type NodeState struct {
-	Running bool
-	Status  string
-	Started string
+	Running        bool
+	Status         string
+	Started        string
+	PublishedPorts nat.PortMap `yaml:"publishedPorts,omitempty" json:"publishedPorts,omitempty"` // host ports actually bound by the runtime (incl. random ones), only set while running
+	OOMKilled      bool        `yaml:"oomKilled,omitempty" json:"oomKilled,omitempty"`           // a process in the node was killed for exceeding its memory limit since it was (re-)started
+}
+
+// ClusterStatus describes the state of a cluster's node containers and whether its Kubernetes API is reachable
+type ClusterStatus struct {
+	Cluster      string       `yaml:"cluster" json:"cluster"`
+	APIReachable bool         `yaml:"apiReachable" json:"apiReachable"`
+	APIError     string       `yaml:"apiError,omitempty" json:"apiError,omitempty"` // why the API isn't reachable
+	Nodes        []NodeStatus `yaml:"nodes" json:"nodes"`
+}
+
+// KubeconfigValidity describes whether the kubeconfig recorded for a cluster still works
+type KubeconfigValidity string
+
+// Results of checking the kubeconfig recorded for a cluster
+const (
+	KubeconfigValid     KubeconfigValidity = "valid"
+	KubeconfigStale     KubeconfigValidity = "stale"     // the API rejects the credentials or can't be reached at the recorded address
+	KubeconfigMissing   KubeconfigValidity = "missing"   // neither the default kubeconfig nor the cluster's kubeconfig file has an entry for the cluster
+	KubeconfigUnchecked KubeconfigValidity = "unchecked" // e.g. the cluster isn't running
+)
+
+// KubeconfigCheck is the result of checking the kubeconfig recorded for a cluster
+type KubeconfigCheck struct {
+	Validity KubeconfigValidity `yaml:"validity" json:"validity"`
+	Path     string             `yaml:"path,omitempty" json:"path,omitempty"` // kubeconfig file with the checked entry
+	Reason   string             `yaml:"reason,omitempty" json:"reason,omitempty"`
+}
+
+// NodeStatus describes the state of a single node container
+type NodeStatus struct {
+	Name    string    `yaml:"name" json:"name"`
+	Role    Role      `yaml:"role" json:"role"`
+	State   string    `yaml:"state" json:"state"`                         // as reported by the runtime, e.g. running, paused or exited
+	Started time.Time `yaml:"started,omitempty" json:"started,omitempty"` // zero if the container isn't running
 }
 
 /*
@@ -397,6 +831,15 @@ const (
 	DefaultLocalRegistryHostingConfigmapTempPath = "/tmp/localRegistryHostingCM.yaml"
 )
 
+// DNS Expose Defaults
+const (
+	DefaultDNSExposeServiceName = DefaultObjectNamePrefix + "-dns"
+	DefaultDNSExposeNodePort    = 30053
+	DefaultDNSExposeHostIP      = "127.0.0.1"
+	DefaultDNSExposeHostPort    = 10053
+	DefaultClusterDomain        = "cluster.local"
+)
+
 // Registry describes a k3d-managed registry
 type Registry struct {
 	ClusterRef   string       // filled automatically -> if created with a cluster
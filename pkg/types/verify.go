@@ -0,0 +1,56 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package types
+
+// VerifyResult is the outcome of a single cluster verification check
+type VerifyResult string
+
+// Possible results of a verification check
+const (
+	VerifyResultPass VerifyResult = "pass"
+	VerifyResultFail VerifyResult = "fail"
+	VerifyResultSkip VerifyResult = "skip"
+)
+
+// DefaultVerifyImage is the image used for the in-cluster verification pods
+const DefaultVerifyImage = "docker.io/library/busybox:1.34"
+
+// VerifyCheck describes the result of a single verification check
+type VerifyCheck struct {
+	Name    string       `yaml:"name" json:"name"`
+	Result  VerifyResult `yaml:"result" json:"result"`
+	Details string       `yaml:"details,omitempty" json:"details,omitempty"`
+}
+
+// ClusterVerifyReport describes the results of all verification checks run against a cluster
+type ClusterVerifyReport struct {
+	Cluster string         `yaml:"cluster" json:"cluster"`
+	Passed  bool           `yaml:"passed" json:"passed"`
+	Checks  []*VerifyCheck `yaml:"checks" json:"checks"`
+}
+
+// ClusterDoctorReport describes the inconsistencies found in a cluster's resources by 'k3d doctor'
+type ClusterDoctorReport struct {
+	Cluster string         `yaml:"cluster" json:"cluster"`
+	Healthy bool           `yaml:"healthy" json:"healthy"`
+	Checks  []*VerifyCheck `yaml:"checks" json:"checks"`
+}
@@ -0,0 +1,35 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package types
+
+// Virtual workers are fake nodes registered in the cluster without any backing container (kwok-style)
+const (
+	// DefaultVirtualWorkersImage is the kwok controller image that keeps the virtual workers alive
+	DefaultVirtualWorkersImage = "registry.k8s.io/kwok/kwok:v0.4.0"
+	// DefaultVirtualWorkersManifestPath is the k3s auto-deploy manifest containing the kwok controller and the fake nodes
+	DefaultVirtualWorkersManifestPath = "/var/lib/rancher/k3s/server/manifests/k3d-virtual-workers.yaml"
+	// VirtualWorkerAnnotation marks nodes that are managed by the kwok controller (also used as taint key)
+	VirtualWorkerAnnotation = "kwok.x-k8s.io/node"
+	// DefaultVirtualWorkerCPUs and DefaultVirtualWorkerMemory are the capacities advertised by each virtual worker
+	DefaultVirtualWorkerCPUs   = "32"
+	DefaultVirtualWorkerMemory = "256Gi"
+)
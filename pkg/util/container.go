@@ -0,0 +1,79 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+)
+
+// files created by the container runtimes inside of their containers
+var containerEnvFiles = []string{"/.dockerenv", "/run/.containerenv"}
+
+// mountinfoContainerIDRegexp matches the container ID in the source paths of the bind-mounted /etc/hostname, /etc/hosts and /etc/resolv.conf
+// e.g. '/var/lib/docker/containers/<ID>/hostname' (docker) or '/var/lib/containers/storage/overlay-containers/<ID>/userdata/hostname' (podman)
+var mountinfoContainerIDRegexp = regexp.MustCompile(`/(?:containers|overlay-containers)/([0-9a-f]{64})/`)
+
+// RunningInContainer checks whether the current process is running inside of a (docker or podman) container
+func RunningInContainer() bool {
+	for _, f := range containerEnvFiles {
+		if _, err := os.Stat(f); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// OwnContainerID returns the ID of the container the current process is running in
+func OwnContainerID() (string, error) {
+	if !RunningInContainer() {
+		return "", fmt.Errorf("not running inside of a container (none of %v exists)", containerEnvFiles)
+	}
+
+	if f, err := os.Open("/proc/self/mountinfo"); err == nil {
+		defer f.Close()
+		if id := containerIDFromMountinfo(f); id != "" {
+			return id, nil
+		}
+	}
+
+	// the hostname of a container defaults to its (short) ID, which the runtime accepts as well
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", fmt.Errorf("failed to get hostname: %w", err)
+	}
+	return hostname, nil
+}
+
+// containerIDFromMountinfo returns the first container ID found in the mount sources listed in mountinfo
+func containerIDFromMountinfo(mountinfo io.Reader) string {
+	scanner := bufio.NewScanner(mountinfo)
+	for scanner.Scan() {
+		if match := mountinfoContainerIDRegexp.FindStringSubmatch(scanner.Text()); match != nil {
+			return match[1]
+		}
+	}
+	return ""
+}
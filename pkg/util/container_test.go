@@ -0,0 +1,57 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestContainerIDFromMountinfo(t *testing.T) {
+	id := "3f4e1c0d9b8a7f6e5d4c3b2a19081726354453627180f9e8d7c6b5a493827161"
+
+	testCases := map[string]struct {
+		mountinfo string
+		expected  string
+	}{
+		"docker": {
+			mountinfo: "612 590 0:52 / / rw,relatime master:226 - overlay overlay rw\n" +
+				"631 612 254:1 /var/lib/docker/containers/" + id + "/resolv.conf /etc/resolv.conf rw,relatime - ext4 /dev/vda1 rw\n" +
+				"632 612 254:1 /var/lib/docker/containers/" + id + "/hostname /etc/hostname rw,relatime - ext4 /dev/vda1 rw\n",
+			expected: id,
+		},
+		"podman": {
+			mountinfo: "701 690 0:61 /containers/storage/overlay-containers/" + id + "/userdata/hostname /etc/hostname rw - tmpfs tmpfs rw\n",
+			expected:  id,
+		},
+		"no container": {
+			mountinfo: "22 1 254:1 / / rw,relatime shared:1 - ext4 /dev/vda1 rw\n",
+			expected:  "",
+		},
+	}
+
+	for name, tc := range testCases {
+		if id := containerIDFromMountinfo(strings.NewReader(tc.mountinfo)); id != tc.expected {
+			t.Errorf("%s: expected container ID '%s', got '%s'", name, tc.expected, id)
+		}
+	}
+}
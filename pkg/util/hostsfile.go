@@ -0,0 +1,124 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// hostsEntryMarker marks the entries of the hosts file managed by k3d, so they're the only ones it ever changes or removes
+const hostsEntryMarker = "# managed by k3d"
+
+// HostsFilePath returns the path of the host's hosts file
+func HostsFilePath() string {
+	if runtime.GOOS == "windows" {
+		return `C:\Windows\System32\drivers\etc\hosts`
+	}
+	return "/etc/hosts"
+}
+
+// HostsFileSetEntry makes hostname resolve to ip via the hosts file at path. The file is only written if it doesn't contain
+// a matching entry yet (e.g. one added manually), replacing a previous entry of k3d for the hostname. It reports whether it changed the file.
+func HostsFileSetEntry(path string, ip string, hostname string) (bool, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read hosts file '%s': %w", path, err)
+	}
+	updated, changed := hostsSetEntry(content, ip, hostname)
+	if !changed {
+		return false, nil
+	}
+	return true, writeHostsFile(path, updated)
+}
+
+// HostsFileRemoveEntry removes the entry k3d added for hostname from the hosts file at path (if there is one) and reports whether it changed the file
+func HostsFileRemoveEntry(path string, hostname string) (bool, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read hosts file '%s': %w", path, err)
+	}
+	updated, changed := hostsRemoveEntry(content, hostname)
+	if !changed {
+		return false, nil
+	}
+	return true, writeHostsFile(path, updated)
+}
+
+// writeHostsFile overwrites the hosts file in place, as it may be a bind mount (e.g. in containers) that can't be replaced by renaming
+func writeHostsFile(path string, content []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat hosts file '%s': %w", path, err)
+	}
+	if err := ioutil.WriteFile(path, content, info.Mode()); err != nil {
+		return fmt.Errorf("failed to write hosts file '%s': %w", path, err)
+	}
+	return nil
+}
+
+// hostsSetEntry returns the hosts file content with an entry of k3d for hostname pointing to ip, unless there's already an entry doing so
+func hostsSetEntry(content []byte, ip string, hostname string) ([]byte, bool) {
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(strings.SplitN(line, "#", 2)[0])
+		if len(fields) < 2 || fields[0] != ip {
+			continue
+		}
+		for _, name := range fields[1:] {
+			if name == hostname {
+				return content, false
+			}
+		}
+	}
+
+	updated, _ := hostsRemoveEntry(content, hostname)
+	if len(updated) > 0 && !bytes.HasSuffix(updated, []byte("\n")) {
+		updated = append(updated, '\n')
+	}
+	return append(updated, []byte(fmt.Sprintf("%s\t%s %s\n", ip, hostname, hostsEntryMarker))...), true
+}
+
+// hostsRemoveEntry returns the hosts file content without the entries of k3d for hostname
+func hostsRemoveEntry(content []byte, hostname string) ([]byte, bool) {
+	lines := strings.SplitAfter(string(content), "\n")
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if strings.HasSuffix(strings.TrimSpace(line), hostsEntryMarker) {
+			fields := strings.Fields(strings.SplitN(line, "#", 2)[0])
+			if len(fields) == 2 && fields[1] == hostname {
+				continue
+			}
+		}
+		kept = append(kept, line)
+	}
+	if len(kept) == len(lines) {
+		return content, false
+	}
+	return []byte(strings.Join(kept, "")), true
+}
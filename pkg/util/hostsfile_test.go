@@ -0,0 +1,77 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestHostsFileEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	original := "127.0.0.1\tlocalhost\n::1\tlocalhost ip6-localhost"
+	if err := ioutil.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+	read := func() string {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+
+	if changed, err := HostsFileSetEntry(path, "127.26.1.8", "dev.k3d.internal"); err != nil || !changed {
+		t.Fatalf("expected the entry to be added, got changed=%t, err=%v", changed, err)
+	}
+	expected := original + "\n127.26.1.8\tdev.k3d.internal # managed by k3d\n"
+	if content := read(); content != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, content)
+	}
+
+	// same entry again: nothing to do
+	if changed, err := HostsFileSetEntry(path, "127.26.1.8", "dev.k3d.internal"); err != nil || changed {
+		t.Errorf("expected no change for an existing entry, got changed=%t, err=%v", changed, err)
+	}
+
+	// different IP: the entry of k3d is replaced
+	if changed, err := HostsFileSetEntry(path, "127.26.1.9", "dev.k3d.internal"); err != nil || !changed {
+		t.Fatalf("expected the entry to be replaced, got changed=%t, err=%v", changed, err)
+	}
+	expected = original + "\n127.26.1.9\tdev.k3d.internal # managed by k3d\n"
+	if content := read(); content != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, content)
+	}
+
+	if changed, err := HostsFileRemoveEntry(path, "dev.k3d.internal"); err != nil || !changed {
+		t.Fatalf("expected the entry to be removed, got changed=%t, err=%v", changed, err)
+	}
+	if content := read(); content != original+"\n" {
+		t.Errorf("expected only the original entries to be left, got:\n%s", content)
+	}
+
+	// entries not managed by k3d are never removed
+	if changed, err := HostsFileRemoveEntry(path, "localhost"); err != nil || changed {
+		t.Errorf("expected no change, got changed=%t, err=%v", changed, err)
+	}
+}
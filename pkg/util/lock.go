@@ -0,0 +1,81 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	l "github.com/rancher/k3d/v5/pkg/logger"
+)
+
+// errLockHeld is returned by tryLockFile if the lock is held by someone else
+var errLockHeld = errors.New("lock is held by another process")
+
+// LockFile acquires an advisory lock on the file at the given path, waiting for other processes holding it until the context is done
+// The lock is held via the operating system (flock/LockFileEx), so it's released automatically if the process dies and can never go stale.
+// The file itself is kept, as removing it would allow two processes to lock different files of the same path.
+// The returned function releases the lock again.
+func LockFile(ctx context.Context, path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file '%s': %w", path, err)
+	}
+
+	waiting := false
+	for {
+		err := tryLockFile(f)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, errLockHeld) {
+			f.Close()
+			return nil, fmt.Errorf("failed to lock '%s': %w", path, err)
+		}
+
+		if !waiting {
+			l.Log().Infof("Waiting for another k3d process holding the lock '%s'...", path)
+			waiting = true
+		}
+		select {
+		case <-ctx.Done():
+			f.Close()
+			return nil, fmt.Errorf("failed to acquire lock '%s': %w", path, ctx.Err())
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+
+	// the PID is only informational (to find out who is holding the lock)
+	if err := f.Truncate(0); err == nil {
+		_, _ = f.WriteAt([]byte(fmt.Sprintf("%d\n", os.Getpid())), 0)
+	}
+
+	return func() {
+		if err := unlockFile(f); err != nil {
+			l.Log().Warnf("Failed to release lock '%s': %v", path, err)
+		}
+		f.Close()
+	}, nil
+}
@@ -0,0 +1,84 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockFile(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "test.lock")
+
+	unlock, err := LockFile(context.Background(), lockPath)
+	if err != nil {
+		t.Fatalf("failed to acquire free lock: %v", err)
+	}
+
+	// a second acquisition has to wait until the lock is released
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if _, err := LockFile(ctx, lockPath); err == nil {
+		t.Fatalf("expected the lock to be held")
+	}
+
+	unlock()
+	unlockAgain, err := LockFile(context.Background(), lockPath)
+	if err != nil {
+		t.Fatalf("failed to acquire released lock: %v", err)
+	}
+	defer unlockAgain()
+
+	// a lock file left behind by a crashed process (the OS released its lock) is not held anymore, no matter how old it is
+	stalePath := filepath.Join(t.TempDir(), "stale.lock")
+	if err := os.WriteFile(stalePath, []byte("4711\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	staleTime := time.Now().Add(-24 * time.Hour)
+	if err := os.Chtimes(stalePath, staleTime, staleTime); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	unlockStale, err := LockFile(ctx, stalePath)
+	if err != nil {
+		t.Fatalf("failed to acquire left-over lock: %v", err)
+	}
+	if content, err := os.ReadFile(stalePath); err != nil || string(content) != fmt.Sprintf("%d\n", os.Getpid()) {
+		t.Errorf("expected the lock file to contain our PID, got '%s' (%v)", content, err)
+	}
+
+	// an old lock is never broken while it's held
+	if err := os.Chtimes(stalePath, staleTime, staleTime); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if _, err := LockFile(ctx, stalePath); err == nil {
+		t.Fatalf("expected the old but held lock not to be broken")
+	}
+	unlockStale()
+}
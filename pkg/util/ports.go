@@ -25,6 +25,7 @@ package util
 import (
 	"fmt"
 	"net"
+	"strconv"
 
 	"github.com/docker/go-connections/nat"
 )
@@ -45,6 +46,26 @@ func GetFreePort() (int, error) {
 	return tcpListener.Addr().(*net.TCPAddr).Port, nil
 }
 
+// IsHostPortFree checks whether the given port can be bound on the given IP (all interfaces, if empty) of the local host.
+// SCTP can't be checked with the standard library, so it's checked like TCP.
+func IsHostPortFree(ip string, port int, proto string) bool {
+	address := net.JoinHostPort(ip, strconv.Itoa(port))
+	if proto == "udp" {
+		conn, err := net.ListenPacket("udp", address)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return false
+	}
+	listener.Close()
+	return true
+}
+
 var equalHostIPs = map[string]interface{}{
 	"":          nil,
 	"127.0.0.1": nil,
@@ -0,0 +1,101 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+)
+
+// hostResolvConfFiles are tried in order: systemd-resolved only lists its local stub resolver in /etc/resolv.conf, but the upstream servers in the latter
+var hostResolvConfFiles = []string{"/etc/resolv.conf", "/run/systemd/resolve/resolv.conf"}
+
+// ResolvConf describes the resolver configuration of a resolv.conf file
+type ResolvConf struct {
+	Nameservers []string
+	Search      []string
+	Options     []string
+}
+
+// HostResolvConf returns the resolver configuration of the host without loopback nameservers, which are unreachable from within containers
+func HostResolvConf() (*ResolvConf, error) {
+	for _, path := range hostResolvConfFiles {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		conf, err := ParseResolvConf(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if len(conf.Nameservers) > 0 {
+			return conf, nil
+		}
+	}
+	return nil, fmt.Errorf("no non-loopback nameserver found in %v", hostResolvConfFiles)
+}
+
+// ParseResolvConf parses the content of a resolv.conf file, dropping loopback nameservers (e.g. systemd-resolved's or dnsmasq's local stub resolvers)
+func ParseResolvConf(r io.Reader) (*ResolvConf, error) {
+	conf := &ResolvConf{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], ";") {
+			continue
+		}
+		switch fields[0] {
+		case "nameserver":
+			ip := net.ParseIP(strings.SplitN(fields[1], "%", 2)[0]) // strip IPv6 zone
+			if ip == nil || ip.IsLoopback() {
+				continue
+			}
+			conf.Nameservers = append(conf.Nameservers, fields[1])
+		case "search", "domain": // the last one wins
+			conf.Search = fields[1:]
+		case "options":
+			conf.Options = append(conf.Options, fields[1:]...)
+		}
+	}
+	return conf, scanner.Err()
+}
+
+// Bytes returns the resolver configuration in resolv.conf format
+func (c *ResolvConf) Bytes() []byte {
+	var buf bytes.Buffer
+	for _, ns := range c.Nameservers {
+		fmt.Fprintf(&buf, "nameserver %s\n", ns)
+	}
+	if len(c.Search) > 0 {
+		fmt.Fprintf(&buf, "search %s\n", strings.Join(c.Search, " "))
+	}
+	if len(c.Options) > 0 {
+		fmt.Fprintf(&buf, "options %s\n", strings.Join(c.Options, " "))
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,67 @@
+/*
+Copyright © 2020-2021 The k3d Author(s)
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package util
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseResolvConf(t *testing.T) {
+	content := `# Generated by NetworkManager
+nameserver 127.0.0.53
+nameserver 10.8.0.1
+nameserver ::1
+nameserver fe80::1%eth0
+domain old.example.com
+search corp.example.com vpn.example.com
+options edns0 trust-ad
+options ndots:2
+`
+	conf, err := ParseResolvConf(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := &ResolvConf{
+		Nameservers: []string{"10.8.0.1", "fe80::1%eth0"},
+		Search:      []string{"corp.example.com", "vpn.example.com"},
+		Options:     []string{"edns0", "trust-ad", "ndots:2"},
+	}
+	if !reflect.DeepEqual(conf, expected) {
+		t.Errorf("expected %+v, got %+v", expected, conf)
+	}
+
+	expectedBytes := "nameserver 10.8.0.1\nnameserver fe80::1%eth0\nsearch corp.example.com vpn.example.com\noptions edns0 trust-ad ndots:2\n"
+	if string(conf.Bytes()) != expectedBytes {
+		t.Errorf("expected\n%s\ngot\n%s", expectedBytes, string(conf.Bytes()))
+	}
+
+	stubOnly, err := ParseResolvConf(strings.NewReader("nameserver 127.0.0.53\noptions edns0\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(stubOnly.Nameservers) != 0 {
+		t.Errorf("expected loopback nameservers to be dropped, got %v", stubOnly.Nameservers)
+	}
+}